@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ErrInvalidToken is returned when a presented token does not match any configured token
+var ErrInvalidToken = errors.New("invalid token")
+
+// Token is a named API token with an optional set of table scopes
+type Token struct {
+	Name  string
+	Value string
+	// Tables holds the table patterns this token may receive changes for.
+	// An empty list grants access to all tables.
+	Tables []string
+}
+
+// AllowsTable reports whether the token is scoped to receive changes for the given table.
+// An empty scope allows every table; see MatchTable for the pattern syntax.
+func (t *Token) AllowsTable(table string) bool {
+	return len(t.Tables) == 0 || MatchTable(t.Tables, table)
+}
+
+// MatchTable reports whether a table matches any of the patterns. Patterns use path.Match
+// syntax. A pattern without a schema (e.g. "users" or "audit_*") also matches
+// schema-qualified tables by their bare name.
+func MatchTable(patterns []string, table string) bool {
+	bare := table
+	if idx := strings.LastIndex(table, "."); idx >= 0 {
+		bare = table[idx+1:]
+	}
+
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, table); ok {
+			return true
+		}
+		if !strings.Contains(pattern, ".") {
+			if ok, _ := path.Match(pattern, bare); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Authenticator validates bearer tokens against a static set of configured tokens
+type Authenticator struct {
+	tokens []*Token
+}
+
+// NewAuthenticator creates an authenticator for the given tokens
+func NewAuthenticator(tokens []*Token) *Authenticator {
+	return &Authenticator{tokens: tokens}
+}
+
+// Authenticate returns the token matching the presented value
+func (a *Authenticator) Authenticate(value string) (*Token, error) {
+	if value == "" {
+		return nil, ErrInvalidToken
+	}
+
+	// Compare against every token so timing does not reveal which entry matched
+	var match *Token
+	for _, token := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(token.Value), []byte(value)) == 1 {
+			match = token
+		}
+	}
+	if match == nil {
+		return nil, ErrInvalidToken
+	}
+	return match, nil
+}
+
+// ParseTokens parses a token specification of the form
+//
+//	name=token[:table,table...][;name=token[:table,table...]...]
+//
+// Entries without a table list are granted access to all tables.
+func ParseTokens(spec string) ([]*Token, error) {
+	var tokens []*Token
+	seenNames := make(map[string]bool)
+	seenValues := make(map[string]bool)
+
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, rest, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("token entry %q must be of the form name=token[:tables]", entry)
+		}
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("token entry %q has an empty name", entry)
+		}
+		if seenNames[name] {
+			return nil, fmt.Errorf("duplicate token name %q", name)
+		}
+		seenNames[name] = true
+
+		value, tableList, _ := strings.Cut(rest, ":")
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return nil, fmt.Errorf("token %q has an empty value", name)
+		}
+		if seenValues[value] {
+			return nil, fmt.Errorf("token %q reuses the value of another token", name)
+		}
+		seenValues[value] = true
+
+		token := &Token{Name: name, Value: value}
+		for _, table := range strings.Split(tableList, ",") {
+			table = strings.TrimSpace(table)
+			if table == "" {
+				continue
+			}
+			if _, err := path.Match(table, ""); err != nil {
+				return nil, fmt.Errorf("token %q has invalid table pattern %q: %w", name, table, err)
+			}
+			token.Tables = append(token.Tables, table)
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+// BearerToken extracts the token from an "authorization" header value
+func BearerToken(header string) string {
+	const prefix = "bearer "
+	if len(header) >= len(prefix) && strings.EqualFold(header[:len(prefix)], prefix) {
+		return strings.TrimSpace(header[len(prefix):])
+	}
+	return strings.TrimSpace(header)
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []*Token
+		wantErr bool
+	}{
+		{
+			name: "single unscoped token",
+			spec: "translicator=abc123",
+			want: []*Token{{Name: "translicator", Value: "abc123"}},
+		},
+		{
+			name: "multiple tokens with scopes",
+			spec: "prod=abc123; dev=def456:public.orders, public.products ;",
+			want: []*Token{
+				{Name: "prod", Value: "abc123"},
+				{Name: "dev", Value: "def456", Tables: []string{"public.orders", "public.products"}},
+			},
+		},
+		{
+			name: "empty spec",
+			spec: "",
+			want: nil,
+		},
+		{
+			name:    "missing separator",
+			spec:    "abc123",
+			wantErr: true,
+		},
+		{
+			name:    "empty value",
+			spec:    "prod=",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate name",
+			spec:    "prod=abc;prod=def",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate value",
+			spec:    "prod=abc;dev=abc",
+			wantErr: true,
+		},
+		{
+			name:    "invalid pattern",
+			spec:    "dev=abc:public.[users",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTokens(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTokens() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTokens() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenAllowsTable(t *testing.T) {
+	tests := []struct {
+		name   string
+		tables []string
+		table  string
+		want   bool
+	}{
+		{"unscoped token", nil, "public.users", true},
+		{"exact match", []string{"public.orders"}, "public.orders", true},
+		{"no match", []string{"public.orders"}, "public.users", false},
+		{"schema wildcard", []string{"public.*"}, "public.users", true},
+		{"schema wildcard other schema", []string{"public.*"}, "billing.invoices", false},
+		{"bare name matches qualified table", []string{"orders"}, "public.orders", true},
+		{"bare name matches bare table", []string{"orders"}, "orders", true},
+		{"bare wildcard", []string{"audit_*"}, "public.audit_log", true},
+		{"qualified pattern does not match bare table", []string{"public.orders"}, "orders", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := &Token{Name: "test", Value: "secret", Tables: tt.tables}
+			if got := token.AllowsTable(tt.table); got != tt.want {
+				t.Errorf("AllowsTable(%q) = %v, want %v", tt.table, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchTable(t *testing.T) {
+	if MatchTable(nil, "public.users") {
+		t.Error("MatchTable() with no patterns should match nothing")
+	}
+	if !MatchTable([]string{"public.orders", "users"}, "public.users") {
+		t.Error("MatchTable() should match a bare pattern against a qualified table")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	prod := &Token{Name: "prod", Value: "abc123"}
+	dev := &Token{Name: "dev", Value: "def456", Tables: []string{"public.orders"}}
+	a := NewAuthenticator([]*Token{prod, dev})
+
+	got, err := a.Authenticate("def456")
+	if err != nil {
+		t.Fatalf("Authenticate() unexpected error: %v", err)
+	}
+	if got != dev {
+		t.Errorf("Authenticate() = %v, want %v", got.Name, dev.Name)
+	}
+
+	for _, value := range []string{"", "wrong", "abc12"} {
+		if _, err := a.Authenticate(value); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Authenticate(%q) error = %v, want ErrInvalidToken", value, err)
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"Bearer abc123", "abc123"},
+		{"bearer abc123", "abc123"},
+		{"abc123", "abc123"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := BearerToken(tt.header); got != tt.want {
+			t.Errorf("BearerToken(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
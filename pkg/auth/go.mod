@@ -0,0 +1,3 @@
+module kasho/pkg/auth
+
+go 1.24.3
@@ -0,0 +1,279 @@
+package dialect
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"kasho/proto"
+)
+
+// Versioned is implemented by dialects whose tables keep row versions instead of
+// updating rows in place, such as ClickHouse's ReplacingMergeTree. Updates are written
+// as inserts of the row's new version, and deletes as inserts of a tombstone row with
+// the deleted flag set.
+type Versioned interface {
+	// VersionColumns returns the names of the version column and the deleted flag column
+	VersionColumns() (version, deleted string)
+
+	// NextVersion returns a version greater than every version returned before
+	NextVersion() uint64
+}
+
+// ClickHouse implements the Dialect interface for ClickHouse analytics replicas. Tables
+// are expected to use ReplacingMergeTree(_version, _is_deleted) ordered by the primary
+// key, so the latest version of each row wins and deleted rows are dropped on merges
+// (or hidden with FINAL).
+type ClickHouse struct {
+	mu          sync.Mutex
+	lastVersion uint64
+	now         func() time.Time
+}
+
+// NewClickHouse creates a new ClickHouse dialect
+func NewClickHouse() *ClickHouse {
+	return &ClickHouse{now: time.Now}
+}
+
+func (c *ClickHouse) Name() string {
+	return "clickhouse"
+}
+
+func (c *ClickHouse) VersionColumns() (string, string) {
+	return "_version", "_is_deleted"
+}
+
+func (c *ClickHouse) NextVersion() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Nanoseconds keep versions increasing across restarts; the counter keeps them
+	// increasing within a nanosecond or when the clock steps back
+	version := max(uint64(c.now().UnixNano()), c.lastVersion+1)
+	c.lastVersion = version
+	return version
+}
+
+func (c *ClickHouse) FormatValue(v *proto.ColumnValue) (string, error) {
+	if v == nil || v.Value == nil {
+		return c.FormatNull(), nil
+	}
+
+	switch val := v.Value.(type) {
+	case *proto.ColumnValue_StringValue:
+		return c.FormatString(val.StringValue), nil
+	case *proto.ColumnValue_IntValue:
+		return c.FormatInt(val.IntValue), nil
+	case *proto.ColumnValue_FloatValue:
+		return c.FormatFloat(val.FloatValue), nil
+	case *proto.ColumnValue_BoolValue:
+		return c.FormatBool(val.BoolValue), nil
+	case *proto.ColumnValue_BytesValue:
+		return c.FormatBytes(val.BytesValue), nil
+	case *proto.ColumnValue_JsonValue:
+		return c.FormatJSON(val.JsonValue), nil
+	case *proto.ColumnValue_UuidValue:
+		return c.FormatUUID(val.UuidValue), nil
+	case *proto.ColumnValue_GeometryValue:
+		return c.FormatGeometry(val.GeometryValue.GetWkt(), val.GeometryValue.GetSrid()), nil
+	case *proto.ColumnValue_TimestampValue:
+		text, err := c.timestampText(val.TimestampValue)
+		if err != nil {
+			return "", err
+		}
+		return c.FormatString(text), nil
+	default:
+		return "", fmt.Errorf("unsupported value type: %T", v.Value)
+	}
+}
+
+// timestampText converts a date (YYYY-MM-DD) or RFC 3339 timestamp to the text form
+// Date and DateTime64 columns parse. Timestamps are written in UTC, so DateTime64
+// columns should be declared with the UTC time zone.
+func (c *ClickHouse) timestampText(value string) (string, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t.Format("2006-01-02"), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC().Format("2006-01-02 15:04:05.999999"), nil
+	}
+	return "", fmt.Errorf("invalid timestamp format: %s", value)
+}
+
+func (c *ClickHouse) Placeholder(n int) string {
+	return "?"
+}
+
+func (c *ClickHouse) BindValue(v *proto.ColumnValue, placeholder string) (string, any, error) {
+	if v == nil || v.Value == nil {
+		return placeholder, nil, nil
+	}
+
+	switch val := v.Value.(type) {
+	case *proto.ColumnValue_StringValue:
+		return placeholder, val.StringValue, nil
+	case *proto.ColumnValue_IntValue:
+		return placeholder, val.IntValue, nil
+	case *proto.ColumnValue_FloatValue:
+		return placeholder, val.FloatValue, nil
+	case *proto.ColumnValue_BoolValue:
+		return placeholder, val.BoolValue, nil
+	case *proto.ColumnValue_BytesValue:
+		return placeholder, val.BytesValue, nil
+	case *proto.ColumnValue_JsonValue:
+		return placeholder, val.JsonValue, nil
+	case *proto.ColumnValue_UuidValue:
+		return placeholder, val.UuidValue, nil
+	case *proto.ColumnValue_GeometryValue:
+		return placeholder, val.GeometryValue.GetWkt(), nil
+	case *proto.ColumnValue_TimestampValue:
+		text, err := c.timestampText(val.TimestampValue)
+		if err != nil {
+			return "", nil, err
+		}
+		return placeholder, text, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported value type: %T", v.Value)
+	}
+}
+
+func (c *ClickHouse) QuoteIdentifier(name string) string {
+	return fmt.Sprintf("`%s`", strings.ReplaceAll(name, "`", "``"))
+}
+
+func (c *ClickHouse) SetupConnection(db *sql.DB) error {
+	return nil
+}
+
+func (c *ClickHouse) SyncSequences(ctx context.Context, db *sql.DB, tables []string) error {
+	// ClickHouse has no sequences or auto-increment columns
+	return nil
+}
+
+func (c *ClickHouse) GeneratedColumns(ctx context.Context, db *sql.DB) (map[string][]string, error) {
+	// MATERIALIZED and ALIAS columns are computed by ClickHouse and can't be inserted.
+	// Tables are keyed both bare (MySQL sources) and qualified with the database.
+	query := `
+		SELECT table, name
+		FROM system.columns
+		WHERE database = currentDatabase() AND default_kind IN ('MATERIALIZED', 'ALIAS')
+		UNION ALL
+		SELECT database || '.' || table, name
+		FROM system.columns
+		WHERE database NOT IN ('system', 'INFORMATION_SCHEMA', 'information_schema')
+		AND default_kind IN ('MATERIALIZED', 'ALIAS')`
+
+	return queryGeneratedColumns(ctx, db, query)
+}
+
+func (c *ClickHouse) ColumnConstraints(ctx context.Context, db *sql.DB) (map[string]map[string]ColumnConstraint, error) {
+	// NULLs inserted into non-Nullable columns become the column default and strings
+	// are unbounded, so there is nothing to validate against
+	return map[string]map[string]ColumnConstraint{}, nil
+}
+
+func (c *ClickHouse) Collations(ctx context.Context, db *sql.DB) ([]string, error) {
+	return queryCollations(ctx, db, `SELECT name FROM system.collations`)
+}
+
+func (c *ClickHouse) SessionTimeZoneDSN(dsn, timeZone string) string {
+	// Timestamps are written as UTC text; the column's time zone decides how it's read
+	return dsn
+}
+
+func (c *ClickHouse) UpsertClause(keyColumns, updateColumns []string) string {
+	// Every write is an insert of a new row version, so replays are harmless
+	return ""
+}
+
+func (c *ClickHouse) GetUserTablesQuery() string {
+	return `SELECT count() FROM system.tables
+		WHERE database = currentDatabase() AND NOT is_temporary`
+}
+
+func (c *ClickHouse) GetDriverName() string {
+	return "clickhouse"
+}
+
+func (c *ClickHouse) FormatDSN(connStr string) string {
+	// clickhouse-go supports URL format natively, including settings such as
+	// async_insert as query parameters
+	return connStr
+}
+
+// Native type formatting methods
+
+func (c *ClickHouse) FormatString(s string) string {
+	// ClickHouse string literals use backslash escapes
+	return clickhouseQuoting.format(s)
+}
+
+func (c *ClickHouse) FormatInt(i int64) string {
+	return fmt.Sprintf("%d", i)
+}
+
+func (c *ClickHouse) FormatFloat(f float64) string {
+	return fmt.Sprintf("%f", f)
+}
+
+func (c *ClickHouse) FormatBool(b bool) string {
+	// Accepted by both Bool and UInt8 columns
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+func (c *ClickHouse) FormatTimestamp(t time.Time) string {
+	return fmt.Sprintf("'%s'", t.UTC().Format("2006-01-02 15:04:05"))
+}
+
+func (c *ClickHouse) FormatDate(t time.Time) string {
+	return fmt.Sprintf("'%s'", t.Format("2006-01-02"))
+}
+
+func (c *ClickHouse) FormatBytes(b []byte) string {
+	return fmt.Sprintf("unhex('%s')", hex.EncodeToString(b))
+}
+
+func (c *ClickHouse) FormatJSON(s string) string {
+	return c.FormatString(s)
+}
+
+func (c *ClickHouse) FormatUUID(s string) string {
+	return c.FormatString(s)
+}
+
+func (c *ClickHouse) FormatGeometry(wkt string, srid uint32) string {
+	// Spatial values are kept as well-known text
+	return c.FormatString(wkt)
+}
+
+func (c *ClickHouse) FormatNull() string {
+	return "NULL"
+}
+
+// DDL type methods
+
+func (c *ClickHouse) TypeUUID() string {
+	return "UUID"
+}
+
+func (c *ClickHouse) TypeText() string {
+	return "String"
+}
+
+func (c *ClickHouse) TypeTimestamp() string {
+	return "DateTime64(6, 'UTC')"
+}
+
+func (c *ClickHouse) TypeDecimal(precision, scale int) string {
+	return fmt.Sprintf("Decimal(%d, %d)", precision, scale)
+}
+
+func (c *ClickHouse) TypeInteger() string {
+	return "Int32"
+}
@@ -0,0 +1,143 @@
+package dialect
+
+import (
+	"testing"
+	"time"
+
+	"kasho/proto"
+)
+
+func TestClickHouse_Name(t *testing.T) {
+	d := NewClickHouse()
+	if got := d.Name(); got != "clickhouse" {
+		t.Errorf("Name() = %v, want clickhouse", got)
+	}
+	if got := d.GetDriverName(); got != "clickhouse" {
+		t.Errorf("GetDriverName() = %v, want clickhouse", got)
+	}
+}
+
+func TestClickHouse_QuoteIdentifier(t *testing.T) {
+	d := NewClickHouse()
+	if got := d.QuoteIdentifier("my`table"); got != "`my``table`" {
+		t.Errorf("QuoteIdentifier() = %v, want %v", got, "`my``table`")
+	}
+}
+
+func TestClickHouse_FormatValue(t *testing.T) {
+	d := NewClickHouse()
+
+	tests := []struct {
+		name    string
+		value   *proto.ColumnValue
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "nil value",
+			value: nil,
+			want:  "NULL",
+		},
+		{
+			name:  "string with single quote",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: "it's"}},
+			want:  `'it\'s'`,
+		},
+		{
+			name:  "string with backslash",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: `C:\path`}},
+			want:  `'C:\\path'`,
+		},
+		{
+			name:  "decimal as string",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: "12345.6789"}},
+			want:  "'12345.6789'",
+		},
+		{
+			name:  "bool value",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_BoolValue{BoolValue: false}},
+			want:  "0",
+		},
+		{
+			name:  "bytes value",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: []byte{0xde, 0xad}}},
+			want:  "unhex('dead')",
+		},
+		{
+			name:  "uuid value",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_UuidValue{UuidValue: "123e4567-e89b-12d3-a456-426614174000"}},
+			want:  "'123e4567-e89b-12d3-a456-426614174000'",
+		},
+		{
+			name:  "date value",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2024-03-20"}},
+			want:  "'2024-03-20'",
+		},
+		{
+			name:  "timestamp value in UTC",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2024-03-20T17:04:05.123456+02:00"}},
+			want:  "'2024-03-20 15:04:05.123456'",
+		},
+		{
+			name:    "invalid timestamp",
+			value:   &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "yesterday"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.FormatValue(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FormatValue() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("FormatValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClickHouse_BindValue(t *testing.T) {
+	d := NewClickHouse()
+	expr, arg, err := d.BindValue(&proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2024-03-20T15:04:05Z"}}, d.Placeholder(1))
+	if err != nil {
+		t.Fatalf("BindValue() error = %v", err)
+	}
+	if expr != "?" || arg != "2024-03-20 15:04:05" {
+		t.Errorf("BindValue() = %v, %#v, want ?, \"2024-03-20 15:04:05\"", expr, arg)
+	}
+}
+
+func TestClickHouse_NextVersion(t *testing.T) {
+	d := NewClickHouse()
+	now := time.Unix(100, 0)
+	d.now = func() time.Time { return now }
+
+	first := d.NextVersion()
+	if first != uint64(now.UnixNano()) {
+		t.Errorf("NextVersion() = %d, want %d", first, now.UnixNano())
+	}
+	// Versions keep increasing when the clock stands still or steps back
+	if second := d.NextVersion(); second != first+1 {
+		t.Errorf("NextVersion() with the same time = %d, want %d", second, first+1)
+	}
+	now = time.Unix(50, 0)
+	if third := d.NextVersion(); third != first+2 {
+		t.Errorf("NextVersion() after the clock stepped back = %d, want %d", third, first+2)
+	}
+}
+
+func TestClickHouse_Types(t *testing.T) {
+	d := NewClickHouse()
+	if got := d.TypeTimestamp(); got != "DateTime64(6, 'UTC')" {
+		t.Errorf("TypeTimestamp() = %v", got)
+	}
+	if got := d.TypeDecimal(18, 4); got != "Decimal(18, 4)" {
+		t.Errorf("TypeDecimal() = %v", got)
+	}
+	if got := d.UpsertClause([]string{"id"}, []string{"name"}); got != "" {
+		t.Errorf("UpsertClause() = %v, want empty", got)
+	}
+}
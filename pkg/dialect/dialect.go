@@ -3,6 +3,8 @@ package dialect
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"slices"
 	"time"
 
 	"kasho/proto"
@@ -16,6 +18,14 @@ type Dialect interface {
 	// FormatValue formats a protobuf ColumnValue for SQL insertion
 	FormatValue(val *proto.ColumnValue) (string, error)
 
+	// Placeholder returns the bind parameter for the n-th (1-based) argument of a statement
+	// PostgreSQL: $n, MySQL: ?
+	Placeholder(n int) string
+
+	// BindValue returns the SQL expression for a value passed as the given bind parameter,
+	// and the argument to bind, for use in prepared statements
+	BindValue(val *proto.ColumnValue, placeholder string) (string, any, error)
+
 	// QuoteIdentifier quotes a table or column name
 	QuoteIdentifier(name string) string
 
@@ -24,8 +34,25 @@ type Dialect interface {
 	// e.g., MySQL: SET FOREIGN_KEY_CHECKS = 0
 	SetupConnection(db *sql.DB) error
 
-	// SyncSequences synchronizes auto-increment/sequence values
-	SyncSequences(ctx context.Context, db *sql.DB) error
+	// SyncSequences synchronizes auto-increment/sequence values of the given tables,
+	// named as they appear in changes, or of every table if tables is empty
+	SyncSequences(ctx context.Context, db *sql.DB, tables []string) error
+
+	// GeneratedColumns returns the generated columns of the replica's tables, keyed by
+	// table name as it appears in changes (PostgreSQL: "schema.table", MySQL: "table")
+	GeneratedColumns(ctx context.Context, db *sql.DB) (map[string][]string, error)
+
+	// ColumnConstraints returns the length limits and nullability of the replica's columns,
+	// keyed by table name as in GeneratedColumns and then by column name
+	ColumnConstraints(ctx context.Context, db *sql.DB) (map[string]map[string]ColumnConstraint, error)
+
+	// Collations returns the names of the collations the replica supports
+	Collations(ctx context.Context, db *sql.DB) ([]string, error)
+
+	// UpsertClause returns the clause appended to an INSERT to make it idempotent.
+	// keyColumns identify the conflicting row; updateColumns are overwritten with the new values.
+	// PostgreSQL: ON CONFLICT ... DO UPDATE, MySQL: ON DUPLICATE KEY UPDATE
+	UpsertClause(keyColumns, updateColumns []string) string
 
 	// GetUserTablesQuery returns SQL to count user tables
 	GetUserTablesQuery() string
@@ -36,6 +63,11 @@ type Dialect interface {
 	// FormatDSN converts a URL-style connection string to the driver's native DSN format
 	FormatDSN(connStr string) string
 
+	// SessionTimeZoneDSN adds a session time zone (e.g. "UTC") to a DSN returned by
+	// FormatDSN, along with any other session settings of the dialect, so that every
+	// pooled connection uses them
+	SessionTimeZoneDSN(dsn, timeZone string) string
+
 	// Native type formatting methods (proto-free)
 	// These can be used by tools that don't need protobuf dependencies
 
@@ -58,6 +90,20 @@ type Dialect interface {
 	// FormatDate formats a time.Time as a date-only value for SQL
 	FormatDate(t time.Time) string
 
+	// FormatBytes formats a binary value for SQL
+	// PostgreSQL: decode('..', 'hex'), MySQL: X'..'
+	FormatBytes(b []byte) string
+
+	// FormatJSON formats a JSON document for SQL
+	// PostgreSQL: '..' (coerced to json/jsonb), MySQL: CAST('..' AS JSON)
+	FormatJSON(s string) string
+
+	// FormatUUID formats a UUID in its canonical 36-character form for SQL
+	FormatUUID(s string) string
+
+	// FormatGeometry formats a spatial value given as well-known text; srid 0 means unspecified
+	FormatGeometry(wkt string, srid uint32) string
+
 	// FormatNull returns the NULL literal for SQL
 	FormatNull() string
 
@@ -80,3 +126,95 @@ type Dialect interface {
 	// TypeInteger returns the column type for integers
 	TypeInteger() string
 }
+
+// ColumnConstraint describes the values a replica column accepts
+type ColumnConstraint struct {
+	// MaxLength is the maximum length of character and binary columns; 0 means unlimited
+	MaxLength int
+	// Nullable is false for NOT NULL columns
+	Nullable bool
+}
+
+// queryColumnConstraints runs a query returning (table, column, max length, nullable) rows
+func queryColumnConstraints(ctx context.Context, db *sql.DB, query string) (map[string]map[string]ColumnConstraint, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query column constraints: %w", err)
+	}
+	defer rows.Close()
+
+	constraints := make(map[string]map[string]ColumnConstraint)
+	for rows.Next() {
+		var table, column string
+		var constraint ColumnConstraint
+		if err := rows.Scan(&table, &column, &constraint.MaxLength, &constraint.Nullable); err != nil {
+			return nil, fmt.Errorf("failed to scan column constraint: %w", err)
+		}
+		if constraints[table] == nil {
+			constraints[table] = make(map[string]ColumnConstraint)
+		}
+		constraints[table][column] = constraint
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return constraints, nil
+}
+
+// queryGeneratedColumns runs a query returning (table, column) rows and groups the columns by table
+func queryGeneratedColumns(ctx context.Context, db *sql.DB, query string) (map[string][]string, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query generated columns: %w", err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string][]string)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, fmt.Errorf("failed to scan generated column: %w", err)
+		}
+		columns[table] = append(columns[table], column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+// queryCollations runs a query returning the names of collations
+func queryCollations(ctx context.Context, db *sql.DB, query string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query collations: %w", err)
+	}
+	defer rows.Close()
+
+	var collations []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan collation: %w", err)
+		}
+		collations = append(collations, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return collations, nil
+}
+
+// includesTable reports whether a table, given by any of its names, is in tables.
+// An empty list includes every table.
+func includesTable(tables []string, names ...string) bool {
+	if len(tables) == 0 {
+		return true
+	}
+	for _, name := range names {
+		if slices.Contains(tables, name) {
+			return true
+		}
+	}
+	return false
+}
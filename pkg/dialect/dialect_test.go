@@ -0,0 +1,25 @@
+package dialect
+
+import "testing"
+
+func TestIncludesTable(t *testing.T) {
+	tests := []struct {
+		name   string
+		tables []string
+		names  []string
+		want   bool
+	}{
+		{name: "empty list includes everything", tables: nil, names: []string{"public.users"}, want: true},
+		{name: "listed", tables: []string{"public.users", "public.orders"}, names: []string{"public.orders"}, want: true},
+		{name: "not listed", tables: []string{"public.users"}, names: []string{"public.orders"}, want: false},
+		{name: "any name matches", tables: []string{"orders"}, names: []string{"orders", "shop.orders"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := includesTable(tt.tables, tt.names...); got != tt.want {
+				t.Errorf("includesTable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -17,6 +17,18 @@ func FromConnectionString(connStr string) (Dialect, error) {
 		return NewMySQL(), nil
 	}
 
+	if strings.HasPrefix(connStr, "clickhouse://") {
+		return NewClickHouse(), nil
+	}
+
+	if strings.HasPrefix(connStr, "oracle://") {
+		return NewOracle(), nil
+	}
+
+	if strings.HasPrefix(connStr, "sqlite://") || strings.HasPrefix(connStr, "sqlite3://") || strings.HasPrefix(connStr, "file:") {
+		return NewSQLite(), nil
+	}
+
 	// Default to PostgreSQL for backwards compatibility with connection strings
 	// that don't have a scheme prefix (common for lib/pq)
 	return NewPostgreSQL(), nil
@@ -29,6 +41,12 @@ func FromName(name string) (Dialect, error) {
 		return NewPostgreSQL(), nil
 	case "mysql", "mariadb":
 		return NewMySQL(), nil
+	case "sqlite", "sqlite3":
+		return NewSQLite(), nil
+	case "clickhouse":
+		return NewClickHouse(), nil
+	case "oracle":
+		return NewOracle(), nil
 	default:
 		return nil, fmt.Errorf("unknown dialect: %s", name)
 	}
@@ -0,0 +1,115 @@
+package dialect
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"strings"
+
+	"kasho/proto"
+)
+
+// ValueWriter is implemented by dialects that can write a formatted value straight into a
+// statement buffer. Long text and binary values are escaped into the buffer in one pass
+// instead of being copied into the string FormatValue returns first.
+type ValueWriter interface {
+	// WriteValue writes the SQL literal of a value to buf, as FormatValue formats it
+	WriteValue(buf *bytes.Buffer, val *proto.ColumnValue) error
+}
+
+// WriteValue writes the SQL literal of a value to buf, using the dialect's WriteValue if
+// it has one
+func WriteValue(d Dialect, buf *bytes.Buffer, val *proto.ColumnValue) error {
+	if w, ok := d.(ValueWriter); ok {
+		return w.WriteValue(buf, val)
+	}
+	formatted, err := d.FormatValue(val)
+	if err != nil {
+		return err
+	}
+	buf.WriteString(formatted)
+	return nil
+}
+
+// literalWriter is a strings.Builder or a bytes.Buffer
+type literalWriter interface {
+	io.StringWriter
+	io.ByteWriter
+	Grow(n int)
+}
+
+// quoting describes how a dialect escapes single-quoted string literals
+type quoting struct {
+	// quote replaces a single quote inside the literal
+	quote string
+	// backslash is set when backslashes are escape characters and must be doubled
+	backslash bool
+}
+
+var (
+	// standardQuoting doubles single quotes, as in PostgreSQL, SQLite and Oracle
+	standardQuoting = quoting{quote: "''"}
+	// mysqlQuoting doubles single quotes and backslashes
+	mysqlQuoting = quoting{quote: "''", backslash: true}
+	// clickhouseQuoting escapes single quotes and backslashes with a backslash
+	clickhouseQuoting = quoting{quote: `\'`, backslash: true}
+)
+
+// format returns s as a string literal, allocated once at its final length
+func (q quoting) format(s string) string {
+	var b strings.Builder
+	q.write(&b, s)
+	return b.String()
+}
+
+// write writes s to w as a string literal, copying the runs between escaped characters
+func (q quoting) write(w literalWriter, s string) {
+	w.Grow(q.length(s))
+	w.WriteByte('\'')
+	for {
+		i := q.next(s)
+		if i < 0 {
+			break
+		}
+		w.WriteString(s[:i])
+		if s[i] == '\'' {
+			w.WriteString(q.quote)
+		} else {
+			w.WriteString(`\\`)
+		}
+		s = s[i+1:]
+	}
+	w.WriteString(s)
+	w.WriteByte('\'')
+}
+
+// next returns the index of the next character of s to escape, or -1
+func (q quoting) next(s string) int {
+	if !q.backslash {
+		return strings.IndexByte(s, '\'')
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\'' || s[i] == '\\' {
+			return i
+		}
+	}
+	return -1
+}
+
+// length returns the length of s as a string literal
+func (q quoting) length(s string) int {
+	n := len(s) + 2 + strings.Count(s, "'")*(len(q.quote)-1)
+	if q.backslash {
+		n += strings.Count(s, `\`)
+	}
+	return n
+}
+
+// writeHex writes a binary value hex encoded between prefix and suffix, encoding into the
+// buffer's free space instead of an intermediate string
+func writeHex(buf *bytes.Buffer, prefix string, b []byte, suffix string) {
+	buf.Grow(len(prefix) + hex.EncodedLen(len(b)) + len(suffix))
+	buf.WriteString(prefix)
+	buf.Write(hex.AppendEncode(buf.AvailableBuffer(), b))
+	buf.WriteString(suffix)
+}
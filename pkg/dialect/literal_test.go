@@ -0,0 +1,109 @@
+package dialect
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"kasho/proto"
+)
+
+func TestQuoting_Format(t *testing.T) {
+	tests := []struct {
+		name    string
+		quoting quoting
+		input   string
+		want    string
+	}{
+		{"standard empty", standardQuoting, "", "''"},
+		{"standard plain", standardQuoting, "hello", "'hello'"},
+		{"standard quotes", standardQuoting, "'it's'", "'''it''s'''"},
+		{"standard keeps backslashes", standardQuoting, `C:\path`, `'C:\path'`},
+		{"mysql quotes and backslashes", mysqlQuoting, `it's C:\path\`, `'it''s C:\\path\\'`},
+		{"mysql backslash before quote", mysqlQuoting, `\'`, `'\\'''`},
+		{"clickhouse quotes and backslashes", clickhouseQuoting, `it's C:\path`, `'it\'s C:\\path'`},
+		{"clickhouse backslash before quote", clickhouseQuoting, `\'`, `'\\\''`},
+		{"multibyte", standardQuoting, "héllo 'wörld' 日本", "'héllo ''wörld'' 日本'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.quoting.format(tt.input)
+			if got != tt.want {
+				t.Errorf("format(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+			if len(got) != tt.quoting.length(tt.input) {
+				t.Errorf("length(%q) = %d, want %d", tt.input, tt.quoting.length(tt.input), len(got))
+			}
+
+			var buf bytes.Buffer
+			buf.WriteString("x = ")
+			tt.quoting.write(&buf, tt.input)
+			if buf.String() != "x = "+tt.want {
+				t.Errorf("write(%q) = %s, want %s", tt.input, buf.String(), "x = "+tt.want)
+			}
+		})
+	}
+}
+
+func TestQuoting_FormatLongValue(t *testing.T) {
+	// A multi-megabyte value is escaped the same as short ones, without reallocating
+	value := strings.Repeat(`a'b\c`, 1<<18)
+	got := mysqlQuoting.format(value)
+	want := "'" + strings.ReplaceAll(strings.ReplaceAll(value, "'", "''"), `\`, `\\`) + "'"
+	if got != want {
+		t.Errorf("format() of a %d byte value differs from the escaped value", len(value))
+	}
+
+	// The literal is allocated once, besides the builder
+	allocs := testing.AllocsPerRun(10, func() {
+		standardQuoting.format(value)
+	})
+	if allocs > 2 {
+		t.Errorf("format() allocated %v times, want 2", allocs)
+	}
+}
+
+func TestWriteHex(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString("(")
+	writeHex(&buf, "X'", []byte{0x00, 0xff, 0x10}, "'")
+	writeHex(&buf, "X'", nil, "'")
+	if got, want := buf.String(), "(X'00ff10'X''"; got != want {
+		t.Errorf("writeHex() = %s, want %s", got, want)
+	}
+}
+
+func TestWriteValue(t *testing.T) {
+	values := []*proto.ColumnValue{
+		nil,
+		{Value: &proto.ColumnValue_StringValue{StringValue: `it's C:\path`}},
+		{Value: &proto.ColumnValue_IntValue{IntValue: 42}},
+		{Value: &proto.ColumnValue_BytesValue{BytesValue: []byte{0xde, 0xad}}},
+		{Value: &proto.ColumnValue_JsonValue{JsonValue: `{"a": "it's"}`}},
+		{Value: &proto.ColumnValue_UuidValue{UuidValue: "550e8400-e29b-41d4-a716-446655440000"}},
+	}
+
+	// Dialects without a WriteValue of their own write what FormatValue returns
+	for _, d := range []Dialect{NewPostgreSQL(), NewMySQL(), NewSQLite(), NewClickHouse(), NewOracle()} {
+		for _, value := range values {
+			want, err := d.FormatValue(value)
+			if err != nil {
+				t.Fatalf("%s: FormatValue() error = %v", d.Name(), err)
+			}
+			var buf bytes.Buffer
+			if err := WriteValue(d, &buf, value); err != nil {
+				t.Fatalf("%s: WriteValue() error = %v", d.Name(), err)
+			}
+			if buf.String() != want {
+				t.Errorf("%s: WriteValue() = %s, want %s", d.Name(), buf.String(), want)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	err := WriteValue(NewSQLite(), &buf, &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "not a timestamp"}})
+	if err == nil {
+		t.Error("WriteValue() of an invalid timestamp should fail")
+	}
+}
@@ -1,11 +1,16 @@
 package dialect
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
 	"log"
+	"math"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,13 +18,55 @@ import (
 )
 
 // MySQL implements the Dialect interface for MySQL databases
-type MySQL struct{}
+type MySQL struct {
+	uuidAsBinary bool
+	// sessionVariables are set on every replica connection, quoted as needed
+	sessionVariables map[string]string
+}
 
 // NewMySQL creates a new MySQL dialect
 func NewMySQL() *MySQL {
 	return &MySQL{}
 }
 
+// SetUUIDAsBinary stores UUIDs in BINARY(16) columns instead of CHAR(36)
+func (m *MySQL) SetUUIDAsBinary(uuidAsBinary bool) {
+	m.uuidAsBinary = uuidAsBinary
+}
+
+// SetSQLMode sets the sql_mode of replica sessions, e.g. to leave out
+// STRICT_TRANS_TABLES so values that don't fit a column are truncated with a warning
+// instead of failing. An empty mode turns every mode off.
+func (m *MySQL) SetSQLMode(mode string) {
+	m.setSessionVariable("sql_mode", "'"+strings.ReplaceAll(mode, "'", "''")+"'")
+}
+
+// SetForeignKeyChecks sets whether replica sessions check foreign keys. Without it,
+// they are turned off on the connection set up by SetupConnection.
+func (m *MySQL) SetForeignKeyChecks(enabled bool) {
+	value := "0"
+	if enabled {
+		value = "1"
+	}
+	m.setSessionVariable("foreign_key_checks", value)
+}
+
+// SetStatementTimeout sets the lock_wait_timeout of replica sessions, in whole seconds,
+// so the server gives up on statements waiting for a metadata lock, e.g. DDL blocked by
+// a long transaction. MySQL can't limit how long DML or DDL runs otherwise;
+// max_execution_time only applies to SELECT and doesn't exist on MariaDB.
+func (m *MySQL) SetStatementTimeout(timeout time.Duration) {
+	seconds := max(int64(math.Ceil(timeout.Seconds())), 1)
+	m.setSessionVariable("lock_wait_timeout", strconv.FormatInt(seconds, 10))
+}
+
+func (m *MySQL) setSessionVariable(name, value string) {
+	if m.sessionVariables == nil {
+		m.sessionVariables = make(map[string]string)
+	}
+	m.sessionVariables[name] = value
+}
+
 func (m *MySQL) Name() string {
 	return "mysql"
 }
@@ -38,89 +85,372 @@ func (m *MySQL) FormatValue(v *proto.ColumnValue) (string, error) {
 		return m.FormatFloat(val.FloatValue), nil
 	case *proto.ColumnValue_BoolValue:
 		return m.FormatBool(val.BoolValue), nil
+	case *proto.ColumnValue_BytesValue:
+		return m.FormatBytes(val.BytesValue), nil
+	case *proto.ColumnValue_JsonValue:
+		return m.FormatJSON(val.JsonValue), nil
+	case *proto.ColumnValue_UuidValue:
+		return m.FormatUUID(val.UuidValue), nil
+	case *proto.ColumnValue_GeometryValue:
+		return m.FormatGeometry(val.GeometryValue.GetWkt(), val.GeometryValue.GetSrid()), nil
 	case *proto.ColumnValue_TimestampValue:
-		// Try to parse as date first (YYYY-MM-DD)
-		if t, err := time.Parse("2006-01-02", val.TimestampValue); err == nil {
-			return m.FormatDate(t), nil
-		}
-		// Try to parse as timestamp
-		if t, err := time.Parse(time.RFC3339, val.TimestampValue); err == nil {
-			return fmt.Sprintf("'%s'", t.Format("2006-01-02 15:04:05")), nil
+		text, err := m.timestampText(val.TimestampValue)
+		if err != nil {
+			return "", err
 		}
-		return "", fmt.Errorf("invalid timestamp format: %s", val.TimestampValue)
+		return fmt.Sprintf("'%s'", text), nil
 	default:
 		return "", fmt.Errorf("unsupported value type: %T", v.Value)
 	}
 }
 
+// WriteValue writes text, JSON and binary values straight into buf, and everything else
+// as FormatValue formats it
+func (m *MySQL) WriteValue(buf *bytes.Buffer, v *proto.ColumnValue) error {
+	switch val := v.GetValue().(type) {
+	case *proto.ColumnValue_StringValue:
+		mysqlQuoting.write(buf, val.StringValue)
+	case *proto.ColumnValue_JsonValue:
+		buf.WriteString("CAST(")
+		mysqlQuoting.write(buf, val.JsonValue)
+		buf.WriteString(" AS JSON)")
+	case *proto.ColumnValue_BytesValue:
+		writeHex(buf, "X'", val.BytesValue, "'")
+	default:
+		formatted, err := m.FormatValue(v)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(formatted)
+	}
+	return nil
+}
+
+// timestampText converts a date (YYYY-MM-DD) or RFC 3339 timestamp to its MySQL text form.
+// MySQL literals carry no offset, so timestamps are written in UTC.
+func (m *MySQL) timestampText(value string) (string, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t.Format("2006-01-02"), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC().Format("2006-01-02 15:04:05.999999"), nil
+	}
+	return "", fmt.Errorf("invalid timestamp format: %s", value)
+}
+
+func (m *MySQL) Placeholder(n int) string {
+	return "?"
+}
+
+func (m *MySQL) BindValue(v *proto.ColumnValue, placeholder string) (string, any, error) {
+	if v == nil || v.Value == nil {
+		return placeholder, nil, nil
+	}
+
+	switch val := v.Value.(type) {
+	case *proto.ColumnValue_StringValue:
+		return placeholder, val.StringValue, nil
+	case *proto.ColumnValue_IntValue:
+		return placeholder, val.IntValue, nil
+	case *proto.ColumnValue_FloatValue:
+		return placeholder, val.FloatValue, nil
+	case *proto.ColumnValue_BoolValue:
+		return placeholder, val.BoolValue, nil
+	case *proto.ColumnValue_BytesValue:
+		return placeholder, val.BytesValue, nil
+	case *proto.ColumnValue_JsonValue:
+		return fmt.Sprintf("CAST(%s AS JSON)", placeholder), val.JsonValue, nil
+	case *proto.ColumnValue_UuidValue:
+		if m.uuidAsBinary {
+			return fmt.Sprintf("UNHEX(REPLACE(%s, '-', ''))", placeholder), val.UuidValue, nil
+		}
+		return placeholder, val.UuidValue, nil
+	case *proto.ColumnValue_GeometryValue:
+		if srid := val.GeometryValue.GetSrid(); srid != 0 {
+			return fmt.Sprintf("ST_GeomFromText(%s, %d, 'axis-order=long-lat')", placeholder, srid), val.GeometryValue.GetWkt(), nil
+		}
+		return fmt.Sprintf("ST_GeomFromText(%s)", placeholder), val.GeometryValue.GetWkt(), nil
+	case *proto.ColumnValue_TimestampValue:
+		text, err := m.timestampText(val.TimestampValue)
+		if err != nil {
+			return "", nil, err
+		}
+		return placeholder, text, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported value type: %T", v.Value)
+	}
+}
+
 func (m *MySQL) QuoteIdentifier(name string) string {
 	return fmt.Sprintf("`%s`", strings.ReplaceAll(name, "`", "``"))
 }
 
 func (m *MySQL) SetupConnection(db *sql.DB) error {
+	// Foreign key checks set with SetForeignKeyChecks are in the DSN
+	if _, ok := m.sessionVariables["foreign_key_checks"]; ok {
+		return nil
+	}
 	// Disable foreign key checks to allow replication without order dependencies
 	_, err := db.Exec("SET FOREIGN_KEY_CHECKS = 0")
 	return err
 }
 
-func (m *MySQL) SyncSequences(ctx context.Context, db *sql.DB) error {
-	// MySQL uses AUTO_INCREMENT which is managed per-table
-	// Query to find tables with auto_increment columns and sync their values
+func (m *MySQL) SessionTimeZoneDSN(dsn, timeZone string) string {
+	// Named zones need the time zone tables loaded, so use an offset for UTC
+	if strings.EqualFold(timeZone, "UTC") {
+		timeZone = "+00:00"
+	}
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	// go-sql-driver sets unrecognized parameters as session variables; values must be quoted
+	dsn += separator + "time_zone=" + url.QueryEscape("'"+timeZone+"'")
+
+	// So are the session variables set with SetSQLMode, SetForeignKeyChecks and SetStatementTimeout
+	names := make([]string, 0, len(m.sessionVariables))
+	for name := range m.sessionVariables {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		dsn += "&" + name + "=" + url.QueryEscape(m.sessionVariables[name])
+	}
+	return dsn
+}
+
+func (m *MySQL) SyncSequences(ctx context.Context, db *sql.DB, tables []string) error {
+	counters, err := m.AutoIncrementCounters(ctx, db, tables)
+	if err != nil {
+		return err
+	}
+
+	updatedCount := 0
+	for _, counter := range counters {
+		if !counter.Drifted() {
+			continue
+		}
+		if err := m.FixAutoIncrement(ctx, db, counter); err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		updatedCount++
+	}
+
+	log.Printf("Updated %d auto_increment values", updatedCount)
+	return nil
+}
+
+// AutoIncrementCounter is the state of a table's AUTO_INCREMENT counter
+type AutoIncrementCounter struct {
+	Schema string
+	Table  string
+	Column string
+	// Next is the value the counter hands out next
+	Next uint64
+	// Max is the largest value in the column; 0 for an empty table or only negative values
+	Max uint64
+	// Limit is the largest value the column type can hold; 0 if unknown
+	Limit uint64
+	// PerGroup is set when the column only follows other columns in composite keys
+	// (MyISAM), so values are generated per key prefix and the counter isn't used
+	PerGroup bool
+}
+
+// Drifted reports whether the counter would hand out a value that's already in use
+func (c AutoIncrementCounter) Drifted() bool {
+	return !c.PerGroup && c.Max > 0 && c.Next <= c.Max
+}
+
+// Exhausted reports whether the column already holds its type's largest value, so no
+// counter value is left
+func (c AutoIncrementCounter) Exhausted() bool {
+	return c.Limit != 0 && c.Max >= c.Limit
+}
+
+// AutoIncrementCounters returns the AUTO_INCREMENT counters of the given tables, named
+// as they appear in changes, or of every table if tables is empty
+func (m *MySQL) AutoIncrementCounters(ctx context.Context, db *sql.DB, tables []string) ([]AutoIncrementCounter, error) {
+	// Use a single connection so the session setting below applies to the queries
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// MySQL 8 caches AUTO_INCREMENT in information_schema for up to a day; older
+	// servers don't have the setting and always report the live value
+	conn.ExecContext(ctx, "SET SESSION information_schema_stats_expiry = 0")
+
 	query := `
 		SELECT
 			t.TABLE_SCHEMA,
 			t.TABLE_NAME,
-			c.COLUMN_NAME
+			c.COLUMN_NAME,
+			c.COLUMN_TYPE,
+			t.AUTO_INCREMENT,
+			EXISTS (
+				SELECT 1 FROM information_schema.statistics s
+				WHERE s.TABLE_SCHEMA = t.TABLE_SCHEMA
+				AND s.TABLE_NAME = t.TABLE_NAME
+				AND s.COLUMN_NAME = c.COLUMN_NAME
+				AND s.SEQ_IN_INDEX = 1
+			)
 		FROM information_schema.tables t
 		JOIN information_schema.columns c
 			ON c.TABLE_SCHEMA = t.TABLE_SCHEMA
 			AND c.TABLE_NAME = t.TABLE_NAME
 		WHERE t.TABLE_SCHEMA NOT IN ('mysql', 'information_schema', 'performance_schema', 'sys')
 		AND t.TABLE_TYPE = 'BASE TABLE'
-		AND c.EXTRA LIKE '%auto_increment%'`
+		AND c.EXTRA LIKE '%auto_increment%'
+		ORDER BY t.TABLE_SCHEMA, t.TABLE_NAME`
 
-	rows, err := db.QueryContext(ctx, query)
+	rows, err := conn.QueryContext(ctx, query)
 	if err != nil {
-		return fmt.Errorf("failed to query auto_increment columns: %w", err)
+		return nil, fmt.Errorf("failed to query auto_increment columns: %w", err)
 	}
-	defer rows.Close()
 
-	updatedCount := 0
+	var counters []AutoIncrementCounter
 	for rows.Next() {
-		var schema, table, column string
-		if err := rows.Scan(&schema, &table, &column); err != nil {
-			return fmt.Errorf("failed to scan auto_increment info: %w", err)
+		var counter AutoIncrementCounter
+		var columnType string
+		var next sql.NullString
+		var leading bool
+		if err := rows.Scan(&counter.Schema, &counter.Table, &counter.Column, &columnType, &next, &leading); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan auto_increment info: %w", err)
 		}
-
-		// Get max value for the column
-		var maxVal sql.NullInt64
-		maxQuery := fmt.Sprintf("SELECT COALESCE(MAX(%s), 0) + 1 FROM %s.%s",
-			m.QuoteIdentifier(column), m.QuoteIdentifier(schema), m.QuoteIdentifier(table))
-		err := db.QueryRowContext(ctx, maxQuery).Scan(&maxVal)
-		if err != nil {
-			log.Printf("Warning: failed to get max value for %s.%s.%s: %v", schema, table, column, err)
+		// Changes name MySQL tables without their database
+		if !includesTable(tables, counter.Table, counter.Schema+"."+counter.Table) {
 			continue
 		}
+		counter.Next = parseUnsigned(next.String)
+		counter.Limit = integerTypeLimit(columnType)
+		counter.PerGroup = !leading
+		counters = append(counters, counter)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-		// Set the auto_increment value
-		alterQuery := fmt.Sprintf("ALTER TABLE %s.%s AUTO_INCREMENT = %d",
-			m.QuoteIdentifier(schema), m.QuoteIdentifier(table), maxVal.Int64)
-		_, err = db.ExecContext(ctx, alterQuery)
-		if err != nil {
-			log.Printf("Warning: failed to set auto_increment for %s.%s: %v", schema, table, err)
-			continue
+	for i := range counters {
+		counter := &counters[i]
+		var maxVal sql.NullString
+		maxQuery := fmt.Sprintf("SELECT MAX(%s) FROM %s.%s",
+			m.QuoteIdentifier(counter.Column), m.QuoteIdentifier(counter.Schema), m.QuoteIdentifier(counter.Table))
+		if err := conn.QueryRowContext(ctx, maxQuery).Scan(&maxVal); err != nil {
+			return nil, fmt.Errorf("failed to get max value for %s.%s.%s: %w", counter.Schema, counter.Table, counter.Column, err)
 		}
-		updatedCount++
+		counter.Max = parseUnsigned(maxVal.String)
 	}
+	return counters, nil
+}
 
-	if err := rows.Err(); err != nil {
-		return err
+// FixAutoIncrement moves a counter past the largest value in its column
+func (m *MySQL) FixAutoIncrement(ctx context.Context, db *sql.DB, counter AutoIncrementCounter) error {
+	if counter.PerGroup {
+		return fmt.Errorf("auto_increment of %s.%s is generated per key prefix and has no counter to fix", counter.Schema, counter.Table)
+	}
+	if counter.Exhausted() || counter.Max == math.MaxUint64 {
+		return fmt.Errorf("auto_increment of %s.%s is exhausted: %s holds its largest value %d", counter.Schema, counter.Table, counter.Column, counter.Max)
 	}
 
-	log.Printf("Updated %d auto_increment values", updatedCount)
+	alterQuery := fmt.Sprintf("ALTER TABLE %s.%s AUTO_INCREMENT = %d",
+		m.QuoteIdentifier(counter.Schema), m.QuoteIdentifier(counter.Table), counter.Max+1)
+	if _, err := db.ExecContext(ctx, alterQuery); err != nil {
+		return fmt.Errorf("failed to set auto_increment for %s.%s: %w", counter.Schema, counter.Table, err)
+	}
 	return nil
 }
 
+// parseUnsigned parses a counter or column value; NULL, negative and non-integer
+// values count as 0
+func parseUnsigned(value string) uint64 {
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// integerTypeLimit returns the largest value of an integer column type such as
+// "bigint(20) unsigned", or 0 for other types
+func integerTypeLimit(columnType string) uint64 {
+	fields := strings.Fields(strings.ToLower(columnType))
+	if len(fields) == 0 {
+		return 0
+	}
+	unsigned := slices.Contains(fields, "unsigned")
+	name, _, _ := strings.Cut(fields[0], "(")
+
+	var bits uint
+	switch name {
+	case "tinyint":
+		bits = 8
+	case "smallint":
+		bits = 16
+	case "mediumint":
+		bits = 24
+	case "int", "integer":
+		bits = 32
+	case "bigint":
+		bits = 64
+	default:
+		return 0
+	}
+	if unsigned {
+		return math.MaxUint64 >> (64 - bits)
+	}
+	return math.MaxUint64 >> (64 - bits + 1)
+}
+
+func (m *MySQL) GeneratedColumns(ctx context.Context, db *sql.DB) (map[string][]string, error) {
+	// EXTRA also says DEFAULT_GENERATED for expression defaults, so look at the expression instead
+	query := `
+		SELECT TABLE_NAME, COLUMN_NAME
+		FROM information_schema.columns
+		WHERE TABLE_SCHEMA = DATABASE()
+		AND GENERATION_EXPRESSION IS NOT NULL
+		AND GENERATION_EXPRESSION <> ''
+		ORDER BY TABLE_NAME, ORDINAL_POSITION`
+
+	return queryGeneratedColumns(ctx, db, query)
+}
+
+func (m *MySQL) ColumnConstraints(ctx context.Context, db *sql.DB) (map[string]map[string]ColumnConstraint, error) {
+	// CHARACTER_MAXIMUM_LENGTH is in characters for text types and in bytes for binary types
+	query := `
+		SELECT TABLE_NAME, COLUMN_NAME,
+			COALESCE(CHARACTER_MAXIMUM_LENGTH, 0), IS_NULLABLE = 'YES'
+		FROM information_schema.columns
+		WHERE TABLE_SCHEMA = DATABASE()`
+
+	return queryColumnConstraints(ctx, db, query)
+}
+
+func (m *MySQL) Collations(ctx context.Context, db *sql.DB) ([]string, error) {
+	return queryCollations(ctx, db, `SELECT COLLATION_NAME FROM information_schema.COLLATIONS`)
+}
+
+func (m *MySQL) UpsertClause(keyColumns, updateColumns []string) string {
+	// MySQL matches any primary or unique key, so the key columns only matter
+	// when there is nothing else to update and a no-op assignment is needed
+	columns := updateColumns
+	if len(columns) == 0 {
+		columns = keyColumns
+	}
+	if len(columns) == 0 {
+		return ""
+	}
+
+	setClauses := make([]string, len(columns))
+	for i, col := range columns {
+		setClauses[i] = fmt.Sprintf("%s = VALUES(%s)", col, col)
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(setClauses, ", ")
+}
+
 func (m *MySQL) GetUserTablesQuery() string {
 	return `SELECT COUNT(*) FROM information_schema.tables
 		WHERE table_schema NOT IN ('mysql', 'information_schema', 'performance_schema', 'sys')
@@ -176,9 +506,7 @@ func (m *MySQL) FormatDSN(connStr string) string {
 
 func (m *MySQL) FormatString(s string) string {
 	// MySQL requires escaping backslashes as well as single quotes
-	escaped := strings.ReplaceAll(s, "'", "''")
-	escaped = strings.ReplaceAll(escaped, "\\", "\\\\")
-	return fmt.Sprintf("'%s'", escaped)
+	return mysqlQuoting.format(s)
 }
 
 func (m *MySQL) FormatInt(i int64) string {
@@ -198,13 +526,37 @@ func (m *MySQL) FormatBool(b bool) string {
 }
 
 func (m *MySQL) FormatTimestamp(t time.Time) string {
-	return fmt.Sprintf("'%s'", t.Format("2006-01-02 15:04:05"))
+	return fmt.Sprintf("'%s'", t.UTC().Format("2006-01-02 15:04:05"))
 }
 
 func (m *MySQL) FormatDate(t time.Time) string {
 	return fmt.Sprintf("'%s'", t.Format("2006-01-02"))
 }
 
+func (m *MySQL) FormatBytes(b []byte) string {
+	return fmt.Sprintf("X'%s'", hex.EncodeToString(b))
+}
+
+func (m *MySQL) FormatJSON(s string) string {
+	return fmt.Sprintf("CAST(%s AS JSON)", m.FormatString(s))
+}
+
+func (m *MySQL) FormatUUID(s string) string {
+	if m.uuidAsBinary {
+		// Same byte order as UUID_TO_BIN without swapping, and works before MySQL 8
+		return fmt.Sprintf("UNHEX(REPLACE(%s, '-', ''))", m.FormatString(s))
+	}
+	return m.FormatString(s)
+}
+
+func (m *MySQL) FormatGeometry(wkt string, srid uint32) string {
+	if srid == 0 {
+		return fmt.Sprintf("ST_GeomFromText(%s)", m.FormatString(wkt))
+	}
+	// Coordinates are carried as x=longitude, y=latitude, which geographic SRSs would otherwise read swapped
+	return fmt.Sprintf("ST_GeomFromText(%s, %d, 'axis-order=long-lat')", m.FormatString(wkt), srid)
+}
+
 func (m *MySQL) FormatNull() string {
 	return "NULL"
 }
@@ -212,6 +564,9 @@ func (m *MySQL) FormatNull() string {
 // DDL type methods
 
 func (m *MySQL) TypeUUID() string {
+	if m.uuidAsBinary {
+		return "BINARY(16)"
+	}
 	return "CHAR(36)"
 }
 
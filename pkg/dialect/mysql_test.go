@@ -1,6 +1,9 @@
 package dialect
 
 import (
+	"bytes"
+	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -193,17 +196,42 @@ func TestMySQL_FormatValue(t *testing.T) {
 			value: &proto.ColumnValue{Value: &proto.ColumnValue_FloatValue{FloatValue: 0.0}},
 			want:  "0.000000",
 		},
+		{
+			name:  "bytes value",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: []byte{0x00, 0xff, 0x10}}},
+			want:  "X'00ff10'",
+		},
+		{
+			name:  "json value",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_JsonValue{JsonValue: `{"a": "it's"}`}},
+			want:  `CAST('{"a": "it''s"}' AS JSON)`,
+		},
+		{
+			name:  "uuid value",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_UuidValue{UuidValue: "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}},
+			want:  "'6ba7b810-9dad-11d1-80b4-00c04fd430c8'",
+		},
+		{
+			name:  "geometry value",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_GeometryValue{GeometryValue: &proto.Geometry{Wkt: "POINT(1 2)", Srid: 4326}}},
+			want:  "ST_GeomFromText('POINT(1 2)', 4326, 'axis-order=long-lat')",
+		},
 		// Edge cases for timestamps
-		// Note: timestamps with timezone are preserved as-is (not converted to UTC)
+		// Note: timestamps with a timezone are converted to UTC
 		{
 			name:  "timestamp with positive timezone",
 			value: &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2024-03-20T15:04:05+02:00"}},
-			want:  "'2024-03-20 15:04:05'",
+			want:  "'2024-03-20 13:04:05'",
 		},
 		{
 			name:  "timestamp with negative timezone",
 			value: &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2024-03-20T15:04:05-05:00"}},
-			want:  "'2024-03-20 15:04:05'",
+			want:  "'2024-03-20 20:04:05'",
+		},
+		{
+			name:  "timestamp with fractional seconds",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2024-03-20T15:04:05.123456Z"}},
+			want:  "'2024-03-20 15:04:05.123456'",
 		},
 	}
 
@@ -217,6 +245,55 @@ func TestMySQL_FormatValue(t *testing.T) {
 			if !tt.wantErr && got != tt.want {
 				t.Errorf("FormatValue() = %v, want %v", got, tt.want)
 			}
+
+			// WriteValue writes the same literal
+			var buf bytes.Buffer
+			if err := d.WriteValue(&buf, tt.value); (err != nil) != tt.wantErr {
+				t.Errorf("WriteValue() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && buf.String() != tt.want {
+				t.Errorf("WriteValue() = %v, want %v", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestMySQL_UpsertClause(t *testing.T) {
+	d := NewMySQL()
+	tests := []struct {
+		name          string
+		keyColumns    []string
+		updateColumns []string
+		want          string
+	}{
+		{
+			name:          "update columns",
+			keyColumns:    []string{"id"},
+			updateColumns: []string{"name", "email"},
+			want:          "ON DUPLICATE KEY UPDATE name = VALUES(name), email = VALUES(email)",
+		},
+		{
+			name:          "no key metadata",
+			updateColumns: []string{"id", "name"},
+			want:          "ON DUPLICATE KEY UPDATE id = VALUES(id), name = VALUES(name)",
+		},
+		{
+			name:       "key only uses no-op assignment",
+			keyColumns: []string{"id"},
+			want:       "ON DUPLICATE KEY UPDATE id = VALUES(id)",
+		},
+		{
+			name: "no columns",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.UpsertClause(tt.keyColumns, tt.updateColumns); got != tt.want {
+				t.Errorf("UpsertClause() = %q, want %q", got, tt.want)
+			}
 		})
 	}
 }
@@ -336,6 +413,65 @@ func TestMySQL_FormatDSN(t *testing.T) {
 
 // Tests for native type formatting methods
 
+func TestMySQL_SessionTimeZoneDSN(t *testing.T) {
+	d := NewMySQL()
+
+	tests := []struct {
+		name     string
+		dsn      string
+		timeZone string
+		want     string
+	}{
+		{
+			name:     "UTC uses an offset",
+			dsn:      "user:password@tcp(localhost:3306)/mydb",
+			timeZone: "UTC",
+			want:     "user:password@tcp(localhost:3306)/mydb?time_zone=%27%2B00%3A00%27",
+		},
+		{
+			name:     "existing parameters",
+			dsn:      "user:password@tcp(localhost:3306)/mydb?parseTime=true",
+			timeZone: "-05:00",
+			want:     "user:password@tcp(localhost:3306)/mydb?parseTime=true&time_zone=%27-05%3A00%27",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.SessionTimeZoneDSN(tt.dsn, tt.timeZone); got != tt.want {
+				t.Errorf("SessionTimeZoneDSN(%q, %q) = %q, want %q", tt.dsn, tt.timeZone, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMySQL_SessionVariablesDSN(t *testing.T) {
+	d := NewMySQL()
+	d.SetSQLMode("NO_ENGINE_SUBSTITUTION,ERROR_FOR_DIVISION_BY_ZERO")
+	d.SetForeignKeyChecks(true)
+
+	got := d.SessionTimeZoneDSN("user:password@tcp(localhost:3306)/mydb", "UTC")
+	want := "user:password@tcp(localhost:3306)/mydb?time_zone=%27%2B00%3A00%27&foreign_key_checks=1" +
+		"&sql_mode=%27NO_ENGINE_SUBSTITUTION%2CERROR_FOR_DIVISION_BY_ZERO%27"
+	if got != want {
+		t.Errorf("SessionTimeZoneDSN() = %q, want %q", got, want)
+	}
+
+	// Statement timeouts are rounded up to whole seconds
+	d = NewMySQL()
+	d.SetStatementTimeout(1500 * time.Millisecond)
+	if got := d.SessionTimeZoneDSN("user@tcp(localhost:3306)/mydb", "UTC"); !strings.HasSuffix(got, "&lock_wait_timeout=2") {
+		t.Errorf("SessionTimeZoneDSN() with a statement timeout = %q, want lock_wait_timeout=2", got)
+	}
+
+	// An empty mode is kept, turning every mode off
+	d = NewMySQL()
+	d.SetSQLMode("")
+	if got := d.SessionTimeZoneDSN("user@tcp(localhost:3306)/mydb", "UTC"); !strings.HasSuffix(got, "&sql_mode=%27%27") {
+		t.Errorf("SessionTimeZoneDSN() with an empty mode = %q, want sql_mode=''", got)
+	}
+}
+
 func TestMySQL_FormatString(t *testing.T) {
 	d := NewMySQL()
 
@@ -418,6 +554,106 @@ func TestMySQL_FormatDate(t *testing.T) {
 	}
 }
 
+func TestMySQL_FormatBytes(t *testing.T) {
+	d := NewMySQL()
+
+	tests := []struct {
+		name  string
+		input []byte
+		want  string
+	}{
+		{"binary data", []byte{0xde, 0xad, 0xbe, 0xef}, "X'deadbeef'"},
+		{"empty", []byte{}, "X''"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.FormatBytes(tt.input); got != tt.want {
+				t.Errorf("FormatBytes(%v) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMySQL_FormatUUID_AsBinary(t *testing.T) {
+	d := NewMySQL()
+	d.SetUUIDAsBinary(true)
+
+	got := d.FormatUUID("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	want := "UNHEX(REPLACE('6ba7b810-9dad-11d1-80b4-00c04fd430c8', '-', ''))"
+	if got != want {
+		t.Errorf("FormatUUID() = %v, want %v", got, want)
+	}
+	if got := d.TypeUUID(); got != "BINARY(16)" {
+		t.Errorf("TypeUUID() = %v, want BINARY(16)", got)
+	}
+}
+
+func TestMySQL_BindValue(t *testing.T) {
+	d := NewMySQL()
+	d.SetUUIDAsBinary(true)
+
+	tests := []struct {
+		name     string
+		value    *proto.ColumnValue
+		wantExpr string
+		wantArg  any
+	}{
+		{
+			name:     "nil value",
+			value:    nil,
+			wantExpr: "?",
+			wantArg:  nil,
+		},
+		{
+			name:     "string value",
+			value:    &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: `a\b`}},
+			wantExpr: "?",
+			wantArg:  `a\b`,
+		},
+		{
+			name:     "json value",
+			value:    &proto.ColumnValue{Value: &proto.ColumnValue_JsonValue{JsonValue: `{"a":1}`}},
+			wantExpr: "CAST(? AS JSON)",
+			wantArg:  `{"a":1}`,
+		},
+		{
+			name:     "binary uuid",
+			value:    &proto.ColumnValue{Value: &proto.ColumnValue_UuidValue{UuidValue: "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}},
+			wantExpr: "UNHEX(REPLACE(?, '-', ''))",
+			wantArg:  "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		},
+		{
+			name:     "timestamp value",
+			value:    &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2024-03-20T17:04:05.5+02:00"}},
+			wantExpr: "?",
+			wantArg:  "2024-03-20 15:04:05.5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, arg, err := d.BindValue(tt.value, d.Placeholder(1))
+			if err != nil {
+				t.Fatalf("BindValue() error = %v", err)
+			}
+			if expr != tt.wantExpr {
+				t.Errorf("BindValue() expr = %v, want %v", expr, tt.wantExpr)
+			}
+			if arg != tt.wantArg {
+				t.Errorf("BindValue() arg = %#v, want %#v", arg, tt.wantArg)
+			}
+		})
+	}
+}
+
+func TestMySQL_FormatGeometry(t *testing.T) {
+	d := NewMySQL()
+	if got := d.FormatGeometry("POINT(1 2)", 0); got != "ST_GeomFromText('POINT(1 2)')" {
+		t.Errorf("FormatGeometry() = %v, want ST_GeomFromText('POINT(1 2)')", got)
+	}
+}
+
 func TestMySQL_FormatNull(t *testing.T) {
 	d := NewMySQL()
 	if got := d.FormatNull(); got != "NULL" {
@@ -505,3 +741,92 @@ func TestMySQL_TypeTimestamp_DiffersFromPostgres(t *testing.T) {
 		t.Errorf("PostgreSQL TypeTimestamp() = %v, want TIMESTAMP WITH TIME ZONE", postgres.TypeTimestamp())
 	}
 }
+
+func TestIntegerTypeLimit(t *testing.T) {
+	tests := []struct {
+		columnType string
+		want       uint64
+	}{
+		{"tinyint(4)", 127},
+		{"tinyint unsigned", 255},
+		{"smallint(6)", 32767},
+		{"mediumint(8) unsigned", 16777215},
+		{"int(11)", 2147483647},
+		{"INT UNSIGNED", 4294967295},
+		{"bigint(20)", 9223372036854775807},
+		{"bigint(20) unsigned", 18446744073709551615},
+		{"bigint unsigned zerofill", 18446744073709551615},
+		{"double", 0},
+		{"", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.columnType, func(t *testing.T) {
+			if got := integerTypeLimit(tt.columnType); got != tt.want {
+				t.Errorf("integerTypeLimit(%q) = %d, want %d", tt.columnType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUnsigned(t *testing.T) {
+	tests := map[string]uint64{
+		"":                     0,
+		"42":                   42,
+		"-5":                   0,
+		"18446744073709551615": 18446744073709551615,
+	}
+	for value, want := range tests {
+		if got := parseUnsigned(value); got != want {
+			t.Errorf("parseUnsigned(%q) = %d, want %d", value, got, want)
+		}
+	}
+}
+
+func TestAutoIncrementCounter(t *testing.T) {
+	tests := []struct {
+		name          string
+		counter       AutoIncrementCounter
+		wantDrifted   bool
+		wantExhausted bool
+	}{
+		{name: "ahead of max", counter: AutoIncrementCounter{Next: 11, Max: 10}},
+		{name: "empty table", counter: AutoIncrementCounter{Next: 1}},
+		{name: "behind max", counter: AutoIncrementCounter{Next: 5, Max: 10}, wantDrifted: true},
+		{name: "equal to max", counter: AutoIncrementCounter{Next: 10, Max: 10}, wantDrifted: true},
+		{name: "per group", counter: AutoIncrementCounter{Next: 1, Max: 10, PerGroup: true}},
+		{
+			name:          "unsigned bigint at its limit",
+			counter:       AutoIncrementCounter{Next: 18446744073709551615, Max: 18446744073709551615, Limit: 18446744073709551615},
+			wantDrifted:   true,
+			wantExhausted: true,
+		},
+		{
+			name:    "unsigned bigint beyond int64",
+			counter: AutoIncrementCounter{Next: 9223372036854775809, Max: 9223372036854775808, Limit: 18446744073709551615},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.counter.Drifted(); got != tt.wantDrifted {
+				t.Errorf("Drifted() = %v, want %v", got, tt.wantDrifted)
+			}
+			if got := tt.counter.Exhausted(); got != tt.wantExhausted {
+				t.Errorf("Exhausted() = %v, want %v", got, tt.wantExhausted)
+			}
+		})
+	}
+}
+
+func TestMySQL_FixAutoIncrement_Refuses(t *testing.T) {
+	m := NewMySQL()
+	perGroup := AutoIncrementCounter{Schema: "shop", Table: "items", Column: "seq", Max: 3, PerGroup: true}
+	if err := m.FixAutoIncrement(context.Background(), nil, perGroup); err == nil {
+		t.Error("FixAutoIncrement() of a per-group counter should return an error")
+	}
+	exhausted := AutoIncrementCounter{Schema: "shop", Table: "items", Column: "id", Max: 127, Limit: 127}
+	if err := m.FixAutoIncrement(context.Background(), nil, exhausted); err == nil {
+		t.Error("FixAutoIncrement() of an exhausted counter should return an error")
+	}
+}
@@ -0,0 +1,143 @@
+package dialect
+
+import (
+	"testing"
+
+	"kasho/proto"
+)
+
+func TestOracle_Name(t *testing.T) {
+	d := NewOracle()
+	if got := d.Name(); got != "oracle" {
+		t.Errorf("Name() = %v, want oracle", got)
+	}
+	if got := d.GetDriverName(); got != "oracle" {
+		t.Errorf("GetDriverName() = %v, want oracle", got)
+	}
+}
+
+func TestOracle_FormatValue(t *testing.T) {
+	d := NewOracle()
+
+	tests := []struct {
+		name    string
+		value   *proto.ColumnValue
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "nil value",
+			value: nil,
+			want:  "NULL",
+		},
+		{
+			name:  "string with single quote",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: "it's"}},
+			want:  "'it''s'",
+		},
+		{
+			name:  "bool value",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_BoolValue{BoolValue: true}},
+			want:  "1",
+		},
+		{
+			name:  "bytes value",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: []byte{0xde, 0xad}}},
+			want:  "HEXTORAW('dead')",
+		},
+		{
+			name:  "geometry value",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_GeometryValue{GeometryValue: &proto.Geometry{Wkt: "POINT(1 2)", Srid: 4326}}},
+			want:  "SDO_GEOMETRY('POINT(1 2)', 4326)",
+		},
+		{
+			name:  "date value",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2024-03-20"}},
+			want:  "DATE '2024-03-20'",
+		},
+		{
+			name:  "timestamp value",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2024-03-20T17:04:05.5+02:00"}},
+			want:  "TIMESTAMP '2024-03-20 15:04:05.500000 +00:00'",
+		},
+		{
+			name:    "invalid timestamp",
+			value:   &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "yesterday"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.FormatValue(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FormatValue() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("FormatValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOracle_BindValue(t *testing.T) {
+	d := NewOracle()
+	placeholder := d.Placeholder(3)
+	if placeholder != ":3" {
+		t.Errorf("Placeholder(3) = %v, want :3", placeholder)
+	}
+
+	expr, arg, err := d.BindValue(&proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2024-03-20T15:04:05Z"}}, placeholder)
+	if err != nil {
+		t.Fatalf("BindValue() error = %v", err)
+	}
+	if expr != "TO_TIMESTAMP_TZ(:3, 'YYYY-MM-DD HH24:MI:SS.FF TZH:TZM')" || arg != "2024-03-20 15:04:05.000000 +00:00" {
+		t.Errorf("BindValue() = %v, %#v", expr, arg)
+	}
+
+	expr, arg, err = d.BindValue(&proto.ColumnValue{Value: &proto.ColumnValue_BoolValue{BoolValue: false}}, placeholder)
+	if err != nil || expr != ":3" || arg != int64(0) {
+		t.Errorf("BindValue(false) = %v, %#v, %v, want :3, 0", expr, arg, err)
+	}
+}
+
+func TestOracle_MergeStatement(t *testing.T) {
+	d := NewOracle()
+
+	got := d.MergeStatement("HR.USERS", []string{"ID", "NAME"}, []string{"1", "'alice'"}, []string{"ID"}, []string{"NAME"})
+	want := "MERGE INTO HR.USERS dst USING (SELECT 1 AS ID, 'alice' AS NAME FROM DUAL) src ON (dst.ID = src.ID)" +
+		" WHEN MATCHED THEN UPDATE SET dst.NAME = src.NAME" +
+		" WHEN NOT MATCHED THEN INSERT (ID, NAME) VALUES (src.ID, src.NAME)"
+	if got != want {
+		t.Errorf("MergeStatement() = %v, want %v", got, want)
+	}
+
+	// Only key columns: existing rows are left as they are
+	got = d.MergeStatement("HR.TAGS", []string{"ID"}, []string{"1"}, []string{"ID"}, nil)
+	want = "MERGE INTO HR.TAGS dst USING (SELECT 1 AS ID FROM DUAL) src ON (dst.ID = src.ID)" +
+		" WHEN NOT MATCHED THEN INSERT (ID) VALUES (src.ID)"
+	if got != want {
+		t.Errorf("MergeStatement() without update columns = %v, want %v", got, want)
+	}
+
+	if got := d.MergeStatement("HR.LOG", []string{"MSG"}, []string{"'hi'"}, nil, []string{"MSG"}); got != "" {
+		t.Errorf("MergeStatement() without a key = %v, want empty", got)
+	}
+	if got := d.MergeStatement("HR.USERS", []string{"NAME"}, []string{"'bob'"}, []string{"ID"}, []string{"NAME"}); got != "" {
+		t.Errorf("MergeStatement() without the key column = %v, want empty", got)
+	}
+}
+
+func TestOracle_Types(t *testing.T) {
+	d := NewOracle()
+	if got := d.TypeDecimal(18, 4); got != "NUMBER(18,4)" {
+		t.Errorf("TypeDecimal() = %v", got)
+	}
+	if got := d.TypeUUID(); got != "VARCHAR2(36)" {
+		t.Errorf("TypeUUID() = %v", got)
+	}
+	if got := d.UpsertClause([]string{"id"}, []string{"name"}); got != "" {
+		t.Errorf("UpsertClause() = %v, want empty", got)
+	}
+}
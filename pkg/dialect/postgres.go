@@ -1,10 +1,14 @@
 package dialect
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
 	"log"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,13 +16,22 @@ import (
 )
 
 // PostgreSQL implements the Dialect interface for PostgreSQL databases
-type PostgreSQL struct{}
+type PostgreSQL struct {
+	// statementTimeout is the statement_timeout of replica sessions, if set
+	statementTimeout time.Duration
+}
 
 // NewPostgreSQL creates a new PostgreSQL dialect
 func NewPostgreSQL() *PostgreSQL {
 	return &PostgreSQL{}
 }
 
+// SetStatementTimeout sets the statement_timeout of replica sessions, so the server
+// cancels statements that run longer, even ones whose client has gone away
+func (p *PostgreSQL) SetStatementTimeout(timeout time.Duration) {
+	p.statementTimeout = timeout
+}
+
 func (p *PostgreSQL) Name() string {
 	return "postgresql"
 }
@@ -37,21 +50,98 @@ func (p *PostgreSQL) FormatValue(v *proto.ColumnValue) (string, error) {
 		return p.FormatFloat(val.FloatValue), nil
 	case *proto.ColumnValue_BoolValue:
 		return p.FormatBool(val.BoolValue), nil
+	case *proto.ColumnValue_BytesValue:
+		return p.FormatBytes(val.BytesValue), nil
+	case *proto.ColumnValue_JsonValue:
+		return p.FormatJSON(val.JsonValue), nil
+	case *proto.ColumnValue_UuidValue:
+		return p.FormatUUID(val.UuidValue), nil
+	case *proto.ColumnValue_GeometryValue:
+		return p.FormatGeometry(val.GeometryValue.GetWkt(), val.GeometryValue.GetSrid()), nil
 	case *proto.ColumnValue_TimestampValue:
-		// Try to parse as date first (YYYY-MM-DD)
-		if t, err := time.Parse("2006-01-02", val.TimestampValue); err == nil {
-			return p.FormatDate(t), nil
-		}
-		// Try to parse as timestamp
-		if t, err := time.Parse(time.RFC3339, val.TimestampValue); err == nil {
-			return fmt.Sprintf("'%s'", t.Format("2006-01-02 15:04:05")), nil
+		text, err := p.timestampText(val.TimestampValue)
+		if err != nil {
+			return "", err
 		}
-		return "", fmt.Errorf("invalid timestamp format: %s", val.TimestampValue)
+		return fmt.Sprintf("'%s'", text), nil
 	default:
 		return "", fmt.Errorf("unsupported value type: %T", v.Value)
 	}
 }
 
+// WriteValue writes text, JSON and binary values straight into buf, and everything else
+// as FormatValue formats it
+func (p *PostgreSQL) WriteValue(buf *bytes.Buffer, v *proto.ColumnValue) error {
+	switch val := v.GetValue().(type) {
+	case *proto.ColumnValue_StringValue:
+		standardQuoting.write(buf, val.StringValue)
+	case *proto.ColumnValue_JsonValue:
+		standardQuoting.write(buf, val.JsonValue)
+	case *proto.ColumnValue_BytesValue:
+		writeHex(buf, "decode('", val.BytesValue, "', 'hex')")
+	default:
+		formatted, err := p.FormatValue(v)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(formatted)
+	}
+	return nil
+}
+
+// timestampText converts a date (YYYY-MM-DD) or RFC 3339 timestamp to its PostgreSQL text form.
+// Timestamps are written in UTC with an explicit offset so timestamptz columns don't depend
+// on the session time zone.
+func (p *PostgreSQL) timestampText(value string) (string, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t.Format("2006-01-02"), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC().Format("2006-01-02 15:04:05.999999") + "+00", nil
+	}
+	return "", fmt.Errorf("invalid timestamp format: %s", value)
+}
+
+func (p *PostgreSQL) Placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (p *PostgreSQL) BindValue(v *proto.ColumnValue, placeholder string) (string, any, error) {
+	if v == nil || v.Value == nil {
+		return placeholder, nil, nil
+	}
+
+	switch val := v.Value.(type) {
+	case *proto.ColumnValue_StringValue:
+		return placeholder, val.StringValue, nil
+	case *proto.ColumnValue_IntValue:
+		return placeholder, val.IntValue, nil
+	case *proto.ColumnValue_FloatValue:
+		return placeholder, val.FloatValue, nil
+	case *proto.ColumnValue_BoolValue:
+		return placeholder, val.BoolValue, nil
+	case *proto.ColumnValue_BytesValue:
+		return placeholder, val.BytesValue, nil
+	case *proto.ColumnValue_JsonValue:
+		return placeholder, val.JsonValue, nil
+	case *proto.ColumnValue_UuidValue:
+		return placeholder, val.UuidValue, nil
+	case *proto.ColumnValue_GeometryValue:
+		if srid := val.GeometryValue.GetSrid(); srid != 0 {
+			return fmt.Sprintf("ST_GeomFromText(%s, %d)", placeholder, srid), val.GeometryValue.GetWkt(), nil
+		}
+		return fmt.Sprintf("ST_GeomFromText(%s)", placeholder), val.GeometryValue.GetWkt(), nil
+	case *proto.ColumnValue_TimestampValue:
+		text, err := p.timestampText(val.TimestampValue)
+		if err != nil {
+			return "", nil, err
+		}
+		return placeholder, text, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported value type: %T", v.Value)
+	}
+}
+
 func (p *PostgreSQL) QuoteIdentifier(name string) string {
 	return fmt.Sprintf("\"%s\"", strings.ReplaceAll(name, "\"", "\"\""))
 }
@@ -70,7 +160,7 @@ func (p *PostgreSQL) SetupConnection(db *sql.DB) error {
 	return err
 }
 
-func (p *PostgreSQL) SyncSequences(ctx context.Context, db *sql.DB) error {
+func (p *PostgreSQL) SyncSequences(ctx context.Context, db *sql.DB, tables []string) error {
 	query := `
 		SELECT
 			n.nspname AS schema,
@@ -96,6 +186,9 @@ func (p *PostgreSQL) SyncSequences(ctx context.Context, db *sql.DB) error {
 		if err := rows.Scan(&schema, &table, &column, &sequence); err != nil {
 			return fmt.Errorf("failed to scan sequence info: %w", err)
 		}
+		if !includesTable(tables, schema+"."+table) {
+			continue
+		}
 
 		fullTable := fmt.Sprintf("%s.%s", p.QuoteIdentifier(schema), p.QuoteIdentifier(table))
 		fullSeq := fmt.Sprintf("%s.%s", p.QuoteIdentifier(schema), p.QuoteIdentifier(sequence))
@@ -122,6 +215,74 @@ func (p *PostgreSQL) SyncSequences(ctx context.Context, db *sql.DB) error {
 	return nil
 }
 
+func (p *PostgreSQL) GeneratedColumns(ctx context.Context, db *sql.DB) (map[string][]string, error) {
+	query := `
+		SELECT table_schema || '.' || table_name, column_name
+		FROM information_schema.columns
+		WHERE is_generated = 'ALWAYS'
+		AND table_schema NOT IN ('pg_catalog', 'information_schema', 'pg_toast')
+		ORDER BY table_schema, table_name, ordinal_position`
+
+	return queryGeneratedColumns(ctx, db, query)
+}
+
+func (p *PostgreSQL) ColumnConstraints(ctx context.Context, db *sql.DB) (map[string]map[string]ColumnConstraint, error) {
+	query := `
+		SELECT table_schema || '.' || table_name, column_name,
+			COALESCE(character_maximum_length, 0), is_nullable = 'YES'
+		FROM information_schema.columns
+		WHERE table_schema NOT IN ('pg_catalog', 'information_schema', 'pg_toast')`
+
+	return queryColumnConstraints(ctx, db, query)
+}
+
+func (p *PostgreSQL) Collations(ctx context.Context, db *sql.DB) ([]string, error) {
+	return queryCollations(ctx, db, `SELECT collname FROM pg_collation`)
+}
+
+func (p *PostgreSQL) SessionTimeZoneDSN(dsn, timeZone string) string {
+	if strings.Contains(dsn, "://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return dsn
+		}
+		// lib/pq sends unrecognized parameters as run-time parameters
+		q := u.Query()
+		q.Set("timezone", timeZone)
+		if p.statementTimeout > 0 {
+			q.Set("statement_timeout", p.statementTimeoutMillis())
+		}
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	dsn = fmt.Sprintf("%s timezone='%s'", dsn, strings.ReplaceAll(timeZone, "'", `\'`))
+	if p.statementTimeout > 0 {
+		dsn += " statement_timeout=" + p.statementTimeoutMillis()
+	}
+	return dsn
+}
+
+func (p *PostgreSQL) statementTimeoutMillis() string {
+	return strconv.FormatInt(max(p.statementTimeout.Milliseconds(), 1), 10)
+}
+
+func (p *PostgreSQL) UpsertClause(keyColumns, updateColumns []string) string {
+	// Without a conflict target PostgreSQL can only skip conflicting rows
+	if len(keyColumns) == 0 {
+		return "ON CONFLICT DO NOTHING"
+	}
+	target := strings.Join(keyColumns, ", ")
+	if len(updateColumns) == 0 {
+		return fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", target)
+	}
+
+	setClauses := make([]string, len(updateColumns))
+	for i, col := range updateColumns {
+		setClauses[i] = fmt.Sprintf("%s = EXCLUDED.%s", col, col)
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", target, strings.Join(setClauses, ", "))
+}
+
 func (p *PostgreSQL) GetUserTablesQuery() string {
 	return `SELECT COUNT(*)
 		FROM information_schema.tables
@@ -141,7 +302,7 @@ func (p *PostgreSQL) FormatDSN(connStr string) string {
 // Native type formatting methods
 
 func (p *PostgreSQL) FormatString(s string) string {
-	return fmt.Sprintf("'%s'", strings.ReplaceAll(s, "'", "''"))
+	return standardQuoting.format(s)
 }
 
 func (p *PostgreSQL) FormatInt(i int64) string {
@@ -164,6 +325,26 @@ func (p *PostgreSQL) FormatDate(t time.Time) string {
 	return fmt.Sprintf("'%s'", t.Format("2006-01-02"))
 }
 
+func (p *PostgreSQL) FormatBytes(b []byte) string {
+	return fmt.Sprintf("decode('%s', 'hex')", hex.EncodeToString(b))
+}
+
+func (p *PostgreSQL) FormatJSON(s string) string {
+	return p.FormatString(s)
+}
+
+func (p *PostgreSQL) FormatUUID(s string) string {
+	return p.FormatString(s)
+}
+
+func (p *PostgreSQL) FormatGeometry(wkt string, srid uint32) string {
+	// Requires PostGIS on the replica
+	if srid == 0 {
+		return fmt.Sprintf("ST_GeomFromText(%s)", p.FormatString(wkt))
+	}
+	return fmt.Sprintf("ST_GeomFromText(%s, %d)", p.FormatString(wkt), srid)
+}
+
 func (p *PostgreSQL) FormatNull() string {
 	return "NULL"
 }
@@ -0,0 +1,299 @@
+package dialect
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"kasho/proto"
+)
+
+// SQLite implements the Dialect interface for SQLite databases, for lightweight
+// development and test replicas. SQLite has no schemas besides "main", no sequences
+// to sync, and only limited ALTER TABLE support, so DDL from other databases rarely
+// applies as is.
+type SQLite struct{}
+
+// NewSQLite creates a new SQLite dialect
+func NewSQLite() *SQLite {
+	return &SQLite{}
+}
+
+func (s *SQLite) Name() string {
+	return "sqlite"
+}
+
+func (s *SQLite) FormatValue(v *proto.ColumnValue) (string, error) {
+	if v == nil || v.Value == nil {
+		return s.FormatNull(), nil
+	}
+
+	switch val := v.Value.(type) {
+	case *proto.ColumnValue_StringValue:
+		return s.FormatString(val.StringValue), nil
+	case *proto.ColumnValue_IntValue:
+		return s.FormatInt(val.IntValue), nil
+	case *proto.ColumnValue_FloatValue:
+		return s.FormatFloat(val.FloatValue), nil
+	case *proto.ColumnValue_BoolValue:
+		return s.FormatBool(val.BoolValue), nil
+	case *proto.ColumnValue_BytesValue:
+		return s.FormatBytes(val.BytesValue), nil
+	case *proto.ColumnValue_JsonValue:
+		return s.FormatJSON(val.JsonValue), nil
+	case *proto.ColumnValue_UuidValue:
+		return s.FormatUUID(val.UuidValue), nil
+	case *proto.ColumnValue_GeometryValue:
+		return s.FormatGeometry(val.GeometryValue.GetWkt(), val.GeometryValue.GetSrid()), nil
+	case *proto.ColumnValue_TimestampValue:
+		text, err := s.timestampText(val.TimestampValue)
+		if err != nil {
+			return "", err
+		}
+		return s.FormatString(text), nil
+	default:
+		return "", fmt.Errorf("unsupported value type: %T", v.Value)
+	}
+}
+
+// timestampText converts a date (YYYY-MM-DD) or RFC 3339 timestamp to the text form
+// SQLite's date and time functions read. Timestamps are stored in UTC.
+func (s *SQLite) timestampText(value string) (string, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t.Format("2006-01-02"), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC().Format("2006-01-02 15:04:05.999999"), nil
+	}
+	return "", fmt.Errorf("invalid timestamp format: %s", value)
+}
+
+func (s *SQLite) Placeholder(n int) string {
+	return "?"
+}
+
+func (s *SQLite) BindValue(v *proto.ColumnValue, placeholder string) (string, any, error) {
+	if v == nil || v.Value == nil {
+		return placeholder, nil, nil
+	}
+
+	switch val := v.Value.(type) {
+	case *proto.ColumnValue_StringValue:
+		return placeholder, val.StringValue, nil
+	case *proto.ColumnValue_IntValue:
+		return placeholder, val.IntValue, nil
+	case *proto.ColumnValue_FloatValue:
+		return placeholder, val.FloatValue, nil
+	case *proto.ColumnValue_BoolValue:
+		return placeholder, val.BoolValue, nil
+	case *proto.ColumnValue_BytesValue:
+		return placeholder, val.BytesValue, nil
+	case *proto.ColumnValue_JsonValue:
+		return placeholder, val.JsonValue, nil
+	case *proto.ColumnValue_UuidValue:
+		return placeholder, val.UuidValue, nil
+	case *proto.ColumnValue_GeometryValue:
+		return placeholder, val.GeometryValue.GetWkt(), nil
+	case *proto.ColumnValue_TimestampValue:
+		text, err := s.timestampText(val.TimestampValue)
+		if err != nil {
+			return "", nil, err
+		}
+		return placeholder, text, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported value type: %T", v.Value)
+	}
+}
+
+func (s *SQLite) QuoteIdentifier(name string) string {
+	return fmt.Sprintf("\"%s\"", strings.ReplaceAll(name, "\"", "\"\""))
+}
+
+func (s *SQLite) SetupConnection(db *sql.DB) error {
+	// SQLite allows a single writer; one connection avoids "database is locked" errors
+	// between pooled connections. Foreign key checks are off unless enabled in the DSN.
+	db.SetMaxOpenConns(1)
+	return nil
+}
+
+func (s *SQLite) SyncSequences(ctx context.Context, db *sql.DB, tables []string) error {
+	// Rowids and AUTOINCREMENT counters follow the largest key inserted, including
+	// explicit ones, so there is nothing to sync
+	return nil
+}
+
+func (s *SQLite) GeneratedColumns(ctx context.Context, db *sql.DB) (map[string][]string, error) {
+	// Hidden 2 and 3 are virtual and stored generated columns. Tables are keyed both
+	// bare and as "main.table", the name changes routed into SQLite's schema carry.
+	query := `
+		SELECT t.name, c.name
+		FROM sqlite_master t
+		JOIN pragma_table_xinfo(t.name) c
+		WHERE t.type = 'table' AND c.hidden IN (2, 3)
+		UNION ALL
+		SELECT 'main.' || t.name, c.name
+		FROM sqlite_master t
+		JOIN pragma_table_xinfo(t.name) c
+		WHERE t.type = 'table' AND c.hidden IN (2, 3)`
+
+	return queryGeneratedColumns(ctx, db, query)
+}
+
+func (s *SQLite) ColumnConstraints(ctx context.Context, db *sql.DB) (map[string]map[string]ColumnConstraint, error) {
+	// SQLite doesn't enforce declared lengths such as VARCHAR(20)
+	query := `
+		SELECT t.name, c.name, 0, c."notnull" = 0
+		FROM sqlite_master t
+		JOIN pragma_table_info(t.name) c
+		WHERE t.type = 'table'
+		UNION ALL
+		SELECT 'main.' || t.name, c.name, 0, c."notnull" = 0
+		FROM sqlite_master t
+		JOIN pragma_table_info(t.name) c
+		WHERE t.type = 'table'`
+
+	return queryColumnConstraints(ctx, db, query)
+}
+
+func (s *SQLite) Collations(ctx context.Context, db *sql.DB) ([]string, error) {
+	return queryCollations(ctx, db, `SELECT name FROM pragma_collation_list`)
+}
+
+func (s *SQLite) SessionTimeZoneDSN(dsn, timeZone string) string {
+	// SQLite has no session time zone; timestamps are stored as UTC text
+	return dsn
+}
+
+func (s *SQLite) UpsertClause(keyColumns, updateColumns []string) string {
+	// Same syntax as PostgreSQL since SQLite 3.24
+	if len(keyColumns) == 0 {
+		return "ON CONFLICT DO NOTHING"
+	}
+	target := strings.Join(keyColumns, ", ")
+	if len(updateColumns) == 0 {
+		return fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", target)
+	}
+
+	setClauses := make([]string, len(updateColumns))
+	for i, col := range updateColumns {
+		setClauses[i] = fmt.Sprintf("%s = excluded.%s", col, col)
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", target, strings.Join(setClauses, ", "))
+}
+
+func (s *SQLite) GetUserTablesQuery() string {
+	return `SELECT COUNT(*) FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'`
+}
+
+func (s *SQLite) GetDriverName() string {
+	return "sqlite"
+}
+
+func (s *SQLite) FormatDSN(connStr string) string {
+	// Convert sqlite://relative/path.db and sqlite:///absolute/path.db to file: URIs;
+	// file: DSNs are passed through
+	rest, ok := cutPrefixFold(connStr, "sqlite://")
+	if !ok {
+		if rest, ok = cutPrefixFold(connStr, "sqlite3://"); !ok {
+			return connStr
+		}
+	}
+
+	path, query, _ := strings.Cut(rest, "?")
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return "file:" + rest
+	}
+	// Wait for other writers instead of failing right away, e.g. while the file is
+	// open in a SQL client
+	if !strings.Contains(query, "busy_timeout") {
+		values.Add("_pragma", "busy_timeout(5000)")
+	}
+	return "file:" + path + "?" + values.Encode()
+}
+
+// cutPrefixFold is strings.CutPrefix ignoring the case of the prefix
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		return s[len(prefix):], true
+	}
+	return s, false
+}
+
+// Native type formatting methods
+
+func (s *SQLite) FormatString(str string) string {
+	return standardQuoting.format(str)
+}
+
+func (s *SQLite) FormatInt(i int64) string {
+	return fmt.Sprintf("%d", i)
+}
+
+func (s *SQLite) FormatFloat(f float64) string {
+	return fmt.Sprintf("%f", f)
+}
+
+func (s *SQLite) FormatBool(b bool) string {
+	// SQLite stores booleans as integers
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+func (s *SQLite) FormatTimestamp(t time.Time) string {
+	return fmt.Sprintf("'%s'", t.UTC().Format("2006-01-02 15:04:05"))
+}
+
+func (s *SQLite) FormatDate(t time.Time) string {
+	return fmt.Sprintf("'%s'", t.Format("2006-01-02"))
+}
+
+func (s *SQLite) FormatBytes(b []byte) string {
+	return fmt.Sprintf("X'%s'", hex.EncodeToString(b))
+}
+
+func (s *SQLite) FormatJSON(str string) string {
+	return s.FormatString(str)
+}
+
+func (s *SQLite) FormatUUID(str string) string {
+	return s.FormatString(str)
+}
+
+func (s *SQLite) FormatGeometry(wkt string, srid uint32) string {
+	// Without SpatiaLite, spatial values are kept as well-known text
+	return s.FormatString(wkt)
+}
+
+func (s *SQLite) FormatNull() string {
+	return "NULL"
+}
+
+// DDL type methods
+
+func (s *SQLite) TypeUUID() string {
+	return "TEXT"
+}
+
+func (s *SQLite) TypeText() string {
+	return "TEXT"
+}
+
+func (s *SQLite) TypeTimestamp() string {
+	return "TEXT"
+}
+
+func (s *SQLite) TypeDecimal(precision, scale int) string {
+	return "NUMERIC"
+}
+
+func (s *SQLite) TypeInteger() string {
+	return "INTEGER"
+}
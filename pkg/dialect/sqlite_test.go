@@ -0,0 +1,205 @@
+package dialect
+
+import (
+	"testing"
+
+	"kasho/proto"
+)
+
+func TestSQLite_Name(t *testing.T) {
+	d := NewSQLite()
+	if got := d.Name(); got != "sqlite" {
+		t.Errorf("Name() = %v, want sqlite", got)
+	}
+}
+
+func TestSQLite_GetDriverName(t *testing.T) {
+	d := NewSQLite()
+	if got := d.GetDriverName(); got != "sqlite" {
+		t.Errorf("GetDriverName() = %v, want sqlite", got)
+	}
+}
+
+func TestSQLite_QuoteIdentifier(t *testing.T) {
+	d := NewSQLite()
+	if got := d.QuoteIdentifier(`my"table`); got != `"my""table"` {
+		t.Errorf("QuoteIdentifier() = %v, want %v", got, `"my""table"`)
+	}
+}
+
+func TestSQLite_FormatValue(t *testing.T) {
+	d := NewSQLite()
+
+	tests := []struct {
+		name    string
+		value   *proto.ColumnValue
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "nil value",
+			value: nil,
+			want:  "NULL",
+		},
+		{
+			name:  "string with single quote",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: "it's"}},
+			want:  "'it''s'",
+		},
+		{
+			name:  "string with backslash",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: `C:\path`}},
+			want:  `'C:\path'`,
+		},
+		{
+			name:  "int value",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: -42}},
+			want:  "-42",
+		},
+		{
+			name:  "bool value",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_BoolValue{BoolValue: true}},
+			want:  "1",
+		},
+		{
+			name:  "bytes value",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: []byte{0xde, 0xad}}},
+			want:  "X'dead'",
+		},
+		{
+			name:  "json value",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_JsonValue{JsonValue: `{"a":"b"}`}},
+			want:  `'{"a":"b"}'`,
+		},
+		{
+			name:  "geometry value",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_GeometryValue{GeometryValue: &proto.Geometry{Wkt: "POINT(1 2)", Srid: 4326}}},
+			want:  "'POINT(1 2)'",
+		},
+		{
+			name:  "date value",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2024-03-20"}},
+			want:  "'2024-03-20'",
+		},
+		{
+			name:  "timestamp value",
+			value: &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2024-03-20T17:04:05.5+02:00"}},
+			want:  "'2024-03-20 15:04:05.5'",
+		},
+		{
+			name:    "invalid timestamp",
+			value:   &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "yesterday"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.FormatValue(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FormatValue() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("FormatValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLite_BindValue(t *testing.T) {
+	d := NewSQLite()
+	expr, arg, err := d.BindValue(&proto.ColumnValue{Value: &proto.ColumnValue_GeometryValue{GeometryValue: &proto.Geometry{Wkt: "POINT(1 2)"}}}, d.Placeholder(2))
+	if err != nil {
+		t.Fatalf("BindValue() error = %v", err)
+	}
+	if expr != "?" || arg != "POINT(1 2)" {
+		t.Errorf("BindValue() = %v, %#v, want ?, \"POINT(1 2)\"", expr, arg)
+	}
+}
+
+func TestSQLite_UpsertClause(t *testing.T) {
+	d := NewSQLite()
+
+	tests := []struct {
+		name          string
+		keyColumns    []string
+		updateColumns []string
+		want          string
+	}{
+		{
+			name:          "update non-key columns",
+			keyColumns:    []string{"id"},
+			updateColumns: []string{"name", "email"},
+			want:          "ON CONFLICT (id) DO UPDATE SET name = excluded.name, email = excluded.email",
+		},
+		{
+			name:       "only key columns",
+			keyColumns: []string{"id"},
+			want:       "ON CONFLICT (id) DO NOTHING",
+		},
+		{
+			name: "no primary key",
+			want: "ON CONFLICT DO NOTHING",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.UpsertClause(tt.keyColumns, tt.updateColumns); got != tt.want {
+				t.Errorf("UpsertClause() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLite_FormatDSN(t *testing.T) {
+	d := NewSQLite()
+
+	tests := []struct {
+		name    string
+		connStr string
+		want    string
+	}{
+		{
+			name:    "relative path",
+			connStr: "sqlite://data/dev.db",
+			want:    "file:data/dev.db?_pragma=busy_timeout%285000%29",
+		},
+		{
+			name:    "absolute path",
+			connStr: "sqlite:///tmp/dev.db",
+			want:    "file:/tmp/dev.db?_pragma=busy_timeout%285000%29",
+		},
+		{
+			name:    "sqlite3 scheme with pragmas",
+			connStr: "SQLITE3://dev.db?_pragma=foreign_keys(1)",
+			want:    "file:dev.db?_pragma=foreign_keys%281%29&_pragma=busy_timeout%285000%29",
+		},
+		{
+			name:    "explicit busy timeout",
+			connStr: "sqlite://dev.db?_pragma=busy_timeout(100)",
+			want:    "file:dev.db?_pragma=busy_timeout%28100%29",
+		},
+		{
+			name:    "file uri passed through",
+			connStr: "file:dev.db?mode=memory",
+			want:    "file:dev.db?mode=memory",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.FormatDSN(tt.connStr); got != tt.want {
+				t.Errorf("FormatDSN() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLite_SessionTimeZoneDSN(t *testing.T) {
+	d := NewSQLite()
+	if got := d.SessionTimeZoneDSN("file:dev.db", "UTC"); got != "file:dev.db" {
+		t.Errorf("SessionTimeZoneDSN() = %v, want file:dev.db", got)
+	}
+}
@@ -0,0 +1,182 @@
+package kvbuffer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultBatchInterval = 10 * time.Millisecond
+	// flushTimeout bounds each flush the buffer makes on its own
+	flushTimeout = 5 * time.Second
+)
+
+// batch queues changes and key writes until they are written together
+type batch struct {
+	size     int
+	interval time.Duration
+
+	mu       sync.Mutex
+	changes  []redis.Z
+	position string // position of the last queued change
+	keys     map[string]string
+	order    []string // keys in the order they were first set
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// EnableBatching makes AddChange queue changes and write them to Redis in a single
+// transaction once size changes are queued, and at least every interval. Changes keep the
+// order they were added in, and Set queues its key behind the changes added before it, so
+// a saved position is never written ahead of the changes it covers. Close flushes
+// whatever is still queued.
+func (b *KVBuffer) EnableBatching(size int, interval time.Duration) {
+	if size <= 1 || b.batch != nil {
+		return
+	}
+	if interval <= 0 {
+		interval = defaultBatchInterval
+	}
+	b.batch = &batch{
+		size:     size,
+		interval: interval,
+		keys:     make(map[string]string),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go b.flushPeriodically()
+}
+
+// Flush writes the queued changes and keys. If the write fails they stay queued and are
+// retried by the next flush.
+func (b *KVBuffer) Flush(ctx context.Context) error {
+	if b.batch == nil {
+		return nil
+	}
+	b.batch.mu.Lock()
+	defer b.batch.mu.Unlock()
+	return b.flushLocked(ctx)
+}
+
+// queueChange adds a change to the batch, flushing it once it is full
+func (b *KVBuffer) queueChange(ctx context.Context, position string, z redis.Z) error {
+	b.batch.mu.Lock()
+	defer b.batch.mu.Unlock()
+
+	b.batch.changes = append(b.batch.changes, z)
+	b.batch.position = position
+	if len(b.batch.changes) < b.batch.size {
+		return nil
+	}
+	return b.flushLocked(ctx)
+}
+
+// queueKey adds a key write to the batch; a later write to the same key replaces it
+func (b *KVBuffer) queueKey(key, value string) {
+	b.batch.mu.Lock()
+	defer b.batch.mu.Unlock()
+
+	if _, ok := b.batch.keys[key]; !ok {
+		b.batch.order = append(b.batch.order, key)
+	}
+	b.batch.keys[key] = value
+}
+
+// queuedKey returns the queued value of a key, if it has one
+func (b *KVBuffer) queuedKey(key string) (string, bool) {
+	b.batch.mu.Lock()
+	defer b.batch.mu.Unlock()
+
+	value, ok := b.batch.keys[key]
+	return value, ok
+}
+
+// addChangesScript adds each change that isn't in the buffer yet and publishes it, in
+// order, returning the number of duplicates skipped.
+// KEYS: changes sorted set, changes channel. ARGV: TTL in seconds, then score/member pairs.
+const addChangesScript = `
+local duplicates = 0
+for i = 2, #ARGV, 2 do
+	if redis.call('ZADD', KEYS[1], 'NX', ARGV[i], ARGV[i + 1]) == 1 then
+		redis.call('PUBLISH', KEYS[2], ARGV[i + 1])
+	else
+		duplicates = duplicates + 1
+	end
+end
+redis.call('EXPIRE', KEYS[1], ARGV[1])
+return duplicates
+`
+
+// flushLocked writes the batch in one MULTI/EXEC round trip: the changes with their
+// publishes in order, then the queued keys
+func (b *KVBuffer) flushLocked(ctx context.Context) error {
+	if len(b.batch.changes) == 0 && len(b.batch.order) == 0 {
+		return nil
+	}
+
+	var skipped *redis.Cmd
+	_, err := b.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(b.batch.changes) > 0 {
+			args := make([]any, 0, 1+2*len(b.batch.changes))
+			args = append(args, int64(changesTTL.Seconds()))
+			for _, z := range b.batch.changes {
+				args = append(args, z.Score, z.Member)
+			}
+			skipped = pipe.Eval(ctx, addChangesScript, []string{changesKey, changesChannel}, args...)
+		}
+		for _, key := range b.batch.order {
+			pipe.Set(ctx, key, b.batch.keys[key], 0)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write %d changes to KV: %w", len(b.batch.changes), err)
+	}
+	if skipped != nil {
+		if duplicates, _ := skipped.Int64(); duplicates > 0 {
+			b.countDuplicates(duplicates, b.batch.position)
+		}
+	}
+
+	b.batch.changes = b.batch.changes[:0]
+	b.batch.keys = make(map[string]string)
+	b.batch.order = b.batch.order[:0]
+	return nil
+}
+
+// flushPeriodically flushes the batch every interval until the buffer is closed
+func (b *KVBuffer) flushPeriodically() {
+	defer close(b.batch.done)
+
+	ticker := time.NewTicker(b.batch.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.batch.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+			if err := b.Flush(ctx); err != nil {
+				log.Printf("Failed to flush KV buffer: %v", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// closeBatch stops the periodic flush and writes what is still queued
+func (b *KVBuffer) closeBatch() error {
+	close(b.batch.stop)
+	<-b.batch.done
+
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+	return b.Flush(ctx)
+}
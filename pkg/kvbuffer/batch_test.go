@@ -0,0 +1,107 @@
+package kvbuffer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+)
+
+func newBatchingBuffer(t *testing.T, size int) (*KVBuffer, redismock.ClientMock) {
+	t.Helper()
+	db, mock := redismock.NewClientMock()
+	kvBuffer := &KVBuffer{client: db}
+	// An interval this long leaves flushing to the test
+	kvBuffer.EnableBatching(size, time.Hour)
+	t.Cleanup(func() {
+		close(kvBuffer.batch.stop)
+		<-kvBuffer.batch.done
+		db.Close()
+	})
+	return kvBuffer, mock
+}
+
+func TestKVBuffer_Batching_FlushesFullBatchInOrder(t *testing.T) {
+	kvBuffer, mock := newBatchingBuffer(t, 2)
+	ctx := context.Background()
+
+	first := TestChange{Position: "0/100", Data: TestDMLData{Table: "users", Kind: "insert"}}
+	second := TestChange{Position: "0/200", Data: TestDMLData{Table: "users", Kind: "update"}}
+	firstData, _ := json.Marshal(first)
+	secondData, _ := json.Marshal(second)
+
+	mock.ExpectTxPipeline()
+	// One of the two changes is already in the buffer
+	mock.ExpectEval(addChangesScript, []string{changesKey, changesChannel}, int64(86400), float64(256), firstData, float64(512), secondData).SetVal(int64(1))
+	mock.ExpectSet("kasho:test:resume", "0/100", 0).SetVal("OK")
+	mock.ExpectTxPipelineExec()
+
+	if err := kvBuffer.AddChange(ctx, first); err != nil {
+		t.Fatalf("AddChange() error = %v", err)
+	}
+	// The key is written after the changes queued before it, even though it was set first
+	if err := kvBuffer.Set(ctx, "kasho:test:resume", "0/100"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got, err := kvBuffer.Get(ctx, "kasho:test:resume"); err != nil || got != "0/100" {
+		t.Errorf("Get() of a queued key = %q, %v, want %q", got, err, "0/100")
+	}
+	if err := kvBuffer.AddChange(ctx, second); err != nil {
+		t.Fatalf("AddChange() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+	if got := kvBuffer.Duplicates(); got != 1 {
+		t.Errorf("Duplicates() = %d, want 1", got)
+	}
+	if len(kvBuffer.batch.changes) != 0 || len(kvBuffer.batch.order) != 0 {
+		t.Errorf("batch not emptied after flush: %d changes, %d keys", len(kvBuffer.batch.changes), len(kvBuffer.batch.order))
+	}
+}
+
+func TestKVBuffer_Batching_KeepsBatchOnError(t *testing.T) {
+	kvBuffer, mock := newBatchingBuffer(t, 10)
+	ctx := context.Background()
+
+	change := TestChange{Position: "0/100", Data: TestDMLData{Table: "users", Kind: "insert"}}
+	data, _ := json.Marshal(change)
+
+	if err := kvBuffer.AddChange(ctx, change); err != nil {
+		t.Fatalf("AddChange() error = %v", err)
+	}
+
+	mock.ExpectTxPipeline()
+	mock.ExpectEval(addChangesScript, []string{changesKey, changesChannel}, int64(86400), float64(256), data).SetErr(errors.New("connection refused"))
+	if err := kvBuffer.Flush(ctx); err == nil {
+		t.Fatal("Flush() should return an error when the write fails")
+	}
+	if len(kvBuffer.batch.changes) != 1 {
+		t.Fatalf("batch holds %d changes after a failed flush, want 1", len(kvBuffer.batch.changes))
+	}
+
+	mock.ExpectTxPipeline()
+	mock.ExpectEval(addChangesScript, []string{changesKey, changesChannel}, int64(86400), float64(256), data).SetVal(int64(0))
+	mock.ExpectTxPipelineExec()
+	if err := kvBuffer.Flush(ctx); err != nil {
+		t.Fatalf("Flush() retry error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestKVBuffer_EnableBatching_SizeOne(t *testing.T) {
+	db, _ := redismock.NewClientMock()
+	defer db.Close()
+
+	kvBuffer := &KVBuffer{client: db}
+	kvBuffer.EnableBatching(1, time.Second)
+	if kvBuffer.batch != nil {
+		t.Error("EnableBatching(1) should leave batching off")
+	}
+}
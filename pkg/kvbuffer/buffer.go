@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/jackc/pglogrepl"
@@ -13,9 +15,9 @@ import (
 )
 
 const (
-	changesKey    = "kasho:changes"
+	changesKey     = "kasho:changes"
 	changesChannel = "kasho:changes"
-	changesTTL    = 24 * time.Hour
+	changesTTL     = 24 * time.Hour
 )
 
 // Change represents a database change event
@@ -27,7 +29,10 @@ type Change interface {
 
 // KVBuffer manages change events in a Redis-backed buffer
 type KVBuffer struct {
-	client *redis.Client
+	client     *redis.Client
+	batch      *batch // nil unless batching is enabled
+	spill      *spill // nil unless spilling is enabled
+	duplicates atomic.Int64
 }
 
 // NewKVBuffer creates a new KV buffer connected to Redis
@@ -38,6 +43,7 @@ func NewKVBuffer(kvURL string) (*KVBuffer, error) {
 	}
 
 	client := redis.NewClient(opts)
+	enableChaos(client)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -49,7 +55,9 @@ func NewKVBuffer(kvURL string) (*KVBuffer, error) {
 	return &KVBuffer{client: client}, nil
 }
 
-// AddChange adds a change to the KV buffer with its position as the score
+// AddChange adds a change to the KV buffer with its position as the score. A change
+// already in the buffer at the same position, such as one re-emitted after the source
+// client reconnects, is counted as a duplicate and neither stored nor published again.
 func (b *KVBuffer) AddChange(ctx context.Context, change Change) error {
 	position := change.GetPosition()
 	score, err := b.parsePositionToScore(position)
@@ -62,13 +70,21 @@ func (b *KVBuffer) AddChange(ctx context.Context, change Change) error {
 		return fmt.Errorf("failed to marshal change: %w", err)
 	}
 
-	err = b.client.ZAdd(ctx, changesKey, redis.Z{
+	if b.batch != nil {
+		return b.queueChange(ctx, position, redis.Z{Score: score, Member: data})
+	}
+
+	added, err := b.client.ZAddNX(ctx, changesKey, redis.Z{
 		Score:  score,
 		Member: data,
-	}).Err()
+	}).Result()
 	if err != nil {
 		return fmt.Errorf("failed to add change to KV: %w", err)
 	}
+	if added == 0 {
+		b.countDuplicates(1, position)
+		return nil
+	}
 
 	err = b.client.Expire(ctx, changesKey, changesTTL).Err()
 	if err != nil {
@@ -83,6 +99,17 @@ func (b *KVBuffer) AddChange(ctx context.Context, change Change) error {
 	return nil
 }
 
+// Duplicates returns the number of duplicate changes skipped since the buffer was created
+func (b *KVBuffer) Duplicates() int64 {
+	return b.duplicates.Load()
+}
+
+// countDuplicates records duplicates skipped among changes up to a position
+func (b *KVBuffer) countDuplicates(n int64, position string) {
+	total := b.duplicates.Add(n)
+	log.Printf("Skipped %d duplicate change(s) at or before position %s (%d in total)", n, position, total)
+}
+
 // GetChangesAfter returns all changes after the given position
 // NOTE: This method is limited to 1000 changes for backward compatibility.
 // Use GetChangesAfterBatch for paginated access to larger result sets.
@@ -103,6 +130,14 @@ func (b *KVBuffer) GetChangesAfterBatch(ctx context.Context, position string, of
 		minScore = "-inf"
 	}
 
+	if b.spill != nil {
+		return b.getSpilledChangesAfter(ctx, score, position == "bootstrap", minScore, offset, limit)
+	}
+	return b.getChangesAfter(ctx, minScore, offset, limit)
+}
+
+// getChangesAfter returns a batch of the changes in Redis from minScore
+func (b *KVBuffer) getChangesAfter(ctx context.Context, minScore string, offset int64, limit int64) ([]json.RawMessage, error) {
 	results, err := b.client.ZRangeByScore(ctx, changesKey, &redis.ZRangeBy{
 		Min:    minScore,
 		Max:    "+inf",
@@ -121,10 +156,36 @@ func (b *KVBuffer) GetChangesAfterBatch(ctx context.Context, position string, of
 	return changes, nil
 }
 
+// Depth returns the number of changes currently held in the buffer, including those
+// spilled to disk
+func (b *KVBuffer) Depth(ctx context.Context) (int64, error) {
+	depth, err := b.client.ZCard(ctx, changesKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get buffer depth: %w", err)
+	}
+	return depth + b.Spilled(), nil
+}
+
+// Size returns the approximate bytes Redis uses to hold the buffer, not counting
+// changes spilled to disk
+func (b *KVBuffer) Size(ctx context.Context) (int64, error) {
+	size, err := b.client.MemoryUsage(ctx, changesKey).Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get buffer size: %w", err)
+	}
+	return size, nil
+}
+
 // parsePositionToScore converts a database position to a Redis sorted set score
 // Supports:
 // - PostgreSQL LSN: "0/100" format
-// - MySQL binlog: "mysql-bin.000001:4" format (filename:offset)
+// - MySQL binlog: "mysql-bin.000001:4" format (filename:offset), "mysql-bin.000001:4:2" for rows (filename:event offset:row ordinal)
+// - MongoDB: "mongo:0000000000000042:8263A1..." format (sequence:resume token)
+// - SQL Server: "mssql:0000000000000042:0000002A000001300003:0000002A000001300002" format (sequence:LSN:seqval)
+// - Oracle: "oracle:0000000000000042:4735918:2:4735900" format (sequence:commit SCN:index:restart SCN)
 // - Bootstrap: "0/BOOTSTRAP%016d" format (negative scores for ordering)
 // - Special "bootstrap" value: returns negative infinity score
 func (b *KVBuffer) parsePositionToScore(position string) (float64, error) {
@@ -141,10 +202,32 @@ func (b *KVBuffer) parsePositionToScore(position string) (float64, error) {
 		return 0, fmt.Errorf("invalid bootstrap position format: %s", position)
 	}
 
-	// MySQL binlog position: "mysql-bin.000001:4" or "binlog.000001:4" → (filenum * 4294967296) + offset
+	// Sequenced positions: "mongo:0000000000000042:8263A1...",
+	// "mssql:0000000000000042:<LSN>:<seqval>" or "oracle:0000000000000042:<SCN>:..." →
+	// sequence; resume tokens don't order, SQL Server LSNs are too wide for a float score
+	// and many Oracle changes share a commit SCN
+	for _, prefix := range []string{"mongo:", "mssql:", "oracle:"} {
+		rest, ok := strings.CutPrefix(position, prefix)
+		if !ok {
+			continue
+		}
+		seq, _, found := strings.Cut(rest, ":")
+		if !found {
+			return 0, fmt.Errorf("invalid sequenced position format: %s", position)
+		}
+		n, err := strconv.ParseUint(seq, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid position sequence: %s", position)
+		}
+		return float64(n), nil
+	}
+
+	// MySQL binlog position: "mysql-bin.000001:4" or "binlog.000001:4" → (filenum * 4294967296) + offset.
+	// Row positions "mysql-bin.000001:4:2" add the row ordinal to the offset of their event;
+	// an event holds fewer rows than bytes, so that stays below the offset of the next one.
 	if strings.Contains(position, ":") && (strings.Contains(position, "bin.") || strings.HasPrefix(position, "binlog.")) {
 		parts := strings.Split(position, ":")
-		if len(parts) == 2 {
+		if len(parts) == 2 || len(parts) == 3 {
 			// Extract file number from "mysql-bin.000001" or "binlog.000001"
 			filename := parts[0]
 			if idx := strings.LastIndex(filename, "."); idx != -1 {
@@ -157,6 +240,13 @@ func (b *KVBuffer) parsePositionToScore(position string) (float64, error) {
 				if err != nil {
 					return 0, fmt.Errorf("invalid MySQL binlog offset: %s", position)
 				}
+				if len(parts) == 3 {
+					row, err := strconv.ParseInt(parts[2], 10, 64)
+					if err != nil {
+						return 0, fmt.Errorf("invalid MySQL binlog row: %s", position)
+					}
+					offset += row
+				}
 				// Combine: file number * 4GB + offset for monotonic ordering
 				return float64(fileNum)*4294967296 + float64(offset), nil
 			}
@@ -182,8 +272,13 @@ func (b *KVBuffer) GetClient() *redis.Client {
 	return b.client
 }
 
-// Get retrieves a value from Redis by key
+// Get retrieves a value from Redis by key, or its queued value when batching
 func (b *KVBuffer) Get(ctx context.Context, key string) (string, error) {
+	if b.batch != nil {
+		if val, ok := b.queuedKey(key); ok {
+			return val, nil
+		}
+	}
 	val, err := b.client.Get(ctx, key).Result()
 	if err == redis.Nil {
 		return "", nil
@@ -194,8 +289,13 @@ func (b *KVBuffer) Get(ctx context.Context, key string) (string, error) {
 	return val, nil
 }
 
-// Set stores a value in Redis with the given key
+// Set stores a value in Redis with the given key. When batching, the write is queued
+// behind the changes already added.
 func (b *KVBuffer) Set(ctx context.Context, key, value string) error {
+	if b.batch != nil {
+		b.queueKey(key, value)
+		return nil
+	}
 	err := b.client.Set(ctx, key, value, 0).Err()
 	if err != nil {
 		return fmt.Errorf("failed to set key %s: %w", key, err)
@@ -203,7 +303,16 @@ func (b *KVBuffer) Set(ctx context.Context, key, value string) error {
 	return nil
 }
 
-// Close closes the KV connection
+// Close flushes any queued changes, closes the spill segments and closes the KV connection
 func (b *KVBuffer) Close() error {
+	if b.spill != nil {
+		b.closeSpill()
+	}
+	if b.batch != nil {
+		if err := b.closeBatch(); err != nil {
+			b.client.Close()
+			return err
+		}
+	}
 	return b.client.Close()
-}
\ No newline at end of file
+}
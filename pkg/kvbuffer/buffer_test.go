@@ -3,8 +3,11 @@ package kvbuffer
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-redis/redismock/v9"
 	"github.com/redis/go-redis/v9"
@@ -65,7 +68,7 @@ func TestKVBuffer_AddChange(t *testing.T) {
 	data, _ := json.Marshal(change)
 
 	// Set expectations
-	mock.ExpectZAdd(changesKey, redis.Z{
+	mock.ExpectZAddNX(changesKey, redis.Z{
 		Score:  float64(256), // LSN 0/100 = 256
 		Member: data,
 	}).SetVal(1)
@@ -105,7 +108,7 @@ func TestKVBuffer_AddChange_BootstrapPosition(t *testing.T) {
 	data, _ := json.Marshal(change)
 
 	// Set expectations - bootstrap positions get negative scores
-	mock.ExpectZAdd(changesKey, redis.Z{
+	mock.ExpectZAddNX(changesKey, redis.Z{
 		Score:  float64(-999999), // -1000000 + 1
 		Member: data,
 	}).SetVal(1)
@@ -124,6 +127,39 @@ func TestKVBuffer_AddChange_BootstrapPosition(t *testing.T) {
 	}
 }
 
+func TestKVBuffer_AddChange_Duplicate(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer db.Close()
+
+	kvBuffer := &KVBuffer{client: db}
+
+	ctx := context.Background()
+	change := TestChange{
+		Position: "0/100",
+		Data: TestDMLData{
+			Table: "users",
+			Kind:  "insert",
+		},
+	}
+	data, _ := json.Marshal(change)
+
+	// The change is already in the buffer, so it is neither expired nor published again
+	mock.ExpectZAddNX(changesKey, redis.Z{
+		Score:  float64(256),
+		Member: data,
+	}).SetVal(0)
+
+	if err := kvBuffer.AddChange(ctx, change); err != nil {
+		t.Errorf("AddChange() error = %v", err)
+	}
+	if got := kvBuffer.Duplicates(); got != 1 {
+		t.Errorf("Duplicates() = %d, want 1", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %v", err)
+	}
+}
+
 func TestKVBuffer_AddChange_InvalidPosition(t *testing.T) {
 	db, _ := redismock.NewClientMock()
 	defer db.Close()
@@ -236,6 +272,49 @@ func TestKVBuffer_GetChangesAfter_EmptyResult(t *testing.T) {
 	}
 }
 
+func TestKVBuffer_Depth(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer db.Close()
+
+	kvBuffer := &KVBuffer{client: db}
+
+	mock.ExpectZCard(changesKey).SetVal(42)
+
+	depth, err := kvBuffer.Depth(context.Background())
+	if err != nil {
+		t.Errorf("Depth() error = %v", err)
+	}
+	if depth != 42 {
+		t.Errorf("Expected depth 42, got %d", depth)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %v", err)
+	}
+}
+
+func TestKVBuffer_Size(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer db.Close()
+
+	kvBuffer := &KVBuffer{client: db}
+
+	mock.ExpectMemoryUsage(changesKey).SetVal(4096)
+	size, err := kvBuffer.Size(context.Background())
+	if err != nil {
+		t.Errorf("Size() error = %v", err)
+	}
+	if size != 4096 {
+		t.Errorf("Size() = %d, want 4096", size)
+	}
+
+	// An empty buffer has no key
+	mock.ExpectMemoryUsage(changesKey).RedisNil()
+	if size, err := kvBuffer.Size(context.Background()); err != nil || size != 0 {
+		t.Errorf("Size() of an empty buffer = %d, %v, want 0", size, err)
+	}
+}
+
 func TestKVBuffer_Close(t *testing.T) {
 	db, mock := redismock.NewClientMock()
 	kvBuffer := &KVBuffer{client: db}
@@ -364,6 +443,34 @@ func TestParsePositionToScore(t *testing.T) {
 			position: "0/BOOTSTRAPinvalid",
 			wantErr: true,
 		},
+		{
+			name:     "MongoDB position",
+			position: "mongo:0000000000000042:8263A1B2C3000000012B022C0100296E5A1004",
+			expected: 42,
+			wantErr:  false,
+		},
+		{
+			name:     "MongoDB position without resume token",
+			position: "mongo:0000000000000042",
+			wantErr:  true,
+		},
+		{
+			name:     "MongoDB position with invalid sequence",
+			position: "mongo:abc:8263A1",
+			wantErr:  true,
+		},
+		{
+			name:     "SQL Server position",
+			position: "mssql:0000000000000007:0000002A000001300003:0000002A000001300002",
+			expected: 7,
+			wantErr:  false,
+		},
+		{
+			name:     "Oracle position",
+			position: "oracle:0000000000000009:4735918:2:4735900",
+			expected: 9,
+			wantErr:  false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -392,7 +499,9 @@ func TestParsePositionToScore_MySQLBinlog(t *testing.T) {
 		{"mysql binlog large file", "mysql-bin.000100:1234567", false},
 		{"mysql binlog zero offset", "mysql-bin.000001:0", false},
 		{"binlog variant", "binlog.000001:100", false},
+		{"mysql binlog row", "mysql-bin.000001:4:2", false},
 		{"invalid no colon", "mysql-bin.000001", true},
+		{"invalid row ordinal", "mysql-bin.000001:4:x", true},
 		{"invalid no file number", "mysql-bin:100", true},
 	}
 
@@ -419,10 +528,14 @@ func TestParsePositionToScore_MySQLBinlog(t *testing.T) {
 func TestParsePositionToScore_MySQLOrdering(t *testing.T) {
 	buffer := &KVBuffer{}
 
-	// Test that positions are ordered correctly
+	// Test that positions are ordered correctly. The rows of an event starting at 100 come
+	// after the event that ends there and before the next one.
 	positions := []string{
 		"mysql-bin.000001:4",
 		"mysql-bin.000001:100",
+		"mysql-bin.000001:100:1",
+		"mysql-bin.000001:100:2",
+		"mysql-bin.000001:100:3",
 		"mysql-bin.000001:1000",
 		"mysql-bin.000002:4",
 		"mysql-bin.000002:100",
@@ -440,4 +553,104 @@ func TestParsePositionToScore_MySQLOrdering(t *testing.T) {
 		}
 		lastScore = score
 	}
-}
\ No newline at end of file
+}
+
+func BenchmarkParsePositionToScore(b *testing.B) {
+	kvBuffer := &KVBuffer{}
+	for name, position := range map[string]string{
+		"postgresql": "16/B374D848",
+		"mysql":      "mysql-bin.000042:123456",
+		"mongo":      "mongo:0000000000000042:8263A1F0",
+		"bootstrap":  "0/BOOTSTRAP0000000000000042",
+	} {
+		b.Run(name, func(b *testing.B) {
+			for b.Loop() {
+				if _, err := kvBuffer.parsePositionToScore(position); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// benchmarkBuffer connects to the Redis in KVBUFFER_BENCH_URL, skipping the benchmark if
+// it isn't set. The buffer's keys are deleted before and after, so point it at a Redis
+// that holds nothing else, e.g. redis://localhost:6379/15.
+func benchmarkBuffer(b *testing.B) *KVBuffer {
+	b.Helper()
+	kvURL := os.Getenv("KVBUFFER_BENCH_URL")
+	if kvURL == "" {
+		b.Skip("KVBUFFER_BENCH_URL not set")
+	}
+	kvBuffer, err := NewKVBuffer(kvURL)
+	if err != nil {
+		b.Fatalf("NewKVBuffer() error = %v", err)
+	}
+	ctx := context.Background()
+	kvBuffer.client.Del(ctx, changesKey)
+	b.Cleanup(func() {
+		kvBuffer.client.Del(ctx, changesKey)
+		kvBuffer.Close()
+	})
+	return kvBuffer
+}
+
+// benchmarkChange is a typical insert at the given sequence number
+func benchmarkChange(seq int) TestChange {
+	return TestChange{
+		Position: fmt.Sprintf("0/%X", 0x1000+seq),
+		Data: TestDMLData{
+			Table:       "public.orders",
+			Kind:        "insert",
+			ColumnNames: []string{"id", "customer_id", "status", "total", "notes"},
+			ColumnValues: []TestColumnValueWrapper{
+				{Value: seq},
+				{Value: 42},
+				{Value: "shipped"},
+				{Value: 199.95},
+				{Value: "Leave at the back door"},
+			},
+		},
+	}
+}
+
+func BenchmarkAddChange(b *testing.B) {
+	for _, batchSize := range []int{1, 100} {
+		b.Run(fmt.Sprintf("batch=%d", batchSize), func(b *testing.B) {
+			kvBuffer := benchmarkBuffer(b)
+			kvBuffer.EnableBatching(batchSize, time.Second)
+			ctx := context.Background()
+			b.ReportAllocs()
+			seq := 0
+			for b.Loop() {
+				if err := kvBuffer.AddChange(ctx, benchmarkChange(seq)); err != nil {
+					b.Fatal(err)
+				}
+				seq++
+			}
+			if err := kvBuffer.Flush(ctx); err != nil {
+				b.Fatal(err)
+			}
+		})
+	}
+}
+
+func BenchmarkGetChangesAfterBatch(b *testing.B) {
+	kvBuffer := benchmarkBuffer(b)
+	ctx := context.Background()
+	for seq := range 10000 {
+		if err := kvBuffer.AddChange(ctx, benchmarkChange(seq)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		changes, err := kvBuffer.GetChangesAfterBatch(ctx, "0/1000", 0, 1000)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(changes) != 1000 {
+			b.Fatalf("got %d changes, want 1000", len(changes))
+		}
+	}
+}
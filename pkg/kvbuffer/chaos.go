@@ -0,0 +1,70 @@
+//go:build chaos
+
+package kvbuffer
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// enableChaos kills Redis connections for resilience testing, in builds with the chaos tag.
+// CHAOS_KILL_REDIS_EVERY=K closes the connection of every Kth write to Redis, which fails
+// like a dropped connection and is retried by the Redis client on a new one.
+func enableChaos(client *redis.Client) {
+	value := os.Getenv("CHAOS_KILL_REDIS_EVERY")
+	if value == "" {
+		return
+	}
+	every, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || every < 2 {
+		log.Fatalf("Invalid CHAOS_KILL_REDIS_EVERY: %q (expected a number of writes, at least 2)", value)
+	}
+	log.Printf("Chaos: killing the Redis connection of every %d writes", every)
+	client.AddHook(&chaosHook{every: every})
+}
+
+// chaosHook wraps every Redis connection so the shared write count can kill it
+type chaosHook struct {
+	every  int64
+	writes atomic.Int64
+}
+
+func (h *chaosHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &chaosConn{Conn: conn, hook: h}, nil
+	}
+}
+
+func (h *chaosHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return next
+}
+
+func (h *chaosHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+// chaosConn is a Redis connection that is closed instead of written to when the write
+// count reaches a multiple of the hook's interval
+type chaosConn struct {
+	net.Conn
+	hook *chaosHook
+}
+
+func (c *chaosConn) Write(b []byte) (int, error) {
+	if c.hook.writes.Add(1)%c.hook.every == 0 {
+		c.Conn.Close()
+		return 0, &net.OpError{Op: "write", Net: "tcp", Err: errors.New("chaos: connection killed")}
+	}
+	return c.Conn.Write(b)
+}
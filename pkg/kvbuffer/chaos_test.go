@@ -0,0 +1,39 @@
+//go:build chaos
+
+package kvbuffer
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestChaosConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go func() {
+		buf := make([]byte, 16)
+		for {
+			if _, err := server.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	conn := &chaosConn{Conn: client, hook: &chaosHook{every: 3}}
+	for i := 1; i <= 2; i++ {
+		if _, err := conn.Write([]byte("PING")); err != nil {
+			t.Fatalf("write %d error = %v", i, err)
+		}
+	}
+
+	// The third write kills the connection with an error the Redis client retries
+	_, err := conn.Write([]byte("PING"))
+	var netErr net.Error
+	if !errors.As(err, &netErr) {
+		t.Fatalf("write 3 error = %v, want a net.Error", err)
+	}
+	if _, err := client.Write([]byte("PING")); err == nil {
+		t.Error("connection still open after being killed")
+	}
+}
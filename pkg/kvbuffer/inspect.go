@@ -0,0 +1,134 @@
+package kvbuffer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Stats describes the changes held in Redis, for inspecting the buffer
+type Stats struct {
+	Changes       int64         // Changes in Redis, not counting changes spilled to disk
+	MemoryBytes   int64         // Approximate bytes Redis uses to hold them
+	FirstPosition string        // Position of the oldest change, empty if there are none
+	LastPosition  string        // Position of the newest change, empty if there are none
+	TTL           time.Duration // Time until the buffer expires, 0 if it doesn't
+}
+
+// Stats returns the number, size and position range of the changes in Redis
+func (b *KVBuffer) Stats(ctx context.Context) (Stats, error) {
+	var stats Stats
+	var err error
+
+	if stats.Changes, err = b.client.ZCard(ctx, changesKey).Result(); err != nil {
+		return stats, fmt.Errorf("failed to count changes: %w", err)
+	}
+	if stats.MemoryBytes, err = b.Size(ctx); err != nil {
+		return stats, err
+	}
+	if stats.FirstPosition, err = b.positionAt(ctx, 0); err != nil {
+		return stats, err
+	}
+	if stats.LastPosition, err = b.positionAt(ctx, -1); err != nil {
+		return stats, err
+	}
+	ttl, err := b.client.TTL(ctx, changesKey).Result()
+	if err != nil {
+		return stats, fmt.Errorf("failed to get buffer TTL: %w", err)
+	}
+	if ttl > 0 {
+		stats.TTL = ttl
+	}
+	return stats, nil
+}
+
+// positionAt returns the position of the change at a rank of the sorted set
+func (b *KVBuffer) positionAt(ctx context.Context, rank int64) (string, error) {
+	members, err := b.client.ZRange(ctx, changesKey, rank, rank).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to read change at rank %d: %w", rank, err)
+	}
+	if len(members) == 0 {
+		return "", nil
+	}
+	var change struct {
+		Position string `json:"position"`
+	}
+	if err := json.Unmarshal([]byte(members[0]), &change); err != nil {
+		return "", fmt.Errorf("invalid change at rank %d: %w", rank, err)
+	}
+	return change.Position, nil
+}
+
+// scoreRange returns the scores of the changes from one position to another, both
+// included. An empty from or to leaves the range open at that end.
+func (b *KVBuffer) scoreRange(from, to string) (string, string, error) {
+	bounds := [2]string{"-inf", "+inf"}
+	for i, position := range []string{from, to} {
+		if position == "" {
+			continue
+		}
+		score, err := b.parsePositionToScore(position)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to parse position: %w", err)
+		}
+		bounds[i] = strconv.FormatFloat(score, 'f', -1, 64)
+	}
+	return bounds[0], bounds[1], nil
+}
+
+// ChangesBetween returns a batch of the changes in Redis from one position to another,
+// both included. An empty from or to leaves the range open at that end.
+func (b *KVBuffer) ChangesBetween(ctx context.Context, from, to string, offset, limit int64) ([]json.RawMessage, error) {
+	minScore, maxScore, err := b.scoreRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	results, err := b.client.ZRangeByScore(ctx, changesKey, &redis.ZRangeBy{
+		Min:    minScore,
+		Max:    maxScore,
+		Offset: offset,
+		Count:  limit,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get changes from KV: %w", err)
+	}
+
+	changes := make([]json.RawMessage, len(results))
+	for i, result := range results {
+		changes[i] = json.RawMessage(result)
+	}
+	return changes, nil
+}
+
+// CountBetween returns the number of changes in Redis from one position to another, both
+// included
+func (b *KVBuffer) CountBetween(ctx context.Context, from, to string) (int64, error) {
+	minScore, maxScore, err := b.scoreRange(from, to)
+	if err != nil {
+		return 0, err
+	}
+	count, err := b.client.ZCount(ctx, changesKey, minScore, maxScore).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count changes: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteBetween removes the changes in Redis from one position to another, both included,
+// and returns the number removed. Consumers that already received them are not affected.
+func (b *KVBuffer) DeleteBetween(ctx context.Context, from, to string) (int64, error) {
+	minScore, maxScore, err := b.scoreRange(from, to)
+	if err != nil {
+		return 0, err
+	}
+	removed, err := b.client.ZRemRangeByScore(ctx, changesKey, minScore, maxScore).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete changes: %w", err)
+	}
+	return removed, nil
+}
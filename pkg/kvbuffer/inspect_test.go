@@ -0,0 +1,118 @@
+package kvbuffer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestKVBuffer_Stats(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer db.Close()
+	kvBuffer := &KVBuffer{client: db}
+	ctx := context.Background()
+
+	first, _ := json.Marshal(TestChange{Position: "0/BOOTSTRAP0000000000000001"})
+	last, _ := json.Marshal(TestChange{Position: "0/1A2B3C"})
+
+	mock.ExpectZCard(changesKey).SetVal(2)
+	mock.ExpectMemoryUsage(changesKey).SetVal(4096)
+	mock.ExpectZRange(changesKey, 0, 0).SetVal([]string{string(first)})
+	mock.ExpectZRange(changesKey, -1, -1).SetVal([]string{string(last)})
+	mock.ExpectTTL(changesKey).SetVal(time.Hour)
+
+	stats, err := kvBuffer.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	want := Stats{
+		Changes:       2,
+		MemoryBytes:   4096,
+		FirstPosition: "0/BOOTSTRAP0000000000000001",
+		LastPosition:  "0/1A2B3C",
+		TTL:           time.Hour,
+	}
+	if stats != want {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+
+	// An empty buffer has no positions and no TTL
+	mock.ExpectZCard(changesKey).SetVal(0)
+	mock.ExpectMemoryUsage(changesKey).RedisNil()
+	mock.ExpectZRange(changesKey, 0, 0).SetVal([]string{})
+	mock.ExpectZRange(changesKey, -1, -1).SetVal([]string{})
+	mock.ExpectTTL(changesKey).SetVal(-2 * time.Nanosecond)
+
+	stats, err = kvBuffer.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats != (Stats{}) {
+		t.Errorf("Stats() = %+v, want zero", stats)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestKVBuffer_ChangesBetween(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer db.Close()
+	kvBuffer := &KVBuffer{client: db}
+	ctx := context.Background()
+
+	change := `{"type":"dml","position":"0/100"}`
+	mock.ExpectZRangeByScore(changesKey, &redis.ZRangeBy{Min: "256", Max: "+inf", Offset: 10, Count: 5}).
+		SetVal([]string{change})
+
+	changes, err := kvBuffer.ChangesBetween(ctx, "0/100", "", 10, 5)
+	if err != nil {
+		t.Fatalf("ChangesBetween() error = %v", err)
+	}
+	if len(changes) != 1 || string(changes[0]) != change {
+		t.Errorf("ChangesBetween() = %s, want [%s]", changes, change)
+	}
+
+	if _, err := kvBuffer.ChangesBetween(ctx, "not a position", "", 0, 5); err == nil {
+		t.Error("ChangesBetween() should fail for an invalid position")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestKVBuffer_DeleteBetween(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer db.Close()
+	kvBuffer := &KVBuffer{client: db}
+	ctx := context.Background()
+
+	mock.ExpectZCount(changesKey, "-inf", "512").SetVal(3)
+	mock.ExpectZRemRangeByScore(changesKey, "256", "512").SetVal(2)
+
+	count, err := kvBuffer.CountBetween(ctx, "", "0/200")
+	if err != nil {
+		t.Fatalf("CountBetween() error = %v", err)
+	}
+	if count != 3 {
+		t.Errorf("CountBetween() = %d, want 3", count)
+	}
+
+	removed, err := kvBuffer.DeleteBetween(ctx, "0/100", "0/200")
+	if err != nil {
+		t.Fatalf("DeleteBetween() error = %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("DeleteBetween() = %d, want 2", removed)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package kvbuffer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// manifestsKey holds the manifests of the batches written with AddBatch, by first position
+const manifestsKey = "kasho:bootstrap:manifests"
+
+// BatchManifest describes a batch of changes written with AddBatch, so the stored changes
+// can be checked against it later
+type BatchManifest struct {
+	Table         string `json:"table,omitempty"` // table of the batch's rows, empty for DDL
+	FirstPosition string `json:"first_position"`
+	LastPosition  string `json:"last_position"`
+	Count         int    `json:"count"`
+	Checksum      string `json:"checksum"` // SHA-256 of the changes' JSON, in order
+}
+
+// BatchFailure is a batch whose stored changes don't match its manifest
+type BatchFailure struct {
+	Manifest BatchManifest
+	Reason   string
+}
+
+// BatchVerification is the result of VerifyBatches
+type BatchVerification struct {
+	Verified int // batches whose stored changes match their manifest
+	Failed   []BatchFailure
+}
+
+// addBatchScript adds each change that isn't in the buffer yet and publishes it, like
+// addChangesScript, and records the batch's manifest, all in one atomic step. It returns
+// the number of changes added and of duplicates skipped.
+// KEYS: changes sorted set, changes channel, manifests hash. ARGV: TTL in seconds, manifest
+// ID, manifest, then score/member pairs.
+const addBatchScript = `
+local added, duplicates = 0, 0
+for i = 4, #ARGV, 2 do
+	if redis.call('ZADD', KEYS[1], 'NX', ARGV[i], ARGV[i + 1]) == 1 then
+		redis.call('PUBLISH', KEYS[2], ARGV[i + 1])
+		added = added + 1
+	else
+		duplicates = duplicates + 1
+	end
+end
+redis.call('HSET', KEYS[3], ARGV[2], ARGV[3])
+redis.call('EXPIRE', KEYS[1], ARGV[1])
+redis.call('EXPIRE', KEYS[3], ARGV[1])
+return {added, duplicates}
+`
+
+// AddBatch writes changes and their manifest in one atomic step, and checks that Redis
+// acknowledged every change as added or as already stored. A batch that fails can be
+// written again: the changes stored by an earlier attempt are skipped as duplicates.
+// Changes queued by AddChange are flushed first, so they stay in order.
+func (b *KVBuffer) AddBatch(ctx context.Context, table string, changes []Change) (BatchManifest, error) {
+	if len(changes) == 0 {
+		return BatchManifest{}, nil
+	}
+	if err := b.Flush(ctx); err != nil {
+		return BatchManifest{}, err
+	}
+
+	manifest := BatchManifest{
+		Table:         table,
+		FirstPosition: changes[0].GetPosition(),
+		LastPosition:  changes[len(changes)-1].GetPosition(),
+		Count:         len(changes),
+	}
+	hash := sha256.New()
+	args := make([]any, 3, 3+2*len(changes))
+	for _, change := range changes {
+		score, err := b.parsePositionToScore(change.GetPosition())
+		if err != nil {
+			return BatchManifest{}, fmt.Errorf("failed to parse position: %w", err)
+		}
+		data, err := json.Marshal(change)
+		if err != nil {
+			return BatchManifest{}, fmt.Errorf("failed to marshal change: %w", err)
+		}
+		hash.Write(data)
+		args = append(args, score, data)
+	}
+	manifest.Checksum = hex.EncodeToString(hash.Sum(nil))
+
+	manifestData, err := json.Marshal(manifest)
+	if err != nil {
+		return BatchManifest{}, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	args[0], args[1], args[2] = int64(changesTTL.Seconds()), manifest.FirstPosition, manifestData
+
+	counts, err := b.client.Eval(ctx, addBatchScript, []string{changesKey, changesChannel, manifestsKey}, args...).Int64Slice()
+	if err != nil {
+		return BatchManifest{}, fmt.Errorf("failed to write batch of %d changes to KV: %w", len(changes), err)
+	}
+	if len(counts) != 2 || counts[0]+counts[1] != int64(len(changes)) {
+		return BatchManifest{}, fmt.Errorf("batch of %d changes at %s was not fully acknowledged: %v", len(changes), manifest.FirstPosition, counts)
+	}
+	if counts[1] > 0 {
+		b.countDuplicates(counts[1], manifest.LastPosition)
+	}
+	return manifest, nil
+}
+
+// VerifyBatches re-reads the changes of every batch written with AddBatch and checks their
+// number and checksum against the batch's manifest. Changes spilled to disk aren't read, so
+// it is meant for buffers filled by a bootstrap, which doesn't spill.
+func (b *KVBuffer) VerifyBatches(ctx context.Context) (BatchVerification, error) {
+	var result BatchVerification
+
+	entries, err := b.client.HGetAll(ctx, manifestsKey).Result()
+	if err != nil {
+		return result, fmt.Errorf("failed to read batch manifests: %w", err)
+	}
+
+	manifests := make([]BatchManifest, 0, len(entries))
+	for id, data := range entries {
+		var manifest BatchManifest
+		if err := json.Unmarshal([]byte(data), &manifest); err != nil {
+			return result, fmt.Errorf("invalid manifest for batch %s: %w", id, err)
+		}
+		manifests = append(manifests, manifest)
+	}
+	scores := make(map[string]float64, len(manifests))
+	for _, manifest := range manifests {
+		for _, position := range []string{manifest.FirstPosition, manifest.LastPosition} {
+			score, err := b.parsePositionToScore(position)
+			if err != nil {
+				return result, fmt.Errorf("invalid position in batch manifest: %w", err)
+			}
+			scores[position] = score
+		}
+	}
+	slices.SortFunc(manifests, func(a, b BatchManifest) int {
+		return compareScores(scores[a.FirstPosition], scores[b.FirstPosition])
+	})
+
+	for _, manifest := range manifests {
+		members, err := b.client.ZRangeByScore(ctx, changesKey, &redis.ZRangeBy{
+			Min: strconv.FormatFloat(scores[manifest.FirstPosition], 'f', -1, 64),
+			Max: strconv.FormatFloat(scores[manifest.LastPosition], 'f', -1, 64),
+		}).Result()
+		if err != nil {
+			return result, fmt.Errorf("failed to read batch at %s: %w", manifest.FirstPosition, err)
+		}
+
+		if len(members) != manifest.Count {
+			result.Failed = append(result.Failed, BatchFailure{Manifest: manifest, Reason: fmt.Sprintf("found %d of %d changes", len(members), manifest.Count)})
+			continue
+		}
+		hash := sha256.New()
+		for _, member := range members {
+			hash.Write([]byte(member))
+		}
+		if hex.EncodeToString(hash.Sum(nil)) != manifest.Checksum {
+			result.Failed = append(result.Failed, BatchFailure{Manifest: manifest, Reason: "checksum mismatch"})
+			continue
+		}
+		result.Verified++
+	}
+	return result, nil
+}
+
+func compareScores(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,118 @@
+package kvbuffer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestKVBuffer_AddBatch(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer db.Close()
+	kvBuffer := &KVBuffer{client: db}
+	ctx := context.Background()
+
+	first := TestChange{Position: "0/BOOTSTRAP0000000000000001", Data: TestDMLData{Table: "users", Kind: "insert"}}
+	second := TestChange{Position: "0/BOOTSTRAP0000000000000002", Data: TestDMLData{Table: "users", Kind: "insert"}}
+	firstData, _ := json.Marshal(first)
+	secondData, _ := json.Marshal(second)
+	sum := sha256.Sum256(append(append([]byte{}, firstData...), secondData...))
+	want := BatchManifest{
+		Table:         "users",
+		FirstPosition: first.Position,
+		LastPosition:  second.Position,
+		Count:         2,
+		Checksum:      hex.EncodeToString(sum[:]),
+	}
+	manifestData, _ := json.Marshal(want)
+	keys := []string{changesKey, changesChannel, manifestsKey}
+
+	// The first change was stored by an earlier attempt
+	mock.ExpectEval(addBatchScript, keys, int64(86400), first.Position, manifestData, float64(-999999), firstData, float64(-999998), secondData).
+		SetVal([]interface{}{int64(1), int64(1)})
+
+	got, err := kvBuffer.AddBatch(ctx, "users", []Change{first, second})
+	if err != nil {
+		t.Fatalf("AddBatch() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("AddBatch() = %+v, want %+v", got, want)
+	}
+	if got := kvBuffer.Duplicates(); got != 1 {
+		t.Errorf("Duplicates() = %d, want 1", got)
+	}
+
+	// A reply that doesn't account for every change is an error
+	mock.ExpectEval(addBatchScript, keys, int64(86400), first.Position, manifestData, float64(-999999), firstData, float64(-999998), secondData).
+		SetVal([]interface{}{int64(1), int64(0)})
+	if _, err := kvBuffer.AddBatch(ctx, "users", []Change{first, second}); err == nil {
+		t.Error("AddBatch() should fail when not every change is acknowledged")
+	}
+
+	mock.ExpectEval(addBatchScript, keys, int64(86400), first.Position, manifestData, float64(-999999), firstData, float64(-999998), secondData).
+		SetErr(errors.New("connection reset"))
+	if _, err := kvBuffer.AddBatch(ctx, "users", []Change{first, second}); err == nil {
+		t.Error("AddBatch() should fail when the write fails")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestKVBuffer_VerifyBatches(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	defer db.Close()
+	kvBuffer := &KVBuffer{client: db}
+	ctx := context.Background()
+
+	checksum := func(members ...string) string {
+		hash := sha256.New()
+		for _, member := range members {
+			hash.Write([]byte(member))
+		}
+		return hex.EncodeToString(hash.Sum(nil))
+	}
+	manifests := []BatchManifest{
+		{Table: "users", FirstPosition: "0/BOOTSTRAP0000000000000001", LastPosition: "0/BOOTSTRAP0000000000000002", Count: 2, Checksum: checksum("a", "b")},
+		{Table: "orders", FirstPosition: "0/BOOTSTRAP0000000000000003", LastPosition: "0/BOOTSTRAP0000000000000004", Count: 2, Checksum: checksum("c", "d")},
+		{FirstPosition: "0/BOOTSTRAP0000000000000005", LastPosition: "0/BOOTSTRAP0000000000000005", Count: 1, Checksum: checksum("e")},
+	}
+	entries := make(map[string]string)
+	for _, manifest := range manifests {
+		data, _ := json.Marshal(manifest)
+		entries[manifest.FirstPosition] = string(data)
+	}
+
+	mock.ExpectHGetAll(manifestsKey).SetVal(entries)
+	mock.ExpectZRangeByScore(changesKey, &redis.ZRangeBy{Min: "-999999", Max: "-999998"}).SetVal([]string{"a", "b"})
+	// A row of the second batch went missing
+	mock.ExpectZRangeByScore(changesKey, &redis.ZRangeBy{Min: "-999997", Max: "-999996"}).SetVal([]string{"c"})
+	mock.ExpectZRangeByScore(changesKey, &redis.ZRangeBy{Min: "-999995", Max: "-999995"}).SetVal([]string{"x"})
+
+	result, err := kvBuffer.VerifyBatches(ctx)
+	if err != nil {
+		t.Fatalf("VerifyBatches() error = %v", err)
+	}
+	if result.Verified != 1 {
+		t.Errorf("Verified = %d, want 1", result.Verified)
+	}
+	if len(result.Failed) != 2 {
+		t.Fatalf("Failed = %+v, want 2 batches", result.Failed)
+	}
+	if result.Failed[0].Manifest.Table != "orders" || result.Failed[0].Reason != "found 1 of 2 changes" {
+		t.Errorf("Failed[0] = %+v", result.Failed[0])
+	}
+	if result.Failed[1].Reason != "checksum mismatch" {
+		t.Errorf("Failed[1] = %+v", result.Failed[1])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,447 @@
+package kvbuffer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	spillCheckInterval = 5 * time.Second
+	// spillBatchSize is how many of the oldest changes are moved to disk at a time
+	spillBatchSize = 10000
+	// spillLowWater is the share of the memory threshold spilling brings Redis back under
+	spillLowWater = 0.9
+	// maxSegmentSize is the size at which a new segment file is started
+	maxSegmentSize = 64 << 20
+	segmentPattern = "segment-*.log"
+)
+
+// spillEntry locates a spilled change in its segment file
+type spillEntry struct {
+	score   float64
+	segment int   // index into spill.segments
+	offset  int64 // offset of the change's JSON in the segment
+	length  int
+}
+
+// spill holds the changes moved out of Redis in append-only segment files. Each line
+// of a segment is a change's score and its JSON, separated by a tab.
+type spill struct {
+	dir       string
+	maxMemory int64
+
+	mu       sync.RWMutex
+	entries  []spillEntry // sorted by score; changes with equal scores keep their spill order
+	segments []*os.File   // the last one is appended to
+	size     int64        // size of the last segment
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// EnableSpill moves the oldest changes from Redis to append-only segment files in dir
+// whenever Redis uses more than maxMemory bytes, until it is back under 90% of it.
+// Spilled changes are read back transparently by GetChangesAfterBatch and counted by
+// Depth. Segments left in dir by an earlier run are loaded, and they are removed once
+// the buffer in Redis has expired.
+func (b *KVBuffer) EnableSpill(dir string, maxMemory int64) error {
+	if maxMemory <= 0 || b.spill != nil {
+		return nil
+	}
+	s, err := openSpill(dir, maxMemory)
+	if err != nil {
+		return err
+	}
+	b.spill = s
+	go b.spillPeriodically()
+	return nil
+}
+
+// Spilled returns the number of changes held on disk
+func (b *KVBuffer) Spilled() int64 {
+	if b.spill == nil {
+		return 0
+	}
+	b.spill.mu.RLock()
+	defer b.spill.mu.RUnlock()
+	return int64(len(b.spill.entries))
+}
+
+// openSpill opens the segment directory and indexes the segments already in it
+func openSpill(dir string, maxMemory int64) (*spill, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+	s := &spill{
+		dir:       dir,
+		maxMemory: maxMemory,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, segmentPattern))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err := s.loadSegment(path); err != nil {
+			s.close()
+			return nil, err
+		}
+	}
+	sort.SliceStable(s.entries, func(i, j int) bool { return s.entries[i].score < s.entries[j].score })
+	if len(s.entries) > 0 {
+		log.Printf("Loaded %d spilled changes from %d segment(s) in %s", len(s.entries), len(s.segments), dir)
+	}
+	return s, nil
+}
+
+// loadSegment indexes a segment file, truncating a partial last line left by a crash
+func (s *spill) loadSegment(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open spill segment: %w", err)
+	}
+	segment := len(s.segments)
+	s.segments = append(s.segments, f)
+
+	reader := bufio.NewReader(f)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				log.Printf("Truncating partial record at the end of %s", path)
+				if err := f.Truncate(offset); err != nil {
+					return fmt.Errorf("failed to truncate spill segment: %w", err)
+				}
+			}
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read spill segment: %w", err)
+		}
+		scoreText, data, ok := bytes.Cut(line, []byte{'\t'})
+		if !ok {
+			return fmt.Errorf("invalid record in spill segment %s at offset %d", path, offset)
+		}
+		score, err := strconv.ParseFloat(string(scoreText), 64)
+		if err != nil {
+			return fmt.Errorf("invalid score in spill segment %s at offset %d", path, offset)
+		}
+		s.entries = append(s.entries, spillEntry{
+			score:   score,
+			segment: segment,
+			offset:  offset + int64(len(scoreText)) + 1,
+			length:  len(data) - 1,
+		})
+		offset += int64(len(line))
+	}
+	s.size = offset
+	return nil
+}
+
+// append writes changes to the last segment, starting a new one when it is full, and
+// syncs them to disk before they are indexed
+func (s *spill) append(changes []redis.Z) error {
+	if len(s.segments) == 0 || s.size >= maxSegmentSize {
+		path := filepath.Join(s.dir, fmt.Sprintf("segment-%016d.log", time.Now().UnixNano()))
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to create spill segment: %w", err)
+		}
+		s.segments = append(s.segments, f)
+		s.size = 0
+	}
+	segment := len(s.segments) - 1
+
+	var buf bytes.Buffer
+	entries := make([]spillEntry, 0, len(changes))
+	for _, z := range changes {
+		data := z.Member.(string)
+		scoreText := strconv.FormatFloat(z.Score, 'g', -1, 64)
+		entries = append(entries, spillEntry{
+			score:   z.Score,
+			segment: segment,
+			offset:  s.size + int64(buf.Len()+len(scoreText)+1),
+			length:  len(data),
+		})
+		buf.WriteString(scoreText)
+		buf.WriteByte('\t')
+		buf.WriteString(data)
+		buf.WriteByte('\n')
+	}
+
+	f := s.segments[segment]
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		// Drop what was written so the segment stays whole
+		f.Truncate(s.size)
+		return fmt.Errorf("failed to write spill segment: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Truncate(s.size)
+		return fmt.Errorf("failed to sync spill segment: %w", err)
+	}
+	s.size += int64(buf.Len())
+
+	sorted := len(s.entries) == 0 || s.entries[len(s.entries)-1].score <= entries[0].score
+	s.entries = append(s.entries, entries...)
+	if !sorted {
+		sort.SliceStable(s.entries, func(i, j int) bool { return s.entries[i].score < s.entries[j].score })
+	}
+	return nil
+}
+
+// read returns the JSON of a spilled change
+func (s *spill) read(entry spillEntry) (json.RawMessage, error) {
+	data := make([]byte, entry.length)
+	if _, err := s.segments[entry.segment].ReadAt(data, entry.offset); err != nil {
+		return nil, fmt.Errorf("failed to read spilled change: %w", err)
+	}
+	return json.RawMessage(data), nil
+}
+
+// after returns the index of the first entry after score, or 0 when all are included
+func (s *spill) after(score float64, all bool) int {
+	if all {
+		return 0
+	}
+	return sort.Search(len(s.entries), func(i int) bool { return s.entries[i].score > score })
+}
+
+// reset removes every segment
+func (s *spill) reset() error {
+	for _, f := range s.segments {
+		f.Close()
+		if err := os.Remove(f.Name()); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove spill segment: %w", err)
+		}
+	}
+	s.segments = nil
+	s.entries = nil
+	s.size = 0
+	return nil
+}
+
+func (s *spill) close() {
+	for _, f := range s.segments {
+		f.Close()
+	}
+}
+
+// spillIfNeeded moves the oldest changes to disk while Redis uses more memory than
+// allowed, and removes the segments once the buffer in Redis has expired
+func (b *KVBuffer) spillIfNeeded(ctx context.Context) error {
+	if b.spill == nil {
+		return nil
+	}
+
+	exists, err := b.client.Exists(ctx, changesKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check buffer: %w", err)
+	}
+	if exists == 0 {
+		b.spill.mu.Lock()
+		defer b.spill.mu.Unlock()
+		if len(b.spill.entries) == 0 {
+			return nil
+		}
+		log.Printf("KV buffer expired, removing %d spilled changes", len(b.spill.entries))
+		return b.spill.reset()
+	}
+
+	used, err := b.usedMemory(ctx)
+	if err != nil {
+		return err
+	}
+	if used < b.spill.maxMemory {
+		return nil
+	}
+
+	spilled := 0
+	for used >= int64(float64(b.spill.maxMemory)*spillLowWater) {
+		n, err := b.spillOldest(ctx, spillBatchSize)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		spilled += n
+		if used, err = b.usedMemory(ctx); err != nil {
+			return err
+		}
+	}
+	log.Printf("Spilled %d changes to %s; Redis uses %d bytes", spilled, b.spill.dir, used)
+	return nil
+}
+
+// spillOldest moves up to n of the oldest changes from Redis to disk. The newest change
+// stays in Redis, so the buffer's key and its TTL live on.
+func (b *KVBuffer) spillOldest(ctx context.Context, n int64) (int, error) {
+	count, err := b.client.ZCard(ctx, changesKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get buffer depth: %w", err)
+	}
+	if n = min(n, count-1); n <= 0 {
+		return 0, nil
+	}
+	changes, err := b.client.ZRangeWithScores(ctx, changesKey, 0, n-1).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get oldest changes from KV: %w", err)
+	}
+	if len(changes) == 0 {
+		return 0, nil
+	}
+
+	// Readers see the changes either in Redis or on disk, never in neither
+	b.spill.mu.Lock()
+	defer b.spill.mu.Unlock()
+
+	if err := b.spill.append(changes); err != nil {
+		return 0, err
+	}
+	members := make([]any, len(changes))
+	for i, z := range changes {
+		members[i] = z.Member
+	}
+	if err := b.client.ZRem(ctx, changesKey, members...).Err(); err != nil {
+		return 0, fmt.Errorf("failed to remove spilled changes from KV: %w", err)
+	}
+	return len(changes), nil
+}
+
+// usedMemory returns the memory Redis reports using
+func (b *KVBuffer) usedMemory(ctx context.Context) (int64, error) {
+	info, err := b.client.Info(ctx, "memory").Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get KV memory usage: %w", err)
+	}
+	for _, line := range strings.Split(info, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), "used_memory:"); ok {
+			return strconv.ParseInt(value, 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("KV memory usage not reported")
+}
+
+// getSpilledChangesAfter returns a batch of changes after minScore from disk and Redis
+// together, in score order
+func (b *KVBuffer) getSpilledChangesAfter(ctx context.Context, score float64, all bool, minScore string, offset, limit int64) ([]json.RawMessage, error) {
+	b.spill.mu.RLock()
+	defer b.spill.mu.RUnlock()
+
+	disk := b.spill.entries[b.spill.after(score, all):]
+	if len(disk) == 0 {
+		return b.getChangesAfter(ctx, minScore, offset, limit)
+	}
+
+	// Usually everything on disk is older than what is left in Redis, and the two are
+	// read one after the other
+	oldest, err := b.client.ZRangeWithScores(ctx, changesKey, 0, 0).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get changes from KV: %w", err)
+	}
+	if len(oldest) == 0 || disk[len(disk)-1].score < oldest[0].Score {
+		var changes []json.RawMessage
+		if offset < int64(len(disk)) {
+			end := min(offset+limit, int64(len(disk)))
+			for _, entry := range disk[offset:end] {
+				data, err := b.spill.read(entry)
+				if err != nil {
+					return nil, err
+				}
+				changes = append(changes, data)
+			}
+		}
+		if rest := limit - int64(len(changes)); rest > 0 {
+			more, err := b.getChangesAfter(ctx, minScore, max(offset-int64(len(disk)), 0), rest)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, more...)
+		}
+		return changes, nil
+	}
+
+	// Otherwise merge the first offset+limit changes of each by score, disk first on ties
+	results, err := b.client.ZRangeByScoreWithScores(ctx, changesKey, &redis.ZRangeBy{
+		Min:   minScore,
+		Max:   "+inf",
+		Count: offset + limit,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get changes from KV: %w", err)
+	}
+	var changes []json.RawMessage
+	i, j := 0, 0
+	for n := int64(0); n < offset+limit && (i < len(disk) || j < len(results)); n++ {
+		fromDisk := j == len(results) || (i < len(disk) && disk[i].score <= results[j].Score)
+		if n < offset {
+			if fromDisk {
+				i++
+			} else {
+				j++
+			}
+			continue
+		}
+		if fromDisk {
+			data, err := b.spill.read(disk[i])
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, data)
+			i++
+		} else {
+			changes = append(changes, json.RawMessage(results[j].Member.(string)))
+			j++
+		}
+	}
+	return changes, nil
+}
+
+// spillPeriodically checks Redis memory until the buffer is closed
+func (b *KVBuffer) spillPeriodically() {
+	defer close(b.spill.done)
+
+	ticker := time.NewTicker(spillCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.spill.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			if err := b.spillIfNeeded(ctx); err != nil {
+				log.Printf("Failed to spill KV buffer: %v", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// closeSpill stops the periodic check and closes the segments
+func (b *KVBuffer) closeSpill() {
+	close(b.spill.stop)
+	<-b.spill.done
+
+	b.spill.mu.Lock()
+	defer b.spill.mu.Unlock()
+	b.spill.close()
+}
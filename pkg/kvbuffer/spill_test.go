@@ -0,0 +1,137 @@
+package kvbuffer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+)
+
+func newSpillingBuffer(t *testing.T, dir string) (*KVBuffer, redismock.ClientMock) {
+	t.Helper()
+	db, mock := redismock.NewClientMock()
+	s, err := openSpill(dir, 1)
+	if err != nil {
+		t.Fatalf("openSpill() error = %v", err)
+	}
+	t.Cleanup(func() {
+		s.close()
+		db.Close()
+	})
+	return &KVBuffer{client: db, spill: s}, mock
+}
+
+func rawStrings(changes []json.RawMessage) []string {
+	var strs []string
+	for _, change := range changes {
+		strs = append(strs, string(change))
+	}
+	return strs
+}
+
+func TestKVBuffer_SpillOldest(t *testing.T) {
+	kvBuffer, mock := newSpillingBuffer(t, t.TempDir())
+	ctx := context.Background()
+
+	first := redis.Z{Score: 256, Member: `{"position":"0/100"}`}
+	second := redis.Z{Score: 512, Member: `{"position":"0/200"}`}
+	third := redis.Z{Score: 768, Member: `{"position":"0/300"}`}
+
+	// The newest change stays in Redis
+	mock.ExpectZCard(changesKey).SetVal(3)
+	mock.ExpectZRangeWithScores(changesKey, 0, 1).SetVal([]redis.Z{first, second})
+	mock.ExpectZRem(changesKey, first.Member, second.Member).SetVal(2)
+	n, err := kvBuffer.spillOldest(ctx, 10)
+	if err != nil {
+		t.Fatalf("spillOldest() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("spillOldest() = %d, want 2", n)
+	}
+
+	// Spilled changes are read back ahead of those still in Redis
+	mock.ExpectZRangeWithScores(changesKey, 0, 0).SetVal([]redis.Z{third})
+	mock.ExpectZRangeByScore(changesKey, &redis.ZRangeBy{Min: "(256", Max: "+inf", Offset: 0, Count: 9}).SetVal([]string{third.Member.(string)})
+	changes, err := kvBuffer.GetChangesAfterBatch(ctx, "0/100", 0, 10)
+	if err != nil {
+		t.Fatalf("GetChangesAfterBatch() error = %v", err)
+	}
+	got := rawStrings(changes)
+	want := []string{second.Member.(string), third.Member.(string)}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("GetChangesAfterBatch() = %v, want %v", got, want)
+	}
+
+	mock.ExpectZCard(changesKey).SetVal(1)
+	if depth, err := kvBuffer.Depth(ctx); err != nil || depth != 3 {
+		t.Errorf("Depth() = %d, %v, want 3", depth, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %v", err)
+	}
+}
+
+func TestKVBuffer_GetChangesAfterBatch_MergesSpill(t *testing.T) {
+	kvBuffer, mock := newSpillingBuffer(t, t.TempDir())
+	ctx := context.Background()
+
+	// A bootstrap change buffered after newer changes were spilled
+	if err := kvBuffer.spill.append([]redis.Z{{Score: 256, Member: `"a"`}, {Score: 768, Member: `"c"`}}); err != nil {
+		t.Fatalf("append() error = %v", err)
+	}
+	mock.ExpectZRangeWithScores(changesKey, 0, 0).SetVal([]redis.Z{{Score: -999999, Member: `"bootstrap"`}})
+	mock.ExpectZRangeByScoreWithScores(changesKey, &redis.ZRangeBy{Min: "-inf", Max: "+inf", Count: 3}).
+		SetVal([]redis.Z{{Score: -999999, Member: `"bootstrap"`}, {Score: 512, Member: `"b"`}})
+
+	changes, err := kvBuffer.GetChangesAfterBatch(ctx, "bootstrap", 1, 2)
+	if err != nil {
+		t.Fatalf("GetChangesAfterBatch() error = %v", err)
+	}
+	got := rawStrings(changes)
+	if len(got) != 2 || got[0] != `"a"` || got[1] != `"b"` {
+		t.Errorf("GetChangesAfterBatch() = %v, want [\"a\" \"b\"]", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expectations were not met: %v", err)
+	}
+}
+
+func TestOpenSpill_LoadsSegments(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "segment-0000000000000001.log")
+	// The last record was cut short by a crash
+	if err := os.WriteFile(path, []byte("512\t\"b\"\n256\t\"a\"\n768\t\"c"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := openSpill(dir, 1)
+	if err != nil {
+		t.Fatalf("openSpill() error = %v", err)
+	}
+	defer s.close()
+
+	if len(s.entries) != 2 {
+		t.Fatalf("openSpill() loaded %d entries, want 2", len(s.entries))
+	}
+	for i, want := range []string{`"a"`, `"b"`} {
+		data, err := s.read(s.entries[i])
+		if err != nil {
+			t.Fatalf("read() error = %v", err)
+		}
+		if string(data) != want {
+			t.Errorf("entry %d = %s, want %s", i, data, want)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len("512\t\"b\"\n256\t\"a\"\n")) {
+		t.Errorf("segment size = %d, want the partial record truncated", info.Size())
+	}
+}
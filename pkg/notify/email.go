@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Email sends each event as a plain-text message over SMTP
+type Email struct {
+	Addr     string // host:port
+	Username string // optional; enables PLAIN auth
+	Password string
+	From     string
+	To       []string
+}
+
+// Notify sends the event. The context is not used: net/smtp has no cancellation support.
+func (e *Email) Notify(_ context.Context, event Event) error {
+	var auth smtp.Auth
+	if e.Username != "" {
+		host, _, err := net.SplitHostPort(e.Addr)
+		if err != nil {
+			return fmt.Errorf("email notification failed: invalid SMTP address %q: %w", e.Addr, err)
+		}
+		auth = smtp.PlainAuth("", e.Username, e.Password, host)
+	}
+
+	if err := smtp.SendMail(e.Addr, auth, e.From, e.To, e.message(event)); err != nil {
+		return fmt.Errorf("email notification failed: %w", err)
+	}
+	return nil
+}
+
+// message renders the RFC 5322 message for an event
+func (e *Email) message(event Event) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", e.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&b, "Subject: [kasho] %s\r\n", event.Summary())
+	fmt.Fprintf(&b, "Date: %s\r\n", event.Time.Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&b, "Service: %s\r\n", event.Service)
+	fmt.Fprintf(&b, "Alert: %s\r\n", event.Alert)
+	fmt.Fprintf(&b, "Status: %s\r\n", event.Status)
+	fmt.Fprintf(&b, "Value: %g (threshold %g)\r\n", event.Value, event.Threshold)
+	fmt.Fprintf(&b, "\r\n%s\r\n", event.Message)
+	return []byte(b.String())
+}
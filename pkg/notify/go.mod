@@ -0,0 +1,3 @@
+module kasho/pkg/notify
+
+go 1.24.3
@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Monitor tracks alert thresholds and notifies on transitions only: once when a value
+// reaches its threshold and once when it drops back below, so a stuck condition
+// doesn't page on every check.
+type Monitor struct {
+	service  string
+	notifier Notifier
+
+	mu     sync.Mutex
+	firing map[string]bool
+	now    func() time.Time
+}
+
+// NewMonitor creates a monitor that reports events for the given service
+func NewMonitor(service string, notifier Notifier) *Monitor {
+	return &Monitor{
+		service:  service,
+		notifier: notifier,
+		firing:   make(map[string]bool),
+		now:      time.Now,
+	}
+}
+
+// Check compares value against threshold for the named alert and sends a notification
+// if the alert started or stopped firing. A threshold of zero or less disables the alert.
+// Delivery failures are logged rather than returned so alerting never blocks replication.
+func (m *Monitor) Check(ctx context.Context, alert string, value, threshold float64, format string, args ...any) {
+	if m == nil || threshold <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	wasFiring := m.firing[alert]
+	isFiring := value >= threshold
+	m.firing[alert] = isFiring
+	m.mu.Unlock()
+
+	if wasFiring == isFiring {
+		return
+	}
+
+	status := Resolved
+	if isFiring {
+		status = Firing
+	}
+	event := Event{
+		Service:   m.service,
+		Alert:     alert,
+		Status:    status,
+		Message:   fmt.Sprintf(format, args...),
+		Value:     value,
+		Threshold: threshold,
+		Time:      m.now(),
+	}
+
+	log.Printf("Alert %s", event.Summary())
+	if err := m.notifier.Notify(ctx, event); err != nil {
+		log.Printf("Failed to send %s notification for %s: %v", status, alert, err)
+	}
+}
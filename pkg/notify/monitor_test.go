@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMonitorCheck(t *testing.T) {
+	recorder := &recordingNotifier{}
+	m := NewMonitor("pg-change-stream", recorder)
+	ctx := context.Background()
+
+	m.Check(ctx, "buffer_depth", 10, 100, "%d changes buffered", 10)
+	m.Check(ctx, "buffer_depth", 150, 100, "%d changes buffered", 150)
+	m.Check(ctx, "buffer_depth", 200, 100, "%d changes buffered", 200)
+	m.Check(ctx, "buffer_depth", 50, 100, "%d changes buffered", 50)
+	m.Check(ctx, "buffer_depth", 40, 100, "%d changes buffered", 40)
+
+	if len(recorder.events) != 2 {
+		t.Fatalf("expected 2 notifications (firing, resolved), got %d: %+v", len(recorder.events), recorder.events)
+	}
+
+	firing := recorder.events[0]
+	if firing.Status != Firing || firing.Value != 150 || firing.Message != "150 changes buffered" {
+		t.Errorf("unexpected firing event: %+v", firing)
+	}
+	if firing.Service != "pg-change-stream" || firing.Alert != "buffer_depth" || firing.Threshold != 100 {
+		t.Errorf("unexpected firing event: %+v", firing)
+	}
+
+	resolved := recorder.events[1]
+	if resolved.Status != Resolved || resolved.Value != 50 {
+		t.Errorf("unexpected resolved event: %+v", resolved)
+	}
+}
+
+func TestMonitorCheck_AlertsAreIndependent(t *testing.T) {
+	recorder := &recordingNotifier{}
+	m := NewMonitor("translicator", recorder)
+	ctx := context.Background()
+
+	m.Check(ctx, "replication_lag", 600, 300, "lag")
+	m.Check(ctx, "apply_errors", 5, 5, "errors")
+
+	if len(recorder.events) != 2 {
+		t.Errorf("expected one notification per alert, got %d", len(recorder.events))
+	}
+}
+
+func TestMonitorCheck_Disabled(t *testing.T) {
+	recorder := &recordingNotifier{}
+	m := NewMonitor("translicator", recorder)
+	m.Check(context.Background(), "replication_lag", 600, 0, "lag")
+
+	var nilMonitor *Monitor
+	nilMonitor.Check(context.Background(), "replication_lag", 600, 300, "lag")
+
+	if len(recorder.events) != 0 {
+		t.Errorf("disabled alerts should not notify, got %+v", recorder.events)
+	}
+}
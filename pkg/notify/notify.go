@@ -0,0 +1,112 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Status is the state an alert moved into
+type Status string
+
+const (
+	// Firing means the alert's value reached its threshold
+	Firing Status = "firing"
+	// Resolved means a firing alert's value dropped back below its threshold
+	Resolved Status = "resolved"
+)
+
+// Event describes an alert transition
+type Event struct {
+	Service   string    `json:"service"`
+	Alert     string    `json:"alert"`
+	Status    Status    `json:"status"`
+	Message   string    `json:"message"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	Time      time.Time `json:"time"`
+}
+
+// Summary returns a one-line description of the event, e.g. for chat messages and email subjects
+func (e Event) Summary() string {
+	return fmt.Sprintf("[%s] %s %s: %s", strings.ToUpper(string(e.Status)), e.Service, e.Alert, e.Message)
+}
+
+// Notifier delivers alert events to an external system
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Multi delivers every event to all of its notifiers
+type Multi []Notifier
+
+// Notify sends the event to each notifier, returning the combined errors of any that failed
+func (m Multi) Notify(ctx context.Context, event Event) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Config holds the notification targets; empty fields are disabled
+type Config struct {
+	WebhookURL      string
+	SlackWebhookURL string
+	SMTPAddr        string // host:port
+	SMTPUsername    string
+	SMTPPassword    string
+	EmailFrom       string
+	EmailTo         []string
+}
+
+// ConfigFromEnv reads the notification targets from NOTIFY_* environment variables
+func ConfigFromEnv() Config {
+	config := Config{
+		WebhookURL:      os.Getenv("NOTIFY_WEBHOOK_URL"),
+		SlackWebhookURL: os.Getenv("NOTIFY_SLACK_WEBHOOK_URL"),
+		SMTPAddr:        os.Getenv("NOTIFY_SMTP_ADDR"),
+		SMTPUsername:    os.Getenv("NOTIFY_SMTP_USERNAME"),
+		SMTPPassword:    os.Getenv("NOTIFY_SMTP_PASSWORD"),
+		EmailFrom:       os.Getenv("NOTIFY_EMAIL_FROM"),
+	}
+	for _, to := range strings.Split(os.Getenv("NOTIFY_EMAIL_TO"), ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			config.EmailTo = append(config.EmailTo, to)
+		}
+	}
+	return config
+}
+
+// New builds a notifier for every configured target. It returns nil when no target is configured.
+func New(config Config) (Notifier, error) {
+	var notifiers Multi
+	if config.WebhookURL != "" {
+		notifiers = append(notifiers, NewWebhook(config.WebhookURL))
+	}
+	if config.SlackWebhookURL != "" {
+		notifiers = append(notifiers, NewSlack(config.SlackWebhookURL))
+	}
+	if config.SMTPAddr != "" {
+		if config.EmailFrom == "" || len(config.EmailTo) == 0 {
+			return nil, fmt.Errorf("email notifications require a sender and at least one recipient")
+		}
+		notifiers = append(notifiers, &Email{
+			Addr:     config.SMTPAddr,
+			Username: config.SMTPUsername,
+			Password: config.SMTPPassword,
+			From:     config.EmailFrom,
+			To:       config.EmailTo,
+		})
+	}
+
+	if len(notifiers) == 0 {
+		return nil, nil
+	}
+	return notifiers, nil
+}
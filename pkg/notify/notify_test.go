@@ -0,0 +1,162 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testEvent() Event {
+	return Event{
+		Service:   "translicator",
+		Alert:     "replication_lag",
+		Status:    Firing,
+		Message:   "replica has not caught up in 5m0s",
+		Value:     300,
+		Threshold: 120,
+		Time:      time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func TestEventSummary(t *testing.T) {
+	want := "[FIRING] translicator replication_lag: replica has not caught up in 5m0s"
+	if got := testEvent().Summary(); got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+}
+
+func TestWebhookNotify(t *testing.T) {
+	var received Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode body: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	if err := NewWebhook(server.URL).Notify(context.Background(), testEvent()); err != nil {
+		t.Fatalf("Notify() unexpected error: %v", err)
+	}
+	if received != testEvent() {
+		t.Errorf("received %+v, want %+v", received, testEvent())
+	}
+}
+
+func TestWebhookNotify_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if err := NewWebhook(server.URL).Notify(context.Background(), testEvent()); err == nil {
+		t.Error("Notify() should fail on a non-2xx response")
+	}
+}
+
+func TestSlackNotify(t *testing.T) {
+	var received map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	if err := NewSlack(server.URL).Notify(context.Background(), testEvent()); err != nil {
+		t.Fatalf("Notify() unexpected error: %v", err)
+	}
+	if received["text"] != testEvent().Summary() {
+		t.Errorf("text = %q, want %q", received["text"], testEvent().Summary())
+	}
+}
+
+func TestEmailMessage(t *testing.T) {
+	e := &Email{Addr: "smtp.example.com:587", From: "kasho@example.com", To: []string{"ops@example.com", "dba@example.com"}}
+	msg := string(e.message(testEvent()))
+
+	for _, want := range []string{
+		"From: kasho@example.com\r\n",
+		"To: ops@example.com, dba@example.com\r\n",
+		"Subject: [kasho] [FIRING] translicator replication_lag: replica has not caught up in 5m0s\r\n",
+		"Value: 300 (threshold 120)\r\n",
+		"\r\n\r\nService: translicator",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message missing %q:\n%s", want, msg)
+		}
+	}
+}
+
+type recordingNotifier struct {
+	events []Event
+	err    error
+}
+
+func (r *recordingNotifier) Notify(_ context.Context, event Event) error {
+	r.events = append(r.events, event)
+	return r.err
+}
+
+func TestMultiNotify(t *testing.T) {
+	ok := &recordingNotifier{}
+	failing := &recordingNotifier{err: errors.New("boom")}
+
+	err := Multi{failing, ok}.Notify(context.Background(), testEvent())
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Notify() error = %v, want boom", err)
+	}
+	if len(ok.events) != 1 {
+		t.Error("a failing notifier should not prevent delivery to the others")
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		want    int
+		wantErr bool
+	}{
+		{name: "nothing configured", config: Config{}, want: 0},
+		{name: "webhook and slack", config: Config{WebhookURL: "http://a", SlackWebhookURL: "http://b"}, want: 2},
+		{name: "email", config: Config{SMTPAddr: "smtp:25", EmailFrom: "a@b", EmailTo: []string{"c@d"}}, want: 1},
+		{name: "email without recipients", config: Config{SMTPAddr: "smtp:25", EmailFrom: "a@b"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := New(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want == 0 {
+				if n != nil {
+					t.Errorf("New() = %v, want nil", n)
+				}
+				return
+			}
+			if multi, ok := n.(Multi); !ok || len(multi) != tt.want {
+				t.Errorf("New() = %v, want %d notifiers", n, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("NOTIFY_WEBHOOK_URL", "http://hooks.example.com")
+	t.Setenv("NOTIFY_EMAIL_TO", "ops@example.com, dba@example.com,")
+
+	config := ConfigFromEnv()
+	if config.WebhookURL != "http://hooks.example.com" {
+		t.Errorf("WebhookURL = %q", config.WebhookURL)
+	}
+	if len(config.EmailTo) != 2 || config.EmailTo[1] != "dba@example.com" {
+		t.Errorf("EmailTo = %v", config.EmailTo)
+	}
+}
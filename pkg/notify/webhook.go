@@ -0,0 +1,74 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const requestTimeout = 10 * time.Second
+
+// Webhook posts each event as JSON to a URL
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhook creates a webhook notifier with a request timeout
+func NewWebhook(url string) *Webhook {
+	return &Webhook{URL: url, Client: &http.Client{Timeout: requestTimeout}}
+}
+
+// Notify posts the event
+func (w *Webhook) Notify(ctx context.Context, event Event) error {
+	if err := postJSON(ctx, w.Client, w.URL, event); err != nil {
+		return fmt.Errorf("webhook notification failed: %w", err)
+	}
+	return nil
+}
+
+// Slack posts each event to a Slack incoming webhook
+type Slack struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewSlack creates a Slack notifier with a request timeout
+func NewSlack(url string) *Slack {
+	return &Slack{URL: url, Client: &http.Client{Timeout: requestTimeout}}
+}
+
+// Notify posts the event summary as a Slack message
+func (s *Slack) Notify(ctx context.Context, event Event) error {
+	if err := postJSON(ctx, s.Client, s.URL, map[string]string{"text": event.Summary()}); err != nil {
+		return fmt.Errorf("slack notification failed: %w", err)
+	}
+	return nil
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package streamserver
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"kasho/proto"
+)
+
+// AccumulationLimits bound the ACCUMULATING state. Zero disables a limit.
+type AccumulationLimits struct {
+	MaxChanges  int64         // Changes added to the buffer since accumulation began
+	MaxBytes    int64         // Bytes the buffer has grown by in Redis
+	MaxDuration time.Duration // Time spent accumulating
+	// Action is the state entered when a limit is reached: StateWaiting abandons the
+	// bootstrap, StateStreaming completes it as CompleteBootstrap would
+	Action State
+}
+
+// AccumulationLimitsFromEnv reads the limits from ACCUMULATION_MAX_CHANGES,
+// ACCUMULATION_MAX_BYTES, ACCUMULATION_MAX_DURATION and ACCUMULATION_LIMIT_ACTION
+func AccumulationLimitsFromEnv() (AccumulationLimits, error) {
+	limits := AccumulationLimits{Action: StateWaiting}
+	var err error
+	if value := os.Getenv("ACCUMULATION_MAX_CHANGES"); value != "" {
+		if limits.MaxChanges, err = strconv.ParseInt(value, 10, 64); err != nil || limits.MaxChanges < 0 {
+			return limits, fmt.Errorf("invalid ACCUMULATION_MAX_CHANGES: %q", value)
+		}
+	}
+	if value := os.Getenv("ACCUMULATION_MAX_BYTES"); value != "" {
+		if limits.MaxBytes, err = strconv.ParseInt(value, 10, 64); err != nil || limits.MaxBytes < 0 {
+			return limits, fmt.Errorf("invalid ACCUMULATION_MAX_BYTES: %q", value)
+		}
+	}
+	if value := os.Getenv("ACCUMULATION_MAX_DURATION"); value != "" {
+		if limits.MaxDuration, err = time.ParseDuration(value); err != nil || limits.MaxDuration < 0 {
+			return limits, fmt.Errorf("invalid ACCUMULATION_MAX_DURATION: %q", value)
+		}
+	}
+	switch action := strings.ToLower(os.Getenv("ACCUMULATION_LIMIT_ACTION")); action {
+	case "", "waiting":
+		limits.Action = StateWaiting
+	case "streaming":
+		limits.Action = StateStreaming
+	default:
+		return limits, fmt.Errorf("invalid ACCUMULATION_LIMIT_ACTION %q: must be waiting or streaming", action)
+	}
+	return limits, nil
+}
+
+// Enabled reports whether any limit is set
+func (l AccumulationLimits) Enabled() bool {
+	return l.MaxChanges > 0 || l.MaxBytes > 0 || l.MaxDuration > 0
+}
+
+// Exceeded describes the first limit an accumulation has reached, or returns ""
+func (l AccumulationLimits) Exceeded(changes, bytes int64, elapsed time.Duration) string {
+	switch {
+	case l.MaxChanges > 0 && changes >= l.MaxChanges:
+		return fmt.Sprintf("%d changes accumulated, limit %d", changes, l.MaxChanges)
+	case l.MaxBytes > 0 && bytes >= l.MaxBytes:
+		return fmt.Sprintf("%d bytes accumulated, limit %d", bytes, l.MaxBytes)
+	case l.MaxDuration > 0 && elapsed >= l.MaxDuration:
+		return fmt.Sprintf("accumulating for %s, limit %s", elapsed.Round(time.Second), l.MaxDuration)
+	}
+	return ""
+}
+
+// transitions lists the states a change stream can move to from a state
+func (l AccumulationLimits) transitions(state State) []*proto.StateTransition {
+	switch state {
+	case StateWaiting:
+		return []*proto.StateTransition{{To: StateAccumulating.String(), Trigger: "StartBootstrap"}}
+	case StateAccumulating:
+		transitions := []*proto.StateTransition{{To: StateStreaming.String(), Trigger: "CompleteBootstrap"}}
+		if l.Enabled() {
+			transitions = append(transitions, &proto.StateTransition{To: l.Action.String(), Trigger: "accumulation limit reached"})
+		}
+		return transitions
+	}
+	return nil
+}
+
+// StateResponse describes a state entered at transitionTime, the transitions it allows
+// and the limits. The accumulated counts are only reported while accumulating.
+func (l AccumulationLimits) StateResponse(state State, transitionTime time.Time, accumulatedChanges, accumulatedBytes int64) *proto.StateResponse {
+	response := &proto.StateResponse{
+		State:          state.String(),
+		Description:    state.Description(),
+		TransitionTime: transitionTime.Format(time.RFC3339),
+		Transitions:    l.transitions(state),
+		AccumulationLimits: &proto.AccumulationLimits{
+			MaxChanges:         l.MaxChanges,
+			MaxBytes:           l.MaxBytes,
+			MaxDurationSeconds: int64(l.MaxDuration.Seconds()),
+			Action:             l.Action.String(),
+		},
+	}
+	if state == StateAccumulating {
+		response.AccumulatedChanges = accumulatedChanges
+		response.AccumulatedBytes = accumulatedBytes
+		response.AccumulatingSeconds = int64(time.Since(transitionTime).Seconds())
+	}
+	return response
+}
+
+// Measurer reports how many changes a buffer holds and how many bytes it takes up
+type Measurer interface {
+	Depth(ctx context.Context) (int64, error)
+	Size(ctx context.Context) (int64, error)
+}
+
+// MeasureBuffer returns the depth and size of a buffer, or zeros if they can't be read
+func MeasureBuffer(ctx context.Context, buffer Measurer) (depth, size int64) {
+	depth, err := buffer.Depth(ctx)
+	if err != nil {
+		log.Printf("Failed to measure buffer depth: %v", err)
+		return 0, 0
+	}
+	size, err = buffer.Size(ctx)
+	if err != nil {
+		log.Printf("Failed to measure buffer size: %v", err)
+		return depth, 0
+	}
+	return depth, size
+}
@@ -0,0 +1,79 @@
+package streamserver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAccumulationLimitsExceeded(t *testing.T) {
+	limits := AccumulationLimits{MaxChanges: 100, MaxBytes: 1024, MaxDuration: time.Minute}
+	tests := []struct {
+		name     string
+		changes  int64
+		bytes    int64
+		elapsed  time.Duration
+		exceeded bool
+	}{
+		{"under all limits", 99, 1023, 59 * time.Second, false},
+		{"changes limit", 100, 0, 0, true},
+		{"bytes limit", 0, 1024, 0, true},
+		{"duration limit", 0, 0, time.Minute, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := limits.Exceeded(tt.changes, tt.bytes, tt.elapsed)
+			if (reason != "") != tt.exceeded {
+				t.Errorf("Exceeded(%d, %d, %v) = %q, want exceeded %v", tt.changes, tt.bytes, tt.elapsed, reason, tt.exceeded)
+			}
+		})
+	}
+
+	if reason := (AccumulationLimits{}).Exceeded(1<<40, 1<<40, 24*time.Hour); reason != "" {
+		t.Errorf("Exceeded() with no limits = %q, want \"\"", reason)
+	}
+}
+
+func TestAccumulationLimitsFromEnv(t *testing.T) {
+	t.Setenv("ACCUMULATION_MAX_CHANGES", "5000")
+	t.Setenv("ACCUMULATION_MAX_BYTES", "")
+	t.Setenv("ACCUMULATION_MAX_DURATION", "30m")
+	t.Setenv("ACCUMULATION_LIMIT_ACTION", "streaming")
+
+	limits, err := AccumulationLimitsFromEnv()
+	if err != nil {
+		t.Fatalf("AccumulationLimitsFromEnv() error = %v", err)
+	}
+	want := AccumulationLimits{MaxChanges: 5000, MaxDuration: 30 * time.Minute, Action: StateStreaming}
+	if limits != want {
+		t.Errorf("AccumulationLimitsFromEnv() = %+v, want %+v", limits, want)
+	}
+	if !limits.Enabled() {
+		t.Error("Enabled() = false, want true")
+	}
+
+	t.Setenv("ACCUMULATION_LIMIT_ACTION", "accumulating")
+	if _, err := AccumulationLimitsFromEnv(); err == nil {
+		t.Error("AccumulationLimitsFromEnv() with invalid action should fail")
+	}
+	t.Setenv("ACCUMULATION_LIMIT_ACTION", "")
+	t.Setenv("ACCUMULATION_MAX_CHANGES", "-1")
+	if _, err := AccumulationLimitsFromEnv(); err == nil {
+		t.Error("AccumulationLimitsFromEnv() with negative ACCUMULATION_MAX_CHANGES should fail")
+	}
+}
+
+func TestAccumulationLimitsTransitions(t *testing.T) {
+	if got := (AccumulationLimits{}).transitions(StateAccumulating); len(got) != 1 || got[0].To != "STREAMING" {
+		t.Errorf("transitions(ACCUMULATING) without limits = %v, want only STREAMING", got)
+	}
+
+	limits := AccumulationLimits{MaxChanges: 10, Action: StateWaiting}
+	got := limits.transitions(StateAccumulating)
+	if len(got) != 2 || got[1].To != "WAITING" {
+		t.Errorf("transitions(ACCUMULATING) with limits = %v, want STREAMING and WAITING", got)
+	}
+	if got := limits.transitions(StateStreaming); len(got) != 0 {
+		t.Errorf("transitions(STREAMING) = %v, want none", got)
+	}
+}
@@ -0,0 +1,247 @@
+package streamserver
+
+import (
+	"strings"
+
+	"kasho/pkg/types"
+)
+
+// ddlTables returns the tables a DDL change creates, alters, drops or references, and
+// false if any of its statements names no table, e.g. CREATE FUNCTION. Unqualified
+// names are in the schema the statement ran in: the change's schema (MySQL database),
+// or public for PostgreSQL, whose DDL doesn't record its search_path. PostgreSQL folds
+// unquoted names to lower case.
+func ddlTables(change types.Change, ddl string) ([]string, bool) {
+	schema := change.Schema
+	if schema == "" && change.Dialect == "postgresql" {
+		schema = "public"
+	}
+	if schema == "" {
+		schema = change.Database
+	}
+	fold := change.Dialect == "postgresql"
+
+	var tables []string
+	var statement []ddlToken
+	for _, token := range tokenizeDDL(ddl) {
+		if token.kind == ddlSpace {
+			continue
+		}
+		if token.kind != ddlPunct || token.text != ";" {
+			statement = append(statement, token)
+			continue
+		}
+		if len(statement) > 0 {
+			names := statementTables(statement, schema, fold)
+			if len(names) == 0 {
+				return nil, false
+			}
+			tables = append(tables, names...)
+		}
+		statement = statement[:0]
+	}
+	if len(statement) > 0 {
+		names := statementTables(statement, schema, fold)
+		if len(names) == 0 {
+			return nil, false
+		}
+		tables = append(tables, names...)
+	}
+	return tables, len(tables) > 0
+}
+
+// statementTables returns the tables named in one statement, found by the keywords
+// before them
+func statementTables(tokens []ddlToken, schema string, fold bool) []string {
+	at := func(k int) ddlToken {
+		if k < 0 || k >= len(tokens) {
+			return ddlToken{}
+		}
+		return tokens[k]
+	}
+	keyword := func(k int) string {
+		if token := at(k); token.kind == ddlWord {
+			return strings.ToUpper(token.text)
+		}
+		return ""
+	}
+	isName := func(k int) bool { return at(k).kind == ddlWord || at(k).kind == ddlQuoted }
+	dot := func(k int) bool { return at(k).kind == ddlPunct && at(k).text == "." }
+
+	var tables []string
+	// name adds the possibly qualified name starting at k and returns the index after it
+	name := func(k int) int {
+		if !isName(k) {
+			return k
+		}
+		parts := []string{identifier(at(k), fold)}
+		for k++; dot(k) && isName(k+1); k += 2 {
+			parts = append(parts, identifier(at(k+1), fold))
+		}
+		if len(parts) == 1 && schema != "" {
+			parts = []string{schema, parts[0]}
+		}
+		if len(parts) > 2 {
+			parts = parts[len(parts)-2:] // database.schema.table
+		}
+		tables = append(tables, strings.Join(parts, "."))
+		return k
+	}
+	// list adds further names of a DROP TABLE a, b or RENAME TABLE a TO b list
+	list := func(k int) {
+		for at(k).text == "," || keyword(k) == "TO" {
+			k = name(k + 1)
+		}
+	}
+
+	first := keyword(0)
+	index := first == "DROP" && keyword(1) == "INDEX" ||
+		first == "CREATE" && (keyword(1) == "INDEX" || keyword(2) == "INDEX")
+	for k := range tokens {
+		switch keyword(k) {
+		case "TABLE":
+			j := k + 1
+			for keyword(j) == "IF" || keyword(j) == "NOT" || keyword(j) == "EXISTS" || keyword(j) == "ONLY" {
+				j++
+			}
+			end := name(j)
+			// CREATE TABLE a LIKE b and CREATE TABLE a (LIKE b)
+			if keyword(end) == "LIKE" {
+				name(end + 1)
+			} else if at(end).text == "(" && keyword(end+1) == "LIKE" {
+				name(end + 2)
+			}
+			if first == "DROP" || first == "RENAME" {
+				list(end)
+			}
+		case "REFERENCES":
+			name(k + 1)
+		case "ON":
+			// The table of CREATE INDEX, and of MySQL's DROP INDEX
+			if index {
+				j := k + 1
+				if keyword(j) == "ONLY" {
+					j++
+				}
+				name(j)
+				index = false
+			}
+		case "TRUNCATE":
+			if k == 0 && keyword(1) != "TABLE" {
+				list(name(1))
+			}
+		case "RENAME":
+			// ALTER TABLE a RENAME [TO|AS] b, but not RENAME COLUMN, INDEX or KEY, or
+			// PostgreSQL's RENAME column TO name
+			if first != "ALTER" || k == 0 {
+				continue
+			}
+			switch keyword(k + 1) {
+			case "TO", "AS":
+				name(k + 2)
+			case "COLUMN", "INDEX", "KEY", "CONSTRAINT":
+			default:
+				if keyword(k+2) != "TO" {
+					name(k + 1)
+				}
+			}
+		}
+	}
+	return tables
+}
+
+type ddlTokenKind int
+
+const (
+	ddlSpace  ddlTokenKind = iota // whitespace and comments
+	ddlWord                       // keywords and unquoted identifiers
+	ddlQuoted                     // "quoted" or `quoted` identifiers
+	ddlString                     // 'string literals'
+	ddlPunct
+)
+
+type ddlToken struct {
+	kind ddlTokenKind
+	text string
+}
+
+// identifier returns the name of an identifier token without its quotes, folding an
+// unquoted one to lower case if fold is set
+func identifier(token ddlToken, fold bool) string {
+	if token.kind != ddlQuoted || len(token.text) < 2 {
+		if fold {
+			return strings.ToLower(token.text)
+		}
+		return token.text
+	}
+	q := token.text[:1]
+	return strings.ReplaceAll(token.text[1:len(token.text)-1], q+q, q)
+}
+
+// tokenizeDDL splits DDL into words, quoted identifiers, string literals, punctuation and
+// the space and comments between them
+func tokenizeDDL(s string) []ddlToken {
+	var tokens []ddlToken
+	for i := 0; i < len(s); {
+		start := i
+		kind := ddlPunct
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			kind = ddlSpace
+			for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r') {
+				i++
+			}
+		case strings.HasPrefix(s[i:], "--"):
+			kind = ddlSpace
+			if end := strings.IndexByte(s[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(s)
+			}
+		case strings.HasPrefix(s[i:], "/*"):
+			kind = ddlSpace
+			if end := strings.Index(s[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(s)
+			}
+		case c == '\'' || c == '"' || c == '`':
+			kind = ddlQuoted
+			if c == '\'' {
+				kind = ddlString
+			}
+			i = closingQuote(s, i)
+		case isWordByte(c):
+			kind = ddlWord
+			for i < len(s) && isWordByte(s[i]) {
+				i++
+			}
+		default:
+			i++
+		}
+		tokens = append(tokens, ddlToken{kind: kind, text: s[start:i]})
+	}
+	return tokens
+}
+
+// closingQuote returns the index after the quote closing the one at open. A doubled
+// quote is part of the quoted text.
+func closingQuote(s string, open int) int {
+	q := s[open]
+	for i := open + 1; i < len(s); i++ {
+		if s[i] != q {
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == q {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(s)
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '$' || c >= 0x80
+}
@@ -0,0 +1,58 @@
+package streamserver
+
+import (
+	"fmt"
+	"path"
+	"slices"
+
+	"kasho/pkg/auth"
+	"kasho/pkg/types"
+	"kasho/proto"
+)
+
+// Filter applies the table and kind filters requested by a stream consumer
+type Filter struct {
+	includeTables []string
+	excludeTables []string
+	excludeKinds  []string
+}
+
+// NewFilter validates the filters of a stream request
+func NewFilter(req *proto.StreamRequest) (*Filter, error) {
+	for _, pattern := range slices.Concat(req.IncludeTables, req.ExcludeTables) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid table pattern %q: %w", pattern, err)
+		}
+	}
+	for _, kind := range req.ExcludeKinds {
+		switch kind {
+		case "insert", "update", "delete", "ddl":
+		default:
+			return nil, fmt.Errorf("invalid change kind %q: must be insert, update, delete or ddl", kind)
+		}
+	}
+	return &Filter{
+		includeTables: req.IncludeTables,
+		excludeTables: req.ExcludeTables,
+		excludeKinds:  req.ExcludeKinds,
+	}, nil
+}
+
+// Allows reports whether a change passes the filters. Table filters only apply to
+// DML; DDL is dropped only when the "ddl" kind is excluded.
+func (f *Filter) Allows(change types.Change) bool {
+	switch data := change.Data.(type) {
+	case *types.DMLData:
+		if slices.Contains(f.excludeKinds, data.Kind) {
+			return false
+		}
+		if len(f.includeTables) > 0 && !auth.MatchTable(f.includeTables, data.Table) {
+			return false
+		}
+		return !auth.MatchTable(f.excludeTables, data.Table)
+	case *types.DDLData:
+		return !slices.Contains(f.excludeKinds, "ddl")
+	default:
+		return true
+	}
+}
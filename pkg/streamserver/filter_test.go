@@ -0,0 +1,62 @@
+package streamserver
+
+import (
+	"testing"
+
+	"kasho/pkg/types"
+	"kasho/proto"
+)
+
+func TestFilter(t *testing.T) {
+	dml := func(table, kind string) types.Change {
+		return types.Change{Position: "0/700", Data: &types.DMLData{Table: table, Kind: kind}}
+	}
+	ddl := types.Change{Position: "0/700", Data: &types.DDLData{ID: 1, DDL: "CREATE TABLE public.users (id int)"}}
+
+	tests := []struct {
+		name   string
+		req    *proto.StreamRequest
+		change types.Change
+		want   bool
+	}{
+		{"no filters", &proto.StreamRequest{}, dml("public.users", "insert"), true},
+		{"included table", &proto.StreamRequest{IncludeTables: []string{"public.orders", "users"}}, dml("public.users", "insert"), true},
+		{"table not included", &proto.StreamRequest{IncludeTables: []string{"public.orders"}}, dml("public.users", "insert"), false},
+		{"excluded table", &proto.StreamRequest{ExcludeTables: []string{"audit_*"}}, dml("public.audit_log", "insert"), false},
+		{"exclude wins over include", &proto.StreamRequest{IncludeTables: []string{"public.*"}, ExcludeTables: []string{"public.audit_log"}}, dml("public.audit_log", "insert"), false},
+		{"excluded kind", &proto.StreamRequest{ExcludeKinds: []string{"delete"}}, dml("public.users", "delete"), false},
+		{"other kind passes", &proto.StreamRequest{ExcludeKinds: []string{"delete"}}, dml("public.users", "update"), true},
+		{"ddl ignores table filters", &proto.StreamRequest{IncludeTables: []string{"public.orders"}}, ddl, true},
+		{"excluded ddl", &proto.StreamRequest{ExcludeKinds: []string{"ddl"}}, ddl, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewFilter(tt.req)
+			if err != nil {
+				t.Fatalf("NewFilter() unexpected error: %v", err)
+			}
+			if got := filter.Allows(tt.change); got != tt.want {
+				t.Errorf("Allows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFilter_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *proto.StreamRequest
+	}{
+		{"bad pattern", &proto.StreamRequest{IncludeTables: []string{"public.[users"}}},
+		{"unknown kind", &proto.StreamRequest{ExcludeKinds: []string{"truncate"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewFilter(tt.req); err == nil {
+				t.Error("NewFilter() expected error")
+			}
+		})
+	}
+}
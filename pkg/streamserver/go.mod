@@ -0,0 +1,24 @@
+module kasho/pkg/streamserver
+
+go 1.24.3
+
+require (
+	google.golang.org/grpc v1.72.1
+	kasho/pkg/auth v0.0.0
+	kasho/pkg/types v0.0.0-00010101000000-000000000000
+	kasho/proto v0.0.0-00010101000000-000000000000
+)
+
+require (
+	golang.org/x/net v0.40.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/text v0.26.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250218202821-56aae31c358a // indirect
+	google.golang.org/protobuf v1.36.6 // indirect
+)
+
+replace kasho/pkg/auth => ../auth
+
+replace kasho/pkg/types => ../types
+
+replace kasho/proto => ../../proto/kasho/proto
@@ -0,0 +1,33 @@
+package streamserver
+
+import (
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+)
+
+// ListenAddress returns the network and address for a gRPC listen host and port. A host
+// of the form unix:/path/to/socket (or unix:///path/to/socket) selects a Unix socket and
+// ignores the port; an empty host listens on all interfaces.
+func ListenAddress(host, port string) (network, address string) {
+	if path, ok := strings.CutPrefix(host, "unix:"); ok {
+		return "unix", strings.TrimPrefix(path, "//")
+	}
+	return "tcp", net.JoinHostPort(host, port)
+}
+
+// Listen opens the gRPC listener for a host and port, see ListenAddress. A socket file
+// left behind at a Unix socket path by an earlier run is removed first.
+func Listen(host, port string) (net.Listener, error) {
+	network, address := ListenAddress(host, port)
+	if network == "unix" {
+		if info, err := os.Lstat(address); err == nil && info.Mode()&fs.ModeSocket != 0 {
+			if err := os.Remove(address); err != nil {
+				return nil, fmt.Errorf("failed to remove stale socket %s: %w", address, err)
+			}
+		}
+	}
+	return net.Listen(network, address)
+}
@@ -0,0 +1,49 @@
+package streamserver
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenAddress(t *testing.T) {
+	tests := []struct {
+		host, port       string
+		network, address string
+	}{
+		{"", "50051", "tcp", ":50051"},
+		{"127.0.0.1", "50051", "tcp", "127.0.0.1:50051"},
+		{"::1", "6000", "tcp", "[::1]:6000"},
+		{"unix:/run/kasho/grpc.sock", "50051", "unix", "/run/kasho/grpc.sock"},
+		{"unix:///run/kasho/grpc.sock", "50051", "unix", "/run/kasho/grpc.sock"},
+	}
+
+	for _, tt := range tests {
+		network, address := ListenAddress(tt.host, tt.port)
+		if network != tt.network || address != tt.address {
+			t.Errorf("ListenAddress(%q, %q) = %q, %q, want %q, %q", tt.host, tt.port, network, address, tt.network, tt.address)
+		}
+	}
+}
+
+func TestListenUnixSocketReplacesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grpc.sock")
+
+	// A socket file left behind by a process that didn't close its listener
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("net.Listen() error = %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	lis, err := Listen("unix:"+path, "")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer lis.Close()
+
+	if lis.Addr().Network() != "unix" || lis.Addr().String() != path {
+		t.Errorf("listening on %s %s, want unix %s", lis.Addr().Network(), lis.Addr().String(), path)
+	}
+}
@@ -0,0 +1,232 @@
+package streamserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// StreamIDHeader is the response header naming a stream with an in-flight limit, for
+// its consumer's Acknowledge calls
+const StreamIDHeader = "kasho-stream-id"
+
+// limiter is a token bucket that allows bursts of up to one second's worth of rate
+type limiter struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(rate float64, now time.Time) *limiter {
+	return &limiter{rate: rate, tokens: rate, last: now}
+}
+
+// reserve takes n tokens from the bucket and returns how long the caller must wait
+// before the reservation is covered
+func (l *limiter) reserve(n float64, now time.Time) time.Duration {
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+
+	l.tokens -= n
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// sentChange is a change sent on a stream that its consumer hasn't acknowledged yet
+type sentChange struct {
+	position string
+	size     uint64
+}
+
+// inFlight bounds the bytes of changes sent ahead of the consumer's acknowledgements
+type inFlight struct {
+	limit    uint64
+	mu       sync.Mutex
+	bytes    uint64
+	sent     []sentChange  // oldest first
+	released chan struct{} // closed when bytes are released
+}
+
+func newInFlight(limit uint64) *inFlight {
+	return &inFlight{limit: limit, released: make(chan struct{})}
+}
+
+// add waits until a change of the given size fits in the limit and counts it as sent.
+// A change larger than the limit is sent once nothing else is in flight.
+func (f *inFlight) add(ctx context.Context, position string, size uint64) error {
+	for {
+		f.mu.Lock()
+		if f.bytes == 0 || f.bytes+size <= f.limit {
+			f.sent = append(f.sent, sentChange{position: position, size: size})
+			f.bytes += size
+			f.mu.Unlock()
+			return nil
+		}
+		released := f.released
+		f.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-released:
+		}
+	}
+}
+
+// acknowledge releases the changes sent up to and including the oldest one at position.
+// A position that wasn't sent, or was already acknowledged, releases nothing.
+func (f *inFlight) acknowledge(position string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, change := range f.sent {
+		if change.position != position {
+			continue
+		}
+		for _, acked := range f.sent[:i+1] {
+			f.bytes -= acked.size
+		}
+		f.sent = f.sent[i+1:]
+		close(f.released)
+		f.released = make(chan struct{})
+		return
+	}
+}
+
+// Pacer throttles a single stream to a maximum number of changes and bytes per second,
+// and to a maximum number of bytes sent ahead of the consumer's acknowledgements. A
+// zero limit disables that dimension.
+type Pacer struct {
+	changes  *limiter
+	bytes    *limiter
+	inFlight *inFlight
+	now      func() time.Time
+}
+
+func NewPacer(changesPerSecond uint32, bytesPerSecond, inFlightBytes uint64) *Pacer {
+	p := &Pacer{now: time.Now}
+	start := p.now()
+	if changesPerSecond > 0 {
+		p.changes = newLimiter(float64(changesPerSecond), start)
+	}
+	if bytesPerSecond > 0 {
+		p.bytes = newLimiter(float64(bytesPerSecond), start)
+	}
+	if inFlightBytes > 0 {
+		p.inFlight = newInFlight(inFlightBytes)
+	}
+	return p
+}
+
+// delay reserves capacity for a change of the given size and returns the required wait
+func (p *Pacer) delay(size int) time.Duration {
+	now := p.now()
+	var d time.Duration
+	if p.changes != nil {
+		d = p.changes.reserve(1, now)
+	}
+	if p.bytes != nil {
+		if bd := p.bytes.reserve(float64(size), now); bd > d {
+			d = bd
+		}
+	}
+	return d
+}
+
+// Wait blocks until a change of the given size may be sent, and counts it as in flight
+// until the consumer acknowledges its position
+func (p *Pacer) Wait(ctx context.Context, position string, size int) error {
+	if p.inFlight != nil {
+		if err := p.inFlight.add(ctx, position, uint64(size)); err != nil {
+			return err
+		}
+	}
+	d := p.delay(size)
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Acknowledge releases the changes sent up to a position from the in-flight limit
+func (p *Pacer) Acknowledge(position string) {
+	if p.inFlight != nil {
+		p.inFlight.acknowledge(position)
+	}
+}
+
+// Streams finds the pacers of open streams with an in-flight limit by their IDs, so the
+// Acknowledge RPC can reach them
+type Streams struct {
+	mu     sync.Mutex
+	pacers map[string]*Pacer
+}
+
+// Open registers the pacer of a stream that has an in-flight limit and sends its ID in
+// the StreamIDHeader header, which must happen before anything is sent on the stream.
+// The returned function unregisters it once the stream ends.
+func (s *Streams) Open(stream grpc.ServerStream, p *Pacer) (func(), error) {
+	if p.inFlight == nil {
+		return func() {}, nil
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	id := hex.EncodeToString(b)
+	if err := stream.SetHeader(metadata.Pairs(StreamIDHeader, id)); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pacers == nil {
+		s.pacers = make(map[string]*Pacer)
+	}
+	s.pacers[id] = p
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.pacers, id)
+	}, nil
+}
+
+// Acknowledge releases the changes sent up to a position on the stream with the given ID
+func (s *Streams) Acknowledge(id, position string) error {
+	s.mu.Lock()
+	p, ok := s.pacers[id]
+	s.mu.Unlock()
+	if !ok {
+		return status.Errorf(codes.NotFound, "no open stream %q", id)
+	}
+	p.Acknowledge(position)
+	return nil
+}
+
+// EffectiveLimit combines the server's configured limit with the consumer's requested limit.
+// Zero means unlimited on either side; otherwise the lower of the two wins.
+func EffectiveLimit[T uint32 | uint64](serverLimit, requested T) T {
+	if requested == 0 || (serverLimit != 0 && requested > serverLimit) {
+		return serverLimit
+	}
+	return requested
+}
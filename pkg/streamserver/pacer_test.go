@@ -0,0 +1,183 @@
+package streamserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestEffectiveLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		server    uint64
+		requested uint64
+		want      uint64
+	}{
+		{"both unlimited", 0, 0, 0},
+		{"server limit only", 100, 0, 100},
+		{"requested limit only", 0, 50, 50},
+		{"requested below server", 100, 50, 50},
+		{"requested above server is capped", 100, 500, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EffectiveLimit(tt.server, tt.requested); got != tt.want {
+				t.Errorf("EffectiveLimit(%d, %d) = %d, want %d", tt.server, tt.requested, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPacerDelay(t *testing.T) {
+	now := time.Unix(0, 0)
+	p := &Pacer{now: func() time.Time { return now }, changes: newLimiter(2, now)}
+
+	// Burst of up to one second's worth of changes is allowed immediately
+	for i := 0; i < 2; i++ {
+		if d := p.delay(0); d != 0 {
+			t.Fatalf("delay() for change %d = %v, want 0", i, d)
+		}
+	}
+	if d := p.delay(0); d != 500*time.Millisecond {
+		t.Errorf("delay() after burst = %v, want 500ms", d)
+	}
+
+	// Tokens refill over time
+	now = now.Add(2 * time.Second)
+	if d := p.delay(0); d != 0 {
+		t.Errorf("delay() after refill = %v, want 0", d)
+	}
+}
+
+func TestPacerDelayBytes(t *testing.T) {
+	now := time.Unix(0, 0)
+	p := &Pacer{now: func() time.Time { return now }, bytes: newLimiter(1000, now)}
+
+	if d := p.delay(1000); d != 0 {
+		t.Errorf("delay() within budget = %v, want 0", d)
+	}
+	if d := p.delay(500); d != 500*time.Millisecond {
+		t.Errorf("delay() over budget = %v, want 500ms", d)
+	}
+}
+
+func TestPacerUnlimited(t *testing.T) {
+	p := NewPacer(0, 0, 0)
+	for i := 0; i < 1000; i++ {
+		if d := p.delay(1 << 20); d != 0 {
+			t.Fatalf("delay() with no limits = %v, want 0", d)
+		}
+	}
+}
+
+// waitAsync calls Wait in the background and returns a channel with its result
+func waitAsync(p *Pacer, position string, size int) <-chan error {
+	done := make(chan error, 1)
+	go func() { done <- p.Wait(context.Background(), position, size) }()
+	return done
+}
+
+func TestPacerInFlight(t *testing.T) {
+	p := NewPacer(0, 0, 100)
+	for _, position := range []string{"0/1", "0/2"} {
+		if err := p.Wait(context.Background(), position, 40); err != nil {
+			t.Fatalf("Wait() within the limit unexpected error: %v", err)
+		}
+	}
+
+	// The consumer falls behind: a third change doesn't fit until it acknowledges
+	done := waitAsync(p, "0/3", 40)
+	select {
+	case err := <-done:
+		t.Fatalf("Wait() = %v with 120 bytes in flight, want it to block", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	p.Acknowledge("0/9") // never sent
+	select {
+	case err := <-done:
+		t.Fatalf("Wait() = %v after acknowledging an unsent position, want it to block", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	p.Acknowledge("0/1")
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Wait() unexpected error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait() still blocked after the consumer acknowledged")
+	}
+
+	// Acknowledging a position releases every change sent before it, and a change
+	// larger than the limit is sent once nothing else is in flight
+	p.Acknowledge("0/3")
+	if err := p.Wait(context.Background(), "0/4", 500); err != nil {
+		t.Fatalf("Wait() for an oversized change unexpected error: %v", err)
+	}
+
+	// A cancelled wait gives up
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.Wait(ctx, "0/5", 1); err != context.Canceled {
+		t.Errorf("Wait() with cancelled context = %v, want context.Canceled", err)
+	}
+}
+
+// headerStream records the header a stream sets
+type headerStream struct {
+	grpc.ServerStream
+	header metadata.MD
+}
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestStreamsAcknowledge(t *testing.T) {
+	var streams Streams
+
+	// Streams without an in-flight limit aren't registered
+	unlimited := &headerStream{}
+	if _, err := streams.Open(unlimited, NewPacer(0, 0, 0)); err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+	if len(unlimited.header) != 0 {
+		t.Errorf("Open() set header %v for a stream without an in-flight limit", unlimited.header)
+	}
+
+	stream := &headerStream{}
+	p := NewPacer(0, 0, 10)
+	closeStream, err := streams.Open(stream, p)
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+	ids := stream.header.Get(StreamIDHeader)
+	if len(ids) != 1 || ids[0] == "" {
+		t.Fatalf("Open() header = %v, want a stream ID", stream.header)
+	}
+
+	if err := p.Wait(context.Background(), "0/1", 10); err != nil {
+		t.Fatal(err)
+	}
+	done := waitAsync(p, "0/2", 10)
+	if err := streams.Acknowledge(ids[0], "0/1"); err != nil {
+		t.Fatalf("Acknowledge() unexpected error: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Wait() still blocked after Acknowledge()")
+	}
+
+	closeStream()
+	if err := streams.Acknowledge(ids[0], "0/2"); status.Code(err) != codes.NotFound {
+		t.Errorf("Acknowledge() on a closed stream = %v, want NotFound", err)
+	}
+}
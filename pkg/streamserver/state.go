@@ -0,0 +1,35 @@
+package streamserver
+
+// State represents the operational state of a change stream
+type State int
+
+const (
+	StateWaiting State = iota
+	StateAccumulating
+	StateStreaming
+)
+
+func (s State) String() string {
+	switch s {
+	case StateWaiting:
+		return "WAITING"
+	case StateAccumulating:
+		return "ACCUMULATING"
+	case StateStreaming:
+		return "STREAMING"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// stateDescriptions explain what a change stream does in each state
+var stateDescriptions = map[State]string{
+	StateWaiting:      "No start position is captured and nothing is read from the source. The service is ready for a bootstrap.",
+	StateAccumulating: "A start position is captured and a bootstrap is loading its snapshot into the buffer. Source changes from the start position are held until the bootstrap completes.",
+	StateStreaming:    "Changes are read from the source into the buffer and sent to consumers. The service stays in this state across restarts.",
+}
+
+// Description explains what a change stream does in the state
+func (s State) Description() string {
+	return stateDescriptions[s]
+}
@@ -0,0 +1,22 @@
+package streamserver
+
+import "testing"
+
+func TestStateString(t *testing.T) {
+	tests := []struct {
+		state State
+		want  string
+	}{
+		{StateWaiting, "WAITING"},
+		{StateAccumulating, "ACCUMULATING"},
+		{StateStreaming, "STREAMING"},
+		{State(99), "UNKNOWN"},
+	}
+
+	for _, tt := range tests {
+		got := tt.state.String()
+		if got != tt.want {
+			t.Errorf("State(%d).String() = %v, want %v", tt.state, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package streamserver
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"kasho/pkg/auth"
+	"kasho/pkg/types"
+	"kasho/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// NewHeartbeat builds a heartbeat message carrying the current source position.
+// The position reflects how far the source has been read, not what this stream
+// has delivered, so consumers must not use it as a resume point.
+func NewHeartbeat(position string) *proto.Change {
+	return &proto.Change{
+		Position:   position,
+		Type:       "heartbeat",
+		ChangeType: proto.ChangeType_CHANGE_TYPE_HEARTBEAT,
+	}
+}
+
+// Authenticate resolves the caller's API token when authenticator is not nil.
+// A nil token with a nil error means the caller may receive all changes.
+func Authenticate(ctx context.Context, authenticator *auth.Authenticator) (*auth.Token, error) {
+	if authenticator == nil {
+		return nil, nil
+	}
+
+	var header string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			header = values[0]
+		}
+	}
+
+	token, err := authenticator.Authenticate(auth.BearerToken(header))
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "missing or invalid API token")
+	}
+	log.Printf("Stream client authenticated with token %q", token.Name)
+	return token, nil
+}
+
+// ChangeAllowed reports whether a change falls within the token's table scopes. A scoped
+// token receives DDL only if every table it names is in scope, and never receives
+// application messages, which aren't tied to a table.
+func ChangeAllowed(token *auth.Token, change types.Change) bool {
+	if token == nil || len(token.Tables) == 0 {
+		return true
+	}
+	switch data := change.Data.(type) {
+	case *types.DMLData:
+		return token.AllowsTable(data.Table)
+	case *types.DDLData:
+		tables, ok := ddlTables(change, data.DDL)
+		if !ok {
+			return false
+		}
+		for _, table := range tables {
+			if !token.AllowsTable(table) {
+				return false
+			}
+		}
+		return true
+	case *types.MessageData:
+		return false
+	}
+	return true
+}
+
+// ConvertChange converts a buffered change into the message sent to stream consumers
+func ConvertChange(change types.Change) *proto.Change {
+	protoChange := &proto.Change{
+		Position:      change.GetPosition(),
+		Type:          change.Type(),
+		ChangeType:    types.ChangeTypeFromString(change.Type()),
+		SourceDialect: types.SourceDialectFromString(change.Dialect),
+		Database:      change.Database,
+		Schema:        change.Schema,
+		TransactionId: change.TransactionID,
+		Metadata:      change.Metadata,
+
+		TransactionStatus: types.TransactionStatusFromString(change.TransactionStatus),
+		SubtransactionId:  change.SubtransactionID,
+	}
+	if !change.CommitTime.IsZero() {
+		protoChange.CommitTimestamp = change.CommitTime.UTC().Format(time.RFC3339Nano)
+	}
+
+	switch data := change.Data.(type) {
+	case *types.DMLData:
+		dml := &proto.DMLData{
+			Table:        data.Table,
+			ColumnNames:  data.ColumnNames,
+			ColumnValues: make([]*proto.ColumnValue, len(data.ColumnValues)),
+			Kind:         data.Kind,
+			PrimaryKey:   data.PrimaryKey,
+			Schema:       data.Schema,
+			DmlKind:      types.DMLKindFromString(data.Kind),
+		}
+		for i, cv := range data.ColumnValues {
+			dml.ColumnValues[i] = cv.ColumnValue
+		}
+		if data.OldKeys != nil {
+			dml.OldKeys = &proto.OldKeys{
+				KeyNames:  data.OldKeys.KeyNames,
+				KeyValues: make([]*proto.ColumnValue, len(data.OldKeys.KeyValues)),
+			}
+			for i, cv := range data.OldKeys.KeyValues {
+				dml.OldKeys.KeyValues[i] = cv.ColumnValue
+			}
+		}
+		protoChange.Data = &proto.Change_Dml{Dml: dml}
+	case *types.DDLData:
+		protoChange.Data = &proto.Change_Ddl{
+			Ddl: &proto.DDLData{
+				Id:       int32(data.ID),
+				Time:     data.Time.Format(time.RFC3339),
+				Username: data.Username,
+				Database: data.Database,
+				Ddl:      data.DDL,
+			},
+		}
+	case *types.MessageData:
+		protoChange.Data = &proto.Change_Message{
+			Message: &proto.MessageData{
+				Prefix:        data.Prefix,
+				Content:       data.Content,
+				Transactional: data.Transactional,
+			},
+		}
+	}
+
+	return protoChange
+}
@@ -0,0 +1,399 @@
+package streamserver
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"kasho/pkg/auth"
+	"kasho/pkg/types"
+	"kasho/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestConvertChange_DMLData(t *testing.T) {
+	tests := []struct {
+		name   string
+		change types.Change
+		want   *proto.Change
+	}{
+		{
+			name: "DML insert without old keys",
+			change: types.Change{
+				Position: "0/100",
+				Data: &types.DMLData{
+					Table:       "public.users",
+					Kind:        "insert",
+					ColumnNames: []string{"id", "name", "email"},
+					ColumnValues: []types.ColumnValueWrapper{
+						{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: 1}}},
+						{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: "John Doe"}}},
+						{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: "john@example.com"}}},
+					},
+				},
+			},
+			want: &proto.Change{
+				Position:   "0/100",
+				Type:       "dml",
+				ChangeType: proto.ChangeType_CHANGE_TYPE_DML,
+				Data: &proto.Change_Dml{
+					Dml: &proto.DMLData{
+						Table:       "public.users",
+						Kind:        "insert",
+						DmlKind:     proto.DMLKind_DML_KIND_INSERT,
+						ColumnNames: []string{"id", "name", "email"},
+						ColumnValues: []*proto.ColumnValue{
+							{Value: &proto.ColumnValue_IntValue{IntValue: 1}},
+							{Value: &proto.ColumnValue_StringValue{StringValue: "John Doe"}},
+							{Value: &proto.ColumnValue_StringValue{StringValue: "john@example.com"}},
+						},
+						OldKeys: nil,
+					},
+				},
+			},
+		},
+		{
+			name: "DML update with old keys",
+			change: types.Change{
+				Position: "0/200",
+				Data: &types.DMLData{
+					Table:       "public.users",
+					Kind:        "update",
+					ColumnNames: []string{"name"},
+					ColumnValues: []types.ColumnValueWrapper{
+						{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: "Jane Doe"}}},
+					},
+					OldKeys: &struct {
+						KeyNames  []string                   `json:"keynames"`
+						KeyValues []types.ColumnValueWrapper `json:"keyvalues"`
+					}{
+						KeyNames: []string{"id"},
+						KeyValues: []types.ColumnValueWrapper{
+							{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: 1}}},
+						},
+					},
+				},
+			},
+			want: &proto.Change{
+				Position:   "0/200",
+				Type:       "dml",
+				ChangeType: proto.ChangeType_CHANGE_TYPE_DML,
+				Data: &proto.Change_Dml{
+					Dml: &proto.DMLData{
+						Table:       "public.users",
+						Kind:        "update",
+						DmlKind:     proto.DMLKind_DML_KIND_UPDATE,
+						ColumnNames: []string{"name"},
+						ColumnValues: []*proto.ColumnValue{
+							{Value: &proto.ColumnValue_StringValue{StringValue: "Jane Doe"}},
+						},
+						OldKeys: &proto.OldKeys{
+							KeyNames: []string{"id"},
+							KeyValues: []*proto.ColumnValue{
+								{Value: &proto.ColumnValue_IntValue{IntValue: 1}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "DML delete with old keys",
+			change: types.Change{
+				Position: "0/300",
+				Data: &types.DMLData{
+					Table:        "public.users",
+					Kind:         "delete",
+					ColumnNames:  []string{},
+					ColumnValues: []types.ColumnValueWrapper{},
+					OldKeys: &struct {
+						KeyNames  []string                   `json:"keynames"`
+						KeyValues []types.ColumnValueWrapper `json:"keyvalues"`
+					}{
+						KeyNames: []string{"id", "email"},
+						KeyValues: []types.ColumnValueWrapper{
+							{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: 1}}},
+							{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: "john@example.com"}}},
+						},
+					},
+				},
+			},
+			want: &proto.Change{
+				Position:   "0/300",
+				Type:       "dml",
+				ChangeType: proto.ChangeType_CHANGE_TYPE_DML,
+				Data: &proto.Change_Dml{
+					Dml: &proto.DMLData{
+						Table:        "public.users",
+						Kind:         "delete",
+						DmlKind:      proto.DMLKind_DML_KIND_DELETE,
+						ColumnNames:  []string{},
+						ColumnValues: []*proto.ColumnValue{},
+						OldKeys: &proto.OldKeys{
+							KeyNames: []string{"id", "email"},
+							KeyValues: []*proto.ColumnValue{
+								{Value: &proto.ColumnValue_IntValue{IntValue: 1}},
+								{Value: &proto.ColumnValue_StringValue{StringValue: "john@example.com"}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ConvertChange(tt.change)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ConvertChange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertChange_DDLData(t *testing.T) {
+	testTime := time.Date(2024, 3, 20, 15, 4, 5, 0, time.UTC)
+
+	change := types.Change{
+		Position: "0/400",
+		Data: &types.DDLData{
+			ID:       123,
+			Time:     testTime,
+			Username: "postgres",
+			Database: "testdb",
+			DDL:      "CREATE TABLE test (id SERIAL PRIMARY KEY, name VARCHAR(100))",
+		},
+	}
+
+	want := &proto.Change{
+		Position:   "0/400",
+		Type:       "ddl",
+		ChangeType: proto.ChangeType_CHANGE_TYPE_DDL,
+		Data: &proto.Change_Ddl{
+			Ddl: &proto.DDLData{
+				Id:       123,
+				Time:     testTime.Format(time.RFC3339),
+				Username: "postgres",
+				Database: "testdb",
+				Ddl:      "CREATE TABLE test (id SERIAL PRIMARY KEY, name VARCHAR(100))",
+			},
+		},
+	}
+
+	got := ConvertChange(change)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ConvertChange() = %v, want %v", got, want)
+	}
+}
+
+func TestConvertChange_TransactionData(t *testing.T) {
+	change := types.Change{
+		Position:          "0/480",
+		Data:              &types.TransactionData{},
+		TransactionID:     "742",
+		TransactionStatus: "aborted",
+		SubtransactionID:  "743",
+	}
+
+	want := &proto.Change{
+		Position:          "0/480",
+		Type:              "transaction",
+		ChangeType:        proto.ChangeType_CHANGE_TYPE_TRANSACTION,
+		TransactionId:     "742",
+		TransactionStatus: proto.TransactionStatus_TRANSACTION_STATUS_ABORTED,
+		SubtransactionId:  "743",
+	}
+
+	got := ConvertChange(change)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ConvertChange() = %v, want %v", got, want)
+	}
+}
+
+func TestConvertChange_MessageData(t *testing.T) {
+	change := types.Change{
+		Position: "0/490",
+		Data:     &types.MessageData{Prefix: "deploy", Content: []byte("deploy-123 complete")},
+	}
+
+	want := &proto.Change{
+		Position:   "0/490",
+		Type:       "message",
+		ChangeType: proto.ChangeType_CHANGE_TYPE_MESSAGE,
+		Data: &proto.Change_Message{
+			Message: &proto.MessageData{Prefix: "deploy", Content: []byte("deploy-123 complete")},
+		},
+	}
+
+	got := ConvertChange(change)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ConvertChange() = %v, want %v", got, want)
+	}
+}
+
+func TestConvertChange_DifferentColumnTypes(t *testing.T) {
+	change := types.Change{
+		Position: "0/500",
+		Data: &types.DMLData{
+			Table:       "public.test_table",
+			Kind:        "insert",
+			ColumnNames: []string{"bool_col", "float_col", "timestamp_col"},
+			ColumnValues: []types.ColumnValueWrapper{
+				{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_BoolValue{BoolValue: true}}},
+				{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_FloatValue{FloatValue: 3.14}}},
+				{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2024-03-20T15:04:05Z"}}},
+			},
+		},
+	}
+
+	want := &proto.Change{
+		Position:   "0/500",
+		Type:       "dml",
+		ChangeType: proto.ChangeType_CHANGE_TYPE_DML,
+		Data: &proto.Change_Dml{
+			Dml: &proto.DMLData{
+				Table:       "public.test_table",
+				Kind:        "insert",
+				DmlKind:     proto.DMLKind_DML_KIND_INSERT,
+				ColumnNames: []string{"bool_col", "float_col", "timestamp_col"},
+				ColumnValues: []*proto.ColumnValue{
+					{Value: &proto.ColumnValue_BoolValue{BoolValue: true}},
+					{Value: &proto.ColumnValue_FloatValue{FloatValue: 3.14}},
+					{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2024-03-20T15:04:05Z"}},
+				},
+				OldKeys: nil,
+			},
+		},
+	}
+
+	got := ConvertChange(change)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ConvertChange() = %v, want %v", got, want)
+	}
+}
+
+func TestConvertChange_EmptyData(t *testing.T) {
+	change := types.Change{
+		Position: "0/600",
+		Data: &types.DMLData{
+			Table:        "public.empty_table",
+			Kind:         "insert",
+			ColumnNames:  []string{},
+			ColumnValues: []types.ColumnValueWrapper{},
+		},
+	}
+
+	want := &proto.Change{
+		Position:   "0/600",
+		Type:       "dml",
+		ChangeType: proto.ChangeType_CHANGE_TYPE_DML,
+		Data: &proto.Change_Dml{
+			Dml: &proto.DMLData{
+				Table:        "public.empty_table",
+				Kind:         "insert",
+				DmlKind:      proto.DMLKind_DML_KIND_INSERT,
+				ColumnNames:  []string{},
+				ColumnValues: []*proto.ColumnValue{},
+				OldKeys:      nil,
+			},
+		},
+	}
+
+	got := ConvertChange(change)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ConvertChange() = %v, want %v", got, want)
+	}
+}
+
+func TestChangeAllowed(t *testing.T) {
+	scoped := &auth.Token{Name: "dev", Value: "secret", Tables: []string{"public.orders"}}
+
+	dml := func(table string) types.Change {
+		return types.Change{Position: "0/700", Data: &types.DMLData{Table: table, Kind: "insert"}}
+	}
+	ddl := func(dialect, schema, statement string) types.Change {
+		return types.Change{Position: "0/700", Dialect: dialect, Schema: schema, Data: &types.DDLData{ID: 1, DDL: statement}}
+	}
+	message := types.Change{Position: "0/700", Data: &types.MessageData{Prefix: "marker"}}
+	unscoped := &auth.Token{Name: "admin", Value: "secret"}
+
+	tests := []struct {
+		name   string
+		token  *auth.Token
+		change types.Change
+		want   bool
+	}{
+		{"authentication disabled", nil, dml("public.users"), true},
+		{"table in scope", scoped, dml("public.orders"), true},
+		{"table out of scope", scoped, dml("public.users"), false},
+		{"ddl in scope", scoped, ddl("postgresql", "", "ALTER TABLE public.orders ADD COLUMN note text"), true},
+		{"ddl in scope by default schema", scoped, ddl("postgresql", "", "CREATE TABLE IF NOT EXISTS Orders (id int)"), true},
+		{"ddl in scope by change schema", scoped, ddl("mysql", "public", "DROP TABLE `orders`"), true},
+		{"ddl out of scope", scoped, ddl("postgresql", "", "CREATE TABLE public.users (id int)"), false},
+		{"ddl out of scope in another schema", scoped, ddl("postgresql", "", "CREATE TABLE audit.orders (id int)"), false},
+		{"ddl unqualified in another schema", scoped, ddl("mysql", "shop", "CREATE TABLE orders (id int)"), false},
+		{"ddl referencing a table out of scope", scoped, ddl("postgresql", "", "ALTER TABLE orders ADD FOREIGN KEY (user_id) REFERENCES users (id)"), false},
+		{"ddl dropping tables in and out of scope", scoped, ddl("postgresql", "", "DROP TABLE orders, users"), false},
+		{"ddl index on a table out of scope", scoped, ddl("postgresql", "", "CREATE INDEX orders_idx ON users (id)"), false},
+		{"ddl renaming out of scope", scoped, ddl("postgresql", "", "ALTER TABLE orders RENAME TO users"), false},
+		{"ddl statement out of scope", scoped, ddl("postgresql", "", "ALTER TABLE orders ADD COLUMN note text; DROP TABLE users"), false},
+		{"ddl naming no table", scoped, ddl("postgresql", "", "CREATE FUNCTION f() RETURNS int AS 'SELECT 1' LANGUAGE sql"), false},
+		{"ddl for an unscoped token", unscoped, ddl("postgresql", "", "CREATE TABLE public.users (id int)"), true},
+		{"message for a scoped token", scoped, message, false},
+		{"message for an unscoped token", unscoped, message, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ChangeAllowed(tt.token, tt.change); got != tt.want {
+				t.Errorf("ChangeAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	token := &auth.Token{Name: "translicator", Value: "secret"}
+
+	// Authentication disabled allows anonymous callers
+	got, err := Authenticate(context.Background(), nil)
+	if err != nil || got != nil {
+		t.Fatalf("Authenticate() without authenticator = %v, %v; want nil, nil", got, err)
+	}
+
+	authenticator := auth.NewAuthenticator([]*auth.Token{token})
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer secret"))
+	got, err = Authenticate(ctx, authenticator)
+	if err != nil {
+		t.Fatalf("Authenticate() unexpected error: %v", err)
+	}
+	if got != token {
+		t.Errorf("Authenticate() = %v, want %v", got, token)
+	}
+
+	for _, ctx := range []context.Context{
+		context.Background(),
+		metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer wrong")),
+	} {
+		if _, err := Authenticate(ctx, authenticator); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("Authenticate() error code = %v, want %v", status.Code(err), codes.Unauthenticated)
+		}
+	}
+}
+
+func TestNewHeartbeat(t *testing.T) {
+	got := NewHeartbeat("0/16B3748")
+	want := &proto.Change{Position: "0/16B3748", Type: "heartbeat", ChangeType: proto.ChangeType_CHANGE_TYPE_HEARTBEAT}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewHeartbeat() = %v, want %v", got, want)
+	}
+	if got.GetDml() != nil || got.GetDdl() != nil {
+		t.Errorf("NewHeartbeat() should not carry change data")
+	}
+}
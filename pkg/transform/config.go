@@ -0,0 +1,888 @@
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"kasho/pkg/version"
+	"kasho/proto"
+)
+
+// TransformFunction represents a function that generates fake data
+type TransformFunction[T ScalarValue] func(original string) T
+
+// TransformType is an enum-like type for fake data generators
+type TransformType string
+
+const (
+	// Personal Information (Gofakeit-based)
+	FakeName        TransformType = "FakeName"
+	FakeFirstName   TransformType = "FakeFirstName"
+	FakeLastName    TransformType = "FakeLastName"
+	FakeEmail       TransformType = "FakeEmail"
+	FakeSSN         TransformType = "FakeSSN"
+	FakeDateOfBirth TransformType = "FakeDateOfBirth"
+	FakePhone       TransformType = "FakePhone"
+	FakeGender      TransformType = "FakeGender"
+	FakeTitle       TransformType = "FakeTitle"
+	FakeJobTitle    TransformType = "FakeJobTitle"
+	FakeIndustry    TransformType = "FakeIndustry"
+	FakeDomainName  TransformType = "FakeDomainName"
+	FakeUsername    TransformType = "FakeUsername"
+	FakePassword    TransformType = "FakePassword"
+
+	// Address Information (Gofakeit-based)
+	FakeStreetAddress TransformType = "FakeStreetAddress"
+	FakeStreet        TransformType = "FakeStreet"
+	FakeCity          TransformType = "FakeCity"
+	FakeState         TransformType = "FakeState"
+	FakeStateAbbr     TransformType = "FakeStateAbbr"
+	FakeZip           TransformType = "FakeZip"
+	FakeCountry       TransformType = "FakeCountry"
+	FakeLatitude      TransformType = "FakeLatitude"
+	FakeLongitude     TransformType = "FakeLongitude"
+
+	// Product Information (Gofakeit-based)
+	FakeCompany            TransformType = "FakeCompany"
+	FakeProduct            TransformType = "FakeProduct"
+	FakeProductName        TransformType = "FakeProductName"
+	FakeProductDescription TransformType = "FakeProductDescription"
+
+	// Text Content (Gofakeit-based)
+	FakeParagraph  TransformType = "FakeParagraph"
+	FakeSentence   TransformType = "FakeSentence"
+	FakeWord       TransformType = "FakeWord"
+	FakeWords      TransformType = "FakeWords"
+	FakeCharacters TransformType = "FakeCharacters"
+	FakeCharacter  TransformType = "FakeCharacter"
+	FakeDigits     TransformType = "FakeDigits"
+
+	// Date and Time (Gofakeit-based)
+	FakeMonth    TransformType = "FakeMonth"
+	FakeMonthNum TransformType = "FakeMonthNum"
+	FakeWeekDay  TransformType = "FakeWeekDay"
+	FakeYear     TransformType = "FakeYear"
+
+	// Financial Information (Gofakeit-based)
+	FakeCreditCardType TransformType = "FakeCreditCardType"
+	FakeCreditCardNum  TransformType = "FakeCreditCardNum"
+	FakeCurrency       TransformType = "FakeCurrency"
+
+	// Custom transforms (non-gofakeit)
+	Bool      TransformType = "Bool"
+	HashBytes TransformType = "HashBytes"
+
+	// Pattern-based transforms
+	Regex TransformType = "Regex"
+
+	// Template-based transforms
+	Template TransformType = "Template"
+
+	// Expression transforms evaluated with CEL
+	Expr TransformType = "Expr"
+
+	// Shifts dates by a per-entity offset, keeping intervals within the entity
+	DateShift TransformType = "DateShift"
+
+	// Numeric perturbation and generalization
+	NumericNoise TransformType = "NumericNoise"
+	Round        TransformType = "Round"
+	Bucket       TransformType = "Bucket"
+
+	// Replaces values that are rare within a window of recent values
+	Suppress TransformType = "Suppress"
+
+	// Hides the middle of a value, keeping its length and first and last characters
+	Mask TransformType = "Mask"
+
+	// Fakes several columns of a row from one consistent fake identity
+	Persona TransformType = "Persona"
+
+	// Password transforms with different algorithms
+	PasswordBcrypt   TransformType = "PasswordBcrypt"
+	PasswordScrypt   TransformType = "PasswordScrypt"
+	PasswordPBKDF2   TransformType = "PasswordPBKDF2"
+	PasswordArgon2id TransformType = "PasswordArgon2id"
+
+	// Custom transforms implemented by a WASM module or a plugin executable
+	Plugin TransformType = "Plugin"
+)
+
+var transformFunctions = map[TransformType]any{
+	// Personal Information (Gofakeit-based)
+	FakeName:        TransformFakeName,
+	FakeFirstName:   TransformFakeFirstName,
+	FakeLastName:    TransformFakeLastName,
+	FakeEmail:       TransformFakeEmail,
+	FakeSSN:         TransformFakeSSN,
+	FakeDateOfBirth: TransformFakeDateOfBirth,
+	FakePhone:       TransformFakePhone,
+	FakeGender:      TransformFakeGender,
+	FakeTitle:       TransformFakeTitle,
+	FakeJobTitle:    TransformFakeJobTitle,
+	FakeIndustry:    TransformFakeIndustry,
+	FakeDomainName:  TransformFakeDomainName,
+	FakeUsername:    TransformFakeUsername,
+	FakePassword:    TransformFakePassword,
+
+	// Address Information (Gofakeit-based)
+	FakeStreetAddress: TransformFakeStreetAddress,
+	FakeStreet:        TransformFakeStreet,
+	FakeCity:          TransformFakeCity,
+	FakeState:         TransformFakeState,
+	FakeStateAbbr:     TransformFakeStateAbbr,
+	FakeZip:           TransformFakeZip,
+	FakeCountry:       TransformFakeCountry,
+	FakeLatitude:      TransformFakeLatitude,
+	FakeLongitude:     TransformFakeLongitude,
+
+	// Product Information (Gofakeit-based)
+	FakeCompany:            TransformFakeCompany,
+	FakeProduct:            TransformFakeProduct,
+	FakeProductName:        TransformFakeProductName,
+	FakeProductDescription: TransformFakeProductDescription,
+
+	// Text Content (Gofakeit-based)
+	FakeParagraph: TransformFakeParagraph,
+	FakeWord:      TransformFakeWord,
+
+	// Date and Time (Gofakeit-based)
+	FakeMonth:    TransformFakeMonth,
+	FakeMonthNum: TransformFakeMonthNum,
+	FakeWeekDay:  TransformFakeWeekDay,
+	FakeYear:     TransformFakeYear,
+
+	// Financial Information (Gofakeit-based)
+	FakeCreditCardType: TransformFakeCreditCardType,
+	FakeCreditCardNum:  TransformFakeCreditCardNum,
+	FakeCurrency:       TransformFakeCurrency,
+
+	// Custom transforms (non-gofakeit)
+	Bool:      TransformBool,
+	HashBytes: TransformHashBytes,
+}
+
+func init() {
+}
+
+// ColumnTransform represents a transform configuration for a column
+// It can be either a simple string (transform type) or a complex object
+type ColumnTransform struct {
+	Type   TransformType  `yaml:"type"`
+	Config map[string]any `yaml:",inline"`
+}
+
+// UnmarshalYAML handles both string and object formats
+func (ct *ColumnTransform) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Try to unmarshal as a string first (simple format)
+	var transformType string
+	if err := unmarshal(&transformType); err == nil {
+		ct.Type = TransformType(transformType)
+		ct.Config = make(map[string]any)
+		return nil
+	}
+
+	// If that fails, try as a map (object format)
+	var raw map[string]any
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	// Extract type field
+	if typeVal, ok := raw["type"]; ok {
+		if typeStr, ok := typeVal.(string); ok {
+			ct.Type = TransformType(typeStr)
+			delete(raw, "type") // Remove type from config
+		} else {
+			return fmt.Errorf("type field must be a string")
+		}
+	} else {
+		return fmt.Errorf("type field is required")
+	}
+
+	// The rest is config
+	if len(raw) > 0 {
+		ct.Config = raw
+	} else {
+		ct.Config = make(map[string]any)
+	}
+	return nil
+}
+
+// Deterministic reports whether the transform always produces the same output for the same input.
+// PasswordBcrypt and password transforms with salt_mode random salt every hash randomly, so
+// their output can't be reproduced, and Suppress depends on which values were seen before.
+func (ct ColumnTransform) Deterministic() bool {
+	if isPasswordType(ct.Type) {
+		return ct.Type != PasswordBcrypt && ct.Config["salt_mode"] != string(saltRandom)
+	}
+	return ct.Type != Suppress
+}
+
+// TableConfig represents the configuration for a single table
+type TableConfig map[string]ColumnTransform
+
+// Config represents the entire configuration
+type Config struct {
+	MajorVersion int                    `yaml:"major_version"`
+	Tables       map[string]TableConfig `yaml:"tables"`
+
+	// Version is the schema version of the config, v1 or v2; v1 configs are migrated to v2
+	// when they are loaded
+	Version string `yaml:"version"`
+
+	// Defaults apply transforms to columns of every table matching a regular expression (v2)
+	Defaults []DefaultTransform `yaml:"defaults"`
+
+	// literalColumns marks, per table, the keys of a migrated v1 config that are column
+	// names although v2 would read them as a wildcard or the table's default
+	literalColumns map[string]map[string]bool
+
+	// Include lists more config files to merge into this one, relative to this file and
+	// possibly globs, e.g. tables/*.yml. Files in a directory named like the config file
+	// with a .d suffix, e.g. transforms.d/ for transforms.yml, are merged as well.
+	Include []string `yaml:"include"`
+
+	// GeneratedColumns overrides the generated columns found on the replica for a table.
+	// Listed columns are left out of INSERTs and UPDATEs; an empty list includes every column.
+	GeneratedColumns map[string][]string `yaml:"generated_columns"`
+
+	// Routing writes source tables and columns to differently named replica tables and columns
+	Routing RoutingConfig `yaml:"routing"`
+
+	// Locale selects regional data for fake names, addresses and phone numbers, e.g. de_DE;
+	// columns can override it with their own locale option
+	Locale string `yaml:"locale"`
+
+	// Validation checks transformed values against the replica's columns before they are applied
+	Validation ValidationConfig `yaml:"validation"`
+
+	// TypeMapping overrides the replica column types DDL from another kind of database gets
+	TypeMapping TypeMappingConfig `yaml:"type_mapping"`
+
+	// DDL decides what happens to DDL changes before they are applied
+	DDL DDLConfig `yaml:"ddl"`
+}
+
+// ValidationConfig configures checks of transformed values. Keys use replica table names,
+// after routing.
+type ValidationConfig struct {
+	// Enabled turns on the length and NOT NULL checks against the replica's column definitions
+	Enabled bool `yaml:"enabled"`
+	// Truncate shortens values longer than their column instead of reporting them
+	Truncate bool `yaml:"truncate"`
+	// AllowedValues lists, per table and column, the only values the replica accepts,
+	// e.g. the members of an enum or check constraint
+	AllowedValues map[string]map[string][]string `yaml:"allowed_values"`
+	// Unique lists, per table, columns whose transformed values should stay unique;
+	// collisions between different source values are reported
+	Unique map[string][]string `yaml:"unique"`
+}
+
+// RoutingConfig maps source names to replica names. Keys use the source table names
+// also used under tables.
+type RoutingConfig struct {
+	// Tables maps a source table to its replica table, e.g. public.users: warehouse.dim_users
+	Tables map[string]string `yaml:"tables"`
+	// Columns maps, per source table, source columns to replica columns
+	Columns map[string]map[string]string `yaml:"columns"`
+}
+
+// TypeMappingConfig overrides the column types of source DDL on the replica. Keys use the
+// source table names also used under tables.
+type TypeMappingConfig struct {
+	// Columns sets, per source table, the replica type of columns, e.g.
+	// public.users: {email: VARCHAR(1024)} for a text column that is indexed on MySQL
+	Columns map[string]map[string]string `yaml:"columns"`
+}
+
+// DDLAction is what a DDL rule does with the statements it matches
+type DDLAction string
+
+const (
+	// DDLSkip doesn't apply the statement
+	DDLSkip DDLAction = "skip"
+	// DDLReplace applies the rule's SQL instead of the statement
+	DDLReplace DDLAction = "replace"
+	// DDLPause stops applying changes until an operator approves or rejects the statement
+	DDLPause DDLAction = "pause"
+)
+
+// DDLConfig lists rules for DDL changes. The first rule matching a statement applies;
+// statements no rule matches are applied.
+type DDLConfig struct {
+	Rules []DDLRule `yaml:"rules"`
+}
+
+// DDLRule matches DDL statements by a regular expression, or destructive statements
+// such as DROP TABLE, DROP COLUMN and TRUNCATE
+type DDLRule struct {
+	Match       string    `yaml:"match"`
+	Destructive bool      `yaml:"destructive"`
+	Action      DDLAction `yaml:"action"`
+	// SQL replaces the statement for the replace action
+	SQL string `yaml:"sql"`
+}
+
+// LoadConfig loads the configuration from a YAML file
+func LoadConfig(path string) (*Config, error) {
+	config, err := loadConfigFiles(path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle version validation and migration
+	if err := validateAndMigrateConfig(config); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	if err := validateRouting(config.Routing); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	if err := validateTypeMapping(config.TypeMapping); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	if err := validateDDL(config.DDL); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	if err := validateDateShifts(config); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	if err := validateNumericTransforms(config); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	if err := validateSuppressions(config); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	if err := validatePersonas(config); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	if err := validateLocales(config); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	if err := validateExprs(config); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	if err := validatePasswords(config); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	if err := validateMasks(config); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	if err := validateDefaults(config, validateDateShifts, validateNumericTransforms, validateSuppressions,
+		validatePersonas, validateLocales, validateExprs, validatePasswords, validateMasks); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	return config, nil
+}
+
+// validateAndMigrateConfig validates the config version and handles migrations
+func validateAndMigrateConfig(config *Config) error {
+	// Check if major version matches Kasho major version
+	kashoMajorVersion := version.MajorVersion()
+	
+	if config.MajorVersion != kashoMajorVersion {
+		return fmt.Errorf("config major version mismatch: got %d, expected %d (Kasho version %s)", 
+			config.MajorVersion, kashoMajorVersion, version.Version)
+	}
+
+	switch config.Version {
+	case "", ConfigV1:
+		return migrateConfigV1(config)
+	case ConfigV2:
+		return nil
+	default:
+		return fmt.Errorf("unsupported config version %q (supported: %s, %s)", config.Version, ConfigV1, ConfigV2)
+	}
+}
+
+// validateRouting rejects empty replica names and columns renamed onto the same replica column
+func validateRouting(routing RoutingConfig) error {
+	for source, target := range routing.Tables {
+		if target == "" {
+			return fmt.Errorf("routing: table %s has no replica table", source)
+		}
+	}
+	for table, columns := range routing.Columns {
+		targets := make(map[string]string, len(columns))
+		for source, target := range columns {
+			if target == "" {
+				return fmt.Errorf("routing: column %s.%s has no replica column", table, source)
+			}
+			if other, ok := targets[target]; ok {
+				return fmt.Errorf("routing: columns %s and %s of %s both map to %s", other, source, table, target)
+			}
+			targets[target] = source
+		}
+	}
+	return nil
+}
+
+// validateTypeMapping rejects column overrides without a type
+func validateTypeMapping(mapping TypeMappingConfig) error {
+	for table, columns := range mapping.Columns {
+		for column, replicaType := range columns {
+			if strings.TrimSpace(replicaType) == "" {
+				return fmt.Errorf("type_mapping: column %s.%s has no replica type", table, column)
+			}
+		}
+	}
+	return nil
+}
+
+// validateDDL checks that every DDL rule matches something and has a valid action
+func validateDDL(config DDLConfig) error {
+	for i, rule := range config.Rules {
+		if rule.Match == "" && !rule.Destructive {
+			return fmt.Errorf("ddl: rule %d needs 'match' or 'destructive: true'", i+1)
+		}
+		if rule.Match != "" {
+			if _, err := regexp.Compile(rule.Match); err != nil {
+				return fmt.Errorf("ddl: rule %d has an invalid match: %w", i+1, err)
+			}
+		}
+		switch rule.Action {
+		case DDLSkip, DDLPause:
+			if rule.SQL != "" {
+				return fmt.Errorf("ddl: rule %d has 'sql' but only replace rules use it", i+1)
+			}
+		case DDLReplace:
+			if strings.TrimSpace(rule.SQL) == "" {
+				return fmt.Errorf("ddl: replace rule %d needs 'sql'", i+1)
+			}
+		default:
+			return fmt.Errorf("ddl: rule %d has unknown action %q (expected %s, %s or %s)", i+1, rule.Action, DDLSkip, DDLReplace, DDLPause)
+		}
+	}
+	return nil
+}
+
+// GetTransformedValue generates a transformed value for a given table, column, and original value
+// For template and password transforms, it also accepts the full DMLData to provide row context
+func GetTransformedValue(c *Config, table string, column string, original *proto.ColumnValue, dmlData *proto.DMLData) (*proto.ColumnValue, error) {
+	colTransform, exists := c.ColumnTransform(table, column)
+	if !exists {
+		return nil, nil // not an error, just no transform for this column
+	}
+
+	// Unchanged TOAST values aren't in the change; the replica already holds the transformed value
+	if original.GetUnchangedToast() {
+		return nil, nil
+	}
+
+	// Handle Regex transform specially
+	if colTransform.Type == Regex {
+		// Extract pattern and replacement from config
+		pattern, ok := colTransform.Config["pattern"].(string)
+		if !ok {
+			return nil, fmt.Errorf("regex transform requires 'pattern' field")
+		}
+		replacement, ok := colTransform.Config["replacement"].(string)
+		if !ok {
+			return nil, fmt.Errorf("regex transform requires 'replacement' field")
+		}
+		
+		// Regex only works on string values
+		if v, ok := original.Value.(*proto.ColumnValue_StringValue); ok {
+			transformFunc := TransformRegex(pattern, replacement)
+			transformed, err := transformFunc(v.StringValue)
+			if err != nil {
+				return nil, fmt.Errorf("regex transform failed: %w", err)
+			}
+			return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: transformed}}, nil
+		}
+		return nil, fmt.Errorf("regex transform requires string value, got %T", original.Value)
+	}
+
+	// Handle Template transform specially
+	if colTransform.Type == Template {
+		// Extract template from config
+		templateStr, ok := colTransform.Config["template"].(string)
+		if !ok {
+			return nil, fmt.Errorf("template transform requires 'template' field")
+		}
+		
+		if dmlData == nil {
+			return nil, fmt.Errorf("template transform requires DML data for row context")
+		}
+		
+		// Build row context from DMLData
+		rowContext := make(map[string]*proto.ColumnValue)
+		for i, colName := range dmlData.ColumnNames {
+			if i < len(dmlData.ColumnValues) {
+				rowContext[colName] = dmlData.ColumnValues[i]
+			}
+		}
+		
+		transformed, err := TransformTemplate(templateStr, rowContext)
+		if err != nil {
+			return nil, fmt.Errorf("template transform failed: %w", err)
+		}
+		return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: transformed}}, nil
+	}
+
+	// Persona columns of a row are generated from the same fake identity
+	if colTransform.Type == Persona {
+		transformed, err := personaValue(c, table, colTransform, columnLocale(c, colTransform), original, dmlData)
+		if err != nil {
+			return nil, fmt.Errorf("persona transform failed: %w", err)
+		}
+		return transformed, nil
+	}
+
+	// Suppress returns nil for values that are common enough to keep
+	if colTransform.Type == Suppress {
+		transformed, err := suppressValue(table, column, colTransform.Config, original)
+		if err != nil {
+			return nil, fmt.Errorf("suppress transform failed: %w", err)
+		}
+		return transformed, nil
+	}
+
+	// Mask keeps int and float columns numeric
+	if colTransform.Type == Mask {
+		transformed, err := maskValue(colTransform.Config, original)
+		if err != nil {
+			return nil, fmt.Errorf("mask transform failed: %w", err)
+		}
+		return transformed, nil
+	}
+
+	// Numeric transforms keep int columns as ints; Bucket produces labels
+	if colTransform.Type == NumericNoise || colTransform.Type == Round || colTransform.Type == Bucket {
+		transformed, err := transformNumericValue(colTransform, original, dmlData)
+		if err != nil {
+			return nil, fmt.Errorf("%s transform failed: %w", colTransform.Type, err)
+		}
+		return transformed, nil
+	}
+
+	// DateShift moves every date of an entity by the same offset
+	if colTransform.Type == DateShift {
+		transformed, err := shiftDateValue(colTransform.Config, original, dmlData)
+		if err != nil {
+			return nil, fmt.Errorf("date shift transform failed: %w", err)
+		}
+		return transformed, nil
+	}
+
+	// Expr transforms keep the type of the expression's result
+	if colTransform.Type == Expr {
+		expression, ok := colTransform.Config["expr"].(string)
+		if !ok {
+			return nil, fmt.Errorf("expr transform requires 'expr' field")
+		}
+
+		if dmlData == nil {
+			return nil, fmt.Errorf("expr transform requires DML data for row context")
+		}
+
+		rowContext := make(map[string]*proto.ColumnValue)
+		for i, colName := range dmlData.ColumnNames {
+			if i < len(dmlData.ColumnValues) {
+				rowContext[colName] = dmlData.ColumnValues[i]
+			}
+		}
+
+		transformed, err := TransformExpr(expression, rowContext)
+		if err != nil {
+			return nil, fmt.Errorf("expr transform failed: %w", err)
+		}
+		return transformed, nil
+	}
+
+	// Plugins receive and return the value as text
+	if colTransform.Type == Plugin {
+		if original.GetValue() == nil {
+			return nil, nil
+		}
+		transformed, err := TransformPlugin(colTransform.Config, valueText(original))
+		if err != nil {
+			return nil, fmt.Errorf("plugin transform failed: %w", err)
+		}
+		return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: transformed}}, nil
+	}
+
+	// Handle Password transforms specially
+	if isPasswordType(colTransform.Type) {
+		return passwordValue(colTransform, original, dmlData)
+	}
+
+	// Fake transforms with locale data use it for string columns
+	if locale := columnLocale(c, colTransform); locale != "" {
+		if v, ok := original.Value.(*proto.ColumnValue_StringValue); ok {
+			if transformed, ok := TransformFakeLocalized(colTransform.Type, locale, v.StringValue); ok {
+				return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: transformed}}, nil
+			}
+		}
+	}
+
+	// For other transforms, use the existing logic
+	fn, err := colTransform.Type.GetTransformFunction()
+	if err != nil {
+		return nil, err
+	}
+
+	// Extract the raw value based on its type
+	var rawValue any
+	switch v := original.Value.(type) {
+	case *proto.ColumnValue_StringValue:
+		rawValue = v.StringValue
+	case *proto.ColumnValue_IntValue:
+		rawValue = v.IntValue
+	case *proto.ColumnValue_FloatValue:
+		rawValue = v.FloatValue
+	case *proto.ColumnValue_BoolValue:
+		rawValue = v.BoolValue
+	case *proto.ColumnValue_BytesValue:
+		rawValue = v.BytesValue
+	case *proto.ColumnValue_JsonValue:
+		rawValue = v.JsonValue
+	case *proto.ColumnValue_UuidValue:
+		rawValue = v.UuidValue
+	case *proto.ColumnValue_TimestampValue:
+		if t, err := time.Parse(time.RFC3339, v.TimestampValue); err == nil {
+			rawValue = t
+		} else {
+			rawValue = v.TimestampValue
+		}
+	default:
+		return nil, fmt.Errorf("unsupported value type: %T", original.Value)
+	}
+
+	// Apply the transform function
+	switch f := fn.(type) {
+	case func(string) string:
+		if str, ok := rawValue.(string); ok {
+			transformed := f(str)
+			return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: transformed}}, nil
+		}
+		return nil, fmt.Errorf("expected string input, got %T", rawValue)
+	case func(int) int:
+		if i, ok := rawValue.(int64); ok {
+			transformed := f(int(i))
+			return &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: int64(transformed)}}, nil
+		}
+		return nil, fmt.Errorf("expected int64 input, got %T", rawValue)
+	case func(float64) float64:
+		if flt, ok := rawValue.(float64); ok {
+			transformed := f(flt)
+			return &proto.ColumnValue{Value: &proto.ColumnValue_FloatValue{FloatValue: transformed}}, nil
+		}
+		return nil, fmt.Errorf("expected float64 input, got %T", rawValue)
+	case func(bool) bool:
+		if b, ok := rawValue.(bool); ok {
+			transformed := f(b)
+			return &proto.ColumnValue{Value: &proto.ColumnValue_BoolValue{BoolValue: transformed}}, nil
+		}
+		return nil, fmt.Errorf("expected bool input, got %T", rawValue)
+	case func([]byte) []byte:
+		if b, ok := rawValue.([]byte); ok {
+			transformed := f(b)
+			return &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: transformed}}, nil
+		}
+		return nil, fmt.Errorf("expected bytes input, got %T", rawValue)
+	case func(time.Time) time.Time:
+		if t, ok := rawValue.(time.Time); ok {
+			transformed := f(t)
+			return &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: transformed.Format(time.RFC3339)}}, nil
+		}
+		return nil, fmt.Errorf("expected time.Time input, got %T", rawValue)
+	default:
+		return nil, fmt.Errorf("unsupported function type: %T", fn)
+	}
+}
+
+// transformOldKeys transforms the old key values of an UPDATE or DELETE. They identify the
+// replica row, which holds transformed values, so key columns with a transform must be
+// transformed the same way as new values. Only the key columns are available to templates.
+func transformOldKeys(c *Config, dml *proto.DMLData, oldKeys *proto.OldKeys) (*proto.OldKeys, error) {
+	result := &proto.OldKeys{
+		KeyNames:  make([]string, len(oldKeys.KeyNames)),
+		KeyValues: make([]*proto.ColumnValue, len(oldKeys.KeyValues)),
+	}
+	copy(result.KeyNames, oldKeys.KeyNames)
+	copy(result.KeyValues, oldKeys.KeyValues)
+
+	if !c.TransformsTable(dml.Table) {
+		return result, nil
+	}
+
+	oldRow, err := TransformChange(c, &proto.Change{
+		Type: "dml",
+		Data: &proto.Change_Dml{Dml: &proto.DMLData{
+			Table:        dml.Table,
+			Schema:       dml.Schema,
+			ColumnNames:  oldKeys.KeyNames,
+			ColumnValues: oldKeys.KeyValues,
+			Kind:         dml.Kind,
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error transforming old keys: %w", err)
+	}
+	copy(result.KeyValues, oldRow.GetDml().ColumnValues)
+	return result, nil
+}
+
+// GetTransformFunction returns the corresponding fake function for a TransformType
+func (ft TransformType) GetTransformFunction() (any, error) {
+	if fn, exists := transformFunctions[ft]; exists {
+		return fn, nil
+	}
+	return nil, fmt.Errorf("unknown transform type: %s", ft)
+}
+
+// TransformChange takes a Change object and returns a new Change object with transformed values
+// Uses a two-pass strategy: first processes non-Template transforms, then Template transforms
+// with access to the already-transformed row data
+func TransformChange(c *Config, change *proto.Change) (*proto.Change, error) {
+	// Create a new Change object to avoid modifying the original
+	newChange := &proto.Change{
+		Position:        change.Position,
+		Type:            change.Type,
+		ChangeType:      change.ChangeType,
+		SourceDialect:   change.SourceDialect,
+		Database:        change.Database,
+		Schema:          change.Schema,
+		TransactionId:   change.TransactionId,
+		CommitTimestamp: change.CommitTimestamp,
+		Metadata:        change.Metadata,
+	}
+
+	switch data := change.Data.(type) {
+	case *proto.Change_Dml:
+		// Create a new DMLData object
+		newDML := &proto.DMLData{
+			Table:        data.Dml.Table,
+			ColumnNames:  make([]string, len(data.Dml.ColumnNames)),
+			ColumnValues: make([]*proto.ColumnValue, len(data.Dml.ColumnValues)),
+			Kind:         data.Dml.Kind,
+			PrimaryKey:   data.Dml.PrimaryKey,
+			Schema:       data.Dml.Schema,
+			DmlKind:      data.Dml.DmlKind,
+		}
+		copy(newDML.ColumnNames, data.Dml.ColumnNames)
+
+		// PASS 1: Transform all non-Template columns first
+		for i, col := range newDML.ColumnNames {
+			// Check if this column has a transform configured
+			colTransform, colExists := c.ColumnTransform(newDML.Table, col)
+			if !colExists {
+				// No transform for this column, copy original value
+				newDML.ColumnValues[i] = data.Dml.ColumnValues[i]
+				continue
+			}
+			
+			// Skip Template, Expr and Password transforms in this pass
+			if colTransform.Type == Template || colTransform.Type == Expr ||
+				colTransform.Type == PasswordBcrypt ||
+				colTransform.Type == PasswordScrypt ||
+				colTransform.Type == PasswordPBKDF2 ||
+				colTransform.Type == PasswordArgon2id {
+				// For now, copy the original value (will be replaced in pass 2)
+				newDML.ColumnValues[i] = data.Dml.ColumnValues[i]
+				continue
+			}
+			
+			// Process non-Template transforms
+			transformed, err := GetTransformedValue(c, newDML.Table, col, data.Dml.ColumnValues[i], data.Dml)
+			if err != nil {
+				return nil, fmt.Errorf("error transforming %s.%s: %w", newDML.Table, col, err)
+			}
+			if transformed != nil {
+				newDML.ColumnValues[i] = transformed
+			} else {
+				// If no transformation, copy the original value
+				newDML.ColumnValues[i] = data.Dml.ColumnValues[i]
+			}
+		}
+
+		// PASS 2: Process Template and Password transforms with access to transformed row data
+		for i, col := range newDML.ColumnNames {
+			// Check if this column has a Template or Password transform configured
+			colTransform, colExists := c.ColumnTransform(newDML.Table, col)
+			if !colExists {
+				continue
+			}
+			
+			// Check if it's a Template or Password transform
+			isPass2Transform := colTransform.Type == Template || colTransform.Type == Expr ||
+				colTransform.Type == PasswordBcrypt ||
+				colTransform.Type == PasswordScrypt ||
+				colTransform.Type == PasswordPBKDF2 ||
+				colTransform.Type == PasswordArgon2id
+			
+			if !isPass2Transform {
+				continue
+			}
+			
+			// Create updated DMLData with transformed values for template context
+			updatedDMLData := &proto.DMLData{
+				Table:        newDML.Table,
+				ColumnNames:  newDML.ColumnNames,
+				ColumnValues: newDML.ColumnValues, // Use the transformed values from pass 1
+				Kind:         newDML.Kind,
+			}
+			
+			// Process Template transform with updated context
+			transformed, err := GetTransformedValue(c, newDML.Table, col, data.Dml.ColumnValues[i], updatedDMLData)
+			if err != nil {
+				return nil, fmt.Errorf("error transforming template %s.%s: %w", newDML.Table, col, err)
+			}
+			if transformed != nil {
+				newDML.ColumnValues[i] = transformed
+			}
+		}
+
+		// PASS 3: Disambiguate values of unique columns that collide
+		if err := applyUnique(c, data.Dml, newDML); err != nil {
+			return nil, err
+		}
+
+		// Transform old keys if present
+		if data.Dml.OldKeys != nil {
+			oldKeys, err := transformOldKeys(c, newDML, data.Dml.OldKeys)
+			if err != nil {
+				return nil, err
+			}
+			newDML.OldKeys = oldKeys
+		}
+
+		newChange.Data = &proto.Change_Dml{Dml: newDML}
+
+	case *proto.Change_Ddl:
+		// For DDL changes, just copy the DDL data
+		newChange.Data = &proto.Change_Ddl{
+			Ddl: &proto.DDLData{
+				Ddl: data.Ddl.Ddl,
+			},
+		}
+
+	case *proto.Change_Message:
+		// Messages carry no row data, so they pass through untransformed
+		newChange.Data = &proto.Change_Message{
+			Message: &proto.MessageData{
+				Prefix:        data.Message.Prefix,
+				Content:       data.Message.Content,
+				Transactional: data.Message.Transactional,
+			},
+		}
+	}
+
+	return newChange, nil
+}
@@ -16,9 +16,10 @@ func TestGetTransformedValue(t *testing.T) {
 		MajorVersion: 0,
 		Tables: map[string]TableConfig{
 			"users": {
-				"name":  {Type: FakeName},
-				"age":   {Type: FakeYear},
-				"email": {Type: FakeEmail},
+				"name":   {Type: FakeName},
+				"age":    {Type: FakeYear},
+				"email":  {Type: FakeEmail},
+				"avatar": {Type: HashBytes},
 			},
 		},
 	}
@@ -67,6 +68,18 @@ func TestGetTransformedValue(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:   "hash bytes",
+			table:  "users",
+			column: "avatar",
+			original: &proto.ColumnValue{
+				Value: &proto.ColumnValue_BytesValue{BytesValue: []byte{0x01}},
+			},
+			want: &proto.ColumnValue{
+				Value: &proto.ColumnValue_BytesValue{BytesValue: []byte{0x4b, 0xf5, 0x12, 0x2f, 0x34, 0x45, 0x54, 0xc5, 0x3b, 0xde, 0x2e, 0xbb, 0x8c, 0xd2, 0xb7, 0xe3, 0xd1, 0x60, 0x0a, 0xd6, 0x31, 0xc3, 0x85, 0xa5, 0xd7, 0xcc, 0xe2, 0x3c, 0x77, 0x85, 0x45, 0x9a}},
+			},
+			wantErr: false,
+		},
 		{
 			name:   "no transform for unknown table",
 			table:  "unknown",
@@ -230,6 +243,71 @@ tables:
     name: FakeName`,
 			wantError: false,
 		},
+		{
+			name: "config with generated column overrides",
+			content: `major_version: 0
+tables:
+  users:
+    name: FakeName
+generated_columns:
+  public.orders: [total]
+  public.invoices: []`,
+			wantError: false,
+		},
+		{
+			name: "config with routing",
+			content: `major_version: 0
+tables:
+  public.users:
+    name: FakeName
+routing:
+  tables:
+    public.users: warehouse.dim_users
+  columns:
+    public.users:
+      name: full_name
+      email: email_address`,
+			wantError: false,
+		},
+		{
+			name: "routing two columns onto one",
+			content: `major_version: 0
+routing:
+  columns:
+    users:
+      first_name: name
+      last_name: name`,
+			wantError: true,
+		},
+		{
+			name: "routing to an empty table",
+			content: `major_version: 0
+routing:
+  tables:
+    users: ""`,
+			wantError: true,
+		},
+		{
+			name: "config with type mapping overrides",
+			content: `major_version: 0
+tables:
+  public.users:
+    name: FakeName
+type_mapping:
+  columns:
+    public.users:
+      email: VARCHAR(1024)`,
+			wantError: false,
+		},
+		{
+			name: "type mapping without a type",
+			content: `major_version: 0
+type_mapping:
+  columns:
+    public.users:
+      email: ""`,
+			wantError: true,
+		},
 		{
 			name: "invalid yaml",
 			content: `major_version: 0
@@ -1222,3 +1300,325 @@ func TestTransformChangeWithCrossColumnTemplates(t *testing.T) {
 	t.Logf("Transformed email: %s", transformedEmail)
 	t.Logf("Transformed username: %s", transformedUsername)
 }
+
+func TestColumnTransformDeterministic(t *testing.T) {
+	tests := []struct {
+		transformType TransformType
+		want          bool
+	}{
+		{FakeName, true},
+		{Regex, true},
+		{Template, true},
+		{PasswordScrypt, true},
+		{PasswordBcrypt, false},
+		{Suppress, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.transformType), func(t *testing.T) {
+			ct := ColumnTransform{Type: tt.transformType}
+			if got := ct.Deterministic(); got != tt.want {
+				t.Errorf("Deterministic() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformChangeUnchangedToast(t *testing.T) {
+	config := &Config{
+		Tables: map[string]TableConfig{
+			"public.documents": {
+				"title": {Type: FakeName},
+				"body":  {Type: FakeParagraph},
+			},
+		},
+	}
+
+	unchanged := &proto.ColumnValue{Value: &proto.ColumnValue_UnchangedToast{UnchangedToast: true}}
+	change := &proto.Change{
+		Type: "dml",
+		Data: &proto.Change_Dml{
+			Dml: &proto.DMLData{
+				Table:       "public.documents",
+				ColumnNames: []string{"title", "body"},
+				ColumnValues: []*proto.ColumnValue{
+					{Value: &proto.ColumnValue_StringValue{StringValue: "Original title"}},
+					unchanged,
+				},
+				Kind: "update",
+			},
+		},
+	}
+
+	result, err := TransformChange(config, change)
+	if err != nil {
+		t.Fatalf("TransformChange() error = %v", err)
+	}
+
+	values := result.GetDml().ColumnValues
+	if values[0].GetStringValue() == "Original title" {
+		t.Error("Expected title to be transformed")
+	}
+	if !values[1].GetUnchangedToast() {
+		t.Errorf("Expected body to stay marked as unchanged TOAST, got %v", values[1])
+	}
+}
+
+func TestTransformChangeNativeTypes(t *testing.T) {
+	config := &Config{
+		Tables: map[string]TableConfig{
+			"app.places": {
+				"notes": {Type: FakeParagraph},
+				"label": {Type: Template, Config: map[string]any{"template": "{{.id}} at {{.location}}"}},
+			},
+		},
+	}
+
+	change := &proto.Change{
+		Type: "dml",
+		Data: &proto.Change_Dml{
+			Dml: &proto.DMLData{
+				Table:       "app.places",
+				ColumnNames: []string{"id", "notes", "location", "label"},
+				ColumnValues: []*proto.ColumnValue{
+					{Value: &proto.ColumnValue_UuidValue{UuidValue: "123e4567-e89b-12d3-a456-426614174000"}},
+					{Value: &proto.ColumnValue_JsonValue{JsonValue: `{"secret":"value"}`}},
+					{Value: &proto.ColumnValue_GeometryValue{GeometryValue: &proto.Geometry{Wkt: "POINT(1 2)", Srid: 4326}}},
+					{Value: &proto.ColumnValue_StringValue{StringValue: "original"}},
+				},
+				Kind: "insert",
+			},
+		},
+	}
+
+	result, err := TransformChange(config, change)
+	if err != nil {
+		t.Fatalf("TransformChange() error = %v", err)
+	}
+
+	values := result.GetDml().ColumnValues
+	if values[0].GetUuidValue() != "123e4567-e89b-12d3-a456-426614174000" {
+		t.Errorf("Expected untransformed UUID to pass through, got %v", values[0])
+	}
+	if values[1].GetStringValue() == "" || values[1].GetStringValue() == `{"secret":"value"}` {
+		t.Errorf("Expected JSON column to be transformed, got %v", values[1])
+	}
+	if values[2].GetGeometryValue().GetWkt() != "POINT(1 2)" {
+		t.Errorf("Expected untransformed geometry to pass through, got %v", values[2])
+	}
+	if got := values[3].GetStringValue(); got != "123e4567-e89b-12d3-a456-426614174000 at POINT(1 2)" {
+		t.Errorf("Template result = %q", got)
+	}
+}
+
+func TestTransformChangeKeepsMetadata(t *testing.T) {
+	config := &Config{
+		Tables: map[string]TableConfig{
+			"users": {"name": {Type: FakeName}},
+		},
+	}
+
+	change := &proto.Change{
+		Type:            "dml",
+		ChangeType:      proto.ChangeType_CHANGE_TYPE_DML,
+		SourceDialect:   proto.SourceDialect_SOURCE_DIALECT_POSTGRESQL,
+		Database:        "shop",
+		Schema:          "app1",
+		TransactionId:   "7421",
+		CommitTimestamp: "2024-03-20T15:00:00Z",
+		Metadata:        map[string]string{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+		Data: &proto.Change_Dml{
+			Dml: &proto.DMLData{
+				Table:        "users",
+				Schema:       "app1",
+				ColumnNames:  []string{"id", "name"},
+				ColumnValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_IntValue{IntValue: 1}}, {Value: &proto.ColumnValue_StringValue{StringValue: "Alice"}}},
+				Kind:         "insert",
+				DmlKind:      proto.DMLKind_DML_KIND_INSERT,
+				PrimaryKey:   []string{"id"},
+			},
+		},
+	}
+
+	result, err := TransformChange(config, change)
+	if err != nil {
+		t.Fatalf("TransformChange() error = %v", err)
+	}
+
+	if result.ChangeType != change.ChangeType || result.SourceDialect != change.SourceDialect ||
+		result.Database != change.Database || result.Schema != change.Schema ||
+		result.TransactionId != change.TransactionId || result.CommitTimestamp != change.CommitTimestamp ||
+		result.Metadata["traceparent"] != change.Metadata["traceparent"] {
+		t.Errorf("source metadata not kept: %v", result)
+	}
+
+	dml := result.GetDml()
+	if dml.DmlKind != proto.DMLKind_DML_KIND_INSERT {
+		t.Errorf("DmlKind = %v, want DML_KIND_INSERT", dml.DmlKind)
+	}
+	if dml.Schema != "app1" {
+		t.Errorf("Schema = %q, want app1", dml.Schema)
+	}
+	if len(dml.PrimaryKey) != 1 || dml.PrimaryKey[0] != "id" {
+		t.Errorf("PrimaryKey = %v, want [id]", dml.PrimaryKey)
+	}
+}
+
+func TestTransformChangeMessage(t *testing.T) {
+	change := &proto.Change{
+		Position:   "0/490",
+		Type:       "message",
+		ChangeType: proto.ChangeType_CHANGE_TYPE_MESSAGE,
+		Data: &proto.Change_Message{
+			Message: &proto.MessageData{Prefix: "deploy", Content: []byte("deploy-123 complete")},
+		},
+	}
+
+	result, err := TransformChange(&Config{}, change)
+	if err != nil {
+		t.Fatalf("TransformChange() error = %v", err)
+	}
+	message := result.GetMessage()
+	if message == nil || message.Prefix != "deploy" || string(message.Content) != "deploy-123 complete" {
+		t.Errorf("message = %v, want the deploy marker", message)
+	}
+}
+
+func TestTransformChangeOldKeys(t *testing.T) {
+	config := &Config{
+		Tables: map[string]TableConfig{
+			"users": {"email": {Type: FakeEmail}},
+		},
+	}
+
+	email := &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: "alice@example.com"}}
+	change := &proto.Change{
+		Type: "dml",
+		Data: &proto.Change_Dml{
+			Dml: &proto.DMLData{
+				Table:        "users",
+				ColumnNames:  []string{"email", "name"},
+				ColumnValues: []*proto.ColumnValue{email, {Value: &proto.ColumnValue_StringValue{StringValue: "Alice"}}},
+				Kind:         "update",
+				OldKeys: &proto.OldKeys{
+					KeyNames:  []string{"email"},
+					KeyValues: []*proto.ColumnValue{email},
+				},
+			},
+		},
+	}
+
+	result, err := TransformChange(config, change)
+	if err != nil {
+		t.Fatalf("TransformChange() error = %v", err)
+	}
+
+	dml := result.GetDml()
+	newEmail := dml.ColumnValues[0].GetStringValue()
+	oldEmail := dml.OldKeys.KeyValues[0].GetStringValue()
+	if oldEmail == "alice@example.com" {
+		t.Error("old key value was not transformed")
+	}
+	if oldEmail != newEmail {
+		t.Errorf("old key = %q, want %q to match the transformed new value", oldEmail, newEmail)
+	}
+	if len(dml.OldKeys.KeyNames) != 1 || dml.OldKeys.KeyNames[0] != "email" {
+		t.Errorf("KeyNames = %v, want [email]", dml.OldKeys.KeyNames)
+	}
+	if change.GetDml().OldKeys.KeyValues[0].GetStringValue() != "alice@example.com" {
+		t.Error("original old keys were modified")
+	}
+}
+
+func TestValidateDDL(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []DDLRule
+		wantErr string
+	}{
+		{"valid rules", []DDLRule{
+			{Match: `(?i)^DROP TABLE .*_tmp`, Action: DDLSkip},
+			{Match: `(?i)^CREATE INDEX`, Action: DDLReplace, SQL: "SELECT 1"},
+			{Destructive: true, Action: DDLPause},
+		}, ""},
+		{"nothing to match", []DDLRule{{Action: DDLSkip}}, "needs 'match' or 'destructive: true'"},
+		{"invalid pattern", []DDLRule{{Match: `(`, Action: DDLSkip}}, "invalid match"},
+		{"replace without sql", []DDLRule{{Match: `x`, Action: DDLReplace}}, "needs 'sql'"},
+		{"sql on a skip rule", []DDLRule{{Match: `x`, Action: DDLSkip, SQL: "SELECT 1"}}, "only replace rules use it"},
+		{"unknown action", []DDLRule{{Match: `x`, Action: "drop"}}, `unknown action "drop"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDDL(DDLConfig{Rules: tt.rules})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateDDL() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateDDL() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// benchmarkChange is an insert into public.users with a mix of transformed and plain columns
+func benchmarkChange() *proto.Change {
+	return &proto.Change{
+		Position: "0/1000",
+		Type:     "dml",
+		Data: &proto.Change_Dml{
+			Dml: &proto.DMLData{
+				Table:       "public.users",
+				Kind:        "insert",
+				ColumnNames: []string{"id", "name", "email", "phone", "bio", "username", "active"},
+				ColumnValues: []*proto.ColumnValue{
+					{Value: &proto.ColumnValue_IntValue{IntValue: 1}},
+					{Value: &proto.ColumnValue_StringValue{StringValue: "John Doe"}},
+					{Value: &proto.ColumnValue_StringValue{StringValue: "john@example.com"}},
+					{Value: &proto.ColumnValue_StringValue{StringValue: "555-123-4567"}},
+					{Value: &proto.ColumnValue_StringValue{StringValue: "Enjoys long walks and short queries."}},
+					{Value: &proto.ColumnValue_StringValue{StringValue: "jdoe"}},
+					{Value: &proto.ColumnValue_BoolValue{BoolValue: true}},
+				},
+			},
+		},
+	}
+}
+
+func BenchmarkTransformChange(b *testing.B) {
+	benchmarks := []struct {
+		name  string
+		table TableConfig
+	}{
+		{name: "passthrough", table: TableConfig{}},
+		{name: "fake", table: TableConfig{
+			"name":  {Type: FakeName},
+			"email": {Type: FakeEmail},
+			"phone": {Type: FakePhone},
+			"bio":   {Type: FakeParagraph},
+		}},
+		{name: "regex", table: TableConfig{
+			"phone": {Type: Regex, Config: map[string]any{"pattern": `\d`, "replacement": "X"}},
+		}},
+		{name: "template", table: TableConfig{
+			"name":     {Type: FakeName},
+			"email":    {Type: Template, Config: map[string]any{"template": "{{.name | lower | slugify}}@example.com"}},
+			"username": {Type: Template, Config: map[string]any{"template": "{{.name | lower}}_user"}},
+		}},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			config := &Config{Tables: map[string]TableConfig{"public.users": bm.table}}
+			change := benchmarkChange()
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := TransformChange(config, change); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
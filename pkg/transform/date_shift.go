@@ -0,0 +1,111 @@
+package transform
+
+import (
+	"fmt"
+
+	"kasho/proto"
+)
+
+const defaultDateShiftMaxDays = 365
+
+// dateShiftDays returns the offset in days for an entity: a non-zero number between
+// -maxDays and maxDays derived from the salted key, so every date of the entity moves by
+// the same amount
+func dateShiftDays(salt, key string, maxDays int) int {
+	days := int(hash(salt+key)%uint64(2*maxDays)) - maxDays
+	if days >= 0 {
+		days++
+	}
+	return days
+}
+
+// TransformDateShift shifts a date by the entity's offset, keeping its format. Dates of the
+// same entity keep their intervals, e.g. the time between signup and first purchase.
+func TransformDateShift(value, salt, key string, maxDays int) (string, error) {
+	t, layout, err := parseTemplateTime(value)
+	if err != nil {
+		return "", err
+	}
+	return t.AddDate(0, 0, dateShiftDays(salt, key, maxDays)).Format(layout), nil
+}
+
+// dateShiftConfig reads the key column, salt and maximum offset of a DateShift transform
+func dateShiftConfig(config map[string]any) (key, salt string, maxDays int, err error) {
+	key, _ = config["key"].(string)
+	if key == "" {
+		return "", "", 0, fmt.Errorf("date shift transform requires 'key' field")
+	}
+	salt, _ = config["salt"].(string)
+
+	maxDays = defaultDateShiftMaxDays
+	switch v := config["max_days"].(type) {
+	case nil:
+	case int:
+		maxDays = v
+	case float64:
+		maxDays = int(v)
+	default:
+		return "", "", 0, fmt.Errorf("date shift 'max_days' must be a number")
+	}
+	if maxDays <= 0 {
+		return "", "", 0, fmt.Errorf("date shift 'max_days' must be positive")
+	}
+	return key, salt, maxDays, nil
+}
+
+// shiftDateValue applies a DateShift transform to a column, taking the entity key from the row
+func shiftDateValue(config map[string]any, original *proto.ColumnValue, dmlData *proto.DMLData) (*proto.ColumnValue, error) {
+	key, salt, maxDays, err := dateShiftConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if dmlData == nil {
+		return nil, fmt.Errorf("date shift transform requires DML data for row context")
+	}
+
+	keyIndex := -1
+	for i, colName := range dmlData.ColumnNames {
+		if colName == key && i < len(dmlData.ColumnValues) {
+			keyIndex = i
+			break
+		}
+	}
+	if keyIndex < 0 {
+		return nil, fmt.Errorf("date shift key column %q not in row", key)
+	}
+	keyText := valueText(dmlData.ColumnValues[keyIndex])
+
+	switch v := original.Value.(type) {
+	case nil:
+		return nil, nil
+	case *proto.ColumnValue_TimestampValue:
+		shifted, err := TransformDateShift(v.TimestampValue, salt, keyText, maxDays)
+		if err != nil {
+			return nil, err
+		}
+		return &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: shifted}}, nil
+	case *proto.ColumnValue_StringValue:
+		shifted, err := TransformDateShift(v.StringValue, salt, keyText, maxDays)
+		if err != nil {
+			return nil, err
+		}
+		return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: shifted}}, nil
+	default:
+		return nil, fmt.Errorf("date shift transform requires a date or timestamp value, got %T", original.Value)
+	}
+}
+
+// validateDateShifts checks the settings of the DateShift transforms of a config
+func validateDateShifts(config *Config) error {
+	for table, columns := range config.Tables {
+		for column, ct := range columns {
+			if ct.Type != DateShift {
+				continue
+			}
+			if _, _, _, err := dateShiftConfig(ct.Config); err != nil {
+				return fmt.Errorf("%s.%s: %w", table, column, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package transform
+
+import (
+	"testing"
+	"time"
+
+	"kasho/proto"
+)
+
+func TestDateShiftDays(t *testing.T) {
+	for i := range 1000 {
+		key := time.Duration(i).String()
+		days := dateShiftDays("salt", key, 30)
+		if days == 0 || days < -30 || days > 30 {
+			t.Fatalf("dateShiftDays(%q) = %d, want a non-zero offset within 30 days", key, days)
+		}
+		if again := dateShiftDays("salt", key, 30); again != days {
+			t.Fatalf("dateShiftDays(%q) is not deterministic: %d != %d", key, days, again)
+		}
+	}
+}
+
+func TestTransformDateShiftPreservesIntervals(t *testing.T) {
+	signup, err := TransformDateShift("2024-01-10T08:00:00Z", "", "user-7", 365)
+	if err != nil {
+		t.Fatalf("TransformDateShift() unexpected error: %v", err)
+	}
+	purchase, err := TransformDateShift("2024-02-20 17:30:00", "", "user-7", 365)
+	if err != nil {
+		t.Fatalf("TransformDateShift() unexpected error: %v", err)
+	}
+
+	signupTime, _ := time.Parse(time.RFC3339, signup)
+	purchaseTime, _ := time.Parse("2006-01-02 15:04:05", purchase)
+	if got, want := purchaseTime.Sub(signupTime), 41*24*time.Hour+9*time.Hour+30*time.Minute; got != want {
+		t.Errorf("interval = %v, want %v", got, want)
+	}
+	if signup == "2024-01-10T08:00:00Z" {
+		t.Error("date was not shifted")
+	}
+
+	// A different salt gives the entity a different offset
+	if dateShiftDays("other", "user-7", 365) == dateShiftDays("", "user-7", 365) {
+		t.Error("salt does not change the offset")
+	}
+
+	if _, err := TransformDateShift("not a date", "", "user-7", 365); err == nil {
+		t.Error("TransformDateShift() expected error for an invalid date")
+	}
+}
+
+func TestTransformChangeWithDateShift(t *testing.T) {
+	config := &Config{
+		Tables: map[string]TableConfig{
+			"users": {
+				"signed_up_at": {Type: DateShift, Config: map[string]any{"key": "id", "max_days": 90}},
+			},
+			"orders": {
+				"ordered_on": {Type: DateShift, Config: map[string]any{"key": "user_id", "max_days": 90}},
+			},
+		},
+	}
+
+	users, err := TransformChange(config, &proto.Change{
+		Type: "dml",
+		Data: &proto.Change_Dml{Dml: &proto.DMLData{
+			Table:       "users",
+			ColumnNames: []string{"id", "signed_up_at"},
+			ColumnValues: []*proto.ColumnValue{
+				{Value: &proto.ColumnValue_IntValue{IntValue: 7}},
+				{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2024-01-10T00:00:00Z"}},
+			},
+			Kind: "insert",
+		}},
+	})
+	if err != nil {
+		t.Fatalf("TransformChange() unexpected error: %v", err)
+	}
+	orders, err := TransformChange(config, &proto.Change{
+		Type: "dml",
+		Data: &proto.Change_Dml{Dml: &proto.DMLData{
+			Table:       "orders",
+			ColumnNames: []string{"user_id", "ordered_on"},
+			ColumnValues: []*proto.ColumnValue{
+				{Value: &proto.ColumnValue_IntValue{IntValue: 7}},
+				{Value: &proto.ColumnValue_StringValue{StringValue: "2024-01-15"}},
+			},
+			Kind: "insert",
+		}},
+	})
+	if err != nil {
+		t.Fatalf("TransformChange() unexpected error: %v", err)
+	}
+
+	// The same entity is shifted by the same offset in every table
+	signup, _ := time.Parse(time.RFC3339, users.GetDml().ColumnValues[1].GetTimestampValue())
+	order, _ := time.Parse("2006-01-02", orders.GetDml().ColumnValues[1].GetStringValue())
+	if got := order.Sub(signup); got != 5*24*time.Hour {
+		t.Errorf("interval between tables = %v, want 5 days", got)
+	}
+}
+
+func TestDateShiftConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]any
+		want    int
+		wantErr bool
+	}{
+		{"defaults", map[string]any{"key": "id"}, defaultDateShiftMaxDays, false},
+		{"yaml integer", map[string]any{"key": "id", "max_days": 30}, 30, false},
+		{"float", map[string]any{"key": "id", "max_days": 30.0}, 30, false},
+		{"missing key", map[string]any{}, 0, true},
+		{"zero days", map[string]any{"key": "id", "max_days": 0}, 0, true},
+		{"not a number", map[string]any{"key": "id", "max_days": "30"}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, maxDays, err := dateShiftConfig(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("dateShiftConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && maxDays != tt.want {
+				t.Errorf("max days = %d, want %d", maxDays, tt.want)
+			}
+		})
+	}
+}
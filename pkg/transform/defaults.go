@@ -0,0 +1,168 @@
+package transform
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Config schema versions. v2 reads a table's default key as the transform of its columns
+// without one of their own and column names with wildcards as patterns, e.g.
+// *_email: FakeEmail, and adds defaults, which apply to matching columns of every table.
+const (
+	ConfigV1 = "v1"
+	ConfigV2 = "v2"
+)
+
+// tableDefaultKey holds the transform of a table's columns that match nothing else in v2
+const tableDefaultKey = "default"
+
+// DefaultTransform applies a transform to the columns of every table whose name matches
+// a regular expression, unless the table configures the column itself
+type DefaultTransform struct {
+	Match     string          `yaml:"match"`
+	Transform ColumnTransform `yaml:"transform"`
+}
+
+var (
+	defaultPatternsMu sync.RWMutex
+	defaultPatterns   = map[string]*regexp.Regexp{}
+)
+
+// defaultPattern returns the compiled regular expression of a default from cache or
+// compiles and caches it
+func defaultPattern(match string) (*regexp.Regexp, error) {
+	defaultPatternsMu.RLock()
+	re, exists := defaultPatterns[match]
+	defaultPatternsMu.RUnlock()
+	if exists {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(match)
+	if err != nil {
+		return nil, err
+	}
+	defaultPatternsMu.Lock()
+	defaultPatterns[match] = re
+	defaultPatternsMu.Unlock()
+	return re, nil
+}
+
+// isWildcard reports whether a column key is a pattern in v2
+func isWildcard(key string) bool {
+	return strings.ContainsAny(key, "*?[")
+}
+
+// ColumnTransform returns the transform of a column. The column's own transform comes
+// first; in v2 configs then the most specific wildcard matching it, the longest, then the
+// table's default and then the first of the config's defaults matching it.
+func (c *Config) ColumnTransform(table, column string) (ColumnTransform, bool) {
+	columns := c.Tables[table]
+	if ct, ok := columns[column]; ok {
+		return ct, true
+	}
+	if c.Version != ConfigV2 {
+		return ColumnTransform{}, false
+	}
+
+	best := ""
+	for key := range columns {
+		if !isWildcard(key) || c.literalColumns[table][key] {
+			continue
+		}
+		if matched, _ := path.Match(key, column); !matched {
+			continue
+		}
+		if best == "" || len(key) > len(best) || (len(key) == len(best) && key < best) {
+			best = key
+		}
+	}
+	if best != "" {
+		return columns[best], true
+	}
+
+	if ct, ok := columns[tableDefaultKey]; ok && !c.literalColumns[table][tableDefaultKey] {
+		return ct, true
+	}
+
+	for _, d := range c.Defaults {
+		re, err := defaultPattern(d.Match)
+		if err == nil && re.MatchString(column) {
+			return d.Transform, true
+		}
+	}
+	return ColumnTransform{}, false
+}
+
+// TransformsTable reports whether columns of a table may have a transform
+func (c *Config) TransformsTable(table string) bool {
+	if _, ok := c.Tables[table]; ok {
+		return true
+	}
+	return c.Version == ConfigV2 && len(c.Defaults) > 0
+}
+
+// migrateConfigV1 migrates a v1 config to v2. v1 only has column names, so keys v2 would
+// read as a wildcard or the table's default keep matching only the column named like them.
+func migrateConfigV1(config *Config) error {
+	if len(config.Defaults) > 0 {
+		return fmt.Errorf("defaults require version %s", ConfigV2)
+	}
+	for table, columns := range config.Tables {
+		for column := range columns {
+			if column != tableDefaultKey && !isWildcard(column) {
+				continue
+			}
+			if config.literalColumns == nil {
+				config.literalColumns = make(map[string]map[string]bool)
+			}
+			if config.literalColumns[table] == nil {
+				config.literalColumns[table] = make(map[string]bool)
+			}
+			config.literalColumns[table][column] = true
+		}
+	}
+	config.Version = ConfigV2
+	return nil
+}
+
+// validateDefaults checks the wildcards and defaults of a v2 config. The transforms of
+// defaults are checked like those of columns, under the table name "defaults".
+func validateDefaults(config *Config, validators ...func(*Config) error) error {
+	for table, columns := range config.Tables {
+		for column := range columns {
+			if !isWildcard(column) || config.literalColumns[table][column] {
+				continue
+			}
+			if _, err := path.Match(column, ""); err != nil {
+				return fmt.Errorf("%s: invalid column wildcard %q: %w", table, column, err)
+			}
+		}
+	}
+
+	if len(config.Defaults) == 0 {
+		return nil
+	}
+	defaults := &Config{Version: config.Version, Locale: config.Locale, Tables: map[string]TableConfig{"defaults": {}}}
+	for _, d := range config.Defaults {
+		if d.Match == "" {
+			return fmt.Errorf("defaults: every default requires 'match'")
+		}
+		if _, err := defaultPattern(d.Match); err != nil {
+			return fmt.Errorf("defaults: invalid match %q: %w", d.Match, err)
+		}
+		if d.Transform.Type == "" {
+			return fmt.Errorf("defaults: default for %q requires 'transform'", d.Match)
+		}
+		defaults.Tables["defaults"][d.Match] = d.Transform
+	}
+	for _, validate := range validators {
+		if err := validate(defaults); err != nil {
+			return err
+		}
+	}
+	return nil
+}
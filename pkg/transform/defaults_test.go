@@ -0,0 +1,180 @@
+package transform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kasho/proto"
+)
+
+func TestConfig_ColumnTransform(t *testing.T) {
+	config := &Config{
+		Version: ConfigV2,
+		Tables: map[string]TableConfig{
+			"users": {
+				"name":           {Type: FakeName},
+				"*_email":        {Type: FakeEmail},
+				"billing_*":      {Type: FakeStreetAddress},
+				"billing_email*": {Type: FakeCompany},
+				"default":        {Type: FakeWord},
+			},
+		},
+		Defaults: []DefaultTransform{
+			{Match: "(^|_)phone$", Transform: ColumnTransform{Type: FakePhone}},
+			{Match: "^notes$", Transform: ColumnTransform{Type: FakeParagraph}},
+		},
+	}
+
+	tests := []struct {
+		table, column string
+		want          TransformType
+	}{
+		{"users", "name", FakeName},
+		{"users", "work_email", FakeEmail},
+		{"users", "billing_email", FakeCompany}, // the longer wildcard is more specific
+		{"users", "billing_city", FakeStreetAddress},
+		{"users", "phone", FakeWord}, // the table's default before the global ones
+		{"orders", "contact_phone", FakePhone},
+		{"orders", "notes", FakeParagraph},
+		{"orders", "total", ""},
+	}
+	for _, tt := range tests {
+		ct, ok := config.ColumnTransform(tt.table, tt.column)
+		if ok != (tt.want != "") || ct.Type != tt.want {
+			t.Errorf("ColumnTransform(%s, %s) = %q, %v, want %q", tt.table, tt.column, ct.Type, ok, tt.want)
+		}
+	}
+}
+
+func TestMigrateConfigV1KeepsColumnNames(t *testing.T) {
+	config := &Config{
+		Tables: map[string]TableConfig{
+			"settings": {
+				"default": {Type: FakeWord},
+				"value":   {Type: FakeParagraph},
+			},
+		},
+	}
+	if err := migrateConfigV1(config); err != nil {
+		t.Fatalf("migrateConfigV1() unexpected error: %v", err)
+	}
+	if config.Version != ConfigV2 {
+		t.Errorf("Version = %q, want %s", config.Version, ConfigV2)
+	}
+	if ct, ok := config.ColumnTransform("settings", "default"); !ok || ct.Type != FakeWord {
+		t.Errorf("ColumnTransform(settings, default) = %q, %v, want FakeWord", ct.Type, ok)
+	}
+	if _, ok := config.ColumnTransform("settings", "key"); ok {
+		t.Error("a v1 column named default became the table's default")
+	}
+
+	if err := migrateConfigV1(&Config{Defaults: []DefaultTransform{{Match: "email", Transform: ColumnTransform{Type: FakeEmail}}}}); err == nil {
+		t.Error("migrateConfigV1() should reject defaults in a v1 config")
+	}
+}
+
+func TestLoadConfigV2(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantError bool
+	}{
+		{
+			name: "wildcards and defaults",
+			content: `major_version: 0
+version: v2
+tables:
+  users:
+    "*_email": FakeEmail
+    default: FakeWord
+defaults:
+  - match: "(^|_)ssn$"
+    transform: FakeSSN`,
+		},
+		{
+			name: "invalid wildcard",
+			content: `version: v2
+tables:
+  users:
+    "[email": FakeEmail`,
+			wantError: true,
+		},
+		{
+			name: "invalid default regex",
+			content: `version: v2
+defaults:
+  - match: "("
+    transform: FakeSSN`,
+			wantError: true,
+		},
+		{
+			name: "default with an invalid transform",
+			content: `version: v2
+defaults:
+  - match: "_at$"
+    transform:
+      type: DateShift`,
+			wantError: true,
+		},
+		{
+			name: "defaults in a v1 config",
+			content: `version: v1
+defaults:
+  - match: "ssn"
+    transform: FakeSSN`,
+			wantError: true,
+		},
+		{
+			name:      "unknown version",
+			content:   `version: v3`,
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "transforms.yml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadConfig(path)
+			if (err != nil) != tt.wantError {
+				t.Errorf("LoadConfig() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestTransformChangeWithDefaults(t *testing.T) {
+	config := &Config{
+		Version: ConfigV2,
+		Defaults: []DefaultTransform{
+			{Match: "email$", Transform: ColumnTransform{Type: FakeEmail}},
+		},
+	}
+	change := &proto.Change{
+		Type: "dml",
+		Data: &proto.Change_Dml{Dml: &proto.DMLData{
+			Table:       "accounts",
+			Kind:        "insert",
+			ColumnNames: []string{"id", "contact_email"},
+			ColumnValues: []*proto.ColumnValue{
+				{Value: &proto.ColumnValue_IntValue{IntValue: 1}},
+				{Value: &proto.ColumnValue_StringValue{StringValue: "jane@example.com"}},
+			},
+		}},
+	}
+
+	transformed, err := TransformChange(config, change)
+	if err != nil {
+		t.Fatalf("TransformChange() unexpected error: %v", err)
+	}
+	values := transformed.GetDml().ColumnValues
+	if values[0].GetIntValue() != 1 {
+		t.Errorf("id = %d, want it unchanged", values[0].GetIntValue())
+	}
+	if values[1].GetStringValue() == "jane@example.com" {
+		t.Error("contact_email was not transformed by the default")
+	}
+}
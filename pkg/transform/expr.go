@@ -0,0 +1,146 @@
+package transform
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"google.golang.org/protobuf/types/known/structpb"
+	"kasho/proto"
+)
+
+// Expr transforms evaluate a CEL expression (https://cel.dev) against the row, which is
+// available as the map `row`, e.g. `int(row.salary / 10000) * 10000`. Besides the CEL
+// standard library, expressions can use:
+//
+//	hash(value)              stable non-negative integer for a value, for keyed randomness
+//	date(string)             parse a timestamp or date column into a timestamp
+//	addDays(timestamp, int)  shift a timestamp by a number of days
+
+var (
+	exprEnv     *cel.Env
+	exprEnvErr  error
+	exprEnvOnce sync.Once
+
+	exprCache   = make(map[string]cel.Program)
+	exprCacheMu sync.RWMutex
+)
+
+func getExprEnv() (*cel.Env, error) {
+	exprEnvOnce.Do(func() {
+		exprEnv, exprEnvErr = cel.NewEnv(
+			cel.Variable("row", cel.MapType(cel.StringType, cel.DynType)),
+			cel.Function("hash",
+				cel.Overload("hash_dyn", []*cel.Type{cel.DynType}, cel.IntType,
+					cel.UnaryBinding(func(value ref.Val) ref.Val {
+						return types.Int(hash(fmt.Sprint(value.Value())) >> 1)
+					}),
+				),
+			),
+			cel.Function("date",
+				cel.Overload("date_string", []*cel.Type{cel.StringType}, cel.TimestampType,
+					cel.UnaryBinding(func(value ref.Val) ref.Val {
+						t, _, err := parseTemplateTime(value.Value())
+						if err != nil {
+							return types.NewErr("%s", err.Error())
+						}
+						return types.Timestamp{Time: t}
+					}),
+				),
+			),
+			cel.Function("addDays",
+				cel.Overload("addDays_timestamp_int", []*cel.Type{cel.TimestampType, cel.IntType}, cel.TimestampType,
+					cel.BinaryBinding(func(value, days ref.Val) ref.Val {
+						t := value.Value().(time.Time)
+						return types.Timestamp{Time: t.AddDate(0, 0, int(days.Value().(int64)))}
+					}),
+				),
+			),
+		)
+	})
+	return exprEnv, exprEnvErr
+}
+
+// getCompiledExpr returns a compiled expression from cache or compiles and caches it
+func getCompiledExpr(expression string) (cel.Program, error) {
+	exprCacheMu.RLock()
+	if program, exists := exprCache[expression]; exists {
+		exprCacheMu.RUnlock()
+		return program, nil
+	}
+	exprCacheMu.RUnlock()
+
+	env, err := getExprEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression: %w", issues.Err())
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %w", err)
+	}
+
+	exprCacheMu.Lock()
+	exprCache[expression] = program
+	exprCacheMu.Unlock()
+
+	return program, nil
+}
+
+// validateExprs compiles the Expr transforms of a config so syntax and type errors are
+// reported when it is loaded
+func validateExprs(config *Config) error {
+	for table, columns := range config.Tables {
+		for column, ct := range columns {
+			if ct.Type != Expr {
+				continue
+			}
+			expression, ok := ct.Config["expr"].(string)
+			if !ok {
+				return fmt.Errorf("expr transform for %s.%s requires 'expr' field", table, column)
+			}
+			if _, err := getCompiledExpr(expression); err != nil {
+				return fmt.Errorf("expr transform for %s.%s: %w", table, column, err)
+			}
+		}
+	}
+	return nil
+}
+
+// TransformExpr evaluates a CEL expression with the row as context. The result keeps
+// its type: integers, floats, booleans, strings, timestamps and null are supported.
+func TransformExpr(expression string, row map[string]*proto.ColumnValue) (*proto.ColumnValue, error) {
+	program, err := getCompiledExpr(expression)
+	if err != nil {
+		return nil, err
+	}
+	out, _, err := program.Eval(map[string]any{"row": convertRowToTemplateData(row)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate expression: %w", err)
+	}
+
+	switch v := out.Value().(type) {
+	case int64:
+		return &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: v}}, nil
+	case uint64:
+		return &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: int64(v)}}, nil
+	case float64:
+		return &proto.ColumnValue{Value: &proto.ColumnValue_FloatValue{FloatValue: v}}, nil
+	case bool:
+		return &proto.ColumnValue{Value: &proto.ColumnValue_BoolValue{BoolValue: v}}, nil
+	case string:
+		return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: v}}, nil
+	case time.Time:
+		return &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: v.Format(time.RFC3339Nano)}}, nil
+	case structpb.NullValue:
+		return &proto.ColumnValue{}, nil
+	default:
+		return nil, fmt.Errorf("expression returned unsupported type %s", out.Type().TypeName())
+	}
+}
@@ -0,0 +1,159 @@
+package transform
+
+import (
+	"testing"
+
+	gproto "google.golang.org/protobuf/proto"
+	"kasho/proto"
+)
+
+func TestTransformExpr(t *testing.T) {
+	row := map[string]*proto.ColumnValue{
+		"id":         {Value: &proto.ColumnValue_IntValue{IntValue: 42}},
+		"salary":     {Value: &proto.ColumnValue_FloatValue{FloatValue: 87250.5}},
+		"level":      {Value: &proto.ColumnValue_StringValue{StringValue: "senior"}},
+		"hired_on":   {Value: &proto.ColumnValue_StringValue{StringValue: "2020-02-28"}},
+		"manager_id": {},
+	}
+
+	tests := []struct {
+		name    string
+		expr    string
+		want    *proto.ColumnValue
+		wantErr bool
+	}{
+		{
+			name: "bucket salary",
+			expr: `int(row.salary / 10000.0) * 10000`,
+			want: &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: 80000}},
+		},
+		{
+			name: "conditional",
+			expr: `row.level == "senior" ? "L3+" : "L1-2"`,
+			want: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: "L3+"}},
+		},
+		{
+			name: "float arithmetic",
+			expr: `row.salary * 2.0`,
+			want: &proto.ColumnValue{Value: &proto.ColumnValue_FloatValue{FloatValue: 174501}},
+		},
+		{
+			name: "boolean",
+			expr: `row.manager_id == null`,
+			want: &proto.ColumnValue{Value: &proto.ColumnValue_BoolValue{BoolValue: true}},
+		},
+		{
+			name: "null",
+			expr: `null`,
+			want: &proto.ColumnValue{},
+		},
+		{
+			name: "add days",
+			expr: `addDays(date(row.hired_on), 2)`,
+			want: &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2020-03-01T00:00:00Z"}},
+		},
+		{
+			name:    "unknown column",
+			expr:    `row.missing + 1`,
+			wantErr: true,
+		},
+		{
+			name:    "syntax error",
+			expr:    `row.id +`,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported result",
+			expr:    `[1, 2]`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TransformExpr(tt.expr, row)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TransformExpr() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !gproto.Equal(got, tt.want) {
+				t.Errorf("TransformExpr() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformExprJitter(t *testing.T) {
+	expr := `addDays(date(row.created_at), hash(row.id) % 61 - 30)`
+	row := map[string]*proto.ColumnValue{
+		"id":         {Value: &proto.ColumnValue_IntValue{IntValue: 7}},
+		"created_at": {Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2024-06-15T12:00:00Z"}},
+	}
+
+	first, err := TransformExpr(expr, row)
+	if err != nil {
+		t.Fatalf("TransformExpr() unexpected error: %v", err)
+	}
+	second, err := TransformExpr(expr, row)
+	if err != nil {
+		t.Fatalf("TransformExpr() unexpected error: %v", err)
+	}
+	if first.GetTimestampValue() != second.GetTimestampValue() {
+		t.Errorf("jitter is not deterministic: %v != %v", first, second)
+	}
+	if first.GetTimestampValue() < "2024-05-16" || first.GetTimestampValue() > "2024-07-16" {
+		t.Errorf("jittered date %v is more than 30 days away", first)
+	}
+}
+
+func TestTransformChangeWithExpr(t *testing.T) {
+	config := &Config{
+		Tables: map[string]TableConfig{
+			"employees": {
+				"name":   ColumnTransform{Type: FakeName, Config: map[string]any{}},
+				"salary": ColumnTransform{Type: Expr, Config: map[string]any{"expr": `row.salary - row.salary % 10000`}},
+			},
+		},
+	}
+
+	change := &proto.Change{
+		Type: "dml",
+		Data: &proto.Change_Dml{Dml: &proto.DMLData{
+			Table:       "employees",
+			ColumnNames: []string{"name", "salary"},
+			ColumnValues: []*proto.ColumnValue{
+				{Value: &proto.ColumnValue_StringValue{StringValue: "Jane"}},
+				{Value: &proto.ColumnValue_IntValue{IntValue: 123456}},
+			},
+			Kind: "insert",
+		}},
+	}
+
+	transformed, err := TransformChange(config, change)
+	if err != nil {
+		t.Fatalf("TransformChange() unexpected error: %v", err)
+	}
+	if got := transformed.GetDml().ColumnValues[1].GetIntValue(); got != 120000 {
+		t.Errorf("salary = %d, want 120000", got)
+	}
+}
+
+func TestValidateExprs(t *testing.T) {
+	valid := &Config{Tables: map[string]TableConfig{
+		"users": {"age": ColumnTransform{Type: Expr, Config: map[string]any{"expr": `row.age / 10 * 10`}}},
+	}}
+	if err := validateExprs(valid); err != nil {
+		t.Errorf("validateExprs() unexpected error: %v", err)
+	}
+
+	for name, config := range map[string]map[string]any{
+		"missing expr": {},
+		"syntax error": {"expr": `row.age /`},
+	} {
+		invalid := &Config{Tables: map[string]TableConfig{
+			"users": {"age": ColumnTransform{Type: Expr, Config: config}},
+		}}
+		if err := validateExprs(invalid); err == nil {
+			t.Errorf("validateExprs() %s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+module kasho/pkg/transform
+
+go 1.24.3
+
+require (
+	github.com/brianvoe/gofakeit/v7 v7.0.2
+	github.com/google/cel-go v0.23.2
+	github.com/tetratelabs/wazero v1.9.0
+	golang.org/x/crypto v0.38.0
+	google.golang.org/protobuf v1.36.6
+	gopkg.in/yaml.v3 v3.0.1
+	kasho/pkg/version v0.0.0
+	kasho/proto v0.0.0-00010101000000-000000000000
+)
+
+require (
+	cel.dev/expr v0.20.0 // indirect
+	github.com/antlr4-go/antlr/v4 v4.13.0 // indirect
+	github.com/stoewer/go-strcase v1.2.0 // indirect
+	golang.org/x/exp v0.0.0-20240823005443-9b4947da3948 // indirect
+	golang.org/x/net v0.40.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/text v0.26.0 // indirect
+	google.golang.org/genproto/googleapis/api v0.0.0-20250218202821-56aae31c358a // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250218202821-56aae31c358a // indirect
+	google.golang.org/grpc v1.72.1 // indirect
+)
+
+replace kasho/pkg/version => ../version
+
+replace kasho/proto => ../../proto/kasho/proto
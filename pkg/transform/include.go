@@ -0,0 +1,194 @@
+package transform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configLoader reads a config split over several files: the files listed under
+// include, resolved relative to the file including them and possibly globs, and the
+// files in the directory next to the main file named like it with a .d suffix, e.g.
+// transforms.d/*.yml for transforms.yml. Each table, and each entry of the other
+// per-table sections, may only be defined in one file.
+type configLoader struct {
+	config  Config
+	owners  map[string]string // "section key" → file that defined it
+	loaded  map[string]bool
+	loading []string // the chain of files including each other, to report cycles
+	secrets SecretResolver
+}
+
+// loadConfigFiles reads a config file and the files it includes into one config. Secret
+// references are resolved with secrets if set, or the registered resolvers otherwise.
+func loadConfigFiles(path string, secrets SecretResolver) (*Config, error) {
+	l := &configLoader{owners: make(map[string]string), loaded: make(map[string]bool), secrets: secrets}
+	if err := l.load(path); err != nil {
+		return nil, err
+	}
+
+	dir := strings.TrimSuffix(path, filepath.Ext(path)) + ".d"
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		files, err := configFilesIn(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if err := l.load(file); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	l.config.Include = nil
+	return &l.config, nil
+}
+
+// configFilesIn returns the YAML files in a directory in name order
+func configFilesIn(dir string) ([]string, error) {
+	var files []string
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	slices.Sort(files)
+	return files, nil
+}
+
+func (l *configLoader) load(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(l.loading, abs) {
+		return fmt.Errorf("config file %s includes itself: %s", path, strings.Join(append(l.loading, abs), " → "))
+	}
+	if l.loaded[abs] {
+		return fmt.Errorf("config file %s is included more than once", path)
+	}
+	l.loaded[abs] = true
+	l.loading = append(l.loading, abs)
+	defer func() { l.loading = l.loading[:len(l.loading)-1] }()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	// Anchors and aliases only work within a file
+	var part Config
+	if err := yaml.Unmarshal(data, &part); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	// Plugins are found relative to the file that uses them
+	if err := resolvePlugins(&part, filepath.Dir(path)); err != nil {
+		return fmt.Errorf("config validation failed: %s: %w", path, err)
+	}
+	if err := resolveSecrets(&part, filepath.Dir(path), l.secrets); err != nil {
+		return fmt.Errorf("config validation failed: %s: %w", path, err)
+	}
+	if err := l.merge(path, &part); err != nil {
+		return fmt.Errorf("config validation failed: %w", err)
+	}
+
+	for _, include := range part.Include {
+		pattern := include
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(path), pattern)
+		}
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid include %q in %s: %w", include, path, err)
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("include %q in %s matches no files", include, path)
+		}
+		for _, file := range files {
+			if err := l.load(file); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// merge adds the settings of one file to the config
+func (l *configLoader) merge(file string, part *Config) error {
+	c := &l.config
+	if part.MajorVersion != 0 {
+		if c.MajorVersion != 0 && c.MajorVersion != part.MajorVersion {
+			return fmt.Errorf("%s has major_version %d, expected %d", file, part.MajorVersion, c.MajorVersion)
+		}
+		c.MajorVersion = part.MajorVersion
+	}
+	if part.Version != "" {
+		if c.Version != "" && c.Version != part.Version {
+			return fmt.Errorf("%s has version %s, already set to %s", file, part.Version, c.Version)
+		}
+		c.Version = part.Version
+	}
+	if part.Locale != "" {
+		if c.Locale != "" && c.Locale != part.Locale {
+			return fmt.Errorf("%s sets locale %s, already set to %s", file, part.Locale, c.Locale)
+		}
+		c.Locale = part.Locale
+	}
+	c.Validation.Enabled = c.Validation.Enabled || part.Validation.Enabled
+	c.Validation.Truncate = c.Validation.Truncate || part.Validation.Truncate
+
+	for _, d := range part.Defaults {
+		id := "default matching " + d.Match
+		if other, ok := l.owners[id]; ok {
+			return fmt.Errorf("%s is defined in both %s and %s", id, other, file)
+		}
+		l.owners[id] = file
+		c.Defaults = append(c.Defaults, d)
+	}
+	// DDL rules apply in the order the files are loaded
+	c.DDL.Rules = append(c.DDL.Rules, part.DDL.Rules...)
+
+	sections := []error{
+		mergeSection(l, file, "table", &c.Tables, part.Tables),
+		mergeSection(l, file, "generated_columns of", &c.GeneratedColumns, part.GeneratedColumns),
+		mergeSection(l, file, "routing of table", &c.Routing.Tables, part.Routing.Tables),
+		mergeSection(l, file, "routing of the columns of", &c.Routing.Columns, part.Routing.Columns),
+		mergeSection(l, file, "type_mapping of", &c.TypeMapping.Columns, part.TypeMapping.Columns),
+		mergeSection(l, file, "allowed_values of", &c.Validation.AllowedValues, part.Validation.AllowedValues),
+		mergeSection(l, file, "unique columns of", &c.Validation.Unique, part.Validation.Unique),
+	}
+	for _, err := range sections {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeSection adds a file's entries of a per-table section, rejecting tables another
+// file already defined
+func mergeSection[V any](l *configLoader, file, section string, dst *map[string]V, src map[string]V) error {
+	tables := make([]string, 0, len(src))
+	for table := range src {
+		tables = append(tables, table)
+	}
+	slices.Sort(tables)
+
+	for _, table := range tables {
+		id := section + " " + table
+		if other, ok := l.owners[id]; ok {
+			return fmt.Errorf("%s is defined in both %s and %s", id, other, file)
+		}
+		l.owners[id] = file
+		if *dst == nil {
+			*dst = make(map[string]V)
+		}
+		(*dst)[table] = src[table]
+	}
+	return nil
+}
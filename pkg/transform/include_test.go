@@ -0,0 +1,146 @@
+package transform
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadConfigIncludes(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"transforms.yml": `major_version: 0
+include:
+  - billing/*.yml
+tables:
+  users:
+    name: FakeName`,
+		"billing/invoices.yml": `tables:
+  invoices:
+    email: FakeEmail
+routing:
+  tables:
+    invoices: warehouse.invoices`,
+		"transforms.d/crm.yml": `anchors: &fake_name
+  type: FakeName
+tables:
+  contacts:
+    name: *fake_name
+validation:
+  unique:
+    contacts: [email]`,
+	})
+
+	config, err := LoadConfig(filepath.Join(dir, "transforms.yml"))
+	if err != nil {
+		t.Fatalf("LoadConfig() unexpected error: %v", err)
+	}
+	for _, table := range []string{"users", "invoices", "contacts"} {
+		if _, ok := config.Tables[table]; !ok {
+			t.Errorf("LoadConfig() has no table %s", table)
+		}
+	}
+	if config.Tables["contacts"]["name"].Type != FakeName {
+		t.Errorf("contacts.name = %q, want the anchored FakeName", config.Tables["contacts"]["name"].Type)
+	}
+	if config.Routing.Tables["invoices"] != "warehouse.invoices" {
+		t.Errorf("routing of invoices = %q, want warehouse.invoices", config.Routing.Tables["invoices"])
+	}
+	if len(config.Validation.Unique["contacts"]) != 1 {
+		t.Errorf("unique columns of contacts = %v, want [email]", config.Validation.Unique["contacts"])
+	}
+}
+
+func TestLoadConfigIncludeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			name: "table defined in two files",
+			files: map[string]string{
+				"transforms.yml": `tables:
+  users:
+    name: FakeName`,
+				"transforms.d/users.yml": `tables:
+  users:
+    email: FakeEmail`,
+			},
+			wantErr: "table users is defined in both",
+		},
+		{
+			name: "routing defined in two files",
+			files: map[string]string{
+				"transforms.yml": `include: [a.yml, b.yml]`,
+				"a.yml": `routing:
+  columns:
+    users: {name: full_name}`,
+				"b.yml": `routing:
+  columns:
+    users: {email: email_address}`,
+			},
+			wantErr: "routing of the columns of users is defined in both",
+		},
+		{
+			name: "include cycle",
+			files: map[string]string{
+				"transforms.yml": `include: [a.yml]`,
+				"a.yml":          `include: [transforms.yml]`,
+			},
+			wantErr: "includes itself",
+		},
+		{
+			name: "include without matches",
+			files: map[string]string{
+				"transforms.yml": `include: [missing/*.yml]`,
+			},
+			wantErr: "matches no files",
+		},
+		{
+			name: "different major versions",
+			files: map[string]string{
+				"transforms.yml":         `major_version: 0`,
+				"transforms.d/other.yml": `major_version: 1`,
+			},
+			wantErr: "major version mismatch",
+		},
+		{
+			name: "anchor from another file",
+			files: map[string]string{
+				"transforms.yml": `include: [a.yml]
+anchors: &fake_name
+  type: FakeName`,
+				"a.yml": `tables:
+  users:
+    name: *fake_name`,
+			},
+			wantErr: "a.yml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfigFiles(t, tt.files)
+			_, err := LoadConfig(filepath.Join(dir, "transforms.yml"))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadConfig() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
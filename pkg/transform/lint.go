@@ -0,0 +1,363 @@
+package transform
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"kasho/proto"
+)
+
+// Finding is a problem Lint found in a config. Table and Column are empty for problems
+// with the config as a whole; defaults are reported under the table "defaults".
+type Finding struct {
+	Table   string
+	Column  string
+	Message string
+}
+
+func (f Finding) String() string {
+	switch {
+	case f.Table == "":
+		return f.Message
+	case f.Column == "":
+		return f.Table + ": " + f.Message
+	default:
+		return f.Table + "." + f.Column + ": " + f.Message
+	}
+}
+
+// Schema holds the data types of the columns of each table, e.g. from a schema dump of
+// the source database, keyed by the table names used under tables
+type Schema map[string]map[string]string
+
+// columnKind is the kind of value a column holds, as far as transforms are concerned
+type columnKind string
+
+const (
+	stringKind columnKind = "string"
+	intKind    columnKind = "integer"
+	floatKind  columnKind = "float"
+	boolKind   columnKind = "boolean"
+	bytesKind  columnKind = "binary"
+	timeKind   columnKind = "date/time"
+)
+
+// kindOf returns the kind of a column of the given data type, e.g. "character varying(255)"
+func kindOf(dataType string) columnKind {
+	t := strings.ToLower(strings.TrimSpace(dataType))
+	if i := strings.IndexByte(t, '('); i >= 0 {
+		t = strings.TrimSpace(t[:i])
+	}
+	t = strings.TrimSuffix(strings.TrimSuffix(t, " unsigned"), " signed")
+	switch {
+	case strings.HasSuffix(t, "[]"):
+		return stringKind
+	case strings.HasPrefix(t, "timestamp"), strings.HasPrefix(t, "time"), t == "date", t == "datetime", t == "datetime2", t == "smalldatetime", t == "datetimeoffset":
+		return timeKind
+	}
+	switch t {
+	case "smallint", "integer", "int", "bigint", "tinyint", "mediumint", "int2", "int4", "int8",
+		"serial", "smallserial", "bigserial", "serial2", "serial4", "serial8", "year":
+		return intKind
+	case "real", "double precision", "double", "float", "float4", "float8", "numeric", "decimal", "money", "number":
+		return floatKind
+	case "boolean", "bool":
+		return boolKind
+	case "bytea", "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary", "raw", "image":
+		return bytesKind
+	}
+	return stringKind
+}
+
+// isKnownType reports whether a transform type exists
+func isKnownType(t TransformType) bool {
+	if _, ok := transformFunctions[t]; ok {
+		return true
+	}
+	switch t {
+	case Regex, Template, Expr, DateShift, NumericNoise, Round, Bucket, Suppress, Mask, Persona,
+		PasswordBcrypt, PasswordScrypt, PasswordPBKDF2, PasswordArgon2id, Plugin:
+		return true
+	}
+	return false
+}
+
+// LintFile loads a config file and the files it includes, and lints it. An error is
+// returned only if the files can't be read or parsed. Secret references are checked but
+// not resolved, so linting doesn't need access to the secrets.
+func LintFile(path string, schema Schema) ([]Finding, error) {
+	config, err := loadConfigFiles(path, func(ref SecretRef) (string, error) { return "secret", nil })
+	if err != nil {
+		return nil, err
+	}
+	return Lint(config, schema), nil
+}
+
+// Lint checks a config the way LoadConfig does, but reports every problem rather than
+// stopping at the first, and also checks the settings transforms only read when they
+// run: unknown transform types, regular expressions and templates. With a schema it also
+// reports columns that don't exist and transforms that can't handle their column's type.
+func Lint(config *Config, schema Schema) []Finding {
+	var findings []Finding
+	// Migrating a v1 config changes how its keys are read, so lint a copy
+	c := *config
+	for _, check := range []func(*Config) error{
+		validateAndMigrateConfig,
+		func(c *Config) error { return validateRouting(c.Routing) },
+		func(c *Config) error { return validateTypeMapping(c.TypeMapping) },
+		func(c *Config) error { return validateDDL(c.DDL) },
+		func(c *Config) error { return validLocale(c.Locale) },
+	} {
+		if err := check(&c); err != nil {
+			findings = append(findings, Finding{Message: err.Error()})
+		}
+	}
+
+	for _, table := range sortedKeys(c.Tables) {
+		columns := c.Tables[table]
+		for _, column := range sortedKeys(columns) {
+			for _, problem := range lintTransform(columns[column]) {
+				findings = append(findings, Finding{Table: table, Column: column, Message: problem})
+			}
+		}
+	}
+	for _, d := range c.Defaults {
+		problems := lintTransform(d.Transform)
+		if _, err := defaultPattern(d.Match); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid match: %v", err))
+		}
+		for _, problem := range problems {
+			findings = append(findings, Finding{Table: "defaults", Column: d.Match, Message: problem})
+		}
+	}
+
+	if schema != nil {
+		findings = append(findings, lintSchema(&c, schema)...)
+	}
+	return findings
+}
+
+// lintTransform returns the problems of a column transform's settings
+func lintTransform(ct ColumnTransform) []string {
+	if !isKnownType(ct.Type) {
+		return []string{fmt.Sprintf("unknown transform type %q", ct.Type)}
+	}
+
+	var problems []string
+	check := func(err error) {
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	parseTemplate := func(field string, required bool) {
+		text, ok := ct.Config[field].(string)
+		if !ok {
+			if required {
+				problems = append(problems, fmt.Sprintf("%s transform requires '%s' field", strings.ToLower(string(ct.Type)), field))
+			}
+			return
+		}
+		if _, err := template.New("transform").Funcs(templateFuncMap).Parse(text); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid %s: %v", field, err))
+		}
+	}
+
+	switch ct.Type {
+	case Regex:
+		pattern, ok := ct.Config["pattern"].(string)
+		if !ok {
+			problems = append(problems, "regex transform requires 'pattern' field")
+		} else if _, err := getCompiledRegex(pattern); err != nil {
+			problems = append(problems, err.Error())
+		}
+		if _, ok := ct.Config["replacement"].(string); !ok {
+			problems = append(problems, "regex transform requires 'replacement' field")
+		}
+	case Template:
+		parseTemplate("template", true)
+	case PasswordBcrypt, PasswordScrypt, PasswordPBKDF2, PasswordArgon2id:
+		_, err := passwordConfig(ct)
+		check(err)
+	case Expr:
+		expression, ok := ct.Config["expr"].(string)
+		if !ok {
+			problems = append(problems, "expr transform requires 'expr' field")
+		} else if _, err := getCompiledExpr(expression); err != nil {
+			problems = append(problems, err.Error())
+		}
+	case DateShift:
+		_, _, _, err := dateShiftConfig(ct.Config)
+		check(err)
+	case NumericNoise:
+		_, _, err := noiseConfig(ct.Config)
+		check(err)
+	case Round:
+		_, err := roundConfig(ct.Config)
+		check(err)
+	case Bucket:
+		_, _, err := bucketConfig(ct.Config)
+		check(err)
+	case Suppress:
+		_, _, _, err := suppressConfig(ct.Config)
+		check(err)
+	case Mask:
+		_, err := maskConfig(ct.Config)
+		check(err)
+	case Persona:
+		_, _, _, err := personaConfig(ct.Config)
+		check(err)
+	case Plugin:
+		if fn, _ := ct.Config["fn"].(string); fn == "" {
+			problems = append(problems, "plugin transform requires 'fn'")
+		}
+	}
+
+	if locale, ok := ct.Config["locale"]; ok {
+		name, _ := locale.(string)
+		check(validLocale(name))
+	}
+	return problems
+}
+
+// lintSchema reports configured tables and columns missing from the schema, and columns
+// whose transform can't handle their type
+func lintSchema(c *Config, schema Schema) []Finding {
+	var findings []Finding
+	for _, table := range sortedKeys(c.Tables) {
+		columns, ok := schema[table]
+		if !ok {
+			findings = append(findings, Finding{Table: table, Message: "table not in schema"})
+			continue
+		}
+		for _, column := range sortedKeys(c.Tables[table]) {
+			if _, ok := columns[column]; ok {
+				continue
+			}
+			// Wildcards and defaults match whichever columns exist
+			if c.Version == ConfigV2 && (column == tableDefaultKey || isWildcard(column)) && !c.literalColumns[table][column] {
+				continue
+			}
+			findings = append(findings, Finding{Table: table, Column: column, Message: "column not in schema"})
+		}
+	}
+
+	for _, table := range sortedKeys(schema) {
+		columns := schema[table]
+		for _, column := range sortedKeys(columns) {
+			ct, ok := c.ColumnTransform(table, column)
+			if !ok || !isKnownType(ct.Type) {
+				continue
+			}
+			if problem := kindMismatch(ct, kindOf(columns[column])); problem != "" {
+				findings = append(findings, Finding{
+					Table:   table,
+					Column:  column,
+					Message: fmt.Sprintf("%s can't transform %s column (%s): %s", ct.Type, kindOf(columns[column]), columns[column], problem),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// kindMismatch explains why a transform can't handle values of a column of the given kind,
+// or returns an empty string if it can
+func kindMismatch(ct ColumnTransform, kind columnKind) string {
+	switch ct.Type {
+	case Expr, Suppress, Template, Plugin:
+		// The replica converts text in the right format, so only the values can tell
+		return ""
+	case NumericNoise, Round, Bucket:
+		if kind != intKind && kind != floatKind {
+			return "it requires numbers"
+		}
+		return ""
+	case DateShift:
+		if kind != timeKind && kind != stringKind {
+			return "it requires dates"
+		}
+		return ""
+	case Regex:
+		if kind != stringKind {
+			return "it requires text"
+		}
+		return ""
+	case Mask:
+		if kind != stringKind && kind != intKind && kind != floatKind {
+			return "it requires text or numbers"
+		}
+		return ""
+	case Persona, PasswordBcrypt, PasswordScrypt, PasswordPBKDF2, PasswordArgon2id:
+		if kind != stringKind {
+			return "it produces text"
+		}
+		return ""
+	}
+
+	want := stringKind
+	switch transformFunctions[ct.Type].(type) {
+	case func(int) int:
+		want = intKind
+	case func(float64) float64:
+		want = floatKind
+	case func(bool) bool:
+		want = boolKind
+	case func([]byte) []byte:
+		want = bytesKind
+	case func(time.Time) time.Time:
+		want = timeKind
+	}
+	if kind != want {
+		return fmt.Sprintf("it requires %s values", want)
+	}
+	return ""
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ParseValue converts the text of a value, e.g. from a CSV sample of rows, to a column
+// value of the kind of the given data type; without a data type it stays text
+func ParseValue(dataType, text string) (*proto.ColumnValue, error) {
+	switch kindOf(dataType) {
+	case intKind:
+		i, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q", dataType, text)
+		}
+		return &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: i}}, nil
+	case floatKind:
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q", dataType, text)
+		}
+		return &proto.ColumnValue{Value: &proto.ColumnValue_FloatValue{FloatValue: f}}, nil
+	case boolKind:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q", dataType, text)
+		}
+		return &proto.ColumnValue{Value: &proto.ColumnValue_BoolValue{BoolValue: b}}, nil
+	case timeKind:
+		return &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: text}}, nil
+	}
+	return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: text}}, nil
+}
+
+// FormatValue returns the text of a column value, or NULL
+func FormatValue(value *proto.ColumnValue) string {
+	if value.GetValue() == nil {
+		return "NULL"
+	}
+	return valueText(value)
+}
@@ -0,0 +1,95 @@
+package transform
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func lintMessages(findings []Finding) []string {
+	messages := make([]string, len(findings))
+	for i, f := range findings {
+		messages[i] = f.String()
+	}
+	return messages
+}
+
+func TestLint(t *testing.T) {
+	config := &Config{
+		Tables: map[string]TableConfig{
+			"users": {
+				"name":  {Type: FakeName},
+				"email": {Type: "FakeMail"},
+				"phone": {Type: Regex, Config: map[string]any{"pattern": `(\d{3}`, "replacement": "XXX"}},
+				"bio":   {Type: Template, Config: map[string]any{"template": "{{ .name "}},
+				"city":  {Type: FakeCity, Config: map[string]any{"locale": "xx_XX"}},
+			},
+		},
+		Routing: RoutingConfig{Tables: map[string]string{"users": ""}},
+	}
+
+	got := lintMessages(Lint(config, nil))
+	want := []string{
+		"routing: table users has no replica table",
+		"users.bio: invalid template: template: transform:1: unclosed action",
+		"users.city: unsupported locale \"xx_XX\" (supported: de_DE, en_GB, en_US, es_ES, fr_FR, ja_JP)",
+		"users.email: unknown transform type \"FakeMail\"",
+		"users.phone: invalid regex pattern: error parsing regexp: missing closing ): `(\\d{3}`",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Lint() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestLintSchema(t *testing.T) {
+	config := &Config{
+		Version: ConfigV2,
+		Tables: map[string]TableConfig{
+			"users": {
+				"id":       {Type: FakeEmail},
+				"*_email":  {Type: FakeEmail},
+				"nickname": {Type: FakeUsername},
+				"balance":  {Type: Round, Config: map[string]any{"nearest": 10}},
+			},
+			"audit": {"actor": {Type: FakeName}},
+		},
+	}
+	schema := Schema{
+		"users": {
+			"id":          "bigint",
+			"work_email":  "character varying(255)",
+			"login_email": "integer",
+			"balance":     "numeric(10,2)",
+		},
+	}
+
+	got := lintMessages(Lint(config, schema))
+	want := []string{
+		"audit: table not in schema",
+		"users.nickname: column not in schema",
+		"users.id: FakeEmail can't transform integer column (bigint): it requires string values",
+		"users.login_email: FakeEmail can't transform integer column (integer): it requires string values",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Lint() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestLintFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transforms.yml")
+	if err := os.WriteFile(path, []byte("tables:\n  users:\n    name: FakeName\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	findings, err := LintFile(path, nil)
+	if err != nil {
+		t.Fatalf("LintFile() unexpected error: %v", err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("LintFile() = %v, want no findings", findings)
+	}
+
+	if _, err := LintFile(filepath.Join(t.TempDir(), "missing.yml"), nil); err == nil {
+		t.Error("LintFile() should fail for a missing file")
+	}
+}
@@ -0,0 +1,179 @@
+package transform
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// Fake transforms generate US data by default. With a locale, set for the whole config or
+// per column, names, addresses, phone numbers and zip codes follow that region instead.
+
+const defaultLocale = "en_US"
+
+type localeName struct {
+	name  string
+	latin string // romanization for emails and usernames, if the name isn't in Latin script
+}
+
+// handle returns the name in a form usable in emails and usernames
+func (n localeName) handle() string {
+	if n.latin != "" {
+		return n.latin
+	}
+	return personaHandle(n.name)
+}
+
+type localeCity struct {
+	name      string
+	state     string
+	stateAbbr string
+}
+
+type localeData struct {
+	firstNames      []localeName
+	lastNames       []localeName
+	familyNameFirst bool
+	streets         []string
+	cities          []localeCity
+	// streetFormat places {street}, {number} and {block}; addressFormat places
+	// {street_address}, {zip}, {city} and {state}
+	streetFormat  string
+	addressFormat string
+	// zipFormat and phoneFormat use # for a digit and ? for an uppercase letter
+	zipFormat    string
+	phoneFormat  string
+	country      string
+	emailDomains []string
+}
+
+// localeFaker generates locale data from a seed
+type localeFaker struct {
+	locale *localeData
+	faker  *gofakeit.Faker
+}
+
+func newLocaleFaker(locale *localeData, seedText string) *localeFaker {
+	return &localeFaker{locale: locale, faker: gofakeit.New(hash(seedText))}
+}
+
+func pick[T any](f *localeFaker, list []T) T {
+	return list[f.faker.Number(0, len(list)-1)]
+}
+
+// pattern replaces # with random digits and ? with random uppercase letters
+func (f *localeFaker) pattern(format string) string {
+	var b strings.Builder
+	for _, r := range format {
+		switch r {
+		case '#':
+			b.WriteByte(byte('0' + f.faker.Number(0, 9)))
+		case '?':
+			b.WriteByte(byte('A' + f.faker.Number(0, 25)))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func (f *localeFaker) fullName(first, last localeName) string {
+	if f.locale.familyNameFirst {
+		return last.name + " " + first.name
+	}
+	return first.name + " " + last.name
+}
+
+func (f *localeFaker) street() string {
+	return strings.NewReplacer(
+		"{street}", pick(f, f.locale.streets),
+		"{number}", strconv.Itoa(f.faker.Number(1, 120)),
+		"{block}", strconv.Itoa(f.faker.Number(1, 9)),
+	).Replace(f.locale.streetFormat)
+}
+
+func (f *localeFaker) address() string {
+	city := pick(f, f.locale.cities)
+	return strings.NewReplacer(
+		"{street_address}", f.street(),
+		"{zip}", f.pattern(f.locale.zipFormat),
+		"{city}", city.name,
+		"{state}", city.state,
+	).Replace(f.locale.addressFormat)
+}
+
+// localeFunctions are the fake transforms that have locale data
+var localeFunctions = map[TransformType]func(f *localeFaker) string{
+	FakeName: func(f *localeFaker) string {
+		return f.fullName(pick(f, f.locale.firstNames), pick(f, f.locale.lastNames))
+	},
+	FakeFirstName:     func(f *localeFaker) string { return pick(f, f.locale.firstNames).name },
+	FakeLastName:      func(f *localeFaker) string { return pick(f, f.locale.lastNames).name },
+	FakePhone:         func(f *localeFaker) string { return f.pattern(f.locale.phoneFormat) },
+	FakeStreetAddress: func(f *localeFaker) string { return f.address() },
+	FakeStreet:        func(f *localeFaker) string { return f.street() },
+	FakeCity:          func(f *localeFaker) string { return pick(f, f.locale.cities).name },
+	FakeState:         func(f *localeFaker) string { return pick(f, f.locale.cities).state },
+	FakeStateAbbr:     func(f *localeFaker) string { return pick(f, f.locale.cities).stateAbbr },
+	FakeZip:           func(f *localeFaker) string { return f.pattern(f.locale.zipFormat) },
+	FakeCountry:       func(f *localeFaker) string { return f.locale.country },
+}
+
+// TransformFakeLocalized runs a fake transform with a locale's data. Transforms without
+// locale data, and the en_US locale, use the default generators.
+func TransformFakeLocalized(transformType TransformType, locale, original string) (string, bool) {
+	data, ok := locales[locale]
+	if !ok {
+		return "", false
+	}
+	fn, ok := localeFunctions[transformType]
+	if !ok {
+		return "", false
+	}
+	return fn(newLocaleFaker(data, original)), true
+}
+
+// columnLocale returns the locale of a column: its own, else the config's
+func columnLocale(c *Config, colTransform ColumnTransform) string {
+	if locale, ok := colTransform.Config["locale"].(string); ok && locale != "" {
+		return locale
+	}
+	return c.Locale
+}
+
+// validLocale reports whether a locale is supported
+func validLocale(locale string) error {
+	if locale == "" || locale == defaultLocale {
+		return nil
+	}
+	if _, ok := locales[locale]; ok {
+		return nil
+	}
+	supported := []string{defaultLocale}
+	for name := range locales {
+		supported = append(supported, name)
+	}
+	sort.Strings(supported)
+	return fmt.Errorf("unsupported locale %q (supported: %s)", locale, strings.Join(supported, ", "))
+}
+
+// validateLocales checks the config's locale and those of its columns
+func validateLocales(config *Config) error {
+	if err := validLocale(config.Locale); err != nil {
+		return err
+	}
+	for table, columns := range config.Tables {
+		for column, ct := range columns {
+			if locale, ok := ct.Config["locale"]; ok {
+				name, _ := locale.(string)
+				if err := validLocale(name); err != nil {
+					return fmt.Errorf("%s.%s: %w", table, column, err)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package transform
+
+// Locale data for fake names, addresses and phone numbers outside the US. gofakeit only
+// generates US data, which is kept for en_US.
+
+var locales = map[string]*localeData{
+	"de_DE": {
+		firstNames: names("Anna", "Lena", "Julia", "Sophie", "Laura", "Katharina", "Sarah", "Marie", "Hannah", "Lisa",
+			"Lukas", "Jonas", "Leon", "Felix", "Maximilian", "Paul", "Tobias", "Florian", "Jan", "Stefan"),
+		lastNames: names("Müller", "Schmidt", "Schneider", "Fischer", "Weber", "Meyer", "Wagner", "Becker", "Schulz", "Hoffmann",
+			"Koch", "Richter", "Klein", "Wolf", "Schröder", "Neumann", "Schwarz", "Zimmermann", "Braun", "Krüger"),
+		streets: []string{"Hauptstraße", "Schulstraße", "Gartenstraße", "Bahnhofstraße", "Dorfstraße", "Bergstraße",
+			"Lindenstraße", "Kirchstraße", "Waldstraße", "Ringstraße", "Goethestraße", "Am Markt"},
+		cities: []localeCity{
+			{"Berlin", "Berlin", "BE"}, {"Hamburg", "Hamburg", "HH"}, {"München", "Bayern", "BY"},
+			{"Köln", "Nordrhein-Westfalen", "NW"}, {"Frankfurt am Main", "Hessen", "HE"}, {"Stuttgart", "Baden-Württemberg", "BW"},
+			{"Düsseldorf", "Nordrhein-Westfalen", "NW"}, {"Leipzig", "Sachsen", "SN"}, {"Dresden", "Sachsen", "SN"},
+			{"Hannover", "Niedersachsen", "NI"}, {"Nürnberg", "Bayern", "BY"}, {"Bremen", "Bremen", "HB"},
+		},
+		streetFormat:  "{street} {number}",
+		addressFormat: "{street_address}, {zip} {city}",
+		zipFormat:     "#####",
+		phoneFormat:   "+49 ### #######",
+		country:       "Deutschland",
+		emailDomains:  []string{"example.de", "mail.example.de", "post.example.de"},
+	},
+	"fr_FR": {
+		firstNames: names("Camille", "Léa", "Manon", "Chloé", "Emma", "Inès", "Sarah", "Julie", "Marie", "Lucie",
+			"Lucas", "Hugo", "Louis", "Nathan", "Gabriel", "Jules", "Thomas", "Antoine", "Nicolas", "Pierre"),
+		lastNames: names("Martin", "Bernard", "Dubois", "Thomas", "Robert", "Richard", "Petit", "Durand", "Leroy", "Moreau",
+			"Simon", "Laurent", "Lefebvre", "Michel", "Garcia", "David", "Bertrand", "Roux", "Vincent", "Fournier"),
+		streets: []string{"rue de la Paix", "rue Victor Hugo", "avenue Jean Jaurès", "rue de la République", "boulevard Gambetta",
+			"rue Pasteur", "place de l'Église", "rue du Moulin", "avenue de la Gare", "rue des Écoles"},
+		cities: []localeCity{
+			{"Paris", "Île-de-France", "IDF"}, {"Marseille", "Provence-Alpes-Côte d'Azur", "PAC"}, {"Lyon", "Auvergne-Rhône-Alpes", "ARA"},
+			{"Toulouse", "Occitanie", "OCC"}, {"Nice", "Provence-Alpes-Côte d'Azur", "PAC"}, {"Nantes", "Pays de la Loire", "PDL"},
+			{"Strasbourg", "Grand Est", "GES"}, {"Montpellier", "Occitanie", "OCC"}, {"Bordeaux", "Nouvelle-Aquitaine", "NAQ"},
+			{"Lille", "Hauts-de-France", "HDF"}, {"Rennes", "Bretagne", "BRE"},
+		},
+		streetFormat:  "{number} {street}",
+		addressFormat: "{street_address}, {zip} {city}",
+		zipFormat:     "#####",
+		phoneFormat:   "+33 # ## ## ## ##",
+		country:       "France",
+		emailDomains:  []string{"example.fr", "mail.example.fr", "courriel.example.fr"},
+	},
+	"es_ES": {
+		firstNames: names("Lucía", "María", "Paula", "Sofía", "Carmen", "Laura", "Marta", "Ana", "Elena", "Sara",
+			"Hugo", "Pablo", "Alejandro", "Daniel", "Javier", "Carlos", "David", "Manuel", "Sergio", "Álvaro"),
+		lastNames: names("García", "Rodríguez", "González", "Fernández", "López", "Martínez", "Sánchez", "Pérez", "Gómez", "Martín",
+			"Jiménez", "Ruiz", "Hernández", "Díaz", "Moreno", "Muñoz", "Álvarez", "Romero", "Alonso", "Navarro"),
+		streets: []string{"Calle Mayor", "Calle Real", "Avenida de la Constitución", "Calle de Alcalá", "Plaza de España",
+			"Calle del Sol", "Gran Vía", "Calle San Juan", "Paseo del Prado", "Calle de la Iglesia"},
+		cities: []localeCity{
+			{"Madrid", "Comunidad de Madrid", "MD"}, {"Barcelona", "Cataluña", "CT"}, {"Valencia", "Comunidad Valenciana", "VC"},
+			{"Sevilla", "Andalucía", "AN"}, {"Zaragoza", "Aragón", "AR"}, {"Málaga", "Andalucía", "AN"},
+			{"Murcia", "Región de Murcia", "MC"}, {"Palma", "Islas Baleares", "IB"}, {"Bilbao", "País Vasco", "PV"},
+			{"Valladolid", "Castilla y León", "CL"},
+		},
+		streetFormat:  "{street}, {number}",
+		addressFormat: "{street_address}, {zip} {city}",
+		zipFormat:     "#####",
+		phoneFormat:   "+34 6## ### ###",
+		country:       "España",
+		emailDomains:  []string{"example.es", "correo.example.es", "mail.example.es"},
+	},
+	"en_GB": {
+		firstNames: names("Olivia", "Amelia", "Isla", "Ava", "Emily", "Sophie", "Grace", "Lily", "Freya", "Charlotte",
+			"Oliver", "George", "Harry", "Jack", "Jacob", "Noah", "Charlie", "Thomas", "Oscar", "William"),
+		lastNames: names("Smith", "Jones", "Taylor", "Brown", "Williams", "Wilson", "Johnson", "Davies", "Robinson", "Wright",
+			"Thompson", "Evans", "Walker", "White", "Roberts", "Green", "Hall", "Wood", "Jackson", "Clarke"),
+		streets: []string{"High Street", "Station Road", "Main Street", "Park Road", "Church Road", "Church Street",
+			"London Road", "Victoria Road", "Green Lane", "Manor Road", "Church Lane", "Queen's Road"},
+		cities: []localeCity{
+			{"London", "Greater London", "LND"}, {"Manchester", "Greater Manchester", "MAN"}, {"Birmingham", "West Midlands", "BIR"},
+			{"Leeds", "West Yorkshire", "LDS"}, {"Glasgow", "Scotland", "GLG"}, {"Liverpool", "Merseyside", "LIV"},
+			{"Bristol", "Bristol", "BST"}, {"Sheffield", "South Yorkshire", "SHF"}, {"Edinburgh", "Scotland", "EDH"},
+			{"Cardiff", "Wales", "CRF"}, {"Belfast", "Northern Ireland", "BFS"},
+		},
+		streetFormat:  "{number} {street}",
+		addressFormat: "{street_address}, {city} {zip}",
+		zipFormat:     "??# #??",
+		phoneFormat:   "+44 7### ######",
+		country:       "United Kingdom",
+		emailDomains:  []string{"example.co.uk", "mail.example.co.uk", "post.example.co.uk"},
+	},
+	"ja_JP": {
+		firstNames: []localeName{
+			{"陽翔", "haruto"}, {"蓮", "ren"}, {"湊", "minato"}, {"大翔", "hiroto"}, {"悠真", "yuma"},
+			{"翔太", "shota"}, {"健太", "kenta"}, {"拓海", "takumi"}, {"大輝", "daiki"}, {"翼", "tsubasa"},
+			{"陽葵", "himari"}, {"凛", "rin"}, {"結菜", "yuna"}, {"葵", "aoi"}, {"美咲", "misaki"},
+			{"さくら", "sakura"}, {"愛", "ai"}, {"彩", "aya"}, {"優花", "yuka"}, {"真央", "mao"},
+		},
+		lastNames: []localeName{
+			{"佐藤", "sato"}, {"鈴木", "suzuki"}, {"高橋", "takahashi"}, {"田中", "tanaka"}, {"伊藤", "ito"},
+			{"渡辺", "watanabe"}, {"山本", "yamamoto"}, {"中村", "nakamura"}, {"小林", "kobayashi"}, {"加藤", "kato"},
+			{"吉田", "yoshida"}, {"山田", "yamada"}, {"佐々木", "sasaki"}, {"山口", "yamaguchi"}, {"松本", "matsumoto"},
+			{"井上", "inoue"}, {"木村", "kimura"}, {"林", "hayashi"}, {"斎藤", "saito"}, {"清水", "shimizu"},
+		},
+		streets: []string{"本町", "中央", "栄町", "緑町", "旭町", "幸町", "桜町", "新町", "元町", "南町"},
+		cities: []localeCity{
+			{"新宿区", "東京都", "13"}, {"渋谷区", "東京都", "13"}, {"横浜市", "神奈川県", "14"}, {"大阪市", "大阪府", "27"},
+			{"名古屋市", "愛知県", "23"}, {"札幌市", "北海道", "01"}, {"福岡市", "福岡県", "40"}, {"神戸市", "兵庫県", "28"},
+			{"京都市", "京都府", "26"}, {"仙台市", "宮城県", "04"}, {"広島市", "広島県", "34"},
+		},
+		familyNameFirst: true,
+		streetFormat:    "{street}{block}丁目{number}",
+		addressFormat:   "〒{zip} {state}{city}{street_address}",
+		zipFormat:       "###-####",
+		phoneFormat:     "+81 90-####-####",
+		country:         "日本",
+		emailDomains:    []string{"example.jp", "mail.example.jp", "example.co.jp"},
+	},
+}
+
+// names builds localeNames from names written in the Latin alphabet
+func names(list ...string) []localeName {
+	result := make([]localeName, len(list))
+	for i, name := range list {
+		result[i] = localeName{name: name}
+	}
+	return result
+}
@@ -0,0 +1,122 @@
+package transform
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"kasho/proto"
+)
+
+func TestTransformFakeLocalized(t *testing.T) {
+	for name := range locales {
+		t.Run(name, func(t *testing.T) {
+			for transformType := range localeFunctions {
+				got, ok := TransformFakeLocalized(transformType, name, "seed")
+				if !ok || got == "" {
+					t.Errorf("%s = %q, %v; want a value", transformType, got, ok)
+				}
+				if again, _ := TransformFakeLocalized(transformType, name, "seed"); again != got {
+					t.Errorf("%s is not deterministic: %q != %q", transformType, got, again)
+				}
+				if strings.ContainsAny(got, "{}#") {
+					t.Errorf("%s = %q has unfilled placeholders", transformType, got)
+				}
+			}
+		})
+	}
+}
+
+func TestTransformFakeLocalizedFormats(t *testing.T) {
+	tests := []struct {
+		transformType TransformType
+		locale        string
+		pattern       string
+	}{
+		{FakeZip, "de_DE", `^\d{5}$`},
+		{FakePhone, "de_DE", `^\+49 \d{3} \d{7}$`},
+		{FakeStreet, "de_DE", `^\D+ \d+$`},
+		{FakeZip, "en_GB", `^[A-Z]{2}\d \d[A-Z]{2}$`},
+		{FakeStreetAddress, "fr_FR", `^\d+ .+, \d{5} .+$`},
+		{FakeZip, "ja_JP", `^\d{3}-\d{4}$`},
+		{FakeStreetAddress, "ja_JP", `^〒\d{3}-\d{4} `},
+	}
+	for _, tt := range tests {
+		got, _ := TransformFakeLocalized(tt.transformType, tt.locale, "seed")
+		if !regexp.MustCompile(tt.pattern).MatchString(got) {
+			t.Errorf("%s in %s = %q, want match for %s", tt.transformType, tt.locale, got, tt.pattern)
+		}
+	}
+
+	if _, ok := TransformFakeLocalized(FakeName, "en_US", "seed"); ok {
+		t.Error("en_US should use the default generators")
+	}
+	if _, ok := TransformFakeLocalized(FakeCompany, "de_DE", "seed"); ok {
+		t.Error("transforms without locale data should use the default generators")
+	}
+}
+
+func TestGetTransformedValueWithLocale(t *testing.T) {
+	config := &Config{
+		Locale: "de_DE",
+		Tables: map[string]TableConfig{
+			"customers": {
+				"country": {Type: FakeCountry, Config: map[string]any{}},
+				"phone":   {Type: FakePhone, Config: map[string]any{"locale": "fr_FR"}},
+				"company": {Type: FakeCompany, Config: map[string]any{}},
+			},
+		},
+	}
+	str := func(s string) *proto.ColumnValue {
+		return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: s}}
+	}
+
+	got, err := GetTransformedValue(config, "customers", "country", str("USA"), nil)
+	if err != nil || got.GetStringValue() != "Deutschland" {
+		t.Errorf("country = %v, %v; want Deutschland from the config's locale", got, err)
+	}
+	got, err = GetTransformedValue(config, "customers", "phone", str("555-0100"), nil)
+	if err != nil || !strings.HasPrefix(got.GetStringValue(), "+33 ") {
+		t.Errorf("phone = %v, %v; want a French number from the column's locale", got, err)
+	}
+	got, err = GetTransformedValue(config, "customers", "company", str("Acme"), nil)
+	if err != nil || got.GetStringValue() != TransformFakeCompany("Acme") {
+		t.Errorf("company = %v, %v; want the default generator", got, err)
+	}
+}
+
+func TestPersonaWithLocale(t *testing.T) {
+	p := newPersona("seed", locales["ja_JP"])
+	if !regexp.MustCompile(`^[a-z]+\.[a-z]+\d+@`).MatchString(p.email) {
+		t.Errorf("email = %q, want a romanized address", p.email)
+	}
+	if !strings.HasPrefix(p.name, p.lastName+" ") {
+		t.Errorf("name = %q, want the family name first", p.name)
+	}
+	if !strings.HasPrefix(p.phone, "+81 ") {
+		t.Errorf("phone = %q, want a Japanese number", p.phone)
+	}
+}
+
+func TestValidateLocales(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *Config
+		wantErr bool
+	}{
+		{"no locale", &Config{}, false},
+		{"en_US", &Config{Locale: "en_US"}, false},
+		{"supported", &Config{Locale: "ja_JP"}, false},
+		{"unsupported", &Config{Locale: "xx_XX"}, true},
+		{"unsupported column locale", &Config{Tables: map[string]TableConfig{
+			"users": {"name": {Type: FakeName, Config: map[string]any{"locale": "de"}}},
+		}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateLocales(tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("validateLocales() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package transform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"kasho/proto"
+)
+
+// Mask hides the middle of a value while keeping its shape, e.g. a card number masked
+// with keep_first: 2 and keep_last: 4 becomes 43**********1234. Strings are masked
+// character by character. Int and float columns are masked digit by digit with
+// mask_digit, so the result is still a valid number of the same magnitude. Values too
+// short to keep keep_first + keep_last characters are masked entirely.
+
+const (
+	defaultMaskChar   = "*"
+	defaultMaskDigit  = "0"
+	defaultMaskLength = 8
+)
+
+// maskSettings are the settings of a Mask transform, with defaults applied
+type maskSettings struct {
+	keepFirst, keepLast int
+	char                rune
+	digit               byte
+	preserveLength      bool
+	length              int // mask characters used when the length isn't preserved
+}
+
+// maskConfig reads and checks the settings of a Mask transform
+func maskConfig(config map[string]any) (maskSettings, error) {
+	s := maskSettings{preserveLength: true}
+	var err error
+	if s.keepFirst, err = configInt(config, "keep_first", 0, 0, 1<<16); err != nil {
+		return s, err
+	}
+	if s.keepLast, err = configInt(config, "keep_last", 0, 0, 1<<16); err != nil {
+		return s, err
+	}
+	if s.length, err = configInt(config, "mask_length", defaultMaskLength, 1, 1<<16); err != nil {
+		return s, err
+	}
+
+	char := defaultMaskChar
+	if v, ok := config["mask_char"]; ok {
+		char, _ = v.(string)
+		if utf8.RuneCountInString(char) != 1 {
+			return s, fmt.Errorf("mask 'mask_char' must be a single character")
+		}
+	}
+	s.char, _ = utf8.DecodeRuneInString(char)
+
+	digit := defaultMaskDigit
+	if v, ok := config["mask_digit"]; ok {
+		digit = fmt.Sprint(v)
+		if len(digit) != 1 || digit[0] < '0' || digit[0] > '9' {
+			return s, fmt.Errorf("mask 'mask_digit' must be a digit from 0 to 9")
+		}
+	}
+	s.digit = digit[0]
+
+	if v, ok := config["preserve_length"]; ok {
+		if s.preserveLength, ok = v.(bool); !ok {
+			return s, fmt.Errorf("mask 'preserve_length' must be true or false")
+		}
+	}
+	return s, nil
+}
+
+// TransformMask masks a string, keeping its first keepFirst and last keepLast characters.
+// Unless preserveLength is set, the masked part is replaced by length mask characters so
+// the result doesn't reveal the value's length.
+func TransformMask(value string, keepFirst, keepLast int, char rune, preserveLength bool, length int) string {
+	runes := []rune(value)
+	if keepFirst+keepLast >= len(runes) {
+		keepFirst, keepLast = 0, 0
+	}
+	masked := len(runes) - keepFirst - keepLast
+	if !preserveLength {
+		masked = length
+	}
+
+	var b strings.Builder
+	b.WriteString(string(runes[:keepFirst]))
+	b.WriteString(strings.Repeat(string(char), masked))
+	b.WriteString(string(runes[len(runes)-keepLast:]))
+	return b.String()
+}
+
+// maskDigits replaces the digits of a formatted number with digit, keeping the first
+// keepFirst and last keepLast digits, the sign and the decimal point
+func maskDigits(text string, keepFirst, keepLast int, digit byte) string {
+	digits := 0
+	for i := 0; i < len(text); i++ {
+		if text[i] >= '0' && text[i] <= '9' {
+			digits++
+		}
+	}
+	if keepFirst+keepLast >= digits {
+		keepFirst, keepLast = 0, 0
+	}
+
+	out := []byte(text)
+	seen := 0
+	for i := range out {
+		if out[i] < '0' || out[i] > '9' {
+			continue
+		}
+		if seen >= keepFirst && seen < digits-keepLast {
+			out[i] = digit
+		}
+		seen++
+	}
+	return string(out)
+}
+
+// maskValue applies a Mask transform to a column, keeping int and float columns numeric
+func maskValue(config map[string]any, original *proto.ColumnValue) (*proto.ColumnValue, error) {
+	s, err := maskConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := original.GetValue().(type) {
+	case nil:
+		return nil, nil
+	case *proto.ColumnValue_StringValue:
+		masked := TransformMask(v.StringValue, s.keepFirst, s.keepLast, s.char, s.preserveLength, s.length)
+		return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: masked}}, nil
+	case *proto.ColumnValue_IntValue:
+		text := maskDigits(strconv.FormatInt(v.IntValue, 10), s.keepFirst, s.keepLast, s.digit)
+		masked, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("masked value %s is out of range", text)
+		}
+		return &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: masked}}, nil
+	case *proto.ColumnValue_FloatValue:
+		text := maskDigits(strconv.FormatFloat(v.FloatValue, 'f', -1, 64), s.keepFirst, s.keepLast, s.digit)
+		masked, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("masked value %s is out of range", text)
+		}
+		return &proto.ColumnValue{Value: &proto.ColumnValue_FloatValue{FloatValue: masked}}, nil
+	default:
+		return nil, fmt.Errorf("mask transform requires a string, int or float value, got %T", original.Value)
+	}
+}
+
+// validateMasks checks the settings of the Mask transforms of a config
+func validateMasks(config *Config) error {
+	for table, columns := range config.Tables {
+		for column, ct := range columns {
+			if ct.Type != Mask {
+				continue
+			}
+			if _, err := maskConfig(ct.Config); err != nil {
+				return fmt.Errorf("%s.%s: %w", table, column, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+
+	"kasho/proto"
+)
+
+func TestTransformMask(t *testing.T) {
+	tests := []struct {
+		name                string
+		value               string
+		keepFirst, keepLast int
+		preserveLength      bool
+		want                string
+	}{
+		{"card number", "4321567812341234", 2, 4, true, "43**********1234"},
+		{"everything", "secret", 0, 0, true, "******"},
+		{"multibyte characters", "Zoë Müller", 1, 1, true, "Z********r"},
+		{"too short to keep", "abc", 2, 2, true, "***"},
+		{"fixed length", "jane@example.com", 1, 0, false, "j********"},
+		{"empty", "", 1, 1, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TransformMask(tt.value, tt.keepFirst, tt.keepLast, '*', tt.preserveLength, 8); got != tt.want {
+				t.Errorf("TransformMask() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskValueNumbers(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]any
+		original *proto.ColumnValue
+		want     *proto.ColumnValue
+	}{
+		{
+			"int",
+			map[string]any{"keep_last": 3},
+			&proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: 98765432}},
+			&proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: 432}},
+		},
+		{
+			"negative int with mask digit",
+			map[string]any{"keep_first": 1, "mask_digit": 9},
+			&proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: -4321}},
+			&proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: -4999}},
+		},
+		{
+			"float",
+			map[string]any{"keep_first": 2},
+			&proto.ColumnValue{Value: &proto.ColumnValue_FloatValue{FloatValue: 1234.56}},
+			&proto.ColumnValue{Value: &proto.ColumnValue_FloatValue{FloatValue: 1200.00}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := maskValue(tt.config, tt.original)
+			if err != nil {
+				t.Fatalf("maskValue() unexpected error: %v", err)
+			}
+			if got.String() != tt.want.String() {
+				t.Errorf("maskValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got, err := maskValue(map[string]any{}, &proto.ColumnValue{}); err != nil || got != nil {
+		t.Errorf("maskValue(NULL) = %v, %v, want nil", got, err)
+	}
+	if _, err := maskValue(map[string]any{}, &proto.ColumnValue{Value: &proto.ColumnValue_BoolValue{BoolValue: true}}); err == nil {
+		t.Error("maskValue() should reject bool values")
+	}
+}
+
+func TestMaskConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]any
+		wantErr string
+	}{
+		{"defaults", map[string]any{}, ""},
+		{"negative keep_first", map[string]any{"keep_first": -1}, "'keep_first' must be between"},
+		{"long mask_char", map[string]any{"mask_char": "**"}, "single character"},
+		{"letter mask_digit", map[string]any{"mask_digit": "x"}, "must be a digit"},
+		{"preserve_length not a bool", map[string]any{"preserve_length": "yes"}, "must be true or false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := maskConfig(tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("maskConfig() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("maskConfig() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package transform
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"kasho/proto"
+)
+
+// Numeric transforms perturb or generalize int and float columns so analytics replicas
+// keep the distribution of values without exposing individual ones:
+//
+//	NumericNoise  adds deterministic jitter of at most `max`, or `percent` of the value
+//	Round         rounds to the nearest multiple of `nearest`
+//	Bucket        maps values to labels: `bounds: [18, 65]`, `labels: ["<18", "18-64", "65+"]`
+
+// configNumber reads a numeric setting; YAML decodes whole numbers as int
+func configNumber(config map[string]any, name string) (float64, bool, error) {
+	if config[name] == nil {
+		return 0, false, nil
+	}
+	n, ok := yamlNumber(config[name])
+	if !ok {
+		return 0, false, fmt.Errorf("'%s' must be a number", name)
+	}
+	return n, true, nil
+}
+
+func yamlNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// numericInput returns a column value as a float and whether it is an integer column
+func numericInput(value *proto.ColumnValue) (float64, bool, error) {
+	switch v := value.Value.(type) {
+	case *proto.ColumnValue_IntValue:
+		return float64(v.IntValue), true, nil
+	case *proto.ColumnValue_FloatValue:
+		return v.FloatValue, false, nil
+	default:
+		return 0, false, fmt.Errorf("requires an int or float value, got %T", value.Value)
+	}
+}
+
+// numericOutput builds a column value of the input's type
+func numericOutput(value float64, isInt bool) *proto.ColumnValue {
+	if isInt {
+		return &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: int64(math.Round(value))}}
+	}
+	return &proto.ColumnValue{Value: &proto.ColumnValue_FloatValue{FloatValue: value}}
+}
+
+// TransformNumericNoise adds an offset between -maxNoise and maxNoise derived from seed
+func TransformNumericNoise(value, maxNoise float64, seed string) float64 {
+	unit := float64(hash(seed)>>11) / (1 << 53) // [0, 1)
+	return value + (2*unit-1)*maxNoise
+}
+
+// TransformRound rounds a value to the nearest multiple of nearest
+func TransformRound(value, nearest float64) float64 {
+	return math.Round(value/nearest) * nearest
+}
+
+// TransformBucket returns the label of the range containing value. bounds must be sorted;
+// labels has one more entry than bounds, and each bound starts a new range.
+func TransformBucket(value float64, bounds []float64, labels []string) string {
+	return labels[sort.Search(len(bounds), func(i int) bool { return bounds[i] > value })]
+}
+
+// bucketConfig reads the bounds and labels of a Bucket transform
+func bucketConfig(config map[string]any) ([]float64, []string, error) {
+	rawBounds, _ := config["bounds"].([]any)
+	rawLabels, _ := config["labels"].([]any)
+	if len(rawBounds) == 0 {
+		return nil, nil, fmt.Errorf("bucket transform requires 'bounds'")
+	}
+	if len(rawLabels) != len(rawBounds)+1 {
+		return nil, nil, fmt.Errorf("bucket transform requires one more label than bounds, got %d labels for %d bounds", len(rawLabels), len(rawBounds))
+	}
+
+	bounds := make([]float64, len(rawBounds))
+	for i, raw := range rawBounds {
+		bound, ok := yamlNumber(raw)
+		if !ok {
+			return nil, nil, fmt.Errorf("bucket 'bounds' must be numbers")
+		}
+		if i > 0 && bound <= bounds[i-1] {
+			return nil, nil, fmt.Errorf("bucket 'bounds' must be in increasing order")
+		}
+		bounds[i] = bound
+	}
+	return bounds, stringList(rawLabels), nil
+}
+
+// noiseConfig reads the maximum jitter of a NumericNoise transform, as an absolute amount
+// or a percentage of the value
+func noiseConfig(config map[string]any) (maxNoise, percent float64, err error) {
+	maxNoise, hasMax, err := configNumber(config, "max")
+	if err != nil {
+		return 0, 0, err
+	}
+	percent, hasPercent, err := configNumber(config, "percent")
+	if err != nil {
+		return 0, 0, err
+	}
+	if hasMax == hasPercent {
+		return 0, 0, fmt.Errorf("numeric noise transform requires exactly one of 'max' or 'percent'")
+	}
+	if maxNoise < 0 || percent < 0 {
+		return 0, 0, fmt.Errorf("numeric noise amount must not be negative")
+	}
+	return maxNoise, percent, nil
+}
+
+// roundConfig reads the rounding step of a Round transform
+func roundConfig(config map[string]any) (float64, error) {
+	nearest, ok, err := configNumber(config, "nearest")
+	if err != nil {
+		return 0, err
+	}
+	if !ok || nearest <= 0 {
+		return 0, fmt.Errorf("round transform requires a positive 'nearest'")
+	}
+	return nearest, nil
+}
+
+// transformNumericValue applies a NumericNoise, Round or Bucket transform to a column
+func transformNumericValue(colTransform ColumnTransform, original *proto.ColumnValue, dmlData *proto.DMLData) (*proto.ColumnValue, error) {
+	if original.GetValue() == nil {
+		return nil, nil
+	}
+	value, isInt, err := numericInput(original)
+	if err != nil {
+		return nil, err
+	}
+
+	switch colTransform.Type {
+	case NumericNoise:
+		maxNoise, percent, err := noiseConfig(colTransform.Config)
+		if err != nil {
+			return nil, err
+		}
+		if percent > 0 {
+			maxNoise = math.Abs(value) * percent / 100
+		}
+		seed := valueText(original)
+		// Seeding from a key column gives equal values in different rows different noise
+		if key, _ := colTransform.Config["key"].(string); key != "" && dmlData != nil {
+			for i, colName := range dmlData.ColumnNames {
+				if colName == key && i < len(dmlData.ColumnValues) {
+					seed = valueText(dmlData.ColumnValues[i]) + ":" + seed
+					break
+				}
+			}
+		}
+		return numericOutput(TransformNumericNoise(value, maxNoise, seed), isInt), nil
+
+	case Round:
+		nearest, err := roundConfig(colTransform.Config)
+		if err != nil {
+			return nil, err
+		}
+		return numericOutput(TransformRound(value, nearest), isInt), nil
+
+	case Bucket:
+		bounds, labels, err := bucketConfig(colTransform.Config)
+		if err != nil {
+			return nil, err
+		}
+		return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: TransformBucket(value, bounds, labels)}}, nil
+	}
+	return nil, fmt.Errorf("%s is not a numeric transform", colTransform.Type)
+}
+
+// validateNumericTransforms checks the settings of the numeric transforms of a config
+func validateNumericTransforms(config *Config) error {
+	for table, columns := range config.Tables {
+		for column, ct := range columns {
+			var err error
+			switch ct.Type {
+			case NumericNoise:
+				_, _, err = noiseConfig(ct.Config)
+			case Round:
+				_, err = roundConfig(ct.Config)
+			case Bucket:
+				_, _, err = bucketConfig(ct.Config)
+			}
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", table, column, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package transform
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"kasho/proto"
+)
+
+func TestTransformNumericNoise(t *testing.T) {
+	for i := range 1000 {
+		seed := strconv.Itoa(i)
+		got := TransformNumericNoise(100, 5, seed)
+		if got < 95 || got > 105 {
+			t.Fatalf("TransformNumericNoise(100, 5, %q) = %v, want within 5", seed, got)
+		}
+		if again := TransformNumericNoise(100, 5, seed); again != got {
+			t.Fatalf("TransformNumericNoise() is not deterministic: %v != %v", got, again)
+		}
+	}
+}
+
+func TestTransformRound(t *testing.T) {
+	tests := []struct {
+		value, nearest, want float64
+	}{
+		{87250, 1000, 87000},
+		{87500, 1000, 88000},
+		{-1249, 500, -1000},
+		{3.14159, 0.01, 3.14},
+	}
+	for _, tt := range tests {
+		if got := TransformRound(tt.value, tt.nearest); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("TransformRound(%v, %v) = %v, want %v", tt.value, tt.nearest, got, tt.want)
+		}
+	}
+}
+
+func TestTransformBucket(t *testing.T) {
+	bounds := []float64{18, 65}
+	labels := []string{"<18", "18-64", "65+"}
+	tests := map[float64]string{0: "<18", 17.9: "<18", 18: "18-64", 64: "18-64", 65: "65+", 120: "65+"}
+	for value, want := range tests {
+		if got := TransformBucket(value, bounds, labels); got != want {
+			t.Errorf("TransformBucket(%v) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestGetTransformedValueNumeric(t *testing.T) {
+	config := &Config{
+		Tables: map[string]TableConfig{
+			"employees": {
+				"salary": {Type: Round, Config: map[string]any{"nearest": 1000}},
+				"bonus":  {Type: NumericNoise, Config: map[string]any{"percent": 10}},
+				"score":  {Type: NumericNoise, Config: map[string]any{"max": 0.5, "key": "id"}},
+				"age":    {Type: Bucket, Config: map[string]any{"bounds": []any{18, 65}, "labels": []any{"<18", "18-64", "65+"}}},
+				"name":   {Type: Round, Config: map[string]any{"nearest": 10}},
+			},
+		},
+	}
+	dml := &proto.DMLData{
+		Table:       "employees",
+		ColumnNames: []string{"id"},
+		ColumnValues: []*proto.ColumnValue{
+			{Value: &proto.ColumnValue_IntValue{IntValue: 7}},
+		},
+	}
+	intValue := func(v int64) *proto.ColumnValue {
+		return &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: v}}
+	}
+
+	got, err := GetTransformedValue(config, "employees", "salary", intValue(87250), dml)
+	if err != nil || got.GetIntValue() != 87000 {
+		t.Errorf("Round = %v, %v; want int 87000", got, err)
+	}
+
+	got, err = GetTransformedValue(config, "employees", "bonus", intValue(2000), dml)
+	if err != nil {
+		t.Fatalf("NumericNoise unexpected error: %v", err)
+	}
+	if bonus := got.GetIntValue(); bonus < 1800 || bonus > 2200 {
+		t.Errorf("NumericNoise = %d, want within 10%% of 2000", bonus)
+	}
+
+	score := &proto.ColumnValue{Value: &proto.ColumnValue_FloatValue{FloatValue: 4.2}}
+	got, err = GetTransformedValue(config, "employees", "score", score, dml)
+	if err != nil {
+		t.Fatalf("NumericNoise unexpected error: %v", err)
+	}
+	if v := got.GetFloatValue(); v < 3.7 || v > 4.7 {
+		t.Errorf("NumericNoise = %v, want within 0.5 of 4.2", v)
+	}
+
+	got, err = GetTransformedValue(config, "employees", "age", intValue(42), dml)
+	if err != nil || got.GetStringValue() != "18-64" {
+		t.Errorf("Bucket = %v, %v; want 18-64", got, err)
+	}
+
+	got, err = GetTransformedValue(config, "employees", "salary", &proto.ColumnValue{}, dml)
+	if err != nil || got != nil {
+		t.Errorf("Round of NULL = %v, %v; want nil", got, err)
+	}
+
+	name := &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: "Jane"}}
+	if _, err := GetTransformedValue(config, "employees", "name", name, dml); err == nil {
+		t.Error("Round of a string: expected error")
+	}
+}
+
+func TestValidateNumericTransforms(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform ColumnTransform
+		wantErr   bool
+	}{
+		{"noise max", ColumnTransform{Type: NumericNoise, Config: map[string]any{"max": 5}}, false},
+		{"noise max and percent", ColumnTransform{Type: NumericNoise, Config: map[string]any{"max": 5, "percent": 10}}, true},
+		{"noise without amount", ColumnTransform{Type: NumericNoise, Config: map[string]any{}}, true},
+		{"round", ColumnTransform{Type: Round, Config: map[string]any{"nearest": 0.5}}, false},
+		{"round zero", ColumnTransform{Type: Round, Config: map[string]any{"nearest": 0}}, true},
+		{"bucket", ColumnTransform{Type: Bucket, Config: map[string]any{"bounds": []any{10, 20.5}, "labels": []any{"a", "b", "c"}}}, false},
+		{"bucket missing label", ColumnTransform{Type: Bucket, Config: map[string]any{"bounds": []any{10, 20}, "labels": []any{"a", "b"}}}, true},
+		{"bucket unsorted", ColumnTransform{Type: Bucket, Config: map[string]any{"bounds": []any{20, 10}, "labels": []any{"a", "b", "c"}}}, true},
+		{"bucket text bound", ColumnTransform{Type: Bucket, Config: map[string]any{"bounds": []any{"ten"}, "labels": []any{"a", "b"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Tables: map[string]TableConfig{"t": {"c": tt.transform}}}
+			if err := validateNumericTransforms(config); (err != nil) != tt.wantErr {
+				t.Errorf("validateNumericTransforms() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
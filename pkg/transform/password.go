@@ -0,0 +1,277 @@
+package transform
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math"
+	"strings"
+	"text/template"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"kasho/proto"
+)
+
+// Password transforms replace password hashes with hashes of a known cleartext, so
+// developers can log in to a replica with demo credentials:
+//
+//	password:
+//	  type: PasswordArgon2id
+//	  cleartext: "{{.username}}-demo"
+//	  memory: 19456
+//
+// `cleartext` is a template rendered against the row unless cleartext_policy is literal.
+// `salt_mode` chooses how the salt is made: derived from the column's original value
+// (deterministic, the default), random for every hash, or none. Bcrypt always salts
+// randomly.
+
+// cleartextPolicy says how the cleartext option is turned into a password
+type cleartextPolicy string
+
+const (
+	cleartextTemplate cleartextPolicy = "template" // rendered against the row
+	cleartextLiteral  cleartextPolicy = "literal"  // used as written, e.g. a fixed password containing {{
+)
+
+// saltMode says how the salt of a password hash is made
+type saltMode string
+
+const (
+	saltDeterministic saltMode = "deterministic"
+	saltRandom        saltMode = "random"
+	saltNone          saltMode = "none"
+)
+
+const (
+	passwordSaltLength = 16
+	passwordKeyLength  = 32
+
+	defaultScryptN          = 131072 // 2^17
+	defaultScryptR          = 8
+	defaultScryptP          = 1
+	defaultPBKDF2Iterations = 600000
+	defaultPBKDF2Hash       = "SHA256"
+	defaultArgon2Time       = 3
+	defaultArgon2Memory     = 65536 // KiB
+	defaultArgon2Threads    = 4
+)
+
+// isPasswordType reports whether a transform type hashes a password
+func isPasswordType(t TransformType) bool {
+	return t == PasswordBcrypt || t == PasswordScrypt || t == PasswordPBKDF2 || t == PasswordArgon2id
+}
+
+// passwordSettings are the settings of a password transform, with defaults applied
+type passwordSettings struct {
+	cleartext string
+	policy    cleartextPolicy
+	salt      saltMode
+
+	cost       int    // bcrypt
+	n, r, p    int    // scrypt
+	iterations int    // PBKDF2
+	hash       string // PBKDF2
+	time       uint32 // Argon2id
+	memory     uint32 // Argon2id
+	threads    uint8  // Argon2id
+}
+
+// configInt reads a whole number setting between min and max, or returns def if it isn't set
+func configInt(config map[string]any, name string, def, min, max int) (int, error) {
+	n, ok, err := configNumber(config, name)
+	if err != nil || !ok {
+		return def, err
+	}
+	if n != math.Trunc(n) {
+		return 0, fmt.Errorf("'%s' must be a whole number", name)
+	}
+	if n < float64(min) || n > float64(max) {
+		return 0, fmt.Errorf("'%s' must be between %d and %d", name, min, max)
+	}
+	return int(n), nil
+}
+
+// passwordConfig reads and checks the settings of a password transform
+func passwordConfig(ct ColumnTransform) (passwordSettings, error) {
+	config := ct.Config
+	var s passwordSettings
+
+	cleartext, ok := config["cleartext"]
+	if !ok {
+		return s, fmt.Errorf("password transform requires 'cleartext' field")
+	}
+	if s.cleartext, ok = cleartext.(string); !ok {
+		return s, fmt.Errorf("password 'cleartext' must be a string")
+	}
+
+	s.policy = cleartextTemplate
+	if v, ok := config["cleartext_policy"]; ok {
+		text, _ := v.(string)
+		s.policy = cleartextPolicy(text)
+		if s.policy != cleartextTemplate && s.policy != cleartextLiteral {
+			return s, fmt.Errorf("password 'cleartext_policy' must be %s or %s", cleartextTemplate, cleartextLiteral)
+		}
+	}
+	if s.policy == cleartextTemplate {
+		if _, err := template.New("cleartext").Funcs(templateFuncMap).Parse(s.cleartext); err != nil {
+			return s, fmt.Errorf("invalid cleartext: %w", err)
+		}
+	}
+
+	var err error
+	if s.salt, err = passwordSaltMode(ct); err != nil {
+		return s, err
+	}
+
+	switch ct.Type {
+	case PasswordBcrypt:
+		s.cost, err = configInt(config, "cost", bcrypt.DefaultCost, bcrypt.MinCost, bcrypt.MaxCost)
+	case PasswordScrypt:
+		if s.n, err = configInt(config, "n", defaultScryptN, 2, math.MaxInt32); err != nil {
+			return s, err
+		}
+		if s.n&(s.n-1) != 0 {
+			return s, fmt.Errorf("scrypt 'n' must be a power of 2")
+		}
+		if s.r, err = configInt(config, "r", defaultScryptR, 1, math.MaxInt32); err != nil {
+			return s, err
+		}
+		s.p, err = configInt(config, "p", defaultScryptP, 1, math.MaxInt32)
+	case PasswordPBKDF2:
+		if s.iterations, err = configInt(config, "iterations", defaultPBKDF2Iterations, 1, math.MaxInt32); err != nil {
+			return s, err
+		}
+		s.hash = defaultPBKDF2Hash
+		if v, ok := config["hash"]; ok {
+			s.hash, _ = v.(string)
+			if s.hash != defaultPBKDF2Hash {
+				return s, fmt.Errorf("unsupported PBKDF2 'hash' %v (only %s supported)", v, defaultPBKDF2Hash)
+			}
+		}
+	case PasswordArgon2id:
+		var time, memory, threads int
+		if time, err = configInt(config, "time", defaultArgon2Time, 1, math.MaxUint32); err != nil {
+			return s, err
+		}
+		if threads, err = configInt(config, "threads", defaultArgon2Threads, 1, math.MaxUint8); err != nil {
+			return s, err
+		}
+		// Argon2 needs at least 8 KiB of memory per thread
+		if memory, err = configInt(config, "memory", defaultArgon2Memory, 8*threads, math.MaxUint32); err != nil {
+			return s, err
+		}
+		s.time, s.memory, s.threads = uint32(time), uint32(memory), uint8(threads)
+	default:
+		return s, fmt.Errorf("%s is not a password transform", ct.Type)
+	}
+	return s, err
+}
+
+// passwordSaltMode reads salt_mode, or the older use_salt flag
+func passwordSaltMode(ct ColumnTransform) (saltMode, error) {
+	mode, hasMode := ct.Config["salt_mode"]
+	useSalt, hasUseSalt := ct.Config["use_salt"]
+	if hasMode && hasUseSalt {
+		return "", fmt.Errorf("password transform takes 'salt_mode' or 'use_salt', not both")
+	}
+
+	salt := saltDeterministic
+	if ct.Type == PasswordBcrypt {
+		salt = saltRandom
+	}
+	if hasUseSalt {
+		b, ok := useSalt.(bool)
+		if !ok {
+			return "", fmt.Errorf("password 'use_salt' must be true or false")
+		}
+		if !b {
+			salt = saltNone
+		}
+	}
+	if hasMode {
+		text, _ := mode.(string)
+		salt = saltMode(text)
+		if salt != saltDeterministic && salt != saltRandom && salt != saltNone {
+			return "", fmt.Errorf("password 'salt_mode' must be %s, %s or %s", saltDeterministic, saltRandom, saltNone)
+		}
+	}
+	if ct.Type == PasswordBcrypt && salt != saltRandom {
+		return "", fmt.Errorf("bcrypt always uses a random salt")
+	}
+	return salt, nil
+}
+
+// passwordSalt makes a salt; a deterministic salt is derived from the column's original
+// value, so replaying a change produces the same hash
+func passwordSalt(mode saltMode, original string) ([]byte, error) {
+	switch mode {
+	case saltRandom:
+		salt := make([]byte, passwordSaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+		return salt, nil
+	case saltNone:
+		return make([]byte, passwordSaltLength), nil
+	default:
+		return generateDeterministicSalt(original, passwordSaltLength), nil
+	}
+}
+
+// passwordValue applies a password transform to a column
+func passwordValue(ct ColumnTransform, original *proto.ColumnValue, dmlData *proto.DMLData) (*proto.ColumnValue, error) {
+	s, err := passwordConfig(ct)
+	if err != nil {
+		return nil, err
+	}
+
+	cleartext := s.cleartext
+	if s.policy == cleartextTemplate && dmlData != nil && strings.Contains(cleartext, "{{") {
+		row := make(map[string]*proto.ColumnValue)
+		for i, colName := range dmlData.ColumnNames {
+			if i < len(dmlData.ColumnValues) {
+				row[colName] = dmlData.ColumnValues[i]
+			}
+		}
+		if cleartext, err = processPasswordCleartext(cleartext, row); err != nil {
+			return nil, fmt.Errorf("failed to process cleartext template: %w", err)
+		}
+	}
+
+	var hashed string
+	if ct.Type == PasswordBcrypt {
+		hashed, err = TransformPasswordBcrypt(cleartext, s.cost)
+	} else {
+		var salt []byte
+		if salt, err = passwordSalt(s.salt, original.GetStringValue()); err != nil {
+			return nil, err
+		}
+		switch ct.Type {
+		case PasswordScrypt:
+			hashed, err = hashScrypt(cleartext, salt, s.n, s.r, s.p)
+		case PasswordPBKDF2:
+			hashed = hashPBKDF2(cleartext, salt, s.iterations)
+		case PasswordArgon2id:
+			hashed = hashArgon2id(cleartext, salt, s.time, s.memory, s.threads)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("password transform failed: %w", err)
+	}
+	return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: hashed}}, nil
+}
+
+// validatePasswords checks the settings of the password transforms of a config
+func validatePasswords(config *Config) error {
+	for table, columns := range config.Tables {
+		for column, ct := range columns {
+			if !isPasswordType(ct.Type) {
+				continue
+			}
+			if _, err := passwordConfig(ct); err != nil {
+				return fmt.Errorf("%s.%s: %w", table, column, err)
+			}
+		}
+	}
+	return nil
+}
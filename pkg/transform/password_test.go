@@ -0,0 +1,121 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+
+	"kasho/proto"
+)
+
+func passwordTransform(t *testing.T, text string) ColumnTransform {
+	t.Helper()
+	var ct ColumnTransform
+	if err := yaml.Unmarshal([]byte(text), &ct); err != nil {
+		t.Fatal(err)
+	}
+	return ct
+}
+
+func TestPasswordConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"bcrypt defaults", "{type: PasswordBcrypt, cleartext: demo}", ""},
+		{"argon2id parameters", "{type: PasswordArgon2id, cleartext: demo, time: 1, memory: 64, threads: 2, salt_mode: none}", ""},
+		{"missing cleartext", "{type: PasswordScrypt}", "requires 'cleartext' field"},
+		{"cleartext not a string", "{type: PasswordScrypt, cleartext: 1234}", "'cleartext' must be a string"},
+		{"invalid template", "{type: PasswordScrypt, cleartext: '{{.id'}", "invalid cleartext"},
+		{"literal braces", "{type: PasswordScrypt, cleartext: '{{.id', cleartext_policy: literal}", ""},
+		{"unknown policy", "{type: PasswordScrypt, cleartext: demo, cleartext_policy: hashed}", "'cleartext_policy' must be"},
+		{"bcrypt cost too high", "{type: PasswordBcrypt, cleartext: demo, cost: 40}", "'cost' must be between 4 and 31"},
+		{"cost not a number", "{type: PasswordBcrypt, cleartext: demo, cost: high}", "'cost' must be a number"},
+		{"bcrypt deterministic salt", "{type: PasswordBcrypt, cleartext: demo, salt_mode: deterministic}", "bcrypt always uses a random salt"},
+		{"scrypt n not a power of 2", "{type: PasswordScrypt, cleartext: demo, n: 1000}", "must be a power of 2"},
+		{"fractional iterations", "{type: PasswordPBKDF2, cleartext: demo, iterations: 1.5}", "must be a whole number"},
+		{"unsupported hash", "{type: PasswordPBKDF2, cleartext: demo, hash: MD5}", "unsupported PBKDF2 'hash'"},
+		{"argon2id memory per thread", "{type: PasswordArgon2id, cleartext: demo, memory: 8, threads: 4}", "'memory' must be between 32"},
+		{"use_salt not a bool", "{type: PasswordArgon2id, cleartext: demo, use_salt: 'no'}", "'use_salt' must be true or false"},
+		{"salt_mode and use_salt", "{type: PasswordArgon2id, cleartext: demo, use_salt: true, salt_mode: none}", "not both"},
+		{"unknown salt mode", "{type: PasswordArgon2id, cleartext: demo, salt_mode: pepper}", "'salt_mode' must be"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := passwordConfig(passwordTransform(t, tt.yaml))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("passwordConfig() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("passwordConfig() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPasswordValue(t *testing.T) {
+	original := &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: "$2a$10$original"}}
+	row := &proto.DMLData{
+		ColumnNames:  []string{"username", "password"},
+		ColumnValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_StringValue{StringValue: "jane"}}, original},
+	}
+	hash := func(yamlText string) string {
+		t.Helper()
+		value, err := passwordValue(passwordTransform(t, yamlText), original, row)
+		if err != nil {
+			t.Fatalf("passwordValue() unexpected error: %v", err)
+		}
+		return value.GetStringValue()
+	}
+
+	t.Run("bcrypt cost from YAML", func(t *testing.T) {
+		hashed := hash("{type: PasswordBcrypt, cleartext: '{{.username}}-demo', cost: 5}")
+		if cost, err := bcrypt.Cost([]byte(hashed)); err != nil || cost != 5 {
+			t.Errorf("cost = %d (%v), want 5", cost, err)
+		}
+		if bcrypt.CompareHashAndPassword([]byte(hashed), []byte("jane-demo")) != nil {
+			t.Error("hash doesn't match the rendered cleartext")
+		}
+	})
+
+	t.Run("literal cleartext", func(t *testing.T) {
+		literal := hash("{type: PasswordPBKDF2, cleartext: '{{.username}}', cleartext_policy: literal, iterations: 10, salt_mode: none}")
+		want, _ := TransformPasswordPBKDF2("{{.username}}", false, 10, "SHA256", "")
+		if literal != want {
+			t.Errorf("hash = %s, want the hash of the literal cleartext %s", literal, want)
+		}
+	})
+
+	t.Run("salt modes", func(t *testing.T) {
+		deterministic := "{type: PasswordArgon2id, cleartext: demo, time: 1, memory: 64, threads: 1}"
+		if hash(deterministic) != hash(deterministic) {
+			t.Error("deterministic salt should produce the same hash")
+		}
+		random := "{type: PasswordArgon2id, cleartext: demo, time: 1, memory: 64, threads: 1, salt_mode: random}"
+		if hash(random) == hash(random) {
+			t.Error("random salt should produce different hashes")
+		}
+		if none := hash("{type: PasswordArgon2id, cleartext: demo, time: 1, memory: 64, threads: 1, salt_mode: none}"); !strings.HasPrefix(none, strings.Repeat("00", passwordSaltLength)+"$") {
+			t.Errorf("hash = %s, want an empty salt", none)
+		}
+	})
+}
+
+func TestLoadConfigRejectsInvalidPasswordSettings(t *testing.T) {
+	config := &Config{Tables: map[string]TableConfig{
+		"users": {"password": passwordTransform(t, "{type: PasswordScrypt, cleartext: demo, n: 1000}")},
+	}}
+	if err := validatePasswords(config); err == nil || !strings.Contains(err.Error(), "users.password") {
+		t.Errorf("validatePasswords() error = %v, want one naming users.password", err)
+	}
+	if (ColumnTransform{Type: PasswordArgon2id, Config: map[string]any{"salt_mode": "random"}}).Deterministic() {
+		t.Error("Deterministic() = true for a randomly salted password transform")
+	}
+}
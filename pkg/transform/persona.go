@@ -0,0 +1,173 @@
+package transform
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"kasho/proto"
+)
+
+// Persona transforms fake several columns of a row from one fake identity, so the email
+// and username match the fake name instead of being faked independently. Columns with the
+// same group on a table share a persona; the persona is seeded by the key column if one
+// is set, otherwise by the original values of all the group's columns.
+
+const defaultPersonaGroup = "default"
+
+// personaFields are the values a persona provides
+var personaFields = map[string]func(p persona) string{
+	"first_name": func(p persona) string { return p.firstName },
+	"last_name":  func(p persona) string { return p.lastName },
+	"name":       func(p persona) string { return p.name },
+	"email":      func(p persona) string { return p.email },
+	"username":   func(p persona) string { return p.username },
+	"phone":      func(p persona) string { return p.phone },
+}
+
+type persona struct {
+	name      string
+	firstName string
+	lastName  string
+	email     string
+	username  string
+	phone     string
+}
+
+// newPersona generates the persona for a seed, using a locale's names and phone numbers if
+// one is given. It uses its own generator rather than the shared one so the fields don't
+// depend on what else was generated.
+func newPersona(seedText string, locale *localeData) persona {
+	faker := gofakeit.New(hash(seedText))
+	var p persona
+	var first, last string
+	if locale != nil {
+		f := &localeFaker{locale: locale, faker: faker}
+		firstName, lastName := pick(f, locale.firstNames), pick(f, locale.lastNames)
+		p.firstName, p.lastName = firstName.name, lastName.name
+		p.phone = f.pattern(locale.phoneFormat)
+		first, last = firstName.handle(), lastName.handle()
+	} else {
+		p.firstName, p.lastName, p.phone = faker.FirstName(), faker.LastName(), faker.Phone()
+		first, last = personaHandle(p.firstName), personaHandle(p.lastName)
+	}
+
+	number := faker.Number(1, 99)
+	domain := faker.DomainName()
+	if locale != nil {
+		domain = locale.emailDomains[faker.Number(0, len(locale.emailDomains)-1)]
+	}
+	p.name = p.firstName + " " + p.lastName
+	if locale != nil && locale.familyNameFirst {
+		p.name = p.lastName + " " + p.firstName
+	}
+	p.email = fmt.Sprintf("%s.%s%d@%s", first, last, number, domain)
+	p.username = fmt.Sprintf("%s%s%d", first[:min(1, len(first))], last, number)
+	return p
+}
+
+// latinFolds spells accented Latin letters the way they are written in email addresses
+var latinFolds = strings.NewReplacer(
+	"ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss",
+	"á", "a", "à", "a", "â", "a", "é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i", "ó", "o", "ò", "o", "ô", "o",
+	"ú", "u", "ù", "u", "û", "u", "ñ", "n", "ç", "c",
+)
+
+// personaHandle lowercases a name and drops characters not allowed in emails and usernames
+func personaHandle(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return -1
+	}, latinFolds.Replace(strings.ToLower(name)))
+}
+
+// personaConfig reads the field and group of a Persona transform
+func personaConfig(config map[string]any) (field, group, key string, err error) {
+	field, _ = config["field"].(string)
+	if _, ok := personaFields[field]; !ok {
+		return "", "", "", fmt.Errorf("persona transform requires 'field', one of first_name, last_name, name, email, username or phone")
+	}
+	group, _ = config["group"].(string)
+	if group == "" {
+		group = defaultPersonaGroup
+	}
+	key, _ = config["key"].(string)
+	return field, group, key, nil
+}
+
+// personaValue returns a column's field of the row's persona
+func personaValue(c *Config, table string, colTransform ColumnTransform, locale string, original *proto.ColumnValue, dmlData *proto.DMLData) (*proto.ColumnValue, error) {
+	field, group, key, err := personaConfig(colTransform.Config)
+	if err != nil {
+		return nil, err
+	}
+	if original.GetValue() == nil {
+		return nil, nil
+	}
+	if dmlData == nil {
+		return nil, fmt.Errorf("persona transform requires DML data for row context")
+	}
+
+	row := make(map[string]*proto.ColumnValue, len(dmlData.ColumnNames))
+	for i, colName := range dmlData.ColumnNames {
+		if i < len(dmlData.ColumnValues) {
+			row[colName] = dmlData.ColumnValues[i]
+		}
+	}
+
+	var seedParts []string
+	if key != "" {
+		keyValue, ok := row[key]
+		if !ok {
+			return nil, fmt.Errorf("persona key column %q not in row", key)
+		}
+		seedParts = []string{valueText(keyValue)}
+	} else {
+		// Without a key, the persona follows the original values of the group's columns
+		var columns []string
+		for column := range row {
+			ct, ok := c.ColumnTransform(table, column)
+			if !ok || ct.Type != Persona {
+				continue
+			}
+			if _, otherGroup, _, err := personaConfig(ct.Config); err == nil && otherGroup == group {
+				columns = append(columns, column)
+			}
+		}
+		sort.Strings(columns)
+		for _, column := range columns {
+			if value := row[column]; value.GetValue() != nil {
+				seedParts = append(seedParts, column+"="+valueText(value))
+			}
+		}
+	}
+
+	p := newPersona(group+"\x00"+strings.Join(seedParts, "\x00"), locales[locale])
+	return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: personaFields[field](p)}}, nil
+}
+
+// validatePersonas checks the settings of the Persona transforms of a config
+func validatePersonas(config *Config) error {
+	for table, columns := range config.Tables {
+		keys := make(map[string]string) // group -> key
+		for column, ct := range columns {
+			if ct.Type != Persona {
+				continue
+			}
+			_, group, key, err := personaConfig(ct.Config)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", table, column, err)
+			}
+			if other, ok := keys[group]; ok && other != key {
+				return fmt.Errorf("%s: persona group %s uses different keys", table, group)
+			}
+			keys[group] = key
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+
+	"kasho/proto"
+)
+
+func TestPersonaHandle(t *testing.T) {
+	tests := map[string]string{"Jane": "jane", "O'Connor": "oconnor", "Mary Ann": "maryann", "Zoë": "zoe", "Müller": "mueller", "Álvaro": "alvaro", "山田": ""}
+	for name, want := range tests {
+		if got := personaHandle(name); got != want {
+			t.Errorf("personaHandle(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestTransformChangeWithPersona(t *testing.T) {
+	personaColumn := func(field string) ColumnTransform {
+		return ColumnTransform{Type: Persona, Config: map[string]any{"field": field}}
+	}
+	config := &Config{
+		Tables: map[string]TableConfig{
+			"users": {
+				"first_name": personaColumn("first_name"),
+				"last_name":  personaColumn("last_name"),
+				"email":      personaColumn("email"),
+				"username":   personaColumn("username"),
+				"full_name":  personaColumn("name"),
+			},
+		},
+	}
+	change := func(first, last string) *proto.Change {
+		str := func(s string) *proto.ColumnValue {
+			return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: s}}
+		}
+		return &proto.Change{
+			Type: "dml",
+			Data: &proto.Change_Dml{Dml: &proto.DMLData{
+				Table:        "users",
+				ColumnNames:  []string{"first_name", "last_name", "email", "username", "full_name"},
+				ColumnValues: []*proto.ColumnValue{str(first), str(last), str("jane@example.com"), str("jdoe"), str(first + " " + last)},
+				Kind:         "insert",
+			}},
+		}
+	}
+
+	result, err := TransformChange(config, change("Jane", "Doe"))
+	if err != nil {
+		t.Fatalf("TransformChange() unexpected error: %v", err)
+	}
+	values := result.GetDml().ColumnValues
+	first, last := values[0].GetStringValue(), values[1].GetStringValue()
+	email, username, fullName := values[2].GetStringValue(), values[3].GetStringValue(), values[4].GetStringValue()
+
+	if !strings.HasPrefix(email, personaHandle(first)+"."+personaHandle(last)) {
+		t.Errorf("email %q doesn't match the fake name %s %s", email, first, last)
+	}
+	if !strings.Contains(username, personaHandle(last)) {
+		t.Errorf("username %q doesn't match the fake last name %s", username, last)
+	}
+	if fullName != first+" "+last {
+		t.Errorf("name = %q, want %q", fullName, first+" "+last)
+	}
+
+	again, err := TransformChange(config, change("Jane", "Doe"))
+	if err != nil {
+		t.Fatalf("TransformChange() unexpected error: %v", err)
+	}
+	if again.GetDml().ColumnValues[2].GetStringValue() != email {
+		t.Error("persona is not deterministic")
+	}
+
+	other, err := TransformChange(config, change("John", "Smith"))
+	if err != nil {
+		t.Fatalf("TransformChange() unexpected error: %v", err)
+	}
+	if other.GetDml().ColumnValues[2].GetStringValue() == email {
+		t.Error("different people got the same persona")
+	}
+}
+
+func TestPersonaKeyAndGroups(t *testing.T) {
+	config := &Config{
+		Tables: map[string]TableConfig{
+			"messages": {
+				"sender_email":    {Type: Persona, Config: map[string]any{"field": "email", "group": "sender", "key": "id"}},
+				"recipient_email": {Type: Persona, Config: map[string]any{"field": "email", "group": "recipient", "key": "id"}},
+			},
+		},
+	}
+	dml := &proto.DMLData{
+		Table:       "messages",
+		ColumnNames: []string{"id", "sender_email", "recipient_email"},
+		ColumnValues: []*proto.ColumnValue{
+			{Value: &proto.ColumnValue_IntValue{IntValue: 1}},
+			{Value: &proto.ColumnValue_StringValue{StringValue: "a@example.com"}},
+			{Value: &proto.ColumnValue_StringValue{StringValue: "b@example.com"}},
+		},
+	}
+
+	sender, err := GetTransformedValue(config, "messages", "sender_email", dml.ColumnValues[1], dml)
+	if err != nil {
+		t.Fatalf("GetTransformedValue() unexpected error: %v", err)
+	}
+	recipient, err := GetTransformedValue(config, "messages", "recipient_email", dml.ColumnValues[2], dml)
+	if err != nil {
+		t.Fatalf("GetTransformedValue() unexpected error: %v", err)
+	}
+	if sender.GetStringValue() == recipient.GetStringValue() {
+		t.Errorf("groups share a persona: %s", sender.GetStringValue())
+	}
+
+	// With a key, the persona doesn't depend on the original values
+	dml.ColumnValues[1] = &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: "changed@example.com"}}
+	changed, err := GetTransformedValue(config, "messages", "sender_email", dml.ColumnValues[1], dml)
+	if err != nil {
+		t.Fatalf("GetTransformedValue() unexpected error: %v", err)
+	}
+	if changed.GetStringValue() != sender.GetStringValue() {
+		t.Errorf("persona changed with the original value: %s != %s", changed.GetStringValue(), sender.GetStringValue())
+	}
+}
+
+func TestValidatePersonas(t *testing.T) {
+	tests := []struct {
+		name    string
+		table   TableConfig
+		wantErr bool
+	}{
+		{"valid", TableConfig{
+			"first_name": {Type: Persona, Config: map[string]any{"field": "first_name", "key": "id"}},
+			"email":      {Type: Persona, Config: map[string]any{"field": "email", "key": "id"}},
+		}, false},
+		{"unknown field", TableConfig{
+			"ssn": {Type: Persona, Config: map[string]any{"field": "ssn"}},
+		}, true},
+		{"different keys in a group", TableConfig{
+			"first_name": {Type: Persona, Config: map[string]any{"field": "first_name", "key": "id"}},
+			"email":      {Type: Persona, Config: map[string]any{"field": "email", "key": "account_id"}},
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Tables: map[string]TableConfig{"users": tt.table}}
+			if err := validatePersonas(config); (err != nil) != tt.wantErr {
+				t.Errorf("validatePersonas() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package transform
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"kasho/proto"
+)
+
+// Plugin transforms run customer code for masking rules the built-in transforms don't
+// cover. A plugin is either a WASM module (module: ./scrub.wasm) or an executable speaking
+// a line-based JSON protocol (command: ./scrub); fn names the function to call.
+
+// pluginRunner calls a named function of a loaded plugin
+type pluginRunner interface {
+	call(fn, value string) (string, error)
+}
+
+var (
+	pluginsMu sync.Mutex
+	plugins   = map[string]pluginRunner{} // keyed by "wasm:" or "exec:" plus the path
+)
+
+// resolvePlugins checks the plugin transforms of a config and makes their paths absolute,
+// relative to the directory holding the config file
+func resolvePlugins(config *Config, dir string) error {
+	for table, columns := range config.Tables {
+		for column, ct := range columns {
+			if err := resolvePlugin(table+"."+column, ct, dir); err != nil {
+				return err
+			}
+		}
+	}
+	for _, d := range config.Defaults {
+		if err := resolvePlugin("defaults."+d.Match, d.Transform, dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolvePlugin checks a plugin transform and makes its path absolute
+func resolvePlugin(name string, ct ColumnTransform, dir string) error {
+	if ct.Type != Plugin {
+		return nil
+	}
+	module, _ := ct.Config["module"].(string)
+	command, _ := ct.Config["command"].(string)
+	fn, _ := ct.Config["fn"].(string)
+	if fn == "" {
+		return fmt.Errorf("plugin transform for %s requires 'fn'", name)
+	}
+	if (module == "") == (command == "") {
+		return fmt.Errorf("plugin transform for %s requires exactly one of 'module' or 'command'", name)
+	}
+
+	key, path := "module", module
+	if command != "" {
+		key, path = "command", command
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("plugin for %s: %w", name, err)
+	}
+	ct.Config[key] = path
+	return nil
+}
+
+// TransformPlugin calls the configured plugin function with a value
+func TransformPlugin(config map[string]any, value string) (string, error) {
+	runner, err := loadPlugin(config)
+	if err != nil {
+		return "", err
+	}
+	fn, _ := config["fn"].(string)
+	return runner.call(fn, value)
+}
+
+// loadPlugin returns the runner for a plugin, starting it on first use
+func loadPlugin(config map[string]any) (pluginRunner, error) {
+	module, _ := config["module"].(string)
+	command, _ := config["command"].(string)
+
+	var key string
+	switch {
+	case module != "":
+		key = "wasm:" + module
+	case command != "":
+		key = "exec:" + command
+	default:
+		return nil, fmt.Errorf("plugin transform requires 'module' or 'command'")
+	}
+
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	if runner, ok := plugins[key]; ok {
+		return runner, nil
+	}
+
+	var runner pluginRunner
+	if module != "" {
+		wasm, err := newWASMPlugin(module)
+		if err != nil {
+			return nil, err
+		}
+		runner = wasm
+	} else {
+		runner = newExecPlugin(command, stringList(config["args"]))
+	}
+	plugins[key] = runner
+	return runner, nil
+}
+
+// stringList converts a YAML list to strings
+func stringList(value any) []string {
+	items, _ := value.([]any)
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		list = append(list, fmt.Sprint(item))
+	}
+	return list
+}
+
+// valueText renders a column value as the text passed to plugins
+func valueText(value *proto.ColumnValue) string {
+	switch v := value.Value.(type) {
+	case *proto.ColumnValue_StringValue:
+		return v.StringValue
+	case *proto.ColumnValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10)
+	case *proto.ColumnValue_FloatValue:
+		return strconv.FormatFloat(v.FloatValue, 'g', -1, 64)
+	case *proto.ColumnValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue)
+	case *proto.ColumnValue_TimestampValue:
+		return v.TimestampValue
+	case *proto.ColumnValue_BytesValue:
+		return hex.EncodeToString(v.BytesValue)
+	case *proto.ColumnValue_JsonValue:
+		return v.JsonValue
+	case *proto.ColumnValue_UuidValue:
+		return v.UuidValue
+	case *proto.ColumnValue_GeometryValue:
+		return v.GeometryValue.GetWkt()
+	default:
+		return strings.TrimSpace(fmt.Sprint(v))
+	}
+}
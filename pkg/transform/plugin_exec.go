@@ -0,0 +1,113 @@
+package transform
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// execPlugin runs transform functions in a long-lived child process.
+//
+// Each call writes one JSON request per line to the process's stdin:
+//
+//	{"fn": "scrub_ssn", "value": "123-45-6789"}
+//
+// and reads one JSON response per line from its stdout:
+//
+//	{"value": "XXX-XX-6789"}  or  {"error": "invalid SSN"}
+//
+// The process is started on first use and restarted after it exits or breaks the protocol.
+// Its stderr is passed through to the service's stderr.
+type execPlugin struct {
+	mu      sync.Mutex
+	command string
+	args    []string
+
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+type execPluginRequest struct {
+	Fn    string `json:"fn"`
+	Value string `json:"value"`
+}
+
+type execPluginResponse struct {
+	Value string `json:"value"`
+	Error string `json:"error,omitempty"`
+}
+
+func newExecPlugin(command string, args []string) *execPlugin {
+	return &execPlugin{command: command, args: args}
+}
+
+func (p *execPlugin) call(fn, value string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cmd == nil {
+		if err := p.start(); err != nil {
+			return "", err
+		}
+	}
+
+	response, err := p.roundTrip(execPluginRequest{Fn: fn, Value: value})
+	if err != nil {
+		p.stop()
+		return "", fmt.Errorf("plugin %s: %w", p.command, err)
+	}
+	if response.Error != "" {
+		return "", fmt.Errorf("plugin %s: %s: %s", p.command, fn, response.Error)
+	}
+	return response.Value, nil
+}
+
+func (p *execPlugin) roundTrip(request execPluginRequest) (execPluginResponse, error) {
+	var response execPluginResponse
+	line, err := json.Marshal(request)
+	if err != nil {
+		return response, err
+	}
+	if _, err := p.stdin.Write(append(line, '\n')); err != nil {
+		return response, fmt.Errorf("failed to write request: %w", err)
+	}
+	reply, err := p.stdout.ReadBytes('\n')
+	if err != nil {
+		return response, fmt.Errorf("failed to read response: %w", err)
+	}
+	if err := json.Unmarshal(reply, &response); err != nil {
+		return response, fmt.Errorf("invalid response %q: %w", reply, err)
+	}
+	return response, nil
+}
+
+func (p *execPlugin) start() error {
+	cmd := exec.Command(p.command, p.args...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start plugin %s: %w", p.command, err)
+	}
+	p.cmd, p.stdin, p.stdout = cmd, stdin, bufio.NewReader(stdout)
+	return nil
+}
+
+// stop kills the process so the next call starts a fresh one
+func (p *execPlugin) stop() {
+	p.stdin.Close()
+	p.cmd.Process.Kill()
+	p.cmd.Wait()
+	p.cmd, p.stdin, p.stdout = nil, nil, nil
+}
@@ -0,0 +1,221 @@
+package transform
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"kasho/proto"
+)
+
+// TestMain lets the test binary act as an exec plugin when KASHO_TEST_PLUGIN is set
+func TestMain(m *testing.M) {
+	if os.Getenv("KASHO_TEST_PLUGIN") == "1" {
+		runTestPlugin()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// runTestPlugin upper-cases values for "upper", fails for "fail" and exits for "crash"
+func runTestPlugin() {
+	scanner := bufio.NewScanner(os.Stdin)
+	encoder := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		var request execPluginRequest
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			os.Exit(2)
+		}
+		switch request.Fn {
+		case "upper":
+			encoder.Encode(execPluginResponse{Value: strings.ToUpper(request.Value)})
+		case "crash":
+			os.Exit(1)
+		default:
+			encoder.Encode(execPluginResponse{Error: "unknown function " + request.Fn})
+		}
+	}
+}
+
+func TestExecPlugin(t *testing.T) {
+	t.Setenv("KASHO_TEST_PLUGIN", "1")
+	plugin := newExecPlugin(os.Args[0], nil)
+
+	got, err := plugin.call("upper", "jane doe")
+	if err != nil {
+		t.Fatalf("call() unexpected error: %v", err)
+	}
+	if got != "JANE DOE" {
+		t.Errorf("call() = %q, want %q", got, "JANE DOE")
+	}
+
+	if _, err := plugin.call("fail", "x"); err == nil || !strings.Contains(err.Error(), "unknown function fail") {
+		t.Errorf("call() error = %v, want the plugin's error", err)
+	}
+
+	// A crashed plugin is restarted on the next call
+	if _, err := plugin.call("crash", "x"); err == nil {
+		t.Error("call() expected error when the plugin exits")
+	}
+	if got, err := plugin.call("upper", "again"); err != nil || got != "AGAIN" {
+		t.Errorf("call() after restart = %q, %v; want %q", got, err, "AGAIN")
+	}
+}
+
+// upperWASM is a minimal module that upper-cases ASCII in place:
+//
+//	(module
+//	  (memory (export "memory") 1)
+//	  (func (export "alloc") (param i32) (result i32) (i32.const 1024))
+//	  (func (export "upper") (param $ptr i32) (param $len i32) (result i64)
+//	    (local $i i32) (local $c i32)
+//	    (block (loop
+//	      (br_if 1 (i32.ge_u (local.get $i) (local.get $len)))
+//	      (local.set $c (i32.load8_u (i32.add (local.get $ptr) (local.get $i))))
+//	      (if (i32.lt_u (i32.sub (local.get $c) (i32.const 97)) (i32.const 26))
+//	        (then (i32.store8 (i32.add (local.get $ptr) (local.get $i)) (i32.sub (local.get $c) (i32.const 32)))))
+//	      (local.set $i (i32.add (local.get $i) (i32.const 1)))
+//	      (br 0)))
+//	    (i64.or (i64.shl (i64.extend_i32_u (local.get $ptr)) (i64.const 32)) (i64.extend_i32_u (local.get $len)))))
+var upperWASM = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, 0x01, 0x0c, 0x02, 0x60,
+	0x01, 0x7f, 0x01, 0x7f, 0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e, 0x03, 0x03,
+	0x02, 0x00, 0x01, 0x05, 0x03, 0x01, 0x00, 0x01, 0x07, 0x1a, 0x03, 0x06,
+	0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x02, 0x00, 0x05, 0x61, 0x6c, 0x6c,
+	0x6f, 0x63, 0x00, 0x00, 0x05, 0x75, 0x70, 0x70, 0x65, 0x72, 0x00, 0x01,
+	0x0a, 0x4f, 0x02, 0x05, 0x00, 0x41, 0x80, 0x08, 0x0b, 0x47, 0x01, 0x02,
+	0x7f, 0x02, 0x40, 0x03, 0x40, 0x20, 0x02, 0x20, 0x01, 0x4f, 0x0d, 0x01,
+	0x20, 0x00, 0x20, 0x02, 0x6a, 0x2d, 0x00, 0x00, 0x21, 0x03, 0x20, 0x03,
+	0x41, 0xe1, 0x00, 0x6b, 0x41, 0x1a, 0x49, 0x04, 0x40, 0x20, 0x00, 0x20,
+	0x02, 0x6a, 0x20, 0x03, 0x41, 0x20, 0x6b, 0x3a, 0x00, 0x00, 0x0b, 0x20,
+	0x02, 0x41, 0x01, 0x6a, 0x21, 0x02, 0x0c, 0x00, 0x0b, 0x0b, 0x20, 0x00,
+	0xad, 0x42, 0x20, 0x86, 0x20, 0x01, 0xad, 0x84, 0x0b,
+}
+
+func writeUpperWASM(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "upper.wasm")
+	if err := os.WriteFile(path, upperWASM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestWASMPlugin(t *testing.T) {
+	plugin, err := newWASMPlugin(writeUpperWASM(t))
+	if err != nil {
+		t.Fatalf("newWASMPlugin() unexpected error: %v", err)
+	}
+
+	got, err := plugin.call("upper", "jane doe")
+	if err != nil {
+		t.Fatalf("call() unexpected error: %v", err)
+	}
+	if got != "JANE DOE" {
+		t.Errorf("call() = %q, want %q", got, "JANE DOE")
+	}
+
+	if _, err := plugin.call("scrub_ssn", "x"); err == nil || !strings.Contains(err.Error(), "does not export scrub_ssn") {
+		t.Errorf("call() error = %v, want missing export error", err)
+	}
+}
+
+func TestWASMPluginInvalidModule(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.wasm")
+	if err := os.WriteFile(path, []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newWASMPlugin(path); err == nil {
+		t.Error("newWASMPlugin() expected error for an invalid module")
+	}
+}
+
+func TestGetTransformedValueWithPlugin(t *testing.T) {
+	t.Setenv("KASHO_TEST_PLUGIN", "1")
+	config := &Config{
+		Tables: map[string]TableConfig{
+			"users": {
+				"name": ColumnTransform{Type: Plugin, Config: map[string]any{"command": os.Args[0], "fn": "upper"}},
+				"id":   ColumnTransform{Type: Plugin, Config: map[string]any{"command": os.Args[0], "fn": "upper"}},
+			},
+		},
+	}
+
+	got, err := GetTransformedValue(config, "users", "name", &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: "jane"}}, nil)
+	if err != nil {
+		t.Fatalf("GetTransformedValue() unexpected error: %v", err)
+	}
+	if got.GetStringValue() != "JANE" {
+		t.Errorf("GetTransformedValue() = %v, want JANE", got)
+	}
+
+	// Non-string values are passed as text
+	got, err = GetTransformedValue(config, "users", "id", &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: 42}}, nil)
+	if err != nil {
+		t.Fatalf("GetTransformedValue() unexpected error: %v", err)
+	}
+	if got.GetStringValue() != "42" {
+		t.Errorf("GetTransformedValue() = %v, want 42", got)
+	}
+}
+
+func TestLoadConfigPlugins(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "scrub.wasm"), []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		column    string
+		wantError bool
+	}{
+		{"relative module", `{type: Plugin, module: ./scrub.wasm, fn: scrub_ssn}`, false},
+		{"missing fn", `{type: Plugin, module: ./scrub.wasm}`, true},
+		{"missing module", `{type: Plugin, module: ./missing.wasm, fn: scrub_ssn}`, true},
+		{"module and command", `{type: Plugin, module: ./scrub.wasm, command: ./scrub, fn: scrub_ssn}`, true},
+		{"neither module nor command", `{type: Plugin, fn: scrub_ssn}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(dir, "transforms.yml")
+			content := "major_version: 0\ntables:\n  users:\n    ssn: " + tt.column + "\n"
+			if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write test config: %v", err)
+			}
+
+			config, err := LoadConfig(configPath)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("LoadConfig() error = %v, wantError %v", err, tt.wantError)
+			}
+			if !tt.wantError {
+				want := filepath.Join(dir, "scrub.wasm")
+				if got := config.Tables["users"]["ssn"].Config["module"]; got != want {
+					t.Errorf("module = %v, want %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetTransformedValueWithWASMPlugin(t *testing.T) {
+	config := &Config{
+		Tables: map[string]TableConfig{
+			"users": {
+				"name": ColumnTransform{Type: Plugin, Config: map[string]any{"module": writeUpperWASM(t), "fn": "upper"}},
+			},
+		},
+	}
+
+	got, err := GetTransformedValue(config, "users", "name", &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: "jane"}}, nil)
+	if err != nil {
+		t.Fatalf("GetTransformedValue() unexpected error: %v", err)
+	}
+	if got.GetStringValue() != "JANE" {
+		t.Errorf("GetTransformedValue() = %v, want JANE", got)
+	}
+}
@@ -0,0 +1,93 @@
+package transform
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// wasmPlugin runs transform functions exported by a WASM module.
+//
+// The module must export its memory, alloc(size i32) i32 and the transform functions,
+// each taking (ptr i32, len i32) and returning an i64 that packs the result as
+// ptr<<32 | len. An exported dealloc(ptr i32, len i32) is called for inputs and results
+// when present. Modules built as WASI reactors are initialized with _initialize.
+type wasmPlugin struct {
+	mu     sync.Mutex
+	module api.Module
+}
+
+func newWASMPlugin(path string) (*wasmPlugin, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WASM module: %w", err)
+	}
+
+	ctx := context.Background()
+	runtime := wazero.NewRuntime(ctx)
+	wasi_snapshot_preview1.MustInstantiate(ctx, runtime)
+
+	compiled, err := runtime.CompileModule(ctx, code)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to compile WASM module %s: %w", path, err)
+	}
+	module, err := runtime.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().WithStartFunctions("_initialize"))
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to instantiate WASM module %s: %w", path, err)
+	}
+	if module.ExportedFunction("alloc") == nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("WASM module %s does not export alloc", path)
+	}
+	return &wasmPlugin{module: module}, nil
+}
+
+func (p *wasmPlugin) call(fn, value string) (string, error) {
+	// Module instances are not safe for concurrent use
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ctx := context.Background()
+	transform := p.module.ExportedFunction(fn)
+	if transform == nil {
+		return "", fmt.Errorf("WASM module does not export %s", fn)
+	}
+
+	results, err := p.module.ExportedFunction("alloc").Call(ctx, uint64(len(value)))
+	if err != nil {
+		return "", fmt.Errorf("alloc failed: %w", err)
+	}
+	inPtr := uint32(results[0])
+	defer p.free(ctx, inPtr, uint32(len(value)))
+	if !p.module.Memory().Write(inPtr, []byte(value)) {
+		return "", fmt.Errorf("alloc returned an out of range pointer")
+	}
+
+	results, err = transform.Call(ctx, uint64(inPtr), uint64(len(value)))
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %w", fn, err)
+	}
+	outPtr, outLen := uint32(results[0]>>32), uint32(results[0])
+	defer p.free(ctx, outPtr, outLen)
+
+	out, ok := p.module.Memory().Read(outPtr, outLen)
+	if !ok {
+		return "", fmt.Errorf("%s returned an out of range result", fn)
+	}
+	// Copy before the guest frees or reuses the memory
+	return string(out), nil
+}
+
+// free releases guest memory if the module exports dealloc
+func (p *wasmPlugin) free(ctx context.Context, ptr, size uint32) {
+	if dealloc := p.module.ExportedFunction("dealloc"); dealloc != nil {
+		dealloc.Call(ctx, uint64(ptr), uint64(size))
+	}
+}
@@ -0,0 +1,158 @@
+package transform
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Options of a transform can reference a secret instead of holding it, e.g. a password
+// pepper or a salt, so the config can be stored and reviewed without it:
+//
+//	salt:
+//	  secretRef: env:KASHO_DATE_SHIFT_SALT
+//
+// References are resolved when the config is loaded. env: and file: references are built
+// in; other schemes, such as KMS services, are registered with RegisterSecretResolver.
+
+// SecretRef references a secret
+type SecretRef struct {
+	URI *url.URL
+	// Ciphertext is the encrypted secret, for references to a key that decrypts it
+	Ciphertext []byte
+}
+
+// SecretResolver returns the secret a reference points to
+type SecretResolver func(ref SecretRef) (string, error)
+
+var (
+	secretResolversMu sync.RWMutex
+	secretResolvers   = map[string]SecretResolver{
+		"env":  resolveEnvSecret,
+		"file": resolveFileSecret,
+	}
+)
+
+// RegisterSecretResolver resolves secret references with the given URI scheme, e.g. awskms
+func RegisterSecretResolver(scheme string, resolver SecretResolver) {
+	secretResolversMu.Lock()
+	defer secretResolversMu.Unlock()
+	secretResolvers[scheme] = resolver
+}
+
+func resolveEnvSecret(ref SecretRef) (string, error) {
+	name := ref.URI.Opaque
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// resolveFileSecret reads a secret from a file, e.g. a mounted Kubernetes secret, without
+// a trailing newline
+func resolveFileSecret(ref SecretRef) (string, error) {
+	path := ref.URI.Path
+	if path == "" {
+		path = ref.URI.Opaque
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// parseSecretRef returns the reference an option holds, if it holds one. Relative file:
+// references are relative to dir.
+func parseSecretRef(value any, dir string) (SecretRef, bool, error) {
+	m, ok := value.(map[string]any)
+	if !ok {
+		return SecretRef{}, false, nil
+	}
+	raw, ok := m["secretRef"]
+	if !ok {
+		return SecretRef{}, false, nil
+	}
+	for key := range m {
+		if key != "secretRef" && key != "ciphertext" {
+			return SecretRef{}, true, fmt.Errorf("unknown secret reference field %q", key)
+		}
+	}
+
+	text, _ := raw.(string)
+	uri, err := url.Parse(text)
+	if err != nil || uri.Scheme == "" {
+		return SecretRef{}, true, fmt.Errorf("invalid secretRef %q: expected a URI such as env:NAME or file:/path", text)
+	}
+	ref := SecretRef{URI: uri}
+
+	if uri.Scheme == "file" && uri.Opaque != "" && !filepath.IsAbs(uri.Opaque) {
+		uri.Opaque = filepath.Join(dir, uri.Opaque)
+	}
+	if ciphertext, ok := m["ciphertext"]; ok {
+		text, _ := ciphertext.(string)
+		ref.Ciphertext, err = base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return SecretRef{}, true, fmt.Errorf("invalid ciphertext: %w", err)
+		}
+	}
+	return ref, true, nil
+}
+
+// resolveSecrets replaces the secret references in the options of a config's transforms
+// with the secrets, using resolve if set or the registered resolvers otherwise
+func resolveSecrets(config *Config, dir string, resolve SecretResolver) error {
+	for table, columns := range config.Tables {
+		for column, ct := range columns {
+			if err := resolveTransformSecrets(ct, dir, resolve); err != nil {
+				return fmt.Errorf("%s.%s: %w", table, column, err)
+			}
+		}
+	}
+	for _, d := range config.Defaults {
+		if err := resolveTransformSecrets(d.Transform, dir, resolve); err != nil {
+			return fmt.Errorf("defaults.%s: %w", d.Match, err)
+		}
+	}
+	return nil
+}
+
+func resolveTransformSecrets(ct ColumnTransform, dir string, resolve SecretResolver) error {
+	options := make([]string, 0, len(ct.Config))
+	for option := range ct.Config {
+		options = append(options, option)
+	}
+	sort.Strings(options)
+
+	for _, option := range options {
+		ref, ok, err := parseSecretRef(ct.Config[option], dir)
+		if err != nil {
+			return fmt.Errorf("option %s: %w", option, err)
+		}
+		if !ok {
+			continue
+		}
+
+		resolver := resolve
+		if resolver == nil {
+			secretResolversMu.RLock()
+			resolver = secretResolvers[ref.URI.Scheme]
+			secretResolversMu.RUnlock()
+			if resolver == nil {
+				return fmt.Errorf("option %s: unsupported secret reference scheme %q", option, ref.URI.Scheme)
+			}
+		}
+		secret, err := resolver(ref)
+		if err != nil {
+			return fmt.Errorf("option %s: failed to resolve %s secret: %w", option, ref.URI.Scheme, err)
+		}
+		ct.Config[option] = secret
+	}
+	return nil
+}
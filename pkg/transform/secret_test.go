@@ -0,0 +1,87 @@
+package transform
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigSecretRefs(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "pepper"), []byte("file-pepper\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KASHO_TEST_SALT", "env-salt")
+	RegisterSecretResolver("testkms", func(ref SecretRef) (string, error) {
+		return ref.URI.Host + ":" + string(ref.Ciphertext), nil
+	})
+
+	path := filepath.Join(dir, "transforms.yml")
+	content := `tables:
+  users:
+    signed_up_at:
+      type: DateShift
+      key: id
+      salt:
+        secretRef: env:KASHO_TEST_SALT
+    password:
+      type: PasswordPBKDF2
+      cleartext:
+        secretRef: file:pepper
+    token:
+      type: Plugin
+      fn: scrub
+      command: /bin/true
+      key:
+        secretRef: testkms://key-1
+        ciphertext: c2VjcmV0`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() unexpected error: %v", err)
+	}
+	users := config.Tables["users"]
+	if got := users["signed_up_at"].Config["salt"]; got != "env-salt" {
+		t.Errorf("salt = %v, want the environment variable's value", got)
+	}
+	if got := users["password"].Config["cleartext"]; got != "file-pepper" {
+		t.Errorf("cleartext = %v, want the file's content", got)
+	}
+	if got := users["token"].Config["key"]; got != "key-1:secret" {
+		t.Errorf("key = %v, want the registered resolver's value", got)
+	}
+}
+
+func TestLoadConfigSecretRefErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		option  string
+		wantErr string
+	}{
+		{"unset variable", "secretRef: env:KASHO_TEST_UNSET", "KASHO_TEST_UNSET is not set"},
+		{"unknown scheme", "secretRef: vault://kasho/salt", `unsupported secret reference scheme "vault"`},
+		{"not a URI", "secretRef: salt", "invalid secretRef"},
+		{"unknown field", "{secretRef: env:HOME, value: x}", `unknown secret reference field "value"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "transforms.yml")
+			content := "tables:\n  users:\n    signed_up_at:\n      type: DateShift\n      key: id\n      salt:\n        " + tt.option + "\n"
+			if strings.HasPrefix(tt.option, "{") {
+				content = "tables:\n  users:\n    signed_up_at:\n      type: DateShift\n      key: id\n      salt: " + tt.option + "\n"
+			}
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadConfig(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadConfig() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
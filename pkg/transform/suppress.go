@@ -0,0 +1,128 @@
+package transform
+
+import (
+	"fmt"
+	"sync"
+
+	"kasho/proto"
+)
+
+// Suppress replaces values that occur fewer than min_frequency times among the last
+// `window` values of a column, so rare values such as unusual job titles or small-town
+// zip codes can't single out a person. Frequencies are counted as changes stream through
+// the translicator, so the first occurrences of every value are suppressed until it has
+// been seen min_frequency times.
+
+const (
+	defaultSuppressWindow      = 10000
+	defaultSuppressReplacement = "OTHER"
+)
+
+// frequencyWindow counts values among the most recent ones observed
+type frequencyWindow struct {
+	mu     sync.Mutex
+	values []string // ring buffer of the last len(values) values
+	next   int
+	full   bool
+	counts map[string]int
+}
+
+func newFrequencyWindow(size int) *frequencyWindow {
+	return &frequencyWindow{values: make([]string, size), counts: make(map[string]int)}
+}
+
+// observe records a value and returns how often it occurs in the window
+func (w *frequencyWindow) observe(value string) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.full {
+		evicted := w.values[w.next]
+		if w.counts[evicted]--; w.counts[evicted] == 0 {
+			delete(w.counts, evicted)
+		}
+	}
+	w.values[w.next] = value
+	w.next = (w.next + 1) % len(w.values)
+	if w.next == 0 {
+		w.full = true
+	}
+	w.counts[value]++
+	return w.counts[value]
+}
+
+var (
+	suppressWindows   = make(map[string]*frequencyWindow) // keyed by table.column
+	suppressWindowsMu sync.Mutex
+)
+
+func getSuppressWindow(table, column string, size int) *frequencyWindow {
+	suppressWindowsMu.Lock()
+	defer suppressWindowsMu.Unlock()
+	key := table + "." + column
+	w, ok := suppressWindows[key]
+	if !ok {
+		w = newFrequencyWindow(size)
+		suppressWindows[key] = w
+	}
+	return w
+}
+
+// suppressConfig reads the settings of a Suppress transform
+func suppressConfig(config map[string]any) (minFrequency, window int, replacement string, err error) {
+	n, ok, err := configNumber(config, "min_frequency")
+	if err != nil {
+		return 0, 0, "", err
+	}
+	if !ok || n < 2 {
+		return 0, 0, "", fmt.Errorf("suppress transform requires 'min_frequency' of at least 2")
+	}
+	minFrequency = int(n)
+
+	window = defaultSuppressWindow
+	if n, ok, err := configNumber(config, "window"); err != nil {
+		return 0, 0, "", err
+	} else if ok {
+		window = int(n)
+	}
+	if window < minFrequency {
+		return 0, 0, "", fmt.Errorf("suppress 'window' must be at least 'min_frequency'")
+	}
+
+	replacement = defaultSuppressReplacement
+	if r, ok := config["replacement"].(string); ok {
+		replacement = r
+	}
+	return minFrequency, window, replacement, nil
+}
+
+// suppressValue counts a column value and replaces it if it is rare
+func suppressValue(table, column string, config map[string]any, original *proto.ColumnValue) (*proto.ColumnValue, error) {
+	minFrequency, window, replacement, err := suppressConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if original.GetValue() == nil {
+		return nil, nil
+	}
+
+	if getSuppressWindow(table, column, window).observe(valueText(original)) >= minFrequency {
+		return nil, nil
+	}
+	return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: replacement}}, nil
+}
+
+// validateSuppressions checks the settings of the Suppress transforms of a config
+func validateSuppressions(config *Config) error {
+	for table, columns := range config.Tables {
+		for column, ct := range columns {
+			if ct.Type != Suppress {
+				continue
+			}
+			if _, _, _, err := suppressConfig(ct.Config); err != nil {
+				return fmt.Errorf("%s.%s: %w", table, column, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package transform
+
+import (
+	"testing"
+
+	"kasho/proto"
+)
+
+func TestFrequencyWindow(t *testing.T) {
+	w := newFrequencyWindow(3)
+	for i, tt := range []struct {
+		value string
+		want  int
+	}{
+		{"a", 1},
+		{"a", 2},
+		{"b", 1},
+		{"a", 2}, // the first "a" has left the window
+		{"c", 1},
+		{"c", 2},
+		{"a", 1},
+	} {
+		if got := w.observe(tt.value); got != tt.want {
+			t.Errorf("observe #%d(%q) = %d, want %d", i, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestGetTransformedValueSuppress(t *testing.T) {
+	config := &Config{
+		Tables: map[string]TableConfig{
+			"suppress_test": {
+				"job_title": {Type: Suppress, Config: map[string]any{"min_frequency": 2, "window": 100}},
+				"zip":       {Type: Suppress, Config: map[string]any{"min_frequency": 2, "replacement": "00000"}},
+			},
+		},
+	}
+	str := func(s string) *proto.ColumnValue {
+		return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: s}}
+	}
+	suppress := func(column, value string) string {
+		t.Helper()
+		got, err := GetTransformedValue(config, "suppress_test", column, str(value), nil)
+		if err != nil {
+			t.Fatalf("GetTransformedValue() unexpected error: %v", err)
+		}
+		if got == nil {
+			return value
+		}
+		return got.GetStringValue()
+	}
+
+	if got := suppress("job_title", "Engineer"); got != "OTHER" {
+		t.Errorf("first Engineer = %q, want OTHER", got)
+	}
+	if got := suppress("job_title", "Engineer"); got != "Engineer" {
+		t.Errorf("second Engineer = %q, want Engineer", got)
+	}
+	if got := suppress("job_title", "Chief Llama Officer"); got != "OTHER" {
+		t.Errorf("rare title = %q, want OTHER", got)
+	}
+	// Columns are counted separately
+	if got := suppress("zip", "Engineer"); got != "00000" {
+		t.Errorf("zip = %q, want the configured replacement", got)
+	}
+
+	got, err := GetTransformedValue(config, "suppress_test", "job_title", &proto.ColumnValue{}, nil)
+	if err != nil || got != nil {
+		t.Errorf("NULL = %v, %v; want nil", got, err)
+	}
+}
+
+func TestValidateSuppressions(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  map[string]any
+		wantErr bool
+	}{
+		{"defaults", map[string]any{"min_frequency": 5}, false},
+		{"all options", map[string]any{"min_frequency": 5, "window": 1000, "replacement": "*"}, false},
+		{"missing min_frequency", map[string]any{}, true},
+		{"min_frequency of 1", map[string]any{"min_frequency": 1}, true},
+		{"window smaller than min_frequency", map[string]any{"min_frequency": 5, "window": 3}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Tables: map[string]TableConfig{"t": {"c": {Type: Suppress, Config: tt.config}}}}
+			if err := validateSuppressions(config); (err != nil) != tt.wantErr {
+				t.Errorf("validateSuppressions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
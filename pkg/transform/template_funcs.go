@@ -0,0 +1,150 @@
+package transform
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Template helpers take the piped value last, so they read naturally in pipelines such
+// as {{ .email | sha256 | substr 0 12 }}. Values are formatted with fmt.Sprint, which lets
+// them accept numeric and boolean columns as well as strings.
+
+// templateTimeLayouts are the timestamp formats dateFormat and dateAdd accept, in the
+// order they are tried
+var templateTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+func templateSHA256(value any) string {
+	sum := sha256.Sum256([]byte(fmt.Sprint(value)))
+	return hex.EncodeToString(sum[:])
+}
+
+func templateMD5(value any) string {
+	sum := md5.Sum([]byte(fmt.Sprint(value)))
+	return hex.EncodeToString(sum[:])
+}
+
+// templateSubstr returns up to length characters starting at start; a negative length
+// takes the rest of the string. Out-of-range bounds are clamped.
+func templateSubstr(start, length int, value any) string {
+	runes := []rune(fmt.Sprint(value))
+	start = max(0, min(start, len(runes)))
+	end := len(runes)
+	if length >= 0 {
+		end = min(start+length, len(runes))
+	}
+	return string(runes[start:end])
+}
+
+// templatePad pads a value to width characters by repeating pad on the left or right
+func templatePad(width int, pad string, value any, left bool) (string, error) {
+	s := fmt.Sprint(value)
+	missing := width - utf8.RuneCountInString(s)
+	if missing <= 0 {
+		return s, nil
+	}
+	if pad == "" {
+		return "", fmt.Errorf("pad string must not be empty")
+	}
+	padding := []rune(strings.Repeat(pad, missing))[:missing]
+	if left {
+		return string(padding) + s, nil
+	}
+	return s + string(padding), nil
+}
+
+func templatePadLeft(width int, pad string, value any) (string, error) {
+	return templatePad(width, pad, value, true)
+}
+
+func templatePadRight(width int, pad string, value any) (string, error) {
+	return templatePad(width, pad, value, false)
+}
+
+func templateReplace(old, new string, value any) string {
+	return strings.ReplaceAll(fmt.Sprint(value), old, new)
+}
+
+func templateTrim(value any) string {
+	return strings.TrimSpace(fmt.Sprint(value))
+}
+
+// parseTemplateTime parses a timestamp column value and returns the layout it matched
+func parseTemplateTime(value any) (time.Time, string, error) {
+	if t, ok := value.(time.Time); ok {
+		return t, time.RFC3339, nil
+	}
+	s := fmt.Sprint(value)
+	for _, layout := range templateTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, layout, nil
+		}
+	}
+	return time.Time{}, "", fmt.Errorf("cannot parse %q as a date", s)
+}
+
+// templateDateFormat formats a date with a Go reference-time layout, e.g. "Jan 2006"
+func templateDateFormat(layout string, value any) (string, error) {
+	t, _, err := parseTemplateTime(value)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(layout), nil
+}
+
+// templateDateAdd shifts a date by a duration such as "-36h" or a number of days such as
+// "30d", keeping the format of the input
+func templateDateAdd(offset string, value any) (string, error) {
+	t, layout, err := parseTemplateTime(value)
+	if err != nil {
+		return "", err
+	}
+	if days, ok := strings.CutSuffix(offset, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return "", fmt.Errorf("invalid day offset %q", offset)
+		}
+		return t.AddDate(0, 0, n).Format(layout), nil
+	}
+	d, err := time.ParseDuration(offset)
+	if err != nil {
+		return "", fmt.Errorf("invalid offset %q: %w", offset, err)
+	}
+	return t.Add(d).Format(layout), nil
+}
+
+// templateFake runs a fake generator seeded by a value, e.g. {{ fake "FirstName" .id }}.
+// The same seed always produces the same result. The generator can be named with or
+// without its "Fake" prefix.
+func templateFake(name string, seedValue any) (string, error) {
+	transformType := TransformType(name)
+	if !strings.HasPrefix(name, "Fake") {
+		transformType = TransformType("Fake" + name)
+	}
+	fn, err := transformType.GetTransformFunction()
+	if err != nil {
+		return "", err
+	}
+
+	input := fmt.Sprint(seedValue)
+	switch f := fn.(type) {
+	case func(string) string:
+		return f(input), nil
+	case func(int) int:
+		return strconv.Itoa(f(int(hash(input) >> 1))), nil
+	case func(float64) float64:
+		return strconv.FormatFloat(f(float64(hash(input))), 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("%s can't be used with fake", transformType)
+	}
+}
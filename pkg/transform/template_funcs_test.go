@@ -0,0 +1,80 @@
+package transform
+
+import (
+	"testing"
+
+	"kasho/proto"
+)
+
+func TestTemplateHelpers(t *testing.T) {
+	row := map[string]*proto.ColumnValue{
+		"id":         {Value: &proto.ColumnValue_IntValue{IntValue: 42}},
+		"email":      {Value: &proto.ColumnValue_StringValue{StringValue: "jane@example.com"}},
+		"code":       {Value: &proto.ColumnValue_StringValue{StringValue: "  AB-12  "}},
+		"created_at": {Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2024-03-15T10:30:00Z"}},
+		"birthday":   {Value: &proto.ColumnValue_StringValue{StringValue: "1990-01-31"}},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{"sha256", `{{ sha256 "abc" }}`, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", false},
+		{"md5", `{{ md5 "abc" }}`, "900150983cd24fb0d6963f7d28e17f72", false},
+		{"hash of a number", `{{ .id | sha256 | substr 0 8 }}`, "73475cb4", false},
+		{"substr", `{{ .email | substr 0 4 }}`, "jane", false},
+		{"substr rest", `{{ .email | substr 5 -1 }}`, "example.com", false},
+		{"substr out of range", `{{ .email | substr 10 100 }}`, "le.com", false},
+		{"padLeft", `{{ .id | padLeft 6 "0" }}`, "000042", false},
+		{"padRight", `{{ .id | padRight 5 "ab" }}`, "42aba", false},
+		{"pad shorter than value", `{{ .email | padLeft 3 "0" }}`, "jane@example.com", false},
+		{"replace", `{{ .email | replace "example.com" "test.local" }}`, "jane@test.local", false},
+		{"trim", `[{{ .code | trim }}]`, "[AB-12]", false},
+		{"dateFormat", `{{ .created_at | dateFormat "Jan 2006" }}`, "Mar 2024", false},
+		{"dateAdd days", `{{ .birthday | dateAdd "1d" }}`, "1990-02-01", false},
+		{"dateAdd duration", `{{ .created_at | dateAdd "-90m" }}`, "2024-03-15T09:00:00Z", false},
+		{"dateAdd invalid offset", `{{ .birthday | dateAdd "soon" }}`, "", true},
+		{"dateFormat invalid date", `{{ .email | dateFormat "2006" }}`, "", true},
+		{"fake unknown generator", `{{ fake "Nonsense" .id }}`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TransformTemplate(tt.template, row)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TransformTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("TransformTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateFake(t *testing.T) {
+	row := map[string]*proto.ColumnValue{
+		"id": {Value: &proto.ColumnValue_IntValue{IntValue: 42}},
+	}
+
+	first, err := TransformTemplate(`{{ fake "FirstName" .id }} {{ fake "FakeLastName" .id }}`, row)
+	if err != nil {
+		t.Fatalf("TransformTemplate() unexpected error: %v", err)
+	}
+	if want := TransformFakeFirstName("42") + " " + TransformFakeLastName("42"); first != want {
+		t.Errorf("fake = %q, want %q (same as the column transforms for the seed)", first, want)
+	}
+
+	second, err := TransformTemplate(`{{ fake "FirstName" .id }} {{ fake "FakeLastName" .id }}`, row)
+	if err != nil {
+		t.Fatalf("TransformTemplate() unexpected error: %v", err)
+	}
+	if first != second {
+		t.Errorf("fake is not deterministic: %q != %q", first, second)
+	}
+
+	if _, err := TransformTemplate(`{{ fake "Year" .id }}`, row); err != nil {
+		t.Errorf("fake with a numeric generator: unexpected error: %v", err)
+	}
+}
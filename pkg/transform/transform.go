@@ -2,6 +2,7 @@ package transform
 
 import (
 	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"hash/fnv"
 	"regexp"
@@ -230,6 +231,12 @@ func TransformBool(original bool) bool {
 	return seed%2 == 1
 }
 
+// Binary
+func TransformHashBytes(original []byte) []byte {
+	sum := sha256.Sum256(original)
+	return sum[:]
+}
+
 // Regex transform support
 var (
 	regexCache   = make(map[string]*regexp.Regexp)
@@ -292,6 +299,16 @@ var templateFuncMap = template.FuncMap{
 		}
 		return ""
 	},
+	"sha256":     templateSHA256,
+	"md5":        templateMD5,
+	"substr":     templateSubstr,
+	"padLeft":    templatePadLeft,
+	"padRight":   templatePadRight,
+	"replace":    templateReplace,
+	"trim":       templateTrim,
+	"dateFormat": templateDateFormat,
+	"dateAdd":    templateDateAdd,
+	"fake":       templateFake,
 }
 
 // convertRowToTemplateData converts protobuf row data to a map suitable for templates
@@ -314,6 +331,14 @@ func convertRowToTemplateData(row map[string]*proto.ColumnValue) map[string]inte
 			data[key] = v.BoolValue
 		case *proto.ColumnValue_TimestampValue:
 			data[key] = v.TimestampValue
+		case *proto.ColumnValue_BytesValue:
+			data[key] = hex.EncodeToString(v.BytesValue)
+		case *proto.ColumnValue_JsonValue:
+			data[key] = v.JsonValue
+		case *proto.ColumnValue_UuidValue:
+			data[key] = v.UuidValue
+		case *proto.ColumnValue_GeometryValue:
+			data[key] = v.GeometryValue.GetWkt()
 		default:
 			data[key] = nil
 		}
@@ -386,56 +411,48 @@ func TransformPasswordBcrypt(cleartext string, cost int) (string, error) {
 
 // TransformPasswordScrypt applies scrypt hashing to the cleartext
 func TransformPasswordScrypt(cleartext string, useSalt bool, n, r, p int, original string) (string, error) {
-	var salt []byte
-	if useSalt {
-		salt = generateDeterministicSalt(original, 16) // 16 bytes salt
-	} else {
-		salt = make([]byte, 16) // Empty salt
-	}
-	
-	// Generate hash
-	hash, err := scrypt.Key([]byte(cleartext), salt, n, r, p, 32) // 32 bytes output
-	if err != nil {
-		return "", fmt.Errorf("scrypt hash failed: %w", err)
-	}
-	
-	// Format: salt$hash (both hex encoded)
-	return fmt.Sprintf("%x$%x", salt, hash), nil
+	return hashScrypt(cleartext, legacyPasswordSalt(useSalt, original), n, r, p)
 }
 
 // TransformPasswordPBKDF2 applies PBKDF2 hashing to the cleartext
 func TransformPasswordPBKDF2(cleartext string, useSalt bool, iterations int, hashFunc string, original string) (string, error) {
-	var salt []byte
-	if useSalt {
-		salt = generateDeterministicSalt(original, 16) // 16 bytes salt
-	} else {
-		salt = make([]byte, 16) // Empty salt
-	}
-	
 	// Only SHA256 supported for now (can extend later)
 	if hashFunc != "SHA256" && hashFunc != "" {
 		return "", fmt.Errorf("unsupported hash function: %s (only SHA256 supported)", hashFunc)
 	}
-	
-	// Generate hash
-	hash := pbkdf2.Key([]byte(cleartext), salt, iterations, 32, sha256.New)
-	
-	// Format: salt$hash (both hex encoded)
-	return fmt.Sprintf("%x$%x", salt, hash), nil
+	return hashPBKDF2(cleartext, legacyPasswordSalt(useSalt, original), iterations), nil
 }
 
 // TransformPasswordArgon2id applies Argon2id hashing to the cleartext
 func TransformPasswordArgon2id(cleartext string, useSalt bool, time, memory uint32, threads uint8, original string) (string, error) {
-	var salt []byte
+	return hashArgon2id(cleartext, legacyPasswordSalt(useSalt, original), time, memory, threads), nil
+}
+
+// legacyPasswordSalt returns a salt derived from the original value, or an empty salt
+func legacyPasswordSalt(useSalt bool, original string) []byte {
 	if useSalt {
-		salt = generateDeterministicSalt(original, 16) // 16 bytes salt
-	} else {
-		salt = make([]byte, 16) // Empty salt
+		return generateDeterministicSalt(original, passwordSaltLength)
+	}
+	return make([]byte, passwordSaltLength)
+}
+
+// hashScrypt hashes the cleartext with scrypt, formatted as salt$hash (both hex encoded)
+func hashScrypt(cleartext string, salt []byte, n, r, p int) (string, error) {
+	hash, err := scrypt.Key([]byte(cleartext), salt, n, r, p, passwordKeyLength)
+	if err != nil {
+		return "", fmt.Errorf("scrypt hash failed: %w", err)
 	}
-	
-	// Generate hash
-	hash := argon2.IDKey([]byte(cleartext), salt, time, memory, threads, 32) // 32 bytes output
-	
-	// Format: salt$hash (both hex encoded)
 	return fmt.Sprintf("%x$%x", salt, hash), nil
 }
+
+// hashPBKDF2 hashes the cleartext with PBKDF2-HMAC-SHA256, formatted as salt$hash
+func hashPBKDF2(cleartext string, salt []byte, iterations int) string {
+	hash := pbkdf2.Key([]byte(cleartext), salt, iterations, passwordKeyLength, sha256.New)
+	return fmt.Sprintf("%x$%x", salt, hash)
+}
+
+// hashArgon2id hashes the cleartext with Argon2id, formatted as salt$hash
+func hashArgon2id(cleartext string, salt []byte, time, memory uint32, threads uint8) string {
+	hash := argon2.IDKey([]byte(cleartext), salt, time, memory, threads, passwordKeyLength)
+	return fmt.Sprintf("%x$%x", salt, hash)
+}
@@ -1,6 +1,7 @@
 package transform
 
 import (
+	"bytes"
 	"fmt"
 	"regexp"
 	"strings"
@@ -351,6 +352,20 @@ func TestTransformBool(t *testing.T) {
 	testLimitedTransform(t, "Bool", TransformBool, true, []bool{true, false})
 }
 
+func TestTransformHashBytes(t *testing.T) {
+	original := []byte{0x00, 0xff, 0x10}
+	result := TransformHashBytes(original)
+	if len(result) != 32 {
+		t.Errorf("TransformHashBytes() returned %d bytes, want 32", len(result))
+	}
+	if !bytes.Equal(result, TransformHashBytes(original)) {
+		t.Error("TransformHashBytes() should be deterministic")
+	}
+	if bytes.Equal(result, TransformHashBytes([]byte{0x00})) {
+		t.Error("TransformHashBytes() should differ for different input")
+	}
+}
+
 // TestTransformOutputFormats verifies that transformed values have the expected format
 func TestTransformOutputFormats(t *testing.T) {
 	tests := []struct {
@@ -0,0 +1,101 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"kasho/proto"
+)
+
+// Columns with `unique: true` keep their transformed values unique. Values are tracked
+// per column as changes are transformed; when a value was already produced from a
+// different source value, a deterministic suffix derived from the source value is added.
+// A source value keeps the value it was first given, so updates and deletes still match.
+
+// maxUniqueAttempts bounds the suffixes tried for a colliding value
+const maxUniqueAttempts = 100
+
+type uniqueColumn struct {
+	claims   map[string]uint64           // transformed value -> hash of the source value it belongs to
+	assigned map[uint64]uniqueAssignment // hash of a source value -> the value it was given
+}
+
+type uniqueAssignment struct {
+	base  string // the transformed value before any suffix
+	value string
+}
+
+var (
+	uniqueColumns   = make(map[string]*uniqueColumn) // keyed by table.column
+	uniqueColumnsMu sync.Mutex
+)
+
+// isUnique reports whether a column transform has the unique option
+func isUnique(ct ColumnTransform) bool {
+	unique, _ := ct.Config["unique"].(bool)
+	return unique
+}
+
+// uniqueValue returns a value for source that no other source value of the column has
+// been given, based on value
+func uniqueValue(table, column, source, value string) (string, error) {
+	uniqueColumnsMu.Lock()
+	defer uniqueColumnsMu.Unlock()
+
+	key := table + "." + column
+	col, ok := uniqueColumns[key]
+	if !ok {
+		col = &uniqueColumn{claims: make(map[string]uint64), assigned: make(map[uint64]uniqueAssignment)}
+		uniqueColumns[key] = col
+	}
+
+	sourceHash := hash(source)
+	if assigned, ok := col.assigned[sourceHash]; ok && assigned.base == value {
+		return assigned.value, nil
+	}
+
+	candidate := value
+	for attempt := 1; ; attempt++ {
+		owner, claimed := col.claims[candidate]
+		if !claimed || owner == sourceHash {
+			col.claims[candidate] = sourceHash
+			col.assigned[sourceHash] = uniqueAssignment{base: value, value: candidate}
+			return candidate, nil
+		}
+		if attempt > maxUniqueAttempts {
+			return "", fmt.Errorf("no unique value for %s.%s after %d attempts", table, column, maxUniqueAttempts)
+		}
+		candidate = withUniqueSuffix(value, fmt.Sprintf("%04x", hash(fmt.Sprintf("%s#%d", source, attempt))&0xffff))
+	}
+}
+
+// withUniqueSuffix appends a suffix to a value, before the domain of email addresses
+func withUniqueSuffix(value, suffix string) string {
+	if local, domain, ok := strings.Cut(value, "@"); ok {
+		return local + "+" + suffix + "@" + domain
+	}
+	return value + "-" + suffix
+}
+
+// applyUnique makes the transformed values of unique columns unique
+func applyUnique(c *Config, original, transformed *proto.DMLData) error {
+	for i, col := range transformed.ColumnNames {
+		ct, ok := c.ColumnTransform(transformed.Table, col)
+		if !ok || !isUnique(ct) || i >= len(transformed.ColumnValues) || i >= len(original.ColumnValues) {
+			continue
+		}
+		v, ok := transformed.ColumnValues[i].GetValue().(*proto.ColumnValue_StringValue)
+		if !ok {
+			continue
+		}
+		value, err := uniqueValue(transformed.Table, col, valueText(original.ColumnValues[i]), v.StringValue)
+		if err != nil {
+			return err
+		}
+		if value != v.StringValue {
+			transformed.ColumnValues[i] = &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: value}}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package transform
+
+import (
+	"testing"
+
+	"kasho/proto"
+)
+
+func TestUniqueValue(t *testing.T) {
+	table := "unique_value_test"
+
+	first, err := uniqueValue(table, "email", "a@example.com", "jane@fake.test")
+	if err != nil || first != "jane@fake.test" {
+		t.Fatalf("uniqueValue() = %q, %v; want the value unchanged", first, err)
+	}
+
+	second, err := uniqueValue(table, "email", "b@example.com", "jane@fake.test")
+	if err != nil {
+		t.Fatalf("uniqueValue() unexpected error: %v", err)
+	}
+	if second == first {
+		t.Fatalf("colliding value was not disambiguated: %q", second)
+	}
+	if want := withUniqueSuffix("jane@fake.test", second[5:9]); second != want {
+		t.Errorf("uniqueValue() = %q, want a suffix before the domain", second)
+	}
+
+	// Source values keep the value they were given
+	for source, want := range map[string]string{"a@example.com": first, "b@example.com": second} {
+		if got, _ := uniqueValue(table, "email", source, "jane@fake.test"); got != want {
+			t.Errorf("uniqueValue(%q) again = %q, want %q", source, got, want)
+		}
+	}
+
+	// Other columns are tracked separately
+	if got, _ := uniqueValue(table, "login", "b@example.com", "jane@fake.test"); got != "jane@fake.test" {
+		t.Errorf("uniqueValue() in another column = %q, want the value unchanged", got)
+	}
+}
+
+func TestWithUniqueSuffix(t *testing.T) {
+	tests := map[string]string{
+		"jane@fake.test": "jane+1a2b@fake.test",
+		"jdoe":           "jdoe-1a2b",
+	}
+	for value, want := range tests {
+		if got := withUniqueSuffix(value, "1a2b"); got != want {
+			t.Errorf("withUniqueSuffix(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestTransformChangeUnique(t *testing.T) {
+	config := &Config{
+		Tables: map[string]TableConfig{
+			"unique_change_test": {
+				// Every row gets the same value, so all but the first collide
+				"email": {Type: Template, Config: map[string]any{"template": "same@fake.test", "unique": true}},
+				"name":  {Type: Template, Config: map[string]any{"template": "same"}},
+			},
+		},
+	}
+	change := func(email string) *proto.Change {
+		return &proto.Change{
+			Type: "dml",
+			Data: &proto.Change_Dml{Dml: &proto.DMLData{
+				Table:       "unique_change_test",
+				ColumnNames: []string{"email", "name"},
+				ColumnValues: []*proto.ColumnValue{
+					{Value: &proto.ColumnValue_StringValue{StringValue: email}},
+					{Value: &proto.ColumnValue_StringValue{StringValue: "x"}},
+				},
+				Kind: "insert",
+			}},
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		result, err := TransformChange(config, change(email))
+		if err != nil {
+			t.Fatalf("TransformChange() unexpected error: %v", err)
+		}
+		values := result.GetDml().ColumnValues
+		if seen[values[0].GetStringValue()] {
+			t.Errorf("duplicate email %q", values[0].GetStringValue())
+		}
+		seen[values[0].GetStringValue()] = true
+		if values[1].GetStringValue() != "same" {
+			t.Errorf("name without unique = %q, want it unchanged", values[1].GetStringValue())
+		}
+	}
+}
@@ -12,6 +12,21 @@ type ColumnValueWrapper struct {
 	*proto.ColumnValue
 }
 
+// taggedValueJSON encodes values that would be ambiguous as plain JSON, such as
+// binary data, native JSON/UUID/geometry values or the unchanged TOAST marker, as an object
+type taggedValueJSON struct {
+	UnchangedToast bool          `json:"unchanged_toast,omitempty"`
+	Bytes          *[]byte       `json:"bytes,omitempty"`
+	JSON           *string       `json:"json,omitempty"`
+	UUID           *string       `json:"uuid,omitempty"`
+	Geometry       *geometryJSON `json:"geometry,omitempty"`
+}
+
+type geometryJSON struct {
+	WKT  string `json:"wkt"`
+	SRID uint32 `json:"srid,omitempty"`
+}
+
 func (cv ColumnValueWrapper) MarshalJSON() ([]byte, error) {
 	if cv.ColumnValue == nil {
 		return json.Marshal(nil)
@@ -27,6 +42,23 @@ func (cv ColumnValueWrapper) MarshalJSON() ([]byte, error) {
 		return json.Marshal(v.BoolValue)
 	case *proto.ColumnValue_TimestampValue:
 		return json.Marshal(v.TimestampValue)
+	case *proto.ColumnValue_UnchangedToast:
+		return json.Marshal(taggedValueJSON{UnchangedToast: v.UnchangedToast})
+	case *proto.ColumnValue_BytesValue:
+		b := v.BytesValue
+		if b == nil {
+			b = []byte{}
+		}
+		return json.Marshal(taggedValueJSON{Bytes: &b})
+	case *proto.ColumnValue_JsonValue:
+		return json.Marshal(taggedValueJSON{JSON: &v.JsonValue})
+	case *proto.ColumnValue_UuidValue:
+		return json.Marshal(taggedValueJSON{UUID: &v.UuidValue})
+	case *proto.ColumnValue_GeometryValue:
+		return json.Marshal(taggedValueJSON{Geometry: &geometryJSON{
+			WKT:  v.GeometryValue.GetWkt(),
+			SRID: v.GeometryValue.GetSrid(),
+		}})
 	case nil:
 		return json.Marshal(nil)
 	default:
@@ -45,6 +77,33 @@ func (cv *ColumnValueWrapper) UnmarshalJSON(data []byte) error {
 		return nil
 	}
 
+	// Tagged values: binary data, native types and the unchanged TOAST marker
+	if len(data) > 0 && data[0] == '{' {
+		var tagged taggedValueJSON
+		if err := json.Unmarshal(data, &tagged); err == nil {
+			switch {
+			case tagged.Bytes != nil:
+				cv.Value = &proto.ColumnValue_BytesValue{BytesValue: *tagged.Bytes}
+				return nil
+			case tagged.JSON != nil:
+				cv.Value = &proto.ColumnValue_JsonValue{JsonValue: *tagged.JSON}
+				return nil
+			case tagged.UUID != nil:
+				cv.Value = &proto.ColumnValue_UuidValue{UuidValue: *tagged.UUID}
+				return nil
+			case tagged.Geometry != nil:
+				cv.Value = &proto.ColumnValue_GeometryValue{GeometryValue: &proto.Geometry{
+					Wkt:  tagged.Geometry.WKT,
+					Srid: tagged.Geometry.SRID,
+				}}
+				return nil
+			case tagged.UnchangedToast:
+				cv.Value = &proto.ColumnValue_UnchangedToast{UnchangedToast: true}
+				return nil
+			}
+		}
+	}
+
 	// Try int (before string to avoid treating "123" as string)
 	var intVal int64
 	if err := json.Unmarshal(data, &intVal); err == nil {
@@ -69,7 +128,7 @@ func (cv *ColumnValueWrapper) UnmarshalJSON(data []byte) error {
 	// Try timestamp (Go's time.Time can parse RFC3339 strings)
 	var timeVal time.Time
 	if err := json.Unmarshal(data, &timeVal); err == nil {
-		cv.Value = &proto.ColumnValue_TimestampValue{TimestampValue: timeVal.Format(time.RFC3339)}
+		cv.Value = &proto.ColumnValue_TimestampValue{TimestampValue: timeVal.UTC().Format(time.RFC3339Nano)}
 		return nil
 	}
 
@@ -88,6 +147,19 @@ type Change struct {
 	Data     interface {
 		Type() string
 	}
+
+	// Source metadata, empty when unknown
+	Dialect       string // "postgresql", "mysql", "mongodb", "sqlserver" or "oracle"
+	Database      string
+	Schema        string
+	TransactionID string
+	CommitTime    time.Time
+	Metadata      map[string]string
+
+	// Streamed transactions (PostgreSQL): "in_progress" for changes sent before their
+	// transaction ended, "committed" or "aborted" for the TransactionData change ending it
+	TransactionStatus string
+	SubtransactionID  string
 }
 
 func (c Change) Type() string {
@@ -104,34 +176,68 @@ func (c Change) MarshalJSON() ([]byte, error) {
 		return nil, err
 	}
 
-	return json.Marshal(struct {
-		Type     string          `json:"type"`
-		Position string          `json:"position"`
-		Data     json.RawMessage `json:"data"`
-	}{
-		Type:     c.Type(),
-		Position: c.Position,
-		Data:     data,
-	})
+	aux := changeJSON{
+		Type:          c.Type(),
+		Position:      c.Position,
+		Data:          data,
+		Dialect:       c.Dialect,
+		Database:      c.Database,
+		Schema:        c.Schema,
+		TransactionID: c.TransactionID,
+		Metadata:      c.Metadata,
+
+		TransactionStatus: c.TransactionStatus,
+		SubtransactionID:  c.SubtransactionID,
+	}
+	if !c.CommitTime.IsZero() {
+		aux.CommitTime = &c.CommitTime
+	}
+	return json.Marshal(aux)
+}
+
+type changeJSON struct {
+	Type          string            `json:"type"`
+	Position      string            `json:"position"`
+	Data          json.RawMessage   `json:"data"`
+	Dialect       string            `json:"dialect,omitempty"`
+	Database      string            `json:"database,omitempty"`
+	Schema        string            `json:"schema,omitempty"`
+	TransactionID string            `json:"transactionid,omitempty"`
+	CommitTime    *time.Time        `json:"committime,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+
+	TransactionStatus string `json:"transactionstatus,omitempty"`
+	SubtransactionID  string `json:"subtransactionid,omitempty"`
 }
 
 func (c *Change) UnmarshalJSON(data []byte) error {
-	var aux struct {
-		Type     string          `json:"type"`
-		Position string          `json:"position"`
-		Data     json.RawMessage `json:"data"`
-	}
+	var aux changeJSON
 	if err := json.Unmarshal(data, &aux); err != nil {
 		return err
 	}
 
 	c.Position = aux.Position
+	c.Dialect = aux.Dialect
+	c.Database = aux.Database
+	c.Schema = aux.Schema
+	c.TransactionID = aux.TransactionID
+	c.Metadata = aux.Metadata
+	c.TransactionStatus = aux.TransactionStatus
+	c.SubtransactionID = aux.SubtransactionID
+	c.CommitTime = time.Time{}
+	if aux.CommitTime != nil {
+		c.CommitTime = *aux.CommitTime
+	}
 
 	switch aux.Type {
 	case "dml":
 		c.Data = &DMLData{}
 	case "ddl":
 		c.Data = &DDLData{}
+	case "transaction":
+		c.Data = &TransactionData{}
+	case "message":
+		c.Data = &MessageData{}
 	default:
 		return fmt.Errorf("unknown change type: %s", aux.Type)
 	}
@@ -148,6 +254,8 @@ type DMLData struct {
 		KeyNames  []string             `json:"keynames"`
 		KeyValues []ColumnValueWrapper `json:"keyvalues"`
 	} `json:"oldkeys,omitempty"`
+	PrimaryKey []string `json:"primarykey,omitempty"`
+	Schema     string   `json:"schema,omitempty"`
 }
 
 func (c DMLData) Type() string {
@@ -165,3 +273,23 @@ type DDLData struct {
 func (c DDLData) Type() string {
 	return "ddl"
 }
+
+// TransactionData ends a streamed transaction, which committed or aborted as the
+// change's TransactionStatus says
+type TransactionData struct{}
+
+func (c TransactionData) Type() string {
+	return "transaction"
+}
+
+// MessageData is a message an application wrote to the source's log, e.g. with
+// pg_logical_emit_message, to mark a point in the stream
+type MessageData struct {
+	Prefix        string `json:"prefix"`
+	Content       []byte `json:"content"`
+	Transactional bool   `json:"transactional"`
+}
+
+func (c MessageData) Type() string {
+	return "message"
+}
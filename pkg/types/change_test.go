@@ -93,6 +93,51 @@ func TestColumnValueWrapper_MarshalJSON(t *testing.T) {
 			},
 			wantJSON: `"hello\nworld\t\"quoted\""`,
 		},
+		{
+			name: "unchanged toast value",
+			cv: ColumnValueWrapper{
+				ColumnValue: &proto.ColumnValue{
+					Value: &proto.ColumnValue_UnchangedToast{UnchangedToast: true},
+				},
+			},
+			wantJSON: `{"unchanged_toast":true}`,
+		},
+		{
+			name: "bytes value",
+			cv: ColumnValueWrapper{
+				ColumnValue: &proto.ColumnValue{
+					Value: &proto.ColumnValue_BytesValue{BytesValue: []byte{0x00, 0xff, 0x10}},
+				},
+			},
+			wantJSON: `{"bytes":"AP8Q"}`,
+		},
+		{
+			name: "json value",
+			cv: ColumnValueWrapper{
+				ColumnValue: &proto.ColumnValue{
+					Value: &proto.ColumnValue_JsonValue{JsonValue: `{"a":1}`},
+				},
+			},
+			wantJSON: `{"json":"{\"a\":1}"}`,
+		},
+		{
+			name: "uuid value",
+			cv: ColumnValueWrapper{
+				ColumnValue: &proto.ColumnValue{
+					Value: &proto.ColumnValue_UuidValue{UuidValue: "123e4567-e89b-12d3-a456-426614174000"},
+				},
+			},
+			wantJSON: `{"uuid":"123e4567-e89b-12d3-a456-426614174000"}`,
+		},
+		{
+			name: "geometry value",
+			cv: ColumnValueWrapper{
+				ColumnValue: &proto.ColumnValue{
+					Value: &proto.ColumnValue_GeometryValue{GeometryValue: &proto.Geometry{Wkt: "POINT(1 2)", Srid: 4326}},
+				},
+			},
+			wantJSON: `{"geometry":{"wkt":"POINT(1 2)","srid":4326}}`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -179,6 +224,60 @@ func TestColumnValueWrapper_UnmarshalJSON(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:     "unchanged toast value",
+			jsonData: `{"unchanged_toast":true}`,
+			want: ColumnValueWrapper{
+				ColumnValue: &proto.ColumnValue{
+					Value: &proto.ColumnValue_UnchangedToast{UnchangedToast: true},
+				},
+			},
+		},
+		{
+			name:     "timestamp with offset is normalized to UTC",
+			jsonData: `"2024-03-20T15:04:05.5+02:00"`,
+			want: ColumnValueWrapper{
+				ColumnValue: &proto.ColumnValue{
+					Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2024-03-20T13:04:05.5Z"},
+				},
+			},
+		},
+		{
+			name:     "bytes value",
+			jsonData: `{"bytes":"AP8Q"}`,
+			want: ColumnValueWrapper{
+				ColumnValue: &proto.ColumnValue{
+					Value: &proto.ColumnValue_BytesValue{BytesValue: []byte{0x00, 0xff, 0x10}},
+				},
+			},
+		},
+		{
+			name:     "json value",
+			jsonData: `{"json":"{\"a\":1}"}`,
+			want: ColumnValueWrapper{
+				ColumnValue: &proto.ColumnValue{
+					Value: &proto.ColumnValue_JsonValue{JsonValue: `{"a":1}`},
+				},
+			},
+		},
+		{
+			name:     "uuid value",
+			jsonData: `{"uuid":"123e4567-e89b-12d3-a456-426614174000"}`,
+			want: ColumnValueWrapper{
+				ColumnValue: &proto.ColumnValue{
+					Value: &proto.ColumnValue_UuidValue{UuidValue: "123e4567-e89b-12d3-a456-426614174000"},
+				},
+			},
+		},
+		{
+			name:     "geometry value",
+			jsonData: `{"geometry":{"wkt":"POINT(1 2)","srid":4326}}`,
+			want: ColumnValueWrapper{
+				ColumnValue: &proto.ColumnValue{
+					Value: &proto.ColumnValue_GeometryValue{GeometryValue: &proto.Geometry{Wkt: "POINT(1 2)", Srid: 4326}},
+				},
+			},
+		},
 		{
 			name:     "invalid json",
 			jsonData: `{"invalid": json}`,
@@ -367,6 +466,99 @@ func TestRoundTripSerialization(t *testing.T) {
 	}
 }
 
+func TestRoundTripSourceMetadata(t *testing.T) {
+	original := Change{
+		Position: "0/12345",
+		Data: &DMLData{
+			Table:       "products",
+			Kind:        "insert",
+			ColumnNames: []string{"id"},
+			ColumnValues: []ColumnValueWrapper{
+				{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: 1}}},
+			},
+		},
+		Dialect:       "postgresql",
+		Database:      "shop",
+		Schema:        "public",
+		TransactionID: "7421",
+		CommitTime:    time.Date(2024, 3, 20, 15, 0, 0, 0, time.UTC),
+		Metadata:      map[string]string{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+	}
+
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+
+	var decoded Change
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+
+	if !reflect.DeepEqual(original, decoded) {
+		t.Errorf("Round trip failed: original = %+v, decoded = %+v", original, decoded)
+	}
+}
+
+func TestRoundTripStreamedTransaction(t *testing.T) {
+	for _, original := range []Change{
+		{
+			Position:          "0/12345",
+			Data:              &DDLData{DDL: "CREATE TABLE t (id int)"},
+			TransactionID:     "7421",
+			TransactionStatus: "in_progress",
+			SubtransactionID:  "7422",
+		},
+		{
+			Position:          "0/12400",
+			Data:              &TransactionData{},
+			TransactionID:     "7421",
+			TransactionStatus: "committed",
+			CommitTime:        time.Date(2024, 3, 20, 15, 0, 0, 0, time.UTC),
+		},
+	} {
+		data, err := json.Marshal(original)
+		if err != nil {
+			t.Fatalf("Failed to marshal: %v", err)
+		}
+
+		var decoded Change
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Failed to unmarshal: %v", err)
+		}
+
+		if !reflect.DeepEqual(original, decoded) {
+			t.Errorf("Round trip failed: original = %+v, decoded = %+v", original, decoded)
+		}
+	}
+}
+
+func TestRoundTripMessage(t *testing.T) {
+	original := Change{
+		Position:      "0/12500",
+		Data:          &MessageData{Prefix: "deploy", Content: []byte("deploy-123 complete"), Transactional: true},
+		Dialect:       "postgresql",
+		TransactionID: "7430",
+	}
+
+	data, err := json.Marshal(original)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+
+	var decoded Change
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+
+	if !reflect.DeepEqual(original, decoded) {
+		t.Errorf("Round trip failed: original = %+v, decoded = %+v", original, decoded)
+	}
+	if decoded.Type() != "message" {
+		t.Errorf("Type() = %q, want message", decoded.Type())
+	}
+}
+
 func TestDMLData_Type(t *testing.T) {
 	dml := &DMLData{}
 	if dml.Type() != "dml" {
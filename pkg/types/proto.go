@@ -0,0 +1,112 @@
+package types
+
+import "kasho/proto"
+
+// The string Type and Kind fields of proto.Change and proto.DMLData predate the
+// ChangeType and DMLKind enums. Producers set both forms; consumers pass received
+// changes through NormalizeChange so code reading either form works with producers
+// that only set one of them.
+
+var changeTypeNames = map[proto.ChangeType]string{
+	proto.ChangeType_CHANGE_TYPE_DML:         "dml",
+	proto.ChangeType_CHANGE_TYPE_DDL:         "ddl",
+	proto.ChangeType_CHANGE_TYPE_HEARTBEAT:   "heartbeat",
+	proto.ChangeType_CHANGE_TYPE_TRANSACTION: "transaction",
+	proto.ChangeType_CHANGE_TYPE_MESSAGE:     "message",
+}
+
+var dmlKindNames = map[proto.DMLKind]string{
+	proto.DMLKind_DML_KIND_INSERT: "insert",
+	proto.DMLKind_DML_KIND_UPDATE: "update",
+	proto.DMLKind_DML_KIND_DELETE: "delete",
+}
+
+var transactionStatusNames = map[proto.TransactionStatus]string{
+	proto.TransactionStatus_TRANSACTION_STATUS_IN_PROGRESS: "in_progress",
+	proto.TransactionStatus_TRANSACTION_STATUS_COMMITTED:   "committed",
+	proto.TransactionStatus_TRANSACTION_STATUS_ABORTED:     "aborted",
+}
+
+var sourceDialectNames = map[proto.SourceDialect]string{
+	proto.SourceDialect_SOURCE_DIALECT_POSTGRESQL: "postgresql",
+	proto.SourceDialect_SOURCE_DIALECT_MYSQL:      "mysql",
+	proto.SourceDialect_SOURCE_DIALECT_MONGODB:    "mongodb",
+	proto.SourceDialect_SOURCE_DIALECT_SQLSERVER:  "sqlserver",
+	proto.SourceDialect_SOURCE_DIALECT_ORACLE:     "oracle",
+}
+
+// ChangeTypeFromString returns the enum for a legacy change type string such as "dml",
+// or CHANGE_TYPE_UNSPECIFIED for an unknown string
+func ChangeTypeFromString(s string) proto.ChangeType {
+	return fromString(changeTypeNames, s)
+}
+
+// ChangeTypeString returns the legacy string for a change type, or "" if it's unspecified
+func ChangeTypeString(t proto.ChangeType) string {
+	return changeTypeNames[t]
+}
+
+// DMLKindFromString returns the enum for a legacy DML kind string such as "insert",
+// or DML_KIND_UNSPECIFIED for an unknown string
+func DMLKindFromString(s string) proto.DMLKind {
+	return fromString(dmlKindNames, s)
+}
+
+// DMLKindString returns the legacy string for a DML kind, or "" if it's unspecified
+func DMLKindString(k proto.DMLKind) string {
+	return dmlKindNames[k]
+}
+
+// SourceDialectFromString returns the enum for a dialect name, "postgresql" or "mysql",
+// or SOURCE_DIALECT_UNSPECIFIED for an unknown name
+func SourceDialectFromString(s string) proto.SourceDialect {
+	return fromString(sourceDialectNames, s)
+}
+
+// SourceDialectString returns the name of a source dialect, or "" if it's unspecified
+func SourceDialectString(d proto.SourceDialect) string {
+	return sourceDialectNames[d]
+}
+
+// TransactionStatusFromString returns the enum for a transaction status such as
+// "in_progress", or TRANSACTION_STATUS_UNSPECIFIED for an unknown or empty status
+func TransactionStatusFromString(s string) proto.TransactionStatus {
+	return fromString(transactionStatusNames, s)
+}
+
+// TransactionStatusString returns the name of a transaction status, or "" if it's unspecified
+func TransactionStatusString(s proto.TransactionStatus) string {
+	return transactionStatusNames[s]
+}
+
+func fromString[T comparable](names map[T]string, s string) T {
+	for value, name := range names {
+		if name == s {
+			return value
+		}
+	}
+	var unspecified T
+	return unspecified
+}
+
+// NormalizeChange sets whichever of the legacy string and enum forms of a change's
+// type and DML kind is missing from the other
+func NormalizeChange(change *proto.Change) {
+	if change == nil {
+		return
+	}
+
+	if change.ChangeType == proto.ChangeType_CHANGE_TYPE_UNSPECIFIED {
+		change.ChangeType = ChangeTypeFromString(change.Type)
+	} else if change.Type == "" {
+		change.Type = ChangeTypeString(change.ChangeType)
+	}
+
+	if dml := change.GetDml(); dml != nil {
+		if dml.DmlKind == proto.DMLKind_DML_KIND_UNSPECIFIED {
+			dml.DmlKind = DMLKindFromString(dml.Kind)
+		} else if dml.Kind == "" {
+			dml.Kind = DMLKindString(dml.DmlKind)
+		}
+	}
+}
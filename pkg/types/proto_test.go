@@ -0,0 +1,79 @@
+package types
+
+import (
+	"testing"
+
+	"kasho/proto"
+)
+
+func TestEnumStringConversions(t *testing.T) {
+	for _, s := range []string{"dml", "ddl", "heartbeat", "transaction", "message"} {
+		if got := ChangeTypeString(ChangeTypeFromString(s)); got != s {
+			t.Errorf("change type %q round trip = %q", s, got)
+		}
+	}
+	for _, s := range []string{"insert", "update", "delete"} {
+		if got := DMLKindString(DMLKindFromString(s)); got != s {
+			t.Errorf("DML kind %q round trip = %q", s, got)
+		}
+	}
+	for _, s := range []string{"postgresql", "mysql", "mongodb", "sqlserver", "oracle"} {
+		if got := SourceDialectString(SourceDialectFromString(s)); got != s {
+			t.Errorf("dialect %q round trip = %q", s, got)
+		}
+	}
+	for _, s := range []string{"in_progress", "committed", "aborted"} {
+		if got := TransactionStatusString(TransactionStatusFromString(s)); got != s {
+			t.Errorf("transaction status %q round trip = %q", s, got)
+		}
+	}
+
+	if got := ChangeTypeFromString("bogus"); got != proto.ChangeType_CHANGE_TYPE_UNSPECIFIED {
+		t.Errorf("ChangeTypeFromString(bogus) = %v", got)
+	}
+	if got := DMLKindString(proto.DMLKind_DML_KIND_UNSPECIFIED); got != "" {
+		t.Errorf("DMLKindString(unspecified) = %q", got)
+	}
+}
+
+func TestNormalizeChange(t *testing.T) {
+	t.Run("legacy strings only", func(t *testing.T) {
+		change := &proto.Change{
+			Type: "dml",
+			Data: &proto.Change_Dml{Dml: &proto.DMLData{Table: "users", Kind: "update"}},
+		}
+		NormalizeChange(change)
+		if change.ChangeType != proto.ChangeType_CHANGE_TYPE_DML {
+			t.Errorf("ChangeType = %v, want CHANGE_TYPE_DML", change.ChangeType)
+		}
+		if change.GetDml().DmlKind != proto.DMLKind_DML_KIND_UPDATE {
+			t.Errorf("DmlKind = %v, want DML_KIND_UPDATE", change.GetDml().DmlKind)
+		}
+	})
+
+	t.Run("enums only", func(t *testing.T) {
+		change := &proto.Change{
+			ChangeType: proto.ChangeType_CHANGE_TYPE_DML,
+			Data:       &proto.Change_Dml{Dml: &proto.DMLData{Table: "users", DmlKind: proto.DMLKind_DML_KIND_DELETE}},
+		}
+		NormalizeChange(change)
+		if change.Type != "dml" {
+			t.Errorf("Type = %q, want dml", change.Type)
+		}
+		if change.GetDml().Kind != "delete" {
+			t.Errorf("Kind = %q, want delete", change.GetDml().Kind)
+		}
+	})
+
+	t.Run("heartbeat", func(t *testing.T) {
+		change := &proto.Change{ChangeType: proto.ChangeType_CHANGE_TYPE_HEARTBEAT}
+		NormalizeChange(change)
+		if change.Type != "heartbeat" {
+			t.Errorf("Type = %q, want heartbeat", change.Type)
+		}
+	})
+
+	t.Run("nil change", func(t *testing.T) {
+		NormalizeChange(nil)
+	})
+}
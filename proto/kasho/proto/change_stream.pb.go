@@ -21,11 +21,250 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// ChangeType is what a Change carries
+type ChangeType int32
+
+const (
+	ChangeType_CHANGE_TYPE_UNSPECIFIED ChangeType = 0
+	ChangeType_CHANGE_TYPE_DML         ChangeType = 1
+	ChangeType_CHANGE_TYPE_DDL         ChangeType = 2
+	ChangeType_CHANGE_TYPE_HEARTBEAT   ChangeType = 3 // No data, position is the source's current position
+	ChangeType_CHANGE_TYPE_TRANSACTION ChangeType = 4 // No data, ends a streamed transaction: see transaction_status
+	ChangeType_CHANGE_TYPE_MESSAGE     ChangeType = 5 // Application marker, e.g. from pg_logical_emit_message
+)
+
+// Enum value maps for ChangeType.
+var (
+	ChangeType_name = map[int32]string{
+		0: "CHANGE_TYPE_UNSPECIFIED",
+		1: "CHANGE_TYPE_DML",
+		2: "CHANGE_TYPE_DDL",
+		3: "CHANGE_TYPE_HEARTBEAT",
+		4: "CHANGE_TYPE_TRANSACTION",
+		5: "CHANGE_TYPE_MESSAGE",
+	}
+	ChangeType_value = map[string]int32{
+		"CHANGE_TYPE_UNSPECIFIED": 0,
+		"CHANGE_TYPE_DML":         1,
+		"CHANGE_TYPE_DDL":         2,
+		"CHANGE_TYPE_HEARTBEAT":   3,
+		"CHANGE_TYPE_TRANSACTION": 4,
+		"CHANGE_TYPE_MESSAGE":     5,
+	}
+)
+
+func (x ChangeType) Enum() *ChangeType {
+	p := new(ChangeType)
+	*p = x
+	return p
+}
+
+func (x ChangeType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ChangeType) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_change_stream_proto_enumTypes[0].Descriptor()
+}
+
+func (ChangeType) Type() protoreflect.EnumType {
+	return &file_proto_change_stream_proto_enumTypes[0]
+}
+
+func (x ChangeType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ChangeType.Descriptor instead.
+func (ChangeType) EnumDescriptor() ([]byte, []int) {
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{0}
+}
+
+// TransactionStatus tells changes of large transactions that are streamed before
+// they commit (PostgreSQL) from committed ones
+type TransactionStatus int32
+
+const (
+	TransactionStatus_TRANSACTION_STATUS_UNSPECIFIED TransactionStatus = 0 // The change was captured after its transaction committed
+	TransactionStatus_TRANSACTION_STATUS_IN_PROGRESS TransactionStatus = 1 // The transaction may still commit or abort; hold the change until it ends
+	TransactionStatus_TRANSACTION_STATUS_COMMITTED   TransactionStatus = 2 // Transaction change: the held changes of transaction_id committed
+	TransactionStatus_TRANSACTION_STATUS_ABORTED     TransactionStatus = 3 // Transaction change: the held changes of transaction_id, or only of subtransaction_id if set, are discarded
+)
+
+// Enum value maps for TransactionStatus.
+var (
+	TransactionStatus_name = map[int32]string{
+		0: "TRANSACTION_STATUS_UNSPECIFIED",
+		1: "TRANSACTION_STATUS_IN_PROGRESS",
+		2: "TRANSACTION_STATUS_COMMITTED",
+		3: "TRANSACTION_STATUS_ABORTED",
+	}
+	TransactionStatus_value = map[string]int32{
+		"TRANSACTION_STATUS_UNSPECIFIED": 0,
+		"TRANSACTION_STATUS_IN_PROGRESS": 1,
+		"TRANSACTION_STATUS_COMMITTED":   2,
+		"TRANSACTION_STATUS_ABORTED":     3,
+	}
+)
+
+func (x TransactionStatus) Enum() *TransactionStatus {
+	p := new(TransactionStatus)
+	*p = x
+	return p
+}
+
+func (x TransactionStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TransactionStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_change_stream_proto_enumTypes[1].Descriptor()
+}
+
+func (TransactionStatus) Type() protoreflect.EnumType {
+	return &file_proto_change_stream_proto_enumTypes[1]
+}
+
+func (x TransactionStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TransactionStatus.Descriptor instead.
+func (TransactionStatus) EnumDescriptor() ([]byte, []int) {
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{1}
+}
+
+// DMLKind is the row operation of a DMLData
+type DMLKind int32
+
+const (
+	DMLKind_DML_KIND_UNSPECIFIED DMLKind = 0
+	DMLKind_DML_KIND_INSERT      DMLKind = 1
+	DMLKind_DML_KIND_UPDATE      DMLKind = 2
+	DMLKind_DML_KIND_DELETE      DMLKind = 3
+)
+
+// Enum value maps for DMLKind.
+var (
+	DMLKind_name = map[int32]string{
+		0: "DML_KIND_UNSPECIFIED",
+		1: "DML_KIND_INSERT",
+		2: "DML_KIND_UPDATE",
+		3: "DML_KIND_DELETE",
+	}
+	DMLKind_value = map[string]int32{
+		"DML_KIND_UNSPECIFIED": 0,
+		"DML_KIND_INSERT":      1,
+		"DML_KIND_UPDATE":      2,
+		"DML_KIND_DELETE":      3,
+	}
+)
+
+func (x DMLKind) Enum() *DMLKind {
+	p := new(DMLKind)
+	*p = x
+	return p
+}
+
+func (x DMLKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DMLKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_change_stream_proto_enumTypes[2].Descriptor()
+}
+
+func (DMLKind) Type() protoreflect.EnumType {
+	return &file_proto_change_stream_proto_enumTypes[2]
+}
+
+func (x DMLKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DMLKind.Descriptor instead.
+func (DMLKind) EnumDescriptor() ([]byte, []int) {
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{2}
+}
+
+// SourceDialect is the kind of database a change was captured from
+type SourceDialect int32
+
+const (
+	SourceDialect_SOURCE_DIALECT_UNSPECIFIED SourceDialect = 0
+	SourceDialect_SOURCE_DIALECT_POSTGRESQL  SourceDialect = 1
+	SourceDialect_SOURCE_DIALECT_MYSQL       SourceDialect = 2
+	SourceDialect_SOURCE_DIALECT_MONGODB     SourceDialect = 3
+	SourceDialect_SOURCE_DIALECT_SQLSERVER   SourceDialect = 4
+	SourceDialect_SOURCE_DIALECT_ORACLE      SourceDialect = 5
+)
+
+// Enum value maps for SourceDialect.
+var (
+	SourceDialect_name = map[int32]string{
+		0: "SOURCE_DIALECT_UNSPECIFIED",
+		1: "SOURCE_DIALECT_POSTGRESQL",
+		2: "SOURCE_DIALECT_MYSQL",
+		3: "SOURCE_DIALECT_MONGODB",
+		4: "SOURCE_DIALECT_SQLSERVER",
+		5: "SOURCE_DIALECT_ORACLE",
+	}
+	SourceDialect_value = map[string]int32{
+		"SOURCE_DIALECT_UNSPECIFIED": 0,
+		"SOURCE_DIALECT_POSTGRESQL":  1,
+		"SOURCE_DIALECT_MYSQL":       2,
+		"SOURCE_DIALECT_MONGODB":     3,
+		"SOURCE_DIALECT_SQLSERVER":   4,
+		"SOURCE_DIALECT_ORACLE":      5,
+	}
+)
+
+func (x SourceDialect) Enum() *SourceDialect {
+	p := new(SourceDialect)
+	*p = x
+	return p
+}
+
+func (x SourceDialect) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SourceDialect) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_change_stream_proto_enumTypes[3].Descriptor()
+}
+
+func (SourceDialect) Type() protoreflect.EnumType {
+	return &file_proto_change_stream_proto_enumTypes[3]
+}
+
+func (x SourceDialect) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SourceDialect.Descriptor instead.
+func (SourceDialect) EnumDescriptor() ([]byte, []int) {
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{3}
+}
+
 type StreamRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	LastPosition  string                 `protobuf:"bytes,1,opt,name=last_position,json=lastPosition,proto3" json:"last_position,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	LastPosition string                 `protobuf:"bytes,1,opt,name=last_position,json=lastPosition,proto3" json:"last_position,omitempty"`
+	// Pacing limits requested by the consumer. Zero uses the server default;
+	// values above the server's configured limit are capped to it.
+	MaxChangesPerSecond uint32 `protobuf:"varint,2,opt,name=max_changes_per_second,json=maxChangesPerSecond,proto3" json:"max_changes_per_second,omitempty"`
+	MaxBytesPerSecond   uint64 `protobuf:"varint,3,opt,name=max_bytes_per_second,json=maxBytesPerSecond,proto3" json:"max_bytes_per_second,omitempty"`
+	// Filters applied by the server before sending. Table patterns use path.Match syntax;
+	// a pattern without a schema also matches schema-qualified tables by their bare name.
+	IncludeTables []string `protobuf:"bytes,4,rep,name=include_tables,json=includeTables,proto3" json:"include_tables,omitempty"` // Only send changes for these tables; empty sends all
+	ExcludeTables []string `protobuf:"bytes,5,rep,name=exclude_tables,json=excludeTables,proto3" json:"exclude_tables,omitempty"` // Never send changes for these tables
+	ExcludeKinds  []string `protobuf:"bytes,6,rep,name=exclude_kinds,json=excludeKinds,proto3" json:"exclude_kinds,omitempty"`    // Change kinds to skip: "insert", "update", "delete", "ddl"
+	// Bytes of changes the server may send ahead of those the consumer has acknowledged.
+	// Zero uses the server default; values above the server's limit are capped to it.
+	// When a limit applies, the response's kasho-stream-id header names the stream for
+	// Acknowledge.
+	MaxInFlightBytes uint64 `protobuf:"varint,7,opt,name=max_in_flight_bytes,json=maxInFlightBytes,proto3" json:"max_in_flight_bytes,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
 func (x *StreamRequest) Reset() {
@@ -65,22 +304,162 @@ func (x *StreamRequest) GetLastPosition() string {
 	return ""
 }
 
+func (x *StreamRequest) GetMaxChangesPerSecond() uint32 {
+	if x != nil {
+		return x.MaxChangesPerSecond
+	}
+	return 0
+}
+
+func (x *StreamRequest) GetMaxBytesPerSecond() uint64 {
+	if x != nil {
+		return x.MaxBytesPerSecond
+	}
+	return 0
+}
+
+func (x *StreamRequest) GetIncludeTables() []string {
+	if x != nil {
+		return x.IncludeTables
+	}
+	return nil
+}
+
+func (x *StreamRequest) GetExcludeTables() []string {
+	if x != nil {
+		return x.ExcludeTables
+	}
+	return nil
+}
+
+func (x *StreamRequest) GetExcludeKinds() []string {
+	if x != nil {
+		return x.ExcludeKinds
+	}
+	return nil
+}
+
+func (x *StreamRequest) GetMaxInFlightBytes() uint64 {
+	if x != nil {
+		return x.MaxInFlightBytes
+	}
+	return 0
+}
+
+type AcknowledgeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StreamId      string                 `protobuf:"bytes,1,opt,name=stream_id,json=streamId,proto3" json:"stream_id,omitempty"` // The kasho-stream-id header of the stream
+	Position      string                 `protobuf:"bytes,2,opt,name=position,proto3" json:"position,omitempty"`                 // Position of the last change the consumer is done with
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AcknowledgeRequest) Reset() {
+	*x = AcknowledgeRequest{}
+	mi := &file_proto_change_stream_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AcknowledgeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AcknowledgeRequest) ProtoMessage() {}
+
+func (x *AcknowledgeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_change_stream_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AcknowledgeRequest.ProtoReflect.Descriptor instead.
+func (*AcknowledgeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AcknowledgeRequest) GetStreamId() string {
+	if x != nil {
+		return x.StreamId
+	}
+	return ""
+}
+
+func (x *AcknowledgeRequest) GetPosition() string {
+	if x != nil {
+		return x.Position
+	}
+	return ""
+}
+
+type AcknowledgeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AcknowledgeResponse) Reset() {
+	*x = AcknowledgeResponse{}
+	mi := &file_proto_change_stream_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AcknowledgeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AcknowledgeResponse) ProtoMessage() {}
+
+func (x *AcknowledgeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_change_stream_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AcknowledgeResponse.ProtoReflect.Descriptor instead.
+func (*AcknowledgeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{2}
+}
+
 type Change struct {
 	state    protoimpl.MessageState `protogen:"open.v1"`
 	Position string                 `protobuf:"bytes,1,opt,name=position,proto3" json:"position,omitempty"`
-	Type     string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Type     string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"` // Legacy form of change_type: "dml", "ddl", or "heartbeat"
 	// Types that are valid to be assigned to Data:
 	//
 	//	*Change_Dml
 	//	*Change_Ddl
-	Data          isChange_Data `protobuf_oneof:"data"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	//	*Change_Message
+	Data              isChange_Data     `protobuf_oneof:"data"`
+	ChangeType        ChangeType        `protobuf:"varint,5,opt,name=change_type,json=changeType,proto3,enum=change_stream.ChangeType" json:"change_type,omitempty"`
+	SourceDialect     SourceDialect     `protobuf:"varint,6,opt,name=source_dialect,json=sourceDialect,proto3,enum=change_stream.SourceDialect" json:"source_dialect,omitempty"`
+	Database          string            `protobuf:"bytes,7,opt,name=database,proto3" json:"database,omitempty"`                                                                            // Source database, when known
+	Schema            string            `protobuf:"bytes,8,opt,name=schema,proto3" json:"schema,omitempty"`                                                                                // Source schema (PostgreSQL) or database (MySQL), when known
+	TransactionId     string            `protobuf:"bytes,9,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"`                                             // Source transaction: PostgreSQL xid or MySQL GTID, when known
+	CommitTimestamp   string            `protobuf:"bytes,10,opt,name=commit_timestamp,json=commitTimestamp,proto3" json:"commit_timestamp,omitempty"`                                      // Commit time of the source transaction, RFC 3339, when known
+	Metadata          map[string]string `protobuf:"bytes,11,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Free-form annotations, such as trace context
+	TransactionStatus TransactionStatus `protobuf:"varint,12,opt,name=transaction_status,json=transactionStatus,proto3,enum=change_stream.TransactionStatus" json:"transaction_status,omitempty"`
+	SubtransactionId  string            `protobuf:"bytes,13,opt,name=subtransaction_id,json=subtransactionId,proto3" json:"subtransaction_id,omitempty"` // PostgreSQL subtransaction of a streamed change or abort, when not the top-level transaction
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
 }
 
 func (x *Change) Reset() {
 	*x = Change{}
-	mi := &file_proto_change_stream_proto_msgTypes[1]
+	mi := &file_proto_change_stream_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -92,7 +471,7 @@ func (x *Change) String() string {
 func (*Change) ProtoMessage() {}
 
 func (x *Change) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_change_stream_proto_msgTypes[1]
+	mi := &file_proto_change_stream_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -105,7 +484,7 @@ func (x *Change) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Change.ProtoReflect.Descriptor instead.
 func (*Change) Descriptor() ([]byte, []int) {
-	return file_proto_change_stream_proto_rawDescGZIP(), []int{1}
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *Change) GetPosition() string {
@@ -147,6 +526,78 @@ func (x *Change) GetDdl() *DDLData {
 	return nil
 }
 
+func (x *Change) GetMessage() *MessageData {
+	if x != nil {
+		if x, ok := x.Data.(*Change_Message); ok {
+			return x.Message
+		}
+	}
+	return nil
+}
+
+func (x *Change) GetChangeType() ChangeType {
+	if x != nil {
+		return x.ChangeType
+	}
+	return ChangeType_CHANGE_TYPE_UNSPECIFIED
+}
+
+func (x *Change) GetSourceDialect() SourceDialect {
+	if x != nil {
+		return x.SourceDialect
+	}
+	return SourceDialect_SOURCE_DIALECT_UNSPECIFIED
+}
+
+func (x *Change) GetDatabase() string {
+	if x != nil {
+		return x.Database
+	}
+	return ""
+}
+
+func (x *Change) GetSchema() string {
+	if x != nil {
+		return x.Schema
+	}
+	return ""
+}
+
+func (x *Change) GetTransactionId() string {
+	if x != nil {
+		return x.TransactionId
+	}
+	return ""
+}
+
+func (x *Change) GetCommitTimestamp() string {
+	if x != nil {
+		return x.CommitTimestamp
+	}
+	return ""
+}
+
+func (x *Change) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *Change) GetTransactionStatus() TransactionStatus {
+	if x != nil {
+		return x.TransactionStatus
+	}
+	return TransactionStatus_TRANSACTION_STATUS_UNSPECIFIED
+}
+
+func (x *Change) GetSubtransactionId() string {
+	if x != nil {
+		return x.SubtransactionId
+	}
+	return ""
+}
+
 type isChange_Data interface {
 	isChange_Data()
 }
@@ -159,10 +610,16 @@ type Change_Ddl struct {
 	Ddl *DDLData `protobuf:"bytes,4,opt,name=ddl,proto3,oneof"`
 }
 
+type Change_Message struct {
+	Message *MessageData `protobuf:"bytes,14,opt,name=message,proto3,oneof"`
+}
+
 func (*Change_Dml) isChange_Data() {}
 
 func (*Change_Ddl) isChange_Data() {}
 
+func (*Change_Message) isChange_Data() {}
+
 type ColumnValue struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// Types that are valid to be assigned to Value:
@@ -172,6 +629,11 @@ type ColumnValue struct {
 	//	*ColumnValue_FloatValue
 	//	*ColumnValue_BoolValue
 	//	*ColumnValue_TimestampValue
+	//	*ColumnValue_UnchangedToast
+	//	*ColumnValue_BytesValue
+	//	*ColumnValue_JsonValue
+	//	*ColumnValue_UuidValue
+	//	*ColumnValue_GeometryValue
 	Value         isColumnValue_Value `protobuf_oneof:"value"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -179,7 +641,7 @@ type ColumnValue struct {
 
 func (x *ColumnValue) Reset() {
 	*x = ColumnValue{}
-	mi := &file_proto_change_stream_proto_msgTypes[2]
+	mi := &file_proto_change_stream_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -191,7 +653,7 @@ func (x *ColumnValue) String() string {
 func (*ColumnValue) ProtoMessage() {}
 
 func (x *ColumnValue) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_change_stream_proto_msgTypes[2]
+	mi := &file_proto_change_stream_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -204,7 +666,7 @@ func (x *ColumnValue) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ColumnValue.ProtoReflect.Descriptor instead.
 func (*ColumnValue) Descriptor() ([]byte, []int) {
-	return file_proto_change_stream_proto_rawDescGZIP(), []int{2}
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *ColumnValue) GetValue() isColumnValue_Value {
@@ -259,6 +721,51 @@ func (x *ColumnValue) GetTimestampValue() string {
 	return ""
 }
 
+func (x *ColumnValue) GetUnchangedToast() bool {
+	if x != nil {
+		if x, ok := x.Value.(*ColumnValue_UnchangedToast); ok {
+			return x.UnchangedToast
+		}
+	}
+	return false
+}
+
+func (x *ColumnValue) GetBytesValue() []byte {
+	if x != nil {
+		if x, ok := x.Value.(*ColumnValue_BytesValue); ok {
+			return x.BytesValue
+		}
+	}
+	return nil
+}
+
+func (x *ColumnValue) GetJsonValue() string {
+	if x != nil {
+		if x, ok := x.Value.(*ColumnValue_JsonValue); ok {
+			return x.JsonValue
+		}
+	}
+	return ""
+}
+
+func (x *ColumnValue) GetUuidValue() string {
+	if x != nil {
+		if x, ok := x.Value.(*ColumnValue_UuidValue); ok {
+			return x.UuidValue
+		}
+	}
+	return ""
+}
+
+func (x *ColumnValue) GetGeometryValue() *Geometry {
+	if x != nil {
+		if x, ok := x.Value.(*ColumnValue_GeometryValue); ok {
+			return x.GeometryValue
+		}
+	}
+	return nil
+}
+
 type isColumnValue_Value interface {
 	isColumnValue_Value()
 }
@@ -283,6 +790,26 @@ type ColumnValue_TimestampValue struct {
 	TimestampValue string `protobuf:"bytes,5,opt,name=timestamp_value,json=timestampValue,proto3,oneof"` // ISO 8601 format
 }
 
+type ColumnValue_UnchangedToast struct {
+	UnchangedToast bool `protobuf:"varint,6,opt,name=unchanged_toast,json=unchangedToast,proto3,oneof"` // PostgreSQL TOAST value not included in the WAL because it did not change
+}
+
+type ColumnValue_BytesValue struct {
+	BytesValue []byte `protobuf:"bytes,7,opt,name=bytes_value,json=bytesValue,proto3,oneof"` // bytea, BLOB and BINARY columns
+}
+
+type ColumnValue_JsonValue struct {
+	JsonValue string `protobuf:"bytes,8,opt,name=json_value,json=jsonValue,proto3,oneof"` // JSON document text
+}
+
+type ColumnValue_UuidValue struct {
+	UuidValue string `protobuf:"bytes,9,opt,name=uuid_value,json=uuidValue,proto3,oneof"` // canonical 36-character form
+}
+
+type ColumnValue_GeometryValue struct {
+	GeometryValue *Geometry `protobuf:"bytes,10,opt,name=geometry_value,json=geometryValue,proto3,oneof"`
+}
+
 func (*ColumnValue_StringValue) isColumnValue_Value() {}
 
 func (*ColumnValue_IntValue) isColumnValue_Value() {}
@@ -293,20 +820,86 @@ func (*ColumnValue_BoolValue) isColumnValue_Value() {}
 
 func (*ColumnValue_TimestampValue) isColumnValue_Value() {}
 
+func (*ColumnValue_UnchangedToast) isColumnValue_Value() {}
+
+func (*ColumnValue_BytesValue) isColumnValue_Value() {}
+
+func (*ColumnValue_JsonValue) isColumnValue_Value() {}
+
+func (*ColumnValue_UuidValue) isColumnValue_Value() {}
+
+func (*ColumnValue_GeometryValue) isColumnValue_Value() {}
+
+// Geometry is a spatial value
+type Geometry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Wkt           string                 `protobuf:"bytes,1,opt,name=wkt,proto3" json:"wkt,omitempty"`    // well-known text, e.g. POINT(1 2)
+	Srid          uint32                 `protobuf:"varint,2,opt,name=srid,proto3" json:"srid,omitempty"` // spatial reference system, 0 if unspecified
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Geometry) Reset() {
+	*x = Geometry{}
+	mi := &file_proto_change_stream_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Geometry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Geometry) ProtoMessage() {}
+
+func (x *Geometry) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_change_stream_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Geometry.ProtoReflect.Descriptor instead.
+func (*Geometry) Descriptor() ([]byte, []int) {
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Geometry) GetWkt() string {
+	if x != nil {
+		return x.Wkt
+	}
+	return ""
+}
+
+func (x *Geometry) GetSrid() uint32 {
+	if x != nil {
+		return x.Srid
+	}
+	return 0
+}
+
 type DMLData struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Table         string                 `protobuf:"bytes,1,opt,name=table,proto3" json:"table,omitempty"`
 	ColumnNames   []string               `protobuf:"bytes,2,rep,name=column_names,json=columnNames,proto3" json:"column_names,omitempty"`
 	ColumnValues  []*ColumnValue         `protobuf:"bytes,3,rep,name=column_values,json=columnValues,proto3" json:"column_values,omitempty"`
-	Kind          string                 `protobuf:"bytes,4,opt,name=kind,proto3" json:"kind,omitempty"`
+	Kind          string                 `protobuf:"bytes,4,opt,name=kind,proto3" json:"kind,omitempty"` // Legacy form of dml_kind: "insert", "update" or "delete"
 	OldKeys       *OldKeys               `protobuf:"bytes,5,opt,name=old_keys,json=oldKeys,proto3" json:"old_keys,omitempty"`
+	PrimaryKey    []string               `protobuf:"bytes,6,rep,name=primary_key,json=primaryKey,proto3" json:"primary_key,omitempty"` // Primary key column names, when known
+	Schema        string                 `protobuf:"bytes,7,opt,name=schema,proto3" json:"schema,omitempty"`                           // Source schema (PostgreSQL) or database (MySQL) of the table, when known
+	DmlKind       DMLKind                `protobuf:"varint,8,opt,name=dml_kind,json=dmlKind,proto3,enum=change_stream.DMLKind" json:"dml_kind,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *DMLData) Reset() {
 	*x = DMLData{}
-	mi := &file_proto_change_stream_proto_msgTypes[3]
+	mi := &file_proto_change_stream_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -318,7 +911,7 @@ func (x *DMLData) String() string {
 func (*DMLData) ProtoMessage() {}
 
 func (x *DMLData) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_change_stream_proto_msgTypes[3]
+	mi := &file_proto_change_stream_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -331,7 +924,7 @@ func (x *DMLData) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DMLData.ProtoReflect.Descriptor instead.
 func (*DMLData) Descriptor() ([]byte, []int) {
-	return file_proto_change_stream_proto_rawDescGZIP(), []int{3}
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *DMLData) GetTable() string {
@@ -366,7 +959,28 @@ func (x *DMLData) GetOldKeys() *OldKeys {
 	if x != nil {
 		return x.OldKeys
 	}
-	return nil
+	return nil
+}
+
+func (x *DMLData) GetPrimaryKey() []string {
+	if x != nil {
+		return x.PrimaryKey
+	}
+	return nil
+}
+
+func (x *DMLData) GetSchema() string {
+	if x != nil {
+		return x.Schema
+	}
+	return ""
+}
+
+func (x *DMLData) GetDmlKind() DMLKind {
+	if x != nil {
+		return x.DmlKind
+	}
+	return DMLKind_DML_KIND_UNSPECIFIED
 }
 
 type OldKeys struct {
@@ -379,7 +993,7 @@ type OldKeys struct {
 
 func (x *OldKeys) Reset() {
 	*x = OldKeys{}
-	mi := &file_proto_change_stream_proto_msgTypes[4]
+	mi := &file_proto_change_stream_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -391,7 +1005,7 @@ func (x *OldKeys) String() string {
 func (*OldKeys) ProtoMessage() {}
 
 func (x *OldKeys) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_change_stream_proto_msgTypes[4]
+	mi := &file_proto_change_stream_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -404,7 +1018,7 @@ func (x *OldKeys) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use OldKeys.ProtoReflect.Descriptor instead.
 func (*OldKeys) Descriptor() ([]byte, []int) {
-	return file_proto_change_stream_proto_rawDescGZIP(), []int{4}
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *OldKeys) GetKeyNames() []string {
@@ -434,7 +1048,7 @@ type DDLData struct {
 
 func (x *DDLData) Reset() {
 	*x = DDLData{}
-	mi := &file_proto_change_stream_proto_msgTypes[5]
+	mi := &file_proto_change_stream_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -446,7 +1060,7 @@ func (x *DDLData) String() string {
 func (*DDLData) ProtoMessage() {}
 
 func (x *DDLData) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_change_stream_proto_msgTypes[5]
+	mi := &file_proto_change_stream_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -459,7 +1073,7 @@ func (x *DDLData) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DDLData.ProtoReflect.Descriptor instead.
 func (*DDLData) Descriptor() ([]byte, []int) {
-	return file_proto_change_stream_proto_rawDescGZIP(), []int{5}
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *DDLData) GetId() int32 {
@@ -497,6 +1111,68 @@ func (x *DDLData) GetDdl() string {
 	return ""
 }
 
+// MessageData is a message an application wrote to the source's log, such as a
+// "deploy-123 complete" marker, with no effect on its tables
+type MessageData struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"` // Identifies the application or kind of message
+	Content       []byte                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	Transactional bool                   `protobuf:"varint,3,opt,name=transactional,proto3" json:"transactional,omitempty"` // Sent when its transaction committed rather than right away
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MessageData) Reset() {
+	*x = MessageData{}
+	mi := &file_proto_change_stream_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MessageData) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MessageData) ProtoMessage() {}
+
+func (x *MessageData) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_change_stream_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MessageData.ProtoReflect.Descriptor instead.
+func (*MessageData) Descriptor() ([]byte, []int) {
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *MessageData) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *MessageData) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+func (x *MessageData) GetTransactional() bool {
+	if x != nil {
+		return x.Transactional
+	}
+	return false
+}
+
 // Bootstrap coordination messages
 type StartBootstrapRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -508,7 +1184,7 @@ type StartBootstrapRequest struct {
 
 func (x *StartBootstrapRequest) Reset() {
 	*x = StartBootstrapRequest{}
-	mi := &file_proto_change_stream_proto_msgTypes[6]
+	mi := &file_proto_change_stream_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -520,7 +1196,7 @@ func (x *StartBootstrapRequest) String() string {
 func (*StartBootstrapRequest) ProtoMessage() {}
 
 func (x *StartBootstrapRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_change_stream_proto_msgTypes[6]
+	mi := &file_proto_change_stream_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -533,7 +1209,7 @@ func (x *StartBootstrapRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StartBootstrapRequest.ProtoReflect.Descriptor instead.
 func (*StartBootstrapRequest) Descriptor() ([]byte, []int) {
-	return file_proto_change_stream_proto_rawDescGZIP(), []int{6}
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *StartBootstrapRequest) GetStartPosition() string {
@@ -558,7 +1234,7 @@ type CompleteBootstrapRequest struct {
 
 func (x *CompleteBootstrapRequest) Reset() {
 	*x = CompleteBootstrapRequest{}
-	mi := &file_proto_change_stream_proto_msgTypes[7]
+	mi := &file_proto_change_stream_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -570,7 +1246,7 @@ func (x *CompleteBootstrapRequest) String() string {
 func (*CompleteBootstrapRequest) ProtoMessage() {}
 
 func (x *CompleteBootstrapRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_change_stream_proto_msgTypes[7]
+	mi := &file_proto_change_stream_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -583,7 +1259,7 @@ func (x *CompleteBootstrapRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CompleteBootstrapRequest.ProtoReflect.Descriptor instead.
 func (*CompleteBootstrapRequest) Descriptor() ([]byte, []int) {
-	return file_proto_change_stream_proto_rawDescGZIP(), []int{7}
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{11}
 }
 
 type GetStatusRequest struct {
@@ -594,7 +1270,7 @@ type GetStatusRequest struct {
 
 func (x *GetStatusRequest) Reset() {
 	*x = GetStatusRequest{}
-	mi := &file_proto_change_stream_proto_msgTypes[8]
+	mi := &file_proto_change_stream_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -606,7 +1282,7 @@ func (x *GetStatusRequest) String() string {
 func (*GetStatusRequest) ProtoMessage() {}
 
 func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_change_stream_proto_msgTypes[8]
+	mi := &file_proto_change_stream_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -619,7 +1295,43 @@ func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetStatusRequest.ProtoReflect.Descriptor instead.
 func (*GetStatusRequest) Descriptor() ([]byte, []int) {
-	return file_proto_change_stream_proto_rawDescGZIP(), []int{8}
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{12}
+}
+
+type GetStateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStateRequest) Reset() {
+	*x = GetStateRequest{}
+	mi := &file_proto_change_stream_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStateRequest) ProtoMessage() {}
+
+func (x *GetStateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_change_stream_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStateRequest.ProtoReflect.Descriptor instead.
+func (*GetStateRequest) Descriptor() ([]byte, []int) {
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{13}
 }
 
 type BootstrapResponse struct {
@@ -635,7 +1347,7 @@ type BootstrapResponse struct {
 
 func (x *BootstrapResponse) Reset() {
 	*x = BootstrapResponse{}
-	mi := &file_proto_change_stream_proto_msgTypes[9]
+	mi := &file_proto_change_stream_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -647,7 +1359,7 @@ func (x *BootstrapResponse) String() string {
 func (*BootstrapResponse) ProtoMessage() {}
 
 func (x *BootstrapResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_change_stream_proto_msgTypes[9]
+	mi := &file_proto_change_stream_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -660,7 +1372,7 @@ func (x *BootstrapResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BootstrapResponse.ProtoReflect.Descriptor instead.
 func (*BootstrapResponse) Descriptor() ([]byte, []int) {
-	return file_proto_change_stream_proto_rawDescGZIP(), []int{9}
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *BootstrapResponse) GetStatus() string {
@@ -706,13 +1418,15 @@ type StatusResponse struct {
 	AccumulatedChanges int64                  `protobuf:"varint,4,opt,name=accumulated_changes,json=accumulatedChanges,proto3" json:"accumulated_changes,omitempty"`
 	ConnectedClients   int32                  `protobuf:"varint,5,opt,name=connected_clients,json=connectedClients,proto3" json:"connected_clients,omitempty"`
 	UptimeSeconds      int64                  `protobuf:"varint,6,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
+	DuplicateChanges   int64                  `protobuf:"varint,7,opt,name=duplicate_changes,json=duplicateChanges,proto3" json:"duplicate_changes,omitempty"`    // Re-emitted changes skipped because they were already buffered
+	UnpublishedTables  int64                  `protobuf:"varint,8,opt,name=unpublished_tables,json=unpublishedTables,proto3" json:"unpublished_tables,omitempty"` // Tables created on the source that aren't in the publication
 	unknownFields      protoimpl.UnknownFields
 	sizeCache          protoimpl.SizeCache
 }
 
 func (x *StatusResponse) Reset() {
 	*x = StatusResponse{}
-	mi := &file_proto_change_stream_proto_msgTypes[10]
+	mi := &file_proto_change_stream_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -724,7 +1438,7 @@ func (x *StatusResponse) String() string {
 func (*StatusResponse) ProtoMessage() {}
 
 func (x *StatusResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_change_stream_proto_msgTypes[10]
+	mi := &file_proto_change_stream_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -737,7 +1451,7 @@ func (x *StatusResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StatusResponse.ProtoReflect.Descriptor instead.
 func (*StatusResponse) Descriptor() ([]byte, []int) {
-	return file_proto_change_stream_proto_rawDescGZIP(), []int{10}
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *StatusResponse) GetState() string {
@@ -782,19 +1496,281 @@ func (x *StatusResponse) GetUptimeSeconds() int64 {
 	return 0
 }
 
+func (x *StatusResponse) GetDuplicateChanges() int64 {
+	if x != nil {
+		return x.DuplicateChanges
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetUnpublishedTables() int64 {
+	if x != nil {
+		return x.UnpublishedTables
+	}
+	return 0
+}
+
+// StateTransition is a state the service can move to and what moves it there
+type StateTransition struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	To            string                 `protobuf:"bytes,1,opt,name=to,proto3" json:"to,omitempty"`
+	Trigger       string                 `protobuf:"bytes,2,opt,name=trigger,proto3" json:"trigger,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StateTransition) Reset() {
+	*x = StateTransition{}
+	mi := &file_proto_change_stream_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StateTransition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StateTransition) ProtoMessage() {}
+
+func (x *StateTransition) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_change_stream_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StateTransition.ProtoReflect.Descriptor instead.
+func (*StateTransition) Descriptor() ([]byte, []int) {
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *StateTransition) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *StateTransition) GetTrigger() string {
+	if x != nil {
+		return x.Trigger
+	}
+	return ""
+}
+
+// AccumulationLimits bound the ACCUMULATING state; zero disables a limit
+type AccumulationLimits struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	MaxChanges         int64                  `protobuf:"varint,1,opt,name=max_changes,json=maxChanges,proto3" json:"max_changes,omitempty"`
+	MaxBytes           int64                  `protobuf:"varint,2,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`
+	MaxDurationSeconds int64                  `protobuf:"varint,3,opt,name=max_duration_seconds,json=maxDurationSeconds,proto3" json:"max_duration_seconds,omitempty"`
+	Action             string                 `protobuf:"bytes,4,opt,name=action,proto3" json:"action,omitempty"` // State entered when a limit is reached: WAITING or STREAMING
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *AccumulationLimits) Reset() {
+	*x = AccumulationLimits{}
+	mi := &file_proto_change_stream_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AccumulationLimits) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AccumulationLimits) ProtoMessage() {}
+
+func (x *AccumulationLimits) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_change_stream_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AccumulationLimits.ProtoReflect.Descriptor instead.
+func (*AccumulationLimits) Descriptor() ([]byte, []int) {
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *AccumulationLimits) GetMaxChanges() int64 {
+	if x != nil {
+		return x.MaxChanges
+	}
+	return 0
+}
+
+func (x *AccumulationLimits) GetMaxBytes() int64 {
+	if x != nil {
+		return x.MaxBytes
+	}
+	return 0
+}
+
+func (x *AccumulationLimits) GetMaxDurationSeconds() int64 {
+	if x != nil {
+		return x.MaxDurationSeconds
+	}
+	return 0
+}
+
+func (x *AccumulationLimits) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+type StateResponse struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	State              string                 `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`
+	Description        string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`                             // What the service does in this state
+	TransitionTime     string                 `protobuf:"bytes,3,opt,name=transition_time,json=transitionTime,proto3" json:"transition_time,omitempty"` // RFC 3339 time the state was entered
+	Transitions        []*StateTransition     `protobuf:"bytes,4,rep,name=transitions,proto3" json:"transitions,omitempty"`
+	AccumulationLimits *AccumulationLimits    `protobuf:"bytes,5,opt,name=accumulation_limits,json=accumulationLimits,proto3" json:"accumulation_limits,omitempty"`
+	// Progress of the current accumulation; the counts are the growth of the KV buffer
+	AccumulatedChanges  int64 `protobuf:"varint,6,opt,name=accumulated_changes,json=accumulatedChanges,proto3" json:"accumulated_changes,omitempty"`
+	AccumulatedBytes    int64 `protobuf:"varint,7,opt,name=accumulated_bytes,json=accumulatedBytes,proto3" json:"accumulated_bytes,omitempty"`
+	AccumulatingSeconds int64 `protobuf:"varint,8,opt,name=accumulating_seconds,json=accumulatingSeconds,proto3" json:"accumulating_seconds,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *StateResponse) Reset() {
+	*x = StateResponse{}
+	mi := &file_proto_change_stream_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StateResponse) ProtoMessage() {}
+
+func (x *StateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_change_stream_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StateResponse.ProtoReflect.Descriptor instead.
+func (*StateResponse) Descriptor() ([]byte, []int) {
+	return file_proto_change_stream_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *StateResponse) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *StateResponse) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *StateResponse) GetTransitionTime() string {
+	if x != nil {
+		return x.TransitionTime
+	}
+	return ""
+}
+
+func (x *StateResponse) GetTransitions() []*StateTransition {
+	if x != nil {
+		return x.Transitions
+	}
+	return nil
+}
+
+func (x *StateResponse) GetAccumulationLimits() *AccumulationLimits {
+	if x != nil {
+		return x.AccumulationLimits
+	}
+	return nil
+}
+
+func (x *StateResponse) GetAccumulatedChanges() int64 {
+	if x != nil {
+		return x.AccumulatedChanges
+	}
+	return 0
+}
+
+func (x *StateResponse) GetAccumulatedBytes() int64 {
+	if x != nil {
+		return x.AccumulatedBytes
+	}
+	return 0
+}
+
+func (x *StateResponse) GetAccumulatingSeconds() int64 {
+	if x != nil {
+		return x.AccumulatingSeconds
+	}
+	return 0
+}
+
 var File_proto_change_stream_proto protoreflect.FileDescriptor
 
 const file_proto_change_stream_proto_rawDesc = "" +
 	"\n" +
-	"\x19proto/change_stream.proto\x12\rchange_stream\"4\n" +
+	"\x19proto/change_stream.proto\x12\rchange_stream\"\xbc\x02\n" +
 	"\rStreamRequest\x12#\n" +
-	"\rlast_position\x18\x01 \x01(\tR\flastPosition\"\x98\x01\n" +
+	"\rlast_position\x18\x01 \x01(\tR\flastPosition\x123\n" +
+	"\x16max_changes_per_second\x18\x02 \x01(\rR\x13maxChangesPerSecond\x12/\n" +
+	"\x14max_bytes_per_second\x18\x03 \x01(\x04R\x11maxBytesPerSecond\x12%\n" +
+	"\x0einclude_tables\x18\x04 \x03(\tR\rincludeTables\x12%\n" +
+	"\x0eexclude_tables\x18\x05 \x03(\tR\rexcludeTables\x12#\n" +
+	"\rexclude_kinds\x18\x06 \x03(\tR\fexcludeKinds\x12-\n" +
+	"\x13max_in_flight_bytes\x18\a \x01(\x04R\x10maxInFlightBytes\"M\n" +
+	"\x12AcknowledgeRequest\x12\x1b\n" +
+	"\tstream_id\x18\x01 \x01(\tR\bstreamId\x12\x1a\n" +
+	"\bposition\x18\x02 \x01(\tR\bposition\"\x15\n" +
+	"\x13AcknowledgeResponse\"\xd3\x05\n" +
 	"\x06Change\x12\x1a\n" +
 	"\bposition\x18\x01 \x01(\tR\bposition\x12\x12\n" +
 	"\x04type\x18\x02 \x01(\tR\x04type\x12*\n" +
 	"\x03dml\x18\x03 \x01(\v2\x16.change_stream.DMLDataH\x00R\x03dml\x12*\n" +
-	"\x03ddl\x18\x04 \x01(\v2\x16.change_stream.DDLDataH\x00R\x03ddlB\x06\n" +
-	"\x04data\"\xc9\x01\n" +
+	"\x03ddl\x18\x04 \x01(\v2\x16.change_stream.DDLDataH\x00R\x03ddl\x126\n" +
+	"\amessage\x18\x0e \x01(\v2\x1a.change_stream.MessageDataH\x00R\amessage\x12:\n" +
+	"\vchange_type\x18\x05 \x01(\x0e2\x19.change_stream.ChangeTypeR\n" +
+	"changeType\x12C\n" +
+	"\x0esource_dialect\x18\x06 \x01(\x0e2\x1c.change_stream.SourceDialectR\rsourceDialect\x12\x1a\n" +
+	"\bdatabase\x18\a \x01(\tR\bdatabase\x12\x16\n" +
+	"\x06schema\x18\b \x01(\tR\x06schema\x12%\n" +
+	"\x0etransaction_id\x18\t \x01(\tR\rtransactionId\x12)\n" +
+	"\x10commit_timestamp\x18\n" +
+	" \x01(\tR\x0fcommitTimestamp\x12?\n" +
+	"\bmetadata\x18\v \x03(\v2#.change_stream.Change.MetadataEntryR\bmetadata\x12O\n" +
+	"\x12transaction_status\x18\f \x01(\x0e2 .change_stream.TransactionStatusR\x11transactionStatus\x12+\n" +
+	"\x11subtransaction_id\x18\r \x01(\tR\x10subtransactionId\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\x06\n" +
+	"\x04data\"\x9b\x03\n" +
 	"\vColumnValue\x12#\n" +
 	"\fstring_value\x18\x01 \x01(\tH\x00R\vstringValue\x12\x1d\n" +
 	"\tint_value\x18\x02 \x01(\x03H\x00R\bintValue\x12!\n" +
@@ -802,14 +1778,30 @@ const file_proto_change_stream_proto_rawDesc = "" +
 	"floatValue\x12\x1f\n" +
 	"\n" +
 	"bool_value\x18\x04 \x01(\bH\x00R\tboolValue\x12)\n" +
-	"\x0ftimestamp_value\x18\x05 \x01(\tH\x00R\x0etimestampValueB\a\n" +
-	"\x05value\"\xca\x01\n" +
+	"\x0ftimestamp_value\x18\x05 \x01(\tH\x00R\x0etimestampValue\x12)\n" +
+	"\x0funchanged_toast\x18\x06 \x01(\bH\x00R\x0eunchangedToast\x12!\n" +
+	"\vbytes_value\x18\a \x01(\fH\x00R\n" +
+	"bytesValue\x12\x1f\n" +
+	"\n" +
+	"json_value\x18\b \x01(\tH\x00R\tjsonValue\x12\x1f\n" +
+	"\n" +
+	"uuid_value\x18\t \x01(\tH\x00R\tuuidValue\x12@\n" +
+	"\x0egeometry_value\x18\n" +
+	" \x01(\v2\x17.change_stream.GeometryH\x00R\rgeometryValueB\a\n" +
+	"\x05value\"0\n" +
+	"\bGeometry\x12\x10\n" +
+	"\x03wkt\x18\x01 \x01(\tR\x03wkt\x12\x12\n" +
+	"\x04srid\x18\x02 \x01(\rR\x04srid\"\xb6\x02\n" +
 	"\aDMLData\x12\x14\n" +
 	"\x05table\x18\x01 \x01(\tR\x05table\x12!\n" +
 	"\fcolumn_names\x18\x02 \x03(\tR\vcolumnNames\x12?\n" +
 	"\rcolumn_values\x18\x03 \x03(\v2\x1a.change_stream.ColumnValueR\fcolumnValues\x12\x12\n" +
 	"\x04kind\x18\x04 \x01(\tR\x04kind\x121\n" +
-	"\bold_keys\x18\x05 \x01(\v2\x16.change_stream.OldKeysR\aoldKeys\"a\n" +
+	"\bold_keys\x18\x05 \x01(\v2\x16.change_stream.OldKeysR\aoldKeys\x12\x1f\n" +
+	"\vprimary_key\x18\x06 \x03(\tR\n" +
+	"primaryKey\x12\x16\n" +
+	"\x06schema\x18\a \x01(\tR\x06schema\x121\n" +
+	"\bdml_kind\x18\b \x01(\x0e2\x16.change_stream.DMLKindR\admlKind\"a\n" +
 	"\aOldKeys\x12\x1b\n" +
 	"\tkey_names\x18\x01 \x03(\tR\bkeyNames\x129\n" +
 	"\n" +
@@ -819,30 +1811,82 @@ const file_proto_change_stream_proto_rawDesc = "" +
 	"\x04time\x18\x02 \x01(\tR\x04time\x12\x1a\n" +
 	"\busername\x18\x03 \x01(\tR\busername\x12\x1a\n" +
 	"\bdatabase\x18\x04 \x01(\tR\bdatabase\x12\x10\n" +
-	"\x03ddl\x18\x05 \x01(\tR\x03ddl\"c\n" +
+	"\x03ddl\x18\x05 \x01(\tR\x03ddl\"e\n" +
+	"\vMessageData\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\fR\acontent\x12$\n" +
+	"\rtransactional\x18\x03 \x01(\bR\rtransactional\"c\n" +
 	"\x15StartBootstrapRequest\x12%\n" +
 	"\x0estart_position\x18\x01 \x01(\tR\rstartPosition\x12#\n" +
 	"\rsnapshot_name\x18\x02 \x01(\tR\fsnapshotName\"\x1a\n" +
 	"\x18CompleteBootstrapRequest\"\x12\n" +
-	"\x10GetStatusRequest\"\xd0\x01\n" +
+	"\x10GetStatusRequest\"\x11\n" +
+	"\x0fGetStateRequest\"\xd0\x01\n" +
 	"\x11BootstrapResponse\x12\x16\n" +
 	"\x06status\x18\x01 \x01(\tR\x06status\x12%\n" +
 	"\x0eprevious_state\x18\x02 \x01(\tR\rpreviousState\x12#\n" +
 	"\rcurrent_state\x18\x03 \x01(\tR\fcurrentState\x12/\n" +
 	"\x13accumulated_changes\x18\x04 \x01(\x03R\x12accumulatedChanges\x12&\n" +
-	"\x0fready_to_stream\x18\x05 \x01(\bR\rreadyToStream\"\xfd\x01\n" +
+	"\x0fready_to_stream\x18\x05 \x01(\bR\rreadyToStream\"\xd9\x02\n" +
 	"\x0eStatusResponse\x12\x14\n" +
 	"\x05state\x18\x01 \x01(\tR\x05state\x12%\n" +
 	"\x0estart_position\x18\x02 \x01(\tR\rstartPosition\x12)\n" +
 	"\x10current_position\x18\x03 \x01(\tR\x0fcurrentPosition\x12/\n" +
 	"\x13accumulated_changes\x18\x04 \x01(\x03R\x12accumulatedChanges\x12+\n" +
 	"\x11connected_clients\x18\x05 \x01(\x05R\x10connectedClients\x12%\n" +
-	"\x0euptime_seconds\x18\x06 \x01(\x03R\ruptimeSeconds2\xde\x02\n" +
+	"\x0euptime_seconds\x18\x06 \x01(\x03R\ruptimeSeconds\x12+\n" +
+	"\x11duplicate_changes\x18\a \x01(\x03R\x10duplicateChanges\x12-\n" +
+	"\x12unpublished_tables\x18\b \x01(\x03R\x11unpublishedTables\";\n" +
+	"\x0fStateTransition\x12\x0e\n" +
+	"\x02to\x18\x01 \x01(\tR\x02to\x12\x18\n" +
+	"\atrigger\x18\x02 \x01(\tR\atrigger\"\x9c\x01\n" +
+	"\x12AccumulationLimits\x12\x1f\n" +
+	"\vmax_changes\x18\x01 \x01(\x03R\n" +
+	"maxChanges\x12\x1b\n" +
+	"\tmax_bytes\x18\x02 \x01(\x03R\bmaxBytes\x120\n" +
+	"\x14max_duration_seconds\x18\x03 \x01(\x03R\x12maxDurationSeconds\x12\x16\n" +
+	"\x06action\x18\x04 \x01(\tR\x06action\"\x97\x03\n" +
+	"\rStateResponse\x12\x14\n" +
+	"\x05state\x18\x01 \x01(\tR\x05state\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12'\n" +
+	"\x0ftransition_time\x18\x03 \x01(\tR\x0etransitionTime\x12@\n" +
+	"\vtransitions\x18\x04 \x03(\v2\x1e.change_stream.StateTransitionR\vtransitions\x12R\n" +
+	"\x13accumulation_limits\x18\x05 \x01(\v2!.change_stream.AccumulationLimitsR\x12accumulationLimits\x12/\n" +
+	"\x13accumulated_changes\x18\x06 \x01(\x03R\x12accumulatedChanges\x12+\n" +
+	"\x11accumulated_bytes\x18\a \x01(\x03R\x10accumulatedBytes\x121\n" +
+	"\x14accumulating_seconds\x18\b \x01(\x03R\x13accumulatingSeconds*\xa4\x01\n" +
+	"\n" +
+	"ChangeType\x12\x1b\n" +
+	"\x17CHANGE_TYPE_UNSPECIFIED\x10\x00\x12\x13\n" +
+	"\x0fCHANGE_TYPE_DML\x10\x01\x12\x13\n" +
+	"\x0fCHANGE_TYPE_DDL\x10\x02\x12\x19\n" +
+	"\x15CHANGE_TYPE_HEARTBEAT\x10\x03\x12\x1b\n" +
+	"\x17CHANGE_TYPE_TRANSACTION\x10\x04\x12\x17\n" +
+	"\x13CHANGE_TYPE_MESSAGE\x10\x05*\x9d\x01\n" +
+	"\x11TransactionStatus\x12\"\n" +
+	"\x1eTRANSACTION_STATUS_UNSPECIFIED\x10\x00\x12\"\n" +
+	"\x1eTRANSACTION_STATUS_IN_PROGRESS\x10\x01\x12 \n" +
+	"\x1cTRANSACTION_STATUS_COMMITTED\x10\x02\x12\x1e\n" +
+	"\x1aTRANSACTION_STATUS_ABORTED\x10\x03*b\n" +
+	"\aDMLKind\x12\x18\n" +
+	"\x14DML_KIND_UNSPECIFIED\x10\x00\x12\x13\n" +
+	"\x0fDML_KIND_INSERT\x10\x01\x12\x13\n" +
+	"\x0fDML_KIND_UPDATE\x10\x02\x12\x13\n" +
+	"\x0fDML_KIND_DELETE\x10\x03*\xbd\x01\n" +
+	"\rSourceDialect\x12\x1e\n" +
+	"\x1aSOURCE_DIALECT_UNSPECIFIED\x10\x00\x12\x1d\n" +
+	"\x19SOURCE_DIALECT_POSTGRESQL\x10\x01\x12\x18\n" +
+	"\x14SOURCE_DIALECT_MYSQL\x10\x02\x12\x1a\n" +
+	"\x16SOURCE_DIALECT_MONGODB\x10\x03\x12\x1c\n" +
+	"\x18SOURCE_DIALECT_SQLSERVER\x10\x04\x12\x19\n" +
+	"\x15SOURCE_DIALECT_ORACLE\x10\x052\x82\x04\n" +
 	"\fChangeStream\x12A\n" +
-	"\x06Stream\x12\x1c.change_stream.StreamRequest\x1a\x15.change_stream.Change\"\x000\x01\x12Z\n" +
+	"\x06Stream\x12\x1c.change_stream.StreamRequest\x1a\x15.change_stream.Change\"\x000\x01\x12V\n" +
+	"\vAcknowledge\x12!.change_stream.AcknowledgeRequest\x1a\".change_stream.AcknowledgeResponse\"\x00\x12Z\n" +
 	"\x0eStartBootstrap\x12$.change_stream.StartBootstrapRequest\x1a .change_stream.BootstrapResponse\"\x00\x12`\n" +
 	"\x11CompleteBootstrap\x12'.change_stream.CompleteBootstrapRequest\x1a .change_stream.BootstrapResponse\"\x00\x12M\n" +
-	"\tGetStatus\x12\x1f.change_stream.GetStatusRequest\x1a\x1d.change_stream.StatusResponse\"\x00B\x13Z\x11kasho/proto;protob\x06proto3"
+	"\tGetStatus\x12\x1f.change_stream.GetStatusRequest\x1a\x1d.change_stream.StatusResponse\"\x00\x12J\n" +
+	"\bGetState\x12\x1e.change_stream.GetStateRequest\x1a\x1c.change_stream.StateResponse\"\x00B\x13Z\x11kasho/proto;protob\x06proto3"
 
 var (
 	file_proto_change_stream_proto_rawDescOnce sync.Once
@@ -856,39 +1900,66 @@ func file_proto_change_stream_proto_rawDescGZIP() []byte {
 	return file_proto_change_stream_proto_rawDescData
 }
 
-var file_proto_change_stream_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_proto_change_stream_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_proto_change_stream_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
 var file_proto_change_stream_proto_goTypes = []any{
-	(*StreamRequest)(nil),            // 0: change_stream.StreamRequest
-	(*Change)(nil),                   // 1: change_stream.Change
-	(*ColumnValue)(nil),              // 2: change_stream.ColumnValue
-	(*DMLData)(nil),                  // 3: change_stream.DMLData
-	(*OldKeys)(nil),                  // 4: change_stream.OldKeys
-	(*DDLData)(nil),                  // 5: change_stream.DDLData
-	(*StartBootstrapRequest)(nil),    // 6: change_stream.StartBootstrapRequest
-	(*CompleteBootstrapRequest)(nil), // 7: change_stream.CompleteBootstrapRequest
-	(*GetStatusRequest)(nil),         // 8: change_stream.GetStatusRequest
-	(*BootstrapResponse)(nil),        // 9: change_stream.BootstrapResponse
-	(*StatusResponse)(nil),           // 10: change_stream.StatusResponse
+	(ChangeType)(0),                  // 0: change_stream.ChangeType
+	(TransactionStatus)(0),           // 1: change_stream.TransactionStatus
+	(DMLKind)(0),                     // 2: change_stream.DMLKind
+	(SourceDialect)(0),               // 3: change_stream.SourceDialect
+	(*StreamRequest)(nil),            // 4: change_stream.StreamRequest
+	(*AcknowledgeRequest)(nil),       // 5: change_stream.AcknowledgeRequest
+	(*AcknowledgeResponse)(nil),      // 6: change_stream.AcknowledgeResponse
+	(*Change)(nil),                   // 7: change_stream.Change
+	(*ColumnValue)(nil),              // 8: change_stream.ColumnValue
+	(*Geometry)(nil),                 // 9: change_stream.Geometry
+	(*DMLData)(nil),                  // 10: change_stream.DMLData
+	(*OldKeys)(nil),                  // 11: change_stream.OldKeys
+	(*DDLData)(nil),                  // 12: change_stream.DDLData
+	(*MessageData)(nil),              // 13: change_stream.MessageData
+	(*StartBootstrapRequest)(nil),    // 14: change_stream.StartBootstrapRequest
+	(*CompleteBootstrapRequest)(nil), // 15: change_stream.CompleteBootstrapRequest
+	(*GetStatusRequest)(nil),         // 16: change_stream.GetStatusRequest
+	(*GetStateRequest)(nil),          // 17: change_stream.GetStateRequest
+	(*BootstrapResponse)(nil),        // 18: change_stream.BootstrapResponse
+	(*StatusResponse)(nil),           // 19: change_stream.StatusResponse
+	(*StateTransition)(nil),          // 20: change_stream.StateTransition
+	(*AccumulationLimits)(nil),       // 21: change_stream.AccumulationLimits
+	(*StateResponse)(nil),            // 22: change_stream.StateResponse
+	nil,                              // 23: change_stream.Change.MetadataEntry
 }
 var file_proto_change_stream_proto_depIdxs = []int32{
-	3,  // 0: change_stream.Change.dml:type_name -> change_stream.DMLData
-	5,  // 1: change_stream.Change.ddl:type_name -> change_stream.DDLData
-	2,  // 2: change_stream.DMLData.column_values:type_name -> change_stream.ColumnValue
-	4,  // 3: change_stream.DMLData.old_keys:type_name -> change_stream.OldKeys
-	2,  // 4: change_stream.OldKeys.key_values:type_name -> change_stream.ColumnValue
-	0,  // 5: change_stream.ChangeStream.Stream:input_type -> change_stream.StreamRequest
-	6,  // 6: change_stream.ChangeStream.StartBootstrap:input_type -> change_stream.StartBootstrapRequest
-	7,  // 7: change_stream.ChangeStream.CompleteBootstrap:input_type -> change_stream.CompleteBootstrapRequest
-	8,  // 8: change_stream.ChangeStream.GetStatus:input_type -> change_stream.GetStatusRequest
-	1,  // 9: change_stream.ChangeStream.Stream:output_type -> change_stream.Change
-	9,  // 10: change_stream.ChangeStream.StartBootstrap:output_type -> change_stream.BootstrapResponse
-	9,  // 11: change_stream.ChangeStream.CompleteBootstrap:output_type -> change_stream.BootstrapResponse
-	10, // 12: change_stream.ChangeStream.GetStatus:output_type -> change_stream.StatusResponse
-	9,  // [9:13] is the sub-list for method output_type
-	5,  // [5:9] is the sub-list for method input_type
-	5,  // [5:5] is the sub-list for extension type_name
-	5,  // [5:5] is the sub-list for extension extendee
-	0,  // [0:5] is the sub-list for field type_name
+	10, // 0: change_stream.Change.dml:type_name -> change_stream.DMLData
+	12, // 1: change_stream.Change.ddl:type_name -> change_stream.DDLData
+	13, // 2: change_stream.Change.message:type_name -> change_stream.MessageData
+	0,  // 3: change_stream.Change.change_type:type_name -> change_stream.ChangeType
+	3,  // 4: change_stream.Change.source_dialect:type_name -> change_stream.SourceDialect
+	23, // 5: change_stream.Change.metadata:type_name -> change_stream.Change.MetadataEntry
+	1,  // 6: change_stream.Change.transaction_status:type_name -> change_stream.TransactionStatus
+	9,  // 7: change_stream.ColumnValue.geometry_value:type_name -> change_stream.Geometry
+	8,  // 8: change_stream.DMLData.column_values:type_name -> change_stream.ColumnValue
+	11, // 9: change_stream.DMLData.old_keys:type_name -> change_stream.OldKeys
+	2,  // 10: change_stream.DMLData.dml_kind:type_name -> change_stream.DMLKind
+	8,  // 11: change_stream.OldKeys.key_values:type_name -> change_stream.ColumnValue
+	20, // 12: change_stream.StateResponse.transitions:type_name -> change_stream.StateTransition
+	21, // 13: change_stream.StateResponse.accumulation_limits:type_name -> change_stream.AccumulationLimits
+	4,  // 14: change_stream.ChangeStream.Stream:input_type -> change_stream.StreamRequest
+	5,  // 15: change_stream.ChangeStream.Acknowledge:input_type -> change_stream.AcknowledgeRequest
+	14, // 16: change_stream.ChangeStream.StartBootstrap:input_type -> change_stream.StartBootstrapRequest
+	15, // 17: change_stream.ChangeStream.CompleteBootstrap:input_type -> change_stream.CompleteBootstrapRequest
+	16, // 18: change_stream.ChangeStream.GetStatus:input_type -> change_stream.GetStatusRequest
+	17, // 19: change_stream.ChangeStream.GetState:input_type -> change_stream.GetStateRequest
+	7,  // 20: change_stream.ChangeStream.Stream:output_type -> change_stream.Change
+	6,  // 21: change_stream.ChangeStream.Acknowledge:output_type -> change_stream.AcknowledgeResponse
+	18, // 22: change_stream.ChangeStream.StartBootstrap:output_type -> change_stream.BootstrapResponse
+	18, // 23: change_stream.ChangeStream.CompleteBootstrap:output_type -> change_stream.BootstrapResponse
+	19, // 24: change_stream.ChangeStream.GetStatus:output_type -> change_stream.StatusResponse
+	22, // 25: change_stream.ChangeStream.GetState:output_type -> change_stream.StateResponse
+	20, // [20:26] is the sub-list for method output_type
+	14, // [14:20] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
 }
 
 func init() { file_proto_change_stream_proto_init() }
@@ -896,29 +1967,36 @@ func file_proto_change_stream_proto_init() {
 	if File_proto_change_stream_proto != nil {
 		return
 	}
-	file_proto_change_stream_proto_msgTypes[1].OneofWrappers = []any{
+	file_proto_change_stream_proto_msgTypes[3].OneofWrappers = []any{
 		(*Change_Dml)(nil),
 		(*Change_Ddl)(nil),
+		(*Change_Message)(nil),
 	}
-	file_proto_change_stream_proto_msgTypes[2].OneofWrappers = []any{
+	file_proto_change_stream_proto_msgTypes[4].OneofWrappers = []any{
 		(*ColumnValue_StringValue)(nil),
 		(*ColumnValue_IntValue)(nil),
 		(*ColumnValue_FloatValue)(nil),
 		(*ColumnValue_BoolValue)(nil),
 		(*ColumnValue_TimestampValue)(nil),
+		(*ColumnValue_UnchangedToast)(nil),
+		(*ColumnValue_BytesValue)(nil),
+		(*ColumnValue_JsonValue)(nil),
+		(*ColumnValue_UuidValue)(nil),
+		(*ColumnValue_GeometryValue)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_change_stream_proto_rawDesc), len(file_proto_change_stream_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   11,
+			NumEnums:      4,
+			NumMessages:   20,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_proto_change_stream_proto_goTypes,
 		DependencyIndexes: file_proto_change_stream_proto_depIdxs,
+		EnumInfos:         file_proto_change_stream_proto_enumTypes,
 		MessageInfos:      file_proto_change_stream_proto_msgTypes,
 	}.Build()
 	File_proto_change_stream_proto = out.File
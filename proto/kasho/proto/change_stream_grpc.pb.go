@@ -20,21 +20,29 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	ChangeStream_Stream_FullMethodName            = "/change_stream.ChangeStream/Stream"
+	ChangeStream_Acknowledge_FullMethodName       = "/change_stream.ChangeStream/Acknowledge"
 	ChangeStream_StartBootstrap_FullMethodName    = "/change_stream.ChangeStream/StartBootstrap"
 	ChangeStream_CompleteBootstrap_FullMethodName = "/change_stream.ChangeStream/CompleteBootstrap"
 	ChangeStream_GetStatus_FullMethodName         = "/change_stream.ChangeStream/GetStatus"
+	ChangeStream_GetState_FullMethodName          = "/change_stream.ChangeStream/GetState"
 )
 
 // ChangeStreamClient is the client API for ChangeStream service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ChangeStreamClient interface {
-	// Stream returns a stream of changes after the given LSN
+	// Stream returns a stream of changes after the given position
 	Stream(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Change], error)
+	// Acknowledge tells the server that a stream's consumer is done with the changes up
+	// to a position, releasing them from the stream's in-flight limit
+	Acknowledge(ctx context.Context, in *AcknowledgeRequest, opts ...grpc.CallOption) (*AcknowledgeResponse, error)
 	// Bootstrap coordination methods
 	StartBootstrap(ctx context.Context, in *StartBootstrapRequest, opts ...grpc.CallOption) (*BootstrapResponse, error)
 	CompleteBootstrap(ctx context.Context, in *CompleteBootstrapRequest, opts ...grpc.CallOption) (*BootstrapResponse, error)
 	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*StatusResponse, error)
+	// GetState describes the current state, the transitions it allows and the
+	// limits on accumulation
+	GetState(ctx context.Context, in *GetStateRequest, opts ...grpc.CallOption) (*StateResponse, error)
 }
 
 type changeStreamClient struct {
@@ -64,6 +72,16 @@ func (c *changeStreamClient) Stream(ctx context.Context, in *StreamRequest, opts
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type ChangeStream_StreamClient = grpc.ServerStreamingClient[Change]
 
+func (c *changeStreamClient) Acknowledge(ctx context.Context, in *AcknowledgeRequest, opts ...grpc.CallOption) (*AcknowledgeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AcknowledgeResponse)
+	err := c.cc.Invoke(ctx, ChangeStream_Acknowledge_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *changeStreamClient) StartBootstrap(ctx context.Context, in *StartBootstrapRequest, opts ...grpc.CallOption) (*BootstrapResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(BootstrapResponse)
@@ -94,16 +112,32 @@ func (c *changeStreamClient) GetStatus(ctx context.Context, in *GetStatusRequest
 	return out, nil
 }
 
+func (c *changeStreamClient) GetState(ctx context.Context, in *GetStateRequest, opts ...grpc.CallOption) (*StateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StateResponse)
+	err := c.cc.Invoke(ctx, ChangeStream_GetState_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ChangeStreamServer is the server API for ChangeStream service.
 // All implementations must embed UnimplementedChangeStreamServer
 // for forward compatibility.
 type ChangeStreamServer interface {
-	// Stream returns a stream of changes after the given LSN
+	// Stream returns a stream of changes after the given position
 	Stream(*StreamRequest, grpc.ServerStreamingServer[Change]) error
+	// Acknowledge tells the server that a stream's consumer is done with the changes up
+	// to a position, releasing them from the stream's in-flight limit
+	Acknowledge(context.Context, *AcknowledgeRequest) (*AcknowledgeResponse, error)
 	// Bootstrap coordination methods
 	StartBootstrap(context.Context, *StartBootstrapRequest) (*BootstrapResponse, error)
 	CompleteBootstrap(context.Context, *CompleteBootstrapRequest) (*BootstrapResponse, error)
 	GetStatus(context.Context, *GetStatusRequest) (*StatusResponse, error)
+	// GetState describes the current state, the transitions it allows and the
+	// limits on accumulation
+	GetState(context.Context, *GetStateRequest) (*StateResponse, error)
 	mustEmbedUnimplementedChangeStreamServer()
 }
 
@@ -117,6 +151,9 @@ type UnimplementedChangeStreamServer struct{}
 func (UnimplementedChangeStreamServer) Stream(*StreamRequest, grpc.ServerStreamingServer[Change]) error {
 	return status.Errorf(codes.Unimplemented, "method Stream not implemented")
 }
+func (UnimplementedChangeStreamServer) Acknowledge(context.Context, *AcknowledgeRequest) (*AcknowledgeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Acknowledge not implemented")
+}
 func (UnimplementedChangeStreamServer) StartBootstrap(context.Context, *StartBootstrapRequest) (*BootstrapResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method StartBootstrap not implemented")
 }
@@ -126,6 +163,9 @@ func (UnimplementedChangeStreamServer) CompleteBootstrap(context.Context, *Compl
 func (UnimplementedChangeStreamServer) GetStatus(context.Context, *GetStatusRequest) (*StatusResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
 }
+func (UnimplementedChangeStreamServer) GetState(context.Context, *GetStateRequest) (*StateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetState not implemented")
+}
 func (UnimplementedChangeStreamServer) mustEmbedUnimplementedChangeStreamServer() {}
 func (UnimplementedChangeStreamServer) testEmbeddedByValue()                      {}
 
@@ -158,6 +198,24 @@ func _ChangeStream_Stream_Handler(srv interface{}, stream grpc.ServerStream) err
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type ChangeStream_StreamServer = grpc.ServerStreamingServer[Change]
 
+func _ChangeStream_Acknowledge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AcknowledgeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChangeStreamServer).Acknowledge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChangeStream_Acknowledge_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChangeStreamServer).Acknowledge(ctx, req.(*AcknowledgeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ChangeStream_StartBootstrap_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(StartBootstrapRequest)
 	if err := dec(in); err != nil {
@@ -212,6 +270,24 @@ func _ChangeStream_GetStatus_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ChangeStream_GetState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChangeStreamServer).GetState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChangeStream_GetState_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChangeStreamServer).GetState(ctx, req.(*GetStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ChangeStream_ServiceDesc is the grpc.ServiceDesc for ChangeStream service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -219,6 +295,10 @@ var ChangeStream_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "change_stream.ChangeStream",
 	HandlerType: (*ChangeStreamServer)(nil),
 	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Acknowledge",
+			Handler:    _ChangeStream_Acknowledge_Handler,
+		},
 		{
 			MethodName: "StartBootstrap",
 			Handler:    _ChangeStream_StartBootstrap_Handler,
@@ -231,6 +311,10 @@ var ChangeStream_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetStatus",
 			Handler:    _ChangeStream_GetStatus_Handler,
 		},
+		{
+			MethodName: "GetState",
+			Handler:    _ChangeStream_GetState_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
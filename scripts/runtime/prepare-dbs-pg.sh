@@ -19,44 +19,28 @@ echo "Primary: ${PRIMARY_DATABASE_HOST}:${PRIMARY_DATABASE_PORT}/${PRIMARY_DATAB
 echo "Replica: ${REPLICA_DATABASE_HOST}:${REPLICA_DATABASE_PORT}/${REPLICA_DATABASE_DB}"
 echo ""
 
-# Step 1: Verify prerequisites
-echo "1. Verifying prerequisites..."
-if ! psql "$PRIMARY_SU_URL" -f "$PROJECT_ROOT/sql/setup/verify-prerequisites.sql"; then
-    echo "ERROR: Prerequisites check failed. Please ensure:"
-    echo "  - wal_level = logical in postgresql.conf"
-    echo "  - PostgreSQL has been restarted after configuration changes"
-    exit 1
-fi
-
-# Step 2: Create Kasho user on primary (read-only + replication)
-echo ""
-echo "2. Creating Kasho user on primary database..."
-if ! (eval $("$SCRIPT_DIR/parse-db-url.sh") && "$PROJECT_ROOT/bin/env-template" < "$PROJECT_ROOT/sql/setup/create-kasho-user-primary.sql.template") | psql "$PRIMARY_SU_URL"; then
+# Step 1: Create Kasho user on primary (read-only + replication)
+echo "1. Creating Kasho user on primary database..."
+if ! (eval $("$SCRIPT_DIR/parse-db-url.sh") && "$PROJECT_ROOT/bin/env-template" < "$PROJECT_ROOT/sql/pg/setup/create-kasho-user-primary.sql.template") | psql "$PRIMARY_SU_URL"; then
     echo "ERROR: Failed to create Kasho user on primary"
     exit 1
 fi
 
-# Step 3: Create Kasho user on replica (read-write)
+# Step 2: Create Kasho user on replica (read-write)
 echo ""
-echo "3. Creating Kasho user on replica database..."
-if ! (eval $("$SCRIPT_DIR/parse-db-url.sh") && "$PROJECT_ROOT/bin/env-template" < "$PROJECT_ROOT/sql/setup/create-kasho-user-replica.sql.template") | psql "$REPLICA_SU_URL"; then
+echo "2. Creating Kasho user on replica database..."
+if ! (eval $("$SCRIPT_DIR/parse-db-url.sh") && "$PROJECT_ROOT/bin/env-template" < "$PROJECT_ROOT/sql/pg/setup/create-kasho-user-replica.sql.template") | psql "$REPLICA_SU_URL"; then
     echo "ERROR: Failed to create Kasho user on replica"
     exit 1
 fi
 
-# Step 4: Set up DDL logging (before replication!)
+# Step 3: Set up DDL logging and the publication, and check the primary is ready
 echo ""
-echo "4. Setting up DDL logging..."
-if ! psql "$PRIMARY_SU_URL" -f "$PROJECT_ROOT/sql/setup/setup-ddl-logging.sql"; then
-    echo "ERROR: Failed to set up DDL logging"
-    exit 1
-fi
-
-# Step 5: Set up replication publication
-echo ""
-echo "5. Setting up replication publication..."
-if ! psql "$PRIMARY_SU_URL" -f "$PROJECT_ROOT/sql/setup/setup-replication.sql"; then
-    echo "ERROR: Failed to set up replication publication"
+echo "3. Setting up DDL logging and replication publication..."
+if ! "$PROJECT_ROOT/bin/kasho-setup-primary" --primary-url "$PRIMARY_SU_URL"; then
+    echo "ERROR: Primary is not ready. Please ensure:"
+    echo "  - wal_level = logical in postgresql.conf"
+    echo "  - PostgreSQL has been restarted after configuration changes"
     exit 1
 fi
 
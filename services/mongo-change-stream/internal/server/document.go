@@ -0,0 +1,303 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"kasho/pkg/types"
+	"kasho/proto"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// DocumentMode selects how documents map to columns
+type DocumentMode string
+
+const (
+	// DocumentModeFlatten maps each field to a column, naming nested fields by their
+	// dotted path ("address.city"). Arrays become JSON columns.
+	DocumentModeFlatten DocumentMode = "flatten"
+	// DocumentModeJSON maps the document key to columns and the whole document to a
+	// single JSON column named "document"
+	DocumentModeJSON DocumentMode = "json"
+)
+
+// documentColumn is the column holding the whole document in DocumentModeJSON
+const documentColumn = "document"
+
+// ParseDocumentMode parses a document mode name, defaulting to DocumentModeFlatten
+func ParseDocumentMode(mode string) (DocumentMode, error) {
+	switch DocumentMode(strings.ToLower(mode)) {
+	case "", DocumentModeFlatten:
+		return DocumentModeFlatten, nil
+	case DocumentModeJSON:
+		return DocumentModeJSON, nil
+	default:
+		return "", fmt.Errorf("unknown document mode %q (expected flatten or json)", mode)
+	}
+}
+
+// Namespace identifies a collection
+type Namespace struct {
+	DB   string `bson:"db"`
+	Coll string `bson:"coll"`
+}
+
+// ChangeEvent is the part of a change stream event we replicate
+type ChangeEvent struct {
+	OperationType string         `bson:"operationType"`
+	ClusterTime   bson.Timestamp `bson:"clusterTime"`
+	WallTime      time.Time      `bson:"wallTime"`
+	Namespace     Namespace      `bson:"ns"`
+	To            Namespace      `bson:"to"`
+	DocumentKey   bson.D         `bson:"documentKey"`
+	FullDocument  bson.D         `bson:"fullDocument"`
+	TxnNumber     *int64         `bson:"txnNumber"`
+	LSID          struct {
+		ID bson.Binary `bson:"id"`
+	} `bson:"lsid"`
+}
+
+// eventTime returns when the event was committed: the wall time where the server
+// reports it (MongoDB 6.0+), otherwise the second of its cluster time
+func (e *ChangeEvent) eventTime() time.Time {
+	if !e.WallTime.IsZero() {
+		return e.WallTime
+	}
+	return time.Unix(int64(e.ClusterTime.T), 0)
+}
+
+// transactionID identifies the multi-document transaction of an event by its session
+// and transaction number; it is empty outside transactions
+func (e *ChangeEvent) transactionID() string {
+	if e.TxnNumber == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", formatUUID(e.LSID.ID.Data), *e.TxnNumber)
+}
+
+// EventToChange converts a change stream event to a Change. It returns nil for events
+// that aren't replicated. Collections map to tables and databases to schemas.
+func EventToChange(e *ChangeEvent, position string, mode DocumentMode) *types.Change {
+	var data interface{ Type() string }
+	switch e.OperationType {
+	case "insert", "replace":
+		kind := "insert"
+		if e.OperationType == "replace" {
+			kind = "update"
+		}
+		data = documentDML(e, kind, e.FullDocument, mode)
+	case "update":
+		// The lookup finds nothing when the document was deleted since; its delete
+		// event follows, so the update can be skipped
+		if e.FullDocument == nil {
+			return nil
+		}
+		data = documentDML(e, "update", e.FullDocument, mode)
+	case "delete":
+		keyNames, keyValues := flattenDocument(e.DocumentKey)
+		data = &types.DMLData{
+			Table:        e.Namespace.Coll,
+			Kind:         "delete",
+			ColumnNames:  []string{},
+			ColumnValues: []types.ColumnValueWrapper{},
+			OldKeys: &struct {
+				KeyNames  []string                   `json:"keynames"`
+				KeyValues []types.ColumnValueWrapper `json:"keyvalues"`
+			}{KeyNames: keyNames, KeyValues: keyValues},
+			PrimaryKey: keyNames,
+			Schema:     e.Namespace.DB,
+		}
+	case "drop":
+		data = collectionDDL(e, "DROP TABLE IF EXISTS "+quoteIdentifier(e.Namespace.Coll))
+	case "rename":
+		data = collectionDDL(e, "ALTER TABLE "+quoteIdentifier(e.Namespace.Coll)+" RENAME TO "+quoteIdentifier(e.To.Coll))
+	case "dropDatabase":
+		// The drop event of each collection precedes it
+		return nil
+	default:
+		log.Printf("Skipping %s event on %s.%s", e.OperationType, e.Namespace.DB, e.Namespace.Coll)
+		return nil
+	}
+
+	return &types.Change{
+		Position:      position,
+		Data:          data,
+		Dialect:       "mongodb",
+		Database:      e.Namespace.DB,
+		Schema:        e.Namespace.DB,
+		TransactionID: e.transactionID(),
+		CommitTime:    e.eventTime(),
+	}
+}
+
+// documentDML builds an insert or update of a whole document, keyed by its document key
+func documentDML(e *ChangeEvent, kind string, document bson.D, mode DocumentMode) *types.DMLData {
+	keyNames, keyValues := flattenDocument(e.DocumentKey)
+	dml := &types.DMLData{
+		Table:      e.Namespace.Coll,
+		Kind:       kind,
+		PrimaryKey: keyNames,
+		Schema:     e.Namespace.DB,
+	}
+
+	if mode == DocumentModeJSON {
+		dml.ColumnNames = slices.Concat(keyNames, []string{documentColumn})
+		dml.ColumnValues = slices.Concat(keyValues, []types.ColumnValueWrapper{jsonColumnValue(document)})
+	} else {
+		dml.ColumnNames, dml.ColumnValues = flattenDocument(document)
+	}
+
+	if kind == "update" {
+		dml.OldKeys = &struct {
+			KeyNames  []string                   `json:"keynames"`
+			KeyValues []types.ColumnValueWrapper `json:"keyvalues"`
+		}{KeyNames: keyNames, KeyValues: keyValues}
+	}
+	return dml
+}
+
+// collectionDDL builds a DDL change for a collection
+func collectionDDL(e *ChangeEvent, ddl string) types.DDLData {
+	return types.DDLData{
+		ID:       0, // MongoDB doesn't have a DDL ID like PostgreSQL
+		Time:     e.eventTime(),
+		Username: "", // Not available from change streams
+		Database: e.Namespace.DB,
+		DDL:      ddl,
+	}
+}
+
+// flattenDocument maps a document to columns, naming the fields of embedded documents
+// by their dotted path
+func flattenDocument(document bson.D) ([]string, []types.ColumnValueWrapper) {
+	names := make([]string, 0, len(document))
+	values := make([]types.ColumnValueWrapper, 0, len(document))
+	var walk func(prefix string, document bson.D)
+	walk = func(prefix string, document bson.D) {
+		for _, field := range document {
+			name := prefix + field.Key
+			if embedded, ok := field.Value.(bson.D); ok && len(embedded) > 0 {
+				walk(name+".", embedded)
+				continue
+			}
+			names = append(names, name)
+			values = append(values, toColumnValue(field.Value))
+		}
+	}
+	walk("", document)
+	return names, values
+}
+
+// toColumnValue converts a BSON value to our ColumnValueWrapper
+func toColumnValue(value any) types.ColumnValueWrapper {
+	switch v := value.(type) {
+	case nil, bson.Null, bson.Undefined:
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{}}
+	case string:
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: v}}}
+	case int32:
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: int64(v)}}}
+	case int64:
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: v}}}
+	case float64:
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_FloatValue{FloatValue: v}}}
+	case bool:
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_BoolValue{BoolValue: v}}}
+	case bson.ObjectID:
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: v.Hex()}}}
+	case bson.Decimal128:
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: v.String()}}}
+	case bson.DateTime:
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: v.Time().UTC().Format(time.RFC3339Nano)}}}
+	case bson.Timestamp:
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: time.Unix(int64(v.T), 0).UTC().Format(time.RFC3339Nano)}}}
+	case bson.Binary:
+		if isUUID(v) {
+			return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_UuidValue{UuidValue: formatUUID(v.Data)}}}
+		}
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: v.Data}}}
+	case []byte:
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: v}}}
+	case bson.D, bson.A:
+		return jsonColumnValue(v)
+	default:
+		// For any other type (regular expressions, JavaScript, min/max keys), convert to string
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: fmt.Sprint(v)}}}
+	}
+}
+
+// jsonColumnValue encodes a document or array as a JSON column value
+func jsonColumnValue(value any) types.ColumnValueWrapper {
+	data, err := json.Marshal(plainValue(value))
+	if err != nil {
+		log.Printf("Failed to encode document as JSON: %v", err)
+		data = []byte("null")
+	}
+	return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_JsonValue{JsonValue: string(data)}}}
+}
+
+// plainValue converts a BSON value to its plain JSON form: object IDs and decimals as
+// strings, dates as RFC 3339 timestamps and binary data as base64
+func plainValue(value any) any {
+	switch v := value.(type) {
+	case bson.D:
+		object := make(map[string]any, len(v))
+		for _, field := range v {
+			object[field.Key] = plainValue(field.Value)
+		}
+		return object
+	case bson.A:
+		array := make([]any, len(v))
+		for i, element := range v {
+			array[i] = plainValue(element)
+		}
+		return array
+	case nil, bson.Null, bson.Undefined:
+		return nil
+	case string, bool, int32, int64, float64:
+		return v
+	case bson.ObjectID:
+		return v.Hex()
+	case bson.Decimal128:
+		return v.String()
+	case bson.DateTime:
+		return v.Time().UTC().Format(time.RFC3339Nano)
+	case bson.Timestamp:
+		return time.Unix(int64(v.T), 0).UTC().Format(time.RFC3339Nano)
+	case bson.Binary:
+		if isUUID(v) {
+			return formatUUID(v.Data)
+		}
+		return base64.StdEncoding.EncodeToString(v.Data)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// isUUID reports whether binary data holds a standard UUID (subtype 4)
+func isUUID(v bson.Binary) bool {
+	return v.Subtype == 0x04 && len(v.Data) == 16
+}
+
+// formatUUID formats 16 bytes in the canonical 8-4-4-4-12 form; other lengths are
+// returned as hex
+func formatUUID(b []byte) string {
+	if len(b) != 16 {
+		return hex.EncodeToString(b)
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// quoteIdentifier quotes a collection name for use as a table name in DDL
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"kasho/pkg/types"
+	"kasho/proto"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var testObjectID = bson.ObjectID{0x65, 0x0a, 0x1f, 0x2b, 0x3c, 0x4d, 0x5e, 0x6f, 0x70, 0x81, 0x92, 0xa3}
+
+func testEvent(operationType string) *ChangeEvent {
+	return &ChangeEvent{
+		OperationType: operationType,
+		WallTime:      time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		Namespace:     Namespace{DB: "shop", Coll: "customers"},
+		DocumentKey:   bson.D{{Key: "_id", Value: testObjectID}},
+		FullDocument: bson.D{
+			{Key: "_id", Value: testObjectID},
+			{Key: "name", Value: "Ada"},
+			{Key: "visits", Value: int32(3)},
+			{Key: "address", Value: bson.D{{Key: "city", Value: "London"}, {Key: "zip", Value: nil}}},
+			{Key: "tags", Value: bson.A{"vip", int64(7)}},
+			{Key: "joined", Value: bson.NewDateTimeFromTime(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))},
+		},
+	}
+}
+
+func stringValue(v string) types.ColumnValueWrapper {
+	return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: v}}}
+}
+
+func TestEventToChange_Flatten(t *testing.T) {
+	change := EventToChange(testEvent("insert"), "mongo:0000000000000001:82", DocumentModeFlatten)
+	if change == nil {
+		t.Fatal("EventToChange() returned nil")
+	}
+	if change.Dialect != "mongodb" || change.Database != "shop" || change.Schema != "shop" {
+		t.Errorf("source metadata = %q, %q, %q, want mongodb, shop, shop", change.Dialect, change.Database, change.Schema)
+	}
+	if !change.CommitTime.Equal(time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("CommitTime = %v, want the event's wall time", change.CommitTime)
+	}
+
+	dml, ok := change.Data.(*types.DMLData)
+	if !ok {
+		t.Fatalf("Data = %T, want *types.DMLData", change.Data)
+	}
+	if dml.Table != "customers" || dml.Kind != "insert" || dml.OldKeys != nil {
+		t.Errorf("dml = table %q, kind %q, old keys %v, want customers, insert and none", dml.Table, dml.Kind, dml.OldKeys)
+	}
+	if want := []string{"_id"}; !reflect.DeepEqual(dml.PrimaryKey, want) {
+		t.Errorf("PrimaryKey = %v, want %v", dml.PrimaryKey, want)
+	}
+
+	wantNames := []string{"_id", "name", "visits", "address.city", "address.zip", "tags", "joined"}
+	if !reflect.DeepEqual(dml.ColumnNames, wantNames) {
+		t.Fatalf("ColumnNames = %v, want %v", dml.ColumnNames, wantNames)
+	}
+	wantValues := []*proto.ColumnValue{
+		{Value: &proto.ColumnValue_StringValue{StringValue: "650a1f2b3c4d5e6f708192a3"}},
+		{Value: &proto.ColumnValue_StringValue{StringValue: "Ada"}},
+		{Value: &proto.ColumnValue_IntValue{IntValue: 3}},
+		{Value: &proto.ColumnValue_StringValue{StringValue: "London"}},
+		{},
+		{Value: &proto.ColumnValue_JsonValue{JsonValue: `["vip",7]`}},
+		{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2024-01-02T03:04:05Z"}},
+	}
+	for i, want := range wantValues {
+		if got := dml.ColumnValues[i].ColumnValue; !reflect.DeepEqual(got.Value, want.Value) {
+			t.Errorf("ColumnValues[%d] = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestEventToChange_JSON(t *testing.T) {
+	change := EventToChange(testEvent("replace"), "mongo:0000000000000002:82", DocumentModeJSON)
+	if change == nil {
+		t.Fatal("EventToChange() returned nil")
+	}
+	dml := change.Data.(*types.DMLData)
+	if dml.Kind != "update" {
+		t.Errorf("Kind = %q, want update", dml.Kind)
+	}
+	if want := []string{"_id", "document"}; !reflect.DeepEqual(dml.ColumnNames, want) {
+		t.Fatalf("ColumnNames = %v, want %v", dml.ColumnNames, want)
+	}
+	wantDocument := `{"_id":"650a1f2b3c4d5e6f708192a3","address":{"city":"London","zip":null},"joined":"2024-01-02T03:04:05Z","name":"Ada","tags":["vip",7],"visits":3}`
+	if got := dml.ColumnValues[1].GetJsonValue(); got != wantDocument {
+		t.Errorf("document = %s, want %s", got, wantDocument)
+	}
+	if dml.OldKeys == nil || !reflect.DeepEqual(dml.OldKeys.KeyValues, []types.ColumnValueWrapper{stringValue("650a1f2b3c4d5e6f708192a3")}) {
+		t.Errorf("OldKeys = %v, want the document key", dml.OldKeys)
+	}
+}
+
+func TestEventToChange_Operations(t *testing.T) {
+	t.Run("delete", func(t *testing.T) {
+		event := testEvent("delete")
+		event.FullDocument = nil
+		dml := EventToChange(event, "mongo:0000000000000003:82", DocumentModeFlatten).Data.(*types.DMLData)
+		if dml.Kind != "delete" || len(dml.ColumnNames) != 0 {
+			t.Errorf("dml = kind %q with %d columns, want delete with none", dml.Kind, len(dml.ColumnNames))
+		}
+		if dml.OldKeys == nil || !reflect.DeepEqual(dml.OldKeys.KeyNames, []string{"_id"}) {
+			t.Errorf("OldKeys = %v, want _id", dml.OldKeys)
+		}
+	})
+
+	t.Run("update of a deleted document", func(t *testing.T) {
+		event := testEvent("update")
+		event.FullDocument = nil
+		if change := EventToChange(event, "mongo:0000000000000004:82", DocumentModeFlatten); change != nil {
+			t.Errorf("EventToChange() = %v, want nil", change)
+		}
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		change := EventToChange(testEvent("drop"), "mongo:0000000000000005:82", DocumentModeFlatten)
+		if ddl := change.Data.(types.DDLData); ddl.DDL != `DROP TABLE IF EXISTS "customers"` || ddl.Database != "shop" {
+			t.Errorf("ddl = %q in %q, want drop of customers in shop", ddl.DDL, ddl.Database)
+		}
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		event := testEvent("rename")
+		event.To = Namespace{DB: "shop", Coll: "clients"}
+		change := EventToChange(event, "mongo:0000000000000006:82", DocumentModeFlatten)
+		if ddl := change.Data.(types.DDLData); ddl.DDL != `ALTER TABLE "customers" RENAME TO "clients"` {
+			t.Errorf("ddl = %q, want rename of customers to clients", ddl.DDL)
+		}
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		event := testEvent("insert")
+		txnNumber := int64(5)
+		event.TxnNumber = &txnNumber
+		event.LSID.ID = bson.Binary{Subtype: 0x04, Data: []byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0}}
+		change := EventToChange(event, "mongo:0000000000000007:82", DocumentModeFlatten)
+		if want := "12345678-9abc-def0-1234-56789abcdef0:5"; change.TransactionID != want {
+			t.Errorf("TransactionID = %q, want %q", change.TransactionID, want)
+		}
+	})
+
+	t.Run("unreplicated event", func(t *testing.T) {
+		if change := EventToChange(testEvent("createIndexes"), "mongo:0000000000000008:82", DocumentModeFlatten); change != nil {
+			t.Errorf("EventToChange() = %v, want nil", change)
+		}
+	})
+}
+
+func TestToColumnValue(t *testing.T) {
+	decimal, err := bson.ParseDecimal128("12.50")
+	if err != nil {
+		t.Fatalf("ParseDecimal128() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		value any
+		want  *proto.ColumnValue
+	}{
+		{"double", 2.5, &proto.ColumnValue{Value: &proto.ColumnValue_FloatValue{FloatValue: 2.5}}},
+		{"bool", true, &proto.ColumnValue{Value: &proto.ColumnValue_BoolValue{BoolValue: true}}},
+		{"null", bson.Null{}, &proto.ColumnValue{}},
+		{"decimal", decimal, &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: "12.50"}}},
+		{
+			"uuid",
+			bson.Binary{Subtype: 0x04, Data: []byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0}},
+			&proto.ColumnValue{Value: &proto.ColumnValue_UuidValue{UuidValue: "12345678-9abc-def0-1234-56789abcdef0"}},
+		},
+		{"binary", bson.Binary{Data: []byte{0x01, 0x02}}, &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: []byte{0x01, 0x02}}}},
+		{"empty document", bson.D{}, &proto.ColumnValue{Value: &proto.ColumnValue_JsonValue{JsonValue: "{}"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toColumnValue(tt.value).ColumnValue; !reflect.DeepEqual(got.Value, tt.want.Value) {
+				t.Errorf("toColumnValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDocumentMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    DocumentMode
+		wantErr bool
+	}{
+		{"", DocumentModeFlatten, false},
+		{"flatten", DocumentModeFlatten, false},
+		{"JSON", DocumentModeJSON, false},
+		{"nested", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDocumentMode(tt.mode)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDocumentMode(%q) error = %v, wantErr %v", tt.mode, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDocumentMode(%q) = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+}
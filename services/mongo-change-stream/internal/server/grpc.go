@@ -0,0 +1,381 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"kasho/pkg/auth"
+	"kasho/pkg/kvbuffer"
+	"kasho/pkg/streamserver"
+	"kasho/pkg/types"
+	"kasho/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+type ChangeStreamServer struct {
+	proto.UnimplementedChangeStreamServer
+	buffer           *kvbuffer.KVBuffer
+	state            *StateInfo
+	stateMu          sync.RWMutex
+	connectedClients int32
+	clientsMu        sync.Mutex
+	startTime        time.Time
+	authenticator    *auth.Authenticator
+
+	// Server-wide pacing limits for the Stream RPC; zero means unlimited
+	maxChangesPerSecond uint32
+	maxBytesPerSecond   uint64
+	maxInFlightBytes    uint64
+	streams             streamserver.Streams // open streams with an in-flight limit
+
+	heartbeatInterval time.Duration
+	currentPosition   string
+	positionMu        sync.RWMutex
+
+	accumulationLimits streamserver.AccumulationLimits
+	accumulatedBytes   int64 // guarded by stateMu
+}
+
+func NewChangeStreamServer(buffer *kvbuffer.KVBuffer) *ChangeStreamServer {
+	return &ChangeStreamServer{
+		buffer:    buffer,
+		startTime: time.Now(),
+		state: &StateInfo{
+			Current:        StateWaiting,
+			TransitionTime: time.Now(),
+		},
+	}
+}
+
+// SetAuthenticator enables token authentication on the Stream RPC
+func (s *ChangeStreamServer) SetAuthenticator(authenticator *auth.Authenticator) {
+	s.authenticator = authenticator
+}
+
+// SetStreamLimits sets the maximum pacing limits applied to every stream.
+// Consumers may request lower limits but cannot exceed these.
+func (s *ChangeStreamServer) SetStreamLimits(changesPerSecond uint32, bytesPerSecond, inFlightBytes uint64) {
+	s.maxChangesPerSecond = changesPerSecond
+	s.maxBytesPerSecond = bytesPerSecond
+	s.maxInFlightBytes = inFlightBytes
+}
+
+// SetHeartbeatInterval sets how often idle streams receive a heartbeat; zero disables heartbeats
+func (s *ChangeStreamServer) SetHeartbeatInterval(interval time.Duration) {
+	s.heartbeatInterval = interval
+}
+
+// SetCurrentPosition records the latest position read from the source database
+func (s *ChangeStreamServer) SetCurrentPosition(position string) {
+	s.positionMu.Lock()
+	defer s.positionMu.Unlock()
+	s.currentPosition = position
+}
+
+// GetCurrentPosition returns the latest position read from the source database
+func (s *ChangeStreamServer) GetCurrentPosition() string {
+	s.positionMu.RLock()
+	defer s.positionMu.RUnlock()
+	return s.currentPosition
+}
+
+// SetState sets the state
+func (s *ChangeStreamServer) SetState(state *StateInfo) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	s.state = state
+}
+
+// CurrentState returns the current state
+func (s *ChangeStreamServer) CurrentState() State {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+	return s.state.Current
+}
+
+// GetStartPosition returns the saved start position from bootstrap
+func (s *ChangeStreamServer) GetStartPosition() string {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+	return s.state.StartPosition
+}
+
+// IncrementAccumulated increments the accumulated change count
+func (s *ChangeStreamServer) IncrementAccumulated() {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	s.state.AccumulatedChanges++
+}
+
+func (s *ChangeStreamServer) Stream(req *proto.StreamRequest, stream proto.ChangeStream_StreamServer) error {
+	token, err := streamserver.Authenticate(stream.Context(), s.authenticator)
+	if err != nil {
+		return err
+	}
+
+	// Send heartbeats on idle streams so consumers can detect dead connections
+	var heartbeats <-chan time.Time
+	if s.heartbeatInterval > 0 {
+		ticker := time.NewTicker(s.heartbeatInterval)
+		defer ticker.Stop()
+		heartbeats = ticker.C
+	}
+	lastSent := time.Now()
+
+	filter, err := streamserver.NewFilter(req)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Pace the stream so bulk replays don't saturate the network or run far ahead of
+	// the consumer. The stream's ID goes out before the first heartbeat.
+	changesPerSecond := streamserver.EffectiveLimit(s.maxChangesPerSecond, req.MaxChangesPerSecond)
+	bytesPerSecond := streamserver.EffectiveLimit(s.maxBytesPerSecond, req.MaxBytesPerSecond)
+	inFlightBytes := streamserver.EffectiveLimit(s.maxInFlightBytes, req.MaxInFlightBytes)
+	if changesPerSecond > 0 || bytesPerSecond > 0 || inFlightBytes > 0 {
+		log.Printf("Pacing stream to %d changes/s, %d bytes/s and %d bytes in flight (0 = unlimited)", changesPerSecond, bytesPerSecond, inFlightBytes)
+	}
+	streamPacer := streamserver.NewPacer(changesPerSecond, bytesPerSecond, inFlightBytes)
+	closeStream, err := s.streams.Open(stream, streamPacer)
+	if err != nil {
+		return err
+	}
+	defer closeStream()
+
+	// Check if we're in streaming state
+	s.stateMu.RLock()
+	currentState := s.state.Current
+	s.stateMu.RUnlock()
+
+	if currentState != StateStreaming {
+		// Block until we're in streaming state
+		for {
+			s.stateMu.RLock()
+			if s.state.Current == StateStreaming {
+				s.stateMu.RUnlock()
+				break
+			}
+			s.stateMu.RUnlock()
+
+			select {
+			case <-stream.Context().Done():
+				return stream.Context().Err()
+			case <-heartbeats:
+				if err := stream.Send(streamserver.NewHeartbeat(s.GetCurrentPosition())); err != nil {
+					return err
+				}
+				lastSent = time.Now()
+			case <-time.After(100 * time.Millisecond):
+				// Check again
+			}
+		}
+	}
+
+	// Track connected clients
+	s.clientsMu.Lock()
+	s.connectedClients++
+	s.clientsMu.Unlock()
+	defer func() {
+		s.clientsMu.Lock()
+		s.connectedClients--
+		s.clientsMu.Unlock()
+	}()
+
+	// Send buffered changes first in batches
+	if req.LastPosition != "" {
+		const batchSize = 1000
+		offset := int64(0)
+
+		for {
+			rawChanges, err := s.buffer.GetChangesAfterBatch(stream.Context(), req.LastPosition, offset, batchSize)
+			if err != nil {
+				return fmt.Errorf("failed to get buffered changes: %w", err)
+			}
+
+			// Send this batch
+			for _, rawChange := range rawChanges {
+				var change types.Change
+				if err := json.Unmarshal(rawChange, &change); err != nil {
+					log.Printf("Error unmarshaling buffered change: %v", err)
+					continue
+				}
+				if !streamserver.ChangeAllowed(token, change) || !filter.Allows(change) {
+					continue
+				}
+
+				protoChange := streamserver.ConvertChange(change)
+				if err := streamPacer.Wait(stream.Context(), protoChange.Position, protobuf.Size(protoChange)); err != nil {
+					return err
+				}
+				if err := stream.Send(protoChange); err != nil {
+					return err
+				}
+			}
+
+			// If we got fewer than batchSize results, we're done
+			if len(rawChanges) < batchSize {
+				break
+			}
+
+			offset += batchSize
+		}
+	}
+
+	// Subscribe to new changes
+	pubsub := s.buffer.Subscribe(stream.Context(), "kasho:changes")
+	defer pubsub.Close()
+
+	// Keep the connection open and wait for new changes
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-heartbeats:
+			if time.Since(lastSent) < s.heartbeatInterval {
+				continue
+			}
+			if err := stream.Send(streamserver.NewHeartbeat(s.GetCurrentPosition())); err != nil {
+				return err
+			}
+			lastSent = time.Now()
+		case msg := <-pubsub.Channel():
+			var change types.Change
+			if err := json.Unmarshal([]byte(msg.Payload), &change); err != nil {
+				log.Printf("Error unmarshaling change: %v", err)
+				continue
+			}
+			if !streamserver.ChangeAllowed(token, change) || !filter.Allows(change) {
+				continue
+			}
+
+			protoChange := streamserver.ConvertChange(change)
+			if err := streamPacer.Wait(stream.Context(), protoChange.Position, protobuf.Size(protoChange)); err != nil {
+				return err
+			}
+			if err := stream.Send(protoChange); err != nil {
+				return err
+			}
+			lastSent = time.Now()
+		}
+	}
+}
+
+// Acknowledge releases the changes a consumer is done with from its stream's in-flight limit
+func (s *ChangeStreamServer) Acknowledge(ctx context.Context, req *proto.AcknowledgeRequest) (*proto.AcknowledgeResponse, error) {
+	if _, err := streamserver.Authenticate(ctx, s.authenticator); err != nil {
+		return nil, err
+	}
+	if err := s.streams.Acknowledge(req.StreamId, req.Position); err != nil {
+		return nil, err
+	}
+	return &proto.AcknowledgeResponse{}, nil
+}
+
+// StartBootstrap begins the accumulation phase for bootstrap
+func (s *ChangeStreamServer) StartBootstrap(ctx context.Context, req *proto.StartBootstrapRequest) (*proto.BootstrapResponse, error) {
+	// Accumulation limits count what the buffer gains from here
+	startDepth, startBytes := streamserver.MeasureBuffer(ctx, s.buffer)
+
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	// Validate we're in WAITING state
+	if s.state.Current != StateWaiting {
+		return &proto.BootstrapResponse{
+			Status:        "error",
+			PreviousState: s.state.Current.String(),
+			CurrentState:  s.state.Current.String(),
+		}, fmt.Errorf("can only start bootstrap from WAITING state, current state: %s", s.state.Current)
+	}
+
+	// Transition to ACCUMULATING
+	s.state.Current = StateAccumulating
+	s.state.StartPosition = req.StartPosition
+	s.state.TransitionTime = time.Now()
+	s.state.AccumulatedChanges = 0
+	s.state.StartDepth = startDepth
+	s.state.StartBytes = startBytes
+	s.accumulatedBytes = 0
+
+	if err := s.SaveState(ctx, s.state); err != nil {
+		// Rollback
+		s.state.Current = StateWaiting
+		return nil, fmt.Errorf("failed to save state: %w", err)
+	}
+
+	return &proto.BootstrapResponse{
+		Status:             "started",
+		PreviousState:      "WAITING",
+		CurrentState:       "ACCUMULATING",
+		AccumulatedChanges: 0,
+		ReadyToStream:      false,
+	}, nil
+}
+
+// CompleteBootstrap transitions from accumulating to streaming
+func (s *ChangeStreamServer) CompleteBootstrap(ctx context.Context, req *proto.CompleteBootstrapRequest) (*proto.BootstrapResponse, error) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	// Validate we're in ACCUMULATING state
+	if s.state.Current != StateAccumulating {
+		return &proto.BootstrapResponse{
+			Status:        "error",
+			PreviousState: s.state.Current.String(),
+			CurrentState:  s.state.Current.String(),
+		}, fmt.Errorf("can only complete bootstrap from ACCUMULATING state, current state: %s", s.state.Current)
+	}
+
+	// Transition to STREAMING
+	previousState := s.state.Current.String()
+	s.state.Current = StateStreaming
+	s.state.TransitionTime = time.Now()
+
+	if err := s.SaveState(ctx, s.state); err != nil {
+		// Rollback
+		s.state.Current = StateAccumulating
+		return nil, fmt.Errorf("failed to save state: %w", err)
+	}
+
+	return &proto.BootstrapResponse{
+		Status:             "completed",
+		PreviousState:      previousState,
+		CurrentState:       "STREAMING",
+		AccumulatedChanges: s.state.AccumulatedChanges,
+		ReadyToStream:      true,
+	}, nil
+}
+
+// GetStatus returns the current status of the change stream
+func (s *ChangeStreamServer) GetStatus(ctx context.Context, req *proto.GetStatusRequest) (*proto.StatusResponse, error) {
+	s.stateMu.RLock()
+	currentState := s.state.Current.String()
+	startPosition := s.state.StartPosition
+	accumulated := s.state.AccumulatedChanges
+	s.stateMu.RUnlock()
+
+	s.clientsMu.Lock()
+	clients := s.connectedClients
+	s.clientsMu.Unlock()
+
+	uptime := int64(time.Since(s.startTime).Seconds())
+
+	currentPosition := s.GetCurrentPosition()
+
+	return &proto.StatusResponse{
+		State:              currentState,
+		StartPosition:      startPosition,
+		CurrentPosition:    currentPosition,
+		AccumulatedChanges: accumulated,
+		ConnectedClients:   clients,
+		UptimeSeconds:      uptime,
+		DuplicateChanges:   s.buffer.Duplicates(),
+	}, nil
+}
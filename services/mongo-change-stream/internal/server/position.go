@@ -0,0 +1,40 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// positionPrefix marks MongoDB positions. Resume tokens are opaque and changes in one
+// transaction share a cluster time, so neither orders changes; positions carry a
+// sequence number assigned as changes are read, followed by the resume token.
+const positionPrefix = "mongo:"
+
+// FormatPosition converts a sequence number and resume token to our string format
+// Format: "mongo:0000000000000042:8263A1..." (sequence:resume token data)
+func FormatPosition(seq uint64, token string) string {
+	return fmt.Sprintf("%s%016d:%s", positionPrefix, seq, token)
+}
+
+// ParsePosition parses our position string format back to a sequence number and
+// resume token. Empty and "bootstrap" positions have neither.
+func ParsePosition(position string) (uint64, string, error) {
+	if position == "" || position == "bootstrap" {
+		return 0, "", nil
+	}
+
+	rest, ok := strings.CutPrefix(position, positionPrefix)
+	if !ok {
+		return 0, "", fmt.Errorf("invalid position format: %s", position)
+	}
+	seqStr, token, ok := strings.Cut(rest, ":")
+	if !ok || token == "" {
+		return 0, "", fmt.Errorf("invalid position format: %s", position)
+	}
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid position sequence: %s", seqStr)
+	}
+	return seq, token, nil
+}
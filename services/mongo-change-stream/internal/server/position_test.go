@@ -0,0 +1,45 @@
+package server
+
+import "testing"
+
+func TestFormatPosition(t *testing.T) {
+	got := FormatPosition(42, "8263A1B2C3000000012B")
+	if want := "mongo:0000000000000042:8263A1B2C3000000012B"; got != want {
+		t.Errorf("FormatPosition() = %v, want %v", got, want)
+	}
+}
+
+func TestParsePosition(t *testing.T) {
+	tests := []struct {
+		name      string
+		position  string
+		wantSeq   uint64
+		wantToken string
+		wantErr   bool
+	}{
+		{
+			name:      "valid position",
+			position:  "mongo:0000000000000042:8263A1B2C3000000012B",
+			wantSeq:   42,
+			wantToken: "8263A1B2C3000000012B",
+		},
+		{name: "empty position", position: ""},
+		{name: "bootstrap position", position: "bootstrap"},
+		{name: "binlog position", position: "mysql-bin.000001:4", wantErr: true},
+		{name: "missing resume token", position: "mongo:0000000000000042", wantErr: true},
+		{name: "invalid sequence", position: "mongo:abc:8263A1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seq, token, err := ParsePosition(tt.position)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePosition() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if seq != tt.wantSeq || token != tt.wantToken {
+				t.Errorf("ParsePosition() = %v, %q, want %v, %q", seq, token, tt.wantSeq, tt.wantToken)
+			}
+		})
+	}
+}
@@ -0,0 +1,401 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"kasho/pkg/types"
+
+	_ "github.com/microsoft/go-mssqldb"
+)
+
+const (
+	maxBackoff = 30 * time.Second
+)
+
+// afterTransaction is a sequence value past every change of a transaction, so polling
+// continues with the next transaction
+var afterTransaction = bytes.Repeat([]byte{0xFF}, lsnLength)
+
+// Client polls the SQL Server change data capture tables
+type Client struct {
+	db           *sql.DB
+	dbURL        string
+	schemas      []string // schemas to capture; empty captures every schema
+	pollInterval time.Duration
+	done         chan struct{}
+	mu           sync.Mutex
+	seq          uint64 // sequence number of the last change read
+	lsn          []byte // commit LSN of the last change read, nil before the first poll
+	seqval       []byte // sequence value of the last change read
+	started      bool
+	known        map[string]bool // capture instances read by earlier polls
+	changeChan   chan types.Change
+	wg           sync.WaitGroup // tracks the polling goroutine
+}
+
+// NewClient creates a new SQL Server CDC client
+// startPosition is the position to resume after (e.g., "mssql:0000000000000042:0000002A000001300003:0000002A000001300002").
+// If empty, the client will start from the current maximum LSN.
+// schemas lists the schemas to capture; if empty, every CDC-enabled table is captured.
+func NewClient(ctx context.Context, dbURL string, schemas []string, pollInterval time.Duration, startPosition string) (*Client, error) {
+	seq, lsn, seqval, err := ParsePosition(startPosition)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start position %q: %w", startPosition, err)
+	}
+
+	client := &Client{
+		dbURL:        dbURL,
+		schemas:      schemas,
+		pollInterval: pollInterval,
+		done:         make(chan struct{}),
+		seq:          seq,
+		lsn:          lsn,
+		seqval:       seqval,
+		started:      lsn != nil,
+		known:        make(map[string]bool),
+		changeChan:   make(chan types.Change, 1000),
+	}
+
+	if err := client.ConnectWithRetry(ctx); err != nil {
+		return nil, err
+	}
+	if lsn != nil {
+		log.Printf("Resuming from LSN %s", FormatLSN(lsn))
+	}
+
+	client.wg.Add(1)
+	go client.run(ctx)
+
+	return client, nil
+}
+
+func (c *Client) Connect(ctx context.Context) error {
+	db, err := sql.Open("sqlserver", c.dbURL)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to ping: %w", err)
+	}
+
+	var enabled bool
+	if err := db.QueryRowContext(ctx, "SELECT is_cdc_enabled FROM sys.databases WHERE name = DB_NAME()").Scan(&enabled); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to check change data capture: %w", err)
+	}
+	if !enabled {
+		db.Close()
+		return fmt.Errorf("change data capture is not enabled on the database (run sys.sp_cdc_enable_db)")
+	}
+
+	c.mu.Lock()
+	c.db = db
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Client) ConnectWithRetry(ctx context.Context) error {
+	backoff := time.Second
+	for {
+		log.Printf("Connecting to SQL Server database...")
+		err := c.Connect(ctx)
+		if err == nil {
+			log.Printf("Successfully connected to SQL Server")
+			return nil
+		}
+
+		log.Printf("Connection failed: %v", err)
+		log.Printf("Retrying in %v...", backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+	}
+}
+
+// run polls the change tables until the client is closed
+func (c *Client) run(ctx context.Context) {
+	defer c.wg.Done()
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.poll(ctx); err != nil {
+			log.Printf("Error polling change tables: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll reads the changes committed since the last poll from every captured table and
+// sends them in commit order
+func (c *Client) poll(ctx context.Context) error {
+	var maxLSN []byte
+	if err := c.db.QueryRowContext(ctx, "SELECT sys.fn_cdc_get_max_lsn()").Scan(&maxLSN); err != nil {
+		return fmt.Errorf("failed to get max LSN: %w", err)
+	}
+
+	c.mu.Lock()
+	fromLSN, afterSeqval := c.lsn, c.seqval
+	if !c.started {
+		// Start from the current position; nil reads everything captured, of which
+		// there is nothing yet
+		fromLSN, afterSeqval = maxLSN, afterTransaction
+		c.lsn, c.seqval, c.started = maxLSN, afterTransaction, true
+		if maxLSN != nil {
+			log.Printf("Starting from current LSN %s", FormatLSN(maxLSN))
+		}
+	}
+	c.mu.Unlock()
+
+	// Nothing was captured yet, or nothing since the last poll
+	if maxLSN == nil || (fromLSN != nil && bytes.Compare(maxLSN, fromLSN) < 0) ||
+		(bytes.Equal(maxLSN, fromLSN) && bytes.Equal(afterSeqval, afterTransaction)) {
+		return nil
+	}
+
+	instances, err := c.captureInstances(ctx)
+	if err != nil {
+		return err
+	}
+
+	var rows []changeRow
+	for _, instance := range instances {
+		instanceRows, err := c.readChanges(ctx, instance, fromLSN, maxLSN)
+		if err != nil {
+			return fmt.Errorf("failed to read changes of %s: %w", instance.Name, err)
+		}
+		for _, row := range instanceRows {
+			// Skip what was read before a restart in the middle of a transaction
+			if fromLSN != nil && bytes.Equal(row.LSN, fromLSN) && bytes.Compare(row.Seqval, afterSeqval) <= 0 {
+				continue
+			}
+			rows = append(rows, row)
+		}
+	}
+	sortRows(rows)
+
+	c.mu.Lock()
+	seq := c.seq
+	c.mu.Unlock()
+	changes, _ := RowsToChanges(rows, seq)
+	for _, change := range changes {
+		select {
+		case c.changeChan <- change:
+		case <-c.done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		seq, lsn, seqval, _ := ParsePosition(change.Position)
+		c.mu.Lock()
+		c.seq, c.lsn, c.seqval = seq, lsn, seqval
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	c.lsn, c.seqval = maxLSN, afterTransaction
+	c.mu.Unlock()
+	return nil
+}
+
+// captureInstances lists the CDC-enabled tables in the captured schemas, and the DDL
+// log. When a table has two capture instances while its schema changes, the newer one
+// is read.
+func (c *Client) captureInstances(ctx context.Context) ([]*captureInstance, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT ct.capture_instance, OBJECT_SCHEMA_NAME(ct.source_object_id), OBJECT_NAME(ct.source_object_id), ct.object_id
+		FROM cdc.change_tables ct
+		ORDER BY ct.create_date`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list capture instances: %w", err)
+	}
+	defer rows.Close()
+
+	bySource := make(map[string]*captureInstance)
+	objectIDs := make(map[*captureInstance]int64)
+	var order []string
+	for rows.Next() {
+		instance := &captureInstance{}
+		var objectID int64
+		if err := rows.Scan(&instance.Name, &instance.Schema, &instance.Table, &objectID); err != nil {
+			return nil, fmt.Errorf("failed to scan capture instance: %w", err)
+		}
+		if instance.Table != ddlLogTable && len(c.schemas) > 0 && !slices.Contains(c.schemas, instance.Schema) {
+			continue
+		}
+		source := instance.Schema + "." + instance.Table
+		if _, ok := bySource[source]; !ok {
+			order = append(order, source)
+		}
+		bySource[source] = instance
+		objectIDs[instance] = objectID
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list capture instances: %w", err)
+	}
+
+	instances := make([]*captureInstance, 0, len(order))
+	for _, source := range order {
+		instance := bySource[source]
+		instance.PrimaryKey, err = c.indexColumns(ctx, objectIDs[instance])
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+// indexColumns returns the columns of the unique index a capture instance identifies
+// rows by, usually the primary key
+func (c *Client) indexColumns(ctx context.Context, objectID int64) ([]string, error) {
+	rows, err := c.db.QueryContext(ctx, "SELECT column_name FROM cdc.index_columns WHERE object_id = @p1 ORDER BY index_ordinal", objectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, fmt.Errorf("failed to scan index column: %w", err)
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// readChanges reads the changes of one capture instance between two LSNs, both inclusive
+func (c *Client) readChanges(ctx context.Context, instance *captureInstance, fromLSN, toLSN []byte) ([]changeRow, error) {
+	var minLSN []byte
+	if err := c.db.QueryRowContext(ctx, "SELECT sys.fn_cdc_get_min_lsn(@p1)", instance.Name).Scan(&minLSN); err != nil {
+		return nil, fmt.Errorf("failed to get min LSN: %w", err)
+	}
+	if minLSN == nil || bytes.Compare(minLSN, toLSN) > 0 {
+		return nil, nil
+	}
+	if fromLSN == nil || bytes.Compare(fromLSN, minLSN) < 0 {
+		if fromLSN != nil && c.known[instance.Name] {
+			log.Printf("WARNING: changes of %s before LSN %s were cleaned up before they were read", instance.Name, FormatLSN(minLSN))
+		}
+		fromLSN = minLSN
+	}
+	c.known[instance.Name] = true
+
+	// 'all update old' returns the row before each update too, for its old key
+	query := fmt.Sprintf(
+		"SELECT sys.fn_cdc_map_lsn_to_time(__$start_lsn) AS [__$commit_time], * FROM cdc.%s(@p1, @p2, N'all update old')",
+		quoteName("fn_cdc_get_all_changes_"+instance.Name))
+	rows, err := c.db.QueryContext(ctx, query, fromLSN, toLSN)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	var columns, dbTypes []string
+	var columnIndexes []int
+	for i, columnType := range columnTypes {
+		if !strings.HasPrefix(columnType.Name(), "__$") {
+			columns = append(columns, columnType.Name())
+			dbTypes = append(dbTypes, columnType.DatabaseTypeName())
+			columnIndexes = append(columnIndexes, i)
+		}
+	}
+
+	var result []changeRow
+	for rows.Next() {
+		values := make([]any, len(columnTypes))
+		pointers := make([]any, len(columnTypes))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := changeRow{Instance: instance, Columns: columns, Types: dbTypes, Values: make([]any, len(columnIndexes))}
+		for i, columnType := range columnTypes {
+			switch columnType.Name() {
+			case "__$commit_time":
+				row.CommitTime, _ = values[i].(time.Time)
+			case "__$start_lsn":
+				row.LSN, _ = values[i].([]byte)
+			case "__$seqval":
+				row.Seqval, _ = values[i].([]byte)
+			case "__$operation":
+				operation, _ := values[i].(int64)
+				row.Operation = int(operation)
+			}
+		}
+		for i, index := range columnIndexes {
+			row.Values[i] = values[index]
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// quoteName quotes an identifier with brackets
+func quoteName(name string) string {
+	return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+}
+
+func (c *Client) Close(ctx context.Context) {
+	close(c.done)
+
+	// Wait for the polling goroutine to finish
+	c.wg.Wait()
+
+	c.mu.Lock()
+	if c.db != nil {
+		c.db.Close()
+		c.db = nil
+	}
+	c.mu.Unlock()
+}
+
+// GetPosition returns the position of the last change read, or "" if none was read yet
+func (c *Client) GetPosition() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lsn == nil {
+		return ""
+	}
+	return FormatPosition(c.seq, c.lsn, c.seqval)
+}
+
+// Changes returns the channel of changes
+func (c *Client) Changes() <-chan types.Change {
+	return c.changeChan
+}
+
+// Done returns a channel that is closed when the client is closed
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
@@ -0,0 +1,231 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"kasho/pkg/types"
+	"kasho/proto"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// ddlLogTable is the CDC-enabled table the kasho DDL trigger writes statements to
+const ddlLogTable = "kasho_ddl_log"
+
+// Operations in the __$operation column of a change table
+const (
+	opDelete       = 1
+	opInsert       = 2
+	opUpdateBefore = 3
+	opUpdateAfter  = 4
+)
+
+// captureInstance is a source table with change data capture enabled
+type captureInstance struct {
+	Name       string // e.g. "dbo_users"
+	Schema     string
+	Table      string
+	PrimaryKey []string
+}
+
+// changeRow is one row read from a change table
+type changeRow struct {
+	Instance   *captureInstance
+	LSN        []byte // commit LSN of the transaction
+	Seqval     []byte // order of the change within the transaction
+	Operation  int
+	CommitTime time.Time
+	Columns    []string
+	Types      []string // database type names of the columns
+	Values     []any
+}
+
+// sortRows orders rows read from several change tables by commit LSN and sequence
+// value; the before image of an update precedes its after image
+func sortRows(rows []changeRow) {
+	slices.SortStableFunc(rows, func(a, b changeRow) int {
+		if c := bytes.Compare(a.LSN, b.LSN); c != 0 {
+			return c
+		}
+		if c := bytes.Compare(a.Seqval, b.Seqval); c != 0 {
+			return c
+		}
+		return a.Operation - b.Operation
+	})
+}
+
+// RowsToChanges converts sorted change rows to changes, numbering them after seq. It
+// returns the changes and the sequence number of the last one.
+func RowsToChanges(rows []changeRow, seq uint64) ([]types.Change, uint64) {
+	var changes []types.Change
+	var before *changeRow
+	for i := range rows {
+		row := &rows[i]
+		if row.Operation == opUpdateBefore {
+			before = row
+			continue
+		}
+		if row.Operation == opUpdateAfter && (before == nil || !bytes.Equal(before.Seqval, row.Seqval) || before.Instance != row.Instance) {
+			before = nil
+		}
+
+		var change *types.Change
+		if row.Instance.Table == ddlLogTable {
+			change = ddlRowToChange(row)
+		} else {
+			change = dmlRowToChange(row, before)
+		}
+		before = nil
+		if change == nil {
+			continue
+		}
+
+		seq++
+		change.Position = FormatPosition(seq, row.LSN, row.Seqval)
+		change.Dialect = "sqlserver"
+		change.TransactionID = FormatLSN(row.LSN)
+		change.CommitTime = row.CommitTime
+		changes = append(changes, *change)
+	}
+	return changes, seq
+}
+
+// dmlRowToChange converts an insert, delete or the after image of an update. The before
+// image of an update, if read, provides its old key.
+func dmlRowToChange(row *changeRow, before *changeRow) *types.Change {
+	instance := row.Instance
+	dml := &types.DMLData{
+		Table:        instance.Schema + "." + instance.Table,
+		ColumnNames:  make([]string, 0, len(row.Columns)),
+		ColumnValues: make([]types.ColumnValueWrapper, 0, len(row.Columns)),
+		PrimaryKey:   instance.PrimaryKey,
+		Schema:       instance.Schema,
+	}
+
+	switch row.Operation {
+	case opInsert:
+		dml.Kind = "insert"
+	case opUpdateAfter:
+		dml.Kind = "update"
+		keyRow := row
+		if before != nil {
+			keyRow = before
+		}
+		dml.OldKeys = oldKeys(instance, keyRow)
+	case opDelete:
+		dml.Kind = "delete"
+		dml.OldKeys = oldKeys(instance, row)
+		return &types.Change{Data: dml}
+	default:
+		return nil
+	}
+
+	for i, name := range row.Columns {
+		dml.ColumnNames = append(dml.ColumnNames, name)
+		dml.ColumnValues = append(dml.ColumnValues, toColumnValue(row.Values[i], row.Types[i]))
+	}
+	return &types.Change{Data: dml}
+}
+
+// oldKeys builds the old key of a row from its primary key columns
+func oldKeys(instance *captureInstance, row *changeRow) *struct {
+	KeyNames  []string                   `json:"keynames"`
+	KeyValues []types.ColumnValueWrapper `json:"keyvalues"`
+} {
+	keys := &struct {
+		KeyNames  []string                   `json:"keynames"`
+		KeyValues []types.ColumnValueWrapper `json:"keyvalues"`
+	}{
+		KeyNames:  make([]string, 0, len(instance.PrimaryKey)),
+		KeyValues: make([]types.ColumnValueWrapper, 0, len(instance.PrimaryKey)),
+	}
+	for _, name := range instance.PrimaryKey {
+		if i := slices.Index(row.Columns, name); i >= 0 {
+			keys.KeyNames = append(keys.KeyNames, name)
+			keys.KeyValues = append(keys.KeyValues, toColumnValue(row.Values[i], row.Types[i]))
+		}
+	}
+	return keys
+}
+
+// ddlRowToChange converts a row inserted into the DDL log to a DDL change
+func ddlRowToChange(row *changeRow) *types.Change {
+	if row.Operation != opInsert {
+		return nil
+	}
+	ddl := types.DDLData{}
+	for i, name := range row.Columns {
+		switch v := row.Values[i].(type) {
+		case int64:
+			if name == "id" {
+				ddl.ID = int(v)
+			}
+		case time.Time:
+			if name == "ts" {
+				ddl.Time = v
+			}
+		case string:
+			switch name {
+			case "username":
+				ddl.Username = v
+			case "database":
+				ddl.Database = v
+			case "ddl":
+				ddl.DDL = v
+			}
+		}
+	}
+	if ddl.DDL == "" {
+		return nil
+	}
+	return &types.Change{Data: ddl}
+}
+
+// toColumnValue converts a SQL Server value to our ColumnValueWrapper
+func toColumnValue(value any, dbType string) types.ColumnValueWrapper {
+	if value == nil {
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{}}
+	}
+
+	switch strings.ToUpper(dbType) {
+	case "DECIMAL", "NUMERIC", "MONEY", "SMALLMONEY":
+		// The driver returns exact numerics as their text, which keeps their precision
+		if v, ok := value.([]byte); ok {
+			return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: string(v)}}}
+		}
+	case "UNIQUEIDENTIFIER":
+		var id mssql.UniqueIdentifier
+		if err := id.Scan(value); err == nil {
+			return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_UuidValue{UuidValue: strings.ToLower(id.String())}}}
+		}
+	case "XML":
+		switch v := value.(type) {
+		case string:
+			return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: v}}}
+		case []byte:
+			return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: string(v)}}}
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: v}}}
+	case []byte:
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: v}}}
+	case int64:
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: v}}}
+	case float64:
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_FloatValue{FloatValue: v}}}
+	case bool:
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_BoolValue{BoolValue: v}}}
+	case time.Time:
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: v.UTC().Format(time.RFC3339Nano)}}}
+	default:
+		// For any other type, convert to string
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: fmt.Sprint(v)}}}
+	}
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"kasho/pkg/types"
+)
+
+func lsn(b byte) []byte {
+	return []byte{0, 0, 0, 0x2A, 0, 0, 0, 0, 0, b}
+}
+
+var usersInstance = &captureInstance{
+	Name:       "dbo_users",
+	Schema:     "dbo",
+	Table:      "users",
+	PrimaryKey: []string{"id"},
+}
+
+var ddlInstance = &captureInstance{
+	Name:       "dbo_kasho_ddl_log",
+	Schema:     "dbo",
+	Table:      ddlLogTable,
+	PrimaryKey: []string{"id"},
+}
+
+func userRow(commit, seqval byte, op int, id int64, name string) changeRow {
+	return changeRow{
+		Instance:   usersInstance,
+		LSN:        lsn(commit),
+		Seqval:     lsn(seqval),
+		Operation:  op,
+		CommitTime: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Columns:    []string{"id", "name"},
+		Types:      []string{"INT", "NVARCHAR"},
+		Values:     []any{id, name},
+	}
+}
+
+func TestRowsToChanges(t *testing.T) {
+	rows := []changeRow{
+		userRow(1, 1, opInsert, 1, "alice"),
+		userRow(2, 1, opUpdateBefore, 1, "alice"),
+		userRow(2, 1, opUpdateAfter, 2, "bob"),
+		userRow(3, 1, opDelete, 2, "bob"),
+	}
+
+	changes, seq := RowsToChanges(rows, 10)
+	if len(changes) != 3 {
+		t.Fatalf("RowsToChanges() returned %d changes, want 3", len(changes))
+	}
+	if seq != 13 {
+		t.Errorf("RowsToChanges() seq = %d, want 13", seq)
+	}
+
+	wantKinds := []string{"insert", "update", "delete"}
+	for i, change := range changes {
+		dml, ok := change.Data.(*types.DMLData)
+		if !ok {
+			t.Fatalf("change %d data = %T, want *types.DMLData", i, change.Data)
+		}
+		if dml.Kind != wantKinds[i] {
+			t.Errorf("change %d kind = %v, want %v", i, dml.Kind, wantKinds[i])
+		}
+		if dml.Table != "dbo.users" {
+			t.Errorf("change %d table = %v, want dbo.users", i, dml.Table)
+		}
+		if change.Dialect != "sqlserver" {
+			t.Errorf("change %d dialect = %v, want sqlserver", i, change.Dialect)
+		}
+	}
+
+	if want := FormatPosition(11, lsn(1), lsn(1)); changes[0].Position != want {
+		t.Errorf("insert position = %v, want %v", changes[0].Position, want)
+	}
+	if want := FormatLSN(lsn(2)); changes[1].TransactionID != want {
+		t.Errorf("update transaction = %v, want %v", changes[1].TransactionID, want)
+	}
+
+	update := changes[1].Data.(*types.DMLData)
+	if update.OldKeys == nil || len(update.OldKeys.KeyValues) != 1 || update.OldKeys.KeyValues[0].GetIntValue() != 1 {
+		t.Errorf("update old keys = %+v, want id 1 from the before image", update.OldKeys)
+	}
+	if got := update.ColumnValues[1].GetStringValue(); got != "bob" {
+		t.Errorf("update name = %v, want bob", got)
+	}
+
+	del := changes[2].Data.(*types.DMLData)
+	if len(del.ColumnNames) != 0 || del.OldKeys == nil || del.OldKeys.KeyValues[0].GetIntValue() != 2 {
+		t.Errorf("delete = %+v, want only old key id 2", del)
+	}
+}
+
+func TestRowsToChangesDDL(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	rows := []changeRow{{
+		Instance:  ddlInstance,
+		LSN:       lsn(5),
+		Seqval:    lsn(1),
+		Operation: opInsert,
+		Columns:   []string{"id", "ts", "username", "database", "ddl"},
+		Types:     []string{"INT", "DATETIME2", "NVARCHAR", "NVARCHAR", "NVARCHAR"},
+		Values:    []any{int64(7), ts, "sa", "app", "ALTER TABLE dbo.users ADD email NVARCHAR(255)"},
+	}}
+
+	changes, _ := RowsToChanges(rows, 0)
+	if len(changes) != 1 {
+		t.Fatalf("RowsToChanges() returned %d changes, want 1", len(changes))
+	}
+	ddl, ok := changes[0].Data.(types.DDLData)
+	if !ok {
+		t.Fatalf("change data = %T, want types.DDLData", changes[0].Data)
+	}
+	if ddl.ID != 7 || !ddl.Time.Equal(ts) || ddl.Username != "sa" || ddl.Database != "app" {
+		t.Errorf("DDL = %+v, unexpected metadata", ddl)
+	}
+	if ddl.DDL != "ALTER TABLE dbo.users ADD email NVARCHAR(255)" {
+		t.Errorf("DDL statement = %v", ddl.DDL)
+	}
+}
+
+func TestSortRows(t *testing.T) {
+	rows := []changeRow{
+		userRow(2, 1, opUpdateAfter, 1, "b"),
+		userRow(1, 2, opInsert, 2, "c"),
+		userRow(2, 1, opUpdateBefore, 1, "a"),
+		userRow(1, 1, opInsert, 1, "a"),
+	}
+	sortRows(rows)
+
+	want := []struct {
+		commit, seqval byte
+		op             int
+	}{
+		{1, 1, opInsert},
+		{1, 2, opInsert},
+		{2, 1, opUpdateBefore},
+		{2, 1, opUpdateAfter},
+	}
+	for i, w := range want {
+		if rows[i].LSN[9] != w.commit || rows[i].Seqval[9] != w.seqval || rows[i].Operation != w.op {
+			t.Errorf("row %d = %X/%X/%d, want %d/%d/%d", i, rows[i].LSN, rows[i].Seqval, rows[i].Operation, w.commit, w.seqval, w.op)
+		}
+	}
+}
+
+func TestToColumnValue(t *testing.T) {
+	if got := toColumnValue([]byte("12.50"), "DECIMAL").GetStringValue(); got != "12.50" {
+		t.Errorf("decimal = %v, want 12.50", got)
+	}
+	if got := toColumnValue("6F9619FF-8B86-D011-B42D-00C04FC964FF", "UNIQUEIDENTIFIER").GetUuidValue(); got != "6f9619ff-8b86-d011-b42d-00c04fc964ff" {
+		t.Errorf("uniqueidentifier = %v", got)
+	}
+	if got := toColumnValue([]byte{0x01, 0x02}, "VARBINARY").GetBytesValue(); len(got) != 2 {
+		t.Errorf("varbinary = %v", got)
+	}
+	if got := toColumnValue(nil, "INT"); got.ColumnValue == nil || got.Value != nil {
+		t.Errorf("nil = %+v, want empty value", got)
+	}
+}
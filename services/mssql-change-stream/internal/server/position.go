@@ -0,0 +1,61 @@
+package server
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// positionPrefix marks SQL Server positions. A change is identified by the commit LSN
+// of its transaction and its sequence value within it; both are 10 bytes, too wide to
+// order as a float score, so positions carry a sequence number assigned as changes
+// are read, followed by the LSN and sequence value.
+const positionPrefix = "mssql:"
+
+// lsnLength is the size of an LSN or sequence value
+const lsnLength = 10
+
+// FormatPosition converts a sequence number, commit LSN and sequence value to our string format
+// Format: "mssql:0000000000000042:0000002A000001300003:0000002A000001300002" (sequence:LSN:seqval)
+func FormatPosition(seq uint64, lsn, seqval []byte) string {
+	return fmt.Sprintf("%s%016d:%X:%X", positionPrefix, seq, lsn, seqval)
+}
+
+// ParsePosition parses our position string format back to a sequence number, commit LSN
+// and sequence value. Empty and "bootstrap" positions have none of them.
+func ParsePosition(position string) (uint64, []byte, []byte, error) {
+	if position == "" || position == "bootstrap" {
+		return 0, nil, nil, nil
+	}
+
+	rest, ok := strings.CutPrefix(position, positionPrefix)
+	if !ok {
+		return 0, nil, nil, fmt.Errorf("invalid position format: %s", position)
+	}
+	parts := strings.Split(rest, ":")
+	if len(parts) != 3 {
+		return 0, nil, nil, fmt.Errorf("invalid position format: %s", position)
+	}
+	seq, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("invalid position sequence: %s", parts[0])
+	}
+	lsn, err := hex.DecodeString(parts[1])
+	if err != nil || len(lsn) != lsnLength {
+		return 0, nil, nil, fmt.Errorf("invalid position LSN: %s", parts[1])
+	}
+	seqval, err := hex.DecodeString(parts[2])
+	if err != nil || len(seqval) != lsnLength {
+		return 0, nil, nil, fmt.Errorf("invalid position sequence value: %s", parts[2])
+	}
+	return seq, lsn, seqval, nil
+}
+
+// FormatLSN formats an LSN the way SQL Server displays it: "0000002A:00000130:0003"
+func FormatLSN(lsn []byte) string {
+	if len(lsn) != lsnLength {
+		return fmt.Sprintf("%X", lsn)
+	}
+	return fmt.Sprintf("%X:%X:%X", lsn[0:4], lsn[4:8], lsn[8:10])
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+)
+
+var (
+	testLSN    = []byte{0x00, 0x00, 0x00, 0x2A, 0x00, 0x00, 0x01, 0x30, 0x00, 0x03}
+	testSeqval = []byte{0x00, 0x00, 0x00, 0x2A, 0x00, 0x00, 0x01, 0x30, 0x00, 0x02}
+)
+
+func TestFormatPosition(t *testing.T) {
+	got := FormatPosition(42, testLSN, testSeqval)
+	if want := "mssql:0000000000000042:0000002A000001300003:0000002A000001300002"; got != want {
+		t.Errorf("FormatPosition() = %v, want %v", got, want)
+	}
+}
+
+func TestParsePosition(t *testing.T) {
+	tests := []struct {
+		name       string
+		position   string
+		wantSeq    uint64
+		wantLSN    []byte
+		wantSeqval []byte
+		wantErr    bool
+	}{
+		{
+			name:       "valid position",
+			position:   "mssql:0000000000000042:0000002A000001300003:0000002A000001300002",
+			wantSeq:    42,
+			wantLSN:    testLSN,
+			wantSeqval: testSeqval,
+		},
+		{name: "empty position", position: ""},
+		{name: "bootstrap position", position: "bootstrap"},
+		{name: "binlog position", position: "mysql-bin.000001:4", wantErr: true},
+		{name: "missing sequence value", position: "mssql:0000000000000042:0000002A000001300003", wantErr: true},
+		{name: "short LSN", position: "mssql:0000000000000042:2A:0000002A000001300002", wantErr: true},
+		{name: "invalid sequence", position: "mssql:abc:0000002A000001300003:0000002A000001300002", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seq, lsn, seqval, err := ParsePosition(tt.position)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePosition() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if seq != tt.wantSeq || !bytes.Equal(lsn, tt.wantLSN) || !bytes.Equal(seqval, tt.wantSeqval) {
+				t.Errorf("ParsePosition() = %v, %X, %X, want %v, %X, %X", seq, lsn, seqval, tt.wantSeq, tt.wantLSN, tt.wantSeqval)
+			}
+		})
+	}
+}
+
+func TestFormatLSN(t *testing.T) {
+	if got, want := FormatLSN(testLSN), "0000002A:00000130:0003"; got != want {
+		t.Errorf("FormatLSN() = %v, want %v", got, want)
+	}
+}
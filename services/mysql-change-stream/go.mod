@@ -5,7 +5,11 @@ go 1.24.3
 require (
 	github.com/go-mysql-org/go-mysql v1.10.0
 	google.golang.org/grpc v1.72.1
+	google.golang.org/protobuf v1.36.6
+	kasho/pkg/auth v0.0.0
 	kasho/pkg/kvbuffer v0.0.0-00010101000000-000000000000
+	kasho/pkg/notify v0.0.0
+	kasho/pkg/streamserver v0.0.0
 	kasho/pkg/types v0.0.0-00010101000000-000000000000
 	kasho/pkg/version v0.0.0
 	kasho/proto v0.0.0-00010101000000-000000000000
@@ -40,10 +44,15 @@ require (
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.26.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250218202821-56aae31c358a // indirect
-	google.golang.org/protobuf v1.36.6 // indirect
 	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
 )
 
+replace kasho/pkg/auth => ../../pkg/auth
+
+replace kasho/pkg/notify => ../../pkg/notify
+
+replace kasho/pkg/streamserver => ../../pkg/streamserver
+
 replace kasho/pkg/kvbuffer => ../../pkg/kvbuffer
 
 replace kasho/pkg/types => ../../pkg/types
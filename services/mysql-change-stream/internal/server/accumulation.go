@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"kasho/pkg/streamserver"
+	"kasho/proto"
+)
+
+// SetAccumulationLimits sets the limits on the ACCUMULATING state
+func (s *ChangeStreamServer) SetAccumulationLimits(limits streamserver.AccumulationLimits) {
+	s.accumulationLimits = limits
+}
+
+// CheckAccumulation measures the accumulation's progress and, once a limit is reached,
+// moves to the limits' action state
+func (s *ChangeStreamServer) CheckAccumulation(ctx context.Context) error {
+	if s.CurrentState() != StateAccumulating {
+		return nil
+	}
+	depth, size := streamserver.MeasureBuffer(ctx, s.buffer)
+
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	// CompleteBootstrap may have run while the buffer was measured
+	if s.state.Current != StateAccumulating {
+		return nil
+	}
+	s.state.AccumulatedChanges = max(depth-s.state.StartDepth, 0)
+	s.accumulatedBytes = max(size-s.state.StartBytes, 0)
+
+	reason := s.accumulationLimits.Exceeded(s.state.AccumulatedChanges, s.accumulatedBytes, time.Since(s.state.TransitionTime))
+	if reason == "" {
+		return nil
+	}
+
+	s.state.Current = s.accumulationLimits.Action
+	s.state.TransitionTime = time.Now()
+	if err := s.SaveState(ctx, s.state); err != nil {
+		// Rollback
+		s.state.Current = StateAccumulating
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	log.Printf("Accumulation limit reached (%s), moved to %s state", reason, s.state.Current)
+	return nil
+}
+
+// MonitorAccumulation checks the accumulation every interval until ctx is done
+func (s *ChangeStreamServer) MonitorAccumulation(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.CheckAccumulation(ctx); err != nil {
+				log.Printf("Failed to check accumulation: %v", err)
+			}
+		}
+	}
+}
+
+// GetState describes the current state, the transitions it allows and the accumulation limits
+func (s *ChangeStreamServer) GetState(ctx context.Context, req *proto.GetStateRequest) (*proto.StateResponse, error) {
+	s.stateMu.RLock()
+	defer s.stateMu.RUnlock()
+
+	return s.accumulationLimits.StateResponse(s.state.Current, s.state.TransitionTime, s.state.AccumulatedChanges, s.accumulatedBytes), nil
+}
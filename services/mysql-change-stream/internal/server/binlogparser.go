@@ -2,6 +2,7 @@ package server
 
 import (
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
@@ -20,14 +21,22 @@ func FormatBinlogPosition(pos mysql.Position) string {
 	return fmt.Sprintf("%s:%d", pos.Name, pos.Pos)
 }
 
-// ParseBinlogPosition parses our position string format back to mysql.Position
+// FormatRowPosition converts the position of a rows event and a row in it to our string format
+// Format: "mysql-bin.000001:4:2" (filename:event offset:row ordinal). Rows of one event
+// share its offset, so the 1-based ordinal keeps their positions unique and ordered.
+func FormatRowPosition(pos mysql.Position, row int) string {
+	return fmt.Sprintf("%s:%d:%d", pos.Name, pos.Pos, row)
+}
+
+// ParseBinlogPosition parses our position string format back to mysql.Position. The
+// row ordinal of a row position is dropped, so streaming resumes at the start of its event.
 func ParseBinlogPosition(position string) (mysql.Position, error) {
 	if position == "" || position == "bootstrap" {
 		return mysql.Position{}, nil
 	}
 
 	parts := strings.Split(position, ":")
-	if len(parts) != 2 {
+	if len(parts) != 2 && len(parts) != 3 {
 		return mysql.Position{}, fmt.Errorf("invalid position format: %s", position)
 	}
 
@@ -42,14 +51,15 @@ func ParseBinlogPosition(position string) (mysql.Position, error) {
 	}, nil
 }
 
-// RowsEventToChanges converts a canal RowsEvent to our Change types
+// RowsEventToChanges converts a canal RowsEvent to our Change types. pos is the offset
+// the event starts at; each row gets its own position within it.
 func RowsEventToChanges(e *canal.RowsEvent, pos mysql.Position) []types.Change {
 	var changes []types.Change
-	position := FormatBinlogPosition(pos)
 
 	// Use just the table name without database prefix to be consistent with
 	// the bootstrap dump parser and transforms config format
 	tableName := e.Table.Name
+	primaryKey := primaryKeyNames(e.Table)
 
 	switch e.Action {
 	case canal.InsertAction:
@@ -59,6 +69,8 @@ func RowsEventToChanges(e *canal.RowsEvent, pos mysql.Position) []types.Change {
 				Kind:         "insert",
 				ColumnNames:  make([]string, 0, len(e.Table.Columns)),
 				ColumnValues: make([]types.ColumnValueWrapper, 0, len(row)),
+				PrimaryKey:   primaryKey,
+				Schema:       e.Table.Schema,
 			}
 
 			for i, col := range e.Table.Columns {
@@ -68,7 +80,7 @@ func RowsEventToChanges(e *canal.RowsEvent, pos mysql.Position) []types.Change {
 				}
 			}
 
-			changes = append(changes, types.Change{Position: position, Data: dml})
+			changes = append(changes, types.Change{Position: FormatRowPosition(pos, len(changes)+1), Data: dml})
 		}
 
 	case canal.UpdateAction:
@@ -85,6 +97,8 @@ func RowsEventToChanges(e *canal.RowsEvent, pos mysql.Position) []types.Change {
 				Kind:         "update",
 				ColumnNames:  make([]string, 0),
 				ColumnValues: make([]types.ColumnValueWrapper, 0),
+				PrimaryKey:   primaryKey,
+				Schema:       e.Table.Schema,
 			}
 
 			// Find primary key columns
@@ -123,7 +137,7 @@ func RowsEventToChanges(e *canal.RowsEvent, pos mysql.Position) []types.Change {
 				}
 			}
 
-			changes = append(changes, types.Change{Position: position, Data: dml})
+			changes = append(changes, types.Change{Position: FormatRowPosition(pos, len(changes)+1), Data: dml})
 		}
 
 	case canal.DeleteAction:
@@ -133,6 +147,8 @@ func RowsEventToChanges(e *canal.RowsEvent, pos mysql.Position) []types.Change {
 				Kind:         "delete",
 				ColumnNames:  make([]string, 0),
 				ColumnValues: make([]types.ColumnValueWrapper, 0),
+				PrimaryKey:   primaryKey,
+				Schema:       e.Table.Schema,
 			}
 
 			// Build OldKeys from primary key columns
@@ -159,7 +175,7 @@ func RowsEventToChanges(e *canal.RowsEvent, pos mysql.Position) []types.Change {
 				}
 			}
 
-			changes = append(changes, types.Change{Position: position, Data: dml})
+			changes = append(changes, types.Change{Position: FormatRowPosition(pos, len(changes)+1), Data: dml})
 		}
 	}
 
@@ -196,6 +212,17 @@ func QueryEventToChange(header *replication.EventHeader, e *replication.QueryEve
 	return &types.Change{Position: position, Data: ddl}
 }
 
+// primaryKeyNames returns the names of the table's primary key columns
+func primaryKeyNames(table *schema.Table) []string {
+	var names []string
+	for _, pkIdx := range table.PKColumns {
+		if pkIdx < len(table.Columns) {
+			names = append(names, table.Columns[pkIdx].Name)
+		}
+	}
+	return names
+}
+
 // isPrimaryKey checks if a column is part of the primary key
 func isPrimaryKey(col *schema.TableColumn, table *schema.Table) bool {
 	for _, pkIdx := range table.PKColumns {
@@ -208,10 +235,42 @@ func isPrimaryKey(col *schema.TableColumn, table *schema.Table) bool {
 
 // toColumnValue converts a MySQL value to our ColumnValueWrapper
 func toColumnValue(value any, col *schema.TableColumn) types.ColumnValueWrapper {
+	if _, ok := value.(unchangedValue); ok {
+		return unchangedColumnValue()
+	}
 	if value == nil {
 		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: ""}}}
 	}
 
+	switch {
+	case col != nil && col.Type == schema.TYPE_JSON:
+		switch v := value.(type) {
+		case string:
+			return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_JsonValue{JsonValue: v}}}
+		case []byte:
+			return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_JsonValue{JsonValue: string(v)}}}
+		}
+	case isSpatialColumn(col):
+		if v, ok := value.([]byte); ok {
+			wkt, srid, err := decodeGeometry(v)
+			if err == nil {
+				return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_GeometryValue{GeometryValue: &proto.Geometry{Wkt: wkt, Srid: srid}}}}
+			}
+			log.Printf("Failed to decode geometry in column %s, passing raw bytes: %v", col.Name, err)
+			return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: v}}}
+		}
+	}
+
+	// TEXT columns also arrive as []byte, so binary data is recognized by the column type
+	if isBinaryColumn(col) {
+		switch v := value.(type) {
+		case string:
+			return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: []byte(v)}}}
+		case []byte:
+			return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: v}}}
+		}
+	}
+
 	switch v := value.(type) {
 	case string:
 		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: v}}}
@@ -244,9 +303,29 @@ func toColumnValue(value any, col *schema.TableColumn) types.ColumnValueWrapper
 	case bool:
 		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_BoolValue{BoolValue: v}}}
 	case time.Time:
-		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: v.Format(time.RFC3339)}}}
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: v.UTC().Format(time.RFC3339Nano)}}}
 	default:
 		// For any other type, convert to string
 		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: fmt.Sprint(v)}}}
 	}
 }
+
+// isBinaryColumn reports whether a column holds BINARY, VARBINARY or BLOB data
+func isBinaryColumn(col *schema.TableColumn) bool {
+	if col == nil {
+		return false
+	}
+	return col.Type == schema.TYPE_BINARY || strings.Contains(strings.ToLower(col.RawType), "blob")
+}
+
+// isSpatialColumn reports whether a column holds a geometry type
+func isSpatialColumn(col *schema.TableColumn) bool {
+	if col == nil {
+		return false
+	}
+	switch strings.ToLower(col.RawType) {
+	case "geometry", "point", "linestring", "polygon", "multipoint", "multilinestring", "multipolygon", "geometrycollection", "geomcollection":
+		return true
+	}
+	return col.Type == schema.TYPE_POINT
+}
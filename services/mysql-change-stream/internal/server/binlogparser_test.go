@@ -1,6 +1,7 @@
 package server
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/go-mysql-org/go-mysql/replication"
 	"github.com/go-mysql-org/go-mysql/schema"
 
+	"kasho/pkg/streamserver"
 	"kasho/pkg/types"
 	"kasho/proto"
 )
@@ -77,6 +79,12 @@ func TestParseBinlogPosition(t *testing.T) {
 			want:     mysql.Position{},
 			wantErr:  false,
 		},
+		{
+			name:     "row position resumes at its event",
+			position: "mysql-bin.000001:1234:3",
+			want:     mysql.Position{Name: "mysql-bin.000001", Pos: 1234},
+			wantErr:  false,
+		},
 		{
 			name:     "invalid format - no colon",
 			position: "mysql-bin.000001",
@@ -384,6 +392,16 @@ func TestToColumnValue(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:  "time.Time value with offset is normalized to UTC",
+			value: time.Date(2024, 3, 20, 15, 4, 5, 0, time.FixedZone("", -5*3600)),
+			check: func(t *testing.T, cv types.ColumnValueWrapper) {
+				expected := "2024-03-20T20:04:05Z"
+				if cv.ColumnValue.GetTimestampValue() != expected {
+					t.Errorf("expected timestamp %s, got %v", expected, cv.ColumnValue.GetTimestampValue())
+				}
+			},
+		},
 		{
 			name:  "byte slice",
 			value: []byte("binary data"),
@@ -421,6 +439,88 @@ func TestToColumnValue(t *testing.T) {
 	}
 }
 
+func TestToColumnValue_Binary(t *testing.T) {
+	tests := []struct {
+		name  string
+		col   *schema.TableColumn
+		value any
+		want  *proto.ColumnValue
+	}{
+		{
+			name:  "blob",
+			col:   &schema.TableColumn{Name: "data", Type: schema.TYPE_STRING, RawType: "blob"},
+			value: []byte{0x00, 0xff},
+			want:  &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: []byte{0x00, 0xff}}},
+		},
+		{
+			name:  "varbinary",
+			col:   &schema.TableColumn{Name: "hash", Type: schema.TYPE_BINARY, RawType: "varbinary(32)"},
+			value: "\x00\x01",
+			want:  &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: []byte{0x00, 0x01}}},
+		},
+		{
+			name:  "text stays a string",
+			col:   &schema.TableColumn{Name: "body", Type: schema.TYPE_STRING, RawType: "text"},
+			value: []byte("hello"),
+			want:  &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: "hello"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toColumnValue(tt.value, tt.col)
+			if !reflect.DeepEqual(got.ColumnValue.GetValue(), tt.want.GetValue()) {
+				t.Errorf("toColumnValue() = %v, want %v", got.ColumnValue, tt.want)
+			}
+		})
+	}
+}
+
+func TestToColumnValue_NativeTypes(t *testing.T) {
+	point := newWKB(4326).header(wkbPoint).point(13.4, 52.52).bytes()
+
+	tests := []struct {
+		name  string
+		col   *schema.TableColumn
+		value any
+		want  *proto.ColumnValue
+	}{
+		{
+			name:  "json",
+			col:   &schema.TableColumn{Name: "attrs", Type: schema.TYPE_JSON, RawType: "json"},
+			value: []byte(`{"a":1}`),
+			want:  &proto.ColumnValue{Value: &proto.ColumnValue_JsonValue{JsonValue: `{"a":1}`}},
+		},
+		{
+			name:  "point",
+			col:   &schema.TableColumn{Name: "location", Type: schema.TYPE_POINT, RawType: "point"},
+			value: point,
+			want:  &proto.ColumnValue{Value: &proto.ColumnValue_GeometryValue{GeometryValue: &proto.Geometry{Wkt: "POINT(13.4 52.52)", Srid: 4326}}},
+		},
+		{
+			name:  "geometry",
+			col:   &schema.TableColumn{Name: "shape", Type: schema.TYPE_STRING, RawType: "geometry"},
+			value: point,
+			want:  &proto.ColumnValue{Value: &proto.ColumnValue_GeometryValue{GeometryValue: &proto.Geometry{Wkt: "POINT(13.4 52.52)", Srid: 4326}}},
+		},
+		{
+			name:  "undecodable geometry falls back to bytes",
+			col:   &schema.TableColumn{Name: "shape", Type: schema.TYPE_STRING, RawType: "geometry"},
+			value: []byte{0x01, 0x02},
+			want:  &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: []byte{0x01, 0x02}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toColumnValue(tt.value, tt.col)
+			if got.ColumnValue.String() != tt.want.String() {
+				t.Errorf("toColumnValue() = %v, want %v", got.ColumnValue, tt.want)
+			}
+		})
+	}
+}
+
 func TestIsPrimaryKey(t *testing.T) {
 	table := &schema.Table{
 		Name:   "users",
@@ -527,8 +627,8 @@ func TestRowsEventToChanges_Insert(t *testing.T) {
 	if change1.Type() != "dml" {
 		t.Errorf("expected type 'dml', got %s", change1.Type())
 	}
-	if change1.GetPosition() != "mysql-bin.000001:1234" {
-		t.Errorf("expected position 'mysql-bin.000001:1234', got %s", change1.GetPosition())
+	if change1.GetPosition() != "mysql-bin.000001:1234:1" {
+		t.Errorf("expected position 'mysql-bin.000001:1234:1', got %s", change1.GetPosition())
 	}
 
 	dml1, ok := change1.Data.(*types.DMLData)
@@ -541,6 +641,9 @@ func TestRowsEventToChanges_Insert(t *testing.T) {
 	if dml1.Table != "users" {
 		t.Errorf("expected table 'users', got %s", dml1.Table)
 	}
+	if dml1.Schema != "testdb" {
+		t.Errorf("expected schema 'testdb', got %s", dml1.Schema)
+	}
 	if len(dml1.ColumnNames) != 3 {
 		t.Errorf("expected 3 column names, got %d", len(dml1.ColumnNames))
 	}
@@ -550,6 +653,9 @@ func TestRowsEventToChanges_Insert(t *testing.T) {
 	if dml1.OldKeys != nil {
 		t.Errorf("expected nil OldKeys for insert")
 	}
+	if len(dml1.PrimaryKey) != 1 || dml1.PrimaryKey[0] != "id" {
+		t.Errorf("expected primary key [id], got %v", dml1.PrimaryKey)
+	}
 
 	// Verify column names
 	expectedCols := []string{"id", "name", "email"}
@@ -568,6 +674,49 @@ func TestRowsEventToChanges_Insert(t *testing.T) {
 	}
 }
 
+func TestRowsEventToChanges_RowPositions(t *testing.T) {
+	table := makeTestTable()
+	pos := mysql.Position{Name: "mysql-bin.000001", Pos: 1234}
+	want := []string{"mysql-bin.000001:1234:1", "mysql-bin.000001:1234:2", "mysql-bin.000001:1234:3"}
+
+	tests := []struct {
+		name   string
+		action string
+		rows   [][]interface{}
+	}{
+		{"insert", canal.InsertAction, [][]interface{}{
+			{int64(1), "a", "a@example.com"},
+			{int64(2), "b", "b@example.com"},
+			{int64(3), "c", "c@example.com"},
+		}},
+		{"update", canal.UpdateAction, [][]interface{}{
+			{int64(1), "a", "a@example.com"}, {int64(1), "x", "a@example.com"},
+			{int64(2), "b", "b@example.com"}, {int64(2), "y", "b@example.com"},
+			{int64(3), "c", "c@example.com"}, {int64(3), "z", "c@example.com"},
+		}},
+		{"delete", canal.DeleteAction, [][]interface{}{
+			{int64(1), "a", "a@example.com"},
+			{int64(2), "b", "b@example.com"},
+			{int64(3), "c", "c@example.com"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := RowsEventToChanges(&canal.RowsEvent{Table: table, Action: tt.action, Rows: tt.rows}, pos)
+			if len(changes) != len(want) {
+				t.Fatalf("expected %d changes, got %d", len(want), len(changes))
+			}
+			// Every row of the event gets its own position, so none is taken for applied
+			for i, change := range changes {
+				if change.GetPosition() != want[i] {
+					t.Errorf("change %d: expected position %s, got %s", i, want[i], change.GetPosition())
+				}
+			}
+		})
+	}
+}
+
 func TestRowsEventToChanges_Update(t *testing.T) {
 	table := makeTestTable()
 
@@ -576,8 +725,8 @@ func TestRowsEventToChanges_Update(t *testing.T) {
 		Table:  table,
 		Action: canal.UpdateAction,
 		Rows: [][]interface{}{
-			{int64(1), "John Doe", "john@example.com"},     // old
-			{int64(1), "John Smith", "john@example.com"},   // new
+			{int64(1), "John Doe", "john@example.com"},   // old
+			{int64(1), "John Smith", "john@example.com"}, // new
 		},
 	}
 	pos := mysql.Position{Name: "mysql-bin.000001", Pos: 5678}
@@ -783,7 +932,7 @@ func TestConvertToProtoChange_MySQL_DMLData(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := convertToProtoChange(tt.change)
+			got := streamserver.ConvertChange(tt.change)
 			if got.Position != tt.want.Position {
 				t.Errorf("Position = %v, want %v", got.Position, tt.want.Position)
 			}
@@ -793,3 +942,55 @@ func TestConvertToProtoChange_MySQL_DMLData(t *testing.T) {
 		})
 	}
 }
+
+func TestCapturesDatabase(t *testing.T) {
+	tests := []struct {
+		name      string
+		databases []string
+		database  string
+		want      bool
+	}{
+		{"no allowlist", nil, "other", true},
+		{"captured database", []string{"app1", "app2"}, "app2", true},
+		{"database not captured", []string{"app1"}, "app2", false},
+		{"no default database", []string{"app1"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := capturesDatabase(tt.databases, tt.database); got != tt.want {
+				t.Errorf("capturesDatabase() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventHandlerSetSourceMetadata(t *testing.T) {
+	commitTime := time.Date(2024, 3, 20, 15, 0, 0, 0, time.UTC)
+	h := &EventHandler{gtid: "3e11fa47-71ca-11e1-9e33-c80aa9429562:23", commitTime: commitTime}
+
+	change := types.Change{Position: "mysql-bin.000001:4", Data: &types.DMLData{Table: "users", Kind: "insert"}}
+	h.setSourceMetadata(&change, "shop")
+
+	if change.Dialect != "mysql" {
+		t.Errorf("Dialect = %q, want mysql", change.Dialect)
+	}
+	if change.Database != "shop" || change.Schema != "shop" {
+		t.Errorf("Database, Schema = %q, %q, want shop, shop", change.Database, change.Schema)
+	}
+	if change.TransactionID != h.gtid {
+		t.Errorf("TransactionID = %q, want %q", change.TransactionID, h.gtid)
+	}
+	if !change.CommitTime.Equal(commitTime) {
+		t.Errorf("CommitTime = %v, want %v", change.CommitTime, commitTime)
+	}
+
+	if err := h.OnXID(nil, mysql.Position{}); err != nil {
+		t.Fatalf("OnXID() error = %v", err)
+	}
+	next := types.Change{Position: "mysql-bin.000001:90", Data: &types.DMLData{Table: "users", Kind: "insert"}}
+	h.setSourceMetadata(&next, "shop")
+	if next.TransactionID != "" || !next.CommitTime.IsZero() {
+		t.Errorf("transaction metadata kept after commit: %q, %v", next.TransactionID, next.CommitTime)
+	}
+}
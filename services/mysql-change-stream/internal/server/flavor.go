@@ -0,0 +1,105 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/go-mysql-org/go-mysql/client"
+)
+
+// Flavor is the server family of the primary. MariaDB has its own GTID events and
+// replication handshake; Aurora MySQL speaks MySQL's protocol but manages binlog
+// retention itself.
+type Flavor string
+
+const (
+	// FlavorMySQL is upstream MySQL, and servers compatible with its binlog such as Percona
+	FlavorMySQL Flavor = "mysql"
+	// FlavorMariaDB is MariaDB, whose GTIDs are domain-server-sequence triples
+	FlavorMariaDB Flavor = "mariadb"
+	// FlavorAurora is Amazon Aurora MySQL
+	FlavorAurora Flavor = "aurora"
+)
+
+// ParseFlavor parses a MYSQL_FLAVOR value; empty or auto means the flavor is detected
+// when connecting
+func ParseFlavor(s string) (Flavor, error) {
+	switch flavor := Flavor(strings.ToLower(strings.TrimSpace(s))); flavor {
+	case "", "auto":
+		return "", nil
+	case FlavorMySQL, FlavorMariaDB, FlavorAurora:
+		return flavor, nil
+	default:
+		return "", fmt.Errorf("invalid flavor %q: must be auto, mysql, mariadb or aurora", s)
+	}
+}
+
+// canalFlavor is the go-mysql flavor of the replication connection
+func (f Flavor) canalFlavor() string {
+	if f == FlavorMariaDB {
+		return "mariadb"
+	}
+	return "mysql"
+}
+
+// flavorFromVersion identifies the flavor from VERSION() and @@aurora_version, which only
+// Aurora has
+func flavorFromVersion(version, auroraVersion string) Flavor {
+	switch {
+	case strings.Contains(strings.ToLower(version), "mariadb"):
+		return FlavorMariaDB
+	case auroraVersion != "":
+		return FlavorAurora
+	default:
+		return FlavorMySQL
+	}
+}
+
+// detectFlavor connects to the primary to identify its flavor
+func detectFlavor(addr, user, password string) (Flavor, error) {
+	conn, err := client.Connect(addr, user, password, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	result, err := conn.Execute("SELECT VERSION()")
+	if err != nil {
+		return "", fmt.Errorf("failed to read version: %w", err)
+	}
+	version, err := result.GetString(0, 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to read version: %w", err)
+	}
+
+	// @@aurora_version is an unknown variable elsewhere
+	var auroraVersion string
+	if result, err := conn.Execute("SELECT @@aurora_version"); err == nil {
+		auroraVersion, _ = result.GetString(0, 0)
+	}
+	flavor := flavorFromVersion(version, auroraVersion)
+
+	if flavor == FlavorAurora {
+		checkAuroraRetention(conn)
+	}
+	return flavor, nil
+}
+
+// checkAuroraRetention warns when Aurora keeps no binlogs for readers. Without a binlog
+// retention period Aurora purges binlogs as soon as it can, so the saved position can be
+// gone after mysql-change-stream was down for a while.
+func checkAuroraRetention(conn *client.Conn) {
+	result, err := conn.Execute("SELECT value FROM mysql.rds_configuration WHERE name = 'binlog retention hours'")
+	if err != nil {
+		log.Printf("Failed to check Aurora binlog retention: %v", err)
+		return
+	}
+	var hours string
+	if result.RowNumber() > 0 {
+		hours, _ = result.GetString(0, 0)
+	}
+	if hours == "" {
+		log.Printf("Warning: Aurora binlog retention hours isn't set, so binlogs can be purged before they are read; set it with CALL mysql.rds_set_configuration('binlog retention hours', 24)")
+	}
+}
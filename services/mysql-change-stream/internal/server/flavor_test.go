@@ -0,0 +1,50 @@
+package server
+
+import "testing"
+
+func TestParseFlavor(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Flavor
+		wantErr bool
+	}{
+		{in: "", want: ""},
+		{in: "auto", want: ""},
+		{in: "MariaDB", want: FlavorMariaDB},
+		{in: " aurora ", want: FlavorAurora},
+		{in: "mysql", want: FlavorMySQL},
+		{in: "percona", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFlavor(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFlavor(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestFlavorFromVersion(t *testing.T) {
+	tests := []struct {
+		name          string
+		version       string
+		auroraVersion string
+		want          Flavor
+		wantCanal     string
+	}{
+		{name: "mysql", version: "8.0.36", want: FlavorMySQL, wantCanal: "mysql"},
+		{name: "mariadb", version: "10.11.6-MariaDB-log", want: FlavorMariaDB, wantCanal: "mariadb"},
+		{name: "mariadb 11", version: "11.4.2-MariaDB-ubu2404", want: FlavorMariaDB, wantCanal: "mariadb"},
+		{name: "aurora", version: "8.0.32", auroraVersion: "3.05.2", want: FlavorAurora, wantCanal: "mysql"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := flavorFromVersion(tt.version, tt.auroraVersion)
+			if got != tt.want {
+				t.Errorf("flavorFromVersion() = %q, want %q", got, tt.want)
+			}
+			if canal := got.canalFlavor(); canal != tt.wantCanal {
+				t.Errorf("canalFlavor() = %q, want %q", canal, tt.wantCanal)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// WKB geometry type codes
+const (
+	wkbPoint              = 1
+	wkbLineString         = 2
+	wkbPolygon            = 3
+	wkbMultiPoint         = 4
+	wkbMultiLineString    = 5
+	wkbMultiPolygon       = 6
+	wkbGeometryCollection = 7
+)
+
+// decodeGeometry converts a geometry value in MySQL's internal format, a 4-byte
+// little-endian SRID followed by WKB, into well-known text and the SRID
+func decodeGeometry(data []byte) (string, uint32, error) {
+	if len(data) < 4 {
+		return "", 0, fmt.Errorf("geometry value too short: %d bytes", len(data))
+	}
+	srid := binary.LittleEndian.Uint32(data)
+
+	r := &wkbReader{data: data[4:]}
+	var wkt strings.Builder
+	if err := r.readGeometry(&wkt, true); err != nil {
+		return "", 0, err
+	}
+	if len(r.data) != 0 {
+		return "", 0, fmt.Errorf("%d trailing bytes after geometry", len(r.data))
+	}
+	return wkt.String(), srid, nil
+}
+
+type wkbReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+func (r *wkbReader) uint32() (uint32, error) {
+	if len(r.data) < 4 {
+		return 0, fmt.Errorf("unexpected end of geometry")
+	}
+	v := r.order.Uint32(r.data)
+	r.data = r.data[4:]
+	return v, nil
+}
+
+func (r *wkbReader) float64() (float64, error) {
+	if len(r.data) < 8 {
+		return 0, fmt.Errorf("unexpected end of geometry")
+	}
+	v := math.Float64frombits(r.order.Uint64(r.data))
+	r.data = r.data[8:]
+	return v, nil
+}
+
+// readGeometry writes one geometry, including its byte order and type header.
+// Nested geometries inside multi-geometries are written without their type name.
+func (r *wkbReader) readGeometry(wkt *strings.Builder, tagged bool) error {
+	if len(r.data) < 1 {
+		return fmt.Errorf("unexpected end of geometry")
+	}
+	switch r.data[0] {
+	case 0:
+		r.order = binary.BigEndian
+	case 1:
+		r.order = binary.LittleEndian
+	default:
+		return fmt.Errorf("invalid WKB byte order %d", r.data[0])
+	}
+	r.data = r.data[1:]
+
+	geometryType, err := r.uint32()
+	if err != nil {
+		return err
+	}
+
+	name, multi, err := wkbTypeName(geometryType)
+	if err != nil {
+		return err
+	}
+	if tagged {
+		wkt.WriteString(name)
+	}
+
+	switch geometryType {
+	case wkbPoint:
+		wkt.WriteString("(")
+		if err := r.readPoint(wkt); err != nil {
+			return err
+		}
+		wkt.WriteString(")")
+		return nil
+	case wkbLineString:
+		return r.readPoints(wkt)
+	case wkbPolygon:
+		return r.readRings(wkt)
+	}
+
+	count, err := r.uint32()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		wkt.WriteString(" EMPTY")
+		return nil
+	}
+	wkt.WriteString("(")
+	for i := uint32(0); i < count; i++ {
+		if i > 0 {
+			wkt.WriteString(",")
+		}
+		// Members of a collection keep their type name; members of multi-geometries don't
+		if err := r.readGeometry(wkt, !multi); err != nil {
+			return err
+		}
+	}
+	wkt.WriteString(")")
+	return nil
+}
+
+// wkbTypeName returns the WKT name of a geometry type and whether it is a multi-geometry
+func wkbTypeName(geometryType uint32) (string, bool, error) {
+	switch geometryType {
+	case wkbPoint:
+		return "POINT", false, nil
+	case wkbLineString:
+		return "LINESTRING", false, nil
+	case wkbPolygon:
+		return "POLYGON", false, nil
+	case wkbMultiPoint:
+		return "MULTIPOINT", true, nil
+	case wkbMultiLineString:
+		return "MULTILINESTRING", true, nil
+	case wkbMultiPolygon:
+		return "MULTIPOLYGON", true, nil
+	case wkbGeometryCollection:
+		return "GEOMETRYCOLLECTION", false, nil
+	default:
+		return "", false, fmt.Errorf("unsupported WKB geometry type %d", geometryType)
+	}
+}
+
+func (r *wkbReader) readPoint(wkt *strings.Builder) error {
+	x, err := r.float64()
+	if err != nil {
+		return err
+	}
+	y, err := r.float64()
+	if err != nil {
+		return err
+	}
+	// Shortest representation that round-trips, so no precision is lost
+	wkt.WriteString(strconv.FormatFloat(x, 'g', -1, 64))
+	wkt.WriteString(" ")
+	wkt.WriteString(strconv.FormatFloat(y, 'g', -1, 64))
+	return nil
+}
+
+func (r *wkbReader) readPoints(wkt *strings.Builder) error {
+	count, err := r.uint32()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		wkt.WriteString(" EMPTY")
+		return nil
+	}
+	wkt.WriteString("(")
+	for i := uint32(0); i < count; i++ {
+		if i > 0 {
+			wkt.WriteString(",")
+		}
+		if err := r.readPoint(wkt); err != nil {
+			return err
+		}
+	}
+	wkt.WriteString(")")
+	return nil
+}
+
+func (r *wkbReader) readRings(wkt *strings.Builder) error {
+	count, err := r.uint32()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		wkt.WriteString(" EMPTY")
+		return nil
+	}
+	wkt.WriteString("(")
+	for i := uint32(0); i < count; i++ {
+		if i > 0 {
+			wkt.WriteString(",")
+		}
+		if err := r.readPoints(wkt); err != nil {
+			return err
+		}
+	}
+	wkt.WriteString(")")
+	return nil
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// wkbBuilder assembles geometry values in MySQL's internal format for tests
+type wkbBuilder struct {
+	buf   bytes.Buffer
+	order binary.ByteOrder
+}
+
+func newWKB(srid uint32) *wkbBuilder {
+	b := &wkbBuilder{order: binary.LittleEndian}
+	_ = binary.Write(&b.buf, binary.LittleEndian, srid)
+	return b
+}
+
+func (b *wkbBuilder) header(geometryType uint32) *wkbBuilder {
+	if b.order == binary.BigEndian {
+		b.buf.WriteByte(0)
+	} else {
+		b.buf.WriteByte(1)
+	}
+	return b.uint32(geometryType)
+}
+
+func (b *wkbBuilder) uint32(v uint32) *wkbBuilder {
+	_ = binary.Write(&b.buf, b.order, v)
+	return b
+}
+
+func (b *wkbBuilder) point(x, y float64) *wkbBuilder {
+	_ = binary.Write(&b.buf, b.order, math.Float64bits(x))
+	_ = binary.Write(&b.buf, b.order, math.Float64bits(y))
+	return b
+}
+
+func (b *wkbBuilder) bytes() []byte {
+	return b.buf.Bytes()
+}
+
+func TestDecodeGeometry(t *testing.T) {
+	bigEndian := newWKB(0)
+	bigEndian.order = binary.BigEndian
+
+	tests := []struct {
+		name     string
+		data     []byte
+		wantWKT  string
+		wantSRID uint32
+	}{
+		{
+			name:     "point with srid",
+			data:     newWKB(4326).header(wkbPoint).point(13.4, 52.52).bytes(),
+			wantWKT:  "POINT(13.4 52.52)",
+			wantSRID: 4326,
+		},
+		{
+			name:    "big-endian point",
+			data:    bigEndian.header(wkbPoint).point(-1.5, 2).bytes(),
+			wantWKT: "POINT(-1.5 2)",
+		},
+		{
+			name:    "linestring",
+			data:    newWKB(0).header(wkbLineString).uint32(2).point(0, 0).point(1, 1).bytes(),
+			wantWKT: "LINESTRING(0 0,1 1)",
+		},
+		{
+			name: "polygon",
+			data: newWKB(0).header(wkbPolygon).uint32(1).
+				uint32(4).point(0, 0).point(1, 0).point(1, 1).point(0, 0).bytes(),
+			wantWKT: "POLYGON((0 0,1 0,1 1,0 0))",
+		},
+		{
+			name: "multipoint",
+			data: newWKB(0).header(wkbMultiPoint).uint32(2).
+				header(wkbPoint).point(1, 2).
+				header(wkbPoint).point(3, 4).bytes(),
+			wantWKT: "MULTIPOINT((1 2),(3 4))",
+		},
+		{
+			name: "geometry collection",
+			data: newWKB(0).header(wkbGeometryCollection).uint32(2).
+				header(wkbPoint).point(1, 2).
+				header(wkbLineString).uint32(2).point(0, 0).point(1, 1).bytes(),
+			wantWKT: "GEOMETRYCOLLECTION(POINT(1 2),LINESTRING(0 0,1 1))",
+		},
+		{
+			name:    "empty collection",
+			data:    newWKB(0).header(wkbGeometryCollection).uint32(0).bytes(),
+			wantWKT: "GEOMETRYCOLLECTION EMPTY",
+		},
+		{
+			name:    "full precision",
+			data:    newWKB(0).header(wkbPoint).point(0.1234567890123456, 1e-10).bytes(),
+			wantWKT: "POINT(0.1234567890123456 1e-10)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wkt, srid, err := decodeGeometry(tt.data)
+			if err != nil {
+				t.Fatalf("decodeGeometry() error = %v", err)
+			}
+			if wkt != tt.wantWKT {
+				t.Errorf("decodeGeometry() wkt = %q, want %q", wkt, tt.wantWKT)
+			}
+			if srid != tt.wantSRID {
+				t.Errorf("decodeGeometry() srid = %d, want %d", srid, tt.wantSRID)
+			}
+		})
+	}
+}
+
+func TestDecodeGeometry_Invalid(t *testing.T) {
+	point := newWKB(0).header(wkbPoint).point(1, 2).bytes()
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "too short", data: []byte{0x00, 0x00}},
+		{name: "truncated point", data: point[:len(point)-4]},
+		{name: "trailing bytes", data: append(append([]byte{}, point...), 0x00)},
+		{name: "bad byte order", data: []byte{0, 0, 0, 0, 2, 1, 0, 0, 0}},
+		{name: "unknown type", data: newWKB(0).header(99).bytes()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeGeometry(tt.data); err == nil {
+				t.Error("decodeGeometry() expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"kasho/pkg/types"
+	"kasho/proto"
+
+	"github.com/go-mysql-org/go-mysql/canal"
+	"github.com/go-mysql-org/go-mysql/schema"
+)
+
+// RowImage is the primary's binlog_row_image, which decides the columns row events carry
+type RowImage string
+
+const (
+	// RowImageFull logs every column of the before and after image
+	RowImageFull RowImage = "FULL"
+	// RowImageMinimal logs the key columns of the before image and the columns an
+	// update or insert set in the after image
+	RowImageMinimal RowImage = "MINIMAL"
+	// RowImageNoBlob logs every column except BLOB and TEXT columns that didn't change
+	RowImageNoBlob RowImage = "NOBLOB"
+)
+
+// unchangedValue marks a column left out of a row image whose value couldn't be fetched.
+// It becomes the unchanged column marker, which the SQL generator leaves out of the
+// statement so the replica keeps its value.
+type unchangedValue struct{}
+
+// unchangedColumnValue is the marker of a column whose value the change doesn't carry
+func unchangedColumnValue() types.ColumnValueWrapper {
+	return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_UnchangedToast{UnchangedToast: true}}}
+}
+
+// rowFetcher runs a query on the primary and returns the values of its first row, or nil
+// if it returned none
+type rowFetcher func(query string, args ...interface{}) ([]interface{}, error)
+
+// canalFetcher fetches rows through canal's connection to the primary
+func canalFetcher(c *canal.Canal) rowFetcher {
+	return func(query string, args ...interface{}) ([]interface{}, error) {
+		result, err := c.Execute(query, args...)
+		if err != nil {
+			return nil, err
+		}
+		if result.Resultset == nil || result.RowNumber() == 0 {
+			return nil, nil
+		}
+		values := make([]interface{}, result.ColumnNumber())
+		for i := range values {
+			if values[i], err = result.GetValue(0, i); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+}
+
+// readRowImage reads the primary's global binlog_row_image. Sessions that set their own
+// aren't detected.
+func readRowImage(c *canal.Canal) (RowImage, error) {
+	result, err := c.Execute("SELECT @@GLOBAL.binlog_row_image")
+	if err != nil {
+		return "", fmt.Errorf("failed to read binlog_row_image: %w", err)
+	}
+	image, err := result.GetString(0, 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to read binlog_row_image: %w", err)
+	}
+	return RowImage(strings.ToUpper(image)), nil
+}
+
+// fillRowImage completes the rows of an event logged with a MINIMAL or NOBLOB row image,
+// so RowsEventToChanges sees full rows. Key columns left out of an update's after image
+// didn't change and are copied from the before image. Other missing columns are fetched
+// from the primary by key when fetcher isn't nil, which gives their current value rather
+// than the one at the event; columns that can't be fetched are marked unchanged.
+//
+// A missing column can't be told apart from one set to NULL, so with MINIMAL every NULL
+// is fetched, and with NOBLOB every NULL BLOB or TEXT column. Deletes only need the key,
+// which every image carries.
+func fillRowImage(e *canal.RowsEvent, image RowImage, fetcher rowFetcher) {
+	if image == RowImageFull || image == "" || len(e.Table.PKColumns) == 0 {
+		return
+	}
+
+	switch e.Action {
+	case canal.InsertAction:
+		// Inserts carry every column with NOBLOB
+		if image != RowImageMinimal {
+			return
+		}
+		for _, row := range e.Rows {
+			fillRow(e.Table, row, missingColumns(e.Table, row, image), fetcher)
+		}
+
+	case canal.UpdateAction:
+		for i := 0; i+1 < len(e.Rows); i += 2 {
+			oldRow, newRow := e.Rows[i], e.Rows[i+1]
+			for _, idx := range e.Table.PKColumns {
+				if idx < len(newRow) && idx < len(oldRow) && newRow[idx] == nil {
+					newRow[idx] = oldRow[idx]
+				}
+			}
+			fillRow(e.Table, newRow, missingColumns(e.Table, newRow, image), fetcher)
+		}
+	}
+}
+
+// missingColumns returns the indices of the row's columns that the row image may have left out
+func missingColumns(table *schema.Table, row []interface{}, image RowImage) []int {
+	var missing []int
+	for idx := range table.Columns {
+		if idx >= len(row) || row[idx] != nil || isPrimaryKey(&table.Columns[idx], table) {
+			continue
+		}
+		if image == RowImageNoBlob && !isLargeColumn(&table.Columns[idx]) {
+			continue
+		}
+		missing = append(missing, idx)
+	}
+	return missing
+}
+
+// isLargeColumn reports whether a column is a BLOB or TEXT column, which NOBLOB leaves out
+// when unchanged
+func isLargeColumn(col *schema.TableColumn) bool {
+	rawType := strings.ToLower(col.RawType)
+	return strings.Contains(rawType, "blob") || strings.Contains(rawType, "text")
+}
+
+// fillRow sets the missing columns of a row to their current value on the primary, or
+// marks them unchanged
+func fillRow(table *schema.Table, row []interface{}, missing []int, fetcher rowFetcher) {
+	if len(missing) == 0 {
+		return
+	}
+	if fetcher != nil {
+		values, err := fetchColumns(table, row, missing, fetcher)
+		if err == nil {
+			for i, idx := range missing {
+				row[idx] = values[i]
+			}
+			return
+		}
+		log.Printf("Failed to fetch columns left out of the row image of %s.%s, leaving them unchanged: %v", table.Schema, table.Name, err)
+	}
+	for _, idx := range missing {
+		row[idx] = unchangedValue{}
+	}
+}
+
+// fetchColumns reads columns of a row from the primary by its primary key
+func fetchColumns(table *schema.Table, row []interface{}, columns []int, fetcher rowFetcher) ([]interface{}, error) {
+	names := make([]string, len(columns))
+	for i, idx := range columns {
+		names[i] = quoteIdentifier(table.Columns[idx].Name)
+	}
+	conditions := make([]string, 0, len(table.PKColumns))
+	args := make([]interface{}, 0, len(table.PKColumns))
+	for _, idx := range table.PKColumns {
+		if idx >= len(row) || row[idx] == nil {
+			return nil, fmt.Errorf("primary key column %d is missing", idx)
+		}
+		conditions = append(conditions, quoteIdentifier(table.Columns[idx].Name)+" = ?")
+		args = append(args, row[idx])
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s.%s WHERE %s",
+		strings.Join(names, ", "), quoteIdentifier(table.Schema), quoteIdentifier(table.Name), strings.Join(conditions, " AND "))
+	values, err := fetcher(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if values == nil {
+		return nil, fmt.Errorf("row no longer exists")
+	}
+	if len(values) != len(columns) {
+		return nil, fmt.Errorf("fetched %d columns, want %d", len(values), len(columns))
+	}
+	return values, nil
+}
+
+// quoteIdentifier quotes a MySQL identifier with backticks
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"kasho/pkg/types"
+
+	"github.com/go-mysql-org/go-mysql/canal"
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/schema"
+)
+
+func makeDocumentsTable() *schema.Table {
+	return &schema.Table{
+		Name:   "documents",
+		Schema: "testdb",
+		Columns: []schema.TableColumn{
+			{Name: "id", Type: schema.TYPE_NUMBER, RawType: "int"},
+			{Name: "title", Type: schema.TYPE_STRING, RawType: "varchar(255)"},
+			{Name: "body", Type: schema.TYPE_STRING, RawType: "text"},
+		},
+		PKColumns: []int{0},
+	}
+}
+
+func TestFillRowImage_MinimalUpdateFetches(t *testing.T) {
+	var gotQuery string
+	var gotArgs []interface{}
+	fetcher := func(query string, args ...interface{}) ([]interface{}, error) {
+		gotQuery, gotArgs = query, args
+		return []interface{}{[]byte("Body")}, nil
+	}
+
+	// MINIMAL logs the key before and the changed title after
+	event := &canal.RowsEvent{
+		Table:  makeDocumentsTable(),
+		Action: canal.UpdateAction,
+		Rows: [][]interface{}{
+			{int64(1), nil, nil},
+			{nil, "New title", nil},
+		},
+	}
+	fillRowImage(event, RowImageMinimal, fetcher)
+
+	if want := "SELECT `body` FROM `testdb`.`documents` WHERE `id` = ?"; gotQuery != want {
+		t.Errorf("query = %q, want %q", gotQuery, want)
+	}
+	if want := []interface{}{int64(1)}; !reflect.DeepEqual(gotArgs, want) {
+		t.Errorf("args = %v, want %v", gotArgs, want)
+	}
+
+	changes := RowsEventToChanges(event, mysql.Position{Name: "mysql-bin.000001", Pos: 4})
+	dml := changes[0].Data.(*types.DMLData)
+	if want := []string{"title", "body"}; !reflect.DeepEqual(dml.ColumnNames, want) {
+		t.Fatalf("ColumnNames = %v, want %v", dml.ColumnNames, want)
+	}
+	if got := dml.ColumnValues[1].GetStringValue(); got != "Body" {
+		t.Errorf("body = %q, want the fetched value", got)
+	}
+	if got := dml.OldKeys.KeyValues[0].GetIntValue(); got != 1 {
+		t.Errorf("old key = %d, want 1", got)
+	}
+}
+
+func TestFillRowImage_MarksUnchanged(t *testing.T) {
+	tests := []struct {
+		name    string
+		image   RowImage
+		fetcher rowFetcher
+	}{
+		{name: "fetching off", image: RowImageMinimal},
+		{name: "row deleted since", image: RowImageMinimal, fetcher: func(string, ...interface{}) ([]interface{}, error) { return nil, nil }},
+		{name: "fetch fails", image: RowImageNoBlob, fetcher: func(string, ...interface{}) ([]interface{}, error) { return nil, errors.New("connection lost") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &canal.RowsEvent{
+				Table:  makeDocumentsTable(),
+				Action: canal.UpdateAction,
+				Rows: [][]interface{}{
+					{int64(1), "Old title", nil},
+					{int64(1), "New title", nil},
+				},
+			}
+			fillRowImage(event, tt.image, tt.fetcher)
+
+			dml := RowsEventToChanges(event, mysql.Position{})[0].Data.(*types.DMLData)
+			if !dml.ColumnValues[1].GetUnchangedToast() {
+				t.Errorf("body = %v, want unchanged", dml.ColumnValues[1].GetValue())
+			}
+			if dml.ColumnValues[0].GetUnchangedToast() {
+				t.Error("title should keep its logged value")
+			}
+		})
+	}
+}
+
+func TestFillRowImage_NoBlobOnlyLargeColumns(t *testing.T) {
+	fetched := false
+	fetcher := func(string, ...interface{}) ([]interface{}, error) {
+		fetched = true
+		return nil, nil
+	}
+
+	// A NULL VARCHAR is logged with NOBLOB, and inserts carry every column
+	for _, event := range []*canal.RowsEvent{
+		{Table: makeDocumentsTable(), Action: canal.UpdateAction, Rows: [][]interface{}{{int64(1), "a", "b"}, {int64(1), nil, "b"}}},
+		{Table: makeDocumentsTable(), Action: canal.InsertAction, Rows: [][]interface{}{{int64(1), nil, nil}}},
+	} {
+		fillRowImage(event, RowImageNoBlob, fetcher)
+	}
+	if fetched {
+		t.Error("NOBLOB fetched a column it always logs")
+	}
+}
+
+func TestFillRowImage_FullUntouched(t *testing.T) {
+	event := &canal.RowsEvent{
+		Table:  makeDocumentsTable(),
+		Action: canal.InsertAction,
+		Rows:   [][]interface{}{{int64(1), nil, nil}},
+	}
+	fillRowImage(event, RowImageFull, func(string, ...interface{}) ([]interface{}, error) {
+		t.Fatal("FULL row images shouldn't be fetched")
+		return nil, nil
+	})
+	if want := []interface{}{int64(1), nil, nil}; !reflect.DeepEqual(event.Rows[0], want) {
+		t.Errorf("row = %v, want %v", event.Rows[0], want)
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"kasho/pkg/auth"
+	"kasho/pkg/kvbuffer"
+	"kasho/pkg/notify"
+	"kasho/pkg/streamserver"
+	"kasho/pkg/version"
+	"kasho/proto"
+	"oracle-change-stream/internal/server"
+
+	"google.golang.org/grpc"
+)
+
+func main() {
+	log.Printf("oracle-change-stream version %s (commit: %s, built: %s)",
+		version.Version, version.GitCommit, version.BuildDate)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	kvURL := os.Getenv("KV_URL")
+	if kvURL == "" {
+		log.Fatal("KV_URL environment variable is required")
+	}
+
+	buffer, err := kvbuffer.NewKVBuffer(kvURL)
+	if err != nil {
+		log.Fatalf("Failed to create KV buffer: %v", err)
+	}
+	defer func() {
+		if err := buffer.Close(); err != nil {
+			log.Printf("Failed to flush KV buffer: %v", err)
+		}
+	}()
+
+	// Optional batching of buffer writes into pipelined transactions, for bursts of changes
+	batchSize, err := strconv.Atoi(getEnvOrDefault("KV_BATCH_SIZE", "1"))
+	if err != nil {
+		log.Fatalf("Invalid KV_BATCH_SIZE: %v", err)
+	}
+	batchInterval, err := time.ParseDuration(getEnvOrDefault("KV_BATCH_INTERVAL", "10ms"))
+	if err != nil {
+		log.Fatalf("Invalid KV_BATCH_INTERVAL: %v", err)
+	}
+	if batchSize > 1 {
+		buffer.EnableBatching(batchSize, batchInterval)
+		log.Printf("Batching KV writes: up to %d changes every %s", batchSize, batchInterval)
+	}
+
+	// Optional spilling of the oldest buffered changes to disk when Redis runs short of memory
+	if spillDir := os.Getenv("KV_SPILL_DIR"); spillDir != "" {
+		maxMemory, err := strconv.ParseInt(getEnvOrDefault("KV_SPILL_MAX_MEMORY", "0"), 10, 64)
+		if err != nil || maxMemory <= 0 {
+			log.Fatal("KV_SPILL_MAX_MEMORY must be a positive number of bytes when KV_SPILL_DIR is set")
+		}
+		if err := buffer.EnableSpill(spillDir, maxMemory); err != nil {
+			log.Fatalf("Failed to enable KV spill: %v", err)
+		}
+		log.Printf("Spilling KV buffer to %s when Redis uses over %d bytes", spillDir, maxMemory)
+	}
+
+	// Create the gRPC server
+	changeStreamServer := server.NewChangeStreamServer(buffer)
+
+	// Enable token authentication on the Stream RPC if tokens are configured
+	if tokenSpec := os.Getenv("CHANGE_STREAM_TOKENS"); tokenSpec != "" {
+		tokens, err := auth.ParseTokens(tokenSpec)
+		if err != nil {
+			log.Fatalf("Invalid CHANGE_STREAM_TOKENS: %v", err)
+		}
+		changeStreamServer.SetAuthenticator(auth.NewAuthenticator(tokens))
+		log.Printf("Stream authentication enabled with %d token(s)", len(tokens))
+	}
+
+	// Initialize state from Redis
+	dbURL := os.Getenv("PRIMARY_DATABASE_URL")
+	if dbURL == "" {
+		log.Fatal("PRIMARY_DATABASE_URL environment variable is required")
+	}
+
+	// Several schemas can share one pipeline; by default every non-system schema is captured.
+	// Oracle stores unquoted names in upper case.
+	captureSchemas := splitList(strings.ToUpper(os.Getenv("CAPTURE_SCHEMAS")))
+	if len(captureSchemas) > 0 {
+		log.Printf("Capturing schemas: %s", strings.Join(captureSchemas, ", "))
+	}
+
+	// Every poll starts a LogMiner session over the redo logs, which takes a moment, so
+	// polling much more often mostly adds load
+	pollInterval, err := time.ParseDuration(getEnvOrDefault("LOGMINER_POLL_INTERVAL", "5s"))
+	if err != nil || pollInterval <= 0 {
+		log.Fatalf("Invalid LOGMINER_POLL_INTERVAL: %q", os.Getenv("LOGMINER_POLL_INTERVAL"))
+	}
+
+	state, err := changeStreamServer.LoadState(ctx)
+	if err != nil {
+		log.Printf("Failed to load state from Redis, using defaults: %v", err)
+	}
+
+	// Determine initial state
+	// For Oracle, we don't have replication slots to check, so we rely on saved state
+	initialState := server.StateWaiting
+	if state != nil && state.Current == server.StateStreaming {
+		initialState = server.StateStreaming
+	}
+
+	if state == nil {
+		state = &server.StateInfo{
+			Current:        initialState,
+			TransitionTime: time.Now(),
+		}
+	} else {
+		state.Current = initialState
+	}
+
+	changeStreamServer.SetState(state)
+	log.Printf("Starting in %s state", state.Current)
+
+	// Optional server-wide pacing limits for the Stream RPC
+	maxChangesPerSecond, err := strconv.ParseUint(getEnvOrDefault("CHANGE_STREAM_MAX_CHANGES_PER_SECOND", "0"), 10, 32)
+	if err != nil {
+		log.Fatalf("Invalid CHANGE_STREAM_MAX_CHANGES_PER_SECOND: %v", err)
+	}
+	maxBytesPerSecond, err := strconv.ParseUint(getEnvOrDefault("CHANGE_STREAM_MAX_BYTES_PER_SECOND", "0"), 10, 64)
+	if err != nil {
+		log.Fatalf("Invalid CHANGE_STREAM_MAX_BYTES_PER_SECOND: %v", err)
+	}
+	maxInFlightBytes, err := strconv.ParseUint(getEnvOrDefault("CHANGE_STREAM_MAX_IN_FLIGHT_BYTES", "0"), 10, 64)
+	if err != nil {
+		log.Fatalf("Invalid CHANGE_STREAM_MAX_IN_FLIGHT_BYTES: %v", err)
+	}
+	changeStreamServer.SetStreamLimits(uint32(maxChangesPerSecond), maxBytesPerSecond, maxInFlightBytes)
+
+	// Heartbeats let consumers detect dead connections on idle databases
+	heartbeatInterval, err := time.ParseDuration(getEnvOrDefault("CHANGE_STREAM_HEARTBEAT_INTERVAL", "10s"))
+	if err != nil {
+		log.Fatalf("Invalid CHANGE_STREAM_HEARTBEAT_INTERVAL: %v", err)
+	}
+	changeStreamServer.SetHeartbeatInterval(heartbeatInterval)
+
+	// Optional limits on how long a bootstrap may hold the service in ACCUMULATING
+	accumulationLimits, err := streamserver.AccumulationLimitsFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid accumulation limits: %v", err)
+	}
+	changeStreamServer.SetAccumulationLimits(accumulationLimits)
+	if accumulationLimits.Enabled() {
+		go changeStreamServer.MonitorAccumulation(ctx, 10*time.Second)
+		log.Printf("Accumulation limits enabled, moving to %s when reached", accumulationLimits.Action)
+	}
+
+	// Alert notifications (webhook, Slack, email) when the KV buffer grows too deep
+	notifier, err := notify.New(notify.ConfigFromEnv())
+	if err != nil {
+		log.Fatalf("Invalid notification config: %v", err)
+	}
+	bufferDepthThreshold, err := strconv.ParseInt(getEnvOrDefault("ALERT_BUFFER_DEPTH", "0"), 10, 64)
+	if err != nil {
+		log.Fatalf("Invalid ALERT_BUFFER_DEPTH: %v", err)
+	}
+	if notifier != nil && bufferDepthThreshold > 0 {
+		go monitorBufferDepth(ctx, buffer, notify.NewMonitor("oracle-change-stream", notifier), bufferDepthThreshold)
+		log.Printf("Alerting when buffer depth reaches %d changes", bufferDepthThreshold)
+	}
+
+	// gRPC listen address: all interfaces by default, or a specific interface or Unix socket
+	lis, err := streamserver.Listen(os.Getenv("GRPC_HOST"), getEnvOrDefault("GRPC_PORT", "50051"))
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+	log.Printf("gRPC server listening on %s %s", lis.Addr().Network(), lis.Addr())
+	s := grpc.NewServer()
+	proto.RegisterChangeStreamServer(s, changeStreamServer)
+	go func() {
+		if err := s.Serve(lis); err != nil {
+			log.Fatalf("failed to serve: %v", err)
+		}
+	}()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		log.Println("Received shutdown signal")
+		s.GracefulStop()
+		cancel()
+	}()
+
+	// Start LogMiner polling goroutine that monitors state changes
+	go func() {
+		var client *server.Client
+		var clientDone <-chan struct{}
+		var changeWg sync.WaitGroup
+
+		for {
+			select {
+			case <-ctx.Done():
+				if client != nil {
+					client.Close(ctx)
+					changeWg.Wait() // Wait for change processor to finish
+				}
+				return
+			case <-time.After(1 * time.Second):
+				currentState := changeStreamServer.CurrentState()
+
+				// If we're in STREAMING state but don't have a client, create one
+				if currentState == server.StateStreaming && client == nil {
+					log.Println("In STREAMING state, starting LogMiner client")
+
+					// Resume after the last buffered change, or from the bootstrap start position
+					startPos, err := changeStreamServer.LoadResumePosition(ctx)
+					if err != nil {
+						log.Printf("Failed to load resume position: %v", err)
+						continue
+					}
+					if startPos == "" {
+						startPos = changeStreamServer.GetStartPosition()
+					}
+
+					client, err = server.NewClient(ctx, dbURL, captureSchemas, pollInterval, startPos)
+					if err != nil {
+						log.Printf("Failed to create LogMiner client: %v", err)
+						continue
+					}
+					clientDone = client.Done()
+
+					// Start goroutine to process changes from the client
+					changeWg.Add(1)
+					go func(c *server.Client, done <-chan struct{}) {
+						defer changeWg.Done()
+						for {
+							select {
+							case <-ctx.Done():
+								return
+							case <-done:
+								// Client was closed, exit this goroutine
+								return
+							case change, ok := <-c.Changes():
+								if !ok {
+									return
+								}
+								// Store change in KV buffer
+								if err := buffer.AddChange(ctx, change); err != nil {
+									log.Printf("Error storing change in KV: %v", err)
+									continue
+								}
+								if err := changeStreamServer.SaveResumePosition(ctx, change.Position); err != nil {
+									log.Printf("Error saving resume position: %v", err)
+								}
+
+								// Update accumulated count if in ACCUMULATING state
+								if changeStreamServer.CurrentState() == server.StateAccumulating {
+									changeStreamServer.IncrementAccumulated()
+								}
+							}
+						}
+					}(client, clientDone)
+				} else if currentState != server.StateStreaming && client != nil {
+					log.Println("Not in STREAMING state, closing LogMiner client")
+					client.Close(ctx)
+					changeWg.Wait() // Wait for change processor to finish before clearing client
+					client = nil
+					clientDone = nil
+				}
+
+				// Track the LogMiner position for status reporting and heartbeats
+				if client != nil {
+					if pos := client.GetPosition(); pos != "" {
+						changeStreamServer.SetCurrentPosition(pos)
+					}
+				}
+			}
+		}
+	}()
+
+	// Wait for shutdown signal
+	<-ctx.Done()
+	log.Println("Shutting down oracle-change-stream")
+}
+
+// monitorBufferDepth periodically compares the number of buffered changes against the alert threshold
+func monitorBufferDepth(ctx context.Context, buffer *kvbuffer.KVBuffer, monitor *notify.Monitor, threshold int64) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			depth, err := buffer.Depth(ctx)
+			if err != nil {
+				log.Printf("Failed to check buffer depth: %v", err)
+				continue
+			}
+			monitor.Check(ctx, "buffer_depth", float64(depth), float64(threshold),
+				"%d changes held in the KV buffer (threshold %d)", depth, threshold)
+		}
+	}
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// splitList splits a comma-separated list, ignoring blank entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,46 @@
+module oracle-change-stream
+
+go 1.24.3
+
+require (
+	github.com/sijms/go-ora/v2 v2.9.0
+	google.golang.org/grpc v1.72.1
+	google.golang.org/protobuf v1.36.6
+	kasho/pkg/auth v0.0.0
+	kasho/pkg/kvbuffer v0.0.0-00010101000000-000000000000
+	kasho/pkg/notify v0.0.0
+	kasho/pkg/streamserver v0.0.0
+	kasho/pkg/types v0.0.0-00010101000000-000000000000
+	kasho/pkg/version v0.0.0
+	kasho/proto v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/jackc/pgio v1.0.0 // indirect
+	github.com/jackc/pglogrepl v0.0.0-20250509230407-a9884f6bd75a // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a // indirect
+	github.com/jackc/pgx/v5 v5.5.4 // indirect
+	github.com/redis/go-redis/v9 v9.8.0 // indirect
+	golang.org/x/crypto v0.39.0 // indirect
+	golang.org/x/net v0.40.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/text v0.26.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250218202821-56aae31c358a // indirect
+)
+
+replace kasho/pkg/auth => ../../pkg/auth
+
+replace kasho/pkg/notify => ../../pkg/notify
+
+replace kasho/pkg/streamserver => ../../pkg/streamserver
+
+replace kasho/pkg/kvbuffer => ../../pkg/kvbuffer
+
+replace kasho/pkg/types => ../../pkg/types
+
+replace kasho/pkg/version => ../../pkg/version
+
+replace kasho/proto => ../../proto/kasho/proto
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"kasho/pkg/types"
+	"kasho/proto"
+)
+
+// LogMiner operation codes
+const (
+	opInsert = 1
+	opDelete = 2
+	opUpdate = 3
+	opDDL    = 5
+)
+
+// minedRow is a row of V$LOGMNR_CONTENTS, with statements continued over several rows
+// joined
+type minedRow struct {
+	CommitSCN  uint64
+	XID        string
+	Operation  int
+	Owner      string
+	Table      string
+	Username   string
+	SQLRedo    string
+	Time       time.Time // when the change was made
+	CommitTime time.Time
+}
+
+// tableInfo describes a captured table
+type tableInfo struct {
+	Columns    []string          // in table order
+	Types      map[string]string // data type by column, e.g. "NUMBER" or "TIMESTAMP(6)"
+	Scales     map[string]int    // scale of NUMBER columns; missing when unspecified
+	PrimaryKey []string
+}
+
+// rowToChange converts a mined row to a change. table describes the changed table and
+// may be nil for DDL or when the table is unknown; values are then kept as text.
+func rowToChange(row *minedRow, table *tableInfo) (*types.Change, error) {
+	var change *types.Change
+	if row.Operation == opDDL {
+		ddl := strings.TrimSpace(row.SQLRedo)
+		if ddl == "" {
+			return nil, nil
+		}
+		change = &types.Change{Data: types.DDLData{
+			Time:     row.Time,
+			Username: row.Username,
+			Database: row.Owner,
+			DDL:      ddl,
+		}}
+	} else {
+		stmt, err := parseRedo(row.SQLRedo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse redo of %s.%s: %w", row.Owner, row.Table, err)
+		}
+		change = &types.Change{Data: statementToDML(stmt, table)}
+	}
+
+	change.Dialect = "oracle"
+	change.Schema = row.Owner
+	change.TransactionID = row.XID
+	change.CommitTime = row.CommitTime
+	return change, nil
+}
+
+// statementToDML converts a redo statement to DML data. The WHERE clause of an update
+// holds the row's old values, which fill in the columns the SET clause doesn't change
+// when supplemental logging covers all columns.
+func statementToDML(stmt *redoStatement, table *tableInfo) *types.DMLData {
+	dml := &types.DMLData{
+		Table:  stmt.Owner + "." + stmt.Table,
+		Kind:   stmt.Kind,
+		Schema: stmt.Owner,
+	}
+	if table != nil {
+		dml.PrimaryKey = table.PrimaryKey
+	}
+
+	var row []redoColumn
+	switch stmt.Kind {
+	case "insert":
+		row = stmt.Set
+	case "update":
+		row = slices.Clone(stmt.Where)
+		for _, col := range stmt.Set {
+			if i := slices.IndexFunc(row, func(c redoColumn) bool { return c.Name == col.Name }); i >= 0 {
+				row[i] = col
+			} else {
+				row = append(row, col)
+			}
+		}
+		if table != nil {
+			// Keep the table's column order
+			slices.SortStableFunc(row, func(a, b redoColumn) int {
+				return slices.Index(table.Columns, a.Name) - slices.Index(table.Columns, b.Name)
+			})
+		}
+	}
+	for _, col := range row {
+		dml.ColumnNames = append(dml.ColumnNames, col.Name)
+		dml.ColumnValues = append(dml.ColumnValues, toColumnValue(col.Value, table, col.Name))
+	}
+
+	if stmt.Kind == "update" || stmt.Kind == "delete" {
+		dml.OldKeys = oldKeys(stmt.Where, table)
+	}
+	return dml
+}
+
+// oldKeys builds the old key of a row from the WHERE clause of its update or delete:
+// the primary key columns, or every column of a table without one
+func oldKeys(where []redoColumn, table *tableInfo) *struct {
+	KeyNames  []string                   `json:"keynames"`
+	KeyValues []types.ColumnValueWrapper `json:"keyvalues"`
+} {
+	keys := &struct {
+		KeyNames  []string                   `json:"keynames"`
+		KeyValues []types.ColumnValueWrapper `json:"keyvalues"`
+	}{}
+	for _, col := range where {
+		if table != nil && len(table.PrimaryKey) > 0 && !slices.Contains(table.PrimaryKey, col.Name) {
+			continue
+		}
+		keys.KeyNames = append(keys.KeyNames, col.Name)
+		keys.KeyValues = append(keys.KeyValues, toColumnValue(col.Value, table, col.Name))
+	}
+	return keys
+}
+
+// Layouts of the values LogMiner writes in redo statements, following the NLS formats
+// set on the mining session
+const (
+	dateLayout        = "2006-01-02 15:04:05"
+	timestampLayout   = "2006-01-02 15:04:05.999999999"
+	timestampTZLayout = "2006-01-02 15:04:05.999999999 -07:00"
+)
+
+// toColumnValue converts a redo value to our ColumnValueWrapper using the column's type
+func toColumnValue(v redoValue, table *tableInfo, column string) types.ColumnValueWrapper {
+	if v.Null {
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{}}
+	}
+	if v.Function == "HEXTORAW" {
+		if b, err := hex.DecodeString(v.Text); err == nil {
+			return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: b}}}
+		}
+	}
+
+	var dataType string
+	scale, hasScale := 0, false
+	if table != nil {
+		dataType = strings.ToUpper(table.Types[column])
+		scale, hasScale = table.Scales[column]
+	}
+
+	switch {
+	case dataType == "NUMBER" || dataType == "INTEGER" || dataType == "SMALLINT":
+		// NUMBER without a scale may hold either; exact decimals are kept as text
+		if !hasScale || scale == 0 {
+			if i, err := strconv.ParseInt(v.Text, 10, 64); err == nil {
+				return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: i}}}
+			}
+		}
+	case dataType == "FLOAT" || dataType == "BINARY_FLOAT" || dataType == "BINARY_DOUBLE":
+		if f, err := strconv.ParseFloat(v.Text, 64); err == nil {
+			return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_FloatValue{FloatValue: f}}}
+		}
+	case dataType == "DATE" || strings.HasPrefix(dataType, "TIMESTAMP"):
+		// Values without a time zone are taken as UTC, the mining session's time zone
+		for _, layout := range []string{timestampTZLayout, timestampLayout, dateLayout} {
+			if t, err := time.Parse(layout, v.Text); err == nil {
+				return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: t.UTC().Format(time.RFC3339Nano)}}}
+			}
+		}
+	case dataType == "RAW" || dataType == "LONG RAW" || dataType == "BLOB":
+		// Binary values are written with HEXTORAW, or EMPTY_BLOB() for LOBs written later
+		if b, err := hex.DecodeString(v.Text); err == nil {
+			return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: b}}}
+		}
+	case dataType == "JSON":
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_JsonValue{JsonValue: v.Text}}}
+	}
+
+	// Character types, intervals and anything else are kept as text
+	return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: v.Text}}}
+}
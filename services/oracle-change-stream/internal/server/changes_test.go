@@ -0,0 +1,174 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"kasho/pkg/types"
+)
+
+var usersTable = &tableInfo{
+	Columns:    []string{"ID", "NAME", "BALANCE", "CREATED_AT", "AVATAR"},
+	Types:      map[string]string{"ID": "NUMBER", "NAME": "VARCHAR2", "BALANCE": "NUMBER", "CREATED_AT": "TIMESTAMP(6) WITH TIME ZONE", "AVATAR": "RAW"},
+	Scales:     map[string]int{"ID": 0, "BALANCE": 2},
+	PrimaryKey: []string{"ID"},
+}
+
+func TestRowToChangeInsert(t *testing.T) {
+	commitTime := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	row := &minedRow{
+		CommitSCN:  100,
+		XID:        "0A001F00B6040000",
+		Operation:  opInsert,
+		Owner:      "HR",
+		Table:      "USERS",
+		SQLRedo:    `insert into "HR"."USERS"("ID","NAME","BALANCE","CREATED_AT","AVATAR") values ('1','alice','12.50',TO_TIMESTAMP_TZ('2025-01-01 14:00:00.5 +02:00'),HEXTORAW('dead'));`,
+		CommitTime: commitTime,
+	}
+
+	change, err := rowToChange(row, usersTable)
+	if err != nil {
+		t.Fatalf("rowToChange() error = %v", err)
+	}
+	if change.Dialect != "oracle" || change.Schema != "HR" || change.TransactionID != "0A001F00B6040000" || !change.CommitTime.Equal(commitTime) {
+		t.Errorf("rowToChange() metadata = %+v", change)
+	}
+
+	dml, ok := change.Data.(*types.DMLData)
+	if !ok {
+		t.Fatalf("change data = %T, want *types.DMLData", change.Data)
+	}
+	if dml.Table != "HR.USERS" || dml.Kind != "insert" || len(dml.PrimaryKey) != 1 {
+		t.Errorf("DML = %+v", dml)
+	}
+	if got := dml.ColumnValues[0].GetIntValue(); got != 1 {
+		t.Errorf("ID = %v, want 1", got)
+	}
+	if got := dml.ColumnValues[2].GetStringValue(); got != "12.50" {
+		t.Errorf("BALANCE = %v, want 12.50", got)
+	}
+	if got := dml.ColumnValues[3].GetTimestampValue(); got != "2025-01-01T12:00:00.5Z" {
+		t.Errorf("CREATED_AT = %v, want 2025-01-01T12:00:00.5Z", got)
+	}
+	if got := dml.ColumnValues[4].GetBytesValue(); string(got) != "\xde\xad" {
+		t.Errorf("AVATAR = %x, want dead", got)
+	}
+}
+
+func TestRowToChangeUpdate(t *testing.T) {
+	row := &minedRow{
+		Operation: opUpdate,
+		Owner:     "HR",
+		Table:     "USERS",
+		SQLRedo:   `update "HR"."USERS" set "NAME" = 'bob' where "ID" = '1' and "NAME" = 'alice' and "BALANCE" IS NULL;`,
+	}
+
+	change, err := rowToChange(row, usersTable)
+	if err != nil {
+		t.Fatalf("rowToChange() error = %v", err)
+	}
+	dml := change.Data.(*types.DMLData)
+	if dml.Kind != "update" {
+		t.Errorf("kind = %v, want update", dml.Kind)
+	}
+
+	// Unchanged columns come from the old values, in table order
+	wantColumns := []string{"ID", "NAME", "BALANCE"}
+	if len(dml.ColumnNames) != len(wantColumns) {
+		t.Fatalf("columns = %v, want %v", dml.ColumnNames, wantColumns)
+	}
+	for i, name := range wantColumns {
+		if dml.ColumnNames[i] != name {
+			t.Errorf("column %d = %v, want %v", i, dml.ColumnNames[i], name)
+		}
+	}
+	if got := dml.ColumnValues[1].GetStringValue(); got != "bob" {
+		t.Errorf("NAME = %v, want bob", got)
+	}
+	if dml.ColumnValues[2].Value != nil {
+		t.Errorf("BALANCE = %v, want NULL", dml.ColumnValues[2])
+	}
+
+	if dml.OldKeys == nil || len(dml.OldKeys.KeyNames) != 1 || dml.OldKeys.KeyNames[0] != "ID" || dml.OldKeys.KeyValues[0].GetIntValue() != 1 {
+		t.Errorf("old keys = %+v, want ID 1", dml.OldKeys)
+	}
+}
+
+func TestRowToChangeDeleteWithoutPrimaryKey(t *testing.T) {
+	row := &minedRow{
+		Operation: opDelete,
+		Owner:     "HR",
+		Table:     "LOG",
+		SQLRedo:   `delete from "HR"."LOG" where "MSG" = 'hi' and "LVL" = '3';`,
+	}
+
+	change, err := rowToChange(row, &tableInfo{Columns: []string{"MSG", "LVL"}, Types: map[string]string{"MSG": "VARCHAR2", "LVL": "NUMBER"}})
+	if err != nil {
+		t.Fatalf("rowToChange() error = %v", err)
+	}
+	dml := change.Data.(*types.DMLData)
+	if len(dml.ColumnNames) != 0 {
+		t.Errorf("delete columns = %v, want none", dml.ColumnNames)
+	}
+	// Without a primary key the whole old row identifies it
+	if dml.OldKeys == nil || len(dml.OldKeys.KeyNames) != 2 || dml.OldKeys.KeyValues[1].GetIntValue() != 3 {
+		t.Errorf("old keys = %+v, want MSG and LVL", dml.OldKeys)
+	}
+}
+
+func TestRowToChangeDDL(t *testing.T) {
+	ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	row := &minedRow{
+		Operation: opDDL,
+		Owner:     "HR",
+		Table:     "USERS",
+		Username:  "APP",
+		SQLRedo:   "ALTER TABLE users ADD (email VARCHAR2(255)) ",
+		Time:      ts,
+	}
+
+	change, err := rowToChange(row, nil)
+	if err != nil {
+		t.Fatalf("rowToChange() error = %v", err)
+	}
+	ddl, ok := change.Data.(types.DDLData)
+	if !ok {
+		t.Fatalf("change data = %T, want types.DDLData", change.Data)
+	}
+	if ddl.DDL != "ALTER TABLE users ADD (email VARCHAR2(255))" || ddl.Username != "APP" || ddl.Database != "HR" || !ddl.Time.Equal(ts) {
+		t.Errorf("DDL = %+v", ddl)
+	}
+}
+
+func TestRowToChangeInvalidRedo(t *testing.T) {
+	row := &minedRow{Operation: opInsert, Owner: "HR", Table: "USERS", SQLRedo: "Unsupported"}
+	if _, err := rowToChange(row, usersTable); err == nil {
+		t.Error("rowToChange() error = nil, want a parse error")
+	}
+}
+
+func TestToColumnValue(t *testing.T) {
+	table := &tableInfo{
+		Types:  map[string]string{"N": "NUMBER", "F": "BINARY_DOUBLE", "D": "DATE", "J": "JSON"},
+		Scales: map[string]int{},
+	}
+
+	if got := toColumnValue(redoValue{Text: "42"}, table, "N").GetIntValue(); got != 42 {
+		t.Errorf("NUMBER = %v, want 42", got)
+	}
+	if got := toColumnValue(redoValue{Text: "4.2"}, table, "N").GetStringValue(); got != "4.2" {
+		t.Errorf("NUMBER without scale = %v, want 4.2", got)
+	}
+	if got := toColumnValue(redoValue{Text: "1.5"}, table, "F").GetFloatValue(); got != 1.5 {
+		t.Errorf("BINARY_DOUBLE = %v, want 1.5", got)
+	}
+	if got := toColumnValue(redoValue{Text: "2025-01-02 03:04:05", Function: "TO_DATE"}, table, "D").GetTimestampValue(); got != "2025-01-02T03:04:05Z" {
+		t.Errorf("DATE = %v, want 2025-01-02T03:04:05Z", got)
+	}
+	if got := toColumnValue(redoValue{Text: `{"a":1}`}, table, "J").GetJsonValue(); got != `{"a":1}` {
+		t.Errorf("JSON = %v", got)
+	}
+	if got := toColumnValue(redoValue{Text: "x"}, nil, "UNKNOWN").GetStringValue(); got != "x" {
+		t.Errorf("unknown column = %v, want x", got)
+	}
+}
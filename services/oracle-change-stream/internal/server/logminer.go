@@ -0,0 +1,479 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"kasho/pkg/types"
+
+	_ "github.com/sijms/go-ora/v2"
+)
+
+const (
+	maxBackoff = 30 * time.Second
+)
+
+// afterCommit is an index past every change committed at an SCN, so mining continues
+// with the next commit
+const afterCommit = math.MaxUint64
+
+// systemSchemas are Oracle-maintained schemas whose changes are never captured
+var systemSchemas = []string{
+	"ANONYMOUS", "APPQOSSYS", "AUDSYS", "CTXSYS", "DBSFWUSER", "DBSNMP", "DVSYS", "GSMADMIN_INTERNAL",
+	"LBACSYS", "MDSYS", "OJVMSYS", "OLAPSYS", "ORDDATA", "ORDSYS", "OUTLN", "REMOTE_SCHEDULER_AGENT",
+	"SYS", "SYSTEM", "WMSYS", "XDB",
+}
+
+// Client mines committed changes from the redo logs with LogMiner
+type Client struct {
+	db           *sql.DB
+	dbURL        string
+	schemas      []string // schemas to capture; empty captures every non-system schema
+	pollInterval time.Duration
+	done         chan struct{}
+	mu           sync.Mutex
+	position     Position // position of the last change read, or the end of the last poll
+	started      bool
+	tables       map[string]*tableInfo // captured tables by "OWNER.TABLE", cleared on DDL
+	changeChan   chan types.Change
+	wg           sync.WaitGroup // tracks the polling goroutine
+}
+
+// NewClient creates a new Oracle LogMiner client
+// startPosition is the position to resume after (e.g., "oracle:0000000000000042:4735918:2:4735900").
+// If empty, the client will start from the current SCN.
+// schemas lists the schemas to capture; if empty, every non-system schema is captured.
+func NewClient(ctx context.Context, dbURL string, schemas []string, pollInterval time.Duration, startPosition string) (*Client, error) {
+	position, err := ParsePosition(startPosition)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start position %q: %w", startPosition, err)
+	}
+
+	client := &Client{
+		dbURL:        dbURL,
+		schemas:      schemas,
+		pollInterval: pollInterval,
+		done:         make(chan struct{}),
+		position:     position,
+		started:      position.CommitSCN != 0,
+		tables:       make(map[string]*tableInfo),
+		changeChan:   make(chan types.Change, 1000),
+	}
+
+	if err := client.ConnectWithRetry(ctx); err != nil {
+		return nil, err
+	}
+	if client.started {
+		log.Printf("Resuming after SCN %d, mining from SCN %d", position.CommitSCN, position.RestartSCN)
+	}
+
+	client.wg.Add(1)
+	go client.run(ctx)
+
+	return client, nil
+}
+
+func (c *Client) Connect(ctx context.Context) error {
+	db, err := sql.Open("oracle", c.dbURL)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to ping: %w", err)
+	}
+
+	var logMode, supplemental string
+	if err := db.QueryRowContext(ctx, "SELECT LOG_MODE, SUPPLEMENTAL_LOG_DATA_MIN FROM V$DATABASE").Scan(&logMode, &supplemental); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to check redo log settings: %w", err)
+	}
+	if logMode != "ARCHIVELOG" {
+		db.Close()
+		return fmt.Errorf("database is in %s mode; LogMiner needs ARCHIVELOG mode", logMode)
+	}
+	if supplemental == "NO" {
+		db.Close()
+		return fmt.Errorf("supplemental logging is not enabled (run ALTER DATABASE ADD SUPPLEMENTAL LOG DATA)")
+	}
+
+	c.mu.Lock()
+	c.db = db
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Client) ConnectWithRetry(ctx context.Context) error {
+	backoff := time.Second
+	for {
+		log.Printf("Connecting to Oracle database...")
+		err := c.Connect(ctx)
+		if err == nil {
+			log.Printf("Successfully connected to Oracle")
+			return nil
+		}
+
+		log.Printf("Connection failed: %v", err)
+		log.Printf("Retrying in %v...", backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		}
+	}
+}
+
+// run mines the redo logs until the client is closed
+func (c *Client) run(ctx context.Context) {
+	defer c.wg.Done()
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.poll(ctx); err != nil {
+			log.Printf("Error mining redo logs: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll mines the transactions committed since the last poll and sends their changes in
+// commit order
+func (c *Client) poll(ctx context.Context) error {
+	// A LogMiner session belongs to a database session
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Transactions open now may commit after this poll; the next one mines from the start
+	// of the oldest so that it sees all of their changes. Both are read in one query so
+	// that no transaction starts in between.
+	var currentSCN uint64
+	var oldestOpen sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT CURRENT_SCN, (SELECT MIN(START_SCN) FROM V$TRANSACTION) FROM V$DATABASE").Scan(&currentSCN, &oldestOpen); err != nil {
+		return fmt.Errorf("failed to get current SCN: %w", err)
+	}
+	restartSCN := currentSCN
+	if oldestOpen.Valid && uint64(oldestOpen.Int64) < restartSCN {
+		restartSCN = uint64(oldestOpen.Int64)
+	}
+
+	c.mu.Lock()
+	from := c.position
+	if !c.started {
+		c.position = Position{CommitSCN: currentSCN, Index: afterCommit, RestartSCN: restartSCN}
+		c.started = true
+		c.mu.Unlock()
+		log.Printf("Starting from current SCN %d", currentSCN)
+		return nil
+	}
+	c.mu.Unlock()
+
+	// Nothing committed since the last poll
+	if currentSCN <= from.CommitSCN {
+		return nil
+	}
+
+	if err := c.startLogMiner(ctx, conn, from.RestartSCN, currentSCN); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), "BEGIN DBMS_LOGMNR.END_LOGMNR; END;")
+
+	if err := c.readChanges(ctx, conn, from); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.position = Position{Seq: c.position.Seq, CommitSCN: currentSCN, Index: afterCommit, RestartSCN: restartSCN}
+	c.mu.Unlock()
+	return nil
+}
+
+// startLogMiner adds the redo logs from startSCN on and starts a LogMiner session
+// returning the transactions committed up to endSCN, with the NLS formats the redo
+// values are parsed with
+func (c *Client) startLogMiner(ctx context.Context, conn *sql.Conn, startSCN, endSCN uint64) error {
+	if _, err := conn.ExecContext(ctx, `ALTER SESSION SET
+		NLS_DATE_FORMAT = 'YYYY-MM-DD HH24:MI:SS'
+		NLS_TIMESTAMP_FORMAT = 'YYYY-MM-DD HH24:MI:SS.FF'
+		NLS_TIMESTAMP_TZ_FORMAT = 'YYYY-MM-DD HH24:MI:SS.FF TZH:TZM'
+		NLS_NUMERIC_CHARACTERS = '.,'
+		TIME_ZONE = 'UTC'`); err != nil {
+		return fmt.Errorf("failed to set session formats: %w", err)
+	}
+
+	files, err := c.logFiles(ctx, conn, startSCN)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no redo logs cover SCN %d", startSCN)
+	}
+	for i, file := range files {
+		option := "DBMS_LOGMNR.ADDFILE"
+		if i == 0 {
+			option = "DBMS_LOGMNR.NEW"
+		}
+		if _, err := conn.ExecContext(ctx, "BEGIN DBMS_LOGMNR.ADD_LOGFILE(LOGFILENAME => :1, OPTIONS => "+option+"); END;", file); err != nil {
+			return fmt.Errorf("failed to add redo log %s: %w", file, err)
+		}
+	}
+
+	// The online catalog names tables as they are now, so changes made before a DDL
+	// statement that renames or drops a column are read with the new definition
+	if _, err := conn.ExecContext(ctx, `BEGIN DBMS_LOGMNR.START_LOGMNR(
+		STARTSCN => :1,
+		ENDSCN => :2,
+		OPTIONS => DBMS_LOGMNR.DICT_FROM_ONLINE_CATALOG + DBMS_LOGMNR.COMMITTED_DATA_ONLY + DBMS_LOGMNR.NO_ROWID_IN_STMT);
+		END;`, startSCN, endSCN); err != nil {
+		return fmt.Errorf("failed to start LogMiner: %w", err)
+	}
+	return nil
+}
+
+// logFiles returns the archived and online redo logs holding changes from an SCN on,
+// one copy of each
+func (c *Client) logFiles(ctx context.Context, conn *sql.Conn, fromSCN uint64) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT name FROM (
+			SELECT a.name, ROW_NUMBER() OVER (PARTITION BY a.thread#, a.sequence# ORDER BY a.dest_id) AS rn
+			FROM v$archived_log a
+			WHERE a.name IS NOT NULL AND a.status = 'A' AND a.next_change# > :1
+		) WHERE rn = 1
+		UNION ALL
+		SELECT MIN(f.member)
+		FROM v$log l
+		JOIN v$logfile f ON f.group# = l.group#
+		WHERE l.next_change# > :2
+		AND NOT EXISTS (
+			SELECT 1 FROM v$archived_log a
+			WHERE a.thread# = l.thread# AND a.sequence# = l.sequence#
+			AND a.name IS NOT NULL AND a.status = 'A'
+		)
+		GROUP BY l.group#`, fromSCN, fromSCN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list redo logs: %w", err)
+	}
+	defer rows.Close()
+
+	var files []string
+	for rows.Next() {
+		var file string
+		if err := rows.Scan(&file); err != nil {
+			return nil, fmt.Errorf("failed to scan redo log: %w", err)
+		}
+		files = append(files, file)
+	}
+	return files, rows.Err()
+}
+
+// readChanges reads the mined changes, skipping those at or before a position, and
+// sends them
+func (c *Client) readChanges(ctx context.Context, conn *sql.Conn, from Position) error {
+	// Changes are indexed among all rows with their commit SCN, before filtering by
+	// schema, so indexes don't move when CAPTURE_SCHEMAS changes across a restart
+	query := fmt.Sprintf(`
+		SELECT COMMIT_SCN, RAWTOHEX(XID), OPERATION_CODE, SEG_OWNER, TABLE_NAME, USERNAME,
+			SQL_REDO, CSF, TIMESTAMP, COMMIT_TIMESTAMP
+		FROM V$LOGMNR_CONTENTS
+		WHERE OPERATION_CODE IN (%d, %d, %d, %d)
+		AND (SEG_OWNER IS NULL OR SEG_OWNER NOT IN ('%s'))`,
+		opInsert, opDelete, opUpdate, opDDL, strings.Join(systemSchemas, "', '"))
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to read LogMiner contents: %w", err)
+	}
+	defer rows.Close()
+
+	var redo strings.Builder
+	var commitSCN, index uint64
+	for rows.Next() {
+		var row minedRow
+		var owner, table, username, sqlRedo sql.NullString
+		var continued int
+		if err := rows.Scan(&row.CommitSCN, &row.XID, &row.Operation, &owner, &table, &username,
+			&sqlRedo, &continued, &row.Time, &row.CommitTime); err != nil {
+			return fmt.Errorf("failed to scan LogMiner contents: %w", err)
+		}
+
+		// Statements longer than 4000 bytes continue on the next rows
+		redo.WriteString(sqlRedo.String)
+		if continued == 1 {
+			continue
+		}
+		row.Owner, row.Table, row.Username, row.SQLRedo = owner.String, table.String, username.String, redo.String()
+		redo.Reset()
+
+		if row.CommitSCN != commitSCN {
+			commitSCN, index = row.CommitSCN, 0
+		}
+		index++
+		if !from.After(commitSCN, index) || !c.captures(row.Owner) {
+			continue
+		}
+
+		change, err := c.toChange(ctx, &row)
+		if err != nil {
+			log.Printf("Skipping change at SCN %d: %v", row.CommitSCN, err)
+			continue
+		}
+		if change == nil {
+			continue
+		}
+
+		c.mu.Lock()
+		position := Position{Seq: c.position.Seq + 1, CommitSCN: commitSCN, Index: index, RestartSCN: from.RestartSCN}
+		c.mu.Unlock()
+		change.Position = FormatPosition(position)
+
+		select {
+		case c.changeChan <- *change:
+		case <-c.done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		c.mu.Lock()
+		c.position = position
+		c.mu.Unlock()
+	}
+	return rows.Err()
+}
+
+// captures reports whether changes in a schema are captured. DDL not tied to a schema
+// is only captured without a schema filter.
+func (c *Client) captures(owner string) bool {
+	if len(c.schemas) == 0 {
+		return true
+	}
+	return slices.Contains(c.schemas, owner)
+}
+
+// toChange converts a mined row to a change, looking up the changed table's columns
+func (c *Client) toChange(ctx context.Context, row *minedRow) (*types.Change, error) {
+	if row.Operation == opDDL {
+		// Table definitions are read again after a schema change
+		clear(c.tables)
+		return rowToChange(row, nil)
+	}
+	if strings.HasPrefix(row.Table, "OBJ# ") {
+		return nil, fmt.Errorf("table %s of %s is not in the dictionary; it may have been dropped", row.Table, row.Owner)
+	}
+	table, err := c.tableInfo(ctx, row.Owner, row.Table)
+	if err != nil {
+		return nil, err
+	}
+	return rowToChange(row, table)
+}
+
+// tableInfo returns the columns and primary key of a table
+func (c *Client) tableInfo(ctx context.Context, owner, name string) (*tableInfo, error) {
+	key := owner + "." + name
+	if table, ok := c.tables[key]; ok {
+		return table, nil
+	}
+
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT COLUMN_NAME, DATA_TYPE, DATA_SCALE
+		FROM ALL_TAB_COLUMNS
+		WHERE OWNER = :1 AND TABLE_NAME = :2
+		ORDER BY COLUMN_ID`, owner, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", key, err)
+	}
+	defer rows.Close()
+
+	table := &tableInfo{Types: make(map[string]string), Scales: make(map[string]int)}
+	for rows.Next() {
+		var column, dataType string
+		var scale sql.NullInt64
+		if err := rows.Scan(&column, &dataType, &scale); err != nil {
+			return nil, fmt.Errorf("failed to scan column of %s: %w", key, err)
+		}
+		table.Columns = append(table.Columns, column)
+		table.Types[column] = dataType
+		if scale.Valid {
+			table.Scales[column] = int(scale.Int64)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", key, err)
+	}
+
+	keyRows, err := c.db.QueryContext(ctx, `
+		SELECT cc.COLUMN_NAME
+		FROM ALL_CONSTRAINTS con
+		JOIN ALL_CONS_COLUMNS cc ON cc.OWNER = con.OWNER AND cc.CONSTRAINT_NAME = con.CONSTRAINT_NAME
+		WHERE con.OWNER = :1 AND con.TABLE_NAME = :2 AND con.CONSTRAINT_TYPE = 'P'
+		ORDER BY cc.POSITION`, owner, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read primary key of %s: %w", key, err)
+	}
+	defer keyRows.Close()
+	for keyRows.Next() {
+		var column string
+		if err := keyRows.Scan(&column); err != nil {
+			return nil, fmt.Errorf("failed to scan primary key of %s: %w", key, err)
+		}
+		table.PrimaryKey = append(table.PrimaryKey, column)
+	}
+	if err := keyRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read primary key of %s: %w", key, err)
+	}
+
+	c.tables[key] = table
+	return table, nil
+}
+
+func (c *Client) Close(ctx context.Context) {
+	close(c.done)
+
+	// Wait for the polling goroutine to finish
+	c.wg.Wait()
+
+	c.mu.Lock()
+	if c.db != nil {
+		c.db.Close()
+		c.db = nil
+	}
+	c.mu.Unlock()
+}
+
+// GetPosition returns the position of the last change read, or "" before the first poll
+func (c *Client) GetPosition() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.started {
+		return ""
+	}
+	return FormatPosition(c.position)
+}
+
+// Changes returns the channel of changes
+func (c *Client) Changes() <-chan types.Change {
+	return c.changeChan
+}
+
+// Done returns a channel that is closed when the client is closed
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// positionPrefix marks Oracle positions. Every change of a transaction, and of other
+// transactions committed with it, shares one commit SCN, so positions carry a sequence
+// number assigned as changes are read. They also carry the change's index among those
+// with its commit SCN, and the SCN to restart mining from so that transactions still
+// open at that point are read from their start.
+const positionPrefix = "oracle:"
+
+// Position identifies a change read from the redo logs
+type Position struct {
+	Seq        uint64 // sequence number assigned as changes are read
+	CommitSCN  uint64 // SCN the change's transaction committed at
+	Index      uint64 // index of the change among the changes committed at CommitSCN
+	RestartSCN uint64 // SCN to start mining from to read the transactions after the change
+}
+
+// FormatPosition converts a position to our string format
+// Format: "oracle:0000000000000042:4735918:2:4735900" (sequence:commit SCN:index:restart SCN)
+func FormatPosition(p Position) string {
+	return fmt.Sprintf("%s%016d:%d:%d:%d", positionPrefix, p.Seq, p.CommitSCN, p.Index, p.RestartSCN)
+}
+
+// ParsePosition parses our position string format. Empty and "bootstrap" positions
+// return the zero position.
+func ParsePosition(position string) (Position, error) {
+	if position == "" || position == "bootstrap" {
+		return Position{}, nil
+	}
+
+	rest, ok := strings.CutPrefix(position, positionPrefix)
+	if !ok {
+		return Position{}, fmt.Errorf("invalid position format: %s", position)
+	}
+	parts := strings.Split(rest, ":")
+	if len(parts) != 4 {
+		return Position{}, fmt.Errorf("invalid position format: %s", position)
+	}
+	var values [4]uint64
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return Position{}, fmt.Errorf("invalid position format: %s", position)
+		}
+		values[i] = n
+	}
+	return Position{Seq: values[0], CommitSCN: values[1], Index: values[2], RestartSCN: values[3]}, nil
+}
+
+// After reports whether a change committed at commitSCN with the given index comes
+// after the position
+func (p Position) After(commitSCN, index uint64) bool {
+	return commitSCN > p.CommitSCN || (commitSCN == p.CommitSCN && index > p.Index)
+}
@@ -0,0 +1,67 @@
+package server
+
+import "testing"
+
+func TestFormatPosition(t *testing.T) {
+	got := FormatPosition(Position{Seq: 42, CommitSCN: 4735918, Index: 2, RestartSCN: 4735900})
+	if want := "oracle:0000000000000042:4735918:2:4735900"; got != want {
+		t.Errorf("FormatPosition() = %v, want %v", got, want)
+	}
+}
+
+func TestParsePosition(t *testing.T) {
+	tests := []struct {
+		name     string
+		position string
+		want     Position
+		wantErr  bool
+	}{
+		{
+			name:     "valid position",
+			position: "oracle:0000000000000042:4735918:2:4735900",
+			want:     Position{Seq: 42, CommitSCN: 4735918, Index: 2, RestartSCN: 4735900},
+		},
+		{name: "empty position", position: ""},
+		{name: "bootstrap position", position: "bootstrap"},
+		{name: "binlog position", position: "mysql-bin.000001:4", wantErr: true},
+		{name: "missing restart SCN", position: "oracle:0000000000000042:4735918:2", wantErr: true},
+		{name: "invalid SCN", position: "oracle:0000000000000042:abc:2:4735900", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePosition(tt.position)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePosition() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParsePosition() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPositionAfter(t *testing.T) {
+	p := Position{CommitSCN: 100, Index: 2}
+	tests := []struct {
+		commitSCN, index uint64
+		want             bool
+	}{
+		{99, 5, false},
+		{100, 1, false},
+		{100, 2, false},
+		{100, 3, true},
+		{101, 1, true},
+	}
+	for _, tt := range tests {
+		if got := p.After(tt.commitSCN, tt.index); got != tt.want {
+			t.Errorf("After(%d, %d) = %v, want %v", tt.commitSCN, tt.index, got, tt.want)
+		}
+	}
+
+	end := Position{CommitSCN: 100, Index: afterCommit}
+	if end.After(100, 7) || !end.After(101, 1) {
+		t.Errorf("a position after every change of a commit should only be followed by later commits")
+	}
+}
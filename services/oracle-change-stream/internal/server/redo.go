@@ -0,0 +1,372 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+)
+
+// redoStatement is a DML statement as LogMiner reconstructs it in SQL_REDO, e.g.
+//
+//	insert into "HR"."USERS"("ID","NAME") values ('1','alice');
+//	update "HR"."USERS" set "NAME" = 'bob' where "ID" = '1' and "NAME" = 'alice';
+//	delete from "HR"."USERS" where "ID" = '1' and "NAME" = 'bob';
+type redoStatement struct {
+	Kind  string // "insert", "update" or "delete"
+	Owner string
+	Table string
+	Set   []redoColumn // inserted values, or the SET clause of an update
+	Where []redoColumn // WHERE clause of an update or delete: the row's old values
+}
+
+// redoColumn is a column and its value in a redo statement
+type redoColumn struct {
+	Name  string
+	Value redoValue
+}
+
+// redoValue is a value in a redo statement
+type redoValue struct {
+	Null     bool
+	Text     string // literal text, or the first argument of a function
+	Function string // e.g. "TO_DATE" or "HEXTORAW"; empty for a literal
+}
+
+// tokenKind is the kind of a token in a redo statement
+type tokenKind int
+
+const (
+	tokenEnd        tokenKind = iota
+	tokenIdentifier           // "NAME"
+	tokenString               // 'text'
+	tokenWord                 // keywords, unquoted names and numbers
+	tokenPunct                // ( ) , = . ;
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// tokenize splits a redo statement into tokens
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			// Quotes inside are doubled
+			var b strings.Builder
+			j := i + 1
+			for {
+				if j >= len(s) {
+					return nil, fmt.Errorf("unterminated quote at offset %d", i)
+				}
+				if s[j] == c {
+					if j+1 < len(s) && s[j+1] == c {
+						b.WriteByte(c)
+						j += 2
+						continue
+					}
+					break
+				}
+				b.WriteByte(s[j])
+				j++
+			}
+			kind := tokenString
+			if c == '"' {
+				kind = tokenIdentifier
+			}
+			tokens = append(tokens, token{kind: kind, text: b.String()})
+			i = j + 1
+		case strings.IndexByte("(),=.;", c) >= 0:
+			tokens = append(tokens, token{kind: tokenPunct, text: string(c)})
+			i++
+		case isWordStart(c):
+			j := i + 1
+			for j < len(s) && isWordPart(s[j]) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: s[i:j]})
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+		}
+	}
+	return tokens, nil
+}
+
+func isWordStart(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-' || c == '+'
+}
+
+func isWordPart(c byte) bool {
+	// Numbers may have a decimal point and an exponent
+	return isWordStart(c) || c == '$' || c == '#' || c == '.'
+}
+
+// redoParser parses the tokens of a redo statement
+type redoParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *redoParser) peek() token {
+	if p.pos >= len(p.tokens) {
+		return token{kind: tokenEnd}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *redoParser) next() token {
+	t := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return t
+}
+
+// keyword consumes a keyword, case-insensitively
+func (p *redoParser) keyword(word string) error {
+	t := p.next()
+	if t.kind != tokenWord || !strings.EqualFold(t.text, word) {
+		return fmt.Errorf("expected %s, got %q", word, t.text)
+	}
+	return nil
+}
+
+// isKeyword reports whether the next token is a keyword, case-insensitively
+func (p *redoParser) isKeyword(word string) bool {
+	t := p.peek()
+	return t.kind == tokenWord && strings.EqualFold(t.text, word)
+}
+
+func (p *redoParser) punct(c string) error {
+	t := p.next()
+	if t.kind != tokenPunct || t.text != c {
+		return fmt.Errorf("expected %s, got %q", c, t.text)
+	}
+	return nil
+}
+
+func (p *redoParser) isPunct(c string) bool {
+	t := p.peek()
+	return t.kind == tokenPunct && t.text == c
+}
+
+func (p *redoParser) identifier() (string, error) {
+	t := p.next()
+	if t.kind != tokenIdentifier && t.kind != tokenWord {
+		return "", fmt.Errorf("expected identifier, got %q", t.text)
+	}
+	return t.text, nil
+}
+
+// tableName parses "OWNER"."TABLE"
+func (p *redoParser) tableName() (string, string, error) {
+	owner, err := p.identifier()
+	if err != nil {
+		return "", "", err
+	}
+	if err := p.punct("."); err != nil {
+		return "", "", err
+	}
+	table, err := p.identifier()
+	if err != nil {
+		return "", "", err
+	}
+	return owner, table, nil
+}
+
+// value parses a literal, NULL or a function call such as TO_DATE('...', '...')
+func (p *redoParser) value() (redoValue, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return redoValue{Text: t.text}, nil
+	case tokenWord:
+		if strings.EqualFold(t.text, "NULL") {
+			return redoValue{Null: true}, nil
+		}
+		if !p.isPunct("(") {
+			return redoValue{Text: t.text}, nil
+		}
+		p.next()
+		v := redoValue{Function: strings.ToUpper(t.text)}
+		for first := true; !p.isPunct(")"); first = false {
+			if !first {
+				if err := p.punct(","); err != nil {
+					return redoValue{}, err
+				}
+			}
+			arg, err := p.value()
+			if err != nil {
+				return redoValue{}, err
+			}
+			if first {
+				v.Text = arg.Text
+			}
+		}
+		p.next()
+		return v, nil
+	default:
+		return redoValue{}, fmt.Errorf("expected value, got %q", t.text)
+	}
+}
+
+// conditions parses a WHERE clause: "A" = '1' and "B" IS NULL
+func (p *redoParser) conditions() ([]redoColumn, error) {
+	var columns []redoColumn
+	for {
+		name, err := p.identifier()
+		if err != nil {
+			return nil, err
+		}
+		if p.isKeyword("IS") {
+			p.next()
+			if err := p.keyword("NULL"); err != nil {
+				return nil, err
+			}
+			columns = append(columns, redoColumn{Name: name, Value: redoValue{Null: true}})
+		} else {
+			if err := p.punct("="); err != nil {
+				return nil, err
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			columns = append(columns, redoColumn{Name: name, Value: v})
+		}
+		if !p.isKeyword("AND") {
+			return columns, nil
+		}
+		p.next()
+	}
+}
+
+// end consumes an optional semicolon and expects the end of the statement
+func (p *redoParser) end() error {
+	if p.isPunct(";") {
+		p.next()
+	}
+	if t := p.peek(); t.kind != tokenEnd {
+		return fmt.Errorf("unexpected %q after statement", t.text)
+	}
+	return nil
+}
+
+// parseRedo parses the SQL_REDO of an insert, update or delete
+func parseRedo(sqlRedo string) (*redoStatement, error) {
+	tokens, err := tokenize(sqlRedo)
+	if err != nil {
+		return nil, err
+	}
+	p := &redoParser{tokens: tokens}
+	stmt := &redoStatement{}
+
+	switch {
+	case p.isKeyword("INSERT"):
+		stmt.Kind = "insert"
+		p.next()
+		if err := p.keyword("INTO"); err != nil {
+			return nil, err
+		}
+		if stmt.Owner, stmt.Table, err = p.tableName(); err != nil {
+			return nil, err
+		}
+		if err := p.punct("("); err != nil {
+			return nil, err
+		}
+		var names []string
+		for first := true; !p.isPunct(")"); first = false {
+			if !first {
+				if err := p.punct(","); err != nil {
+					return nil, err
+				}
+			}
+			name, err := p.identifier()
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, name)
+		}
+		p.next()
+		if err := p.keyword("VALUES"); err != nil {
+			return nil, err
+		}
+		if err := p.punct("("); err != nil {
+			return nil, err
+		}
+		for i := range names {
+			if i > 0 {
+				if err := p.punct(","); err != nil {
+					return nil, err
+				}
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Set = append(stmt.Set, redoColumn{Name: names[i], Value: v})
+		}
+		if err := p.punct(")"); err != nil {
+			return nil, err
+		}
+	case p.isKeyword("UPDATE"):
+		stmt.Kind = "update"
+		p.next()
+		if stmt.Owner, stmt.Table, err = p.tableName(); err != nil {
+			return nil, err
+		}
+		if err := p.keyword("SET"); err != nil {
+			return nil, err
+		}
+		for first := true; first || p.isPunct(","); first = false {
+			if !first {
+				p.next()
+			}
+			name, err := p.identifier()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.punct("="); err != nil {
+				return nil, err
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Set = append(stmt.Set, redoColumn{Name: name, Value: v})
+		}
+		if p.isKeyword("WHERE") {
+			p.next()
+			if stmt.Where, err = p.conditions(); err != nil {
+				return nil, err
+			}
+		}
+	case p.isKeyword("DELETE"):
+		stmt.Kind = "delete"
+		p.next()
+		if err := p.keyword("FROM"); err != nil {
+			return nil, err
+		}
+		if stmt.Owner, stmt.Table, err = p.tableName(); err != nil {
+			return nil, err
+		}
+		if p.isKeyword("WHERE") {
+			p.next()
+			if stmt.Where, err = p.conditions(); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported redo statement: %q", p.peek().text)
+	}
+
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRedo(t *testing.T) {
+	tests := []struct {
+		name    string
+		redo    string
+		want    *redoStatement
+		wantErr bool
+	}{
+		{
+			name: "insert",
+			redo: `insert into "HR"."USERS"("ID","NAME","BIO") values ('1','O''Brien',NULL);`,
+			want: &redoStatement{
+				Kind: "insert", Owner: "HR", Table: "USERS",
+				Set: []redoColumn{
+					{Name: "ID", Value: redoValue{Text: "1"}},
+					{Name: "NAME", Value: redoValue{Text: "O'Brien"}},
+					{Name: "BIO", Value: redoValue{Null: true}},
+				},
+			},
+		},
+		{
+			name: "update with functions",
+			redo: `update "HR"."USERS" set "BORN" = TO_DATE('2000-01-02 00:00:00', 'YYYY-MM-DD HH24:MI:SS'), "AVATAR" = HEXTORAW('dead') where "ID" = '1' and "BORN" IS NULL;`,
+			want: &redoStatement{
+				Kind: "update", Owner: "HR", Table: "USERS",
+				Set: []redoColumn{
+					{Name: "BORN", Value: redoValue{Text: "2000-01-02 00:00:00", Function: "TO_DATE"}},
+					{Name: "AVATAR", Value: redoValue{Text: "dead", Function: "HEXTORAW"}},
+				},
+				Where: []redoColumn{
+					{Name: "ID", Value: redoValue{Text: "1"}},
+					{Name: "BORN", Value: redoValue{Null: true}},
+				},
+			},
+		},
+		{
+			name: "delete with unquoted number",
+			redo: `delete from "HR"."USERS" where "ID" = -1.5E+3`,
+			want: &redoStatement{
+				Kind: "delete", Owner: "HR", Table: "USERS",
+				Where: []redoColumn{{Name: "ID", Value: redoValue{Text: "-1.5E+3"}}},
+			},
+		},
+		{
+			name: "empty LOB",
+			redo: `insert into "HR"."DOCS"("ID","BODY") values ('1',EMPTY_CLOB());`,
+			want: &redoStatement{
+				Kind: "insert", Owner: "HR", Table: "DOCS",
+				Set: []redoColumn{
+					{Name: "ID", Value: redoValue{Text: "1"}},
+					{Name: "BODY", Value: redoValue{Function: "EMPTY_CLOB"}},
+				},
+			},
+		},
+		{name: "unterminated string", redo: `insert into "HR"."USERS"("ID") values ('1);`, wantErr: true},
+		{name: "trailing tokens", redo: `delete from "HR"."USERS" where "ID" = '1' or 1 = 1`, wantErr: true},
+		{name: "unsupported statement", redo: `Unsupported`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRedo(tt.redo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRedo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRedo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"kasho/pkg/streamserver"
+)
+
+// State represents the operational state of the change stream
+type State = streamserver.State
+
+const (
+	StateWaiting      = streamserver.StateWaiting
+	StateAccumulating = streamserver.StateAccumulating
+	StateStreaming    = streamserver.StateStreaming
+)
+
+// StateInfo holds the persistent state information
+type StateInfo struct {
+	Current            State     `json:"current"`
+	StartPosition      string    `json:"start_position,omitempty"` // LogMiner position (e.g., "oracle:0000000000000042:4735918:2:4735900")
+	TransitionTime     time.Time `json:"transition_time"`
+	AccumulatedChanges int64     `json:"accumulated_changes"`
+	StartDepth         int64     `json:"start_depth,omitempty"` // Buffer depth when accumulation began
+	StartBytes         int64     `json:"start_bytes,omitempty"` // Buffer size in bytes when accumulation began
+}
+
+const stateKey = "kasho:oracle-change-stream:state"
+
+// resumeKey holds the position of the last change added to the buffer. Polling resumes
+// after it on restart, so a transaction read in part isn't read again and sequence
+// numbers keep increasing.
+const resumeKey = "kasho:oracle-change-stream:resume"
+
+// LoadState loads the state from Redis
+func (s *ChangeStreamServer) LoadState(ctx context.Context) (*StateInfo, error) {
+	data, err := s.buffer.Get(ctx, stateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+
+	if data == "" {
+		// No state found, default to waiting
+		return &StateInfo{
+			Current:        StateWaiting,
+			TransitionTime: time.Now(),
+		}, nil
+	}
+
+	var state StateInfo
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+
+	return &state, nil
+}
+
+// SaveState saves the state to Redis
+func (s *ChangeStreamServer) SaveState(ctx context.Context, state *StateInfo) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	if err := s.buffer.Set(ctx, stateKey, string(data)); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+
+	return nil
+}
+
+// TransitionState updates the state and saves it
+func (s *ChangeStreamServer) TransitionState(ctx context.Context, newState State, startPosition string) error {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	oldState := s.state.Current
+	s.state.Current = newState
+	s.state.TransitionTime = time.Now()
+
+	if startPosition != "" {
+		s.state.StartPosition = startPosition
+	}
+
+	if err := s.SaveState(ctx, s.state); err != nil {
+		// Rollback on error
+		s.state.Current = oldState
+		return err
+	}
+
+	return nil
+}
+
+// LoadResumePosition loads the position of the last buffered change from Redis, or ""
+// if no change was buffered yet
+func (s *ChangeStreamServer) LoadResumePosition(ctx context.Context) (string, error) {
+	position, err := s.buffer.Get(ctx, resumeKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to load resume position: %w", err)
+	}
+	return position, nil
+}
+
+// SaveResumePosition saves the position of the last buffered change to Redis
+func (s *ChangeStreamServer) SaveResumePosition(ctx context.Context, position string) error {
+	if err := s.buffer.Set(ctx, resumeKey, position); err != nil {
+		return fmt.Errorf("failed to save resume position: %w", err)
+	}
+	return nil
+}
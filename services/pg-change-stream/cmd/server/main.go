@@ -3,14 +3,17 @@ package main
 import (
 	"context"
 	"log"
-	"net"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"kasho/pkg/auth"
 	"kasho/pkg/kvbuffer"
+	"kasho/pkg/notify"
+	"kasho/pkg/streamserver"
 	"kasho/pkg/version"
 	"kasho/proto"
 	"pg-change-stream/internal/server"
@@ -34,17 +37,137 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to create KV buffer: %v", err)
 	}
-	defer buffer.Close()
+	defer func() {
+		if err := buffer.Close(); err != nil {
+			log.Printf("Failed to flush KV buffer: %v", err)
+		}
+	}()
+
+	// Optional batching of buffer writes into pipelined transactions, for bursts of changes
+	batchSize, err := strconv.Atoi(getEnvOrDefault("KV_BATCH_SIZE", "1"))
+	if err != nil {
+		log.Fatalf("Invalid KV_BATCH_SIZE: %v", err)
+	}
+	batchInterval, err := time.ParseDuration(getEnvOrDefault("KV_BATCH_INTERVAL", "10ms"))
+	if err != nil {
+		log.Fatalf("Invalid KV_BATCH_INTERVAL: %v", err)
+	}
+	if batchSize > 1 {
+		buffer.EnableBatching(batchSize, batchInterval)
+		log.Printf("Batching KV writes: up to %d changes every %s", batchSize, batchInterval)
+	}
+
+	// Optional spilling of the oldest buffered changes to disk when Redis runs short of memory
+	if spillDir := os.Getenv("KV_SPILL_DIR"); spillDir != "" {
+		maxMemory, err := strconv.ParseInt(getEnvOrDefault("KV_SPILL_MAX_MEMORY", "0"), 10, 64)
+		if err != nil || maxMemory <= 0 {
+			log.Fatal("KV_SPILL_MAX_MEMORY must be a positive number of bytes when KV_SPILL_DIR is set")
+		}
+		if err := buffer.EnableSpill(spillDir, maxMemory); err != nil {
+			log.Fatalf("Failed to enable KV spill: %v", err)
+		}
+		log.Printf("Spilling KV buffer to %s when Redis uses over %d bytes", spillDir, maxMemory)
+	}
 
 	// Create the gRPC server
 	changeStreamServer := server.NewChangeStreamServer(buffer)
 
+	// Enable token authentication on the Stream RPC if tokens are configured
+	if tokenSpec := os.Getenv("CHANGE_STREAM_TOKENS"); tokenSpec != "" {
+		tokens, err := auth.ParseTokens(tokenSpec)
+		if err != nil {
+			log.Fatalf("Invalid CHANGE_STREAM_TOKENS: %v", err)
+		}
+		changeStreamServer.SetAuthenticator(auth.NewAuthenticator(tokens))
+		log.Printf("Stream authentication enabled with %d token(s)", len(tokens))
+	}
+
 	// Initialize state from Redis and database
 	dbURL := os.Getenv("PRIMARY_DATABASE_URL")
 	if dbURL == "" {
 		log.Fatal("PRIMARY_DATABASE_URL environment variable is required")
 	}
 
+	// Several schemas can share one pipeline; by default every published table is captured
+	captureSchemas := splitList(os.Getenv("CAPTURE_SCHEMAS"))
+	if len(captureSchemas) > 0 {
+		log.Printf("Capturing schemas: %s", strings.Join(captureSchemas, ", "))
+	}
+
+	// Large transactions can be streamed while in progress instead of decoded at commit (PostgreSQL 14+)
+	streamLargeTransactions, err := strconv.ParseBool(getEnvOrDefault("STREAM_LARGE_TRANSACTIONS", "false"))
+	if err != nil {
+		log.Fatalf("Invalid STREAM_LARGE_TRANSACTIONS: %v", err)
+	}
+	if streamLargeTransactions {
+		log.Printf("Streaming large transactions before they commit")
+	}
+
+	// Prepared transactions can be decoded at PREPARE TRANSACTION instead of at COMMIT PREPARED (PostgreSQL 15+)
+	twoPhaseCommit, err := strconv.ParseBool(getEnvOrDefault("TWO_PHASE_COMMIT", "false"))
+	if err != nil {
+		log.Fatalf("Invalid TWO_PHASE_COMMIT: %v", err)
+	}
+	if twoPhaseCommit {
+		log.Printf("Decoding prepared transactions when they are prepared")
+	}
+
+	// Application markers written with pg_logical_emit_message are passed on as message changes (PostgreSQL 14+)
+	logicalMessages, err := strconv.ParseBool(getEnvOrDefault("LOGICAL_MESSAGES", "false"))
+	if err != nil {
+		log.Fatalf("Invalid LOGICAL_MESSAGES: %v", err)
+	}
+	if logicalMessages {
+		log.Printf("Passing on logical decoding messages")
+	}
+
+	// The output plugin the slot decodes with; managed services may only offer wal2json
+	logicalDecoder, err := server.ParseLogicalDecoder(os.Getenv("LOGICAL_DECODER"))
+	if err != nil {
+		log.Fatalf("Invalid LOGICAL_DECODER: %v", err)
+	}
+	replicationOptions := server.ReplicationOptions{
+		Decoder:                 logicalDecoder,
+		StreamLargeTransactions: streamLargeTransactions,
+		TwoPhaseCommit:          twoPhaseCommit,
+		LogicalMessages:         logicalMessages,
+	}
+	if err := replicationOptions.Validate(); err != nil {
+		log.Fatalf("Invalid replication options: %v", err)
+	}
+	log.Printf("Decoding the WAL with %s", logicalDecoder)
+
+	// Optional persistence of relations to the KV buffer, so changes can be decoded
+	// after a restart before PostgreSQL describes their tables again
+	persistRelations, err := strconv.ParseBool(getEnvOrDefault("PERSIST_RELATIONS", "false"))
+	if err != nil {
+		log.Fatalf("Invalid PERSIST_RELATIONS: %v", err)
+	}
+	relations := server.NewRelations(nil)
+	if persistRelations {
+		relations = server.NewRelations(buffer)
+		log.Printf("Persisting relations to the KV buffer")
+	}
+
+	// Tables created after setup may not be in the publication, so their changes would be missing
+	publicationMode, err := server.ParsePublicationMode(os.Getenv("PUBLICATION_NEW_TABLES"))
+	if err != nil {
+		log.Fatalf("Invalid PUBLICATION_NEW_TABLES: %v", err)
+	}
+	// wal2json decodes every table, so there is no publication to check
+	if logicalDecoder == server.DecoderWal2JSON {
+		publicationMode = server.PublicationOff
+	}
+	publications, err := server.NewPublications(dbURL, publicationMode, captureSchemas)
+	if err != nil {
+		log.Fatalf("Failed to set up publication check: %v", err)
+	}
+	defer publications.Close()
+	changeStreamServer.SetPublications(publications)
+	if publicationMode != server.PublicationOff {
+		log.Printf("Checking that new tables are published (%s)", publicationMode)
+	}
+
 	state, err := changeStreamServer.LoadState(ctx)
 	if err != nil {
 		log.Printf("Failed to load state from Redis, using defaults: %v", err)
@@ -69,17 +192,59 @@ func main() {
 	changeStreamServer.SetState(state)
 	log.Printf("Starting in %s state", state.Current)
 
-	// Get gRPC port from environment or use default
-	port := os.Getenv("GRPC_PORT")
-	if port == "" {
-		port = "50051"
+	// Optional server-wide pacing limits for the Stream RPC
+	maxChangesPerSecond, err := strconv.ParseUint(getEnvOrDefault("CHANGE_STREAM_MAX_CHANGES_PER_SECOND", "0"), 10, 32)
+	if err != nil {
+		log.Fatalf("Invalid CHANGE_STREAM_MAX_CHANGES_PER_SECOND: %v", err)
+	}
+	maxBytesPerSecond, err := strconv.ParseUint(getEnvOrDefault("CHANGE_STREAM_MAX_BYTES_PER_SECOND", "0"), 10, 64)
+	if err != nil {
+		log.Fatalf("Invalid CHANGE_STREAM_MAX_BYTES_PER_SECOND: %v", err)
+	}
+	maxInFlightBytes, err := strconv.ParseUint(getEnvOrDefault("CHANGE_STREAM_MAX_IN_FLIGHT_BYTES", "0"), 10, 64)
+	if err != nil {
+		log.Fatalf("Invalid CHANGE_STREAM_MAX_IN_FLIGHT_BYTES: %v", err)
+	}
+	changeStreamServer.SetStreamLimits(uint32(maxChangesPerSecond), maxBytesPerSecond, maxInFlightBytes)
+
+	// Heartbeats let consumers detect dead connections on idle databases
+	heartbeatInterval, err := time.ParseDuration(getEnvOrDefault("CHANGE_STREAM_HEARTBEAT_INTERVAL", "10s"))
+	if err != nil {
+		log.Fatalf("Invalid CHANGE_STREAM_HEARTBEAT_INTERVAL: %v", err)
+	}
+	changeStreamServer.SetHeartbeatInterval(heartbeatInterval)
+
+	// Optional limits on how long a bootstrap may hold the service in ACCUMULATING
+	accumulationLimits, err := streamserver.AccumulationLimitsFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid accumulation limits: %v", err)
+	}
+	changeStreamServer.SetAccumulationLimits(accumulationLimits)
+	if accumulationLimits.Enabled() {
+		go changeStreamServer.MonitorAccumulation(ctx, 10*time.Second)
+		log.Printf("Accumulation limits enabled, moving to %s when reached", accumulationLimits.Action)
+	}
+
+	// Alert notifications (webhook, Slack, email) when the KV buffer grows too deep
+	notifier, err := notify.New(notify.ConfigFromEnv())
+	if err != nil {
+		log.Fatalf("Invalid notification config: %v", err)
+	}
+	bufferDepthThreshold, err := strconv.ParseInt(getEnvOrDefault("ALERT_BUFFER_DEPTH", "0"), 10, 64)
+	if err != nil {
+		log.Fatalf("Invalid ALERT_BUFFER_DEPTH: %v", err)
+	}
+	if notifier != nil && bufferDepthThreshold > 0 {
+		go monitorBufferDepth(ctx, buffer, notify.NewMonitor("pg-change-stream", notifier), bufferDepthThreshold)
+		log.Printf("Alerting when buffer depth reaches %d changes", bufferDepthThreshold)
 	}
 
-	lis, err := net.Listen("tcp", ":"+port)
+	// gRPC listen address: all interfaces by default, or a specific interface or Unix socket
+	lis, err := streamserver.Listen(os.Getenv("GRPC_HOST"), getEnvOrDefault("GRPC_PORT", "50051"))
 	if err != nil {
-		log.Fatalf("failed to listen on port %s: %v", port, err)
+		log.Fatalf("failed to listen: %v", err)
 	}
-	log.Printf("gRPC server listening on port %s", port)
+	log.Printf("gRPC server listening on %s %s", lis.Addr().Network(), lis.Addr())
 	s := grpc.NewServer()
 	proto.RegisterChangeStreamServer(s, changeStreamServer)
 	go func() {
@@ -110,12 +275,12 @@ func main() {
 				}
 				return
 			case <-time.After(1 * time.Second):
-				currentState := changeStreamServer.GetState()
+				currentState := changeStreamServer.CurrentState()
 
 				// If we're in STREAMING state but don't have a client, create one
 				if currentState == server.StateStreaming && client == nil {
 					log.Println("In STREAMING state, starting WAL client")
-					client, err = server.NewClient(ctx, dbURL)
+					client, err = server.NewClient(ctx, dbURL, replicationOptions, relations)
 					if err != nil {
 						log.Printf("Failed to create WAL client: %v", err)
 						continue
@@ -143,15 +308,27 @@ func main() {
 						}
 						continue
 					}
+					changeStreamServer.SetCurrentPosition(client.CurrentPosition())
 
 					for _, change := range changes {
+						if !server.CapturesSchema(captureSchemas, change) {
+							continue
+						}
+
 						// Store change in KV buffer
 						if err := buffer.AddChange(ctx, change); err != nil {
 							log.Printf("Error storing change in KV: %v", err)
 						}
 
+						// Warnings about unpublished tables follow the DDL creating them
+						for _, warning := range publications.Check(ctx, change) {
+							if err := buffer.AddChange(ctx, warning); err != nil {
+								log.Printf("Error storing change in KV: %v", err)
+							}
+						}
+
 						// Update accumulated count if in ACCUMULATING state
-						if changeStreamServer.GetState() == server.StateAccumulating {
+						if changeStreamServer.CurrentState() == server.StateAccumulating {
 							changeStreamServer.IncrementAccumulated()
 						}
 					}
@@ -163,3 +340,42 @@ func main() {
 	// Wait for shutdown signal
 	<-ctx.Done()
 }
+
+// monitorBufferDepth periodically compares the number of buffered changes against the alert threshold
+func monitorBufferDepth(ctx context.Context, buffer *kvbuffer.KVBuffer, monitor *notify.Monitor, threshold int64) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			depth, err := buffer.Depth(ctx)
+			if err != nil {
+				log.Printf("Failed to check buffer depth: %v", err)
+				continue
+			}
+			monitor.Check(ctx, "buffer_depth", float64(depth), float64(threshold),
+				"%d changes held in the KV buffer (threshold %d)", depth, threshold)
+		}
+	}
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// splitList splits a comma-separated list, ignoring blank entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
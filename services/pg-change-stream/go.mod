@@ -7,7 +7,11 @@ require (
 	github.com/jackc/pgx/v5 v5.5.4
 	github.com/lib/pq v1.10.9
 	google.golang.org/grpc v1.72.1
+	google.golang.org/protobuf v1.36.6
+	kasho/pkg/auth v0.0.0
 	kasho/pkg/kvbuffer v0.0.0-00010101000000-000000000000
+	kasho/pkg/notify v0.0.0
+	kasho/pkg/streamserver v0.0.0
 	kasho/pkg/types v0.0.0-00010101000000-000000000000
 	kasho/pkg/version v0.0.0
 	kasho/proto v0.0.0-00010101000000-000000000000
@@ -25,9 +29,14 @@ require (
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.26.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250218202821-56aae31c358a // indirect
-	google.golang.org/protobuf v1.36.6 // indirect
 )
 
+replace kasho/pkg/auth => ../../pkg/auth
+
+replace kasho/pkg/notify => ../../pkg/notify
+
+replace kasho/pkg/streamserver => ../../pkg/streamserver
+
 replace kasho/pkg/kvbuffer => ../../pkg/kvbuffer
 
 replace kasho/pkg/types => ../../pkg/types
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"kasho/pkg/types"
+
+	"github.com/jackc/pglogrepl"
+)
+
+// Decoder decodes the output plugin messages of one replication connection into changes
+type Decoder interface {
+	ParseWALData(ctx context.Context, walData []byte, lsn pglogrepl.LSN) ([]types.Change, error)
+}
+
+// LogicalDecoder is the output plugin the replication slot decodes the WAL with
+type LogicalDecoder string
+
+const (
+	// DecoderPgoutput is PostgreSQL's built-in plugin, which sends the tables of a publication
+	DecoderPgoutput LogicalDecoder = "pgoutput"
+	// DecoderWal2JSON is the wal2json plugin, which sends every table as JSON. Some managed
+	// services offer it where creating publications isn't allowed.
+	DecoderWal2JSON LogicalDecoder = "wal2json"
+)
+
+// ParseLogicalDecoder parses a LOGICAL_DECODER value; empty means pgoutput
+func ParseLogicalDecoder(s string) (LogicalDecoder, error) {
+	switch decoder := LogicalDecoder(strings.ToLower(strings.TrimSpace(s))); decoder {
+	case "":
+		return DecoderPgoutput, nil
+	case DecoderPgoutput, DecoderWal2JSON:
+		return decoder, nil
+	default:
+		return "", fmt.Errorf("invalid logical decoder %q: must be pgoutput or wal2json", s)
+	}
+}
+
+// Validate checks that the decoder supports the requested replication options. wal2json
+// only decodes transactions once they commit.
+func (o ReplicationOptions) Validate() error {
+	if o.Decoder != DecoderWal2JSON {
+		return nil
+	}
+	if o.StreamLargeTransactions {
+		return fmt.Errorf("wal2json doesn't stream large transactions")
+	}
+	if o.TwoPhaseCommit {
+		return fmt.Errorf("wal2json doesn't decode prepared transactions")
+	}
+	return nil
+}
+
+// pluginArgs are the START_REPLICATION options of the decoder's output plugin
+func pluginArgs(options ReplicationOptions) []string {
+	if options.Decoder == DecoderWal2JSON {
+		// Format version 2 sends one JSON object per change, and type OIDs let values be
+		// decoded like pgoutput's
+		return []string{
+			`"format-version" '2'`,
+			`"include-xids" '1'`,
+			`"include-timestamp" '1'`,
+			`"include-pk" '1'`,
+			`"include-type-oids" '1'`,
+		}
+	}
+
+	// With streaming on, PostgreSQL 14+ sends transactions that outgrow
+	// logical_decoding_work_mem in blocks while they are still in progress
+	args := []string{"proto_version '2'", "publication_names '" + publicationName + "'"}
+	if options.StreamLargeTransactions {
+		args = append(args, "streaming 'on'")
+	}
+	// With two_phase on, PostgreSQL 15+ sends prepared transactions at PREPARE
+	// TRANSACTION and their outcome at COMMIT or ROLLBACK PREPARED (protocol 3)
+	if options.TwoPhaseCommit {
+		args[0] = "proto_version '3'"
+		args = append(args, "two_phase 'on'")
+	}
+	// Messages applications write with pg_logical_emit_message are only sent on request
+	if options.LogicalMessages {
+		args = append(args, "messages 'true'")
+	}
+	return args
+}
+
+// newDecoder creates the decoder of a new replication connection
+func newDecoder(options ReplicationOptions, relations *Relations) Decoder {
+	if options.Decoder == DecoderWal2JSON {
+		return NewWal2JSONDecoder(options.LogicalMessages)
+	}
+	return NewParser(relations)
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLogicalDecoder(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    LogicalDecoder
+		wantErr bool
+	}{
+		{input: "", want: DecoderPgoutput},
+		{input: "pgoutput", want: DecoderPgoutput},
+		{input: " WAL2JSON ", want: DecoderWal2JSON},
+		{input: "test_decoding", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLogicalDecoder(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLogicalDecoder(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLogicalDecoder(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestPluginArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ReplicationOptions
+		want    []string
+	}{
+		{
+			name:    "pgoutput",
+			options: ReplicationOptions{},
+			want:    []string{"proto_version '2'", "publication_names 'kasho_pub'"},
+		},
+		{
+			name:    "pgoutput with options",
+			options: ReplicationOptions{Decoder: DecoderPgoutput, StreamLargeTransactions: true, TwoPhaseCommit: true, LogicalMessages: true},
+			want:    []string{"proto_version '3'", "publication_names 'kasho_pub'", "streaming 'on'", "two_phase 'on'", "messages 'true'"},
+		},
+		{
+			name:    "wal2json",
+			options: ReplicationOptions{Decoder: DecoderWal2JSON, LogicalMessages: true},
+			want:    []string{`"format-version" '2'`, `"include-xids" '1'`, `"include-timestamp" '1'`, `"include-pk" '1'`, `"include-type-oids" '1'`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pluginArgs(tt.options); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pluginArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplicationOptionsValidate(t *testing.T) {
+	if err := (ReplicationOptions{StreamLargeTransactions: true, TwoPhaseCommit: true}).Validate(); err != nil {
+		t.Errorf("pgoutput Validate() error = %v", err)
+	}
+	if err := (ReplicationOptions{Decoder: DecoderWal2JSON, LogicalMessages: true}).Validate(); err != nil {
+		t.Errorf("wal2json Validate() error = %v", err)
+	}
+	for _, options := range []ReplicationOptions{
+		{Decoder: DecoderWal2JSON, StreamLargeTransactions: true},
+		{Decoder: DecoderWal2JSON, TwoPhaseCommit: true},
+	} {
+		if err := options.Validate(); err == nil {
+			t.Errorf("Validate(%+v) should fail", options)
+		}
+	}
+}
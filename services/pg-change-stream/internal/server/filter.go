@@ -0,0 +1,23 @@
+package server
+
+import (
+	"slices"
+
+	"kasho/pkg/types"
+)
+
+// CapturesSchema reports whether a change read from the WAL belongs to one of the
+// captured schemas. An empty list captures every schema; DDL is always captured.
+func CapturesSchema(schemas []string, change types.Change) bool {
+	if len(schemas) == 0 {
+		return true
+	}
+	switch data := change.Data.(type) {
+	case types.DMLData:
+		return slices.Contains(schemas, data.Schema)
+	case *types.DMLData:
+		return slices.Contains(schemas, data.Schema)
+	default:
+		return true
+	}
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"testing"
+
+	"kasho/pkg/types"
+)
+
+func TestCapturesSchema(t *testing.T) {
+	app1 := types.Change{Position: "0/700", Data: types.DMLData{Table: "app1.users", Schema: "app1"}}
+	app2 := types.Change{Position: "0/700", Data: types.DMLData{Table: "app2.users", Schema: "app2"}}
+	ddl := types.Change{Position: "0/700", Data: types.DDLData{ID: 1, DDL: "CREATE TABLE app2.users (id int)"}}
+
+	tests := []struct {
+		name    string
+		schemas []string
+		change  types.Change
+		want    bool
+	}{
+		{"no allowlist", nil, app2, true},
+		{"captured schema", []string{"app1", "app2"}, app2, true},
+		{"schema not captured", []string{"app1"}, app2, false},
+		{"pointer data", []string{"app1"}, types.Change{Data: &types.DMLData{Schema: "app1"}}, true},
+		{"ddl always captured", []string{"app1"}, ddl, true},
+		{"captured in first schema", []string{"app1"}, app1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CapturesSchema(tt.schemas, tt.change); got != tt.want {
+				t.Errorf("CapturesSchema() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
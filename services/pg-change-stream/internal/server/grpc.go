@@ -8,9 +8,15 @@ import (
 	"sync"
 	"time"
 
+	"kasho/pkg/auth"
 	"kasho/pkg/kvbuffer"
+	"kasho/pkg/streamserver"
 	"kasho/pkg/types"
 	"kasho/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
 )
 
 type ChangeStreamServer struct {
@@ -21,6 +27,22 @@ type ChangeStreamServer struct {
 	connectedClients int32
 	clientsMu        sync.Mutex
 	startTime        time.Time
+	authenticator    *auth.Authenticator
+
+	// Server-wide pacing limits for the Stream RPC; zero means unlimited
+	maxChangesPerSecond uint32
+	maxBytesPerSecond   uint64
+	maxInFlightBytes    uint64
+	streams             streamserver.Streams // open streams with an in-flight limit
+
+	heartbeatInterval time.Duration
+	currentPosition   string
+	positionMu        sync.RWMutex
+
+	accumulationLimits streamserver.AccumulationLimits
+	accumulatedBytes   int64 // guarded by stateMu
+
+	publications *Publications
 }
 
 func NewChangeStreamServer(buffer *kvbuffer.KVBuffer) *ChangeStreamServer {
@@ -34,6 +56,43 @@ func NewChangeStreamServer(buffer *kvbuffer.KVBuffer) *ChangeStreamServer {
 	}
 }
 
+// SetAuthenticator enables token authentication on the Stream RPC
+func (s *ChangeStreamServer) SetAuthenticator(authenticator *auth.Authenticator) {
+	s.authenticator = authenticator
+}
+
+// SetStreamLimits sets the maximum pacing limits applied to every stream.
+// Consumers may request lower limits but cannot exceed these.
+func (s *ChangeStreamServer) SetStreamLimits(changesPerSecond uint32, bytesPerSecond, inFlightBytes uint64) {
+	s.maxChangesPerSecond = changesPerSecond
+	s.maxBytesPerSecond = bytesPerSecond
+	s.maxInFlightBytes = inFlightBytes
+}
+
+// SetHeartbeatInterval sets how often idle streams receive a heartbeat; zero disables heartbeats
+func (s *ChangeStreamServer) SetHeartbeatInterval(interval time.Duration) {
+	s.heartbeatInterval = interval
+}
+
+// SetPublications sets the publication check whose unpublished tables GetStatus reports
+func (s *ChangeStreamServer) SetPublications(publications *Publications) {
+	s.publications = publications
+}
+
+// SetCurrentPosition records the latest position read from the source database
+func (s *ChangeStreamServer) SetCurrentPosition(position string) {
+	s.positionMu.Lock()
+	defer s.positionMu.Unlock()
+	s.currentPosition = position
+}
+
+// GetCurrentPosition returns the latest position read from the source database
+func (s *ChangeStreamServer) GetCurrentPosition() string {
+	s.positionMu.RLock()
+	defer s.positionMu.RUnlock()
+	return s.currentPosition
+}
+
 // SetState sets the state
 func (s *ChangeStreamServer) SetState(state *StateInfo) {
 	s.stateMu.Lock()
@@ -41,8 +100,8 @@ func (s *ChangeStreamServer) SetState(state *StateInfo) {
 	s.state = state
 }
 
-// GetState returns the current state
-func (s *ChangeStreamServer) GetState() State {
+// CurrentState returns the current state
+func (s *ChangeStreamServer) CurrentState() State {
 	s.stateMu.RLock()
 	defer s.stateMu.RUnlock()
 	return s.state.Current
@@ -56,6 +115,40 @@ func (s *ChangeStreamServer) IncrementAccumulated() {
 }
 
 func (s *ChangeStreamServer) Stream(req *proto.StreamRequest, stream proto.ChangeStream_StreamServer) error {
+	token, err := streamserver.Authenticate(stream.Context(), s.authenticator)
+	if err != nil {
+		return err
+	}
+
+	// Send heartbeats on idle streams so consumers can detect dead connections
+	var heartbeats <-chan time.Time
+	if s.heartbeatInterval > 0 {
+		ticker := time.NewTicker(s.heartbeatInterval)
+		defer ticker.Stop()
+		heartbeats = ticker.C
+	}
+	lastSent := time.Now()
+
+	filter, err := streamserver.NewFilter(req)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Pace the stream so bulk replays don't saturate the network or run far ahead of
+	// the consumer. The stream's ID goes out before the first heartbeat.
+	changesPerSecond := streamserver.EffectiveLimit(s.maxChangesPerSecond, req.MaxChangesPerSecond)
+	bytesPerSecond := streamserver.EffectiveLimit(s.maxBytesPerSecond, req.MaxBytesPerSecond)
+	inFlightBytes := streamserver.EffectiveLimit(s.maxInFlightBytes, req.MaxInFlightBytes)
+	if changesPerSecond > 0 || bytesPerSecond > 0 || inFlightBytes > 0 {
+		log.Printf("Pacing stream to %d changes/s, %d bytes/s and %d bytes in flight (0 = unlimited)", changesPerSecond, bytesPerSecond, inFlightBytes)
+	}
+	streamPacer := streamserver.NewPacer(changesPerSecond, bytesPerSecond, inFlightBytes)
+	closeStream, err := s.streams.Open(stream, streamPacer)
+	if err != nil {
+		return err
+	}
+	defer closeStream()
+
 	// Check if we're in streaming state
 	s.stateMu.RLock()
 	currentState := s.state.Current
@@ -74,6 +167,11 @@ func (s *ChangeStreamServer) Stream(req *proto.StreamRequest, stream proto.Chang
 			select {
 			case <-stream.Context().Done():
 				return stream.Context().Err()
+			case <-heartbeats:
+				if err := stream.Send(streamserver.NewHeartbeat(s.GetCurrentPosition())); err != nil {
+					return err
+				}
+				lastSent = time.Now()
 			case <-time.After(100 * time.Millisecond):
 				// Check again
 			}
@@ -108,8 +206,14 @@ func (s *ChangeStreamServer) Stream(req *proto.StreamRequest, stream proto.Chang
 					log.Printf("Error unmarshaling buffered change: %v", err)
 					continue
 				}
+				if !streamserver.ChangeAllowed(token, change) || !filter.Allows(change) {
+					continue
+				}
 				
-				protoChange := convertToProtoChange(change)
+				protoChange := streamserver.ConvertChange(change)
+				if err := streamPacer.Wait(stream.Context(), protoChange.Position, protobuf.Size(protoChange)); err != nil {
+					return err
+				}
 				if err := stream.Send(protoChange); err != nil {
 					return err
 				}
@@ -133,65 +237,52 @@ func (s *ChangeStreamServer) Stream(req *proto.StreamRequest, stream proto.Chang
 		select {
 		case <-stream.Context().Done():
 			return nil
+		case <-heartbeats:
+			if time.Since(lastSent) < s.heartbeatInterval {
+				continue
+			}
+			if err := stream.Send(streamserver.NewHeartbeat(s.GetCurrentPosition())); err != nil {
+				return err
+			}
+			lastSent = time.Now()
 		case msg := <-pubsub.Channel():
 			var change types.Change
 			if err := json.Unmarshal([]byte(msg.Payload), &change); err != nil {
 				log.Printf("Error unmarshaling change: %v", err)
 				continue
 			}
+			if !streamserver.ChangeAllowed(token, change) || !filter.Allows(change) {
+				continue
+			}
 			
-			protoChange := convertToProtoChange(change)
+			protoChange := streamserver.ConvertChange(change)
+			if err := streamPacer.Wait(stream.Context(), protoChange.Position, protobuf.Size(protoChange)); err != nil {
+				return err
+			}
 			if err := stream.Send(protoChange); err != nil {
 				return err
 			}
+			lastSent = time.Now()
 		}
 	}
 }
 
-func convertToProtoChange(change types.Change) *proto.Change {
-	protoChange := &proto.Change{
-		Position: change.GetPosition(),
-		Type:     change.Type(),
+// Acknowledge releases the changes a consumer is done with from its stream's in-flight limit
+func (s *ChangeStreamServer) Acknowledge(ctx context.Context, req *proto.AcknowledgeRequest) (*proto.AcknowledgeResponse, error) {
+	if _, err := streamserver.Authenticate(ctx, s.authenticator); err != nil {
+		return nil, err
 	}
-
-	switch data := change.Data.(type) {
-	case *types.DMLData:
-		dml := &proto.DMLData{
-			Table:        data.Table,
-			ColumnNames:  data.ColumnNames,
-			ColumnValues: make([]*proto.ColumnValue, len(data.ColumnValues)),
-			Kind:         data.Kind,
-		}
-		for i, cv := range data.ColumnValues {
-			dml.ColumnValues[i] = cv.ColumnValue
-		}
-		if data.OldKeys != nil {
-			dml.OldKeys = &proto.OldKeys{
-				KeyNames:  data.OldKeys.KeyNames,
-				KeyValues: make([]*proto.ColumnValue, len(data.OldKeys.KeyValues)),
-			}
-			for i, cv := range data.OldKeys.KeyValues {
-				dml.OldKeys.KeyValues[i] = cv.ColumnValue
-			}
-		}
-		protoChange.Data = &proto.Change_Dml{Dml: dml}
-	case *types.DDLData:
-		protoChange.Data = &proto.Change_Ddl{
-			Ddl: &proto.DDLData{
-				Id:       int32(data.ID),
-				Time:     data.Time.Format(time.RFC3339),
-				Username: data.Username,
-				Database: data.Database,
-				Ddl:      data.DDL,
-			},
-		}
+	if err := s.streams.Acknowledge(req.StreamId, req.Position); err != nil {
+		return nil, err
 	}
-
-	return protoChange
+	return &proto.AcknowledgeResponse{}, nil
 }
 
 // StartBootstrap begins the accumulation phase for bootstrap
 func (s *ChangeStreamServer) StartBootstrap(ctx context.Context, req *proto.StartBootstrapRequest) (*proto.BootstrapResponse, error) {
+	// Accumulation limits count what the buffer gains from here
+	startDepth, startBytes := streamserver.MeasureBuffer(ctx, s.buffer)
+
 	s.stateMu.Lock()
 	defer s.stateMu.Unlock()
 	
@@ -209,6 +300,9 @@ func (s *ChangeStreamServer) StartBootstrap(ctx context.Context, req *proto.Star
 	s.state.StartLSN = req.StartPosition
 	s.state.TransitionTime = time.Now()
 	s.state.AccumulatedChanges = 0
+	s.state.StartDepth = startDepth
+	s.state.StartBytes = startBytes
+	s.accumulatedBytes = 0
 	
 	if err := s.SaveState(ctx, s.state); err != nil {
 		// Rollback
@@ -273,9 +367,7 @@ func (s *ChangeStreamServer) GetStatus(ctx context.Context, req *proto.GetStatus
 	
 	uptime := int64(time.Since(s.startTime).Seconds())
 	
-	// Get current LSN from WAL client if available
-	currentLSN := ""
-	// TODO: Get from WAL client when integrated
+	currentLSN := s.GetCurrentPosition()
 	
 	return &proto.StatusResponse{
 		State:              currentState,
@@ -284,5 +376,7 @@ func (s *ChangeStreamServer) GetStatus(ctx context.Context, req *proto.GetStatus
 		AccumulatedChanges: accumulated,
 		ConnectedClients:   clients,
 		UptimeSeconds:      uptime,
+		DuplicateChanges:   s.buffer.Duplicates(),
+		UnpublishedTables:  s.publications.Unpublished(),
 	}, nil
 }
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"kasho/pkg/types"
+
+	"github.com/lib/pq"
+)
+
+// publicationName is the publication the WAL client subscribes to
+const publicationName = "kasho_pub"
+
+// UnpublishedTablePrefix is the prefix of the message change emitted for a table created on
+// the primary that isn't in the publication. Its content is the table's qualified name.
+const UnpublishedTablePrefix = "kasho.unpublished_table"
+
+// PublicationMode is what happens when a table created on the primary isn't in the
+// publication, so its changes would silently be missing from the stream
+type PublicationMode string
+
+const (
+	// PublicationOff doesn't check new tables
+	PublicationOff PublicationMode = "off"
+	// PublicationWarn logs the table, counts it and emits an UnpublishedTablePrefix message change
+	PublicationWarn PublicationMode = "warn"
+	// PublicationAdd adds the table to the publication
+	PublicationAdd PublicationMode = "add"
+)
+
+// ParsePublicationMode parses a PUBLICATION_NEW_TABLES value; empty means warn
+func ParsePublicationMode(s string) (PublicationMode, error) {
+	switch mode := PublicationMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "":
+		return PublicationWarn, nil
+	case PublicationOff, PublicationWarn, PublicationAdd:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid publication mode %q: must be off, warn or add", s)
+	}
+}
+
+// createTablePattern matches the tables created by the statements of a DDL change.
+// Temporary and foreign tables can't be published, so they aren't matched.
+var createTablePattern = regexp.MustCompile(`(?is)(?:^|;)\s*CREATE\s+(?:UNLOGGED\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?((?:"[^"]*"|[^\s(;,"])+)`)
+
+// CreatedTables returns the names of the tables a DDL statement creates, as written
+func CreatedTables(ddl string) []string {
+	var tables []string
+	for _, match := range createTablePattern.FindAllStringSubmatch(ddl, -1) {
+		tables = append(tables, match[1])
+	}
+	return tables
+}
+
+// Publications checks that tables created on the primary are in the publication
+type Publications struct {
+	db          *sql.DB
+	mode        PublicationMode
+	schemas     []string
+	unpublished atomic.Int64
+}
+
+// NewPublications creates a publication check on the primary. Only tables in schemas are
+// checked; an empty list checks every schema.
+func NewPublications(dbURL string, mode PublicationMode, schemas []string) (*Publications, error) {
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return &Publications{db: db, mode: mode, schemas: schemas}, nil
+}
+
+// Close closes the connection to the primary
+func (p *Publications) Close() error {
+	return p.db.Close()
+}
+
+// Unpublished returns how many created tables were found missing from the publication and
+// weren't added to it
+func (p *Publications) Unpublished() int64 {
+	if p == nil {
+		return 0
+	}
+	return p.unpublished.Load()
+}
+
+// Check checks the tables created by a DDL change. In warn mode, or when adding a table
+// fails, it returns a message change for each table that isn't published. Rows written to a
+// table before it is added, such as in the transaction creating it, aren't streamed.
+func (p *Publications) Check(ctx context.Context, change types.Change) []types.Change {
+	if p.mode == PublicationOff {
+		return nil
+	}
+	var ddl string
+	switch data := change.Data.(type) {
+	case types.DDLData:
+		ddl = data.DDL
+	case *types.DDLData:
+		ddl = data.DDL
+	default:
+		return nil
+	}
+
+	var warnings []types.Change
+	for _, name := range CreatedTables(ddl) {
+		schema, table, published, err := p.lookup(ctx, name)
+		if err != nil {
+			log.Printf("Failed to check whether table %s is published: %v", name, err)
+			continue
+		}
+		// The table was dropped since, or isn't in a captured schema
+		if table == "" || published || (len(p.schemas) > 0 && !slices.Contains(p.schemas, schema)) {
+			continue
+		}
+
+		qualified := schema + "." + table
+		if p.mode == PublicationAdd {
+			err := p.add(ctx, schema, table)
+			if err == nil {
+				log.Printf("Added new table %s to publication %s", qualified, publicationName)
+				continue
+			}
+			log.Printf("Failed to add table %s to publication %s: %v", qualified, publicationName, err)
+		}
+
+		p.unpublished.Add(1)
+		log.Printf("Warning: new table %s isn't in publication %s, so its changes aren't streamed", qualified, publicationName)
+		warnings = append(warnings, types.Change{
+			Position: change.Position,
+			Data: types.MessageData{
+				Prefix:  UnpublishedTablePrefix,
+				Content: []byte(qualified),
+			},
+			Dialect: "postgresql",
+		})
+	}
+	return warnings
+}
+
+// lookup resolves a table name as written in DDL and reports whether the publication
+// includes it. The table is empty if it doesn't exist or can't be published.
+func (p *Publications) lookup(ctx context.Context, name string) (schema, table string, published bool, err error) {
+	err = p.db.QueryRowContext(ctx, `
+		SELECT n.nspname, c.relname,
+			EXISTS (SELECT 1 FROM pg_publication_tables pt
+				WHERE pt.pubname = $2 AND pt.schemaname = n.nspname AND pt.tablename = c.relname)
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.oid = to_regclass($1) AND c.relkind IN ('r', 'p') AND c.relpersistence = 'p'
+	`, name, publicationName).Scan(&schema, &table, &published)
+	if err == sql.ErrNoRows {
+		return "", "", false, nil
+	}
+	return schema, table, published, err
+}
+
+// add adds a table to the publication. The statement is marked as logged, so the DDL
+// capture installed by kasho-setup-primary doesn't send it to replicas, which have no
+// publication.
+func (p *Publications) add(ctx context.Context, schema, table string) error {
+	statement := fmt.Sprintf("ALTER PUBLICATION %s ADD TABLE %s.%s", publicationName, pq.QuoteIdentifier(schema), pq.QuoteIdentifier(table))
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "SELECT set_config('kasho.ddl_logged', $1, true)", statement); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, statement); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"kasho/pkg/types"
+)
+
+func TestCreatedTables(t *testing.T) {
+	tests := []struct {
+		name string
+		ddl  string
+		want []string
+	}{
+		{"unqualified", "CREATE TABLE users (id int)", []string{"users"}},
+		{"qualified", "create table public.orders(id int)", []string{"public.orders"}},
+		{"quoted", `CREATE TABLE IF NOT EXISTS "Sales"."Order Items" (id int)`, []string{`"Sales"."Order Items"`}},
+		{"unlogged", "CREATE UNLOGGED TABLE staging (id int)", []string{"staging"}},
+		{"as select", "CREATE TABLE archive AS SELECT * FROM orders", []string{"archive"}},
+		{"several statements", "CREATE TABLE a (id int); ALTER TABLE a ADD COLUMN b int;\nCREATE TABLE c (id int);", []string{"a", "c"}},
+		{"temporary", "CREATE TEMP TABLE scratch (id int)", nil},
+		{"foreign", "CREATE FOREIGN TABLE remote (id int) SERVER other", nil},
+		{"not at statement start", "CREATE FUNCTION f() RETURNS void AS $$ BEGIN EXECUTE 'CREATE TABLE x (id int)'; END $$ LANGUAGE plpgsql", nil},
+		{"alter", "ALTER TABLE users ADD COLUMN email text", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CreatedTables(tt.ddl); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CreatedTables(%q) = %q, want %q", tt.ddl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePublicationMode(t *testing.T) {
+	for input, want := range map[string]PublicationMode{"": PublicationWarn, "off": PublicationOff, " ADD ": PublicationAdd, "warn": PublicationWarn} {
+		got, err := ParsePublicationMode(input)
+		if err != nil || got != want {
+			t.Errorf("ParsePublicationMode(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParsePublicationMode("always"); err == nil {
+		t.Error("ParsePublicationMode(\"always\") expected an error")
+	}
+}
+
+func TestPublicationsCheckSkipsWithoutQuery(t *testing.T) {
+	// The database is never reached: sql.Open doesn't connect
+	ddl := types.Change{Position: "0/100", Data: types.DDLData{DDL: "CREATE TABLE users (id int)"}}
+	dml := types.Change{Position: "0/100", Data: &types.DMLData{Table: "public.users", Kind: "insert"}}
+	alter := types.Change{Position: "0/100", Data: &types.DDLData{DDL: "ALTER TABLE users ADD COLUMN email text"}}
+
+	off, err := NewPublications("postgres://localhost:1/none", PublicationOff, nil)
+	if err != nil {
+		t.Fatalf("NewPublications() unexpected error: %v", err)
+	}
+	defer off.Close()
+	warn, err := NewPublications("postgres://localhost:1/none", PublicationWarn, nil)
+	if err != nil {
+		t.Fatalf("NewPublications() unexpected error: %v", err)
+	}
+	defer warn.Close()
+
+	for name, check := range map[string]func() []types.Change{
+		"off":       func() []types.Change { return off.Check(context.Background(), ddl) },
+		"dml":       func() []types.Change { return warn.Check(context.Background(), dml) },
+		"no create": func() []types.Change { return warn.Check(context.Background(), alter) },
+	} {
+		if got := check(); got != nil {
+			t.Errorf("%s: Check() = %v, want no warnings", name, got)
+		}
+	}
+	if warn.Unpublished() != 0 {
+		t.Errorf("Unpublished() = %d, want 0", warn.Unpublished())
+	}
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+
+	"github.com/jackc/pglogrepl"
+)
+
+// relationKeyPrefix prefixes the KV keys of persisted relations, which are keyed by OID
+const relationKeyPrefix = "kasho:pg:relation:"
+
+// RelationStore persists relations, such as the KV buffer
+type RelationStore interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string) error
+}
+
+// storedRelation is a persisted relation and its schema version
+type storedRelation struct {
+	Version         string         `json:"version"`
+	Namespace       string         `json:"namespace"`
+	Name            string         `json:"name"`
+	ReplicaIdentity uint8          `json:"replica_identity"`
+	Columns         []storedColumn `json:"columns"`
+}
+
+type storedColumn struct {
+	Name         string `json:"name"`
+	DataType     uint32 `json:"data_type"`
+	TypeModifier int32  `json:"type_modifier"`
+	Flags        uint8  `json:"flags"`
+}
+
+// Relations tracks the relations pgoutput described, by OID. pgoutput describes a
+// relation once per replication connection, before its first change; with a store, the
+// relations are persisted so changes can still be decoded if the description isn't
+// received again, e.g. after a restart. A stored relation is only rewritten when its
+// schema version, a hash of its name and columns, changes.
+type Relations struct {
+	mu       sync.Mutex
+	byID     map[uint32]*pglogrepl.RelationMessageV2
+	versions map[uint32]string
+	store    RelationStore
+}
+
+// NewRelations creates the relation tracking of a replication client. The store may be nil
+// to keep relations in memory only.
+func NewRelations(store RelationStore) *Relations {
+	return &Relations{
+		byID:     make(map[uint32]*pglogrepl.RelationMessageV2),
+		versions: make(map[uint32]string),
+		store:    store,
+	}
+}
+
+// Add records a relation. A failure to persist it is logged; the relation is still
+// known in memory.
+func (r *Relations) Add(ctx context.Context, rel *pglogrepl.RelationMessageV2) {
+	stored := storeRelation(rel)
+
+	r.mu.Lock()
+	r.byID[rel.RelationID] = rel
+	changed := r.versions[rel.RelationID] != stored.Version
+	r.versions[rel.RelationID] = stored.Version
+	r.mu.Unlock()
+
+	if r.store == nil || !changed {
+		return
+	}
+	data, err := json.Marshal(stored)
+	if err == nil {
+		err = r.store.Set(ctx, relationKey(rel.RelationID), string(data))
+	}
+	if err != nil {
+		log.Printf("Failed to persist relation %s.%s: %v", rel.Namespace, rel.RelationName, err)
+	}
+}
+
+// Get returns a relation by OID, loading it from the store if it wasn't described on this
+// connection
+func (r *Relations) Get(ctx context.Context, id uint32) (*pglogrepl.RelationMessageV2, error) {
+	r.mu.Lock()
+	rel, ok := r.byID[id]
+	r.mu.Unlock()
+	if ok {
+		return rel, nil
+	}
+	if r.store == nil {
+		return nil, fmt.Errorf("unknown relation ID %d", id)
+	}
+
+	data, err := r.store.Get(ctx, relationKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load relation ID %d: %w", id, err)
+	}
+	if data == "" {
+		return nil, fmt.Errorf("unknown relation ID %d", id)
+	}
+	var stored storedRelation
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		return nil, fmt.Errorf("invalid stored relation ID %d: %w", id, err)
+	}
+
+	rel = loadRelation(id, stored)
+	r.mu.Lock()
+	r.byID[id] = rel
+	r.versions[id] = stored.Version
+	r.mu.Unlock()
+	log.Printf("Loaded relation %s.%s (version %s) from the KV buffer", rel.Namespace, rel.RelationName, stored.Version)
+	return rel, nil
+}
+
+func relationKey(id uint32) string {
+	return relationKeyPrefix + strconv.FormatUint(uint64(id), 10)
+}
+
+// storeRelation converts a relation to its persisted form
+func storeRelation(rel *pglogrepl.RelationMessageV2) storedRelation {
+	stored := storedRelation{
+		Namespace:       rel.Namespace,
+		Name:            rel.RelationName,
+		ReplicaIdentity: rel.ReplicaIdentity,
+		Columns:         make([]storedColumn, len(rel.Columns)),
+	}
+	for i, col := range rel.Columns {
+		stored.Columns[i] = storedColumn{Name: col.Name, DataType: col.DataType, TypeModifier: col.TypeModifier, Flags: col.Flags}
+	}
+	data, _ := json.Marshal(stored)
+	sum := sha256.Sum256(data)
+	stored.Version = hex.EncodeToString(sum[:8])
+	return stored
+}
+
+// loadRelation converts a persisted relation back to a relation message
+func loadRelation(id uint32, stored storedRelation) *pglogrepl.RelationMessageV2 {
+	rel := &pglogrepl.RelationMessageV2{
+		RelationMessage: pglogrepl.RelationMessage{
+			RelationID:      id,
+			Namespace:       stored.Namespace,
+			RelationName:    stored.Name,
+			ReplicaIdentity: stored.ReplicaIdentity,
+			ColumnNum:       uint16(len(stored.Columns)),
+		},
+	}
+	for _, col := range stored.Columns {
+		rel.Columns = append(rel.Columns, &pglogrepl.RelationMessageColumn{Name: col.Name, DataType: col.DataType, TypeModifier: col.TypeModifier, Flags: col.Flags})
+	}
+	return rel
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pglogrepl"
+)
+
+// mapStore is an in-memory RelationStore that counts writes
+type mapStore struct {
+	values map[string]string
+	sets   int
+}
+
+func (s *mapStore) Get(ctx context.Context, key string) (string, error) {
+	return s.values[key], nil
+}
+
+func (s *mapStore) Set(ctx context.Context, key, value string) error {
+	s.values[key] = value
+	s.sets++
+	return nil
+}
+
+func testRelation(columns ...string) *pglogrepl.RelationMessageV2 {
+	rel := &pglogrepl.RelationMessageV2{
+		RelationMessage: pglogrepl.RelationMessage{
+			RelationID:      16384,
+			Namespace:       "public",
+			RelationName:    "users",
+			ReplicaIdentity: 'd',
+			ColumnNum:       uint16(len(columns)),
+		},
+	}
+	for i, name := range columns {
+		col := &pglogrepl.RelationMessageColumn{Name: name, DataType: 25, TypeModifier: -1}
+		if i == 0 {
+			col.Flags = 1 // key column
+		}
+		rel.Columns = append(rel.Columns, col)
+	}
+	return rel
+}
+
+func TestRelationsPersist(t *testing.T) {
+	ctx := context.Background()
+	store := &mapStore{values: map[string]string{}}
+
+	NewRelations(store).Add(ctx, testRelation("id", "email"))
+	if _, ok := store.values["kasho:pg:relation:16384"]; !ok {
+		t.Fatalf("relation not stored, keys = %v", store.values)
+	}
+
+	// A new process loads the relation without it being described again
+	rel, err := NewRelations(store).Get(ctx, 16384)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if rel.Namespace != "public" || rel.RelationName != "users" || rel.ReplicaIdentity != 'd' {
+		t.Errorf("Get() = %s.%s (identity %c), want public.users (identity d)", rel.Namespace, rel.RelationName, rel.ReplicaIdentity)
+	}
+	if len(rel.Columns) != 2 || rel.ColumnNum != 2 {
+		t.Fatalf("Get() has %d columns (ColumnNum %d), want 2", len(rel.Columns), rel.ColumnNum)
+	}
+	if col := rel.Columns[0]; col.Name != "id" || col.DataType != 25 || col.TypeModifier != -1 || col.Flags != 1 {
+		t.Errorf("Get() column 0 = %+v, want id text key", *col)
+	}
+	if col := rel.Columns[1]; col.Name != "email" || col.Flags != 0 {
+		t.Errorf("Get() column 1 = %+v, want email", *col)
+	}
+}
+
+func TestRelationsWriteOnVersionChange(t *testing.T) {
+	ctx := context.Background()
+	store := &mapStore{values: map[string]string{}}
+	relations := NewRelations(store)
+
+	relations.Add(ctx, testRelation("id", "email"))
+	relations.Add(ctx, testRelation("id", "email"))
+	if store.sets != 1 {
+		t.Errorf("store written %d times for an unchanged relation, want 1", store.sets)
+	}
+
+	relations.Add(ctx, testRelation("id", "email", "name"))
+	if store.sets != 2 {
+		t.Errorf("store written %d times after a column was added, want 2", store.sets)
+	}
+	rel, err := NewRelations(store).Get(ctx, 16384)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(rel.Columns) != 3 {
+		t.Errorf("Get() has %d columns, want the 3 of the latest version", len(rel.Columns))
+	}
+
+	// A relation loaded from the store isn't written back when described again
+	loaded := NewRelations(store)
+	if _, err := loaded.Get(ctx, 16384); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	loaded.Add(ctx, testRelation("id", "email", "name"))
+	if store.sets != 2 {
+		t.Errorf("store written %d times after reloading, want 2", store.sets)
+	}
+}
+
+func TestRelationsUnknown(t *testing.T) {
+	ctx := context.Background()
+	for name, relations := range map[string]*Relations{
+		"memory": NewRelations(nil),
+		"store":  NewRelations(&mapStore{values: map[string]string{}}),
+	} {
+		if _, err := relations.Get(ctx, 42); err == nil {
+			t.Errorf("%s: Get() of an unknown relation should fail", name)
+		}
+	}
+}
@@ -5,36 +5,27 @@ import (
 	"encoding/json"
 	"fmt"
 	"time"
+
+	"kasho/pkg/streamserver"
 )
 
 // State represents the operational state of the change stream
-type State int
+type State = streamserver.State
 
 const (
-	StateWaiting State = iota
-	StateAccumulating
-	StateStreaming
+	StateWaiting      = streamserver.StateWaiting
+	StateAccumulating = streamserver.StateAccumulating
+	StateStreaming    = streamserver.StateStreaming
 )
 
-func (s State) String() string {
-	switch s {
-	case StateWaiting:
-		return "WAITING"
-	case StateAccumulating:
-		return "ACCUMULATING"
-	case StateStreaming:
-		return "STREAMING"
-	default:
-		return "UNKNOWN"
-	}
-}
-
 // StateInfo holds the persistent state information
 type StateInfo struct {
 	Current            State     `json:"current"`
 	StartLSN           string    `json:"start_lsn,omitempty"`
 	TransitionTime     time.Time `json:"transition_time"`
 	AccumulatedChanges int64     `json:"accumulated_changes"`
+	StartDepth         int64     `json:"start_depth,omitempty"` // Buffer depth when accumulation began
+	StartBytes         int64     `json:"start_bytes,omitempty"` // Buffer size in bytes when accumulation began
 }
 
 const stateKey = "kasho:change-stream:state"
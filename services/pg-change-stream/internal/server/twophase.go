@@ -0,0 +1,118 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"time"
+
+	"kasho/pkg/types"
+
+	"github.com/jackc/pglogrepl"
+)
+
+// Message types pgoutput sends for two-phase transactions with protocol version 3
+const (
+	beginPrepareByteID     = 'b'
+	prepareByteID          = 'P'
+	commitPreparedByteID   = 'K'
+	rollbackPreparedByteID = 'r'
+	streamPrepareByteID    = 'p'
+)
+
+// preparedMessage is a two-phase message. pglogrepl only decodes protocol version 2,
+// so these are decoded here.
+type preparedMessage struct {
+	typ  byte
+	xid  uint32
+	gid  string
+	time time.Time // the commit or rollback time; the prepare time otherwise
+}
+
+func isPreparedMessage(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	switch data[0] {
+	case beginPrepareByteID, prepareByteID, commitPreparedByteID, rollbackPreparedByteID, streamPrepareByteID:
+		return true
+	}
+	return false
+}
+
+// parsePreparedMessage decodes a two-phase message:
+//
+//	BEGIN PREPARE:     prepare LSN, end LSN, prepare time, xid, gid
+//	PREPARE:           flags, prepare LSN, end LSN, prepare time, xid, gid
+//	STREAM PREPARE:    flags, prepare LSN, end LSN, prepare time, xid, gid
+//	COMMIT PREPARED:   flags, commit LSN, end LSN, commit time, xid, gid
+//	ROLLBACK PREPARED: flags, prepare end LSN, rollback end LSN, prepare time, rollback time, xid, gid
+func parsePreparedMessage(data []byte) (*preparedMessage, error) {
+	msg := &preparedMessage{typ: data[0]}
+	body := data[1:]
+	if msg.typ != beginPrepareByteID {
+		body = body[min(1, len(body)):] // flags
+	}
+
+	skip := 16 // two LSNs
+	if msg.typ == rollbackPreparedByteID {
+		skip += 8 // the prepare time
+	}
+	if len(body) < skip+8+4+1 {
+		return nil, fmt.Errorf("%c message too short: %d bytes", msg.typ, len(data))
+	}
+	body = body[skip:]
+
+	msg.time = pgTime(int64(binary.BigEndian.Uint64(body)))
+	msg.xid = binary.BigEndian.Uint32(body[8:])
+	gid, _, found := bytes.Cut(body[12:], []byte{0})
+	if !found {
+		return nil, fmt.Errorf("%c message has an unterminated gid", msg.typ)
+	}
+	msg.gid = string(gid)
+	return msg, nil
+}
+
+// pgTime converts microseconds since 2000-01-01 UTC to a time
+func pgTime(micros int64) time.Time {
+	return time.Unix(946684800, 0).UTC().Add(time.Duration(micros) * time.Microsecond)
+}
+
+// handlePreparedMessage tracks a prepared transaction. Its changes are sent as in
+// progress when it's prepared, and a transaction change follows when it's committed
+// or rolled back, as for a streamed transaction, so consumers hold them until then.
+// The transaction change is at the COMMIT PREPARED position, so the transaction is
+// applied in the order it committed rather than the order it was prepared.
+func (p *Parser) handlePreparedMessage(data []byte, lsn pglogrepl.LSN) ([]types.Change, error) {
+	msg, err := parsePreparedMessage(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing WAL message: %w", err)
+	}
+
+	change := types.Change{
+		Position:      lsn.String(),
+		Data:          types.TransactionData{},
+		Dialect:       "postgresql",
+		TransactionID: strconv.FormatUint(uint64(msg.xid), 10),
+		Metadata:      map[string]string{"gid": msg.gid},
+	}
+	switch msg.typ {
+	case beginPrepareByteID:
+		p.prepare.active = true
+		p.prepare.xid = msg.xid
+	case prepareByteID:
+		p.prepare.active = false
+		p.prepare.xid = 0
+	case streamPrepareByteID:
+		// The streamed changes wait for COMMIT PREPARED like the others
+	case commitPreparedByteID:
+		change.TransactionStatus = "committed"
+		change.CommitTime = msg.time
+		return []types.Change{change}, nil
+	case rollbackPreparedByteID:
+		change.TransactionStatus = "aborted"
+		return []types.Change{change}, nil
+	}
+	return nil, nil
+}
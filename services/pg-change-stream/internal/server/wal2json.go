@@ -0,0 +1,236 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"time"
+
+	"kasho/pkg/types"
+
+	"github.com/jackc/pglogrepl"
+)
+
+// wal2jsonMessage is a change in wal2json's format version 2, which sends one JSON object
+// per change
+type wal2jsonMessage struct {
+	Action        string           `json:"action"`
+	Xid           uint32           `json:"xid"`
+	Timestamp     string           `json:"timestamp"`
+	Schema        string           `json:"schema"`
+	Table         string           `json:"table"`
+	Columns       []wal2jsonColumn `json:"columns"`
+	Identity      []wal2jsonColumn `json:"identity"`
+	PK            []wal2jsonColumn `json:"pk"`
+	Transactional bool             `json:"transactional"`
+	Prefix        string           `json:"prefix"`
+	Content       string           `json:"content"`
+}
+
+// wal2jsonColumn is a column of a change. Numbers and booleans are JSON values, other types
+// their text representation.
+type wal2jsonColumn struct {
+	Name    string `json:"name"`
+	TypeOID uint32 `json:"typeoid"`
+	Value   any    `json:"value"`
+}
+
+// Wal2JSONDecoder decodes the wal2json messages of one replication connection into the
+// same changes as pgoutput. wal2json describes each change in full, so it needs no
+// relations. Unchanged TOASTed columns are left out of updates.
+type Wal2JSONDecoder struct {
+	transaction     sourceTransaction
+	logicalMessages bool
+}
+
+// NewWal2JSONDecoder creates a decoder for a new replication connection. wal2json always
+// sends logical decoding messages; they are dropped unless logicalMessages is set.
+func NewWal2JSONDecoder(logicalMessages bool) *Wal2JSONDecoder {
+	return &Wal2JSONDecoder{logicalMessages: logicalMessages}
+}
+
+func (d *Wal2JSONDecoder) ParseWALData(ctx context.Context, walData []byte, lsn pglogrepl.LSN) ([]types.Change, error) {
+	var msg wal2jsonMessage
+	decoder := json.NewDecoder(bytes.NewReader(walData))
+	decoder.UseNumber()
+	if err := decoder.Decode(&msg); err != nil {
+		return nil, fmt.Errorf("error parsing wal2json message: %w", err)
+	}
+
+	var changes []types.Change
+
+	switch msg.Action {
+	case "B":
+		commitTime, err := decodeColumnData(&pglogrepl.TupleDataColumn{Data: []byte(msg.Timestamp)}, 1184)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding commit time: %w", err)
+		}
+		d.transaction = sourceTransaction{xid: msg.Xid}
+		if t, ok := commitTime.(time.Time); ok {
+			d.transaction.commitTime = t
+		}
+
+	case "C":
+		d.transaction = sourceTransaction{}
+
+	case "I":
+		tableName := fmt.Sprintf("%s.%s", msg.Schema, msg.Table)
+		if tableName == ddlLogTable {
+			ddl := types.DDLData{}
+			for _, col := range msg.Columns {
+				value, err := decodeWal2JSONValue(col)
+				if err != nil {
+					return nil, err
+				}
+				setDDLColumn(&ddl, col.Name, value)
+			}
+			changes = append(changes, types.Change{Position: lsn.String(), Data: ddl})
+			break
+		}
+
+		dml := newWal2JSONDML(msg, "insert")
+		for _, col := range msg.Columns {
+			value, err := decodeWal2JSONValue(col)
+			if err != nil {
+				return nil, err
+			}
+			dml.ColumnNames = append(dml.ColumnNames, col.Name)
+			dml.ColumnValues = append(dml.ColumnValues, toColumnValue(value))
+		}
+		changes = append(changes, types.Change{Position: lsn.String(), Data: dml})
+
+	case "U":
+		// The identity holds the old replica identity columns, or with REPLICA IDENTITY
+		// FULL every old column
+		oldValues := make(map[string]any, len(msg.Identity))
+		for _, col := range msg.Identity {
+			value, err := decodeWal2JSONValue(col)
+			if err != nil {
+				return nil, err
+			}
+			oldValues[col.Name] = value
+		}
+
+		dml := newWal2JSONDML(msg, "update")
+		dml.OldKeys = newOldKeys(len(dml.PrimaryKey))
+		keys := make(map[string]bool, len(dml.PrimaryKey))
+		for _, name := range dml.PrimaryKey {
+			keys[name] = true
+		}
+
+		// Key columns are only included when they changed, and other columns unless
+		// the identity shows they didn't
+		for _, col := range msg.Columns {
+			newValue, err := decodeWal2JSONValue(col)
+			if err != nil {
+				return nil, err
+			}
+			oldValue, exists := oldValues[col.Name]
+			if keys[col.Name] {
+				key := newValue
+				if exists {
+					key = oldValue
+				}
+				dml.OldKeys.KeyNames = append(dml.OldKeys.KeyNames, col.Name)
+				dml.OldKeys.KeyValues = append(dml.OldKeys.KeyValues, toColumnValue(key))
+				if !exists {
+					continue
+				}
+			}
+			if !exists || !reflect.DeepEqual(oldValue, newValue) {
+				dml.ColumnNames = append(dml.ColumnNames, col.Name)
+				dml.ColumnValues = append(dml.ColumnValues, toColumnValue(newValue))
+			}
+		}
+		changes = append(changes, types.Change{Position: lsn.String(), Data: dml})
+
+	case "D":
+		dml := newWal2JSONDML(msg, "delete")
+		dml.OldKeys = newOldKeys(len(msg.Identity))
+		for _, col := range msg.Identity {
+			value, err := decodeWal2JSONValue(col)
+			if err != nil {
+				return nil, err
+			}
+			dml.OldKeys.KeyNames = append(dml.OldKeys.KeyNames, col.Name)
+			dml.OldKeys.KeyValues = append(dml.OldKeys.KeyValues, toColumnValue(value))
+		}
+		changes = append(changes, types.Change{Position: lsn.String(), Data: dml})
+
+	case "M":
+		if !d.logicalMessages {
+			break
+		}
+		changes = append(changes, types.Change{
+			Position: lsn.String(),
+			Data: types.MessageData{
+				Prefix:        msg.Prefix,
+				Content:       []byte(msg.Content),
+				Transactional: msg.Transactional,
+			},
+		})
+
+	default:
+		log.Printf("Unhandled wal2json action: %q", msg.Action)
+	}
+
+	for i := range changes {
+		d.transaction.setSourceMetadata(&changes[i])
+	}
+	return changes, nil
+}
+
+// newWal2JSONDML creates the DML change of a wal2json message, without its columns
+func newWal2JSONDML(msg wal2jsonMessage, kind string) types.DMLData {
+	dml := types.DMLData{
+		Table:        fmt.Sprintf("%s.%s", msg.Schema, msg.Table),
+		Kind:         kind,
+		ColumnNames:  make([]string, 0, len(msg.Columns)),
+		ColumnValues: make([]types.ColumnValueWrapper, 0, len(msg.Columns)),
+		Schema:       msg.Schema,
+	}
+	for _, col := range msg.PK {
+		dml.PrimaryKey = append(dml.PrimaryKey, col.Name)
+	}
+	return dml
+}
+
+// newOldKeys creates the old key values of an update or delete
+func newOldKeys(n int) *struct {
+	KeyNames  []string                   `json:"keynames"`
+	KeyValues []types.ColumnValueWrapper `json:"keyvalues"`
+} {
+	return &struct {
+		KeyNames  []string                   `json:"keynames"`
+		KeyValues []types.ColumnValueWrapper `json:"keyvalues"`
+	}{
+		KeyNames:  make([]string, 0, n),
+		KeyValues: make([]types.ColumnValueWrapper, 0, n),
+	}
+}
+
+// decodeWal2JSONValue decodes a column value by its type OID, like a pgoutput text value
+func decodeWal2JSONValue(col wal2jsonColumn) (any, error) {
+	var text string
+	switch v := col.Value.(type) {
+	case nil:
+		return nil, nil
+	case json.Number:
+		text = v.String()
+	case bool:
+		text = strconv.FormatBool(v)
+	case string:
+		text = v
+	default:
+		return nil, fmt.Errorf("unexpected value for column %s: %v", col.Name, v)
+	}
+	value, err := decodeColumnData(&pglogrepl.TupleDataColumn{Data: []byte(text)}, col.TypeOID)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding column %s: %w", col.Name, err)
+	}
+	return value, nil
+}
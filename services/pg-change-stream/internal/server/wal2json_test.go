@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"kasho/pkg/types"
+
+	"github.com/jackc/pglogrepl"
+)
+
+func decodeWal2JSON(t *testing.T, d *Wal2JSONDecoder, messages ...string) []types.Change {
+	t.Helper()
+	var changes []types.Change
+	for i, msg := range messages {
+		decoded, err := d.ParseWALData(context.Background(), []byte(msg), pglogrepl.LSN(100+i))
+		if err != nil {
+			t.Fatalf("ParseWALData(%s) error = %v", msg, err)
+		}
+		changes = append(changes, decoded...)
+	}
+	return changes
+}
+
+func TestWal2JSON_Insert(t *testing.T) {
+	changes := decodeWal2JSON(t, NewWal2JSONDecoder(false),
+		`{"action":"B","xid":731,"timestamp":"2024-03-20 15:00:00.5+00"}`,
+		`{"action":"I","xid":731,"schema":"public","table":"users","columns":[`+
+			`{"name":"id","type":"integer","typeoid":23,"value":1},`+
+			`{"name":"email","type":"text","typeoid":25,"value":"a@example.com"},`+
+			`{"name":"active","type":"boolean","typeoid":16,"value":true},`+
+			`{"name":"score","type":"double precision","typeoid":701,"value":1.5},`+
+			`{"name":"bio","type":"text","typeoid":25,"value":null}],`+
+			`"pk":[{"name":"id","type":"integer","typeoid":23}]}`,
+		`{"action":"C","xid":731,"timestamp":"2024-03-20 15:00:00.5+00"}`,
+	)
+	if len(changes) != 1 {
+		t.Fatalf("got %d changes, want 1", len(changes))
+	}
+
+	change := changes[0]
+	if change.TransactionID != "731" || change.Dialect != "postgresql" || change.Schema != "public" {
+		t.Errorf("change = %+v, want transaction 731 from postgresql schema public", change)
+	}
+	if want := time.Date(2024, 3, 20, 15, 0, 0, 500000000, time.UTC); !change.CommitTime.Equal(want) {
+		t.Errorf("CommitTime = %v, want %v", change.CommitTime, want)
+	}
+
+	dml := change.Data.(types.DMLData)
+	if dml.Table != "public.users" || dml.Kind != "insert" {
+		t.Errorf("DML = %s %s, want insert into public.users", dml.Kind, dml.Table)
+	}
+	if want := []string{"id", "email", "active", "score", "bio"}; !reflect.DeepEqual(dml.ColumnNames, want) {
+		t.Errorf("ColumnNames = %v, want %v", dml.ColumnNames, want)
+	}
+	if want := []string{"id"}; !reflect.DeepEqual(dml.PrimaryKey, want) {
+		t.Errorf("PrimaryKey = %v, want %v", dml.PrimaryKey, want)
+	}
+	if got := dml.ColumnValues[0].GetIntValue(); got != 1 {
+		t.Errorf("id = %d, want 1", got)
+	}
+	if got := dml.ColumnValues[1].GetStringValue(); got != "a@example.com" {
+		t.Errorf("email = %q, want a@example.com", got)
+	}
+	if got := dml.ColumnValues[2].GetBoolValue(); !got {
+		t.Error("active = false, want true")
+	}
+	if got := dml.ColumnValues[3].GetFloatValue(); got != 1.5 {
+		t.Errorf("score = %v, want 1.5", got)
+	}
+}
+
+func TestWal2JSON_DDL(t *testing.T) {
+	changes := decodeWal2JSON(t, NewWal2JSONDecoder(false),
+		`{"action":"I","schema":"public","table":"kasho_ddl_log","columns":[`+
+			`{"name":"id","type":"integer","typeoid":23,"value":42},`+
+			`{"name":"username","type":"text","typeoid":25,"value":"admin"},`+
+			`{"name":"database","type":"text","typeoid":25,"value":"shop"},`+
+			`{"name":"ddl","type":"text","typeoid":25,"value":"CREATE TABLE t (id int)"}]}`,
+	)
+	if len(changes) != 1 {
+		t.Fatalf("got %d changes, want 1", len(changes))
+	}
+	ddl, ok := changes[0].Data.(types.DDLData)
+	if !ok {
+		t.Fatalf("Data = %T, want types.DDLData", changes[0].Data)
+	}
+	if ddl.ID != 42 || ddl.Username != "admin" || ddl.Database != "shop" || ddl.DDL != "CREATE TABLE t (id int)" {
+		t.Errorf("DDL = %+v", ddl)
+	}
+	if changes[0].Database != "shop" {
+		t.Errorf("Database = %q, want shop", changes[0].Database)
+	}
+}
+
+func TestWal2JSON_Update(t *testing.T) {
+	tests := []struct {
+		name        string
+		msg         string
+		wantColumns []string
+		wantOldKey  int64
+	}{
+		{
+			// Unchanged TOASTed columns are left out of the new columns
+			name: "default identity",
+			msg: `{"action":"U","schema":"public","table":"users","columns":[` +
+				`{"name":"id","type":"integer","typeoid":23,"value":1},` +
+				`{"name":"email","type":"text","typeoid":25,"value":"b@example.com"}],` +
+				`"identity":[{"name":"id","type":"integer","typeoid":23,"value":1}],` +
+				`"pk":[{"name":"id","type":"integer","typeoid":23}]}`,
+			wantColumns: []string{"email"},
+			wantOldKey:  1,
+		},
+		{
+			name: "key changed",
+			msg: `{"action":"U","schema":"public","table":"users","columns":[` +
+				`{"name":"id","type":"integer","typeoid":23,"value":2},` +
+				`{"name":"email","type":"text","typeoid":25,"value":"b@example.com"}],` +
+				`"identity":[{"name":"id","type":"integer","typeoid":23,"value":1}],` +
+				`"pk":[{"name":"id","type":"integer","typeoid":23}]}`,
+			wantColumns: []string{"id", "email"},
+			wantOldKey:  1,
+		},
+		{
+			name: "full identity",
+			msg: `{"action":"U","schema":"public","table":"users","columns":[` +
+				`{"name":"id","type":"integer","typeoid":23,"value":1},` +
+				`{"name":"email","type":"text","typeoid":25,"value":"a@example.com"},` +
+				`{"name":"name","type":"text","typeoid":25,"value":"New"}],` +
+				`"identity":[{"name":"id","type":"integer","typeoid":23,"value":1},` +
+				`{"name":"email","type":"text","typeoid":25,"value":"a@example.com"},` +
+				`{"name":"name","type":"text","typeoid":25,"value":"Old"}],` +
+				`"pk":[{"name":"id","type":"integer","typeoid":23}]}`,
+			wantColumns: []string{"name"},
+			wantOldKey:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := decodeWal2JSON(t, NewWal2JSONDecoder(false), tt.msg)
+			if len(changes) != 1 {
+				t.Fatalf("got %d changes, want 1", len(changes))
+			}
+			dml := changes[0].Data.(types.DMLData)
+			if dml.Kind != "update" {
+				t.Errorf("Kind = %q, want update", dml.Kind)
+			}
+			if !reflect.DeepEqual(dml.ColumnNames, tt.wantColumns) {
+				t.Errorf("ColumnNames = %v, want %v", dml.ColumnNames, tt.wantColumns)
+			}
+			if dml.OldKeys == nil || !reflect.DeepEqual(dml.OldKeys.KeyNames, []string{"id"}) {
+				t.Fatalf("OldKeys = %+v, want id", dml.OldKeys)
+			}
+			if got := dml.OldKeys.KeyValues[0].GetIntValue(); got != tt.wantOldKey {
+				t.Errorf("old id = %d, want %d", got, tt.wantOldKey)
+			}
+		})
+	}
+}
+
+func TestWal2JSON_Delete(t *testing.T) {
+	changes := decodeWal2JSON(t, NewWal2JSONDecoder(false),
+		`{"action":"D","schema":"app","table":"orders",`+
+			`"identity":[{"name":"id","type":"bigint","typeoid":20,"value":9000000000}],`+
+			`"pk":[{"name":"id","type":"bigint","typeoid":20}]}`,
+	)
+	if len(changes) != 1 {
+		t.Fatalf("got %d changes, want 1", len(changes))
+	}
+	dml := changes[0].Data.(types.DMLData)
+	if dml.Table != "app.orders" || dml.Kind != "delete" || changes[0].Schema != "app" {
+		t.Errorf("DML = %s %s (schema %s), want delete from app.orders", dml.Kind, dml.Table, changes[0].Schema)
+	}
+	if len(dml.ColumnNames) != 0 {
+		t.Errorf("ColumnNames = %v, want none", dml.ColumnNames)
+	}
+	if got := dml.OldKeys.KeyValues[0].GetIntValue(); got != 9000000000 {
+		t.Errorf("old id = %d, want 9000000000", got)
+	}
+}
+
+func TestWal2JSON_Messages(t *testing.T) {
+	msg := `{"action":"M","transactional":false,"prefix":"kasho.marker","content":"checkpoint"}`
+
+	if changes := decodeWal2JSON(t, NewWal2JSONDecoder(false), msg); len(changes) != 0 {
+		t.Errorf("got %d changes with logical messages off, want 0", len(changes))
+	}
+
+	changes := decodeWal2JSON(t, NewWal2JSONDecoder(true), msg)
+	if len(changes) != 1 {
+		t.Fatalf("got %d changes, want 1", len(changes))
+	}
+	message := changes[0].Data.(types.MessageData)
+	if message.Prefix != "kasho.marker" || string(message.Content) != "checkpoint" || message.Transactional {
+		t.Errorf("message = %+v", message)
+	}
+}
+
+func TestWal2JSON_Invalid(t *testing.T) {
+	d := NewWal2JSONDecoder(false)
+	if _, err := d.ParseWALData(context.Background(), []byte("not json"), 100); err == nil {
+		t.Error("ParseWALData() with invalid JSON should fail")
+	}
+	bad := `{"action":"I","schema":"public","table":"t","columns":[{"name":"n","type":"integer","typeoid":23,"value":"x"}]}`
+	if _, err := d.ParseWALData(context.Background(), []byte(bad), 100); err == nil {
+		t.Error("ParseWALData() with an invalid integer should fail")
+	}
+}
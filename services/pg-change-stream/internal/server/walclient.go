@@ -14,11 +14,23 @@ import (
 )
 
 type Client struct {
-	conn    *pgx.Conn
-	slotLSN pglogrepl.LSN
-	ticker  *time.Ticker
-	done    chan struct{}
-	dbURL   string
+	conn      *pgx.Conn
+	slotLSN   pglogrepl.LSN
+	serverLSN pglogrepl.LSN
+	ticker    *time.Ticker
+	done      chan struct{}
+	dbURL     string
+	options   ReplicationOptions
+	relations *Relations
+	decoder   Decoder
+}
+
+// ReplicationOptions are the output plugin and its optional features a Client asks for
+type ReplicationOptions struct {
+	Decoder                 LogicalDecoder // the slot's output plugin; empty means pgoutput
+	StreamLargeTransactions bool           // send large transactions before they commit (PostgreSQL 14+)
+	TwoPhaseCommit          bool           // send prepared transactions at PREPARE TRANSACTION (PostgreSQL 15+)
+	LogicalMessages         bool           // send pg_logical_emit_message messages (PostgreSQL 14+)
 }
 
 const (
@@ -46,7 +58,8 @@ func (c *Client) Connect(ctx context.Context) error {
 	var active bool
 	var restartLSN string
 	var confirmedFlushLSN string
-	if err := conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pg_replication_slots WHERE slot_name = 'kasho_slot'), active, restart_lsn, confirmed_flush_lsn FROM pg_replication_slots WHERE slot_name = 'kasho_slot'").Scan(&slotExists, &active, &restartLSN, &confirmedFlushLSN); err != nil {
+	var plugin string
+	if err := conn.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pg_replication_slots WHERE slot_name = 'kasho_slot'), active, restart_lsn, confirmed_flush_lsn, plugin FROM pg_replication_slots WHERE slot_name = 'kasho_slot'").Scan(&slotExists, &active, &restartLSN, &confirmedFlushLSN, &plugin); err != nil {
 		conn.Close(ctx)
 		return fmt.Errorf("failed to check replication slot: %w", err)
 	}
@@ -57,6 +70,16 @@ func (c *Client) Connect(ctx context.Context) error {
 		return fmt.Errorf("replication slot 'kasho_slot' does not exist")
 	}
 
+	// The slot decodes with the plugin it was created with, whatever the decoder expects
+	decoder := c.options.Decoder
+	if decoder == "" {
+		decoder = DecoderPgoutput
+	}
+	if plugin != string(decoder) {
+		conn.Close(ctx)
+		return fmt.Errorf("replication slot 'kasho_slot' uses the %s plugin, but LOGICAL_DECODER is %s", plugin, decoder)
+	}
+
 	log.Printf("Connecting to WAL database...")
 	walConn, err := pgx.Connect(ctx, walURL)
 	if err != nil {
@@ -74,7 +97,7 @@ func (c *Client) Connect(ctx context.Context) error {
 	log.Printf("Starting replication from LSN: %s", startLSN)
 	if err := pglogrepl.StartReplication(ctx, walConn.PgConn(), "kasho_slot", startLSN, pglogrepl.StartReplicationOptions{
 		Mode:       pglogrepl.LogicalReplication,
-		PluginArgs: []string{"proto_version '2'", "publication_names 'kasho_pub'"},
+		PluginArgs: pluginArgs(c.options),
 	}); err != nil {
 		conn.Close(ctx)
 		walConn.Close(ctx)
@@ -93,6 +116,7 @@ func (c *Client) Connect(ctx context.Context) error {
 	}
 	c.conn = walConn
 	c.slotLSN = startLSN
+	c.decoder = newDecoder(c.options, c.relations)
 	c.ticker = time.NewTicker(10 * time.Second)
 	c.done = make(chan struct{})
 
@@ -124,8 +148,12 @@ func (c *Client) ConnectWithRetry(ctx context.Context) error {
 	}
 }
 
-func NewClient(ctx context.Context, dbURL string) (*Client, error) {
-	client := &Client{dbURL: dbURL}
+// NewClient connects to the database and starts replication with the given options.
+// Large transactions streamed before they commit and prepared transactions are
+// received marked as in progress. Relations are shared across clients, so a new client
+// can decode changes to relations described to an earlier one.
+func NewClient(ctx context.Context, dbURL string, options ReplicationOptions, relations *Relations) (*Client, error) {
+	client := &Client{dbURL: dbURL, options: options, relations: relations}
 	if err := client.ConnectWithRetry(ctx); err != nil {
 		return nil, err
 	}
@@ -165,12 +193,30 @@ func (c *Client) ReceiveMessage(ctx context.Context) ([]types.Change, error) {
 	if err != nil {
 		return nil, err
 	}
-	changes, lsn, err := ParseMessage(msg)
+	if serverLSN, ok := ParseKeepalive(msg); ok {
+		c.serverLSN = serverLSN
+		return nil, nil
+	}
+	changes, lsn, err := ParseMessage(ctx, c.decoder, msg)
 	if err != nil {
 		return nil, err
 	}
 	if lsn != 0 {
 		c.slotLSN = lsn
 	}
+	for i := range changes {
+		if changes[i].Database == "" {
+			changes[i].Database = c.conn.Config().Database
+		}
+	}
 	return changes, nil
 }
+
+// CurrentPosition returns the latest WAL position seen from the server,
+// including positions reported by keepalives while the database is idle
+func (c *Client) CurrentPosition() string {
+	if c.serverLSN > c.slotLSN {
+		return c.serverLSN.String()
+	}
+	return c.slotLSN.String()
+}
@@ -1,9 +1,12 @@
 package server
 
 import (
+	"context"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
 	"time"
 
 	"kasho/pkg/types"
@@ -13,9 +16,42 @@ import (
 	"github.com/jackc/pgx/v5/pgproto3"
 )
 
-var relationMap = make(map[uint32]*pglogrepl.RelationMessageV2)
+// Parser decodes the pgoutput messages of one replication connection
+type Parser struct {
+	relations *Relations
 
-func ParseMessage(msg pgproto3.BackendMessage) ([]types.Change, pglogrepl.LSN, error) {
+	transaction sourceTransaction
+
+	// stream is the large transaction being streamed before it commits, between a
+	// STREAM START and STREAM STOP message
+	stream struct {
+		active bool
+		xid    uint32
+	}
+
+	// prepare is the transaction being decoded at PREPARE TRANSACTION, between a BEGIN
+	// PREPARE and PREPARE message
+	prepare struct {
+		active bool
+		xid    uint32
+	}
+}
+
+// sourceTransaction is the source transaction of the changes being decoded, as announced
+// by the last BEGIN message
+type sourceTransaction struct {
+	xid        uint32
+	commitTime time.Time
+}
+
+// NewParser creates a parser for a new replication connection. Relations outlive the
+// connection, so they are passed in.
+func NewParser(relations *Relations) *Parser {
+	return &Parser{relations: relations}
+}
+
+// ParseMessage decodes the WAL data of a replication message with the connection's decoder
+func ParseMessage(ctx context.Context, decoder Decoder, msg pgproto3.BackendMessage) ([]types.Change, pglogrepl.LSN, error) {
 	copyData, ok := msg.(*pgproto3.CopyData)
 	if !ok {
 		return nil, 0, nil
@@ -30,7 +66,7 @@ func ParseMessage(msg pgproto3.BackendMessage) ([]types.Change, pglogrepl.LSN, e
 		return nil, 0, fmt.Errorf("error parsing WAL data: %w", err)
 	}
 
-	changes, err := ParseWALData(walData.WALData, walData.WALStart)
+	changes, err := decoder.ParseWALData(ctx, walData.WALData, walData.WALStart)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -38,6 +74,65 @@ func ParseMessage(msg pgproto3.BackendMessage) ([]types.Change, pglogrepl.LSN, e
 	return changes, walData.WALStart, nil
 }
 
+// ParseKeepalive returns the server's WAL end position from a primary keepalive message
+func ParseKeepalive(msg pgproto3.BackendMessage) (pglogrepl.LSN, bool) {
+	copyData, ok := msg.(*pgproto3.CopyData)
+	if !ok || len(copyData.Data) == 0 || copyData.Data[0] != pglogrepl.PrimaryKeepaliveMessageByteID {
+		return 0, false
+	}
+
+	pkm, err := pglogrepl.ParsePrimaryKeepaliveMessage(copyData.Data[1:])
+	if err != nil {
+		return 0, false
+	}
+	return pkm.ServerWALEnd, true
+}
+
+// ddlLogTable is the table the primary's DDL trigger logs statements to
+const ddlLogTable = "public.kasho_ddl_log"
+
+// setDDLColumn sets the field of a DDL change from a column of its kasho_ddl_log row
+func setDDLColumn(ddl *types.DDLData, name string, value any) {
+	switch name {
+	case "id":
+		if id, ok := value.(int32); ok {
+			ddl.ID = int(id)
+		}
+	case "time":
+		if t, ok := value.(time.Time); ok {
+			ddl.Time = t
+		}
+	case "username":
+		if username, ok := value.(string); ok {
+			ddl.Username = username
+		}
+	case "database":
+		if db, ok := value.(string); ok {
+			ddl.Database = db
+		}
+	case "ddl":
+		if ddlStr, ok := value.(string); ok {
+			ddl.DDL = ddlStr
+		}
+	}
+}
+
+// keyColumnNames returns the names of the relation's replica identity key columns,
+// which is the primary key under the default REPLICA IDENTITY setting
+func keyColumnNames(rel *pglogrepl.RelationMessageV2) []string {
+	var names []string
+	for _, col := range rel.Columns {
+		if col.Flags == 1 {
+			names = append(names, col.Name)
+		}
+	}
+	return names
+}
+
+// jsonText and uuidText keep json/jsonb and uuid columns apart from plain text
+type jsonText string
+type uuidText string
+
 func decodeColumnData(col *pglogrepl.TupleDataColumn, colType uint32) (any, error) {
 	if col == nil {
 		return nil, nil
@@ -95,18 +190,32 @@ func decodeColumnData(col *pglogrepl.TupleDataColumn, colType uint32) (any, erro
 			return nil, fmt.Errorf("invalid timestamp value: %s", strValue)
 		}
 		return t, nil
-	case 1184: // timestamptz
-		t, err := time.Parse("2006-01-02 15:04:05.999999-07", strValue)
-		if err != nil {
-			return nil, fmt.Errorf("invalid timestamptz value: %s", strValue)
+	case 1184: // timestamptz, with an offset like +02 or +05:30 depending on the zone
+		for _, layout := range []string{"2006-01-02 15:04:05.999999-07", "2006-01-02 15:04:05.999999-07:00"} {
+			if t, err := time.Parse(layout, strValue); err == nil {
+				return t, nil
+			}
 		}
-		return t, nil
+		return nil, fmt.Errorf("invalid timestamptz value: %s", strValue)
 	case 1082: // date
 		t, err := time.Parse("2006-01-02", strValue)
 		if err != nil {
 			return nil, fmt.Errorf("invalid date value: %s", strValue)
 		}
 		return t, nil
+	case 17: // bytea, sent in the default hex output format
+		if !strings.HasPrefix(strValue, `\x`) {
+			return nil, fmt.Errorf("invalid bytea value (bytea_output must be hex): %s", strValue)
+		}
+		val, err := hex.DecodeString(strValue[2:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid bytea value: %s", strValue)
+		}
+		return val, nil
+	case 114, 3802: // json, jsonb
+		return jsonText(strValue), nil
+	case 2950: // uuid
+		return uuidText(strValue), nil
 	case 25, 1043: // text, varchar
 		return strValue, nil
 	default:
@@ -118,6 +227,10 @@ func toColumnValue(value any) types.ColumnValueWrapper {
 	switch v := value.(type) {
 	case string:
 		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: v}}}
+	case jsonText:
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_JsonValue{JsonValue: string(v)}}}
+	case uuidText:
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_UuidValue{UuidValue: string(v)}}}
 	case int32:
 		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: int64(v)}}}
 	case int64:
@@ -128,16 +241,27 @@ func toColumnValue(value any) types.ColumnValueWrapper {
 		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_FloatValue{FloatValue: v}}}
 	case bool:
 		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_BoolValue{BoolValue: v}}}
+	case []byte:
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: v}}}
 	case time.Time:
-		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: v.Format(time.RFC3339)}}}
+		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: v.UTC().Format(time.RFC3339Nano)}}}
 	default:
 		// For any other type, convert to string
 		return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: fmt.Sprint(v)}}}
 	}
 }
 
-func ParseWALData(walData []byte, lsn pglogrepl.LSN) ([]types.Change, error) {
-	msg, err := pglogrepl.ParseV2(walData, false)
+// unchangedToastValue marks a column whose TOASTed value was left out of the WAL
+func unchangedToastValue() types.ColumnValueWrapper {
+	return types.ColumnValueWrapper{ColumnValue: &proto.ColumnValue{Value: &proto.ColumnValue_UnchangedToast{UnchangedToast: true}}}
+}
+
+func (p *Parser) ParseWALData(ctx context.Context, walData []byte, lsn pglogrepl.LSN) ([]types.Change, error) {
+	if isPreparedMessage(walData) {
+		return p.handlePreparedMessage(walData, lsn)
+	}
+
+	msg, err := pglogrepl.ParseV2(walData, p.stream.active)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing WAL message: %w", err)
 	}
@@ -146,16 +270,16 @@ func ParseWALData(walData []byte, lsn pglogrepl.LSN) ([]types.Change, error) {
 
 	switch v := msg.(type) {
 	case *pglogrepl.RelationMessageV2:
-		relationMap[v.RelationID] = v
+		p.relations.Add(ctx, v)
 
 	case *pglogrepl.InsertMessageV2:
-		rel, ok := relationMap[v.RelationID]
-		if !ok {
-			return nil, fmt.Errorf("unknown relation ID %d", v.RelationID)
+		rel, err := p.relations.Get(ctx, v.RelationID)
+		if err != nil {
+			return nil, err
 		}
 
 		tableName := fmt.Sprintf("%s.%s", rel.Namespace, rel.RelationName)
-		if tableName == "public.kasho_ddl_log" {
+		if tableName == ddlLogTable {
 			ddl := types.DDLData{}
 			for i, col := range rel.Columns {
 				if i < len(v.Tuple.Columns) {
@@ -167,28 +291,7 @@ func ParseWALData(walData []byte, lsn pglogrepl.LSN) ([]types.Change, error) {
 					if err != nil {
 						return nil, fmt.Errorf("error decoding column %s: %w", col.Name, err)
 					}
-					switch col.Name {
-					case "id":
-						if id, ok := value.(int32); ok {
-							ddl.ID = int(id)
-						}
-					case "time":
-						if t, ok := value.(time.Time); ok {
-							ddl.Time = t
-						}
-					case "username":
-						if username, ok := value.(string); ok {
-							ddl.Username = username
-						}
-					case "database":
-						if db, ok := value.(string); ok {
-							ddl.Database = db
-						}
-					case "ddl":
-						if ddlStr, ok := value.(string); ok {
-							ddl.DDL = ddlStr
-						}
-					}
+					setDDLColumn(&ddl, col.Name, value)
 				}
 			}
 			changes = append(changes, types.Change{Position: lsn.String(), Data: ddl})
@@ -198,6 +301,8 @@ func ParseWALData(walData []byte, lsn pglogrepl.LSN) ([]types.Change, error) {
 				Kind:         "insert",
 				ColumnNames:  make([]string, 0, len(rel.Columns)),
 				ColumnValues: make([]types.ColumnValueWrapper, 0, len(v.Tuple.Columns)),
+				PrimaryKey:   keyColumnNames(rel),
+				Schema:       rel.Namespace,
 			}
 
 			for i, col := range rel.Columns {
@@ -219,9 +324,9 @@ func ParseWALData(walData []byte, lsn pglogrepl.LSN) ([]types.Change, error) {
 		}
 
 	case *pglogrepl.UpdateMessageV2:
-		rel, ok := relationMap[v.RelationID]
-		if !ok {
-			return nil, fmt.Errorf("unknown relation ID %d", v.RelationID)
+		rel, err := p.relations.Get(ctx, v.RelationID)
+		if err != nil {
+			return nil, err
 		}
 
 		// Find primary key columns
@@ -237,6 +342,8 @@ func ParseWALData(walData []byte, lsn pglogrepl.LSN) ([]types.Change, error) {
 			Kind:         "update",
 			ColumnNames:  make([]string, 0),
 			ColumnValues: make([]types.ColumnValueWrapper, 0),
+			PrimaryKey:   keyColumnNames(rel),
+			Schema:       rel.Namespace,
 		}
 
 		// Initialize OldKeys with primary key columns
@@ -285,6 +392,17 @@ func ParseWALData(walData []byte, lsn pglogrepl.LSN) ([]types.Change, error) {
 				if colData == nil {
 					continue
 				}
+
+				// Unchanged TOASTed values aren't sent, so mark them rather than
+				// decoding the empty data as NULL
+				if colData.DataType == pglogrepl.TupleDataTypeToast {
+					if col.Flags != 1 {
+						dml.ColumnNames = append(dml.ColumnNames, col.Name)
+						dml.ColumnValues = append(dml.ColumnValues, unchangedToastValue())
+					}
+					continue
+				}
+
 				newValue, err := decodeColumnData(colData, col.DataType)
 				if err != nil {
 					return nil, fmt.Errorf("error decoding column %s: %w", col.Name, err)
@@ -316,9 +434,9 @@ func ParseWALData(walData []byte, lsn pglogrepl.LSN) ([]types.Change, error) {
 		changes = append(changes, types.Change{Position: lsn.String(), Data: dml})
 
 	case *pglogrepl.DeleteMessageV2:
-		rel, ok := relationMap[v.RelationID]
-		if !ok {
-			return nil, fmt.Errorf("unknown relation ID %d", v.RelationID)
+		rel, err := p.relations.Get(ctx, v.RelationID)
+		if err != nil {
+			return nil, err
 		}
 
 		dml := types.DMLData{
@@ -326,6 +444,8 @@ func ParseWALData(walData []byte, lsn pglogrepl.LSN) ([]types.Change, error) {
 			Kind:         "delete",
 			ColumnNames:  make([]string, 0, len(rel.Columns)),
 			ColumnValues: make([]types.ColumnValueWrapper, 0, len(v.OldTuple.Columns)),
+			PrimaryKey:   keyColumnNames(rel),
+			Schema:       rel.Namespace,
 		}
 
 		// Add old key values
@@ -354,15 +474,105 @@ func ParseWALData(walData []byte, lsn pglogrepl.LSN) ([]types.Change, error) {
 
 		changes = append(changes, types.Change{Position: lsn.String(), Data: dml})
 
+	case *pglogrepl.LogicalDecodingMessageV2:
+		changes = append(changes, types.Change{
+			Position: lsn.String(),
+			Data: types.MessageData{
+				Prefix:        v.Prefix,
+				Content:       v.Content,
+				Transactional: v.Transactional,
+			},
+		})
+
 	case *pglogrepl.BeginMessage:
-		// No changes to create for begin messages
+		p.transaction.xid = v.Xid
+		p.transaction.commitTime = v.CommitTime
 
 	case *pglogrepl.CommitMessage:
-		// No changes to create for commit messages
+		p.transaction = sourceTransaction{}
+
+	case *pglogrepl.StreamStartMessageV2:
+		p.stream.active = true
+		p.stream.xid = v.Xid
+
+	case *pglogrepl.StreamStopMessageV2:
+		p.stream.active = false
+		p.stream.xid = 0
+
+	case *pglogrepl.StreamCommitMessageV2:
+		changes = append(changes, types.Change{
+			Position:          lsn.String(),
+			Data:              types.TransactionData{},
+			TransactionID:     strconv.FormatUint(uint64(v.Xid), 10),
+			TransactionStatus: "committed",
+			CommitTime:        v.CommitTime,
+		})
+
+	case *pglogrepl.StreamAbortMessageV2:
+		change := types.Change{
+			Position:          lsn.String(),
+			Data:              types.TransactionData{},
+			TransactionID:     strconv.FormatUint(uint64(v.Xid), 10),
+			TransactionStatus: "aborted",
+		}
+		// Only a subtransaction rolled back; the rest of the transaction goes on
+		if v.SubXid != v.Xid {
+			change.SubtransactionID = strconv.FormatUint(uint64(v.SubXid), 10)
+		}
+		changes = append(changes, change)
 
 	default:
 		log.Printf("Unhandled message type: %T", msg)
 	}
 
+	for i := range changes {
+		p.transaction.setSourceMetadata(&changes[i])
+		if p.stream.active {
+			p.setStreamMetadata(&changes[i], messageXid(msg))
+		} else if p.prepare.active {
+			changes[i].TransactionID = strconv.FormatUint(uint64(p.prepare.xid), 10)
+			changes[i].TransactionStatus = "in_progress"
+		}
+	}
 	return changes, nil
 }
+
+// messageXid returns the transaction of a change streamed before it commits. Changes
+// made in a subtransaction carry the subtransaction's xid.
+func messageXid(msg pglogrepl.Message) uint32 {
+	switch v := msg.(type) {
+	case *pglogrepl.InsertMessageV2:
+		return v.Xid
+	case *pglogrepl.UpdateMessageV2:
+		return v.Xid
+	case *pglogrepl.DeleteMessageV2:
+		return v.Xid
+	case *pglogrepl.LogicalDecodingMessageV2:
+		return v.Xid
+	}
+	return 0
+}
+
+// setStreamMetadata marks a change of the streamed transaction as in progress
+func (p *Parser) setStreamMetadata(change *types.Change, xid uint32) {
+	change.TransactionID = strconv.FormatUint(uint64(p.stream.xid), 10)
+	change.TransactionStatus = "in_progress"
+	if xid != 0 && xid != p.stream.xid {
+		change.SubtransactionID = strconv.FormatUint(uint64(xid), 10)
+	}
+}
+
+// setSourceMetadata records the source dialect, schema and transaction of a decoded change
+func (t sourceTransaction) setSourceMetadata(change *types.Change) {
+	change.Dialect = "postgresql"
+	switch data := change.Data.(type) {
+	case types.DMLData:
+		change.Schema = data.Schema
+	case types.DDLData:
+		change.Database = data.Database
+	}
+	if t.xid != 0 {
+		change.TransactionID = strconv.FormatUint(uint64(t.xid), 10)
+		change.CommitTime = t.commitTime
+	}
+}
@@ -3,23 +3,49 @@ package main
 import (
 	"context"
 	dbsql "database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"kasho/pkg/dialect"
+	"kasho/pkg/notify"
+	"kasho/pkg/transform"
 	"kasho/pkg/version"
 	"kasho/proto"
+	"translicator/internal/apply"
+	"translicator/internal/approval"
+	"translicator/internal/audit"
+	"translicator/internal/ddl"
+	"translicator/internal/dlq"
+	"translicator/internal/metrics"
+	"translicator/internal/objectstore"
+	"translicator/internal/routing"
+	"translicator/internal/secrets"
+	"translicator/internal/sequences"
 	"translicator/internal/sql"
-	"translicator/internal/transform"
+	"translicator/internal/stream"
+	"translicator/internal/validate"
+	"translicator/internal/warehouse"
+	"translicator/internal/webhook"
 
+	_ "github.com/ClickHouse/clickhouse-go/v2"
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
+	_ "github.com/sijms/go-ora/v2"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	_ "modernc.org/sqlite"
 )
 
 const (
@@ -56,23 +82,25 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Use hardcoded config directory path - expects mounted /app/config directory
-	configFile := "/app/config/transforms.yml"
+	// Expects a mounted /app/config directory; CONFIG_DIR points elsewhere when running
+	// outside a container, as kasho dev up does
+	configDir := getEnvOrDefault("CONFIG_DIR", "/app/config")
+	configFile := filepath.Join(configDir, "transforms.yml")
 
 	// Verify config directory exists and is actually a directory
-	configDir := "/app/config"
 	if stat, err := os.Stat(configDir); os.IsNotExist(err) {
-		log.Fatal("Config directory /app/config does not exist. Please mount a config directory to /app/config")
+		log.Fatalf("Config directory %s does not exist. Please mount a config directory to %s", configDir, configDir)
 	} else if err != nil {
 		log.Fatalf("Error checking config directory: %v", err)
 	} else if !stat.IsDir() {
-		log.Fatal("/app/config exists but is not a directory. Please mount a config directory to /app/config")
+		log.Fatalf("%s exists but is not a directory. Please mount a config directory to %s", configDir, configDir)
 	}
 
 	if _, err := os.Stat(configFile); os.IsNotExist(err) {
-		log.Fatal("Required config file /app/config/transforms.yml not found. Please ensure transforms.yml exists in the mounted config directory")
+		log.Fatalf("Required config file %s not found. Please ensure transforms.yml exists in the mounted config directory", configFile)
 	}
 
+	secrets.Register()
 	config, err := transform.LoadConfig(configFile)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
@@ -83,6 +111,24 @@ func main() {
 		log.Fatal("REPLICA_DATABASE_URL environment variable is required")
 	}
 
+	// An s3:// or gs:// URL writes changes as files to object storage instead of a replica database
+	if objectstore.IsURL(dbConnStr) {
+		runObjectSink(ctx, cancel, config, dbConnStr)
+		return
+	}
+
+	// A snowflake:// or bigquery:// URL loads changes into a warehouse in batches
+	if warehouse.IsURL(dbConnStr) {
+		runWarehouseSink(ctx, cancel, config, dbConnStr)
+		return
+	}
+
+	// An http:// or https:// URL posts changes as JSON batches to a webhook
+	if webhook.IsURL(dbConnStr) {
+		runWebhookSink(ctx, cancel, config, dbConnStr)
+		return
+	}
+
 	// Determine the dialect from the connection string
 	dbDialect, err := dialect.FromConnectionString(dbConnStr)
 	if err != nil {
@@ -90,11 +136,70 @@ func main() {
 	}
 	log.Printf("Using %s dialect", dbDialect.Name())
 
+	// MySQL replicas can store UUIDs compactly in BINARY(16) columns
+	if value := os.Getenv("UUID_AS_BINARY"); value != "" {
+		uuidAsBinary, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Fatalf("Invalid UUID_AS_BINARY: %v", err)
+		}
+		if mysqlDialect, ok := dbDialect.(*dialect.MySQL); ok {
+			mysqlDialect.SetUUIDAsBinary(uuidAsBinary)
+			log.Printf("UUIDs as BINARY(16): %v", uuidAsBinary)
+		} else {
+			log.Printf("UUID_AS_BINARY only applies to MySQL replicas, ignoring")
+		}
+	}
+
+	// MySQL replica sessions can relax sql_mode, e.g. for anonymized values that no longer
+	// fit strict columns, and check foreign keys; an empty MYSQL_SQL_MODE turns every mode off
+	if mysqlDialect, ok := dbDialect.(*dialect.MySQL); ok {
+		if mode, ok := os.LookupEnv("MYSQL_SQL_MODE"); ok {
+			mysqlDialect.SetSQLMode(mode)
+			log.Printf("Replica sql_mode: %q", mode)
+		}
+		if value := os.Getenv("MYSQL_FOREIGN_KEY_CHECKS"); value != "" {
+			checks, err := strconv.ParseBool(value)
+			if err != nil {
+				log.Fatalf("Invalid MYSQL_FOREIGN_KEY_CHECKS: %v", err)
+			}
+			mysqlDialect.SetForeignKeyChecks(checks)
+			log.Printf("Replica foreign key checks: %v", checks)
+		}
+	}
+
+	// Statements on the replica can be limited per change kind. The longest limit is also set
+	// on the replica sessions, so the server stops statements even if their client is gone.
+	statementTimeouts, err := apply.ParseStatementTimeouts(os.Getenv("APPLY_STATEMENT_TIMEOUT"), os.Getenv("APPLY_STATEMENT_TIMEOUTS"))
+	if err != nil {
+		log.Fatalf("Invalid APPLY_STATEMENT_TIMEOUTS: %v", err)
+	}
+	if longest := statementTimeouts.Longest(); longest > 0 {
+		switch d := dbDialect.(type) {
+		case *dialect.PostgreSQL:
+			d.SetStatementTimeout(longest)
+		case *dialect.MySQL:
+			d.SetStatementTimeout(longest)
+		}
+	}
+
 	// Create SQL generator with the detected dialect
 	sqlGenerator := sql.NewSQLGenerator(dbDialect)
 
-	// Convert connection string to driver-specific DSN format
-	dsn := dbDialect.FormatDSN(dbConnStr)
+	// Idempotent mode turns inserts into upserts so replays after a crash don't fail on key violations
+	if value := os.Getenv("IDEMPOTENT_APPLY"); value != "" {
+		idempotent, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Fatalf("Invalid IDEMPOTENT_APPLY: %v", err)
+		}
+		sqlGenerator.SetIdempotent(idempotent)
+		log.Printf("Idempotent apply: %v", idempotent)
+	}
+
+	// Convert connection string to driver-specific DSN format. The session time zone is
+	// part of the DSN so it applies to every pooled connection; change timestamps are UTC.
+	timeZone := getEnvOrDefault("REPLICA_TIME_ZONE", "UTC")
+	dsn := dbDialect.SessionTimeZoneDSN(dbDialect.FormatDSN(dbConnStr), timeZone)
+	log.Printf("Replica session time zone: %s", timeZone)
 
 	db, err := connectWithRetry(ctx, func() (*dbsql.DB, error) {
 		log.Printf("Connecting to replica database ...")
@@ -114,132 +219,900 @@ func main() {
 	defer db.Close()
 	log.Printf("Successfully connected to replica database")
 
+	// Replica connection pool limits; unset values keep the database/sql defaults
+	if value := os.Getenv("REPLICA_MAX_OPEN_CONNS"); value != "" {
+		maxOpen, err := strconv.Atoi(value)
+		if err != nil {
+			log.Fatalf("Invalid REPLICA_MAX_OPEN_CONNS: %v", err)
+		}
+		db.SetMaxOpenConns(maxOpen)
+	}
+	if value := os.Getenv("REPLICA_MAX_IDLE_CONNS"); value != "" {
+		maxIdle, err := strconv.Atoi(value)
+		if err != nil {
+			log.Fatalf("Invalid REPLICA_MAX_IDLE_CONNS: %v", err)
+		}
+		db.SetMaxIdleConns(maxIdle)
+	}
+	if value := os.Getenv("REPLICA_CONN_MAX_LIFETIME"); value != "" {
+		lifetime, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid REPLICA_CONN_MAX_LIFETIME: %v", err)
+		}
+		db.SetConnMaxLifetime(lifetime)
+	}
+
 	// Set up connection for replication (dialect-specific)
 	if err := dbDialect.SetupConnection(db); err != nil {
 		log.Fatalf("Failed to set up connection: %v", err)
 	}
 	log.Printf("Connection setup complete for %s dialect", dbDialect.Name())
 
-	// Start periodic sequence/auto-increment sync
-	syncTicker := time.NewTicker(15 * time.Second)
-	defer syncTicker.Stop()
+	// Generated columns are computed by the replica, which rejects explicit values for them
+	loadGeneratedColumns := func(ctx context.Context) {
+		generated, err := dbDialect.GeneratedColumns(ctx, db)
+		if err != nil {
+			log.Printf("Warning: failed to read generated columns: %v", err)
+			generated = make(map[string][]string)
+		}
+		for table, columns := range config.GeneratedColumns {
+			generated[table] = columns
+		}
+		sqlGenerator.SetGeneratedColumns(generated)
+	}
+	loadGeneratedColumns(ctx)
 
-	hasInserts := false
+	// Transformed values are checked against the replica's columns before they are applied
+	validator := validate.NewValidator(config.Validation)
+	loadColumnConstraints := func(ctx context.Context) {
+		if !config.Validation.Enabled {
+			return
+		}
+		columns, err := dbDialect.ColumnConstraints(ctx, db)
+		if err != nil {
+			log.Printf("Warning: failed to read column constraints: %v", err)
+			return
+		}
+		validator.SetColumns(columns)
+	}
+	loadColumnConstraints(ctx)
+	if validator.Enabled() {
+		log.Printf("Validating transformed values (truncate: %v)", config.Validation.Truncate)
+	}
 
-	go func() {
-		for {
-			select {
-			case <-syncTicker.C:
-				if hasInserts {
-					if err := dbDialect.SyncSequences(ctx, db); err != nil {
-						log.Printf("Error during sequence sync: %v", err)
-					}
-					hasInserts = false
+	// Values larger than MAX_VALUE_BYTES are truncated or rejected before they are
+	// transformed, so a few huge values can't multiply into unbounded memory use
+	maxValueBytes, err := strconv.Atoi(getEnvOrDefault("MAX_VALUE_BYTES", "0"))
+	if err != nil {
+		log.Fatalf("Invalid MAX_VALUE_BYTES: %v", err)
+	}
+	maxValuePolicy, err := validate.ParseSizePolicy(os.Getenv("MAX_VALUE_POLICY"))
+	if err != nil {
+		log.Fatalf("Invalid MAX_VALUE_POLICY: %v", err)
+	}
+	sizeLimit := validate.SizeLimit{MaxBytes: maxValueBytes, Policy: maxValuePolicy}
+	if sizeLimit.Enabled() {
+		log.Printf("Limiting values to %d bytes (policy: %s)", maxValueBytes, maxValuePolicy)
+	}
+
+	applier := apply.NewApplier(db, sqlGenerator)
+
+	// Conflict policy decides what to do with UPDATE/DELETE changes whose row was changed on the replica
+	conflictPolicy, err := apply.ParseConflictPolicy(os.Getenv("CONFLICT_POLICY"))
+	if err != nil {
+		log.Fatalf("Invalid CONFLICT_POLICY: %v", err)
+	}
+	var deadLetters *dlq.Writer
+	if dlqPath := os.Getenv("DLQ_PATH"); dlqPath != "" {
+		deadLetters, err = dlq.Open(dlqPath)
+		if err != nil {
+			log.Fatalf("Failed to open dead-letter file: %v", err)
+		}
+		defer deadLetters.Close()
+		log.Printf("Writing dead letters to %s", dlqPath)
+	}
+	// The audit log records which columns of each change were transformed, with salted hashes of the original values
+	var auditor *audit.Auditor
+	var auditSink audit.Sink
+	if auditLog := os.Getenv("AUDIT_LOG"); auditLog != "" {
+		auditor, err = audit.NewAuditor(config, os.Getenv("AUDIT_SALT"))
+		if err != nil {
+			log.Fatalf("Invalid AUDIT_SALT: %v", err)
+		}
+		if auditLog == "replica" {
+			auditSink, err = audit.OpenTable(ctx, db, dbDialect)
+		} else {
+			auditSink, err = audit.OpenFile(auditLog)
+		}
+		if err != nil {
+			log.Fatalf("Failed to open audit log: %v", err)
+		}
+		defer auditSink.Close()
+		log.Printf("Writing audit log to %s", auditLog)
+	}
+	if err := applier.SetConflictPolicy(conflictPolicy, deadLetters); err != nil {
+		log.Fatalf("Invalid CONFLICT_POLICY: %v", err)
+	}
+	if conflictPolicy != apply.NoConflictCheck {
+		log.Printf("Conflict policy: %s", conflictPolicy)
+	}
+
+	// Statements failing with transient errors (deadlocks, serialization failures, lost connections) are retried
+	maxAttempts, err := strconv.Atoi(getEnvOrDefault("APPLY_MAX_ATTEMPTS", "5"))
+	if err != nil {
+		log.Fatalf("Invalid APPLY_MAX_ATTEMPTS: %v", err)
+	}
+	retryBackoff, err := time.ParseDuration(getEnvOrDefault("APPLY_RETRY_BACKOFF", "100ms"))
+	if err != nil {
+		log.Fatalf("Invalid APPLY_RETRY_BACKOFF: %v", err)
+	}
+	applier.SetRetry(maxAttempts, retryBackoff)
+	applier.SetStatementTimeouts(statementTimeouts)
+	if statementTimeouts.Enabled() {
+		log.Printf("Statement timeouts: %v by default, by kind %v", statementTimeouts.Default, statementTimeouts.Kinds)
+	}
+
+	// Repeated DML shapes (table, columns, kind) are prepared once and executed with bind parameters
+	stmtCacheSize, err := strconv.Atoi(getEnvOrDefault("PREPARED_STATEMENT_CACHE_SIZE", "0"))
+	if err != nil {
+		log.Fatalf("Invalid PREPARED_STATEMENT_CACHE_SIZE: %v", err)
+	}
+	// clickhouse-go prepares an INSERT as a client-side batch that is only sent on commit,
+	// so statements can't be reused; use BULK_LOAD_BATCH_SIZE to batch inserts instead
+	if dbDialect.Name() == "clickhouse" && stmtCacheSize > 0 {
+		log.Printf("Prepared statement cache is not supported for %s, executing statements directly", dbDialect.Name())
+		stmtCacheSize = 0
+	}
+	applier.SetStatementCache(stmtCacheSize)
+	if stmtCacheSize > 0 {
+		log.Printf("Prepared statement cache: %d statements", stmtCacheSize)
+	}
+
+	// Exactly-once mode records each applied position in a ledger table in the same
+	// transaction as the change, and skips changes that are delivered again
+	var ledger *apply.Ledger
+	if value := os.Getenv("EXACTLY_ONCE"); value != "" {
+		exactlyOnce, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Fatalf("Invalid EXACTLY_ONCE: %v", err)
+		}
+		if exactlyOnce {
+			ledger, err = apply.OpenLedger(ctx, db, dbDialect)
+			if err != nil {
+				log.Fatalf("Failed to open ledger: %v", err)
+			}
+			applier.SetLedger(ledger)
+			log.Printf("Exactly-once apply, recording positions in %s", apply.LedgerTable)
+		}
+	}
+	if ledger != nil {
+		ledgerSize, err := strconv.ParseInt(getEnvOrDefault("EXACTLY_ONCE_LEDGER_SIZE", "100000"), 10, 64)
+		if err != nil {
+			log.Fatalf("Invalid EXACTLY_ONCE_LEDGER_SIZE: %v", err)
+		}
+		go pruneLedger(ctx, ledger, ledgerSize)
+	}
+
+	// Periodically sync sequence/auto-increment values of tables that received inserts
+	sequenceSyncer := sequences.NewSyncer(func(ctx context.Context, tables []string) error {
+		return dbDialect.SyncSequences(ctx, db, tables)
+	})
+	go sequenceSyncer.Run(ctx, 15*time.Second)
+
+	client, streamCtx := connectChangeStream(ctx)
+	defer client.Close()
+	streamClient := proto.NewChangeStreamClient(client)
+
+	// Alert notifications (webhook, Slack, email) for replication lag and repeated apply failures
+	notifier, err := notify.New(notify.ConfigFromEnv())
+	if err != nil {
+		log.Fatalf("Invalid notification config: %v", err)
+	}
+	var monitor *notify.Monitor
+	if notifier != nil {
+		monitor = notify.NewMonitor("translicator", notifier)
+	}
+	lagThreshold, err := strconv.Atoi(getEnvOrDefault("ALERT_LAG_SECONDS", "0"))
+	if err != nil {
+		log.Fatalf("Invalid ALERT_LAG_SECONDS: %v", err)
+	}
+	errorThreshold, err := strconv.Atoi(getEnvOrDefault("ALERT_CONSECUTIVE_ERRORS", "0"))
+	if err != nil {
+		log.Fatalf("Invalid ALERT_CONSECUTIVE_ERRORS: %v", err)
+	}
+
+	// Expose metrics over HTTP if an address is configured
+	if metricsAddr := os.Getenv("METRICS_ADDR"); metricsAddr != "" {
+		go func() {
+			log.Printf("Serving metrics on %s", metricsAddr)
+			if err := metrics.Serve(metricsAddr); err != nil {
+				log.Printf("Metrics server stopped: %v", err)
+			}
+		}()
+	}
+
+	// Consecutive failed changes; only touched from the stream handler
+	consecutiveErrors := 0
+	recordApplyError := func(ctx context.Context, err error) {
+		if err == nil {
+			if consecutiveErrors > 0 {
+				consecutiveErrors = 0
+				monitor.Check(ctx, "apply_errors", 0, float64(errorThreshold), "changes are applying again")
+			}
+			return
+		}
+		consecutiveErrors++
+		monitor.Check(ctx, "apply_errors", float64(consecutiveErrors), float64(errorThreshold),
+			"%d consecutive changes failed to apply; last error: %v", consecutiveErrors, err)
+	}
+
+	// applyChange applies a transformed change on its own. Failed changes are logged and
+	// dead-lettered; only transient errors are returned, to have the change redelivered.
+	applyChange := func(ctx context.Context, change *proto.Change) error {
+		applyStart := time.Now()
+		stmt, err := applier.Apply(ctx, change)
+		if errors.Is(err, apply.ErrSkipped) {
+			log.Printf("Skipped change at %s: %v", change.Position, err)
+			return nil
+		}
+		dml := change.GetDml()
+		if err != nil {
+			log.Printf("Error applying change: %v", err)
+			recordApplyError(ctx, err)
+			if dml != nil {
+				metrics.Tables.RecordError(dml.Table)
+			}
+			// A transient error that outlasted the retries, e.g. the replica being down, stops
+			// the stream so the change is redelivered after reconnecting
+			var classified *apply.ClassifiedError
+			if errors.As(err, &classified) && classified.Class.Transient() {
+				return err
+			}
+			if deadLetters != nil {
+				if err := deadLetters.Write(change, err.Error(), stmt); err != nil {
+					log.Printf("Error writing dead letter: %v", err)
 				}
-			case <-ctx.Done():
-				return
 			}
+			return nil
 		}
-	}()
+		recordApplyError(ctx, nil)
 
-	serverAddr := os.Getenv("CHANGE_STREAM_SERVICE_ADDR")
-	if serverAddr == "" {
-		log.Fatal("CHANGE_STREAM_SERVICE_ADDR environment variable is required")
+		if dml != nil {
+			metrics.Tables.RecordApply(dml.Table, dml.Kind, change.Position, time.Since(applyStart))
+			if dml.Kind == "insert" {
+				sequenceSyncer.MarkInsert(dml.Table)
+			}
+		}
+		// Schema changes can add or drop generated columns and change column definitions
+		if change.GetDdl() != nil {
+			loadGeneratedColumns(ctx)
+			loadColumnConstraints(ctx)
+		}
+
+		log.Printf("%s (%s): %s", change.Position, change.Type, stmt)
+		return nil
 	}
-	client, err := connectWithRetry(ctx, func() (*grpc.ClientConn, error) {
-		log.Printf("Connecting to change stream service ...")
-		return grpc.NewClient(serverAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	})
+
+	// Runs of inserts into one table, e.g. a replayed bootstrap, are bulk loaded. They're
+	// loaded when a change that doesn't fit the batch arrives or the stream is caught up.
+	var batcher *apply.Batcher
+	batchSize, err := strconv.Atoi(getEnvOrDefault("BULK_LOAD_BATCH_SIZE", "0"))
 	if err != nil {
-		log.Fatalf("Failed to connect to change stream service after retries: %v", err)
+		log.Fatalf("Invalid BULK_LOAD_BATCH_SIZE: %v", err)
 	}
-	defer client.Close()
-	log.Printf("Successfully connected to change stream service")
+	if batchSize > 0 {
+		if sqlGenerator.Idempotent() {
+			log.Printf("Bulk loading is not used with IDEMPOTENT_APPLY, inserts are upserted one at a time")
+		} else if ledger != nil {
+			log.Printf("Bulk loading is not used with EXACTLY_ONCE, inserts are recorded in the ledger one at a time")
+		} else {
+			batcher, err = apply.NewBatcher(db, sqlGenerator, dbDialect.Name(), batchSize)
+			if err != nil {
+				log.Fatalf("Invalid BULK_LOAD_BATCH_SIZE: %v", err)
+			}
+			log.Printf("Bulk loading runs of up to %d inserts", batchSize)
+		}
+	}
+	flushInserts := func(ctx context.Context) error {
+		if batcher == nil || len(batcher.Pending()) == 0 {
+			return nil
+		}
+		pending := batcher.Pending()
+		table := batcher.Table()
+		loadStart := time.Now()
+		err := batcher.Load(ctx)
+		if err == nil {
+			recordApplyError(ctx, nil)
+			elapsed := time.Since(loadStart) / time.Duration(len(pending))
+			for _, change := range pending {
+				metrics.Tables.RecordApply(table, change.GetDml().Kind, change.Position, elapsed)
+			}
+			sequenceSyncer.MarkInsert(table)
+			log.Printf("%s..%s: bulk loaded %d inserts into %s", pending[0].Position, pending[len(pending)-1].Position, len(pending), table)
+			return nil
+		}
 
-	streamClient := proto.NewChangeStreamClient(client)
+		// Fall back to single inserts so only the offending rows fail
+		log.Printf("Bulk load of %d inserts into %s failed, applying them one at a time: %v", len(pending), table, err)
+		for len(batcher.Pending()) > 0 {
+			if err := applyChange(ctx, batcher.Pending()[0]); err != nil {
+				return err
+			}
+			batcher.Drop(1)
+		}
+		return nil
+	}
+
+	// With FOREIGN_KEY_MODE=defer, the changes of a source transaction are applied in one
+	// replica transaction with foreign key checks deferred to its commit
+	var txGroup *apply.TxGroup
+	switch mode := os.Getenv("FOREIGN_KEY_MODE"); mode {
+	case "":
+	case "defer":
+		if conflictPolicy != apply.NoConflictCheck {
+			log.Printf("Foreign key checks are not deferred with CONFLICT_POLICY, changes are checked one at a time")
+			break
+		}
+		txGroup, err = apply.NewTxGroup(applier, dbDialect.Name())
+		if err != nil {
+			log.Fatalf("Invalid FOREIGN_KEY_MODE: %v", err)
+		}
+		log.Printf("Applying source transactions with foreign key checks deferred")
+	default:
+		log.Fatalf("Invalid FOREIGN_KEY_MODE: %q (expected defer)", mode)
+	}
+	// Savepoints let a change that deadlocks be retried without rolling back the rest of its transaction
+	if value := os.Getenv("APPLY_SAVEPOINTS"); value != "" {
+		savepoints, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Fatalf("Invalid APPLY_SAVEPOINTS: %v", err)
+		}
+		if txGroup != nil {
+			txGroup.SetSavepoints(savepoints)
+			log.Printf("Savepoints per change in transactions: %v", savepoints)
+		} else {
+			log.Printf("APPLY_SAVEPOINTS only applies with FOREIGN_KEY_MODE=defer, ignoring")
+		}
+	}
+	flushTransaction := func(ctx context.Context) error {
+		if txGroup == nil || len(txGroup.Pending()) == 0 {
+			return nil
+		}
+		pending := txGroup.Pending()
+		applyStart := time.Now()
+		stmts, err := txGroup.Apply(ctx)
+		if err == nil {
+			recordApplyError(ctx, nil)
+			elapsed := time.Since(applyStart) / time.Duration(len(pending))
+			for _, change := range pending {
+				dml := change.GetDml()
+				metrics.Tables.RecordApply(dml.Table, dml.Kind, change.Position, elapsed)
+				if dml.Kind == "insert" {
+					sequenceSyncer.MarkInsert(dml.Table)
+				}
+			}
+			log.Printf("%s..%s: applied transaction %s: %d statements", pending[0].Position, pending[len(pending)-1].Position,
+				pending[0].TransactionId, len(stmts))
+			return nil
+		}
+
+		// Fall back to applying the changes one at a time so only the offending ones fail
+		log.Printf("Transaction %s of %d changes failed, applying them one at a time: %v", pending[0].TransactionId, len(pending), err)
+		for len(txGroup.Pending()) > 0 {
+			if err := applyChange(ctx, txGroup.Pending()[0]); err != nil {
+				return err
+			}
+			txGroup.Drop(1)
+		}
+		return nil
+	}
+
+	// applyTransformed applies a transformed change after the pending ones: as part of the
+	// pending source transaction, the pending run of inserts, or on its own
+	applyTransformed := func(ctx context.Context, change *proto.Change) error {
+		if txGroup != nil && change.TransactionId != "" {
+			// Pending bulk inserts come before the transaction
+			if err := flushInserts(ctx); err != nil {
+				return err
+			}
+			if txGroup.Add(change) {
+				return nil
+			}
+			if err := flushTransaction(ctx); err != nil {
+				return err
+			}
+			if txGroup.Add(change) {
+				return nil
+			}
+		}
+		// Anything else comes after the pending transaction
+		if err := flushTransaction(ctx); err != nil {
+			return err
+		}
 
-	// Handle shutdown signals
-	sigChan := make(chan os.Signal, 1)
+		if batcher != nil {
+			if batcher.Add(change) {
+				return nil
+			}
+			// Pending inserts are loaded before anything else is applied. This happens before
+			// the change is added, so a failed load has it redelivered rather than duplicated.
+			if err := flushInserts(ctx); err != nil {
+				return err
+			}
+			if batcher.Add(change) {
+				return nil
+			}
+		}
+		return applyChange(ctx, change)
+	}
+
+	// With APPROVAL_DELETE_THRESHOLD, the deletes of a source transaction are held until
+	// it is known whether there are more of them in a row than the threshold; such mass
+	// deletes wait for an operator's approval
+	var deleteRun *approval.DeleteRun
+	deleteThreshold, err := strconv.Atoi(getEnvOrDefault("APPROVAL_DELETE_THRESHOLD", "0"))
+	if err != nil {
+		log.Fatalf("Invalid APPROVAL_DELETE_THRESHOLD: %v", err)
+	}
+	if deleteThreshold > 0 {
+		deleteRun = approval.NewDeleteRun(deleteThreshold)
+	}
+	// applyDeletes applies held deletes, unless they wait for approval
+	applyDeletes := func(ctx context.Context) error {
+		for deleteRun != nil && !deleteRun.Parked() && deleteRun.Len() > 0 {
+			if err := applyTransformed(ctx, deleteRun.Pending()[0]); err != nil {
+				return err
+			}
+			deleteRun.Drop(1)
+		}
+		return nil
+	}
+
+	// Held deletes come first; of the other two, at most one is pending at a time
+	flushPending := func(ctx context.Context) error {
+		if err := applyDeletes(ctx); err != nil {
+			return err
+		}
+		if err := flushInserts(ctx); err != nil {
+			return err
+		}
+		return flushTransaction(ctx)
+	}
+
+	// A replica filled by a bootstrap gets all of its sequences synced once the
+	// bootstrap backlog has been applied, since its rows bypass the sequences
+	var bootstrapping atomic.Bool
+	onCaughtUp := func(ctx context.Context) {
+		if err := flushPending(ctx); err != nil {
+			log.Printf("Error applying pending changes: %v", err)
+		}
+		if !bootstrapping.CompareAndSwap(true, false) {
+			return
+		}
+		log.Printf("Bootstrap applied, syncing all sequences")
+		if err := sequenceSyncer.SyncAll(ctx); err != nil {
+			log.Printf("Error during post-bootstrap sequence sync: %v", err)
+		}
+	}
+
+	router := newRouter(config)
+
+	// DDL from another kind of database gets the replica's column types
+	ddlTranslator := ddl.NewTranslator(dbDialect.Name(), config.TypeMapping)
+
+	// Clauses of DDL the replica can't accept, e.g. OWNER TO a role it doesn't have, are
+	// removed instead of failing the statement
+	var ddlSanitizer *ddl.Sanitizer
+	sanitize, err := strconv.ParseBool(getEnvOrDefault("DDL_SANITIZE", "true"))
+	if err != nil {
+		log.Fatalf("Invalid DDL_SANITIZE: %v", err)
+	}
+	if sanitize {
+		collations, err := dbDialect.Collations(ctx, db)
+		if err != nil {
+			log.Printf("Warning: failed to read replica collations, COLLATE clauses are kept: %v", err)
+		}
+		ddlSanitizer = ddl.NewSanitizer(dbDialect.Name(), collations)
+	}
+
+	// Operator rules from the ddl section of transforms.yml skip, replace or hold DDL
+	// statements for approval
+	ddlRules, err := ddl.NewRules(config.DDL)
+	if err != nil {
+		log.Fatalf("Invalid ddl rules: %v", err)
+	}
+	// With APPROVAL_DDL, every DDL statement is held for approval
+	approveDDL, err := strconv.ParseBool(getEnvOrDefault("APPROVAL_DDL", "false"))
+	if err != nil {
+		log.Fatalf("Invalid APPROVAL_DDL: %v", err)
+	}
+	if (ddlRules.Pauses() || approveDDL || deleteRun != nil) && os.Getenv("ADMIN_ADDR") == "" {
+		log.Fatalf("Holding changes for approval (APPROVAL_DDL, APPROVAL_DELETE_THRESHOLD or ddl rules with action pause) requires ADMIN_ADDR")
+	}
+
+	// Main replication loop
+	consumerConfig := newConsumerConfig()
+	consumerConfig.OnCaughtUp = onCaughtUp
+	consumer := stream.NewConsumer(streamClient, consumerConfig)
+
+	// Held changes pause the pipeline until they are approved or rejected through the
+	// admin endpoints, or APPROVAL_TIMEOUT decides them with APPROVAL_TIMEOUT_ACTION
+	approvalTimeout, err := time.ParseDuration(getEnvOrDefault("APPROVAL_TIMEOUT", "0s"))
+	if err != nil {
+		log.Fatalf("Invalid APPROVAL_TIMEOUT: %v", err)
+	}
+	approvalTimeoutAction, err := approval.ParseAction(os.Getenv("APPROVAL_TIMEOUT_ACTION"))
+	if err != nil {
+		log.Fatalf("Invalid APPROVAL_TIMEOUT_ACTION: %v", err)
+	}
+	approvals := approval.NewQueue(approval.Config{
+		Timeout:       approvalTimeout,
+		TimeoutAction: approvalTimeoutAction,
+		OnDecide: func(item approval.Item) {
+			log.Printf("%s at %s %s by %s", item.Kind, item.Position, item.Decision, item.DecidedBy)
+			consumer.Resume("")
+		},
+	})
+
+	// holdDeletes holds the deletes of a transaction until it is known whether they need
+	// approval. It reports whether it handled the change.
+	holdDeletes := func(ctx context.Context, change *proto.Change) (bool, error) {
+		if approved, ok := deleteRun.Decision(change); ok {
+			if !approved {
+				log.Printf("Skipped delete at %s: the deletes of transaction %s were rejected", change.Position, change.TransactionId)
+				return true, nil
+			}
+			return false, applyDeletes(ctx)
+		}
+		held, over := deleteRun.Add(change)
+		if held {
+			return true, nil
+		}
+		if !over {
+			// Held deletes come before the change, which may start a new run
+			if err := applyDeletes(ctx); err != nil {
+				return true, err
+			}
+			held, _ := deleteRun.Add(change)
+			return held, nil
+		}
+
+		approved, decided := approvals.Check(approval.Item{
+			Kind:          approval.KindDelete,
+			Position:      change.Position,
+			TransactionID: change.TransactionId,
+			Table:         change.GetDml().Table,
+			Rows:          deleteRun.Len() + 1,
+			Reason:        fmt.Sprintf("more than %d deletes in one transaction", deleteRun.Threshold()),
+		})
+		if !decided {
+			// The change isn't acknowledged, so it is handled again once an operator has
+			// decided and the consumer resumes; the deletes before it stay held
+			deleteRun.Park()
+			if err := flushPending(ctx); err != nil {
+				return true, err
+			}
+			consumer.RequestPause()
+			log.Printf("Holding deletes of transaction %s at %s for approval: more than %d", change.TransactionId, change.Position, deleteRun.Threshold())
+			return true, fmt.Errorf("deletes of transaction %s held for approval", change.TransactionId)
+		}
+		if dropped := deleteRun.Decide(approved); !approved {
+			log.Printf("Skipped %d deletes of transaction %s: rejected", dropped+1, change.TransactionId)
+			return true, nil
+		}
+		return false, applyDeletes(ctx)
+	}
+
+	// On shutdown, stop taking changes from the stream and let the change being applied
+	// finish before cancelling everything; a second signal skips the wait
+	drainTimeout, err := time.ParseDuration(getEnvOrDefault("SHUTDOWN_DRAIN_TIMEOUT", "30s"))
+	if err != nil {
+		log.Fatalf("Invalid SHUTDOWN_DRAIN_TIMEOUT: %v", err)
+	}
+	sigChan := make(chan os.Signal, 2)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
 		<-sigChan
-		log.Println("Received shutdown signal")
+		log.Printf("Received shutdown signal, draining for up to %v", drainTimeout)
+		drainCtx, drainCancel := context.WithTimeout(ctx, drainTimeout)
+		defer drainCancel()
+		go func() {
+			select {
+			case <-sigChan:
+				log.Println("Received second shutdown signal, not waiting for the drain")
+				drainCancel()
+			case <-drainCtx.Done():
+			}
+		}()
+
+		if err := consumer.Pause(drainCtx); err != nil {
+			log.Printf("Drain did not finish: %v; stopping with a change still being applied", err)
+		} else if err := flushPending(drainCtx); err != nil {
+			log.Printf("Drain did not finish: %v; stopping with pending changes not applied", err)
+		} else {
+			log.Printf("Drained; last applied position %q", consumer.Position())
+		}
 		cancel()
 	}()
 
-	// Main replication loop
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
+	// Admin endpoints: POST /admin/sync-sequences syncs every sequence now,
+	// GET /admin/status reports positions and per-table apply statistics, and
+	// POST /admin/pause and /admin/resume[?position=bootstrap] stop and restart applying,
+	// and GET /admin/approvals with POST /admin/approvals/{id}/approve and .../reject
+	// decide on changes held for approval
+	if adminAddr := os.Getenv("ADMIN_ADDR"); adminAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/admin/sync-sequences", sequenceSyncer.Handler())
+		mux.Handle("/admin/status", metrics.StatusHandler(func() metrics.Status {
+			return metrics.Status{
+				Position:       metrics.StreamPosition.Value(),
+				SourcePosition: metrics.StreamSourcePosition.Value(),
+				LagSeconds:     consumer.Lag().Seconds(),
+				CaughtUpAt:     consumer.CaughtUpAt(),
+				Paused:         consumer.Paused(),
+				Approvals:      len(approvals.Pending()),
+				Tables:         metrics.Tables.Snapshot(),
+			}
+		}))
+		mux.HandleFunc("POST /admin/pause", func(w http.ResponseWriter, r *http.Request) {
+			if err := consumer.Pause(r.Context()); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
 				return
-			default:
-				// Check if replica database has any user tables to determine starting position
-				lastPosition := determineStartingPosition(db, dbDialect)
-				log.Printf("Starting stream from position: %s", lastPosition)
+			}
+			// The handler is stopped, so pending changes can be applied from here
+			if err := flushPending(r.Context()); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			log.Printf("Paused by admin request")
+			w.WriteHeader(http.StatusNoContent)
+		})
+		mux.HandleFunc("POST /admin/resume", func(w http.ResponseWriter, r *http.Request) {
+			position := r.URL.Query().Get("position")
+			if position == "bootstrap" {
+				bootstrapping.Store(true)
+			}
+			consumer.Resume(position)
+			log.Printf("Resumed by admin request (position: %q)", position)
+			w.WriteHeader(http.StatusNoContent)
+		})
+		mux.HandleFunc("GET /admin/approvals", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(struct {
+				Pending []approval.Item `json:"pending"`
+				Decided []approval.Item `json:"decided"`
+			}{approvals.Pending(), approvals.Decided()})
+		})
+		decide := func(approve bool) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+				if err != nil {
+					http.Error(w, "invalid id", http.StatusBadRequest)
+					return
+				}
+				item, err := approvals.Decide(id, approve, "admin")
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusNotFound)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(item)
+			}
+		}
+		mux.HandleFunc("POST /admin/approvals/{id}/approve", decide(true))
+		mux.HandleFunc("POST /admin/approvals/{id}/reject", decide(false))
+		go func() {
+			log.Printf("Serving admin endpoints on %s", adminAddr)
+			if err := http.ListenAndServe(adminAddr, mux); err != nil {
+				log.Printf("Admin server stopped: %v", err)
+			}
+		}()
+	}
+
+	if monitor != nil && lagThreshold > 0 {
+		go func() {
+			ticker := time.NewTicker(15 * time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					lag := consumer.Lag()
+					monitor.Check(ctx, "replication_lag", lag.Seconds(), float64(lagThreshold),
+						"translicator has not caught up with the change stream for %v", lag.Round(time.Second))
+				}
+			}
+		}()
+	}
 
-				stream, err := streamClient.Stream(ctx, &proto.StreamRequest{LastPosition: lastPosition})
+	go func() {
+		startPosition := func() string {
+			// An exactly-once replica resumes after the last change recorded in its ledger
+			if ledger != nil {
+				position, err := ledger.LastPosition(ctx)
 				if err != nil {
-					log.Printf("Failed to start stream: %v", err)
-					time.Sleep(time.Second)
-					continue
+					log.Printf("Error reading ledger: %v", err)
+				} else if position != "" {
+					log.Printf("Resuming after %s, the last position in the ledger", position)
+					bootstrapping.Store(false)
+					return position
 				}
+			}
 
-				for {
-					change, err := stream.Recv()
-					if err != nil {
-						log.Printf("Error receiving change: %v", err)
-						break
+			// Check if replica database has any user tables to determine starting position
+			position := determineStartingPosition(db, dbDialect)
+			bootstrapping.Store(position == "bootstrap")
+			return position
+		}
+		consumer.Run(streamCtx, startPosition, func(ctx context.Context, change *proto.Change) error {
+			// A message has nothing to apply; pending inserts and transactions are applied, so
+			// the replica has every change before the marker
+			if change.GetMessage() != nil {
+				return flushPending(ctx)
+			}
+
+			// Oversized values are cut or the change skipped before transforms copy them
+			if violations := sizeLimit.Check(change.GetDml()); len(violations) > 0 {
+				blocked := false
+				for _, violation := range violations {
+					log.Printf("Value size at %s: %s", change.Position, violation)
+					blocked = blocked || violation.Blocking()
+				}
+				if blocked {
+					err := fmt.Errorf("values of %s exceed MAX_VALUE_BYTES", change.GetDml().Table)
+					log.Printf("Skipped change at %s: %v", change.Position, err)
+					recordApplyError(ctx, err)
+					metrics.Tables.RecordError(change.GetDml().Table)
+					if deadLetters != nil {
+						if err := deadLetters.Write(change, err.Error(), ""); err != nil {
+							log.Printf("Error writing dead letter: %v", err)
+						}
 					}
+					return nil
+				}
+			}
 
-					transformedChange, err := transform.TransformChange(config, change)
-					if err != nil {
-						log.Printf("Error transforming change: %v", err)
-						continue
+			transformedChange, err := transform.TransformChange(config, change)
+			if err != nil {
+				log.Printf("Error transforming change: %v", err)
+				recordApplyError(ctx, err)
+				if dml := change.GetDml(); dml != nil {
+					metrics.Tables.RecordError(dml.Table)
+				}
+				return nil
+			}
+
+			// A change is only applied once its audit record is stored; a failed write is retried
+			if auditor != nil {
+				if record, ok := auditor.Record(change); ok {
+					if err := auditSink.Write(ctx, record); err != nil {
+						log.Printf("Error writing audit record: %v", err)
+						return err
 					}
+				}
+			}
 
-					// Debug: Check if transform was applied
-					if dml := change.GetDml(); dml != nil && dml.Table == "users" {
-						transformedDml := transformedChange.GetDml()
-						if transformedDml != nil && len(transformedDml.ColumnNames) > 0 {
-							// Find password column index
-							for i, col := range transformedDml.ColumnNames {
-								if col == "password" && i < len(dml.ColumnValues) && i < len(transformedDml.ColumnValues) {
-									origPwd := "nil"
-									transPwd := "nil"
-									if dml.ColumnValues[i] != nil {
-										origPwd = fmt.Sprintf("%v", dml.ColumnValues[i].GetStringValue())[:20] + "..."
-									}
-									if transformedDml.ColumnValues[i] != nil {
-										transPwd = fmt.Sprintf("%v", transformedDml.ColumnValues[i].GetStringValue())[:20] + "..."
-									}
-									log.Printf("Transform debug - users table password: original=%s, transformed=%s", origPwd, transPwd)
-									break
-								}
-							}
+			router.Route(transformedChange.GetDml())
+
+			replaced := false
+			if statement := change.GetDdl(); statement != nil {
+				holdReason := ""
+				if approveDDL {
+					holdReason = "APPROVAL_DDL is set"
+				}
+				if rule, ok := ddlRules.Match(statement.Ddl); ok {
+					switch rule.Action {
+					case transform.DDLSkip:
+						log.Printf("Skipped DDL at %s: matched ddl rule %q", change.Position, rule.Name)
+						return flushPending(ctx)
+					case transform.DDLReplace:
+						log.Printf("Replaced DDL at %s: matched ddl rule %q", change.Position, rule.Name)
+						transformedChange.GetDdl().Ddl = rule.SQL
+						replaced = true
+					case transform.DDLPause:
+						holdReason = fmt.Sprintf("matched ddl rule %q", rule.Name)
+					}
+				}
+				if holdReason != "" {
+					approved, decided := approvals.Check(approval.Item{
+						Kind:      approval.KindDDL,
+						Position:  change.Position,
+						Statement: statement.Ddl,
+						Reason:    holdReason,
+					})
+					if !decided {
+						// The statement isn't acknowledged, so it is handled again once an
+						// operator has decided and the consumer resumes
+						if err := flushPending(ctx); err != nil {
+							return err
 						}
+						consumer.RequestPause()
+						log.Printf("Holding DDL at %s for approval (%s): %s", change.Position, holdReason, statement.Ddl)
+						return fmt.Errorf("DDL at %s held for approval", change.Position)
 					}
-
-					stmt, err := sqlGenerator.ToSQL(transformedChange)
-					if err != nil {
-						log.Printf("Error generating SQL: %v", err)
-						continue
+					if !approved {
+						log.Printf("Skipped DDL at %s: rejected", change.Position)
+						return flushPending(ctx)
 					}
+				}
+			}
+
+			// Replacement SQL is written for the replica as is
+			if !replaced {
+				for _, warning := range ddlTranslator.Translate(transformedChange) {
+					log.Printf("Type mapping at %s: %s", change.Position, warning)
+				}
+			}
+			if ddlSanitizer != nil && !replaced {
+				removals, keep := ddlSanitizer.Sanitize(transformedChange)
+				for _, removal := range removals {
+					log.Printf("DDL sanitized at %s: %s", change.Position, removal)
+				}
+				// Nothing is left to apply, e.g. of ALTER TABLE ... OWNER TO
+				if !keep {
+					log.Printf("Skipped DDL at %s: nothing the replica can apply", change.Position)
+					return flushPending(ctx)
+				}
+			}
 
-					if _, err := db.ExecContext(ctx, stmt); err != nil {
-						log.Printf("Error executing SQL: %v", err)
-						continue
+			// DDL is routed last, since type mappings and the sanitizer match source table names
+			if !replaced {
+				router.RouteDDL(transformedChange)
+			}
+
+			// Report values the replica would reject instead of failing with its error
+			if violations := validator.Check(change.GetDml(), transformedChange.GetDml()); len(violations) > 0 {
+				blocked := false
+				for _, violation := range violations {
+					log.Printf("Validation at %s: %s", change.Position, violation)
+					blocked = blocked || violation.Blocking()
+				}
+				if blocked {
+					err := fmt.Errorf("transformed values violate column constraints of %s", transformedChange.GetDml().Table)
+					log.Printf("Skipped change at %s: %v", change.Position, err)
+					recordApplyError(ctx, err)
+					metrics.Tables.RecordError(transformedChange.GetDml().Table)
+					if deadLetters != nil {
+						if err := deadLetters.Write(transformedChange, err.Error(), ""); err != nil {
+							log.Printf("Error writing dead letter: %v", err)
+						}
 					}
+					return nil
+				}
+			}
 
-					if dml := transformedChange.GetDml(); dml != nil && dml.Kind == "insert" {
-						hasInserts = true
+			// Debug: Check if transform was applied
+			if dml := change.GetDml(); dml != nil && dml.Table == "users" {
+				transformedDml := transformedChange.GetDml()
+				if transformedDml != nil && len(transformedDml.ColumnNames) > 0 {
+					// Find password column index
+					for i, col := range transformedDml.ColumnNames {
+						if col == "password" && i < len(dml.ColumnValues) && i < len(transformedDml.ColumnValues) {
+							origPwd := "nil"
+							transPwd := "nil"
+							if dml.ColumnValues[i] != nil {
+								origPwd = fmt.Sprintf("%v", dml.ColumnValues[i].GetStringValue())[:20] + "..."
+							}
+							if transformedDml.ColumnValues[i] != nil {
+								transPwd = fmt.Sprintf("%v", transformedDml.ColumnValues[i].GetStringValue())[:20] + "..."
+							}
+							log.Printf("Transform debug - users table password: original=%s, transformed=%s", origPwd, transPwd)
+							break
+						}
 					}
+				}
+			}
 
-					log.Printf("%s (%s): %s", change.Position, change.Type, stmt)
+			if deleteRun != nil {
+				handled, err := holdDeletes(ctx, transformedChange)
+				if handled || err != nil {
+					return err
 				}
 			}
-		}
+			return applyTransformed(ctx, transformedChange)
+		})
 	}()
 
 	// Wait for shutdown
@@ -247,6 +1120,87 @@ func main() {
 	log.Println("Shutting down translicator")
 }
 
+// connectChangeStream connects to the change stream service. The returned context
+// carries the API token for stream requests if the change stream requires authentication.
+func connectChangeStream(ctx context.Context) (*grpc.ClientConn, context.Context) {
+	serverAddr := os.Getenv("CHANGE_STREAM_SERVICE_ADDR")
+	if serverAddr == "" {
+		log.Fatal("CHANGE_STREAM_SERVICE_ADDR environment variable is required")
+	}
+	client, err := connectWithRetry(ctx, func() (*grpc.ClientConn, error) {
+		log.Printf("Connecting to change stream service ...")
+		return grpc.NewClient(serverAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	})
+	if err != nil {
+		log.Fatalf("Failed to connect to change stream service after retries: %v", err)
+	}
+	log.Printf("Successfully connected to change stream service")
+
+	streamCtx := ctx
+	if token := os.Getenv("CHANGE_STREAM_TOKEN"); token != "" {
+		streamCtx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	}
+	return client, streamCtx
+}
+
+// newConsumerConfig reads the change stream settings
+func newConsumerConfig() stream.Config {
+	// Reconnect if the stream goes quiet for longer than this; the change stream
+	// sends heartbeats on idle connections, so silence means the connection is dead
+	idleTimeout := 30 * time.Second
+	if value := os.Getenv("CHANGE_STREAM_IDLE_TIMEOUT"); value != "" {
+		var err error
+		idleTimeout, err = time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid CHANGE_STREAM_IDLE_TIMEOUT: %v", err)
+		}
+	}
+	maxInFlightBytes, err := strconv.ParseUint(getEnvOrDefault("CHANGE_STREAM_MAX_IN_FLIGHT_BYTES", "0"), 10, 64)
+	if err != nil {
+		log.Fatalf("Invalid CHANGE_STREAM_MAX_IN_FLIGHT_BYTES: %v", err)
+	}
+
+	return stream.Config{
+		IdleTimeout:      idleTimeout,
+		MaxInFlightBytes: maxInFlightBytes,
+		// Filters the change stream applies before sending, so unneeded changes never cross the network
+		IncludeTables: splitList(os.Getenv("STREAM_INCLUDE_TABLES")),
+		ExcludeTables: splitList(os.Getenv("STREAM_EXCLUDE_TABLES")),
+		ExcludeKinds:  splitList(os.Getenv("STREAM_EXCLUDE_KINDS")),
+		// Large transactions streamed before they commit are held here until they end
+		SpoolDir: os.Getenv("TRANSACTION_SPOOL_DIR"),
+	}
+}
+
+// newRouter routes changes from several source schemas (or MySQL databases) into their
+// own replica schemas
+func newRouter(config *transform.Config) *routing.Router {
+	schemaMap, err := routing.ParseSchemaMap(os.Getenv("SCHEMA_MAP"))
+	if err != nil {
+		log.Fatalf("Invalid SCHEMA_MAP: %v", err)
+	}
+	for source, replica := range schemaMap {
+		log.Printf("Routing schema %s to %s", source, replica)
+	}
+	return routing.NewRouter(schemaMap, config.Routing)
+}
+
+// pruneLedger keeps the exactly-once ledger at about keep positions
+func pruneLedger(ctx context.Context, ledger *apply.Ledger, keep int64) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := ledger.Prune(ctx, keep); err != nil {
+				log.Printf("Error pruning ledger: %v", err)
+			}
+		}
+	}
+}
+
 // determineStartingPosition checks if the replica has any user tables
 // Returns "bootstrap" if empty (needs bootstrap), or "" if tables exist
 func determineStartingPosition(db *dbsql.DB, dbDialect dialect.Dialect) string {
@@ -267,3 +1221,21 @@ func determineStartingPosition(db *dbsql.DB, dbDialect dialect.Dialect) string {
 	log.Printf("Replica database has %d tables, will only request new changes", tableCount)
 	return ""
 }
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// splitList splits a comma-separated list, ignoring blank entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"kasho/pkg/transform"
+	"kasho/proto"
+	"translicator/internal/metrics"
+	"translicator/internal/objectstore"
+	"translicator/internal/stream"
+)
+
+// runObjectSink writes transformed changes as files to object storage instead of
+// applying them to a replica database. Changes are buffered and flushed when
+// OBJECT_FLUSH_RECORDS are pending or every OBJECT_FLUSH_INTERVAL. Each flush is
+// committed with a manifest, and replication resumes from the last manifest's position.
+func runObjectSink(ctx context.Context, cancel context.CancelFunc, config *transform.Config, storeURL string) {
+	store, err := objectstore.Open(ctx, storeURL)
+	if err != nil {
+		log.Fatalf("Failed to open object storage: %v", err)
+	}
+	defer store.Close()
+
+	format, err := objectstore.ParseFormat(getEnvOrDefault("OBJECT_FORMAT", "parquet"))
+	if err != nil {
+		log.Fatalf("Invalid OBJECT_FORMAT: %v", err)
+	}
+	maxRecords, err := strconv.Atoi(getEnvOrDefault("OBJECT_FLUSH_RECORDS", "10000"))
+	if err != nil || maxRecords <= 0 {
+		log.Fatalf("Invalid OBJECT_FLUSH_RECORDS: %q", os.Getenv("OBJECT_FLUSH_RECORDS"))
+	}
+	interval, err := time.ParseDuration(getEnvOrDefault("OBJECT_FLUSH_INTERVAL", "1m"))
+	if err != nil || interval <= 0 {
+		log.Fatalf("Invalid OBJECT_FLUSH_INTERVAL: %q", os.Getenv("OBJECT_FLUSH_INTERVAL"))
+	}
+
+	writer, err := objectstore.NewWriter(ctx, store, format, maxRecords)
+	if err != nil {
+		log.Fatalf("Failed to read object storage manifests: %v", err)
+	}
+	log.Printf("Writing %s files to %s, flushing every %v or %d changes", format, storeURL, interval, maxRecords)
+
+	client, streamCtx := connectChangeStream(ctx)
+	defer client.Close()
+	router := newRouter(config)
+	consumer := stream.NewConsumer(proto.NewChangeStreamClient(client), newConsumerConfig())
+
+	flush := func(ctx context.Context) error {
+		pending := writer.Pending()
+		if err := writer.Flush(ctx); err != nil {
+			return err
+		}
+		if pending > 0 {
+			log.Printf("Flushed %d changes, committed position %q", pending, writer.Position())
+		}
+		return nil
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// Failed flushes keep their changes buffered for the next attempt
+				if err := flush(ctx); err != nil {
+					log.Printf("Error flushing to object storage: %v", err)
+				}
+			}
+		}
+	}()
+
+	if metricsAddr := os.Getenv("METRICS_ADDR"); metricsAddr != "" {
+		go func() {
+			log.Printf("Serving metrics on %s", metricsAddr)
+			if err := metrics.Serve(metricsAddr); err != nil {
+				log.Printf("Metrics server stopped: %v", err)
+			}
+		}()
+	}
+
+	// On shutdown, stop taking changes and commit the buffered ones; whatever isn't
+	// committed is requested again from the last manifest's position on restart
+	drainTimeout, err := time.ParseDuration(getEnvOrDefault("SHUTDOWN_DRAIN_TIMEOUT", "30s"))
+	if err != nil {
+		log.Fatalf("Invalid SHUTDOWN_DRAIN_TIMEOUT: %v", err)
+	}
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		log.Printf("Received shutdown signal, flushing for up to %v", drainTimeout)
+		drainCtx, drainCancel := context.WithTimeout(ctx, drainTimeout)
+		defer drainCancel()
+		if err := consumer.Pause(drainCtx); err != nil {
+			log.Printf("Drain did not finish: %v", err)
+		} else if err := flush(drainCtx); err != nil {
+			log.Printf("Drain did not finish: %v; resuming from %q on restart", err, writer.Position())
+		} else {
+			log.Printf("Drained; committed position %q", writer.Position())
+		}
+		cancel()
+	}()
+
+	go func() {
+		startPosition := func() string {
+			if position := writer.Position(); position != "" {
+				log.Printf("Resuming after the last manifest")
+				return position
+			}
+			log.Printf("No manifest found, will request all changes from beginning")
+			return "bootstrap"
+		}
+		consumer.Run(streamCtx, startPosition, func(ctx context.Context, change *proto.Change) error {
+			transformedChange, err := transform.TransformChange(config, change)
+			if err != nil {
+				log.Printf("Error transforming change at %s: %v", change.Position, err)
+				if dml := change.GetDml(); dml != nil {
+					metrics.Tables.RecordError(dml.Table)
+				}
+				return nil
+			}
+			router.Route(transformedChange.GetDml())
+
+			// A full buffer is flushed first; if that fails the change is redelivered
+			if err := writer.Add(ctx, transformedChange); err != nil {
+				log.Printf("Error flushing to object storage: %v", err)
+				return err
+			}
+			if dml := transformedChange.GetDml(); dml != nil {
+				metrics.Tables.RecordApply(dml.Table, dml.Kind, change.Position, 0)
+			}
+			return nil
+		})
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down translicator")
+}
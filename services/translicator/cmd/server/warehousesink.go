@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"kasho/pkg/transform"
+	"kasho/proto"
+	"translicator/internal/metrics"
+	"translicator/internal/stream"
+	"translicator/internal/warehouse"
+)
+
+// runWarehouseSink loads transformed changes into Snowflake or BigQuery in
+// micro-batches instead of applying them one by one. Changes are buffered and loaded
+// when WAREHOUSE_BATCH_ROWS are pending or every WAREHOUSE_BATCH_INTERVAL. Each load
+// records its position in the warehouse, and replication resumes from there.
+func runWarehouseSink(ctx context.Context, cancel context.CancelFunc, config *transform.Config, warehouseURL string) {
+	wh, err := connectWithRetry(ctx, func() (warehouse.Warehouse, error) {
+		log.Printf("Connecting to warehouse ...")
+		return warehouse.Open(ctx, warehouseURL)
+	})
+	if err != nil {
+		log.Fatalf("Failed to connect to warehouse after retries: %v", err)
+	}
+	defer wh.Close()
+
+	maxRows, err := strconv.Atoi(getEnvOrDefault("WAREHOUSE_BATCH_ROWS", "50000"))
+	if err != nil || maxRows <= 0 {
+		log.Fatalf("Invalid WAREHOUSE_BATCH_ROWS: %q", os.Getenv("WAREHOUSE_BATCH_ROWS"))
+	}
+	interval, err := time.ParseDuration(getEnvOrDefault("WAREHOUSE_BATCH_INTERVAL", "1m"))
+	if err != nil || interval <= 0 {
+		log.Fatalf("Invalid WAREHOUSE_BATCH_INTERVAL: %q", os.Getenv("WAREHOUSE_BATCH_INTERVAL"))
+	}
+
+	batcher, err := warehouse.NewBatcher(ctx, wh, maxRows)
+	if err != nil {
+		log.Fatalf("Failed to read warehouse load position: %v", err)
+	}
+	log.Printf("Loading into warehouse, every %v or %d changes", interval, maxRows)
+
+	client, streamCtx := connectChangeStream(ctx)
+	defer client.Close()
+	router := newRouter(config)
+	consumer := stream.NewConsumer(proto.NewChangeStreamClient(client), newConsumerConfig())
+
+	flush := func(ctx context.Context) error {
+		pending := batcher.Pending()
+		if err := batcher.Flush(ctx); err != nil {
+			return err
+		}
+		if pending > 0 {
+			log.Printf("Loaded %d changes, committed position %q", pending, batcher.Position())
+		}
+		return nil
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// Failed loads keep their changes buffered for the next attempt
+				if err := flush(ctx); err != nil {
+					log.Printf("Error loading into warehouse: %v", err)
+				}
+			}
+		}
+	}()
+
+	if metricsAddr := os.Getenv("METRICS_ADDR"); metricsAddr != "" {
+		go func() {
+			log.Printf("Serving metrics on %s", metricsAddr)
+			if err := metrics.Serve(metricsAddr); err != nil {
+				log.Printf("Metrics server stopped: %v", err)
+			}
+		}()
+	}
+
+	// On shutdown, stop taking changes and load the buffered ones; whatever isn't
+	// loaded is requested again from the last load's position on restart
+	drainTimeout, err := time.ParseDuration(getEnvOrDefault("SHUTDOWN_DRAIN_TIMEOUT", "30s"))
+	if err != nil {
+		log.Fatalf("Invalid SHUTDOWN_DRAIN_TIMEOUT: %v", err)
+	}
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		log.Printf("Received shutdown signal, loading for up to %v", drainTimeout)
+		drainCtx, drainCancel := context.WithTimeout(ctx, drainTimeout)
+		defer drainCancel()
+		if err := consumer.Pause(drainCtx); err != nil {
+			log.Printf("Drain did not finish: %v", err)
+		} else if err := flush(drainCtx); err != nil {
+			log.Printf("Drain did not finish: %v; resuming from %q on restart", err, batcher.Position())
+		} else {
+			log.Printf("Drained; committed position %q", batcher.Position())
+		}
+		cancel()
+	}()
+
+	go func() {
+		startPosition := func() string {
+			if position := batcher.Position(); position != "" {
+				log.Printf("Resuming after the last load")
+				return position
+			}
+			log.Printf("No load position found, will request all changes from beginning")
+			return "bootstrap"
+		}
+		consumer.Run(streamCtx, startPosition, func(ctx context.Context, change *proto.Change) error {
+			transformedChange, err := transform.TransformChange(config, change)
+			if err != nil {
+				log.Printf("Error transforming change at %s: %v", change.Position, err)
+				if dml := change.GetDml(); dml != nil {
+					metrics.Tables.RecordError(dml.Table)
+				}
+				return nil
+			}
+			router.Route(transformedChange.GetDml())
+
+			// A full buffer is loaded first; if that fails the change is redelivered
+			if err := batcher.Add(ctx, transformedChange); err != nil {
+				log.Printf("Error loading into warehouse: %v", err)
+				return err
+			}
+			if dml := transformedChange.GetDml(); dml != nil {
+				metrics.Tables.RecordApply(dml.Table, dml.Kind, change.Position, 0)
+			}
+			return nil
+		})
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down translicator")
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"kasho/pkg/transform"
+	"kasho/proto"
+	"translicator/internal/metrics"
+	"translicator/internal/stream"
+	"translicator/internal/webhook"
+)
+
+// runWebhookSink POSTs transformed changes as signed JSON batches to an HTTP endpoint
+// instead of applying them to a replica database. Changes are buffered and sent when
+// WEBHOOK_BATCH_SIZE are pending or every WEBHOOK_FLUSH_INTERVAL. The position of the
+// last delivered batch is saved to WEBHOOK_STATE_PATH, and replication resumes from it.
+func runWebhookSink(ctx context.Context, cancel context.CancelFunc, config *transform.Config, endpoint string) {
+	batchSize, err := strconv.Atoi(getEnvOrDefault("WEBHOOK_BATCH_SIZE", "500"))
+	if err != nil || batchSize <= 0 {
+		log.Fatalf("Invalid WEBHOOK_BATCH_SIZE: %q", os.Getenv("WEBHOOK_BATCH_SIZE"))
+	}
+	interval, err := time.ParseDuration(getEnvOrDefault("WEBHOOK_FLUSH_INTERVAL", "1s"))
+	if err != nil || interval <= 0 {
+		log.Fatalf("Invalid WEBHOOK_FLUSH_INTERVAL: %q", os.Getenv("WEBHOOK_FLUSH_INTERVAL"))
+	}
+	maxAttempts, err := strconv.Atoi(getEnvOrDefault("WEBHOOK_MAX_ATTEMPTS", "5"))
+	if err != nil {
+		log.Fatalf("Invalid WEBHOOK_MAX_ATTEMPTS: %v", err)
+	}
+	retryBackoff, err := time.ParseDuration(getEnvOrDefault("WEBHOOK_RETRY_BACKOFF", "500ms"))
+	if err != nil {
+		log.Fatalf("Invalid WEBHOOK_RETRY_BACKOFF: %v", err)
+	}
+
+	secret := os.Getenv("WEBHOOK_SECRET")
+	if secret == "" {
+		log.Printf("Warning: WEBHOOK_SECRET is not set, requests will not be signed")
+	}
+	statePath := os.Getenv("WEBHOOK_STATE_PATH")
+	if statePath == "" {
+		log.Printf("Warning: WEBHOOK_STATE_PATH is not set, all changes will be sent again after a restart")
+	}
+
+	sender, err := webhook.NewSender(endpoint, secret, statePath, batchSize)
+	if err != nil {
+		log.Fatalf("Failed to create webhook sender: %v", err)
+	}
+	sender.SetRetry(maxAttempts, retryBackoff)
+	log.Printf("Posting changes to webhook, every %v or %d changes", interval, batchSize)
+
+	client, streamCtx := connectChangeStream(ctx)
+	defer client.Close()
+	router := newRouter(config)
+	consumer := stream.NewConsumer(proto.NewChangeStreamClient(client), newConsumerConfig())
+
+	flush := func(ctx context.Context) error {
+		pending := sender.Pending()
+		if err := sender.Flush(ctx); err != nil {
+			return err
+		}
+		if pending > 0 {
+			log.Printf("Sent %d changes, saved position %q", pending, sender.Position())
+		}
+		return nil
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// Failed sends keep their changes buffered for the next attempt
+				if err := flush(ctx); err != nil {
+					log.Printf("Error sending to webhook: %v", err)
+				}
+			}
+		}
+	}()
+
+	if metricsAddr := os.Getenv("METRICS_ADDR"); metricsAddr != "" {
+		go func() {
+			log.Printf("Serving metrics on %s", metricsAddr)
+			if err := metrics.Serve(metricsAddr); err != nil {
+				log.Printf("Metrics server stopped: %v", err)
+			}
+		}()
+	}
+
+	// On shutdown, stop taking changes and send the buffered ones; whatever isn't
+	// delivered is requested again from the saved position on restart
+	drainTimeout, err := time.ParseDuration(getEnvOrDefault("SHUTDOWN_DRAIN_TIMEOUT", "30s"))
+	if err != nil {
+		log.Fatalf("Invalid SHUTDOWN_DRAIN_TIMEOUT: %v", err)
+	}
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		log.Printf("Received shutdown signal, sending for up to %v", drainTimeout)
+		drainCtx, drainCancel := context.WithTimeout(ctx, drainTimeout)
+		defer drainCancel()
+		if err := consumer.Pause(drainCtx); err != nil {
+			log.Printf("Drain did not finish: %v", err)
+		} else if err := flush(drainCtx); err != nil {
+			log.Printf("Drain did not finish: %v; resuming from %q on restart", err, sender.Position())
+		} else {
+			log.Printf("Drained; saved position %q", sender.Position())
+		}
+		cancel()
+	}()
+
+	go func() {
+		startPosition := func() string {
+			if position := sender.Position(); position != "" {
+				log.Printf("Resuming after the last delivered batch")
+				return position
+			}
+			log.Printf("No saved position found, will request all changes from beginning")
+			return "bootstrap"
+		}
+		consumer.Run(streamCtx, startPosition, func(ctx context.Context, change *proto.Change) error {
+			transformedChange, err := transform.TransformChange(config, change)
+			if err != nil {
+				log.Printf("Error transforming change at %s: %v", change.Position, err)
+				if dml := change.GetDml(); dml != nil {
+					metrics.Tables.RecordError(dml.Table)
+				}
+				return nil
+			}
+			router.Route(transformedChange.GetDml())
+
+			// A full buffer is sent first; if that fails the change is redelivered
+			if err := sender.Add(ctx, transformedChange); err != nil {
+				log.Printf("Error sending to webhook: %v", err)
+				return err
+			}
+			if dml := transformedChange.GetDml(); dml != nil {
+				metrics.Tables.RecordApply(dml.Table, dml.Kind, change.Position, 0)
+			}
+			return nil
+		})
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down translicator")
+}
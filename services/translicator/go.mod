@@ -3,24 +3,40 @@ module translicator
 go 1.24.3
 
 require (
-	github.com/brianvoe/gofakeit/v7 v7.0.2
+	cloud.google.com/go/bigquery v1.67.0
+	cloud.google.com/go/storage v1.51.0
+	github.com/ClickHouse/clickhouse-go/v2 v2.34.0
+	github.com/aws/aws-sdk-go-v2 v1.36.3
+	github.com/aws/aws-sdk-go-v2/config v1.29.14
+	github.com/aws/aws-sdk-go-v2/service/s3 v1.79.3
 	github.com/go-sql-driver/mysql v1.9.3
 	github.com/lib/pq v1.10.9
-	golang.org/x/crypto v0.38.0
+	github.com/parquet-go/parquet-go v0.25.0
+	github.com/sijms/go-ora/v2 v2.9.0
+	github.com/snowflakedb/gosnowflake v1.14.0
+	google.golang.org/api v0.229.0
 	google.golang.org/grpc v1.72.1
-	gopkg.in/yaml.v3 v3.0.1
+	google.golang.org/protobuf v1.36.6
 	kasho/pkg/dialect v0.0.0
+	kasho/pkg/notify v0.0.0
+	kasho/pkg/transform v0.0.0
+	kasho/pkg/types v0.0.0
 	kasho/pkg/version v0.0.0
 	kasho/proto v0.0.0-00010101000000-000000000000
+	modernc.org/sqlite v1.34.5
 )
 
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
+	github.com/brianvoe/gofakeit/v7 v7.0.2 // indirect
+	github.com/google/cel-go v0.23.2 // indirect
+	github.com/tetratelabs/wazero v1.9.0 // indirect
+	golang.org/x/crypto v0.38.0 // indirect
 	golang.org/x/net v0.40.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.26.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250218202821-56aae31c358a // indirect
-	google.golang.org/protobuf v1.36.6 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 replace pg-change-stream => ../pg-change-stream
@@ -29,4 +45,10 @@ replace kasho/proto => ../../proto/kasho/proto
 
 replace kasho/pkg/dialect => ../../pkg/dialect
 
+replace kasho/pkg/notify => ../../pkg/notify
+
+replace kasho/pkg/transform => ../../pkg/transform
+
+replace kasho/pkg/types => ../../pkg/types
+
 replace kasho/pkg/version => ../../pkg/version
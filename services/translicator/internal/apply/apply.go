@@ -0,0 +1,285 @@
+package apply
+
+import (
+	"context"
+	dbsql "database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"kasho/proto"
+	"translicator/internal/chaos"
+	"translicator/internal/dlq"
+	"translicator/internal/metrics"
+	"translicator/internal/sql"
+)
+
+const (
+	defaultMaxAttempts  = 5
+	defaultRetryBackoff = 100 * time.Millisecond
+	maxRetryBackoff     = 5 * time.Second
+)
+
+// ErrSkipped is returned when a change was deliberately not applied
+var ErrSkipped = errors.New("change skipped")
+
+// ConflictPolicy decides what happens when an UPDATE or DELETE targets a row
+// that no longer matches the replica, e.g. because of a local write
+type ConflictPolicy string
+
+const (
+	// NoConflictCheck applies changes without looking at the replica first
+	NoConflictCheck ConflictPolicy = ""
+	// SourceWins applies the change anyway and logs the conflict
+	SourceWins ConflictPolicy = "source-wins"
+	// ReplicaWins skips the change, keeping the replica's version of the row
+	ReplicaWins ConflictPolicy = "replica-wins"
+	// Fail skips the change and records it in the dead-letter file with a conflict report
+	Fail ConflictPolicy = "fail"
+)
+
+// ParseConflictPolicy validates a conflict policy name
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(name); policy {
+	case NoConflictCheck, SourceWins, ReplicaWins, Fail:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown conflict policy %q (expected source-wins, replica-wins, or fail)", name)
+	}
+}
+
+// Applier executes transformed changes against the replica database
+type Applier struct {
+	db             *dbsql.DB
+	generator      *sql.SQLGenerator
+	conflictPolicy ConflictPolicy
+	deadLetters    *dlq.Writer
+	maxAttempts    int
+	retryBackoff   time.Duration
+	sleep          func(ctx context.Context, d time.Duration) error
+	stmts          *stmtCache
+	ledger         *Ledger
+	timeouts       StatementTimeouts
+}
+
+// NewApplier creates an applier that generates SQL with the given generator
+func NewApplier(db *dbsql.DB, generator *sql.SQLGenerator) *Applier {
+	return &Applier{
+		db:           db,
+		generator:    generator,
+		maxAttempts:  defaultMaxAttempts,
+		retryBackoff: defaultRetryBackoff,
+		sleep:        sleepContext,
+	}
+}
+
+// SetRetry sets how often a statement that fails with a transient error is attempted
+// in total, and the delay before the first retry, which doubles with each further retry
+func (a *Applier) SetRetry(maxAttempts int, backoff time.Duration) {
+	a.maxAttempts = max(maxAttempts, 1)
+	a.retryBackoff = backoff
+}
+
+// SetStatementCache enables executing DML changes as prepared statements, keeping up to
+// size of them; 0 disables the cache and sends every change as a literal statement
+func (a *Applier) SetStatementCache(size int) {
+	if a.stmts != nil {
+		a.stmts.clear()
+		a.stmts = nil
+	}
+	if size > 0 {
+		a.stmts = newStmtCache(a.db, size)
+	}
+}
+
+// SetConflictPolicy enables conflict detection for UPDATE and DELETE changes.
+// deadLetters is required for the Fail policy.
+func (a *Applier) SetConflictPolicy(policy ConflictPolicy, deadLetters *dlq.Writer) error {
+	if policy == Fail && deadLetters == nil {
+		return fmt.Errorf("conflict policy %q requires a dead-letter file", policy)
+	}
+	a.conflictPolicy = policy
+	a.deadLetters = deadLetters
+	return nil
+}
+
+// SetStatementTimeouts limits how long each attempt at a change's statement may run.
+// A statement that takes longer is cancelled and fails with a ClassTimeout error.
+func (a *Applier) SetStatementTimeouts(timeouts StatementTimeouts) {
+	a.timeouts = timeouts
+}
+
+// SetLedger enables exactly-once apply: changes whose position is in the ledger are
+// skipped, and every other change is applied in a transaction that records its position.
+// DDL on replicas where it commits implicitly is recorded once it has committed.
+func (a *Applier) SetLedger(ledger *Ledger) {
+	a.ledger = ledger
+}
+
+// Apply generates and executes the SQL statement for a transformed change and returns it
+func (a *Applier) Apply(ctx context.Context, change *proto.Change) (string, error) {
+	if a.ledger != nil {
+		applied, err := a.ledger.Applied(ctx, change.Position)
+		if err != nil {
+			return "", fmt.Errorf("error checking ledger: %w", &ClassifiedError{Class: ClassifyError(err), Err: err})
+		}
+		if applied {
+			return "", fmt.Errorf("%w: already applied", ErrSkipped)
+		}
+	}
+
+	stmt, err := a.generator.ToSQL(change)
+	if errors.Is(err, sql.ErrNoChanges) {
+		return "", fmt.Errorf("%w: %v", ErrSkipped, err)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error generating SQL: %w", err)
+	}
+
+	if err := a.checkConflict(ctx, change, stmt); err != nil {
+		return stmt, err
+	}
+
+	run, err := a.execFunc(change, stmt)
+	if err != nil {
+		return stmt, fmt.Errorf("error generating SQL: %w", err)
+	}
+	execute := func(ctx context.Context) error { return run(ctx, nil) }
+	separate := a.ledger != nil && change.GetDdl() != nil && a.ledger.recordsDDLSeparately()
+	if a.ledger != nil && !separate {
+		execute = func(ctx context.Context) error { return a.ledger.record(ctx, []string{change.Position}, run) }
+	}
+	if err := a.exec(ctx, execute); err != nil {
+		return stmt, fmt.Errorf("error executing SQL: %w", err)
+	}
+	if separate {
+		// Retrying a failed record must not run the committed DDL again
+		record := func(ctx context.Context) error {
+			return a.ledger.record(ctx, []string{change.Position}, func(context.Context, *dbsql.Tx) error { return nil })
+		}
+		if err := a.exec(ctx, record); err != nil {
+			return stmt, fmt.Errorf("error recording applied DDL: %w", err)
+		}
+	}
+	// Schema changes can invalidate prepared statements, e.g. by changing a column's type
+	if a.stmts != nil && change.GetDdl() != nil {
+		a.stmts.clear()
+	}
+	return stmt, nil
+}
+
+// execFunc returns the function that executes a change, in tx unless it's nil: a cached
+// prepared statement for DML when the statement cache is enabled, the literal statement
+// otherwise, bounded by the change's statement timeout
+func (a *Applier) execFunc(change *proto.Change, stmt string) (func(ctx context.Context, tx *dbsql.Tx) error, error) {
+	run, err := a.statementFunc(change, stmt)
+	if err != nil {
+		return nil, err
+	}
+	return a.withTimeout(change, run), nil
+}
+
+func (a *Applier) statementFunc(change *proto.Change, stmt string) (func(ctx context.Context, tx *dbsql.Tx) error, error) {
+	dml := change.GetDml()
+	if a.stmts == nil || dml == nil {
+		return func(ctx context.Context, tx *dbsql.Tx) error {
+			var err error
+			if tx != nil {
+				_, err = tx.ExecContext(ctx, stmt)
+			} else {
+				_, err = a.db.ExecContext(ctx, stmt)
+			}
+			return err
+		}, nil
+	}
+
+	query, args, err := a.generator.ToPreparedSQL(dml)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, tx *dbsql.Tx) error {
+		prepared, err := a.stmts.get(ctx, query)
+		if err != nil {
+			return err
+		}
+		if tx != nil {
+			prepared = tx.StmtContext(ctx, prepared)
+		}
+		_, err = prepared.ExecContext(ctx, args...)
+		return err
+	}, nil
+}
+
+// exec runs a statement, retrying transient errors with backoff. Failures are
+// returned as a *ClassifiedError.
+func (a *Applier) exec(ctx context.Context, run func(ctx context.Context) error) error {
+	backoff := a.retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := chaos.Exec(ctx, run)
+		if err == nil {
+			return nil
+		}
+
+		class := ClassifyError(err)
+		metrics.ApplyErrors.Add(string(class), 1)
+		if !class.Transient() || attempt >= a.maxAttempts || ctx.Err() != nil {
+			return &ClassifiedError{Class: class, Err: err}
+		}
+
+		log.Printf("Transient %s error (attempt %d of %d), retrying in %v: %v", class, attempt, a.maxAttempts, backoff, err)
+		metrics.ApplyRetries.Add(1)
+		if err := a.sleep(ctx, backoff); err != nil {
+			return &ClassifiedError{Class: class, Err: err}
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// checkConflict looks up the row targeted by an UPDATE or DELETE and applies the
+// conflict policy if it is missing from the replica
+func (a *Applier) checkConflict(ctx context.Context, change *proto.Change, stmt string) error {
+	dml := change.GetDml()
+	if a.conflictPolicy == NoConflictCheck || dml == nil || (dml.Kind != "update" && dml.Kind != "delete") {
+		return nil
+	}
+
+	query, err := a.generator.ToRowExistsSQL(dml)
+	if err != nil {
+		return fmt.Errorf("error generating conflict check: %w", err)
+	}
+
+	var exists int
+	err = a.db.QueryRowContext(ctx, query).Scan(&exists)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, dbsql.ErrNoRows) {
+		return fmt.Errorf("error checking for conflict: %w", &ClassifiedError{Class: ClassifyError(err), Err: err})
+	}
+
+	reason := fmt.Sprintf("%s conflict on %s: row not found on replica", dml.Kind, dml.Table)
+	switch a.conflictPolicy {
+	case SourceWins:
+		log.Printf("%s, applying anyway (policy: %s)", reason, a.conflictPolicy)
+		return nil
+	case ReplicaWins:
+		return fmt.Errorf("%w: %s", ErrSkipped, reason)
+	default:
+		if err := a.deadLetters.Write(change, reason, stmt); err != nil {
+			return fmt.Errorf("%s: %w", reason, err)
+		}
+		return fmt.Errorf("%w: %s (recorded in dead-letter file)", ErrSkipped, reason)
+	}
+}
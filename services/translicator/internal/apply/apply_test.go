@@ -0,0 +1,290 @@
+package apply
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"kasho/pkg/dialect"
+	"kasho/proto"
+	"translicator/internal/dlq"
+	"translicator/internal/sql"
+
+	"github.com/lib/pq"
+)
+
+func updateChange() *proto.Change {
+	return &proto.Change{
+		Position: "0/100",
+		Type:     "dml",
+		Data: &proto.Change_Dml{Dml: &proto.DMLData{
+			Table:       "users",
+			Kind:        "update",
+			ColumnNames: []string{"name"},
+			ColumnValues: []*proto.ColumnValue{
+				{Value: &proto.ColumnValue_StringValue{StringValue: "Jane"}},
+			},
+			OldKeys: &proto.OldKeys{
+				KeyNames:  []string{"id"},
+				KeyValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_IntValue{IntValue: 1}}},
+			},
+		}},
+	}
+}
+
+const (
+	updateSQL = "UPDATE users SET name = 'Jane' WHERE id = 1;"
+	existsSQL = "SELECT 1 FROM users WHERE id = 1 LIMIT 1;"
+)
+
+func TestParseConflictPolicy(t *testing.T) {
+	for _, name := range []string{"", "source-wins", "replica-wins", "fail"} {
+		if _, err := ParseConflictPolicy(name); err != nil {
+			t.Errorf("ParseConflictPolicy(%q) unexpected error: %v", name, err)
+		}
+	}
+	if _, err := ParseConflictPolicy("last-write-wins"); err == nil {
+		t.Error("ParseConflictPolicy() should reject unknown policies")
+	}
+}
+
+func TestApply_ConflictPolicies(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    ConflictPolicy
+		rowExists bool
+		wantExec  bool
+		wantSkip  bool
+		wantDLQ   bool
+	}{
+		{"no check", NoConflictCheck, false, true, false, false},
+		{"row exists", ReplicaWins, true, true, false, false},
+		{"source wins", SourceWins, false, true, false, false},
+		{"replica wins", ReplicaWins, false, false, true, false},
+		{"fail routes to dead-letter file", Fail, false, false, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fake *fakeDB
+			if tt.rowExists {
+				fake = newFakeDB(existsSQL)
+			} else {
+				fake = newFakeDB()
+			}
+			db := fake.open()
+			defer db.Close()
+
+			dlqPath := filepath.Join(t.TempDir(), "dlq.jsonl")
+			deadLetters, err := dlq.Open(dlqPath)
+			if err != nil {
+				t.Fatalf("dlq.Open() unexpected error: %v", err)
+			}
+			defer deadLetters.Close()
+
+			a := NewApplier(db, sql.NewSQLGenerator(dialect.NewPostgreSQL()))
+			if err := a.SetConflictPolicy(tt.policy, deadLetters); err != nil {
+				t.Fatalf("SetConflictPolicy() unexpected error: %v", err)
+			}
+
+			stmt, err := a.Apply(context.Background(), updateChange())
+			if stmt != updateSQL {
+				t.Errorf("Apply() statement = %q, want %q", stmt, updateSQL)
+			}
+			if gotSkip := errors.Is(err, ErrSkipped); gotSkip != tt.wantSkip {
+				t.Errorf("Apply() error = %v, want skipped = %v", err, tt.wantSkip)
+			}
+			if !tt.wantSkip && err != nil {
+				t.Errorf("Apply() unexpected error: %v", err)
+			}
+
+			executed := fake.executed()
+			if gotExec := len(executed) == 1 && executed[0] == updateSQL; gotExec != tt.wantExec {
+				t.Errorf("executed = %v, want exec = %v", executed, tt.wantExec)
+			}
+
+			data, err := os.ReadFile(dlqPath)
+			if err != nil {
+				t.Fatalf("failed to read dead-letter file: %v", err)
+			}
+			if gotDLQ := strings.Contains(string(data), "row not found"); gotDLQ != tt.wantDLQ {
+				t.Errorf("dead-letter file = %q, want entry = %v", data, tt.wantDLQ)
+			}
+		})
+	}
+}
+
+func TestApply_InsertSkipsConflictCheck(t *testing.T) {
+	fake := newFakeDB()
+	db := fake.open()
+	defer db.Close()
+
+	a := NewApplier(db, sql.NewSQLGenerator(dialect.NewPostgreSQL()))
+	if err := a.SetConflictPolicy(ReplicaWins, nil); err != nil {
+		t.Fatalf("SetConflictPolicy() unexpected error: %v", err)
+	}
+
+	insert := &proto.Change{
+		Position: "0/200",
+		Type:     "dml",
+		Data: &proto.Change_Dml{Dml: &proto.DMLData{
+			Table:        "users",
+			Kind:         "insert",
+			ColumnNames:  []string{"id"},
+			ColumnValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_IntValue{IntValue: 2}}},
+		}},
+	}
+	if _, err := a.Apply(context.Background(), insert); err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	if len(fake.queries) != 0 {
+		t.Errorf("inserts should not be checked for conflicts, got queries %v", fake.queries)
+	}
+}
+
+func TestSetConflictPolicy_FailRequiresDeadLetters(t *testing.T) {
+	a := NewApplier(nil, sql.NewSQLGenerator(dialect.NewPostgreSQL()))
+	if err := a.SetConflictPolicy(Fail, nil); err == nil {
+		t.Error("SetConflictPolicy(Fail, nil) should return an error")
+	}
+}
+
+func TestApply_SkipsEmptyUpdate(t *testing.T) {
+	fake := newFakeDB()
+	db := fake.open()
+	defer db.Close()
+
+	change := updateChange()
+	change.GetDml().ColumnValues[0] = &proto.ColumnValue{Value: &proto.ColumnValue_UnchangedToast{UnchangedToast: true}}
+
+	a := NewApplier(db, sql.NewSQLGenerator(dialect.NewPostgreSQL()))
+	_, err := a.Apply(context.Background(), change)
+	if !errors.Is(err, ErrSkipped) {
+		t.Fatalf("Apply() error = %v, want ErrSkipped", err)
+	}
+	if executed := fake.executed(); len(executed) != 0 {
+		t.Errorf("nothing should be executed, got %v", executed)
+	}
+}
+
+func TestApply_RetriesTransientErrors(t *testing.T) {
+	fake := newFakeDB()
+	fake.execErrs = []error{&pq.Error{Code: "40P01"}, &pq.Error{Code: "40001"}}
+	db := fake.open()
+	defer db.Close()
+
+	a := NewApplier(db, sql.NewSQLGenerator(dialect.NewPostgreSQL()))
+	var delays []time.Duration
+	a.sleep = func(_ context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+
+	if _, err := a.Apply(context.Background(), updateChange()); err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	if executed := fake.executed(); len(executed) != 1 || executed[0] != updateSQL {
+		t.Errorf("executed = %v, want [%s]", executed, updateSQL)
+	}
+	if want := []time.Duration{defaultRetryBackoff, 2 * defaultRetryBackoff}; !reflect.DeepEqual(delays, want) {
+		t.Errorf("retry delays = %v, want %v", delays, want)
+	}
+}
+
+func TestApply_GivesUpAfterMaxAttempts(t *testing.T) {
+	fake := newFakeDB()
+	fake.execErr = &pq.Error{Code: "40P01"}
+	db := fake.open()
+	defer db.Close()
+
+	a := NewApplier(db, sql.NewSQLGenerator(dialect.NewPostgreSQL()))
+	a.SetRetry(3, time.Millisecond)
+	retries := 0
+	a.sleep = func(context.Context, time.Duration) error {
+		retries++
+		return nil
+	}
+
+	_, err := a.Apply(context.Background(), updateChange())
+	var classified *ClassifiedError
+	if !errors.As(err, &classified) || classified.Class != ClassDeadlock {
+		t.Fatalf("Apply() error = %v, want a deadlock ClassifiedError", err)
+	}
+	if retries != 2 {
+		t.Errorf("retries = %d, want 2", retries)
+	}
+}
+
+func TestApply_DoesNotRetryPermanentErrors(t *testing.T) {
+	fake := newFakeDB()
+	fake.execErr = &pq.Error{Code: "23505"}
+	db := fake.open()
+	defer db.Close()
+
+	a := NewApplier(db, sql.NewSQLGenerator(dialect.NewPostgreSQL()))
+	a.sleep = func(context.Context, time.Duration) error {
+		t.Error("permanent errors should not be retried")
+		return nil
+	}
+
+	_, err := a.Apply(context.Background(), updateChange())
+	var classified *ClassifiedError
+	if !errors.As(err, &classified) || classified.Class != ClassConstraint {
+		t.Fatalf("Apply() error = %v, want a constraint ClassifiedError", err)
+	}
+}
+
+func TestApply_StatementCache(t *testing.T) {
+	fake := newFakeDB()
+	db := fake.open()
+	defer db.Close()
+
+	a := NewApplier(db, sql.NewSQLGenerator(dialect.NewPostgreSQL()))
+	a.SetStatementCache(1)
+
+	insert := func(table string) *proto.Change {
+		return &proto.Change{Data: &proto.Change_Dml{Dml: &proto.DMLData{
+			Table:        table,
+			Kind:         "insert",
+			ColumnNames:  []string{"id"},
+			ColumnValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_IntValue{IntValue: 1}}},
+		}}}
+	}
+	for _, change := range []*proto.Change{updateChange(), updateChange(), insert("users"), insert("users")} {
+		if _, err := a.Apply(context.Background(), change); err != nil {
+			t.Fatalf("Apply() unexpected error: %v", err)
+		}
+	}
+
+	// Repeated shapes reuse the prepared statement; the cache holds a single statement
+	wantPrepares := []string{
+		"UPDATE users SET name = $1 WHERE id = $2;",
+		"INSERT INTO users (id) VALUES ($1);",
+	}
+	fake.mu.Lock()
+	prepares, closed := fake.prepares, fake.closed
+	fake.mu.Unlock()
+	if !reflect.DeepEqual(prepares, wantPrepares) {
+		t.Errorf("prepared = %v, want %v", prepares, wantPrepares)
+	}
+	if closed != 1 {
+		t.Errorf("closed %d statements, want 1 (evicted)", closed)
+	}
+	if executed := fake.executed(); len(executed) != 4 {
+		t.Errorf("executed %d statements, want 4", len(executed))
+	}
+
+	// Schema changes clear the cache
+	ddl := &proto.Change{Data: &proto.Change_Ddl{Ddl: &proto.DDLData{Ddl: "ALTER TABLE users ADD COLUMN age int;"}}}
+	if _, err := a.Apply(context.Background(), ddl); err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	if len(a.stmts.entries) != 0 {
+		t.Errorf("cache has %d statements after DDL, want 0", len(a.stmts.entries))
+	}
+}
@@ -0,0 +1,93 @@
+package apply
+
+import (
+	"context"
+	dbsql "database/sql"
+	"slices"
+
+	"kasho/proto"
+	"translicator/internal/sql"
+)
+
+// Batcher collects consecutive inserts into the same table and columns, such as a
+// replayed bootstrap, and writes them with a single bulk load (PostgreSQL COPY,
+// MySQL LOAD DATA LOCAL INFILE, a multi-row ClickHouse INSERT) instead of one INSERT
+// each. On ClickHouse, updates and deletes are inserts of row versions and are
+// batched as well.
+type Batcher struct {
+	db        *dbsql.DB
+	generator *sql.SQLGenerator
+	load      bulkLoader
+	size      int
+
+	table   string
+	columns []string
+	rows    [][]any
+	changes []*proto.Change
+}
+
+// NewBatcher creates a batcher that bulk loads up to size inserts at once into a
+// replica of the given dialect
+func NewBatcher(db *dbsql.DB, generator *sql.SQLGenerator, dialectName string, size int) (*Batcher, error) {
+	load, err := bulkLoaderFor(dialectName)
+	if err != nil {
+		return nil, err
+	}
+	return &Batcher{db: db, generator: generator, load: load, size: size}, nil
+}
+
+// Add adds a transformed change to the batch and reports whether it was added. Changes
+// that can't be bulk loaded, such as updates and deletes or inserts with geometries,
+// inserts into another table or columns than the pending ones, and changes arriving
+// when the batch is full are not added; the pending batch must be loaded before they
+// are applied.
+func (b *Batcher) Add(change *proto.Change) bool {
+	dml := change.GetDml()
+	if dml == nil || len(b.changes) >= b.size {
+		return false
+	}
+	columns, row, err := b.generator.ToBulkRow(dml)
+	if err != nil {
+		return false
+	}
+	if len(b.changes) > 0 && (dml.Table != b.table || !slices.Equal(columns, b.columns)) {
+		return false
+	}
+
+	b.table, b.columns = dml.Table, columns
+	b.rows = append(b.rows, row)
+	b.changes = append(b.changes, change)
+	return true
+}
+
+// Pending returns the changes in the batch, in the order they were added
+func (b *Batcher) Pending() []*proto.Change {
+	return b.changes
+}
+
+// Table returns the table of the pending changes
+func (b *Batcher) Table() string {
+	return b.table
+}
+
+// Load bulk loads the batch and empties it. If loading fails nothing is written and
+// the changes stay pending, to be applied one at a time.
+func (b *Batcher) Load(ctx context.Context) error {
+	if len(b.changes) == 0 {
+		return nil
+	}
+	if err := b.load(ctx, b.db, b.table, b.columns, b.rows); err != nil {
+		return &ClassifiedError{Class: ClassifyError(err), Err: err}
+	}
+	b.Drop(len(b.changes))
+	return nil
+}
+
+// Drop removes the first n pending changes, e.g. once they were applied one at a time
+func (b *Batcher) Drop(n int) {
+	b.rows = b.rows[n:]
+	b.changes = b.changes[n:]
+	if len(b.changes) == 0 {
+		b.rows, b.changes, b.columns, b.table = nil, nil, nil, ""
+	}
+}
@@ -0,0 +1,139 @@
+package apply
+
+import (
+	"context"
+	dbsql "database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"kasho/pkg/dialect"
+	"kasho/proto"
+	"translicator/internal/sql"
+)
+
+func insertChange(table string, id int64) *proto.Change {
+	return &proto.Change{Data: &proto.Change_Dml{Dml: &proto.DMLData{
+		Table:        table,
+		Kind:         "insert",
+		ColumnNames:  []string{"id"},
+		ColumnValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_IntValue{IntValue: id}}},
+	}}}
+}
+
+func TestBatcher_Add(t *testing.T) {
+	b, err := NewBatcher(nil, sql.NewSQLGenerator(dialect.NewPostgreSQL()), "postgresql", 2)
+	if err != nil {
+		t.Fatalf("NewBatcher() unexpected error: %v", err)
+	}
+
+	if !b.Add(insertChange("public.users", 1)) {
+		t.Fatal("Add() should accept the first insert")
+	}
+	if b.Add(insertChange("public.orders", 1)) {
+		t.Error("Add() should not accept an insert into another table")
+	}
+	if b.Add(updateChange()) {
+		t.Error("Add() should not accept an update")
+	}
+	geometry := &proto.Change{Data: &proto.Change_Dml{Dml: &proto.DMLData{
+		Table:        "public.users",
+		Kind:         "insert",
+		ColumnNames:  []string{"id"},
+		ColumnValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_GeometryValue{GeometryValue: &proto.Geometry{Wkt: "POINT(1 2)"}}}},
+	}}}
+	if b.Add(geometry) {
+		t.Error("Add() should not accept values written through SQL expressions")
+	}
+	if !b.Add(insertChange("public.users", 2)) {
+		t.Fatal("Add() should accept a second insert into the same table")
+	}
+	if b.Add(insertChange("public.users", 3)) {
+		t.Error("Add() should not accept inserts into a full batch")
+	}
+}
+
+func TestBatcher_AddVersioned(t *testing.T) {
+	b, err := NewBatcher(nil, sql.NewSQLGenerator(dialect.NewClickHouse()), "clickhouse", 10)
+	if err != nil {
+		t.Fatalf("NewBatcher() unexpected error: %v", err)
+	}
+
+	// Inserts and deletes are both row versions with the same columns
+	if !b.Add(insertChange("analytics.users", 1)) {
+		t.Fatal("Add() should accept an insert")
+	}
+	deleteChange := &proto.Change{Data: &proto.Change_Dml{Dml: &proto.DMLData{
+		Table: "analytics.users",
+		Kind:  "delete",
+		OldKeys: &proto.OldKeys{
+			KeyNames:  []string{"id"},
+			KeyValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_IntValue{IntValue: 1}}},
+		},
+	}}}
+	if !b.Add(deleteChange) {
+		t.Fatal("Add() should accept a delete written as a tombstone")
+	}
+	if want := []string{"id", "_version", "_is_deleted"}; !reflect.DeepEqual(b.columns, want) {
+		t.Errorf("columns = %v, want %v", b.columns, want)
+	}
+	if deleted := b.rows[1][2]; deleted != int64(1) {
+		t.Errorf("delete row deleted flag = %v, want 1", deleted)
+	}
+}
+
+func TestBatcher_Load(t *testing.T) {
+	b, err := NewBatcher(nil, sql.NewSQLGenerator(dialect.NewPostgreSQL()), "postgresql", 10)
+	if err != nil {
+		t.Fatalf("NewBatcher() unexpected error: %v", err)
+	}
+	var loaded [][]any
+	loadErr := errors.New("duplicate key")
+	b.load = func(_ context.Context, _ *dbsql.DB, table string, columns []string, rows [][]any) error {
+		if table != "public.users" || !reflect.DeepEqual(columns, []string{"id"}) {
+			t.Errorf("load(%s, %v), want public.users [id]", table, columns)
+		}
+		if loadErr != nil {
+			return loadErr
+		}
+		loaded = rows
+		return nil
+	}
+
+	b.Add(insertChange("public.users", 1))
+	b.Add(insertChange("public.users", 2))
+
+	// A failed load keeps the changes pending
+	if err := b.Load(context.Background()); !errors.Is(err, loadErr) {
+		t.Fatalf("Load() error = %v, want %v", err, loadErr)
+	}
+	if len(b.Pending()) != 2 {
+		t.Fatalf("Pending() after failed load = %d changes, want 2", len(b.Pending()))
+	}
+
+	loadErr = nil
+	if err := b.Load(context.Background()); err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if want := [][]any{{int64(1)}, {int64(2)}}; !reflect.DeepEqual(loaded, want) {
+		t.Errorf("loaded rows = %v, want %v", loaded, want)
+	}
+	if len(b.Pending()) != 0 {
+		t.Errorf("Pending() after load = %d changes, want 0", len(b.Pending()))
+	}
+
+	// Once empty, inserts into any table start a new batch
+	if !b.Add(insertChange("public.orders", 1)) {
+		t.Error("Add() after load should accept an insert into another table")
+	}
+	b.Drop(1)
+	if len(b.Pending()) != 0 || b.Table() != "" {
+		t.Errorf("Drop() left %d changes for table %q", len(b.Pending()), b.Table())
+	}
+}
+
+func TestNewBatcher_UnsupportedDialect(t *testing.T) {
+	if _, err := NewBatcher(nil, nil, "sqlite", 10); err == nil {
+		t.Error("NewBatcher() with an unsupported dialect should return an error")
+	}
+}
@@ -0,0 +1,159 @@
+package apply
+
+import (
+	"bytes"
+	"context"
+	dbsql "database/sql"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// bulkLoader writes rows into a table in one operation, all or nothing
+type bulkLoader func(ctx context.Context, db *dbsql.DB, table string, columns []string, rows [][]any) error
+
+// bulkLoaderFor returns the bulk loader of a replica dialect
+func bulkLoaderFor(dialectName string) (bulkLoader, error) {
+	switch dialectName {
+	case "postgresql":
+		return copyIn, nil
+	case "mysql":
+		return loadDataLocal, nil
+	case "clickhouse":
+		return insertValues, nil
+	default:
+		return nil, fmt.Errorf("bulk loading is not supported for %s", dialectName)
+	}
+}
+
+// copyIn loads rows with COPY FROM STDIN. Tables are named "schema.table" as in changes.
+func copyIn(ctx context.Context, db *dbsql.DB, table string, columns []string, rows [][]any) error {
+	schema, name, found := strings.Cut(table, ".")
+	if !found {
+		schema, name = "", table
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var query string
+	if schema == "" {
+		query = pq.CopyIn(name, columns...)
+	} else {
+		query = pq.CopyInSchema(schema, name, columns...)
+	}
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return err
+		}
+	}
+	// An exec without arguments ends the COPY
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return err
+	}
+	if err := stmt.Close(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertValues loads rows with a single multi-row INSERT. ClickHouse writes an INSERT
+// as one part, atomically, and merges parts in the background, so one large INSERT is
+// much cheaper than many small ones.
+func insertValues(ctx context.Context, db *dbsql.DB, table string, columns []string, rows [][]any) error {
+	tuple := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	tuples := make([]string, len(rows))
+	args := make([]any, 0, len(rows)*len(columns))
+	for i, row := range rows {
+		tuples[i] = tuple
+		args = append(args, row...)
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", table, strings.Join(columns, ", "), strings.Join(tuples, ", "))
+	_, err := db.ExecContext(ctx, query, args...)
+	return err
+}
+
+var loadDataReaders atomic.Int64
+
+// loadDataLocal loads rows with LOAD DATA LOCAL INFILE, streaming them from memory.
+// The replica must allow it with local_infile=ON.
+func loadDataLocal(ctx context.Context, db *dbsql.DB, table string, columns []string, rows [][]any) error {
+	var data []byte
+	for _, row := range rows {
+		for i, value := range row {
+			if i > 0 {
+				data = append(data, '\t')
+			}
+			var err error
+			if data, err = appendLoadDataValue(data, value); err != nil {
+				return err
+			}
+		}
+		data = append(data, '\n')
+	}
+
+	name := fmt.Sprintf("kasho-%d", loadDataReaders.Add(1))
+	mysql.RegisterReaderHandler(name, func() io.Reader { return bytes.NewReader(data) })
+	defer mysql.DeregisterReaderHandler(name)
+
+	_, err := db.ExecContext(ctx, fmt.Sprintf("LOAD DATA LOCAL INFILE 'Reader::%s' INTO TABLE %s CHARACTER SET utf8mb4 (%s)",
+		name, table, strings.Join(columns, ", ")))
+	return err
+}
+
+// appendLoadDataValue appends a value in the default LOAD DATA format: tab-separated
+// fields, escaped with backslashes, and \N for NULL
+func appendLoadDataValue(data []byte, value any) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return append(data, `\N`...), nil
+	case string:
+		return appendLoadDataEscaped(data, []byte(v)), nil
+	case []byte:
+		return appendLoadDataEscaped(data, v), nil
+	case int64:
+		return strconv.AppendInt(data, v, 10), nil
+	case float64:
+		return strconv.AppendFloat(data, v, 'f', -1, 64), nil
+	case bool:
+		if v {
+			return append(data, '1'), nil
+		}
+		return append(data, '0'), nil
+	default:
+		return nil, fmt.Errorf("unsupported bulk load value type: %T", value)
+	}
+}
+
+func appendLoadDataEscaped(data, value []byte) []byte {
+	for _, b := range value {
+		switch b {
+		case '\\':
+			data = append(data, '\\', '\\')
+		case '\t':
+			data = append(data, '\\', 't')
+		case '\n':
+			data = append(data, '\\', 'n')
+		case 0:
+			data = append(data, '\\', '0')
+		default:
+			data = append(data, b)
+		}
+	}
+	return data
+}
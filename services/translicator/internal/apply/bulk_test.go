@@ -0,0 +1,52 @@
+package apply
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestAppendLoadDataValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{name: "null", value: nil, want: `\N`},
+		{name: "string with specials", value: "a\tb\nc\\d", want: `a\tb\nc\\d`},
+		{name: "literal backslash N", value: `\N`, want: `\\N`},
+		{name: "bytes", value: []byte{'x', 0, 'y'}, want: `x\0y`},
+		{name: "int", value: int64(-42), want: "-42"},
+		{name: "float", value: 0.00001, want: "0.00001"},
+		{name: "bool", value: true, want: "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := appendLoadDataValue(nil, tt.value)
+			if err != nil {
+				t.Fatalf("appendLoadDataValue() unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("appendLoadDataValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := appendLoadDataValue(nil, struct{}{}); err == nil {
+		t.Error("appendLoadDataValue() with an unsupported type should return an error")
+	}
+}
+
+func TestInsertValues(t *testing.T) {
+	fake := newFakeDB()
+	rows := [][]any{{int64(1), "a"}, {int64(2), nil}}
+	if err := insertValues(context.Background(), fake.open(), "analytics.users", []string{"id", "name"}, rows); err != nil {
+		t.Fatalf("insertValues() unexpected error: %v", err)
+	}
+
+	want := []string{"INSERT INTO analytics.users (id, name) VALUES (?, ?), (?, ?)"}
+	if got := fake.executed(); !reflect.DeepEqual(got, want) {
+		t.Errorf("executed = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,114 @@
+package apply
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// ErrorClass groups replica errors by whether applying the change again can succeed
+type ErrorClass string
+
+const (
+	// ClassDeadlock covers deadlocks and lock wait timeouts
+	ClassDeadlock ErrorClass = "deadlock"
+	// ClassSerialization covers serialization failures of concurrent transactions
+	ClassSerialization ErrorClass = "serialization"
+	// ClassConnection covers lost or refused connections and server shutdowns
+	ClassConnection ErrorClass = "connection"
+	// ClassConstraint covers constraint violations such as duplicate keys
+	ClassConstraint ErrorClass = "constraint"
+	// ClassSyntax covers invalid statements and unknown tables or columns
+	ClassSyntax ErrorClass = "syntax"
+	// ClassTimeout covers statements cancelled for running longer than their timeout
+	ClassTimeout ErrorClass = "timeout"
+	// ClassOther covers everything else
+	ClassOther ErrorClass = "other"
+)
+
+// Transient reports whether errors of the class can go away when the statement is retried
+func (c ErrorClass) Transient() bool {
+	return c == ClassDeadlock || c == ClassSerialization || c == ClassConnection
+}
+
+// ClassifiedError is a failed statement together with the class of its error
+type ClassifiedError struct {
+	Class ErrorClass
+	Err   error
+}
+
+func (e *ClassifiedError) Error() string {
+	return fmt.Sprintf("%s error: %v", e.Class, e.Err)
+}
+
+func (e *ClassifiedError) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyError determines the class of an error returned by the replica database
+func ClassifyError(err error) ErrorClass {
+	// A statement cut off by its timeout may surface as a cancellation or a lost connection
+	if errors.Is(err, ErrStatementTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return ClassTimeout
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return classifySQLState(string(pqErr.Code))
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return classifyMySQLError(mysqlErr.Number)
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) || errors.As(err, &netErr) {
+		return ClassConnection
+	}
+	return ClassOther
+}
+
+// classifySQLState classifies a PostgreSQL SQLSTATE code
+func classifySQLState(code string) ErrorClass {
+	switch {
+	case code == "40P01" || code == "55P03":
+		return ClassDeadlock
+	case code == "40001":
+		return ClassSerialization
+	case code == "57014": // query_canceled, e.g. by statement_timeout
+		return ClassTimeout
+	case strings.HasPrefix(code, "08") || code == "57P01" || code == "57P02" || code == "57P03":
+		return ClassConnection
+	case strings.HasPrefix(code, "23"):
+		return ClassConstraint
+	case strings.HasPrefix(code, "42"):
+		return ClassSyntax
+	default:
+		return ClassOther
+	}
+}
+
+// classifyMySQLError classifies a MySQL server error number
+func classifyMySQLError(number uint16) ErrorClass {
+	switch number {
+	case 1213, 1205: // ER_LOCK_DEADLOCK, ER_LOCK_WAIT_TIMEOUT
+		return ClassDeadlock
+	case 1053, 1077, 1152, 1159, 1161, 2006, 2013: // shutdown, aborted connection, network errors, server gone
+		return ClassConnection
+	case 1022, 1048, 1062, 1169, 1216, 1217, 1451, 1452, 1557, 1586, 3819: // duplicate keys, NOT NULL, foreign keys, CHECK
+		return ClassConstraint
+	case 1054, 1064, 1146, 1149: // unknown column, syntax error, unknown table
+		return ClassSyntax
+	case 1317, 1969, 3024: // query interrupted, MariaDB max_statement_time, max_execution_time
+		return ClassTimeout
+	default:
+		return ClassOther
+	}
+}
@@ -0,0 +1,61 @@
+package apply
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorClass
+	}{
+		{"postgres deadlock", &pq.Error{Code: "40P01"}, ClassDeadlock},
+		{"postgres serialization failure", &pq.Error{Code: "40001"}, ClassSerialization},
+		{"postgres admin shutdown", &pq.Error{Code: "57P01"}, ClassConnection},
+		{"postgres connection failure", &pq.Error{Code: "08006"}, ClassConnection},
+		{"postgres unique violation", &pq.Error{Code: "23505"}, ClassConstraint},
+		{"postgres undefined column", &pq.Error{Code: "42703"}, ClassSyntax},
+		{"postgres statement timeout", &pq.Error{Code: "57014"}, ClassTimeout},
+		{"postgres division by zero", &pq.Error{Code: "22012"}, ClassOther},
+		{"mysql deadlock", &mysql.MySQLError{Number: 1213}, ClassDeadlock},
+		{"mysql lock wait timeout", &mysql.MySQLError{Number: 1205}, ClassDeadlock},
+		{"mysql duplicate entry", &mysql.MySQLError{Number: 1062}, ClassConstraint},
+		{"mysql syntax error", &mysql.MySQLError{Number: 1064}, ClassSyntax},
+		{"mysql max_execution_time exceeded", &mysql.MySQLError{Number: 3024}, ClassTimeout},
+		{"mysql invalid connection", mysql.ErrInvalidConn, ClassConnection},
+		{"bad connection", driver.ErrBadConn, ClassConnection},
+		{"deadline exceeded", fmt.Errorf("exec: %w", context.DeadlineExceeded), ClassTimeout},
+		{"statement timeout over a lost connection", fmt.Errorf("%w: %w", ErrStatementTimeout, driver.ErrBadConn), ClassTimeout},
+		{"wrapped", fmt.Errorf("exec: %w", &pq.Error{Code: "40P01"}), ClassDeadlock},
+		{"unknown", errors.New("boom"), ClassOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyError(tt.err); got != tt.want {
+				t.Errorf("ClassifyError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorClassTransient(t *testing.T) {
+	for _, class := range []ErrorClass{ClassDeadlock, ClassSerialization, ClassConnection} {
+		if !class.Transient() {
+			t.Errorf("%s should be transient", class)
+		}
+	}
+	for _, class := range []ErrorClass{ClassConstraint, ClassSyntax, ClassTimeout, ClassOther} {
+		if class.Transient() {
+			t.Errorf("%s should be permanent", class)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package apply
+
+import (
+	"context"
+	dbsql "database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// fakeDB is a minimal database/sql driver that records statements and
+// answers row lookups from a fixed set of queries. Queries with arguments are
+// looked up with their arguments appended, e.g. "SELECT ... = $1 [0/100]".
+type fakeDB struct {
+	mu       sync.Mutex
+	execs    []string
+	queries  []string
+	prepares []string
+	closed   int
+	rows     map[string]bool
+	execErr  error
+	// execErrs are returned by successive execs before falling back to execErr
+	execErrs []error
+	// stmtErrs are returned by successive execs of a statement before it succeeds
+	stmtErrs map[string][]error
+	// hang makes execs block until their context ends, like a statement waiting on a lock
+	hang bool
+	// ledger, when set, holds the positions recorded in kasho_applied: lookups find
+	// them and recording one twice fails like a primary key violation
+	ledger map[string]bool
+}
+
+func newFakeDB(rows ...string) *fakeDB {
+	f := &fakeDB{rows: make(map[string]bool)}
+	for _, query := range rows {
+		f.rows[query] = true
+	}
+	return f
+}
+
+func (f *fakeDB) open() *dbsql.DB {
+	return dbsql.OpenDB(fakeConnector{f})
+}
+
+func (f *fakeDB) executed() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.execs...)
+}
+
+type fakeConnector struct{ db *fakeDB }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: c.db}, nil }
+func (c fakeConnector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return nil, errors.New("use fakeConnector") }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Close() error { return nil }
+
+// Begin starts a transaction; its statements are recorded between BEGIN and COMMIT or ROLLBACK
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.execs = append(c.db.execs, "BEGIN")
+	return fakeTx{c.db}, nil
+}
+
+type fakeTx struct{ db *fakeDB }
+
+func (tx fakeTx) Commit() error   { return tx.end("COMMIT") }
+func (tx fakeTx) Rollback() error { return tx.end("ROLLBACK") }
+
+func (tx fakeTx) end(stmt string) error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	tx.db.execs = append(tx.db.execs, stmt)
+	return nil
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.prepares = append(c.db.prepares, query)
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if c.db.hang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	if len(c.db.execErrs) > 0 {
+		err := c.db.execErrs[0]
+		c.db.execErrs = c.db.execErrs[1:]
+		return nil, err
+	}
+	if errs := c.db.stmtErrs[query]; len(errs) > 0 {
+		c.db.stmtErrs[query] = errs[1:]
+		return nil, errs[0]
+	}
+	if c.db.execErr != nil {
+		return nil, c.db.execErr
+	}
+	if c.db.ledger != nil && strings.HasPrefix(query, "INSERT INTO kasho_applied") {
+		position := fmt.Sprint(args[0].Value)
+		if c.db.ledger[position] {
+			return nil, fmt.Errorf("duplicate entry %q for key 'PRIMARY'", position)
+		}
+		c.db.ledger[position] = true
+	}
+	c.db.execs = append(c.db.execs, query)
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	for _, arg := range args {
+		query += fmt.Sprintf(" [%v]", arg.Value)
+	}
+	c.db.queries = append(c.db.queries, query)
+	if c.db.ledger != nil && strings.HasPrefix(query, "SELECT 1 FROM kasho_applied") {
+		return &fakeRows{remaining: c.db.ledger[fmt.Sprint(args[0].Value)]}, nil
+	}
+	return &fakeRows{remaining: c.db.rows[query]}, nil
+}
+
+// fakeStmt executes its query like an unprepared statement
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Close() error {
+	s.conn.db.mu.Lock()
+	defer s.conn.db.mu.Unlock()
+	s.conn.db.closed++
+	return nil
+}
+
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, nil)
+}
+
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, nil)
+}
+
+type fakeRows struct{ remaining bool }
+
+func (r *fakeRows) Columns() []string { return []string{"?column?"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if !r.remaining {
+		return io.EOF
+	}
+	r.remaining = false
+	dest[0] = int64(1)
+	return nil
+}
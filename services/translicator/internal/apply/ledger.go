@@ -0,0 +1,152 @@
+package apply
+
+import (
+	"context"
+	dbsql "database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"kasho/pkg/dialect"
+)
+
+// LedgerTable is the replica table holding the positions of changes applied in
+// exactly-once mode
+const LedgerTable = "kasho_applied"
+
+// Ledger records the position of every change applied in exactly-once mode in the
+// replica, in the same transaction as the change itself. A change delivered again,
+// e.g. after a crash between applying it and acknowledging it, is found in the
+// ledger and skipped.
+type Ledger struct {
+	db      *dbsql.DB
+	dialect dialect.Dialect
+	seq     atomic.Int64 // sequence number of the last recorded position
+}
+
+// OpenLedger creates the ledger table if it doesn't exist yet
+func OpenLedger(ctx context.Context, db *dbsql.DB, d dialect.Dialect) (*Ledger, error) {
+	// ClickHouse has no transactions to tie the ledger to the change
+	if d.Name() == "clickhouse" {
+		return nil, fmt.Errorf("exactly-once apply is not supported for %s replicas", d.Name())
+	}
+
+	l := &Ledger{db: db, dialect: d}
+	if err := l.create(ctx); err != nil {
+		return nil, fmt.Errorf("error creating %s: %w", LedgerTable, err)
+	}
+	var seq dbsql.NullInt64
+	if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT MAX(seq) FROM %s", LedgerTable)).Scan(&seq); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", LedgerTable, err)
+	}
+	l.seq.Store(seq.Int64)
+	return l, nil
+}
+
+// create creates the ledger table. Not every replica supports CREATE TABLE IF NOT
+// EXISTS, so a failing query for the table is taken to mean it's missing.
+func (l *Ledger) create(ctx context.Context) error {
+	rows, err := l.db.QueryContext(ctx, fmt.Sprintf("SELECT seq FROM %s WHERE 1 = 0", LedgerTable))
+	if err == nil {
+		return rows.Close()
+	}
+
+	seqType := "BIGINT"
+	if l.dialect.Name() == "oracle" {
+		seqType = "NUMBER(19)"
+	}
+	_, err = l.db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s (position VARCHAR(255) NOT NULL PRIMARY KEY, seq %s NOT NULL)",
+		LedgerTable, seqType))
+	return err
+}
+
+// LastPosition returns the position of the most recently applied change, or "" if
+// the ledger is empty
+func (l *Ledger) LastPosition(ctx context.Context) (string, error) {
+	seq := l.seq.Load()
+	if seq == 0 {
+		return "", nil
+	}
+	var position string
+	query := fmt.Sprintf("SELECT position FROM %s WHERE seq = %d", LedgerTable, seq)
+	if err := l.db.QueryRowContext(ctx, query).Scan(&position); err != nil {
+		return "", fmt.Errorf("error reading %s: %w", LedgerTable, err)
+	}
+	return position, nil
+}
+
+// Applied reports whether the change at a position was applied
+func (l *Ledger) Applied(ctx context.Context, position string) (bool, error) {
+	var found int
+	query := fmt.Sprintf("SELECT 1 FROM %s WHERE position = %s", LedgerTable, l.dialect.Placeholder(1))
+	err := l.db.QueryRowContext(ctx, query, position).Scan(&found)
+	if errors.Is(err, dbsql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// record runs the statements of changes and records their positions in one transaction
+func (l *Ledger) record(ctx context.Context, positions []string, run func(ctx context.Context, tx *dbsql.Tx) error) error {
+	seq := l.seq.Load()
+	err := inTx(ctx, l.db, func(ctx context.Context, tx *dbsql.Tx) error {
+		if err := run(ctx, tx); err != nil {
+			return err
+		}
+		query := fmt.Sprintf("INSERT INTO %s (position, seq) VALUES (%s, %s)",
+			LedgerTable, l.dialect.Placeholder(1), l.dialect.Placeholder(2))
+		for _, position := range positions {
+			seq++
+			if _, err := tx.ExecContext(ctx, query, position, seq); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	l.seq.Store(seq)
+	return nil
+}
+
+// recordsDDLSeparately reports whether DDL commits the transaction it runs in, as on
+// MySQL and Oracle. Its position can't be recorded atomically with it there, so the DDL
+// is applied on its own and recorded after it commits; a crash in between applies it
+// again when it's redelivered.
+func (l *Ledger) recordsDDLSeparately() bool {
+	switch l.dialect.Name() {
+	case "mysql", "oracle":
+		return true
+	}
+	return false
+}
+
+// inTx runs statements in a transaction and commits it if they succeed
+func inTx(ctx context.Context, db *dbsql.DB, run func(ctx context.Context, tx *dbsql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := run(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Prune deletes all but the keep most recent positions and returns how many were
+// deleted. Changes are redelivered from the consumer's last position, so only
+// recent positions are ever looked up again.
+func (l *Ledger) Prune(ctx context.Context, keep int64) (int64, error) {
+	oldest := l.seq.Load() - keep
+	if oldest <= 0 {
+		return 0, nil
+	}
+	result, err := l.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE seq <= %d", LedgerTable, oldest))
+	if err != nil {
+		return 0, fmt.Errorf("error pruning %s: %w", LedgerTable, err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,241 @@
+package apply
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"kasho/pkg/dialect"
+	"kasho/proto"
+	"translicator/internal/sql"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	maxSeqSQL     = "SELECT MAX(seq) FROM kasho_applied"
+	ledgerInsert  = "INSERT INTO kasho_applied (position, seq) VALUES ($1, $2)"
+	ledgerLookup  = "SELECT 1 FROM kasho_applied WHERE position = $1"
+	ledgerRowsSQL = "SELECT seq FROM kasho_applied WHERE 1 = 0"
+)
+
+func TestApply_Ledger(t *testing.T) {
+	fake := newFakeDB(maxSeqSQL, ledgerLookup+" [0/50]")
+	db := fake.open()
+	defer db.Close()
+	ctx := context.Background()
+
+	ledger, err := OpenLedger(ctx, db, dialect.NewPostgreSQL())
+	if err != nil {
+		t.Fatalf("OpenLedger() unexpected error: %v", err)
+	}
+	a := NewApplier(db, sql.NewSQLGenerator(dialect.NewPostgreSQL()))
+	a.SetLedger(ledger)
+
+	// The change and its position are committed together
+	if _, err := a.Apply(ctx, updateChange()); err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	want := []string{"BEGIN", updateSQL, ledgerInsert, "COMMIT"}
+	if got := fake.executed(); !reflect.DeepEqual(got, want) {
+		t.Errorf("executed = %v, want %v", got, want)
+	}
+	if seq := ledger.seq.Load(); seq != 2 {
+		t.Errorf("ledger sequence = %d, want 2", seq)
+	}
+
+	// A change already in the ledger is skipped
+	duplicate := updateChange()
+	duplicate.Position = "0/50"
+	if _, err := a.Apply(ctx, duplicate); !errors.Is(err, ErrSkipped) {
+		t.Errorf("Apply() of an applied change error = %v, want ErrSkipped", err)
+	}
+	if got := fake.executed(); len(got) != len(want) {
+		t.Errorf("executed = %v, want nothing more executed", got)
+	}
+}
+
+func TestApply_LedgerRollsBackFailedChange(t *testing.T) {
+	fake := newFakeDB(maxSeqSQL)
+	db := fake.open()
+	defer db.Close()
+	ctx := context.Background()
+
+	ledger, err := OpenLedger(ctx, db, dialect.NewPostgreSQL())
+	if err != nil {
+		t.Fatalf("OpenLedger() unexpected error: %v", err)
+	}
+	a := NewApplier(db, sql.NewSQLGenerator(dialect.NewPostgreSQL()))
+	a.SetLedger(ledger)
+
+	fake.execErrs = []error{errors.New("syntax error")}
+	if _, err := a.Apply(ctx, updateChange()); err == nil {
+		t.Fatal("Apply() expected error")
+	}
+	want := []string{"BEGIN", "ROLLBACK"}
+	if got := fake.executed(); !reflect.DeepEqual(got, want) {
+		t.Errorf("executed = %v, want %v", got, want)
+	}
+	if seq := ledger.seq.Load(); seq != 1 {
+		t.Errorf("ledger sequence = %d, want 1", seq)
+	}
+}
+
+func TestOpenLedger(t *testing.T) {
+	fake := newFakeDB(maxSeqSQL)
+	db := fake.open()
+	defer db.Close()
+
+	if _, err := OpenLedger(context.Background(), db, dialect.NewPostgreSQL()); err != nil {
+		t.Fatalf("OpenLedger() unexpected error: %v", err)
+	}
+	// The table exists, so it isn't created
+	if got := fake.executed(); len(got) != 0 {
+		t.Errorf("executed = %v, want nothing", got)
+	}
+	if len(fake.queries) == 0 || fake.queries[0] != ledgerRowsSQL {
+		t.Errorf("queries = %v, want the table checked first", fake.queries)
+	}
+
+	if _, err := OpenLedger(context.Background(), db, dialect.NewClickHouse()); err == nil {
+		t.Error("OpenLedger() should reject ClickHouse replicas")
+	}
+}
+
+func TestLedger_Prune(t *testing.T) {
+	fake := newFakeDB()
+	db := fake.open()
+	defer db.Close()
+
+	ledger := &Ledger{db: db, dialect: dialect.NewPostgreSQL()}
+	ledger.seq.Store(10)
+	if _, err := ledger.Prune(context.Background(), 100); err != nil {
+		t.Fatalf("Prune() unexpected error: %v", err)
+	}
+	if got := fake.executed(); len(got) != 0 {
+		t.Errorf("executed = %v, want nothing pruned", got)
+	}
+
+	if _, err := ledger.Prune(context.Background(), 4); err != nil {
+		t.Fatalf("Prune() unexpected error: %v", err)
+	}
+	want := []string{"DELETE FROM kasho_applied WHERE seq <= 6"}
+	if got := fake.executed(); !reflect.DeepEqual(got, want) {
+		t.Errorf("executed = %v, want %v", got, want)
+	}
+}
+
+func TestApply_LedgerRecordsMySQLDDLAfterCommit(t *testing.T) {
+	fake := newFakeDB(maxSeqSQL)
+	db := fake.open()
+	defer db.Close()
+	ctx := context.Background()
+
+	ledger, err := OpenLedger(ctx, db, dialect.NewMySQL())
+	if err != nil {
+		t.Fatalf("OpenLedger() unexpected error: %v", err)
+	}
+	a := NewApplier(db, sql.NewSQLGenerator(dialect.NewMySQL()))
+	a.SetLedger(ledger)
+	a.sleep = func(context.Context, time.Duration) error { return nil }
+
+	// MySQL commits DDL implicitly, so recording it is a transaction of its own. A
+	// failed record is retried without running the DDL again.
+	const mysqlLedgerInsert = "INSERT INTO kasho_applied (position, seq) VALUES (?, ?)"
+	fake.stmtErrs = map[string][]error{mysqlLedgerInsert: {&mysql.MySQLError{Number: 1213}}}
+	ddl := &proto.Change{Position: "mysql-bin.000001:400", Data: &proto.Change_Ddl{Ddl: &proto.DDLData{Ddl: "ALTER TABLE users ADD COLUMN age int"}}}
+	stmt, err := a.Apply(ctx, ddl)
+	if err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	want := []string{stmt, "BEGIN", "ROLLBACK", "BEGIN", mysqlLedgerInsert, "COMMIT"}
+	if got := fake.executed(); !reflect.DeepEqual(got, want) {
+		t.Errorf("executed = %v, want %v", got, want)
+	}
+	if seq := ledger.seq.Load(); seq != 2 {
+		t.Errorf("ledger sequence = %d, want 2", seq)
+	}
+
+	// DML is still recorded in its own transaction
+	if _, err := a.Apply(ctx, updateChange()); err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	if got := fake.executed()[len(want):]; len(got) != 4 || got[0] != "BEGIN" || got[2] != mysqlLedgerInsert || got[3] != "COMMIT" {
+		t.Errorf("executed = %v, want the update and its position in one transaction", got)
+	}
+}
+
+func TestApply_LedgerAppliesEveryRowOfARowsEvent(t *testing.T) {
+	// A MySQL rows event carrying three rows yields one position per row
+	positions := []string{"mysql-bin.000001:1234:1", "mysql-bin.000001:1234:2", "mysql-bin.000001:1234:3"}
+	const mysqlLedgerInsert = "INSERT INTO kasho_applied (position, seq) VALUES (?, ?)"
+
+	countInserts := func(executed []string) int {
+		n := 0
+		for _, stmt := range executed {
+			if stmt == mysqlLedgerInsert {
+				n++
+			}
+		}
+		return n
+	}
+
+	t.Run("one transaction per change", func(t *testing.T) {
+		fake := newFakeDB(maxSeqSQL)
+		fake.ledger = make(map[string]bool)
+		db := fake.open()
+		defer db.Close()
+		ctx := context.Background()
+
+		ledger, err := OpenLedger(ctx, db, dialect.NewMySQL())
+		if err != nil {
+			t.Fatalf("OpenLedger() unexpected error: %v", err)
+		}
+		a := NewApplier(db, sql.NewSQLGenerator(dialect.NewMySQL()))
+		a.SetLedger(ledger)
+
+		for i, position := range positions {
+			if _, err := a.Apply(ctx, txnInsert(position, "", "users", int64(i+1))); err != nil {
+				t.Fatalf("Apply(%s) unexpected error: %v", position, err)
+			}
+		}
+		if got := countInserts(fake.executed()); got != len(positions) {
+			t.Errorf("recorded %d positions, want %d", got, len(positions))
+		}
+	})
+
+	t.Run("grouped transaction", func(t *testing.T) {
+		fake := newFakeDB(maxSeqSQL)
+		fake.ledger = make(map[string]bool)
+		db := fake.open()
+		defer db.Close()
+		ctx := context.Background()
+
+		ledger, err := OpenLedger(ctx, db, dialect.NewMySQL())
+		if err != nil {
+			t.Fatalf("OpenLedger() unexpected error: %v", err)
+		}
+		a := NewApplier(db, sql.NewSQLGenerator(dialect.NewMySQL()))
+		a.SetLedger(ledger)
+		g, err := NewTxGroup(a, "mysql")
+		if err != nil {
+			t.Fatalf("NewTxGroup() unexpected error: %v", err)
+		}
+		for i, position := range positions {
+			g.Add(txnInsert(position, "900", "users", int64(i+1)))
+		}
+
+		stmts, err := g.Apply(ctx)
+		if err != nil {
+			t.Fatalf("Apply() unexpected error: %v", err)
+		}
+		if len(stmts) != len(positions) {
+			t.Errorf("Apply() executed %d changes, want %d", len(stmts), len(positions))
+		}
+		if got := countInserts(fake.executed()); got != len(positions) {
+			t.Errorf("recorded %d positions, want %d", got, len(positions))
+		}
+	})
+}
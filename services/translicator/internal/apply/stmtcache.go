@@ -0,0 +1,74 @@
+package apply
+
+import (
+	"container/list"
+	"context"
+	dbsql "database/sql"
+	"log"
+
+	"translicator/internal/metrics"
+)
+
+// stmtCache keeps prepared statements by query, so that changes of the same shape are
+// parsed and planned by the replica only once. The least recently used statement is
+// closed when the cache is full.
+type stmtCache struct {
+	db       *dbsql.DB
+	capacity int
+	order    *list.List // of *cachedStmt, most recently used first
+	entries  map[string]*list.Element
+}
+
+type cachedStmt struct {
+	query string
+	stmt  *dbsql.Stmt
+}
+
+func newStmtCache(db *dbsql.DB, capacity int) *stmtCache {
+	return &stmtCache{
+		db:       db,
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// get returns the prepared statement for a query, preparing it if needed
+func (c *stmtCache) get(ctx context.Context, query string) (*dbsql.Stmt, error) {
+	if elem, ok := c.entries[query]; ok {
+		metrics.PreparedStatements.Add("hit", 1)
+		c.order.MoveToFront(elem)
+		return elem.Value.(*cachedStmt).stmt, nil
+	}
+
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	metrics.PreparedStatements.Add("miss", 1)
+	c.entries[query] = c.order.PushFront(&cachedStmt{query: query, stmt: stmt})
+
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Remove(c.order.Back()).(*cachedStmt)
+		delete(c.entries, oldest.query)
+		c.close(oldest)
+		metrics.PreparedStatements.Add("evict", 1)
+	}
+	return stmt, nil
+}
+
+// clear closes all prepared statements, e.g. after a schema change that may have
+// invalidated them
+func (c *stmtCache) clear() {
+	for elem := c.order.Front(); elem != nil; elem = elem.Next() {
+		c.close(elem.Value.(*cachedStmt))
+	}
+	c.order.Init()
+	clear(c.entries)
+}
+
+func (c *stmtCache) close(cached *cachedStmt) {
+	if err := cached.stmt.Close(); err != nil {
+		log.Printf("Warning: failed to close prepared statement: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package apply
+
+import (
+	"context"
+	dbsql "database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"kasho/proto"
+	"translicator/internal/metrics"
+)
+
+// ErrStatementTimeout is returned when a statement runs longer than its timeout
+var ErrStatementTimeout = errors.New("statement timed out")
+
+// TimeoutKinds are the change kinds a statement timeout can be set for
+var TimeoutKinds = []string{"insert", "update", "delete", "ddl"}
+
+// StatementTimeouts limits how long the statement of a change may run on the replica
+type StatementTimeouts struct {
+	// Default applies to kinds without a timeout of their own; 0 means no limit
+	Default time.Duration
+	// Kinds holds the timeouts of single kinds, see TimeoutKinds
+	Kinds map[string]time.Duration
+}
+
+// ParseStatementTimeouts parses a default timeout and comma-separated kind=duration
+// overrides, e.g. "30s" and "ddl=10m,delete=1m". Either may be empty.
+func ParseStatementTimeouts(defaultTimeout, kinds string) (StatementTimeouts, error) {
+	var timeouts StatementTimeouts
+	if defaultTimeout != "" {
+		d, err := time.ParseDuration(defaultTimeout)
+		if err != nil || d < 0 {
+			return StatementTimeouts{}, fmt.Errorf("invalid timeout %q", defaultTimeout)
+		}
+		timeouts.Default = d
+	}
+	for _, item := range strings.Split(kinds, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		kind, value, ok := strings.Cut(item, "=")
+		kind = strings.TrimSpace(kind)
+		if !ok || !slices.Contains(TimeoutKinds, kind) {
+			return StatementTimeouts{}, fmt.Errorf("invalid timeout %q (expected kind=duration, with kind one of %s)", item, strings.Join(TimeoutKinds, ", "))
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || d < 0 {
+			return StatementTimeouts{}, fmt.Errorf("invalid timeout %q for %s", value, kind)
+		}
+		if timeouts.Kinds == nil {
+			timeouts.Kinds = make(map[string]time.Duration)
+		}
+		timeouts.Kinds[kind] = d
+	}
+	return timeouts, nil
+}
+
+// Enabled reports whether any kind has a timeout
+func (t StatementTimeouts) Enabled() bool {
+	for _, kind := range TimeoutKinds {
+		if t.forKind(kind) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Longest returns the longest timeout of any kind, or 0 if some kind has no limit
+func (t StatementTimeouts) Longest() time.Duration {
+	var longest time.Duration
+	for _, kind := range TimeoutKinds {
+		d := t.forKind(kind)
+		if d == 0 {
+			return 0
+		}
+		longest = max(longest, d)
+	}
+	return longest
+}
+
+// For returns the timeout of the statement of a change, 0 if it has none
+func (t StatementTimeouts) For(change *proto.Change) time.Duration {
+	return t.forKind(changeKind(change))
+}
+
+func (t StatementTimeouts) forKind(kind string) time.Duration {
+	if d, ok := t.Kinds[kind]; ok {
+		return d
+	}
+	return t.Default
+}
+
+// changeKind returns the kind of a change: the DML kind, or "ddl"
+func changeKind(change *proto.Change) string {
+	if change.GetDdl() != nil {
+		return "ddl"
+	}
+	return change.GetDml().GetKind()
+}
+
+// withTimeout bounds each attempt at a change's statement by its timeout. A statement
+// cut off by it fails with ErrStatementTimeout, whatever error the driver returned.
+func (a *Applier) withTimeout(change *proto.Change, run func(ctx context.Context, tx *dbsql.Tx) error) func(ctx context.Context, tx *dbsql.Tx) error {
+	timeout := a.timeouts.For(change)
+	if timeout <= 0 {
+		return run
+	}
+	kind := changeKind(change)
+	return func(ctx context.Context, tx *dbsql.Tx) error {
+		stmtCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		err := run(stmtCtx, tx)
+		if err != nil && ctx.Err() == nil && errors.Is(stmtCtx.Err(), context.DeadlineExceeded) {
+			metrics.ApplyTimeouts.Add(kind, 1)
+			return fmt.Errorf("%w after %v: %w", ErrStatementTimeout, timeout, err)
+		}
+		return err
+	}
+}
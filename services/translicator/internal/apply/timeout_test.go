@@ -0,0 +1,99 @@
+package apply
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"kasho/pkg/dialect"
+	"kasho/proto"
+	"translicator/internal/sql"
+)
+
+func TestParseStatementTimeouts(t *testing.T) {
+	tests := []struct {
+		name           string
+		defaultTimeout string
+		kinds          string
+		want           StatementTimeouts
+		wantErr        bool
+	}{
+		{name: "none"},
+		{name: "default only", defaultTimeout: "30s", want: StatementTimeouts{Default: 30 * time.Second}},
+		{
+			name:           "kinds",
+			defaultTimeout: "30s",
+			kinds:          "ddl=10m, delete = 1m",
+			want: StatementTimeouts{
+				Default: 30 * time.Second,
+				Kinds:   map[string]time.Duration{"ddl": 10 * time.Minute, "delete": time.Minute},
+			},
+		},
+		{name: "no limit for a kind", kinds: "ddl=0", want: StatementTimeouts{Kinds: map[string]time.Duration{"ddl": 0}}},
+		{name: "invalid default", defaultTimeout: "soon", wantErr: true},
+		{name: "negative default", defaultTimeout: "-1s", wantErr: true},
+		{name: "unknown kind", kinds: "truncate=1s", wantErr: true},
+		{name: "missing duration", kinds: "ddl", wantErr: true},
+		{name: "invalid duration", kinds: "ddl=long", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStatementTimeouts(tt.defaultTimeout, tt.kinds)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStatementTimeouts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseStatementTimeouts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatementTimeouts(t *testing.T) {
+	timeouts := StatementTimeouts{Default: 5 * time.Second, Kinds: map[string]time.Duration{"ddl": time.Minute}}
+	ddl := &proto.Change{Data: &proto.Change_Ddl{Ddl: &proto.DDLData{Ddl: "CREATE TABLE t (id int)"}}}
+	if got := timeouts.For(ddl); got != time.Minute {
+		t.Errorf("For(ddl) = %v, want 1m", got)
+	}
+	if got := timeouts.For(updateChange()); got != 5*time.Second {
+		t.Errorf("For(update) = %v, want 5s", got)
+	}
+	if !timeouts.Enabled() || timeouts.Longest() != time.Minute {
+		t.Errorf("Enabled() = %v, Longest() = %v, want true and 1m", timeouts.Enabled(), timeouts.Longest())
+	}
+
+	// Without a default, the kinds left out have no limit
+	timeouts = StatementTimeouts{Kinds: map[string]time.Duration{"ddl": time.Minute}}
+	if got := timeouts.For(updateChange()); got != 0 {
+		t.Errorf("For(update) = %v, want no limit", got)
+	}
+	if !timeouts.Enabled() || timeouts.Longest() != 0 {
+		t.Errorf("Enabled() = %v, Longest() = %v, want true and no limit", timeouts.Enabled(), timeouts.Longest())
+	}
+	if (StatementTimeouts{}).Enabled() {
+		t.Error("Enabled() = true without timeouts")
+	}
+}
+
+func TestApply_StatementTimeout(t *testing.T) {
+	fake := newFakeDB()
+	fake.hang = true
+	db := fake.open()
+	defer db.Close()
+
+	a := NewApplier(db, sql.NewSQLGenerator(dialect.NewPostgreSQL()))
+	a.SetStatementTimeouts(StatementTimeouts{Kinds: map[string]time.Duration{"update": 10 * time.Millisecond}})
+	a.sleep = func(context.Context, time.Duration) error {
+		t.Error("timed out statements should not be retried")
+		return nil
+	}
+
+	_, err := a.Apply(context.Background(), updateChange())
+	var classified *ClassifiedError
+	if !errors.As(err, &classified) || classified.Class != ClassTimeout || !errors.Is(err, ErrStatementTimeout) {
+		t.Fatalf("Apply() error = %v, want a timeout ClassifiedError", err)
+	}
+}
@@ -0,0 +1,216 @@
+package apply
+
+import (
+	"context"
+	dbsql "database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"kasho/proto"
+	"translicator/internal/metrics"
+	"translicator/internal/sql"
+)
+
+// TxGroup collects the consecutive DML changes of one source transaction and applies
+// them in one replica transaction with foreign key checks deferred to its commit, so
+// a transaction that writes a child row before its parent, or deletes a parent before
+// its children, applies on a replica that enforces foreign keys.
+type TxGroup struct {
+	applier *Applier
+	// before defers foreign key checks at the start of the replica transaction, after
+	// restores them before it commits
+	before, after []string
+	// savepoint, rollbackTo and release wrap each change in a savepoint when savepoints
+	// are enabled; release is empty if savepoints don't need releasing
+	savepoint, rollbackTo, release string
+	savepoints                     bool
+
+	id      string
+	changes []*proto.Change
+}
+
+// NewTxGroup creates a transaction group that applies changes with the given applier
+// on a replica of the given dialect
+func NewTxGroup(applier *Applier, dialectName string) (*TxGroup, error) {
+	g := &TxGroup{
+		applier:    applier,
+		savepoint:  "SAVEPOINT kasho_change",
+		rollbackTo: "ROLLBACK TO SAVEPOINT kasho_change",
+		release:    "RELEASE SAVEPOINT kasho_change",
+	}
+	switch dialectName {
+	case "postgresql", "oracle":
+		// Only constraints declared DEFERRABLE are deferred
+		g.before = []string{"SET CONSTRAINTS ALL DEFERRED"}
+		if dialectName == "oracle" {
+			// Oracle has no RELEASE; a savepoint replaces an earlier one of the same name
+			g.release = ""
+		}
+	case "mysql":
+		// MySQL can't defer foreign key checks, so they are skipped for the transaction
+		g.before = []string{"SET @kasho_foreign_key_checks = @@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS = 0"}
+		g.after = []string{"SET FOREIGN_KEY_CHECKS = @kasho_foreign_key_checks"}
+	case "sqlite":
+		g.before = []string{"PRAGMA defer_foreign_keys = ON"}
+	default:
+		return nil, fmt.Errorf("deferring foreign key checks is not supported for %s replicas", dialectName)
+	}
+	return g, nil
+}
+
+// SetSavepoints sets whether each change after the first is applied in a savepoint, so a
+// change failing with a deadlock or lock wait timeout is retried on its own instead of
+// rolling back and retrying the whole transaction
+func (g *TxGroup) SetSavepoints(enabled bool) {
+	g.savepoints = enabled
+}
+
+// Add adds a transformed change to the group and reports whether it was added. Only
+// DML changes with a transaction ID are grouped; a change of another transaction than
+// the pending one, or anything else, is not added, and the pending group must be
+// applied before it.
+func (g *TxGroup) Add(change *proto.Change) bool {
+	if change.GetDml() == nil || change.TransactionId == "" {
+		return false
+	}
+	if len(g.changes) > 0 && change.TransactionId != g.id {
+		return false
+	}
+	g.id = change.TransactionId
+	g.changes = append(g.changes, change)
+	return true
+}
+
+// Pending returns the changes in the group, in the order they were added
+func (g *TxGroup) Pending() []*proto.Change {
+	return g.changes
+}
+
+// Apply applies the group in one transaction and empties it, returning the statements
+// that were executed. Changes already in the exactly-once ledger or without anything
+// to update are left out. If applying fails nothing is written and the changes stay
+// pending, to be applied one at a time.
+func (g *TxGroup) Apply(ctx context.Context) ([]string, error) {
+	if len(g.changes) == 0 {
+		return nil, nil
+	}
+	a := g.applier
+
+	var (
+		stmts     []string
+		positions []string
+		runs      []func(ctx context.Context, tx *dbsql.Tx) error
+	)
+	for _, change := range g.changes {
+		if a.ledger != nil {
+			applied, err := a.ledger.Applied(ctx, change.Position)
+			if err != nil {
+				return nil, fmt.Errorf("error checking ledger: %w", &ClassifiedError{Class: ClassifyError(err), Err: err})
+			}
+			if applied {
+				continue
+			}
+		}
+
+		stmt, err := a.generator.ToSQL(change)
+		if errors.Is(err, sql.ErrNoChanges) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error generating SQL at %s: %w", change.Position, err)
+		}
+		run, err := a.execFunc(change, stmt)
+		if err != nil {
+			return nil, fmt.Errorf("error generating SQL at %s: %w", change.Position, err)
+		}
+		stmts = append(stmts, stmt)
+		positions = append(positions, change.Position)
+		runs = append(runs, run)
+	}
+	if len(runs) == 0 {
+		g.Drop(len(g.changes))
+		return nil, nil
+	}
+
+	run := func(ctx context.Context, tx *dbsql.Tx) error {
+		for _, stmt := range g.before {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		for i, run := range runs {
+			// Nothing before the first change is worth keeping
+			if g.savepoints && i > 0 {
+				run = g.inSavepoint(run)
+			}
+			if err := run(ctx, tx); err != nil {
+				return err
+			}
+		}
+		for _, stmt := range g.after {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	execute := func(ctx context.Context) error { return inTx(ctx, a.db, run) }
+	if a.ledger != nil {
+		execute = func(ctx context.Context) error { return a.ledger.record(ctx, positions, run) }
+	}
+	if err := a.exec(ctx, execute); err != nil {
+		return stmts, fmt.Errorf("error executing transaction %s: %w", g.id, err)
+	}
+	g.Drop(len(g.changes))
+	return stmts, nil
+}
+
+// inSavepoint wraps a change of the transaction in a savepoint. A deadlock or lock wait
+// timeout is retried with backoff after rolling back to the savepoint, which keeps the
+// changes before it. Other errors, and ones that outlast the retries, fail the
+// transaction, which the applier then retries as a whole if the error is transient.
+func (g *TxGroup) inSavepoint(run func(ctx context.Context, tx *dbsql.Tx) error) func(ctx context.Context, tx *dbsql.Tx) error {
+	a := g.applier
+	return func(ctx context.Context, tx *dbsql.Tx) error {
+		if _, err := tx.ExecContext(ctx, g.savepoint); err != nil {
+			return err
+		}
+		backoff := a.retryBackoff
+		for attempt := 1; ; attempt++ {
+			err := run(ctx, tx)
+			if err == nil {
+				if g.release != "" {
+					_, err = tx.ExecContext(ctx, g.release)
+				}
+				return err
+			}
+
+			class := ClassifyError(err)
+			if class != ClassDeadlock || attempt >= a.maxAttempts || ctx.Err() != nil {
+				return err
+			}
+			// Rolling back keeps the savepoint for the next attempt. MySQL rolls back the
+			// whole transaction on a deadlock, savepoint included.
+			if _, rollbackErr := tx.ExecContext(ctx, g.rollbackTo); rollbackErr != nil {
+				return err
+			}
+
+			metrics.ApplyErrors.Add(string(class), 1)
+			log.Printf("Transient %s error in transaction %s (attempt %d of %d), retrying from savepoint in %v: %v", class, g.id, attempt, a.maxAttempts, backoff, err)
+			metrics.ApplyRetries.Add(1)
+			if err := a.sleep(ctx, backoff); err != nil {
+				return err
+			}
+			backoff = min(backoff*2, maxRetryBackoff)
+		}
+	}
+}
+
+// Drop removes the first n pending changes, e.g. once they were applied one at a time
+func (g *TxGroup) Drop(n int) {
+	g.changes = g.changes[n:]
+	if len(g.changes) == 0 {
+		g.changes, g.id = nil, ""
+	}
+}
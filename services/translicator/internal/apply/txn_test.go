@@ -0,0 +1,186 @@
+package apply
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"kasho/pkg/dialect"
+	"kasho/proto"
+	"translicator/internal/sql"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func txnInsert(position, txid, table string, id int64) *proto.Change {
+	return &proto.Change{
+		Position:      position,
+		TransactionId: txid,
+		Data: &proto.Change_Dml{Dml: &proto.DMLData{
+			Table:        table,
+			Kind:         "insert",
+			ColumnNames:  []string{"id"},
+			ColumnValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_IntValue{IntValue: id}}},
+		}},
+	}
+}
+
+func TestTxGroup_Add(t *testing.T) {
+	g, err := NewTxGroup(NewApplier(nil, sql.NewSQLGenerator(dialect.NewPostgreSQL())), "postgresql")
+	if err != nil {
+		t.Fatalf("NewTxGroup() unexpected error: %v", err)
+	}
+
+	if g.Add(txnInsert("0/1", "", "orders", 1)) {
+		t.Error("Add() grouped a change without a transaction")
+	}
+	if !g.Add(txnInsert("0/2", "900", "order_items", 1)) || !g.Add(txnInsert("0/3", "900", "orders", 1)) {
+		t.Fatal("Add() rejected a change of the pending transaction")
+	}
+	if g.Add(txnInsert("0/4", "901", "orders", 2)) {
+		t.Error("Add() grouped a change of another transaction")
+	}
+	ddl := &proto.Change{Position: "0/5", TransactionId: "900", Data: &proto.Change_Ddl{Ddl: &proto.DDLData{Ddl: "ALTER TABLE orders ADD note text"}}}
+	if g.Add(ddl) {
+		t.Error("Add() grouped a DDL change")
+	}
+	if len(g.Pending()) != 2 {
+		t.Errorf("Pending() = %d changes, want 2", len(g.Pending()))
+	}
+
+	if _, err := NewTxGroup(nil, "clickhouse"); err == nil {
+		t.Error("NewTxGroup() should reject ClickHouse replicas")
+	}
+}
+
+func TestTxGroup_Apply(t *testing.T) {
+	fake := newFakeDB()
+	db := fake.open()
+	defer db.Close()
+
+	g, err := NewTxGroup(NewApplier(db, sql.NewSQLGenerator(dialect.NewPostgreSQL())), "postgresql")
+	if err != nil {
+		t.Fatalf("NewTxGroup() unexpected error: %v", err)
+	}
+	// The child row comes first, as the source transaction wrote it
+	g.Add(txnInsert("0/2", "900", "order_items", 7))
+	g.Add(txnInsert("0/3", "900", "orders", 7))
+
+	stmts, err := g.Apply(context.Background())
+	if err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	want := []string{"BEGIN", "SET CONSTRAINTS ALL DEFERRED", stmts[0], stmts[1], "COMMIT"}
+	if got := fake.executed(); !reflect.DeepEqual(got, want) {
+		t.Errorf("executed = %v, want %v", got, want)
+	}
+	if len(g.Pending()) != 0 {
+		t.Errorf("Pending() = %d changes after Apply(), want none", len(g.Pending()))
+	}
+}
+
+func TestTxGroup_ApplyFailureKeepsChanges(t *testing.T) {
+	fake := newFakeDB()
+	db := fake.open()
+	defer db.Close()
+
+	g, err := NewTxGroup(NewApplier(db, sql.NewSQLGenerator(dialect.NewMySQL())), "mysql")
+	if err != nil {
+		t.Fatalf("NewTxGroup() unexpected error: %v", err)
+	}
+	g.Add(txnInsert("0/2", "900", "order_items", 7))
+	g.Add(txnInsert("0/3", "900", "orders", 7))
+
+	fake.execErrs = []error{errors.New("syntax error")}
+	if _, err := g.Apply(context.Background()); err == nil {
+		t.Fatal("Apply() expected error")
+	}
+	if want := []string{"BEGIN", "ROLLBACK"}; !reflect.DeepEqual(fake.executed(), want) {
+		t.Errorf("executed = %v, want %v", fake.executed(), want)
+	}
+	if len(g.Pending()) != 2 {
+		t.Errorf("Pending() = %d changes after a failed Apply(), want 2", len(g.Pending()))
+	}
+}
+
+func TestTxGroup_SavepointRetriesChange(t *testing.T) {
+	fake := newFakeDB()
+	db := fake.open()
+	defer db.Close()
+
+	generator := sql.NewSQLGenerator(dialect.NewPostgreSQL())
+	a := NewApplier(db, generator)
+	a.sleep = func(context.Context, time.Duration) error { return nil }
+	g, err := NewTxGroup(a, "postgresql")
+	if err != nil {
+		t.Fatalf("NewTxGroup() unexpected error: %v", err)
+	}
+	g.SetSavepoints(true)
+	var stmts []string
+	for i, table := range []string{"orders", "order_items", "payments"} {
+		change := txnInsert(fmt.Sprintf("0/%d", i+2), "900", table, 7)
+		stmt, err := generator.ToSQL(change)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g.Add(change)
+		stmts = append(stmts, stmt)
+	}
+
+	// The second change deadlocks once and is retried without the first
+	fake.stmtErrs = map[string][]error{stmts[1]: {&pq.Error{Code: "40P01"}}}
+	if _, err := g.Apply(context.Background()); err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	want := []string{
+		"BEGIN", "SET CONSTRAINTS ALL DEFERRED", stmts[0],
+		"SAVEPOINT kasho_change", "ROLLBACK TO SAVEPOINT kasho_change", stmts[1], "RELEASE SAVEPOINT kasho_change",
+		"SAVEPOINT kasho_change", stmts[2], "RELEASE SAVEPOINT kasho_change",
+		"COMMIT",
+	}
+	if got := fake.executed(); !reflect.DeepEqual(got, want) {
+		t.Errorf("executed = %v, want %v", got, want)
+	}
+}
+
+func TestTxGroup_SavepointFallsBackToTransactionRetry(t *testing.T) {
+	fake := newFakeDB()
+	db := fake.open()
+	defer db.Close()
+
+	generator := sql.NewSQLGenerator(dialect.NewMySQL())
+	a := NewApplier(db, generator)
+	a.sleep = func(context.Context, time.Duration) error { return nil }
+	g, err := NewTxGroup(a, "mysql")
+	if err != nil {
+		t.Fatalf("NewTxGroup() unexpected error: %v", err)
+	}
+	g.SetSavepoints(true)
+	first, second := txnInsert("0/2", "900", "orders", 7), txnInsert("0/3", "900", "order_items", 7)
+	g.Add(first)
+	g.Add(second)
+	stmt0, _ := generator.ToSQL(first)
+	stmt1, _ := generator.ToSQL(second)
+
+	// A MySQL deadlock rolls back the whole transaction, so the savepoint is gone
+	fake.stmtErrs = map[string][]error{
+		stmt1:                                {&mysql.MySQLError{Number: 1213}},
+		"ROLLBACK TO SAVEPOINT kasho_change": {&mysql.MySQLError{Number: 1305}},
+	}
+	if _, err := g.Apply(context.Background()); err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	before := "SET @kasho_foreign_key_checks = @@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS = 0"
+	after := "SET FOREIGN_KEY_CHECKS = @kasho_foreign_key_checks"
+	want := []string{
+		"BEGIN", before, stmt0, "SAVEPOINT kasho_change", "ROLLBACK",
+		"BEGIN", before, stmt0, "SAVEPOINT kasho_change", stmt1, "RELEASE SAVEPOINT kasho_change", after, "COMMIT",
+	}
+	if got := fake.executed(); !reflect.DeepEqual(got, want) {
+		t.Errorf("executed = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,181 @@
+package approval
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Kind is what an item holds
+type Kind string
+
+const (
+	KindDDL    Kind = "ddl"    // a DDL statement
+	KindDelete Kind = "delete" // a run of deletes of one source transaction
+)
+
+// Action is the decision taken on an item when it times out
+type Action string
+
+const (
+	Approve Action = "approve"
+	Reject  Action = "reject"
+)
+
+// ParseAction parses an action name; an empty name is Reject
+func ParseAction(name string) (Action, error) {
+	switch Action(name) {
+	case "", Reject:
+		return Reject, nil
+	case Approve:
+		return Approve, nil
+	}
+	return "", fmt.Errorf("unknown action %q (expected approve or reject)", name)
+}
+
+// ErrNotFound is returned when deciding an item that isn't pending
+var ErrNotFound = errors.New("no pending item with that ID")
+
+// historySize is the number of decided items kept for the admin API
+const historySize = 20
+
+// Item is a change held until an operator approves or rejects it
+type Item struct {
+	ID            int64      `json:"id"`
+	Kind          Kind       `json:"kind"`
+	Position      string     `json:"position"`
+	TransactionID string     `json:"transaction_id,omitempty"`
+	Table         string     `json:"table,omitempty"`
+	Statement     string     `json:"statement,omitempty"`
+	Rows          int        `json:"rows,omitempty"` // deletes held so far
+	Reason        string     `json:"reason"`
+	HeldAt        time.Time  `json:"held_at"`
+	Deadline      *time.Time `json:"deadline,omitempty"`
+	Decision      string     `json:"decision,omitempty"`   // approved or rejected
+	DecidedBy     string     `json:"decided_by,omitempty"` // admin or timeout
+	DecidedAt     *time.Time `json:"decided_at,omitempty"`
+}
+
+// key identifies what a decision applies to: a DDL statement by its position, and a run
+// of deletes by its transaction, since the run is redelivered from its last change
+func (i Item) key() string {
+	if i.Kind == KindDelete {
+		return string(i.Kind) + ":" + i.TransactionID
+	}
+	return string(i.Kind) + ":" + i.Position
+}
+
+// Config controls how long items wait for a decision
+type Config struct {
+	// Timeout decides an item with TimeoutAction once it has waited this long; zero
+	// waits for an operator forever
+	Timeout       time.Duration
+	TimeoutAction Action
+	// OnDecide is called, outside the queue's lock, after an item was decided
+	OnDecide func(item Item)
+}
+
+// Queue holds items until they are decided. The handler checks an item with Check, which
+// parks it the first time; once an operator has decided it, the next Check of the same
+// item returns the decision. Items and decisions are kept in memory.
+type Queue struct {
+	config Config
+	now    func() time.Time
+
+	mu       sync.Mutex
+	nextID   int64
+	pending  []*Item
+	decision map[string]bool // approved, by key, until the item is checked again
+	history  []Item          // most recently decided last
+}
+
+// NewQueue creates an empty queue
+func NewQueue(config Config) *Queue {
+	return &Queue{config: config, now: time.Now, decision: make(map[string]bool)}
+}
+
+// Check returns the decision on an item. If it hasn't been decided, it is parked, unless
+// it already is, and decided is false.
+func (q *Queue) Check(item Item) (approved, decided bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	key := item.key()
+	if approved, ok := q.decision[key]; ok {
+		delete(q.decision, key)
+		return approved, true
+	}
+	for _, pending := range q.pending {
+		if pending.key() == key {
+			return false, false
+		}
+	}
+
+	q.nextID++
+	item.ID = q.nextID
+	item.HeldAt = q.now()
+	item.Decision, item.DecidedBy, item.DecidedAt, item.Deadline = "", "", nil, nil
+	if q.config.Timeout > 0 {
+		deadline := item.HeldAt.Add(q.config.Timeout)
+		item.Deadline = &deadline
+		id := item.ID
+		time.AfterFunc(q.config.Timeout, func() {
+			// Already decided by an operator if not found
+			q.Decide(id, q.config.TimeoutAction == Approve, "timeout")
+		})
+	}
+	q.pending = append(q.pending, &item)
+	return false, false
+}
+
+// Decide approves or rejects a pending item and returns it
+func (q *Queue) Decide(id int64, approve bool, by string) (Item, error) {
+	q.mu.Lock()
+	var item *Item
+	for i, pending := range q.pending {
+		if pending.ID == id {
+			item = pending
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			break
+		}
+	}
+	if item == nil {
+		q.mu.Unlock()
+		return Item{}, ErrNotFound
+	}
+	item.Decision = "rejected"
+	if approve {
+		item.Decision = "approved"
+	}
+	decidedAt := q.now()
+	item.DecidedBy, item.DecidedAt = by, &decidedAt
+	q.decision[item.key()] = approve
+	q.history = append(q.history, *item)
+	if len(q.history) > historySize {
+		q.history = q.history[len(q.history)-historySize:]
+	}
+	q.mu.Unlock()
+
+	if q.config.OnDecide != nil {
+		q.config.OnDecide(*item)
+	}
+	return *item, nil
+}
+
+// Pending returns the items waiting for a decision, oldest first
+func (q *Queue) Pending() []Item {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	items := make([]Item, 0, len(q.pending))
+	for _, item := range q.pending {
+		items = append(items, *item)
+	}
+	return items
+}
+
+// Decided returns the most recently decided items, most recent last
+func (q *Queue) Decided() []Item {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]Item{}, q.history...)
+}
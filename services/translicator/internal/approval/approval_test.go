@@ -0,0 +1,107 @@
+package approval
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueueDecide(t *testing.T) {
+	var decided []Item
+	q := NewQueue(Config{OnDecide: func(item Item) { decided = append(decided, item) }})
+
+	ddl := Item{Kind: KindDDL, Position: "0/100", Statement: "DROP TABLE users", Reason: "APPROVAL_DDL"}
+	if _, ok := q.Check(ddl); ok {
+		t.Fatal("Check() decided an item nobody approved")
+	}
+	// Redelivered before a decision: still one pending item
+	if _, ok := q.Check(ddl); ok {
+		t.Fatal("Check() decided an item nobody approved")
+	}
+	pending := q.Pending()
+	if len(pending) != 1 || pending[0].ID != 1 || pending[0].Position != "0/100" {
+		t.Fatalf("Pending() = %+v, want the statement at 0/100", pending)
+	}
+
+	if _, err := q.Decide(2, true, "admin"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Decide() of an unknown item: error = %v, want ErrNotFound", err)
+	}
+	item, err := q.Decide(1, true, "admin")
+	if err != nil {
+		t.Fatalf("Decide() unexpected error: %v", err)
+	}
+	if item.Decision != "approved" || item.DecidedBy != "admin" || item.DecidedAt == nil {
+		t.Errorf("Decide() = %+v, want approved by admin", item)
+	}
+	if len(decided) != 1 || decided[0].ID != 1 {
+		t.Errorf("OnDecide got %+v, want item 1", decided)
+	}
+	if len(q.Pending()) != 0 {
+		t.Errorf("Pending() = %+v, want none", q.Pending())
+	}
+	if history := q.Decided(); len(history) != 1 || history[0].Decision != "approved" {
+		t.Errorf("Decided() = %+v, want the approved item", history)
+	}
+
+	if approved, ok := q.Check(ddl); !approved || !ok {
+		t.Errorf("Check() = %v, %v; want approved", approved, ok)
+	}
+	// A decision applies once
+	if _, ok := q.Check(ddl); ok {
+		t.Error("Check() reused a decision")
+	}
+	if _, err := q.Decide(2, false, "admin"); err != nil {
+		t.Fatalf("Decide() unexpected error: %v", err)
+	}
+	if approved, ok := q.Check(ddl); approved || !ok {
+		t.Errorf("Check() = %v, %v; want rejected", approved, ok)
+	}
+
+	// Deletes are decided by transaction, whichever change of the run is checked
+	q.Check(Item{Kind: KindDelete, Position: "0/500", TransactionID: "42", Rows: 11})
+	q.Decide(3, false, "admin")
+	if approved, ok := q.Check(Item{Kind: KindDelete, Position: "0/600", TransactionID: "42"}); approved || !ok {
+		t.Errorf("Check() = %v, %v; want rejected", approved, ok)
+	}
+}
+
+func TestQueueTimeout(t *testing.T) {
+	for _, action := range []Action{Reject, Approve} {
+		t.Run(string(action), func(t *testing.T) {
+			decided := make(chan Item, 1)
+			q := NewQueue(Config{
+				Timeout:       10 * time.Millisecond,
+				TimeoutAction: action,
+				OnDecide:      func(item Item) { decided <- item },
+			})
+			item := Item{Kind: KindDDL, Position: "0/100"}
+			q.Check(item)
+			if pending := q.Pending(); len(pending) != 1 || pending[0].Deadline == nil {
+				t.Fatalf("Pending() = %+v, want an item with a deadline", pending)
+			}
+
+			select {
+			case got := <-decided:
+				if got.DecidedBy != "timeout" {
+					t.Errorf("decided by %q, want timeout", got.DecidedBy)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("item was not decided after its timeout")
+			}
+			if approved, ok := q.Check(item); !ok || approved != (action == Approve) {
+				t.Errorf("Check() = %v, %v; want approved %v", approved, ok, action == Approve)
+			}
+		})
+	}
+}
+
+func TestParseAction(t *testing.T) {
+	for name, want := range map[string]Action{"": Reject, "reject": Reject, "approve": Approve} {
+		if got, err := ParseAction(name); err != nil || got != want {
+			t.Errorf("ParseAction(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseAction("ignore"); err == nil {
+		t.Error("ParseAction(\"ignore\") should fail")
+	}
+}
@@ -0,0 +1,102 @@
+package approval
+
+import "kasho/proto"
+
+// DeleteRun holds a run of consecutive deletes of one source transaction until it is
+// known whether the run is larger than the threshold, so a mass delete can wait for
+// approval before any of its rows are deleted on the replica. Runs up to the threshold
+// are applied as usual.
+type DeleteRun struct {
+	threshold int
+	txn       string
+	changes   []*proto.Change
+	parked    bool
+
+	decided  string // transaction whose remaining deletes follow the decision
+	approved bool
+}
+
+// NewDeleteRun creates a run that holds up to threshold deletes
+func NewDeleteRun(threshold int) *DeleteRun {
+	return &DeleteRun{threshold: threshold}
+}
+
+// Threshold returns the largest run applied without approval
+func (r *DeleteRun) Threshold() int {
+	return r.threshold
+}
+
+func isDelete(change *proto.Change) bool {
+	dml := change.GetDml()
+	return dml != nil && dml.Kind == "delete" && change.TransactionId != ""
+}
+
+// Decision returns the decision on the transaction of a delete, if its run was decided
+func (r *DeleteRun) Decision(change *proto.Change) (approved, ok bool) {
+	if !isDelete(change) || r.decided == "" || change.TransactionId != r.decided {
+		return false, false
+	}
+	return r.approved, true
+}
+
+// Add holds a delete and reports whether it was held. A change that isn't a delete of
+// the run's transaction isn't held; the run must be applied before it is.
+// over reports a delete that would take the run past the threshold; it isn't held, and
+// the run is to be parked for approval.
+func (r *DeleteRun) Add(change *proto.Change) (held, over bool) {
+	if !isDelete(change) {
+		return false, false
+	}
+	if len(r.changes) > 0 && change.TransactionId != r.txn {
+		return false, false
+	}
+	if len(r.changes) >= r.threshold {
+		return false, true
+	}
+	r.txn = change.TransactionId
+	r.changes = append(r.changes, change)
+	return true, false
+}
+
+// Len returns the number of deletes held
+func (r *DeleteRun) Len() int {
+	return len(r.changes)
+}
+
+// Park marks the run as waiting for approval, so it isn't applied
+func (r *DeleteRun) Park() {
+	r.parked = true
+}
+
+// Parked reports whether the run waits for approval
+func (r *DeleteRun) Parked() bool {
+	return r.parked
+}
+
+// Pending returns the deletes held, in order. Unless the run is parked, they are to be
+// applied and dropped before anything else.
+func (r *DeleteRun) Pending() []*proto.Change {
+	return r.changes
+}
+
+// Drop removes the first n held deletes, e.g. once they were applied
+func (r *DeleteRun) Drop(n int) {
+	r.changes = r.changes[n:]
+	if len(r.changes) == 0 {
+		r.changes, r.txn = nil, ""
+	}
+}
+
+// Decide records the decision on a parked run. An approved run is no longer parked, so
+// it can be applied; a rejected one is dropped. The transaction's later deletes follow
+// the same decision. It returns the number of deletes dropped.
+func (r *DeleteRun) Decide(approved bool) int {
+	r.decided, r.approved = r.txn, approved
+	r.parked = false
+	if approved {
+		return 0
+	}
+	dropped := len(r.changes)
+	r.Drop(dropped)
+	return dropped
+}
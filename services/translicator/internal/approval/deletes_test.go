@@ -0,0 +1,70 @@
+package approval
+
+import (
+	"testing"
+
+	"kasho/proto"
+)
+
+func deleteIn(txn, position string) *proto.Change {
+	return &proto.Change{Position: position, TransactionId: txn, Data: &proto.Change_Dml{Dml: &proto.DMLData{Table: "users", Kind: "delete"}}}
+}
+
+func TestDeleteRunUnderThreshold(t *testing.T) {
+	run := NewDeleteRun(2)
+	for _, position := range []string{"0/1", "0/2"} {
+		if held, over := run.Add(deleteIn("7", position)); !held || over {
+			t.Fatalf("Add(%s) = %v, %v; want held", position, held, over)
+		}
+	}
+	// A change of another transaction comes after the run
+	if held, over := run.Add(deleteIn("8", "0/3")); held || over {
+		t.Fatalf("Add() of another transaction = %v, %v; want not held", held, over)
+	}
+	if pending := run.Pending(); len(pending) != 2 || pending[0].Position != "0/1" {
+		t.Fatalf("Pending() = %v, want the deletes at 0/1 and 0/2", pending)
+	}
+	run.Drop(2)
+	if run.Len() != 0 {
+		t.Errorf("Len() = %d after dropping the run, want 0", run.Len())
+	}
+	if held, _ := run.Add(deleteIn("8", "0/3")); !held {
+		t.Error("Add() didn't start a new run")
+	}
+	run.Drop(1)
+	insert := &proto.Change{TransactionId: "8", Data: &proto.Change_Dml{Dml: &proto.DMLData{Kind: "insert"}}}
+	if held, _ := run.Add(insert); held {
+		t.Error("Add() held an insert")
+	}
+}
+
+func TestDeleteRunOverThreshold(t *testing.T) {
+	for _, approved := range []bool{true, false} {
+		run := NewDeleteRun(2)
+		run.Add(deleteIn("7", "0/1"))
+		run.Add(deleteIn("7", "0/2"))
+		if held, over := run.Add(deleteIn("7", "0/3")); held || !over {
+			t.Fatalf("Add() past the threshold = %v, %v; want over", held, over)
+		}
+		run.Park()
+		if !run.Parked() {
+			t.Fatal("Parked() = false after Park()")
+		}
+
+		dropped := run.Decide(approved)
+		wantLen, wantDropped := 2, 0
+		if !approved {
+			wantLen, wantDropped = 0, 2
+		}
+		if run.Parked() || run.Len() != wantLen || dropped != wantDropped {
+			t.Errorf("Decide(%v): parked %v, %d held, %d dropped; want not parked, %d held, %d dropped",
+				approved, run.Parked(), run.Len(), dropped, wantLen, wantDropped)
+		}
+		if got, ok := run.Decision(deleteIn("7", "0/4")); !ok || got != approved {
+			t.Errorf("Decision() = %v, %v; want %v for the rest of the transaction", got, ok, approved)
+		}
+		if _, ok := run.Decision(deleteIn("9", "0/5")); ok {
+			t.Error("Decision() applied to another transaction")
+		}
+	}
+}
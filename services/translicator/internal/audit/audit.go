@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"kasho/pkg/transform"
+	"kasho/proto"
+
+	gproto "google.golang.org/protobuf/proto"
+)
+
+// Column is a transformed column of an audited change
+type Column struct {
+	Name      string `json:"name"`
+	Transform string `json:"transform"`
+	// Hash is the salted hash of the original value, so a known value can be shown to
+	// have been transformed without the log holding it
+	Hash string `json:"hash"`
+}
+
+// Record is the evidence of what was transformed in one change
+type Record struct {
+	Time     time.Time `json:"time"`
+	Position string    `json:"position"`
+	Table    string    `json:"table"`
+	Kind     string    `json:"kind"`
+	Columns  []Column  `json:"columns"`
+}
+
+// Sink stores audit records
+type Sink interface {
+	Write(ctx context.Context, record Record) error
+	Close() error
+}
+
+// Auditor builds the audit records of changes
+type Auditor struct {
+	config *transform.Config
+	salt   []byte
+	now    func() time.Time
+}
+
+// NewAuditor creates an auditor for changes transformed with config. Original values are
+// hashed with HMAC-SHA256 keyed by salt.
+func NewAuditor(config *transform.Config, salt string) (*Auditor, error) {
+	if salt == "" {
+		return nil, fmt.Errorf("a salt is required to hash original values")
+	}
+	return &Auditor{config: config, salt: []byte(salt), now: time.Now}, nil
+}
+
+// Record returns the audit record of a source change. It reports false for changes
+// without transformed columns.
+func (a *Auditor) Record(change *proto.Change) (Record, bool) {
+	dml := change.GetDml()
+	if dml == nil || !a.config.TransformsTable(dml.Table) {
+		return Record{}, false
+	}
+
+	record := Record{
+		Time:     a.now().UTC(),
+		Position: change.Position,
+		Table:    dml.Table,
+		Kind:     dml.Kind,
+	}
+	for i, name := range dml.ColumnNames {
+		ct, ok := a.config.ColumnTransform(dml.Table, name)
+		if !ok || i >= len(dml.ColumnValues) {
+			continue
+		}
+		value := dml.ColumnValues[i]
+		// Unchanged TOAST values aren't in the change, so nothing was transformed
+		if value.GetUnchangedToast() {
+			continue
+		}
+		record.Columns = append(record.Columns, Column{Name: name, Transform: string(ct.Type), Hash: a.Hash(value)})
+	}
+	if len(record.Columns) == 0 {
+		return Record{}, false
+	}
+	return record, true
+}
+
+// Hash returns the salted hash of a value as recorded in the audit log, e.g. to check
+// whether a known value was transformed. Values are hashed in their protobuf encoding, so
+// values of different types, such as the number 1 and the string "1", hash differently.
+func (a *Auditor) Hash(value *proto.ColumnValue) string {
+	data, _ := gproto.MarshalOptions{Deterministic: true}.Marshal(value)
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
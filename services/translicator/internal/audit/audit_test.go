@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"kasho/pkg/transform"
+	"kasho/proto"
+)
+
+func stringValue(s string) *proto.ColumnValue {
+	return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: s}}
+}
+
+func TestAuditorRecord(t *testing.T) {
+	config := &transform.Config{Tables: map[string]transform.TableConfig{
+		"public.users": {
+			"email":    {Type: transform.FakeEmail},
+			"password": {Type: transform.PasswordBcrypt},
+		},
+	}}
+	auditor, err := NewAuditor(config, "pepper")
+	if err != nil {
+		t.Fatalf("NewAuditor() unexpected error: %v", err)
+	}
+	auditor.now = func() time.Time { return time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC) }
+
+	change := &proto.Change{Position: "0/100", Data: &proto.Change_Dml{Dml: &proto.DMLData{
+		Table:        "public.users",
+		Kind:         "update",
+		ColumnNames:  []string{"id", "email", "password"},
+		ColumnValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_IntValue{IntValue: 1}}, stringValue("jane@example.com"), {Value: &proto.ColumnValue_UnchangedToast{UnchangedToast: true}}},
+	}}}
+	record, ok := auditor.Record(change)
+	if !ok {
+		t.Fatal("Record() found nothing transformed")
+	}
+	if record.Position != "0/100" || record.Table != "public.users" || record.Kind != "update" || !record.Time.Equal(auditor.now()) {
+		t.Errorf("Record() = %+v, want the change's position, table and kind", record)
+	}
+	// The unchanged TOAST password isn't in the change
+	if len(record.Columns) != 1 || record.Columns[0].Name != "email" || record.Columns[0].Transform != "FakeEmail" {
+		t.Fatalf("Record() columns = %+v, want only email", record.Columns)
+	}
+
+	hash := record.Columns[0].Hash
+	if len(hash) != 64 || hash != auditor.Hash(stringValue("jane@example.com")) {
+		t.Errorf("Hash = %q, want the HMAC-SHA256 of the original value", hash)
+	}
+	if hash == auditor.Hash(stringValue("john@example.com")) {
+		t.Error("different values hash the same")
+	}
+	other, _ := NewAuditor(config, "salt")
+	if hash == other.Hash(stringValue("jane@example.com")) {
+		t.Error("different salts hash the same")
+	}
+
+	for _, change := range []*proto.Change{
+		{Position: "0/200", Data: &proto.Change_Dml{Dml: &proto.DMLData{Table: "public.orders", Kind: "insert", ColumnNames: []string{"id"}, ColumnValues: []*proto.ColumnValue{stringValue("1")}}}},
+		{Position: "0/300", Data: &proto.Change_Ddl{Ddl: &proto.DDLData{Ddl: "DROP TABLE users"}}},
+	} {
+		if record, ok := auditor.Record(change); ok {
+			t.Errorf("Record(%s) = %+v, want nothing to audit", change.Position, record)
+		}
+	}
+
+	if _, err := NewAuditor(config, ""); err == nil {
+		t.Error("NewAuditor() without a salt should fail")
+	}
+}
@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"context"
+	dbsql "database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"kasho/pkg/dialect"
+)
+
+// FileSink appends audit records to a JSON Lines file
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenFile opens (or creates) the audit file at path for appending. Records are only
+// ever appended; rotating or archiving the file is left to the operator.
+func OpenFile(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	return &FileSink{file: file}, nil
+}
+
+// Write appends a record
+func (s *FileSink) Write(ctx context.Context, record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return nil
+}
+
+// Close closes the audit file
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// Table is the replica table audit records are written to by a TableSink
+const Table = "kasho_audit"
+
+// TableSink inserts audit records into the replica's kasho_audit table. The transformed
+// columns are stored as JSON.
+type TableSink struct {
+	db      *dbsql.DB
+	dialect dialect.Dialect
+}
+
+// OpenTable creates the audit table if it doesn't exist yet
+func OpenTable(ctx context.Context, db *dbsql.DB, d dialect.Dialect) (*TableSink, error) {
+	s := &TableSink{db: db, dialect: d}
+	if err := s.create(ctx); err != nil {
+		return nil, fmt.Errorf("error creating %s: %w", Table, err)
+	}
+	return s, nil
+}
+
+// create creates the audit table. Not every replica supports CREATE TABLE IF NOT EXISTS,
+// so a failing query for the table is taken to mean it's missing.
+func (s *TableSink) create(ctx context.Context) error {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT position FROM %s WHERE 1 = 0", Table))
+	if err == nil {
+		return rows.Close()
+	}
+
+	textType, suffix := "TEXT", ""
+	switch s.dialect.Name() {
+	case "oracle":
+		textType = "CLOB"
+	case "clickhouse":
+		textType, suffix = "String", " ENGINE = MergeTree ORDER BY recorded_at"
+	}
+	_, err = s.db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s (recorded_at VARCHAR(40) NOT NULL, position VARCHAR(255) NOT NULL, "+
+		"table_name VARCHAR(255) NOT NULL, kind VARCHAR(16) NOT NULL, transformed_columns %s NOT NULL)%s", Table, textType, suffix))
+	return err
+}
+
+// Write inserts a record
+func (s *TableSink) Write(ctx context.Context, record Record) error {
+	columns, err := json.Marshal(record.Columns)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (recorded_at, position, table_name, kind, transformed_columns) VALUES (%s, %s, %s, %s, %s)",
+		Table, s.dialect.Placeholder(1), s.dialect.Placeholder(2), s.dialect.Placeholder(3), s.dialect.Placeholder(4), s.dialect.Placeholder(5))
+	if _, err := s.db.ExecContext(ctx, query, record.Time.Format(time.RFC3339Nano), record.Position, record.Table, record.Kind, string(columns)); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return nil
+}
+
+// Close does nothing; the replica connection is shared
+func (s *TableSink) Close() error {
+	return nil
+}
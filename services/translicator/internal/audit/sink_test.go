@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	dbsql "database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kasho/pkg/dialect"
+
+	_ "modernc.org/sqlite"
+)
+
+var testRecord = Record{
+	Time:     time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC),
+	Position: "0/100",
+	Table:    "public.users",
+	Kind:     "insert",
+	Columns:  []Column{{Name: "email", Transform: "FakeEmail", Hash: "ab12"}},
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	for i := 0; i < 2; i++ {
+		// Reopening appends
+		sink, err := OpenFile(path)
+		if err != nil {
+			t.Fatalf("OpenFile() unexpected error: %v", err)
+		}
+		if err := sink.Write(context.Background(), testRecord); err != nil {
+			t.Fatalf("Write() unexpected error: %v", err)
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatalf("Close() unexpected error: %v", err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open audit file: %v", err)
+	}
+	defer file.Close()
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid audit line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 || records[1].Position != "0/100" || records[1].Columns[0].Hash != "ab12" {
+		t.Errorf("audit file = %+v, want the record twice", records)
+	}
+}
+
+func TestTableSink(t *testing.T) {
+	ctx := context.Background()
+	db, err := dbsql.Open("sqlite", filepath.Join(t.TempDir(), "replica.db"))
+	if err != nil {
+		t.Fatalf("failed to open replica: %v", err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 2; i++ {
+		// The table is created once
+		sink, err := OpenTable(ctx, db, dialect.NewSQLite())
+		if err != nil {
+			t.Fatalf("OpenTable() unexpected error: %v", err)
+		}
+		if err := sink.Write(ctx, testRecord); err != nil {
+			t.Fatalf("Write() unexpected error: %v", err)
+		}
+	}
+
+	var count int
+	var recordedAt, columns string
+	row := db.QueryRow("SELECT COUNT(*), MAX(recorded_at), MAX(transformed_columns) FROM kasho_audit WHERE position = '0/100' AND table_name = 'public.users' AND kind = 'insert'")
+	if err := row.Scan(&count, &recordedAt, &columns); err != nil {
+		t.Fatalf("failed to read kasho_audit: %v", err)
+	}
+	if count != 2 || recordedAt != "2026-10-16T12:00:00Z" || columns != `[{"name":"email","transform":"FakeEmail","hash":"ab12"}]` {
+		t.Errorf("kasho_audit = %d rows, %q, %q; want 2 rows of the record", count, recordedAt, columns)
+	}
+}
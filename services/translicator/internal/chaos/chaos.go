@@ -0,0 +1,55 @@
+//go:build chaos
+
+package chaos
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"sync/atomic"
+)
+
+// errExec wraps driver.ErrBadConn so an injected failure is retried like a lost connection
+var errExec = fmt.Errorf("chaos: statement failed: %w", driver.ErrBadConn)
+
+var (
+	dropPercent float64
+	failEvery   int64
+	execs       atomic.Int64
+)
+
+func init() {
+	if value := os.Getenv("CHAOS_DROP_STREAM_PERCENT"); value != "" {
+		percent, err := strconv.ParseFloat(value, 64)
+		if err != nil || percent < 0 || percent >= 100 {
+			log.Fatalf("Invalid CHAOS_DROP_STREAM_PERCENT: %q (expected a percentage below 100)", value)
+		}
+		dropPercent = percent
+		log.Printf("Chaos: dropping %g%% of stream changes", dropPercent)
+	}
+	if value := os.Getenv("CHAOS_FAIL_EXEC_EVERY"); value != "" {
+		every, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || every < 2 {
+			log.Fatalf("Invalid CHAOS_FAIL_EXEC_EVERY: %q (expected a number of statements, at least 2)", value)
+		}
+		failEvery = every
+		log.Printf("Chaos: failing every %d statements", failEvery)
+	}
+}
+
+// DropChange reports whether to lose a received change
+func DropChange() bool {
+	return dropPercent > 0 && rand.Float64()*100 < dropPercent
+}
+
+// Exec runs a statement, or fails it with a connection error if it is the Kth
+func Exec(ctx context.Context, run func(ctx context.Context) error) error {
+	if failEvery > 0 && execs.Add(1)%failEvery == 0 {
+		return errExec
+	}
+	return run(ctx)
+}
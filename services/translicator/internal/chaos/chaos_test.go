@@ -0,0 +1,59 @@
+//go:build chaos
+
+package chaos
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+func TestExec(t *testing.T) {
+	defer func(every int64) { failEvery = every }(failEvery)
+	failEvery = 3
+	execs.Store(0)
+
+	ran := 0
+	run := func(ctx context.Context) error {
+		ran++
+		return nil
+	}
+	var failed []int
+	for i := 1; i <= 6; i++ {
+		if err := Exec(context.Background(), run); err != nil {
+			if !errors.Is(err, driver.ErrBadConn) {
+				t.Errorf("Exec() error = %v, want driver.ErrBadConn", err)
+			}
+			failed = append(failed, i)
+		}
+	}
+	if len(failed) != 2 || failed[0] != 3 || failed[1] != 6 {
+		t.Errorf("Exec() failed statements %v, want [3 6]", failed)
+	}
+	if ran != 4 {
+		t.Errorf("Exec() ran %d statements, want 4", ran)
+	}
+}
+
+func TestDropChange(t *testing.T) {
+	defer func(percent float64) { dropPercent = percent }(dropPercent)
+
+	dropPercent = 0
+	for range 100 {
+		if DropChange() {
+			t.Fatal("DropChange() = true with no percentage set")
+		}
+	}
+
+	dropPercent = 50
+	dropped := 0
+	for range 1000 {
+		if DropChange() {
+			dropped++
+		}
+	}
+	if dropped < 350 || dropped > 650 {
+		t.Errorf("DropChange() dropped %d of 1000 changes, want about 500", dropped)
+	}
+}
@@ -0,0 +1,15 @@
+// Package chaos injects faults into the translicator for resilience testing. Faults are
+// only compiled into builds with the chaos build tag, and configured from the environment:
+//
+//   - CHAOS_DROP_STREAM_PERCENT: percentage of received changes lost, each breaking its
+//     stream as a dropped connection would
+//   - CHAOS_FAIL_EXEC_EVERY: fail every Kth statement executed on the replica with a
+//     connection error
+//
+// Other builds use the no-op versions in nochaos.go.
+package chaos
+
+import "errors"
+
+// ErrDropped is the error a stream fails with when one of its changes is dropped
+var ErrDropped = errors.New("chaos: change dropped")
@@ -0,0 +1,15 @@
+//go:build !chaos
+
+package chaos
+
+import "context"
+
+// DropChange reports whether to lose a received change, which is never without the chaos tag
+func DropChange() bool {
+	return false
+}
+
+// Exec runs a statement; failures are only injected with the chaos tag
+func Exec(ctx context.Context, run func(ctx context.Context) error) error {
+	return run(ctx)
+}
@@ -0,0 +1,107 @@
+package ddl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"kasho/pkg/transform"
+)
+
+// Rule is the rule of the ddl section of transforms.yml a statement matched
+type Rule struct {
+	Action transform.DDLAction
+	SQL    string // replacement statement of a replace rule
+	Name   string // the rule's pattern, or "destructive", for logs
+}
+
+type compiledRule struct {
+	Rule
+	pattern     *regexp.Regexp // nil for a destructive rule
+	destructive bool
+}
+
+// Rules decides what happens to DDL statements before they are applied
+type Rules struct {
+	rules []compiledRule
+}
+
+// NewRules compiles the rules of the ddl section of transforms.yml
+func NewRules(config transform.DDLConfig) (*Rules, error) {
+	r := &Rules{}
+	for i, rule := range config.Rules {
+		c := compiledRule{
+			Rule:        Rule{Action: rule.Action, SQL: rule.SQL, Name: rule.Match},
+			destructive: rule.Destructive,
+		}
+		if rule.Match != "" {
+			pattern, err := regexp.Compile(rule.Match)
+			if err != nil {
+				return nil, fmt.Errorf("ddl rule %d: %w", i+1, err)
+			}
+			c.pattern = pattern
+		} else {
+			c.Name = "destructive"
+		}
+		r.rules = append(r.rules, c)
+	}
+	return r, nil
+}
+
+// Pauses reports whether any rule pauses for approval
+func (r *Rules) Pauses() bool {
+	for _, rule := range r.rules {
+		if rule.Action == transform.DDLPause {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns the first rule matching a statement. A rule with both a pattern and
+// destructive set matches destructive statements matching the pattern.
+func (r *Rules) Match(statement string) (Rule, bool) {
+	statement = strings.TrimSpace(statement)
+	for _, rule := range r.rules {
+		if rule.pattern != nil && !rule.pattern.MatchString(statement) {
+			continue
+		}
+		if rule.destructive && !Destructive(statement) {
+			continue
+		}
+		return rule.Rule, true
+	}
+	return Rule{}, false
+}
+
+var (
+	destructiveStatementPattern = regexp.MustCompile(`(?is)^\s*(?:DROP\s+(?:TABLE|SCHEMA|DATABASE)\b|TRUNCATE\b)`)
+	alterTablePrefixPattern     = regexp.MustCompile(`(?is)^\s*ALTER\s+TABLE\b`)
+	dropActionPattern           = regexp.MustCompile(`(?is)\bDROP\s+([^\s,;(]+)`)
+)
+
+// Destructive reports whether a statement deletes data: DROP TABLE, DROP SCHEMA, DROP
+// DATABASE, TRUNCATE, or ALTER TABLE dropping a column. Dropping a constraint, an index
+// or a column's default doesn't delete data.
+func Destructive(statement string) bool {
+	if destructiveStatementPattern.MatchString(statement) {
+		return true
+	}
+	if !alterTablePrefixPattern.MatchString(statement) {
+		return false
+	}
+	literals := literalSpans(statement)
+	for _, m := range dropActionPattern.FindAllStringSubmatchIndex(statement, -1) {
+		if inSpans(literals, m[0]) {
+			continue
+		}
+		switch strings.ToUpper(statement[m[2]:m[3]]) {
+		case "CONSTRAINT", "INDEX", "KEY", "PRIMARY", "FOREIGN", "CHECK", "PARTITION",
+			"DEFAULT", "NOT", "IDENTITY", "EXPRESSION", "SYSTEM":
+			continue
+		}
+		// DROP COLUMN, or MySQL's DROP without COLUMN
+		return true
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package ddl
+
+import (
+	"testing"
+
+	"kasho/pkg/transform"
+)
+
+func TestRulesMatch(t *testing.T) {
+	rules, err := NewRules(transform.DDLConfig{Rules: []transform.DDLRule{
+		{Match: `(?i)^DROP TABLE \S*_tmp\b`, Action: transform.DDLSkip},
+		{Match: `(?i)^CREATE INDEX CONCURRENTLY`, Action: transform.DDLReplace, SQL: "SELECT 1"},
+		{Destructive: true, Action: transform.DDLPause},
+	}})
+	if err != nil {
+		t.Fatalf("NewRules() unexpected error: %v", err)
+	}
+	if !rules.Pauses() {
+		t.Error("Pauses() = false, want true")
+	}
+
+	tests := []struct {
+		statement string
+		want      transform.DDLAction // empty when no rule matches
+	}{
+		{"DROP TABLE import_tmp", transform.DDLSkip},
+		{"  CREATE INDEX CONCURRENTLY idx ON users (email)", transform.DDLReplace},
+		{"DROP TABLE users", transform.DDLPause},
+		{"ALTER TABLE users DROP COLUMN email", transform.DDLPause},
+		{"ALTER TABLE users ADD COLUMN note text", ""},
+	}
+	for _, tt := range tests {
+		rule, ok := rules.Match(tt.statement)
+		if got := rule.Action; ok != (tt.want != "") || got != tt.want {
+			t.Errorf("Match(%q) = %q, %v; want %q", tt.statement, got, ok, tt.want)
+		}
+	}
+}
+
+func TestDestructive(t *testing.T) {
+	tests := []struct {
+		statement string
+		want      bool
+	}{
+		{"DROP TABLE users", true},
+		{"drop schema reporting cascade", true},
+		{"DROP DATABASE app", true},
+		{"TRUNCATE orders", true},
+		{"ALTER TABLE users DROP COLUMN email", true},
+		{"ALTER TABLE `users` DROP `email`, ADD `mail` text", true},
+		{"ALTER TABLE users DROP CONSTRAINT users_email_key", false},
+		{"ALTER TABLE users ALTER COLUMN email DROP NOT NULL, ALTER COLUMN name DROP DEFAULT", false},
+		{"ALTER TABLE users DROP INDEX idx_email, DROP PRIMARY KEY", false},
+		{"ALTER TABLE notes ALTER COLUMN body SET DEFAULT 'DROP x'", false},
+		{"DROP INDEX idx_email", false},
+		{"CREATE TABLE drop_log (id int)", false},
+	}
+	for _, tt := range tests {
+		if got := Destructive(tt.statement); got != tt.want {
+			t.Errorf("Destructive(%q) = %v, want %v", tt.statement, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package ddl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"kasho/proto"
+)
+
+// Removal reports a clause the sanitizer removed from a statement, or a statement it
+// skipped entirely
+type Removal struct {
+	Clause string
+	Reason string
+}
+
+func (r Removal) String() string {
+	return fmt.Sprintf("removed %q: %s", r.Clause, r.Reason)
+}
+
+// clauseRule removes the clauses of a statement that match pattern. If keep is set, a
+// match is only removed when keep returns false for the clause's name, the first group.
+type clauseRule struct {
+	pattern *regexp.Regexp
+	reason  string
+	keep    func(name string) bool
+	// listItem clauses can be items of a comma-separated list, such as the actions of
+	// ALTER TABLE, and are removed with a comma separating them from the next item
+	listItem bool
+}
+
+// statementRule skips statements that match pattern, which the replica can't run at all
+type statementRule struct {
+	pattern *regexp.Regexp
+	reason  string
+}
+
+// name matches an identifier, quoted or not, possibly qualified
+const name = "(\"[^\"]*\"|`[^`]*`|[^\\s,;()]+)"
+
+const (
+	reasonRoles       = "the replica may not have the source's roles"
+	reasonTablespaces = "the replica may not have the source's tablespaces"
+	reasonPaths       = "the source's file system paths don't exist on the replica"
+	reasonCollation   = "the replica doesn't have the collation; its default is used"
+	reasonEngine      = "the replica doesn't support the storage engine; its default is used"
+)
+
+// sanitizeRules are the statements and clauses each kind of replica can't accept. Clause
+// rules run in order.
+var sanitizeRules = map[string]struct {
+	statements []statementRule
+	clauses    []clauseRule
+}{
+	"postgresql": {
+		statements: []statementRule{
+			{regexp.MustCompile(`(?is)^\s*(CREATE|ALTER|DROP)\s+TABLESPACE\b`), reasonTablespaces},
+			{regexp.MustCompile(`(?is)^\s*(CREATE|ALTER|DROP)\s+(ROLE|USER|GROUP)\b`), reasonRoles},
+		},
+		clauses: []clauseRule{
+			{pattern: regexp.MustCompile(`(?is)\s*\bOWNER\s+TO\s+` + name), reason: reasonRoles, listItem: true},
+			{pattern: regexp.MustCompile(`(?is)\s*\bSET\s+TABLESPACE\s+` + name), reason: reasonTablespaces, listItem: true},
+			{pattern: regexp.MustCompile(`(?is)\s+USING\s+INDEX\s+TABLESPACE\s+` + name), reason: reasonTablespaces},
+			{pattern: regexp.MustCompile(`(?is)\s+TABLESPACE\s+` + name), reason: reasonTablespaces},
+			{pattern: regexp.MustCompile(`(?is)\s+COLLATE\s+` + name), reason: reasonCollation},
+		},
+	},
+	"mysql": {
+		statements: []statementRule{
+			{regexp.MustCompile(`(?is)^\s*(CREATE|ALTER|DROP)\s+(UNDO\s+)?TABLESPACE\b`), reasonTablespaces},
+			{regexp.MustCompile(`(?is)^\s*ALTER\s+TABLE\s+\S+\s+(DISCARD|IMPORT)\b.*\bTABLESPACE\b`), reasonTablespaces},
+			{regexp.MustCompile(`(?is)^\s*(CREATE|ALTER|DROP|RENAME)\s+USER\b`), reasonRoles},
+		},
+		clauses: []clauseRule{
+			{pattern: regexp.MustCompile(`(?is)\s+DEFINER\s*=\s*(\S+)`), reason: reasonRoles},
+			{pattern: regexp.MustCompile(`(?is)\s*\b(?:DATA|INDEX)\s+DIRECTORY\s*=?\s*('[^']*')`), reason: reasonPaths, listItem: true},
+			{pattern: regexp.MustCompile(`(?is)\s*\bTABLESPACE\s*=?\s*` + name + `(?:\s+STORAGE\s+(?:DISK|MEMORY))?`), reason: reasonTablespaces, listItem: true},
+			{pattern: regexp.MustCompile(`(?is)\s*\bENGINE\s*=?\s*` + name), reason: reasonEngine, keep: mysqlEngine, listItem: true},
+			{pattern: regexp.MustCompile(`(?is)(?:\s+DEFAULT)?\s+COLLATE\s*=?\s*` + name), reason: reasonCollation},
+		},
+	},
+	"sqlite": {
+		clauses: []clauseRule{
+			{pattern: regexp.MustCompile(`(?is)\s+COLLATE\s+` + name), reason: reasonCollation},
+		},
+	},
+}
+
+// mysqlEngine reports whether a storage engine is built into MySQL
+func mysqlEngine(engine string) bool {
+	switch strings.ToLower(engine) {
+	case "innodb", "myisam", "memory", "heap", "csv", "archive", "blackhole", "merge", "mrg_myisam":
+		return true
+	}
+	return false
+}
+
+// emptyAlterPattern matches an ALTER statement without actions, left when every action
+// was removed
+var emptyAlterPattern = regexp.MustCompile(`(?is)^\s*ALTER\s+(?:TABLE|FOREIGN\s+TABLE|SEQUENCE|VIEW|MATERIALIZED\s+VIEW|INDEX|` +
+	`FUNCTION|PROCEDURE|AGGREGATE|TYPE|DOMAIN|SCHEMA|DATABASE|EVENT)\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?[^\s(]+(?:\s*\([^)]*\))?\s*$`)
+
+// Sanitizer strips the clauses of DDL statements that a replica can't accept, such as
+// OWNER TO, TABLESPACE, storage engines and collations it doesn't have, so a statement
+// like CREATE TABLE still runs without them. Statements the replica can't run at all,
+// such as CREATE TABLESPACE, are skipped.
+type Sanitizer struct {
+	replica    string
+	collations map[string]bool // nil if unknown, then collations are kept
+}
+
+// NewSanitizer creates a sanitizer for a replica of the named dialect with the given
+// collations, e.g. from dialect.Collations. With no collations, COLLATE clauses are kept.
+func NewSanitizer(replica string, collations []string) *Sanitizer {
+	s := &Sanitizer{replica: replica}
+	if len(collations) > 0 {
+		s.collations = map[string]bool{"default": true}
+		for _, collation := range collations {
+			s.collations[strings.ToLower(collation)] = true
+		}
+	}
+	return s
+}
+
+// Sanitize removes unsupported clauses from the DDL of a change in place. It returns what
+// was removed and whether the statement is still to be applied.
+func (s *Sanitizer) Sanitize(change *proto.Change) ([]Removal, bool) {
+	ddl := change.GetDdl()
+	if ddl == nil {
+		return nil, true
+	}
+	rules := sanitizeRules[s.replica]
+
+	for _, rule := range rules.statements {
+		if rule.pattern.MatchString(ddl.Ddl) {
+			return []Removal{{Clause: firstLine(ddl.Ddl), Reason: rule.reason}}, false
+		}
+	}
+
+	body := strings.TrimSpace(ddl.Ddl)
+	terminator := ""
+	if strings.HasSuffix(body, ";") {
+		body, terminator = strings.TrimSpace(strings.TrimSuffix(body, ";")), ";"
+	}
+
+	var removals []Removal
+	for _, rule := range rules.clauses {
+		keep := rule.keep
+		if rule.reason == reasonCollation {
+			keep = s.hasCollation
+		}
+		body = removeClauses(body, rule, keep, &removals)
+	}
+	if len(removals) == 0 {
+		return nil, true
+	}
+
+	body = strings.TrimSpace(body)
+	if emptyAlterPattern.MatchString(body) {
+		return removals, false
+	}
+	ddl.Ddl = body + terminator
+	return removals, true
+}
+
+// hasCollation reports whether the replica has a collation, or whether it is unknown
+func (s *Sanitizer) hasCollation(collation string) bool {
+	if s.collations == nil {
+		return true
+	}
+	// PostgreSQL qualifies collations with their schema, e.g. pg_catalog."C"
+	if i := strings.LastIndex(collation, `."`); i >= 0 {
+		collation = collation[i+1:]
+	}
+	return s.collations[strings.ToLower(unquoteName(collation))]
+}
+
+// removeClauses removes the matches of a rule outside string literals
+func removeClauses(body string, rule clauseRule, keep func(string) bool, removals *[]Removal) string {
+	literals := literalSpans(body)
+	var b strings.Builder
+	last := 0
+	for _, m := range rule.pattern.FindAllStringSubmatchIndex(body, -1) {
+		if m[0] < last || inSpans(literals, m[0]) {
+			continue
+		}
+		if keep != nil && len(m) > 3 && m[2] >= 0 && keep(body[m[2]:m[3]]) {
+			continue
+		}
+		start, end := m[0], m[1]
+		if rule.listItem {
+			if before := strings.TrimRight(body[last:start], " \t\r\n"); strings.HasSuffix(before, ",") {
+				start = last + len(before) - 1
+			} else if after := strings.TrimLeft(body[end:], " \t\r\n"); strings.HasPrefix(after, ",") {
+				end = len(body) - len(after) + 1
+			}
+		}
+		b.WriteString(body[last:start])
+		// Keep the words on either side of the clause apart
+		if start > 0 && end < len(body) && body[start-1] != ' ' && body[end] != ' ' && body[end] != ',' && body[end] != ')' {
+			b.WriteByte(' ')
+		}
+		last = end
+		*removals = append(*removals, Removal{Clause: strings.Trim(body[start:end], " \t\r\n,"), Reason: rule.reason})
+	}
+	b.WriteString(body[last:])
+	return b.String()
+}
+
+// literalSpans returns the start and end of the string literals in a statement
+func literalSpans(s string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\'' {
+			continue
+		}
+		if start < 0 {
+			start = i
+		} else if i+1 < len(s) && s[i+1] == '\'' {
+			i++ // escaped quote
+		} else {
+			spans = append(spans, [2]int{start, i + 1})
+			start = -1
+		}
+	}
+	return spans
+}
+
+func inSpans(spans [][2]int, i int) bool {
+	for _, span := range spans {
+		if i > span[0] && i < span[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// firstLine shortens a statement for a report
+func firstLine(ddl string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(ddl), "\n")
+	if len(line) > 80 {
+		line = line[:77] + "..."
+	}
+	return line
+}
@@ -0,0 +1,152 @@
+package ddl
+
+import (
+	"strings"
+	"testing"
+
+	"kasho/proto"
+)
+
+func TestSanitize(t *testing.T) {
+	collations := []string{"C", "POSIX", "en_US.utf8", "utf8mb4_general_ci", "utf8mb4_bin", "NOCASE"}
+	tests := []struct {
+		name         string
+		replica      string
+		ddl          string
+		want         string // empty when the statement is skipped
+		wantRemovals []string
+	}{
+		{
+			name:    "postgresql tablespace and unknown collation",
+			replica: "postgresql",
+			ddl:     `CREATE TABLE public.users (id int PRIMARY KEY USING INDEX TABLESPACE fast, name text COLLATE "de_DE.utf8", email text COLLATE "C") TABLESPACE data;`,
+			want:    `CREATE TABLE public.users (id int PRIMARY KEY, name text, email text COLLATE "C");`,
+			wantRemovals: []string{
+				`removed "USING INDEX TABLESPACE fast": ` + reasonTablespaces,
+				`removed "TABLESPACE data": ` + reasonTablespaces,
+				`removed "COLLATE \"de_DE.utf8\"": ` + reasonCollation,
+			},
+		},
+		{
+			name:         "postgresql qualified collation",
+			replica:      "postgresql",
+			ddl:          `ALTER TABLE users ALTER COLUMN name TYPE text COLLATE pg_catalog."default"`,
+			want:         `ALTER TABLE users ALTER COLUMN name TYPE text COLLATE pg_catalog."default"`,
+			wantRemovals: nil,
+		},
+		{
+			name:         "postgresql owner only",
+			replica:      "postgresql",
+			ddl:          `ALTER TABLE public.users OWNER TO app;`,
+			wantRemovals: []string{`removed "OWNER TO app": ` + reasonRoles},
+		},
+		{
+			name:         "postgresql function owner",
+			replica:      "postgresql",
+			ddl:          `ALTER FUNCTION public.touch(integer, text) OWNER TO "app-admin";`,
+			wantRemovals: []string{`removed "OWNER TO \"app-admin\"": ` + reasonRoles},
+		},
+		{
+			name:         "postgresql owner among other actions",
+			replica:      "postgresql",
+			ddl:          `ALTER TABLE users OWNER TO app, ADD COLUMN note text, SET TABLESPACE slow`,
+			want:         `ALTER TABLE users ADD COLUMN note text`,
+			wantRemovals: []string{`removed "OWNER TO app": ` + reasonRoles, `removed "SET TABLESPACE slow": ` + reasonTablespaces},
+		},
+		{
+			name:         "postgresql create tablespace",
+			replica:      "postgresql",
+			ddl:          `CREATE TABLESPACE fast LOCATION '/ssd/pg'`,
+			wantRemovals: []string{`removed "CREATE TABLESPACE fast LOCATION '/ssd/pg'": ` + reasonTablespaces},
+		},
+		{
+			name:    "clauses in string literals are kept",
+			replica: "postgresql",
+			ddl:     `CREATE TABLE notes (body text DEFAULT 'see TABLESPACE docs' COLLATE "xx")`,
+			want:    `CREATE TABLE notes (body text DEFAULT 'see TABLESPACE docs')`,
+			wantRemovals: []string{
+				`removed "COLLATE \"xx\"": ` + reasonCollation,
+			},
+		},
+		{
+			name:    "mysql table options",
+			replica: "mysql",
+			ddl:     "CREATE TABLE `users` (`id` int NOT NULL, `name` varchar(50) COLLATE utf8mb4_0900_ai_ci) ENGINE=Aria DATA DIRECTORY='/data/mysql' DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin",
+			want:    "CREATE TABLE `users` (`id` int NOT NULL, `name` varchar(50)) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin",
+			wantRemovals: []string{
+				`removed "DATA DIRECTORY='/data/mysql'": ` + reasonPaths,
+				`removed "ENGINE=Aria": ` + reasonEngine,
+				`removed "COLLATE utf8mb4_0900_ai_ci": ` + reasonCollation,
+			},
+		},
+		{
+			name:         "mysql supported engine is kept",
+			replica:      "mysql",
+			ddl:          "CREATE TABLE t (id int) ENGINE=InnoDB",
+			want:         "CREATE TABLE t (id int) ENGINE=InnoDB",
+			wantRemovals: nil,
+		},
+		{
+			name:         "mysql definer",
+			replica:      "mysql",
+			ddl:          "CREATE DEFINER=`root`@`%` SQL SECURITY DEFINER VIEW v AS SELECT 1",
+			want:         "CREATE SQL SECURITY DEFINER VIEW v AS SELECT 1",
+			wantRemovals: []string{"removed \"DEFINER=`root`@`%`\": " + reasonRoles},
+		},
+		{
+			name:         "mysql engine as first action",
+			replica:      "mysql",
+			ddl:          "ALTER TABLE t ENGINE=TokuDB, ADD COLUMN c int",
+			want:         "ALTER TABLE t ADD COLUMN c int",
+			wantRemovals: []string{`removed "ENGINE=TokuDB": ` + reasonEngine},
+		},
+		{
+			name:         "mysql discard tablespace",
+			replica:      "mysql",
+			ddl:          "ALTER TABLE t DISCARD TABLESPACE",
+			wantRemovals: []string{`removed "ALTER TABLE t DISCARD TABLESPACE": ` + reasonTablespaces},
+		},
+		{
+			name:         "sqlite collation",
+			replica:      "sqlite",
+			ddl:          "CREATE TABLE t (name TEXT COLLATE nocase, code TEXT COLLATE unicode_ci)",
+			want:         "CREATE TABLE t (name TEXT COLLATE nocase, code TEXT)",
+			wantRemovals: []string{`removed "COLLATE unicode_ci": ` + reasonCollation},
+		},
+		{
+			name:         "replica without rules",
+			replica:      "clickhouse",
+			ddl:          "CREATE TABLE t (id Int32) ENGINE = MergeTree ORDER BY id",
+			want:         "CREATE TABLE t (id Int32) ENGINE = MergeTree ORDER BY id",
+			wantRemovals: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change := ddlChange(proto.SourceDialect_SOURCE_DIALECT_UNSPECIFIED, "public", tt.ddl)
+			removals, keep := NewSanitizer(tt.replica, collations).Sanitize(change)
+			if keep != (tt.want != "") {
+				t.Fatalf("Sanitize() kept the statement = %v, want %v", keep, tt.want != "")
+			}
+			if keep && change.GetDdl().Ddl != tt.want {
+				t.Errorf("Sanitize() DDL =\n%s\nwant\n%s", change.GetDdl().Ddl, tt.want)
+			}
+			var got []string
+			for _, r := range removals {
+				got = append(got, r.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.wantRemovals, "\n") {
+				t.Errorf("Sanitize() removals =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.wantRemovals, "\n"))
+			}
+		})
+	}
+}
+
+func TestSanitizeKeepsCollationsWhenUnknown(t *testing.T) {
+	ddl := `CREATE TABLE users (name text COLLATE "de_DE.utf8")`
+	change := ddlChange(proto.SourceDialect_SOURCE_DIALECT_UNSPECIFIED, "public", ddl)
+	if removals, keep := NewSanitizer("postgresql", nil).Sanitize(change); !keep || len(removals) != 0 || change.GetDdl().Ddl != ddl {
+		t.Errorf("Sanitize() = %v, %v, %q; want the statement unchanged", removals, keep, change.GetDdl().Ddl)
+	}
+}
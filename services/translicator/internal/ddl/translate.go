@@ -0,0 +1,423 @@
+// Package ddl rewrites DDL from the source database for the replica's dialect
+package ddl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"kasho/pkg/transform"
+	"kasho/pkg/types"
+	"kasho/proto"
+)
+
+// Warning reports a column whose values may not survive on the replica as they were in
+// the source, e.g. because its replica type is shorter or less precise
+type Warning struct {
+	Table   string
+	Column  string // empty for a warning about the table
+	Message string
+}
+
+func (w Warning) String() string {
+	if w.Column == "" {
+		return fmt.Sprintf("%s: %s", w.Table, w.Message)
+	}
+	return fmt.Sprintf("%s.%s: %s", w.Table, w.Column, w.Message)
+}
+
+// Translator maps the column types of CREATE TABLE and ALTER TABLE statements from the
+// source database to the replica's dialect, with per-column overrides from the
+// type_mapping section of transforms.yml. DDL from a database of the replica's kind, or
+// a pair of dialects without a mapping, only gets the overrides.
+type Translator struct {
+	replica   string
+	overrides map[string]map[string]string
+}
+
+// NewTranslator creates a translator for a replica of the named dialect
+func NewTranslator(replica string, config transform.TypeMappingConfig) *Translator {
+	return &Translator{replica: replica, overrides: config.Columns}
+}
+
+// Translate rewrites the DDL of a change in place and returns warnings for columns whose
+// values may not survive the mapping. Other statements, and statements that can't be
+// parsed, are left unchanged.
+func (t *Translator) Translate(change *proto.Change) []Warning {
+	ddl := change.GetDdl()
+	if ddl == nil {
+		return nil
+	}
+	s := &statement{
+		translator: t,
+		schema:     change.Schema,
+	}
+	if s.schema == "" {
+		s.schema = change.Database
+	}
+	if source := types.SourceDialectString(change.SourceDialect); source != t.replica {
+		if pair, ok := pairMappings[dialectPair{source, t.replica}]; ok {
+			s.pair = &pair
+		}
+	}
+	if translated, ok := s.translate(ddl.Ddl); ok {
+		ddl.Ddl = translated
+	}
+	return s.warnings
+}
+
+// statement translates a single DDL statement
+type statement struct {
+	translator *Translator
+	pair       *pairMapping // nil when only overrides apply
+	schema     string       // schema or database of the change, for unqualified table names
+	changed    bool
+	warnings   []Warning
+}
+
+var (
+	createTablePattern = regexp.MustCompile(`(?is)^(\s*CREATE\s+(?:TEMPORARY\s+|TEMP\s+|UNLOGGED\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?)([^\s(]+)\s*\(`)
+	alterTablePattern  = regexp.MustCompile(`(?is)^(\s*ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?)(\S+)\s+(.*)$`)
+	addColumnPattern   = regexp.MustCompile(`(?is)^(ADD\s+(?:COLUMN\s+)?(?:IF\s+NOT\s+EXISTS\s+)?)(.*)$`)
+	alterTypePattern   = regexp.MustCompile(`(?is)^ALTER\s+(?:COLUMN\s+)?(\S+)\s+(?:SET\s+DATA\s+)?TYPE\s+(.*?)(\s+USING\s+.*)?$`)
+	modifyPattern      = regexp.MustCompile(`(?is)^MODIFY\s+(?:COLUMN\s+)?(.*)$`)
+	changePattern      = regexp.MustCompile(`(?is)^(CHANGE\s+(?:COLUMN\s+)?\S+\s+)(.*)$`)
+	uniqueKeyPattern   = regexp.MustCompile(`(?is)^UNIQUE\s+(?:KEY|INDEX)\s*(?:[^\s(]+\s*)?(\(.*)$`)
+)
+
+// translate returns the statement for the replica, or false if it is unchanged
+func (s *statement) translate(ddl string) (string, bool) {
+	body := strings.TrimSpace(ddl)
+	terminator := ""
+	if strings.HasSuffix(body, ";") {
+		body, terminator = strings.TrimSpace(strings.TrimSuffix(body, ";")), ";"
+	}
+
+	var translated string
+	if m := createTablePattern.FindStringSubmatchIndex(body); m != nil {
+		open := m[1] - 1
+		end := matchingParen(body, open)
+		if end < 0 {
+			return "", false
+		}
+		prefix, name := body[m[2]:m[3]], body[m[4]:m[5]]
+		options := body[end+1:]
+		defs := s.createDefinitions(unquoteName(name), body[open+1:end])
+		// Table options such as ENGINE or WITH are specific to the source database
+		if s.pair != nil && strings.TrimSpace(options) != "" {
+			options = ""
+			s.changed = true
+		}
+		translated = prefix + name + " (" + strings.Join(defs, ", ") + ")" + options
+	} else if m := alterTablePattern.FindStringSubmatch(body); m != nil {
+		table := unquoteName(m[2])
+		actions := splitTopLevel(m[3])
+		for i, action := range actions {
+			actions[i] = s.alterAction(table, action)
+		}
+		translated = m[1] + m[2] + " " + strings.Join(actions, ", ")
+	} else {
+		return "", false
+	}
+
+	if !s.changed {
+		return "", false
+	}
+	if s.pair != nil {
+		translated = requote(translated, s.pair.quotes[0], s.pair.quotes[1])
+	}
+	return translated + terminator, true
+}
+
+// createDefinitions translates the column and constraint definitions of CREATE TABLE
+func (s *statement) createDefinitions(table, body string) []string {
+	var defs []string
+	for _, def := range splitTopLevel(body) {
+		words := strings.Fields(def)
+		if len(words) == 0 {
+			continue
+		}
+		switch strings.ToUpper(words[0]) {
+		case "PRIMARY", "CONSTRAINT", "FOREIGN", "CHECK", "EXCLUDE", "LIKE":
+			defs = append(defs, def)
+			continue
+		case "UNIQUE":
+			if m := uniqueKeyPattern.FindStringSubmatch(def); m != nil && s.pair != nil && s.pair.dropIndexes {
+				def = "UNIQUE " + m[1]
+				s.changed = true
+			}
+			defs = append(defs, def)
+			continue
+		case "KEY", "INDEX", "FULLTEXT", "SPATIAL":
+			if s.pair != nil && s.pair.dropIndexes {
+				s.warn(table, "", fmt.Sprintf("index %q is dropped, create it on the replica", def))
+				s.changed = true
+				continue
+			}
+			defs = append(defs, def)
+			continue
+		}
+		defs = append(defs, s.column(table, def))
+	}
+	return defs
+}
+
+// alterAction translates an action of ALTER TABLE that adds or changes a column
+func (s *statement) alterAction(table, action string) string {
+	action = strings.TrimSpace(action)
+	if m := addColumnPattern.FindStringSubmatch(action); m != nil {
+		switch strings.ToUpper(strings.Fields(m[2] + " x")[0]) {
+		case "CONSTRAINT", "PRIMARY", "UNIQUE", "FOREIGN", "CHECK", "INDEX", "KEY", "FULLTEXT", "SPATIAL":
+			return action
+		}
+		return m[1] + s.column(table, m[2])
+	}
+	if m := alterTypePattern.FindStringSubmatch(action); m != nil {
+		replicaType, _ := s.mapType(table, unquoteName(m[1]), m[2])
+		// MySQL changes a column's type with MODIFY, which has no USING clause
+		if s.pair != nil && s.translator.replica == "mysql" {
+			s.changed = true
+			return "MODIFY COLUMN " + m[1] + " " + replicaType
+		}
+		return "ALTER COLUMN " + m[1] + " TYPE " + replicaType + m[3]
+	}
+	if m := modifyPattern.FindStringSubmatch(action); m != nil {
+		if s.pair != nil && s.translator.replica == "postgresql" {
+			// PostgreSQL changes a column's type with ALTER COLUMN; the rest of the
+			// definition doesn't carry over
+			name, rest, found := cutName(strings.TrimSpace(m[1]))
+			if !found {
+				return action
+			}
+			typeText, _ := splitType(rest)
+			replicaType, _ := s.mapType(table, unquoteName(name), typeText)
+			s.changed = true
+			return "ALTER COLUMN " + name + " TYPE " + replicaType
+		}
+		return "MODIFY COLUMN " + s.column(table, m[1])
+	}
+	if m := changePattern.FindStringSubmatch(action); m != nil {
+		return m[1] + s.column(table, m[2])
+	}
+	return action
+}
+
+// column translates a column definition such as "price numeric(10, 2) NOT NULL"
+func (s *statement) column(table, def string) string {
+	def = strings.TrimSpace(def)
+	name, rest, found := cutName(def)
+	if !found || strings.TrimSpace(rest) == "" {
+		return def
+	}
+	column := unquoteName(name)
+	typeText, attributes := splitType(rest)
+	if typeText == "" {
+		return def
+	}
+
+	replicaType, autoIncrement := s.mapType(table, column, typeText)
+	if s.pair != nil {
+		for _, r := range s.pair.attributes {
+			if !r.pattern.MatchString(attributes) {
+				continue
+			}
+			attributes = r.pattern.ReplaceAllString(attributes, r.replacement)
+			s.changed = true
+			if r.lossy != "" {
+				s.warn(table, column, r.lossy)
+			}
+		}
+		if autoIncrement && s.pair.autoIncrement != "" {
+			attributes = " " + s.pair.autoIncrement + attributes
+		}
+	}
+	return name + " " + replicaType + attributes
+}
+
+// mapType returns the replica type of a column and whether the replica should generate
+// its values, warning when values may not survive the mapping
+func (s *statement) mapType(table, column, sourceType string) (string, bool) {
+	if override, ok := s.override(table, column); ok {
+		s.changed = true
+		if reason := narrowing(sourceType, override); reason != "" {
+			s.warn(table, column, fmt.Sprintf("%s -> %s: %s", sourceType, override, reason))
+		}
+		return override, false
+	}
+	if s.pair == nil {
+		return sourceType, false
+	}
+
+	m, params, ok := s.pair.lookup(sourceType)
+	if !ok {
+		s.warn(table, column, fmt.Sprintf("%s has no mapping to %s and is kept as is", sourceType, s.translator.replica))
+		return sourceType, false
+	}
+	replicaType := m.format(params)
+	s.changed = true
+	reason := m.lossy
+	if params == "" && m.bareLossy != "" {
+		reason = m.bareLossy
+	}
+	if reason != "" {
+		s.warn(table, column, fmt.Sprintf("%s -> %s: %s", sourceType, replicaType, reason))
+	}
+	return replicaType, m.autoIncrement
+}
+
+// override returns the configured replica type of a column. Unqualified table names are
+// also looked up in the change's schema.
+func (s *statement) override(table, column string) (string, bool) {
+	columns, ok := s.translator.overrides[table]
+	if !ok && !strings.Contains(table, ".") && s.schema != "" {
+		columns, ok = s.translator.overrides[s.schema+"."+table]
+	}
+	if !ok {
+		return "", false
+	}
+	replicaType, ok := columns[column]
+	return replicaType, ok
+}
+
+func (s *statement) warn(table, column, message string) {
+	s.warnings = append(s.warnings, Warning{Table: table, Column: column, Message: message})
+}
+
+// attributeKeywords start the column attributes that follow a column's type
+var attributeKeywords = []string{
+	" NOT NULL", " NULL", " DEFAULT ", " PRIMARY KEY", " REFERENCES ", " UNIQUE", " CHECK",
+	" GENERATED ", " COLLATE ", " CHARACTER SET ", " CHARSET ", " AUTO_INCREMENT", " COMMENT ",
+	" CONSTRAINT ", " ON UPDATE ", " AS (", " USING ",
+}
+
+// splitType splits the rest of a column definition into its type and the attributes
+// after it, which keep their leading space
+func splitType(rest string) (string, string) {
+	rest = strings.TrimSpace(rest)
+	padded := " " + strings.ToUpper(rest) + " "
+	typeEnd := len(rest)
+	depth := 0
+	for i := 0; i < len(rest); i++ {
+		switch rest[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if depth != 0 {
+			continue
+		}
+		// padded[i] is the character before rest[i]
+		for _, keyword := range attributeKeywords {
+			if strings.HasPrefix(padded[i:], keyword) && isBoundary(padded, i+len(keyword)) {
+				typeEnd = i - 1
+				break
+			}
+		}
+		if typeEnd < len(rest) {
+			break
+		}
+	}
+	if typeEnd < 0 {
+		return "", rest
+	}
+	return strings.TrimSpace(rest[:typeEnd]), rest[typeEnd:]
+}
+
+// isBoundary reports whether a keyword ending at i isn't followed by more of a word
+func isBoundary(s string, i int) bool {
+	if i >= len(s) || s[i-1] == ' ' || s[i-1] == '(' {
+		return true
+	}
+	c := s[i]
+	return !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_')
+}
+
+// matchingParen returns the index of the parenthesis closing the one at open, or -1
+func matchingParen(s string, open int) int {
+	depth := 0
+	var quote byte
+	for i := open; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitTopLevel splits on commas outside parentheses and quotes
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote rune
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		parts = append(parts, last)
+	}
+	return parts
+}
+
+// cutName splits a leading, possibly quoted, identifier from the rest of s
+func cutName(s string) (string, string, bool) {
+	if s == "" {
+		return "", "", false
+	}
+	if q := s[0]; q == '"' || q == '`' {
+		end := strings.IndexByte(s[1:], q)
+		if end < 0 {
+			return "", "", false
+		}
+		return s[:end+2], s[end+2:], true
+	}
+	name, rest, _ := strings.Cut(s, " ")
+	return name, rest, name != ""
+}
+
+// unquoteName removes identifier quotes from a possibly qualified name
+func unquoteName(name string) string {
+	return strings.NewReplacer(`"`, "", "`", "").Replace(name)
+}
+
+// requote replaces identifier quotes outside string literals
+func requote(s string, from, to byte) string {
+	b := []byte(s)
+	inString := false
+	for i, c := range b {
+		switch {
+		case c == '\'':
+			inString = !inString
+		case c == from && !inString:
+			b[i] = to
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,165 @@
+package ddl
+
+import (
+	"strings"
+	"testing"
+
+	"kasho/pkg/transform"
+	"kasho/proto"
+)
+
+func ddlChange(source proto.SourceDialect, schema, ddl string) *proto.Change {
+	return &proto.Change{
+		SourceDialect: source,
+		Schema:        schema,
+		Data:          &proto.Change_Ddl{Ddl: &proto.DDLData{Ddl: ddl}},
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name         string
+		replica      string
+		source       proto.SourceDialect
+		overrides    map[string]map[string]string
+		ddl          string
+		want         string
+		wantWarnings []string
+	}{
+		{
+			name:    "postgresql create table for mysql",
+			replica: "mysql",
+			source:  proto.SourceDialect_SOURCE_DIALECT_POSTGRESQL,
+			ddl:     `CREATE TABLE public.users (id serial PRIMARY KEY, email text NOT NULL, balance numeric(10,2) DEFAULT 0, tags text[], "order" integer);`,
+			want:    "CREATE TABLE public.users (id INT AUTO_INCREMENT PRIMARY KEY, email LONGTEXT NOT NULL, balance DECIMAL(10, 2) DEFAULT 0, tags JSON, `order` INT);",
+			wantWarnings: []string{
+				"public.users.tags: text[] -> JSON: arrays are stored as JSON",
+			},
+		},
+		{
+			name:    "mysql create table for postgresql",
+			replica: "postgresql",
+			source:  proto.SourceDialect_SOURCE_DIALECT_MYSQL,
+			ddl: "CREATE TABLE `orders` (`id` int unsigned NOT NULL AUTO_INCREMENT, `status` enum('new','paid') DEFAULT 'new', " +
+				"`paid` tinyint(1) NOT NULL DEFAULT '0', `note` varchar(255) CHARACTER SET utf8mb4 COMMENT 'free text', " +
+				"`updated_at` datetime(3) ON UPDATE CURRENT_TIMESTAMP(3), PRIMARY KEY (`id`), KEY `idx_status` (`status`), " +
+				"UNIQUE KEY `uq_note` (`note`)) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+			want: `CREATE TABLE "orders" ("id" bigint NOT NULL GENERATED BY DEFAULT AS IDENTITY, "status" text DEFAULT 'new', ` +
+				`"paid" boolean NOT NULL DEFAULT '0', "note" varchar(255), "updated_at" timestamp(3), PRIMARY KEY ("id"), UNIQUE ("note"))`,
+			wantWarnings: []string{
+				"orders.status: enum('new','paid') -> text: allowed values are not enforced",
+				"orders.updated_at: ON UPDATE CURRENT_TIMESTAMP has no PostgreSQL equivalent and is dropped",
+				"orders: index \"KEY `idx_status` (`status`)\" is dropped, create it on the replica",
+			},
+		},
+		{
+			name:      "override for an indexed text column",
+			replica:   "mysql",
+			source:    proto.SourceDialect_SOURCE_DIALECT_POSTGRESQL,
+			overrides: map[string]map[string]string{"public.users": {"email": "VARCHAR(1024)"}},
+			ddl:       "CREATE TABLE users (id bigint PRIMARY KEY, email text)",
+			want:      "CREATE TABLE users (id BIGINT PRIMARY KEY, email VARCHAR(1024))",
+			wantWarnings: []string{
+				"users.email: text -> VARCHAR(1024): values longer than 1024 characters don't fit",
+			},
+		},
+		{
+			name:      "override on the same dialect",
+			replica:   "postgresql",
+			source:    proto.SourceDialect_SOURCE_DIALECT_POSTGRESQL,
+			overrides: map[string]map[string]string{"public.users": {"email": "varchar(320)"}},
+			ddl:       "ALTER TABLE public.users ADD COLUMN email varchar(255) NOT NULL",
+			want:      "ALTER TABLE public.users ADD COLUMN email varchar(320) NOT NULL",
+		},
+		{
+			name:    "postgresql alter table for mysql",
+			replica: "mysql",
+			source:  proto.SourceDialect_SOURCE_DIALECT_POSTGRESQL,
+			ddl:     "ALTER TABLE public.users ADD COLUMN age smallint, ALTER COLUMN email TYPE varchar(100) USING email::varchar",
+			want:    "ALTER TABLE public.users ADD COLUMN age SMALLINT, MODIFY COLUMN email VARCHAR(100)",
+		},
+		{
+			name:    "mysql modify for postgresql",
+			replica: "postgresql",
+			source:  proto.SourceDialect_SOURCE_DIALECT_MYSQL,
+			ddl:     "ALTER TABLE orders MODIFY COLUMN total decimal(12,2) NOT NULL",
+			want:    "ALTER TABLE orders ALTER COLUMN total TYPE numeric(12, 2)",
+		},
+		{
+			name:    "same dialect without overrides",
+			replica: "postgresql",
+			source:  proto.SourceDialect_SOURCE_DIALECT_POSTGRESQL,
+			ddl:     "CREATE TABLE t (id serial PRIMARY KEY) WITH (fillfactor = 70);",
+			want:    "CREATE TABLE t (id serial PRIMARY KEY) WITH (fillfactor = 70);",
+		},
+		{
+			name:    "other statements",
+			replica: "mysql",
+			source:  proto.SourceDialect_SOURCE_DIALECT_POSTGRESQL,
+			ddl:     "CREATE INDEX idx_users_email ON users (email)",
+			want:    "CREATE INDEX idx_users_email ON users (email)",
+		},
+		{
+			name:    "type without a mapping",
+			replica: "mysql",
+			source:  proto.SourceDialect_SOURCE_DIALECT_POSTGRESQL,
+			ddl:     "CREATE TABLE t (mood mood_type)",
+			want:    "CREATE TABLE t (mood mood_type)",
+			wantWarnings: []string{
+				"t.mood: mood_type has no mapping to mysql and is kept as is",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			translator := NewTranslator(tt.replica, transform.TypeMappingConfig{Columns: tt.overrides})
+			change := ddlChange(tt.source, "public", tt.ddl)
+			warnings := translator.Translate(change)
+
+			if got := change.GetDdl().Ddl; got != tt.want {
+				t.Errorf("Translate() ddl =\n%s\nwant\n%s", got, tt.want)
+			}
+			var got []string
+			for _, w := range warnings {
+				got = append(got, w.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.wantWarnings, "\n") {
+				t.Errorf("Translate() warnings = %q, want %q", got, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestTranslate_DML(t *testing.T) {
+	translator := NewTranslator("mysql", transform.TypeMappingConfig{})
+	change := &proto.Change{Data: &proto.Change_Dml{Dml: &proto.DMLData{Table: "users", Kind: "insert"}}}
+	if warnings := translator.Translate(change); warnings != nil {
+		t.Errorf("Translate() of DML = %v, want no warnings", warnings)
+	}
+}
+
+func TestNarrowing(t *testing.T) {
+	tests := []struct {
+		source  string
+		replica string
+		want    string
+	}{
+		{"text", "VARCHAR(1024)", "values longer than 1024 characters don't fit"},
+		{"varchar(255)", "VARCHAR(1024)", ""},
+		{"character varying(2000)", "varchar(1024)", "values longer than 1024 characters don't fit"},
+		{"numeric(12,4)", "DECIMAL(10, 2)", "fractional digits beyond 2 are rounded"},
+		{"numeric(14, 2)", "DECIMAL(10,2)", "values with more than 8 integer digits don't fit"},
+		{"numeric", "DECIMAL(20, 4)", "unbounded numeric values are limited to 16 integer and 4 fractional digits"},
+		{"bigint", "INT", "values outside the range of int don't fit"},
+		{"integer", "BIGINT", ""},
+		{"text", "LONGTEXT", ""},
+		{"uuid", "CHAR(36)", ""},
+	}
+
+	for _, tt := range tests {
+		if got := narrowing(tt.source, tt.replica); got != tt.want {
+			t.Errorf("narrowing(%q, %q) = %q, want %q", tt.source, tt.replica, got, tt.want)
+		}
+	}
+}
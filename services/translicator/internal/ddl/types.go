@@ -0,0 +1,297 @@
+package ddl
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// mapping is how a source column type is written on the replica
+type mapping struct {
+	typ           string // replica type, with %s for the source type's parameters, e.g. "(10, 2)"
+	bare          string // replica type when the source type has no parameters, if different
+	lossy         string // why values may not survive the mapping; empty if they do
+	bareLossy     string // why values of the type without parameters may not survive
+	autoIncrement bool   // the source type generates values, e.g. serial
+}
+
+// format returns the replica type for a source type with the given parameters
+func (m mapping) format(params string) string {
+	if !strings.Contains(m.typ, "%s") {
+		return m.typ
+	}
+	if params == "" {
+		if m.bare != "" {
+			return m.bare
+		}
+		return fmt.Sprintf(m.typ, "")
+	}
+	return fmt.Sprintf(m.typ, "("+params+")")
+}
+
+// rewrite replaces a column attribute, e.g. AUTO_INCREMENT with an identity
+type rewrite struct {
+	pattern     *regexp.Regexp
+	replacement string
+	lossy       string // why dropping or replacing the attribute changes behavior
+}
+
+// pairMapping describes how DDL of a source dialect is written for a replica dialect
+type pairMapping struct {
+	types      map[string]mapping
+	attributes []rewrite
+	// quotes are the identifier quotes of the source and the replica
+	quotes [2]byte
+	// autoIncrement is the attribute that makes the replica generate a column's values
+	autoIncrement string
+	// dropIndexes drops index definitions inside CREATE TABLE, which the replica can't
+	// declare there
+	dropIndexes bool
+}
+
+// dialectPair is a source and a replica dialect, by name
+type dialectPair struct {
+	source  string
+	replica string
+}
+
+// pairMappings maps the column types of DDL between kinds of databases. Types are keyed by
+// their lower-case name without parameters, e.g. "timestamp with time zone"; a key with
+// parameters, e.g. "tinyint(1)", takes precedence. Arrays are keyed as "array".
+var pairMappings = map[dialectPair]pairMapping{
+	{"postgresql", "mysql"}: {
+		types: map[string]mapping{
+			"smallint":                    {typ: "SMALLINT"},
+			"int2":                        {typ: "SMALLINT"},
+			"integer":                     {typ: "INT"},
+			"int":                         {typ: "INT"},
+			"int4":                        {typ: "INT"},
+			"bigint":                      {typ: "BIGINT"},
+			"int8":                        {typ: "BIGINT"},
+			"smallserial":                 {typ: "SMALLINT", autoIncrement: true},
+			"serial2":                     {typ: "SMALLINT", autoIncrement: true},
+			"serial":                      {typ: "INT", autoIncrement: true},
+			"serial4":                     {typ: "INT", autoIncrement: true},
+			"bigserial":                   {typ: "BIGINT", autoIncrement: true},
+			"serial8":                     {typ: "BIGINT", autoIncrement: true},
+			"real":                        {typ: "FLOAT"},
+			"float4":                      {typ: "FLOAT"},
+			"double precision":            {typ: "DOUBLE"},
+			"float8":                      {typ: "DOUBLE"},
+			"float":                       {typ: "DOUBLE"},
+			"numeric":                     {typ: "DECIMAL%s", bare: "DECIMAL(65, 30)", bareLossy: "unbounded numeric values are limited to 35 integer and 30 fractional digits"},
+			"decimal":                     {typ: "DECIMAL%s", bare: "DECIMAL(65, 30)", bareLossy: "unbounded numeric values are limited to 35 integer and 30 fractional digits"},
+			"money":                       {typ: "DECIMAL(19, 2)", lossy: "the currency symbol and formatting are not kept"},
+			"boolean":                     {typ: "TINYINT(1)"},
+			"bool":                        {typ: "TINYINT(1)"},
+			"character":                   {typ: "CHAR%s", bare: "CHAR(1)"},
+			"char":                        {typ: "CHAR%s", bare: "CHAR(1)"},
+			"character varying":           {typ: "VARCHAR%s", bare: "LONGTEXT"},
+			"varchar":                     {typ: "VARCHAR%s", bare: "LONGTEXT"},
+			"text":                        {typ: "LONGTEXT"},
+			"citext":                      {typ: "LONGTEXT"},
+			"bytea":                       {typ: "LONGBLOB"},
+			"uuid":                        {typ: "CHAR(36)"},
+			"json":                        {typ: "JSON"},
+			"jsonb":                       {typ: "JSON", lossy: "key order and duplicate keys follow MySQL's JSON normalization"},
+			"xml":                         {typ: "LONGTEXT"},
+			"date":                        {typ: "DATE"},
+			"time":                        {typ: "TIME%s", bare: "TIME(6)"},
+			"time without time zone":      {typ: "TIME%s", bare: "TIME(6)"},
+			"time with time zone":         {typ: "TIME%s", bare: "TIME(6)", lossy: "time zone offsets are not kept"},
+			"timetz":                      {typ: "TIME%s", bare: "TIME(6)", lossy: "time zone offsets are not kept"},
+			"timestamp":                   {typ: "DATETIME%s", bare: "DATETIME(6)"},
+			"timestamp without time zone": {typ: "DATETIME%s", bare: "DATETIME(6)"},
+			"timestamp with time zone":    {typ: "DATETIME%s", bare: "DATETIME(6)"},
+			"timestamptz":                 {typ: "DATETIME%s", bare: "DATETIME(6)"},
+			"interval":                    {typ: "VARCHAR(255)", lossy: "intervals are stored as text"},
+			"inet":                        {typ: "VARCHAR(43)"},
+			"cidr":                        {typ: "VARCHAR(43)"},
+			"macaddr":                     {typ: "VARCHAR(17)"},
+			"bit":                         {typ: "BIT%s", bare: "BIT(1)"},
+			"bit varying":                 {typ: "BIT%s", bare: "BIT(64)", lossy: "bit strings are padded to a fixed length"},
+			"varbit":                      {typ: "BIT%s", bare: "BIT(64)", lossy: "bit strings are padded to a fixed length"},
+			"tsvector":                    {typ: "LONGTEXT", lossy: "text search vectors are stored as text"},
+			"hstore":                      {typ: "JSON"},
+			"array":                       {typ: "JSON", lossy: "arrays are stored as JSON"},
+			"geometry":                    {typ: "GEOMETRY"},
+			"geography":                   {typ: "GEOMETRY", lossy: "geodetic calculations are not kept"},
+			"point":                       {typ: "POINT"},
+		},
+		attributes: []rewrite{
+			{pattern: regexp.MustCompile(`(?i)\s+GENERATED\s+(?:ALWAYS|BY\s+DEFAULT)\s+AS\s+IDENTITY(?:\s*\([^)]*\))?`), replacement: " AUTO_INCREMENT"},
+			{pattern: regexp.MustCompile(`(?i)\s+COLLATE\s+"[^"]*"`), replacement: ""},
+		},
+		quotes:        [2]byte{'"', '`'},
+		autoIncrement: "AUTO_INCREMENT",
+	},
+	{"mysql", "postgresql"}: {
+		types: map[string]mapping{
+			"tinyint(1)":         {typ: "boolean"},
+			"tinyint":            {typ: "smallint"},
+			"tinyint unsigned":   {typ: "smallint"},
+			"smallint":           {typ: "smallint"},
+			"smallint unsigned":  {typ: "integer"},
+			"mediumint":          {typ: "integer"},
+			"mediumint unsigned": {typ: "integer"},
+			"int":                {typ: "integer"},
+			"integer":            {typ: "integer"},
+			"int unsigned":       {typ: "bigint"},
+			"integer unsigned":   {typ: "bigint"},
+			"bigint":             {typ: "bigint"},
+			"bigint unsigned":    {typ: "numeric(20, 0)"},
+			"float":              {typ: "real"},
+			"double":             {typ: "double precision"},
+			"double precision":   {typ: "double precision"},
+			"real":               {typ: "double precision"},
+			"decimal":            {typ: "numeric%s", bare: "numeric(10, 0)"},
+			"numeric":            {typ: "numeric%s", bare: "numeric(10, 0)"},
+			"dec":                {typ: "numeric%s", bare: "numeric(10, 0)"},
+			"bool":               {typ: "boolean"},
+			"boolean":            {typ: "boolean"},
+			"bit":                {typ: "bit%s", bare: "bit(1)"},
+			"char":               {typ: "char%s", bare: "char(1)"},
+			"varchar":            {typ: "varchar%s"},
+			"tinytext":           {typ: "text"},
+			"text":               {typ: "text"},
+			"mediumtext":         {typ: "text"},
+			"longtext":           {typ: "text"},
+			"binary":             {typ: "bytea"},
+			"varbinary":          {typ: "bytea"},
+			"tinyblob":           {typ: "bytea"},
+			"blob":               {typ: "bytea"},
+			"mediumblob":         {typ: "bytea"},
+			"longblob":           {typ: "bytea"},
+			"json":               {typ: "jsonb"},
+			"enum":               {typ: "text", lossy: "allowed values are not enforced"},
+			"set":                {typ: "text", lossy: "allowed values are not enforced"},
+			"date":               {typ: "date"},
+			"time":               {typ: "time%s"},
+			"datetime":           {typ: "timestamp%s"},
+			"timestamp":          {typ: "timestamptz%s"},
+			"year":               {typ: "smallint"},
+			"geometry":           {typ: "geometry"},
+			"point":              {typ: "geometry(Point)"},
+			"linestring":         {typ: "geometry(LineString)"},
+			"polygon":            {typ: "geometry(Polygon)"},
+		},
+		attributes: []rewrite{
+			{pattern: regexp.MustCompile(`(?i)\s+AUTO_INCREMENT\b`), replacement: " GENERATED BY DEFAULT AS IDENTITY"},
+			{pattern: regexp.MustCompile(`(?i)\s+(?:CHARACTER\s+SET|CHARSET)\s+\S+`), replacement: ""},
+			{pattern: regexp.MustCompile(`(?i)\s+COLLATE\s+\S+`), replacement: ""},
+			{pattern: regexp.MustCompile(`(?i)\s+COMMENT\s+'(?:[^']|'')*'`), replacement: ""},
+			{pattern: regexp.MustCompile(`(?i)\s+ON\s+UPDATE\s+CURRENT_TIMESTAMP(?:\s*\(\s*\d*\s*\))?`), replacement: "", lossy: "ON UPDATE CURRENT_TIMESTAMP has no PostgreSQL equivalent and is dropped"},
+		},
+		quotes:      [2]byte{'`', '"'},
+		dropIndexes: true,
+	},
+}
+
+var typeParamsPattern = regexp.MustCompile(`\(([^)]*)\)`)
+
+// parseType splits a column type such as "timestamp(3) with time zone" into its
+// lower-case name, "timestamp with time zone", and parameters, "3"
+func parseType(sqlType string) (string, string) {
+	t := strings.ToLower(strings.TrimSpace(sqlType))
+	var params string
+	if m := typeParamsPattern.FindStringSubmatchIndex(t); m != nil {
+		parts := strings.Split(t[m[2]:m[3]], ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		params = strings.Join(parts, ", ")
+		t = t[:m[0]] + " " + t[m[1]:]
+	}
+	t = strings.ReplaceAll(t, " zerofill", "")
+	return strings.Join(strings.Fields(t), " "), params
+}
+
+// lookup finds the mapping of a source type
+func (p pairMapping) lookup(sqlType string) (mapping, string, bool) {
+	name, params := parseType(sqlType)
+	if strings.HasSuffix(name, "[]") || strings.HasSuffix(name, " array") {
+		m, ok := p.types["array"]
+		return m, "", ok
+	}
+	if params != "" {
+		if m, ok := p.types[name+"("+params+")"]; ok {
+			return m, params, true
+		}
+	}
+	m, ok := p.types[name]
+	return m, params, ok
+}
+
+// Type families compared when checking an override for lost values
+var (
+	unboundedTextTypes = []string{"text", "tinytext", "mediumtext", "longtext", "clob", "nclob", "citext", "json", "jsonb", "xml"}
+	lengthTextTypes    = []string{"char", "character", "varchar", "character varying", "nchar", "nvarchar", "varchar2", "nvarchar2"}
+	decimalTypes       = []string{"numeric", "decimal", "dec", "number"}
+	integerSizes       = map[string]int{
+		"tinyint": 1, "smallint": 2, "int2": 2, "mediumint": 3,
+		"int": 4, "integer": 4, "int4": 4, "serial": 4, "serial4": 4,
+		"bigint": 8, "int8": 8, "bigserial": 8, "serial8": 8,
+	}
+)
+
+// narrowing reports why values of a source type may not fit a replica type, or "" if
+// they do as far as can be told from the types
+func narrowing(sourceType, replicaType string) string {
+	source, sourceParams := parseType(sourceType)
+	replica, replicaParams := parseType(replicaType)
+
+	if slices.Contains(lengthTextTypes, replica) {
+		length, err := strconv.Atoi(replicaParams)
+		if err != nil {
+			return ""
+		}
+		sourceLength, bounded := 0, false
+		if slices.Contains(lengthTextTypes, source) {
+			sourceLength, bounded = atoi(sourceParams)
+		} else if !slices.Contains(unboundedTextTypes, source) {
+			return ""
+		}
+		if !bounded || sourceLength > length {
+			return fmt.Sprintf("values longer than %d characters don't fit", length)
+		}
+		return ""
+	}
+
+	if slices.Contains(decimalTypes, replica) && slices.Contains(decimalTypes, source) && replicaParams != "" {
+		precision, scale := decimalParams(replicaParams)
+		if sourceParams == "" {
+			return fmt.Sprintf("unbounded numeric values are limited to %d integer and %d fractional digits", precision-scale, scale)
+		}
+		sourcePrecision, sourceScale := decimalParams(sourceParams)
+		if sourcePrecision-sourceScale > precision-scale {
+			return fmt.Sprintf("values with more than %d integer digits don't fit", precision-scale)
+		}
+		if sourceScale > scale {
+			return fmt.Sprintf("fractional digits beyond %d are rounded", scale)
+		}
+		return ""
+	}
+
+	source = strings.TrimSuffix(source, " unsigned")
+	if size, ok := integerSizes[replica]; ok {
+		if sourceSize, ok := integerSizes[source]; ok && sourceSize > size {
+			return fmt.Sprintf("values outside the range of %s don't fit", replica)
+		}
+	}
+	return ""
+}
+
+// decimalParams parses the precision and scale of a decimal type, e.g. "10, 2"
+func decimalParams(params string) (int, int) {
+	p, s, _ := strings.Cut(params, ",")
+	precision, _ := atoi(p)
+	scale, _ := atoi(s)
+	return precision, scale
+}
+
+func atoi(s string) (int, bool) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	return n, err == nil
+}
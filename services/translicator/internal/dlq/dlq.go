@@ -0,0 +1,74 @@
+package dlq
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"kasho/proto"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Entry is a single dead-lettered change with the reason it could not be applied
+type Entry struct {
+	Time      time.Time       `json:"time"`
+	Position  string          `json:"position"`
+	Table     string          `json:"table,omitempty"`
+	Reason    string          `json:"reason"`
+	Statement string          `json:"statement,omitempty"`
+	Change    json.RawMessage `json:"change"`
+}
+
+// Writer appends dead-lettered changes to a JSON Lines file
+type Writer struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Open opens (or creates) the dead-letter file at path for appending
+func Open(path string) (*Writer, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead-letter file: %w", err)
+	}
+	return &Writer{file: file}, nil
+}
+
+// Write records a change that could not be applied
+func (w *Writer) Write(change *proto.Change, reason, statement string) error {
+	data, err := protojson.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("failed to marshal change: %w", err)
+	}
+
+	entry := Entry{
+		Time:      time.Now().UTC(),
+		Position:  change.Position,
+		Reason:    reason,
+		Statement: statement,
+		Change:    data,
+	}
+	if dml := change.GetDml(); dml != nil {
+		entry.Table = dml.Table
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead-letter entry: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write dead-letter entry: %w", err)
+	}
+	return nil
+}
+
+// Close closes the dead-letter file
+func (w *Writer) Close() error {
+	return w.file.Close()
+}
@@ -0,0 +1,75 @@
+package dlq
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kasho/proto"
+)
+
+func TestWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dlq.jsonl")
+	w, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() unexpected error: %v", err)
+	}
+
+	change := &proto.Change{
+		Position: "0/100",
+		Type:     "dml",
+		Data: &proto.Change_Dml{Dml: &proto.DMLData{
+			Table:       "public.users",
+			Kind:        "update",
+			ColumnNames: []string{"name"},
+			ColumnValues: []*proto.ColumnValue{
+				{Value: &proto.ColumnValue_StringValue{StringValue: "Jane"}},
+			},
+		}},
+	}
+	if err := w.Write(change, "row not found", "UPDATE public.users SET name = 'Jane' WHERE id = 1;"); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	if err := w.Write(&proto.Change{Position: "0/200", Type: "ddl"}, "failed", ""); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open dead-letter file: %v", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid entry %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if entries[0].Position != "0/100" || entries[0].Table != "public.users" || entries[0].Reason != "row not found" {
+		t.Errorf("unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Table != "" {
+		t.Errorf("DDL entry should not have a table, got %q", entries[1].Table)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(entries[0].Change, &decoded); err != nil {
+		t.Fatalf("change is not valid JSON: %v", err)
+	}
+	if decoded["position"] != "0/100" {
+		t.Errorf("change position = %v, want 0/100", decoded["position"])
+	}
+}
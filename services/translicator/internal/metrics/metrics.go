@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"expvar"
+	"net/http"
+)
+
+// Stream connection metrics, published under /debug/vars
+var (
+	StreamReconnects         = expvar.NewInt("stream_reconnects")
+	StreamChangesReceived    = expvar.NewInt("stream_changes_received")
+	StreamHeartbeatsReceived = expvar.NewInt("stream_heartbeats_received")
+	StreamPosition           = expvar.NewString("stream_position")
+	StreamSourcePosition     = expvar.NewString("stream_source_position")
+	// Changes of streamed transactions held until their transaction commits or aborts
+	StreamSpooledChanges = expvar.NewInt("stream_spooled_changes")
+	// Application messages, e.g. markers written with pg_logical_emit_message
+	StreamMessagesReceived = expvar.NewInt("stream_messages_received")
+)
+
+// Apply error metrics: failed statements per error class (see apply.ErrorClass),
+// retries of statements that failed with a transient error, and statements cut off by
+// their timeout per change kind (insert, update, delete, ddl)
+var (
+	ApplyErrors   = expvar.NewMap("apply_errors")
+	ApplyRetries  = expvar.NewInt("apply_retries")
+	ApplyTimeouts = expvar.NewMap("apply_timeouts")
+)
+
+// Prepared statement cache lookups by outcome: "hit", "miss" (statement prepared),
+// and "evict" (least recently used statement closed to make room)
+var PreparedStatements = expvar.NewMap("prepared_statements")
+
+// Handler returns an HTTP handler that serves all published metrics as JSON
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.Handle("/metrics", expvar.Handler())
+	mux.Handle("/metrics/prometheus", PrometheusHandler(Tables))
+	return mux
+}
+
+// Serve exposes the metrics endpoint on the given address
+func Serve(addr string) error {
+	return http.ListenAndServe(addr, Handler())
+}
@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TableStats are the apply counters of a single table
+type TableStats struct {
+	Inserts             int64     `json:"inserts"`
+	Updates             int64     `json:"updates"`
+	Deletes             int64     `json:"deletes"`
+	Errors              int64     `json:"errors"`
+	LastPosition        string    `json:"last_position,omitempty"`
+	LastAppliedAt       time.Time `json:"last_applied_at,omitzero"`
+	LastApplyLatencySec float64   `json:"last_apply_latency_seconds"`
+}
+
+// TableStatsRegistry collects apply statistics per table
+type TableStatsRegistry struct {
+	mu     sync.Mutex
+	tables map[string]*TableStats
+	now    func() time.Time
+}
+
+// NewTableStatsRegistry creates an empty registry
+func NewTableStatsRegistry() *TableStatsRegistry {
+	return &TableStatsRegistry{tables: make(map[string]*TableStats), now: time.Now}
+}
+
+// Tables holds the translicator's per-table statistics, published under /debug/vars as "tables"
+var Tables = NewTableStatsRegistry()
+
+func init() {
+	expvar.Publish("tables", expvar.Func(func() any { return Tables.Snapshot() }))
+}
+
+func (r *TableStatsRegistry) get(table string) *TableStats {
+	stats, ok := r.tables[table]
+	if !ok {
+		stats = &TableStats{}
+		r.tables[table] = stats
+	}
+	return stats
+}
+
+// RecordApply counts a change of the given kind (insert, update, delete) applied to a table
+func (r *TableStatsRegistry) RecordApply(table, kind, position string, latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := r.get(table)
+	switch kind {
+	case "insert":
+		stats.Inserts++
+	case "update":
+		stats.Updates++
+	case "delete":
+		stats.Deletes++
+	}
+	stats.LastPosition = position
+	stats.LastAppliedAt = r.now()
+	stats.LastApplyLatencySec = latency.Seconds()
+}
+
+// RecordError counts a change to a table that could not be transformed or applied
+func (r *TableStatsRegistry) RecordError(table string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.get(table).Errors++
+}
+
+// Snapshot returns a copy of the statistics of every table
+func (r *TableStatsRegistry) Snapshot() map[string]TableStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	snapshot := make(map[string]TableStats, len(r.tables))
+	for table, stats := range r.tables {
+		snapshot[table] = *stats
+	}
+	return snapshot
+}
+
+// WritePrometheus writes the statistics in the Prometheus text format, labelled by table
+func (r *TableStatsRegistry) WritePrometheus(w io.Writer) error {
+	snapshot := r.Snapshot()
+	tables := make([]string, 0, len(snapshot))
+	for table := range snapshot {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	var b strings.Builder
+	b.WriteString("# HELP kasho_translicator_table_changes_applied_total Changes applied to the replica by table and kind.\n")
+	b.WriteString("# TYPE kasho_translicator_table_changes_applied_total counter\n")
+	for _, table := range tables {
+		stats := snapshot[table]
+		for _, kind := range []struct {
+			name  string
+			count int64
+		}{{"insert", stats.Inserts}, {"update", stats.Updates}, {"delete", stats.Deletes}} {
+			fmt.Fprintf(&b, "kasho_translicator_table_changes_applied_total{table=%q,kind=%q} %d\n", table, kind.name, kind.count)
+		}
+	}
+	b.WriteString("# HELP kasho_translicator_table_errors_total Changes that failed to transform or apply by table.\n")
+	b.WriteString("# TYPE kasho_translicator_table_errors_total counter\n")
+	for _, table := range tables {
+		fmt.Fprintf(&b, "kasho_translicator_table_errors_total{table=%q} %d\n", table, snapshot[table].Errors)
+	}
+	b.WriteString("# HELP kasho_translicator_table_last_applied_timestamp_seconds Unix time of the last change applied by table.\n")
+	b.WriteString("# TYPE kasho_translicator_table_last_applied_timestamp_seconds gauge\n")
+	for _, table := range tables {
+		if at := snapshot[table].LastAppliedAt; !at.IsZero() {
+			fmt.Fprintf(&b, "kasho_translicator_table_last_applied_timestamp_seconds{table=%q} %g\n", table, float64(at.UnixMilli())/1000)
+		}
+	}
+	b.WriteString("# HELP kasho_translicator_table_apply_latency_seconds Time taken to apply the last change by table.\n")
+	b.WriteString("# TYPE kasho_translicator_table_apply_latency_seconds gauge\n")
+	for _, table := range tables {
+		fmt.Fprintf(&b, "kasho_translicator_table_apply_latency_seconds{table=%q} %g\n", table, snapshot[table].LastApplyLatencySec)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// PrometheusHandler serves the per-table statistics in the Prometheus text format
+func PrometheusHandler(r *TableStatsRegistry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		r.WritePrometheus(w)
+	})
+}
+
+// Status is the translicator's replication status with a per-table breakdown
+type Status struct {
+	Position       string                `json:"position"`
+	SourcePosition string                `json:"source_position"`
+	LagSeconds     float64               `json:"lag_seconds"`
+	CaughtUpAt     time.Time             `json:"caught_up_at"`
+	Paused         bool                  `json:"paused"`
+	Approvals      int                   `json:"pending_approvals"` // changes held for approval
+	Tables         map[string]TableStats `json:"tables"`
+}
+
+// StatusHandler serves the status returned by status as JSON
+func StatusHandler(status func() Status) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status())
+	})
+}
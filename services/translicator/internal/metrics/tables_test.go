@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTableStatsRegistry(t *testing.T) {
+	r := NewTableStatsRegistry()
+	appliedAt := time.Unix(1700000000, 0).UTC()
+	r.now = func() time.Time { return appliedAt }
+
+	r.RecordApply("public.users", "insert", "0/100", 2*time.Millisecond)
+	r.RecordApply("public.users", "update", "0/200", 3*time.Millisecond)
+	r.RecordApply("public.orders", "delete", "0/300", time.Millisecond)
+	r.RecordError("public.orders")
+
+	snapshot := r.Snapshot()
+	users := snapshot["public.users"]
+	if users.Inserts != 1 || users.Updates != 1 || users.Deletes != 0 || users.Errors != 0 {
+		t.Errorf("users counters = %+v", users)
+	}
+	if users.LastPosition != "0/200" || users.LastApplyLatencySec != 0.003 || !users.LastAppliedAt.Equal(appliedAt) {
+		t.Errorf("users last apply = %+v", users)
+	}
+	orders := snapshot["public.orders"]
+	if orders.Deletes != 1 || orders.Errors != 1 {
+		t.Errorf("orders counters = %+v", orders)
+	}
+
+	// The snapshot is a copy
+	users.Inserts = 100
+	if r.Snapshot()["public.users"].Inserts != 1 {
+		t.Error("Snapshot() should not share state with the registry")
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	r := NewTableStatsRegistry()
+	r.now = func() time.Time { return time.Unix(1700000000, 500000000) }
+	r.RecordApply("public.users", "insert", "0/100", 250*time.Millisecond)
+	r.RecordError("public.orders")
+
+	var b strings.Builder
+	if err := r.WritePrometheus(&b); err != nil {
+		t.Fatalf("WritePrometheus() unexpected error: %v", err)
+	}
+	out := b.String()
+
+	for _, line := range []string{
+		"# TYPE kasho_translicator_table_changes_applied_total counter",
+		`kasho_translicator_table_changes_applied_total{table="public.users",kind="insert"} 1`,
+		`kasho_translicator_table_changes_applied_total{table="public.users",kind="update"} 0`,
+		`kasho_translicator_table_errors_total{table="public.orders"} 1`,
+		`kasho_translicator_table_last_applied_timestamp_seconds{table="public.users"} 1.7000000005e+09`,
+		`kasho_translicator_table_apply_latency_seconds{table="public.users"} 0.25`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("WritePrometheus() output missing %q:\n%s", line, out)
+		}
+	}
+	// Tables that never applied a change have no last-applied timestamp
+	if strings.Contains(out, `kasho_translicator_table_last_applied_timestamp_seconds{table="public.orders"}`) {
+		t.Errorf("WritePrometheus() reported a timestamp for a table without applies:\n%s", out)
+	}
+}
+
+func TestStatusHandler(t *testing.T) {
+	handler := StatusHandler(func() Status {
+		return Status{
+			Position:   "0/100",
+			LagSeconds: 1.5,
+			Tables:     map[string]TableStats{"public.users": {Inserts: 3}},
+		}
+	})
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/admin/status", nil))
+
+	var got Status
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode status: %v", err)
+	}
+	if got.Position != "0/100" || got.LagSeconds != 1.5 || got.Tables["public.users"].Inserts != 3 {
+		t.Errorf("status = %+v", got)
+	}
+}
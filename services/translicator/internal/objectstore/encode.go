@@ -0,0 +1,164 @@
+package objectstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"kasho/proto"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress/zstd"
+)
+
+// Format is the file format changes are written in
+type Format string
+
+const (
+	// JSONL writes one JSON object per change and line
+	JSONL Format = "jsonl"
+	// Parquet writes a columnar file with the row and old keys as JSON columns
+	Parquet Format = "parquet"
+)
+
+// ParseFormat validates a file format name
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(name); format {
+	case JSONL, Parquet:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown file format %q (expected jsonl or parquet)", name)
+	}
+}
+
+// Record is a transformed change as it is written to object storage
+type Record struct {
+	Position        string         `json:"position"`
+	Table           string         `json:"table"`
+	Kind            string         `json:"kind"`
+	CommitTimestamp string         `json:"commit_timestamp,omitempty"`
+	TransactionID   string         `json:"transaction_id,omitempty"`
+	Row             map[string]any `json:"row,omitempty"`
+	OldKeys         map[string]any `json:"old_keys,omitempty"`
+}
+
+// NewRecord converts a DML change. Unchanged TOAST values aren't part of the change and
+// are left out of the row.
+func NewRecord(change *proto.Change) Record {
+	dml := change.GetDml()
+	record := Record{
+		Position:        change.Position,
+		Table:           dml.Table,
+		Kind:            dml.Kind,
+		CommitTimestamp: change.CommitTimestamp,
+		TransactionID:   change.TransactionId,
+	}
+	if len(dml.ColumnNames) > 0 {
+		record.Row = make(map[string]any, len(dml.ColumnNames))
+		for i, col := range dml.ColumnNames {
+			if i >= len(dml.ColumnValues) || dml.ColumnValues[i].GetUnchangedToast() {
+				continue
+			}
+			record.Row[col] = JSONValue(dml.ColumnValues[i])
+		}
+	}
+	if keys := dml.OldKeys; keys != nil && len(keys.KeyNames) > 0 {
+		record.OldKeys = make(map[string]any, len(keys.KeyNames))
+		for i, key := range keys.KeyNames {
+			if i < len(keys.KeyValues) {
+				record.OldKeys[key] = JSONValue(keys.KeyValues[i])
+			}
+		}
+	}
+	return record
+}
+
+// JSONValue converts a column value to its JSON form. Binary values become base64
+// strings, JSON documents are embedded as is and geometries become well-known text.
+func JSONValue(v *proto.ColumnValue) any {
+	if v == nil || v.Value == nil {
+		return nil
+	}
+	switch val := v.Value.(type) {
+	case *proto.ColumnValue_StringValue:
+		return val.StringValue
+	case *proto.ColumnValue_IntValue:
+		return val.IntValue
+	case *proto.ColumnValue_FloatValue:
+		return val.FloatValue
+	case *proto.ColumnValue_BoolValue:
+		return val.BoolValue
+	case *proto.ColumnValue_TimestampValue:
+		return val.TimestampValue
+	case *proto.ColumnValue_BytesValue:
+		return val.BytesValue
+	case *proto.ColumnValue_JsonValue:
+		if json.Valid([]byte(val.JsonValue)) {
+			return json.RawMessage(val.JsonValue)
+		}
+		return val.JsonValue
+	case *proto.ColumnValue_UuidValue:
+		return val.UuidValue
+	case *proto.ColumnValue_GeometryValue:
+		return val.GeometryValue.GetWkt()
+	default:
+		return nil
+	}
+}
+
+// parquetRecord is the Parquet schema of a Record. Rows of different tables have
+// different columns, so the row and old keys are JSON columns.
+type parquetRecord struct {
+	Position        string `parquet:"position"`
+	Table           string `parquet:"table"`
+	Kind            string `parquet:"kind"`
+	CommitTimestamp string `parquet:"commit_timestamp,optional"`
+	TransactionID   string `parquet:"transaction_id,optional"`
+	Row             []byte `parquet:"row,optional,json"`
+	OldKeys         []byte `parquet:"old_keys,optional,json"`
+}
+
+// encode writes records as a file of the format
+func encode(format Format, records []Record) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case JSONL:
+		enc := json.NewEncoder(&buf)
+		for _, record := range records {
+			if err := enc.Encode(record); err != nil {
+				return nil, err
+			}
+		}
+	case Parquet:
+		rows := make([]parquetRecord, len(records))
+		for i, record := range records {
+			rows[i] = parquetRecord{
+				Position:        record.Position,
+				Table:           record.Table,
+				Kind:            record.Kind,
+				CommitTimestamp: record.CommitTimestamp,
+				TransactionID:   record.TransactionID,
+			}
+			if record.Row != nil {
+				row, err := json.Marshal(record.Row)
+				if err != nil {
+					return nil, err
+				}
+				rows[i].Row = row
+			}
+			if record.OldKeys != nil {
+				keys, err := json.Marshal(record.OldKeys)
+				if err != nil {
+					return nil, err
+				}
+				rows[i].OldKeys = keys
+			}
+		}
+		if err := parquet.Write(&buf, rows, parquet.Compression(&zstd.Codec{})); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown file format %q", format)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,76 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// gcsStore stores objects in a Google Cloud Storage bucket, using Application Default
+// Credentials
+type gcsStore struct {
+	client *storage.Client
+	bucket *storage.BucketHandle
+	prefix string
+}
+
+func openGCS(ctx context.Context, bucket, prefix string) (*gcsStore, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS client: %w", err)
+	}
+	return &gcsStore{client: client, bucket: client.Bucket(bucket), prefix: prefix}, nil
+}
+
+func (g *gcsStore) Put(ctx context.Context, key string, data []byte) error {
+	// The object only becomes visible once the writer is closed successfully
+	w := g.bucket.Object(join(g.prefix, key)).NewWriter(ctx)
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (g *gcsStore) Get(ctx context.Context, key string) ([]byte, error) {
+	r, err := g.bucket.Object(join(g.prefix, key)).NewReader(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, ErrNotExist
+		}
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func (g *gcsStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	it := g.bucket.Objects(ctx, &storage.Query{Prefix: join(g.prefix, prefix)})
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		key := attrs.Name
+		if g.prefix != "" {
+			key = strings.TrimPrefix(key, g.prefix+"/")
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (g *gcsStore) Close() error {
+	return g.client.Close()
+}
@@ -0,0 +1,70 @@
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrNotExist is returned when reading an object that doesn't exist
+var ErrNotExist = errors.New("object does not exist")
+
+// Store reads and writes whole objects by key. Writes are atomic: readers see either
+// the previous object or the complete new one.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	// List returns the keys starting with prefix, in lexical order
+	List(ctx context.Context, prefix string) ([]string, error)
+	Close() error
+}
+
+// IsURL reports whether a replica URL names an object storage location instead of a
+// database
+func IsURL(rawURL string) bool {
+	scheme, _, found := strings.Cut(rawURL, "://")
+	if !found {
+		return false
+	}
+	switch strings.ToLower(scheme) {
+	case "s3", "gs":
+		return true
+	default:
+		return false
+	}
+}
+
+// Open opens the store for an s3://bucket/prefix or gs://bucket/prefix URL.
+// Keys passed to the store are relative to the prefix.
+func Open(ctx context.Context, rawURL string) (Store, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid object storage URL: %w", err)
+	}
+	prefix := strings.Trim(u.Path, "/")
+
+	switch strings.ToLower(u.Scheme) {
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("object storage URL %s has no bucket", rawURL)
+		}
+		return openS3(ctx, u.Host, prefix)
+	case "gs":
+		if u.Host == "" {
+			return nil, fmt.Errorf("object storage URL %s has no bucket", rawURL)
+		}
+		return openGCS(ctx, u.Host, prefix)
+	default:
+		return nil, fmt.Errorf("unsupported object storage scheme %q (expected s3 or gs)", u.Scheme)
+	}
+}
+
+// join prefixes a key, leaving it as is without a prefix
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "/" + key
+}
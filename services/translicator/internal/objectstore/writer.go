@@ -0,0 +1,206 @@
+package objectstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"kasho/proto"
+)
+
+// manifestPrefix is where manifests are stored, relative to the store's prefix
+const manifestPrefix = "_manifests/"
+
+// Manifest commits the data files written by one flush. Readers should only read files
+// listed in a manifest: files left behind by a failed flush are never listed, and are
+// overwritten when the flush is retried.
+type Manifest struct {
+	Sequence    uint64    `json:"sequence"`
+	Position    string    `json:"position"`
+	CommittedAt time.Time `json:"committed_at"`
+	Files       []File    `json:"files"`
+}
+
+// File is a data file listed in a manifest
+type File struct {
+	Path          string `json:"path"`
+	Table         string `json:"table"`
+	Records       int    `json:"records"`
+	FirstPosition string `json:"first_position"`
+	LastPosition  string `json:"last_position"`
+}
+
+// Writer buffers transformed changes and flushes them as files partitioned by table and
+// hour, followed by a manifest that commits them. The position of the last manifest is
+// where replication resumes after a restart, so every change ends up in exactly one
+// committed file.
+type Writer struct {
+	store      Store
+	format     Format
+	maxRecords int
+	now        func() time.Time
+
+	mu           sync.Mutex
+	sequence     uint64
+	position     string
+	records      []Record
+	lastPosition string
+}
+
+// NewWriter creates a writer that flushes at most maxRecords changes per flush,
+// continuing after the last manifest in the store
+func NewWriter(ctx context.Context, store Store, format Format, maxRecords int) (*Writer, error) {
+	w := &Writer{store: store, format: format, maxRecords: maxRecords, now: time.Now}
+
+	keys, err := store.List(ctx, manifestPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list manifests: %w", err)
+	}
+	if len(keys) == 0 {
+		return w, nil
+	}
+	data, err := store.Get(ctx, keys[len(keys)-1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", keys[len(keys)-1], err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", keys[len(keys)-1], err)
+	}
+	w.sequence, w.position, w.lastPosition = manifest.Sequence, manifest.Position, manifest.Position
+	return w, nil
+}
+
+// Position returns the position of the last committed manifest, or "" if nothing was
+// committed yet
+func (w *Writer) Position() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.position
+}
+
+// Pending returns the number of buffered changes
+func (w *Writer) Pending() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.records)
+}
+
+// Add buffers a transformed change, flushing the buffer first if it is full. If that
+// flush fails the change isn't added, so it can be redelivered. Only DML is written;
+// other changes just advance the position, and messages flush the buffer so a marker
+// ends a manifest.
+func (w *Writer) Add(ctx context.Context, change *proto.Change) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if change.GetMessage() != nil {
+		previous := w.lastPosition
+		w.lastPosition = change.Position
+		if err := w.flush(ctx); err != nil {
+			w.lastPosition = previous
+			return err
+		}
+		return nil
+	}
+
+	if change.GetDml() != nil && len(w.records) >= w.maxRecords {
+		if err := w.flush(ctx); err != nil {
+			return err
+		}
+	}
+	if change.GetDml() != nil {
+		w.records = append(w.records, NewRecord(change))
+	}
+	w.lastPosition = change.Position
+	return nil
+}
+
+// Flush writes the buffered changes and commits them with a manifest. If it fails the
+// changes stay buffered.
+func (w *Writer) Flush(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flush(ctx)
+}
+
+func (w *Writer) flush(ctx context.Context) error {
+	if w.lastPosition == w.position {
+		return nil
+	}
+
+	sequence := w.sequence + 1
+	manifest := Manifest{Sequence: sequence, Position: w.lastPosition, CommittedAt: w.now().UTC(), Files: []File{}}
+	for i, partition := range w.partitions() {
+		records := partition.records
+		path := fmt.Sprintf("%s/part-%020d-%04d.%s", partition.key, sequence, i, w.format)
+		data, err := encode(w.format, records)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", path, err)
+		}
+		if err := w.store.Put(ctx, path, data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		manifest.Files = append(manifest.Files, File{
+			Path:          path,
+			Table:         records[0].Table,
+			Records:       len(records),
+			FirstPosition: records[0].Position,
+			LastPosition:  records[len(records)-1].Position,
+		})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := w.store.Put(ctx, manifestKey(sequence), data); err != nil {
+		return fmt.Errorf("failed to commit manifest %d: %w", sequence, err)
+	}
+
+	w.sequence, w.position, w.records = sequence, w.lastPosition, nil
+	return nil
+}
+
+// manifestKey returns the key of a manifest; zero padding keeps them in sequence order
+func manifestKey(sequence uint64) string {
+	return fmt.Sprintf("%s%020d.json", manifestPrefix, sequence)
+}
+
+type partition struct {
+	key     string
+	records []Record
+}
+
+// partitions groups the buffered records by table and hour, in key order. The hour is
+// the commit time of the source transaction, or the current time if it isn't known.
+func (w *Writer) partitions() []partition {
+	byKey := make(map[string]*partition)
+	var keys []string
+	for _, record := range w.records {
+		hour := w.now().UTC()
+		if t, err := time.Parse(time.RFC3339Nano, record.CommitTimestamp); err == nil {
+			hour = t.UTC()
+		}
+		key := fmt.Sprintf("table=%s/date=%s/hour=%s", url.PathEscape(record.Table), hour.Format("2006-01-02"), hour.Format("15"))
+		p, ok := byKey[key]
+		if !ok {
+			p = &partition{key: key}
+			byKey[key] = p
+			keys = append(keys, key)
+		}
+		p.records = append(p.records, record)
+	}
+
+	slices.SortFunc(keys, strings.Compare)
+	partitions := make([]partition, len(keys))
+	for i, key := range keys {
+		partitions[i] = *byKey[key]
+	}
+	return partitions
+}
@@ -0,0 +1,259 @@
+package objectstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"kasho/proto"
+)
+
+func dmlChange(position, table, committed string, id int64) *proto.Change {
+	return &proto.Change{
+		Position:        position,
+		CommitTimestamp: committed,
+		Data: &proto.Change_Dml{Dml: &proto.DMLData{
+			Table:        table,
+			Kind:         "insert",
+			ColumnNames:  []string{"id"},
+			ColumnValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_IntValue{IntValue: id}}},
+		}},
+	}
+}
+
+// memStore keeps objects in memory and fails writes while fail is set
+type memStore struct {
+	objects map[string][]byte
+	fail    bool
+}
+
+func newMemStore() *memStore {
+	return &memStore{objects: make(map[string][]byte)}
+}
+
+func (m *memStore) Put(_ context.Context, key string, data []byte) error {
+	if m.fail {
+		return errors.New("access denied")
+	}
+	m.objects[key] = data
+	return nil
+}
+
+func (m *memStore) Get(_ context.Context, key string) ([]byte, error) {
+	data, ok := m.objects[key]
+	if !ok {
+		return nil, ErrNotExist
+	}
+	return data, nil
+}
+
+func (m *memStore) List(_ context.Context, prefix string) ([]string, error) {
+	var keys []string
+	for key := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (m *memStore) Close() error { return nil }
+
+func TestWriter_Flush(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	w, err := NewWriter(ctx, store, JSONL, 100)
+	if err != nil {
+		t.Fatalf("NewWriter() unexpected error: %v", err)
+	}
+	if w.Position() != "" {
+		t.Errorf("Position() of an empty store = %q, want empty", w.Position())
+	}
+
+	changes := []*proto.Change{
+		dmlChange("0/1", "public.users", "2026-10-16T14:05:00Z", 1),
+		dmlChange("0/2", "public.orders", "2026-10-16T14:06:00Z", 1),
+		dmlChange("0/3", "public.users", "2026-10-16T15:00:00+00:00", 2),
+		{Position: "0/4", Data: &proto.Change_Ddl{Ddl: &proto.DDLData{Ddl: "ALTER TABLE users ADD COLUMN age int"}}},
+	}
+	for _, change := range changes {
+		if err := w.Add(ctx, change); err != nil {
+			t.Fatalf("Add() unexpected error: %v", err)
+		}
+	}
+	if w.Pending() != 3 {
+		t.Errorf("Pending() = %d, want 3 (DDL isn't written)", w.Pending())
+	}
+	if err := w.Flush(ctx); err != nil {
+		t.Fatalf("Flush() unexpected error: %v", err)
+	}
+
+	data, err := store.Get(ctx, manifestKey(1))
+	if err != nil {
+		t.Fatalf("manifest not written: %v", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("invalid manifest: %v", err)
+	}
+	if manifest.Sequence != 1 || manifest.Position != "0/4" {
+		t.Errorf("manifest = sequence %d position %q, want 1 0/4", manifest.Sequence, manifest.Position)
+	}
+	var paths []string
+	for _, file := range manifest.Files {
+		paths = append(paths, file.Path)
+	}
+	want := []string{
+		"table=public.orders/date=2026-10-16/hour=14/part-00000000000000000001-0000.jsonl",
+		"table=public.users/date=2026-10-16/hour=14/part-00000000000000000001-0001.jsonl",
+		"table=public.users/date=2026-10-16/hour=15/part-00000000000000000001-0002.jsonl",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("files = %v, want %v", paths, want)
+	}
+
+	data, err = store.Get(ctx, want[1])
+	if err != nil {
+		t.Fatalf("data file not written: %v", err)
+	}
+	if got := strings.TrimSpace(string(data)); got != `{"position":"0/1","table":"public.users","kind":"insert","commit_timestamp":"2026-10-16T14:05:00Z","row":{"id":1}}` {
+		t.Errorf("data file = %s", got)
+	}
+
+	// A new writer continues after the last manifest
+	w, err = NewWriter(ctx, store, JSONL, 100)
+	if err != nil {
+		t.Fatalf("NewWriter() unexpected error: %v", err)
+	}
+	if w.Position() != "0/4" {
+		t.Errorf("Position() after restart = %q, want 0/4", w.Position())
+	}
+	// Nothing new to commit
+	if err := w.Flush(ctx); err != nil {
+		t.Fatalf("Flush() unexpected error: %v", err)
+	}
+	if _, err := store.Get(ctx, manifestKey(2)); !errors.Is(err, ErrNotExist) {
+		t.Errorf("empty flush wrote a manifest: %v", err)
+	}
+}
+
+func TestWriter_FailedFlush(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	w, err := NewWriter(ctx, store, JSONL, 1)
+	if err != nil {
+		t.Fatalf("NewWriter() unexpected error: %v", err)
+	}
+	w.now = func() time.Time { return time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC) }
+
+	if err := w.Add(ctx, dmlChange("0/1", "public.users", "", 1)); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+	// The buffer is full, so the next change flushes first; a failed flush rejects it
+	store.fail = true
+	if err := w.Add(ctx, dmlChange("0/2", "public.users", "", 2)); err == nil {
+		t.Fatal("Add() with a failing flush should return an error")
+	}
+	if w.Pending() != 1 || w.Position() != "" {
+		t.Errorf("after failed flush: Pending() = %d, Position() = %q, want 1 and empty", w.Pending(), w.Position())
+	}
+
+	store.fail = false
+	if err := w.Add(ctx, dmlChange("0/2", "public.users", "", 2)); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+	if w.Pending() != 1 || w.Position() != "0/1" {
+		t.Errorf("after flush: Pending() = %d, Position() = %q, want 1 and 0/1", w.Pending(), w.Position())
+	}
+	keys, err := store.List(ctx, "table=")
+	if err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	// Without a commit time, changes are partitioned by the time they're flushed
+	if want := []string{"table=public.users/date=2026-10-16/hour=09/part-00000000000000000001-0000.jsonl"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("data files = %v, want %v", keys, want)
+	}
+}
+
+func TestWriter_MessageFlushes(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	w, err := NewWriter(ctx, store, JSONL, 100)
+	if err != nil {
+		t.Fatalf("NewWriter() unexpected error: %v", err)
+	}
+
+	if err := w.Add(ctx, dmlChange("0/1", "public.users", "2024-03-20T15:00:00Z", 1)); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+	marker := &proto.Change{
+		Position: "0/2",
+		Data:     &proto.Change_Message{Message: &proto.MessageData{Prefix: "deploy", Content: []byte("deploy-123 complete")}},
+	}
+
+	store.fail = true
+	if err := w.Add(ctx, marker); err == nil {
+		t.Fatal("Add() of a message with a failing flush should return an error")
+	}
+	store.fail = false
+	if err := w.Add(ctx, marker); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+	// The buffered change is written and the manifest ends at the marker
+	if w.Pending() != 0 || w.Position() != "0/2" {
+		t.Errorf("after message: Pending() = %d, Position() = %q, want 0 and 0/2", w.Pending(), w.Position())
+	}
+}
+
+func TestNewRecord(t *testing.T) {
+	change := &proto.Change{
+		Position:      "0/9",
+		TransactionId: "742",
+		Data: &proto.Change_Dml{Dml: &proto.DMLData{
+			Table:       "public.documents",
+			Kind:        "update",
+			ColumnNames: []string{"title", "body", "meta", "blob"},
+			ColumnValues: []*proto.ColumnValue{
+				{Value: &proto.ColumnValue_StringValue{StringValue: "Hello"}},
+				{Value: &proto.ColumnValue_UnchangedToast{UnchangedToast: true}},
+				{Value: &proto.ColumnValue_JsonValue{JsonValue: `{"tags":["a"]}`}},
+				{Value: &proto.ColumnValue_BytesValue{BytesValue: []byte("hi")}},
+			},
+			OldKeys: &proto.OldKeys{
+				KeyNames:  []string{"id"},
+				KeyValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_IntValue{IntValue: 5}}},
+			},
+		}},
+	}
+
+	data, err := json.Marshal(NewRecord(change))
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+	want := `{"position":"0/9","table":"public.documents","kind":"update","transaction_id":"742","row":{"blob":"aGk=","meta":{"tags":["a"]},"title":"Hello"},"old_keys":{"id":5}}`
+	if string(data) != want {
+		t.Errorf("record = %s, want %s", data, want)
+	}
+}
+
+func TestIsURL(t *testing.T) {
+	tests := map[string]bool{
+		"s3://bucket/kasho":           true,
+		"GS://bucket":                 true,
+		"file:///var/lib/kasho/lake":  false,
+		"postgresql://localhost/db":   false,
+		"file:dev.db":                 false,
+		"clickhouse://localhost:9000": false,
+	}
+	for url, want := range tests {
+		if got := IsURL(url); got != want {
+			t.Errorf("IsURL(%q) = %v, want %v", url, got, want)
+		}
+	}
+}
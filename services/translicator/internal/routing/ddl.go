@@ -0,0 +1,271 @@
+package routing
+
+import (
+	"strings"
+
+	"kasho/proto"
+)
+
+// RouteDDL moves the tables a DDL change names into their replica schemas, like Route
+// does for DML. Qualified names in a mapped schema are rewritten wherever they appear.
+// Unqualified table names are in the schema the statement ran in: the change's schema
+// (MySQL database), or public for PostgreSQL, whose DDL doesn't record its search_path.
+// Those are qualified when that schema is mapped. String literals and comments are left
+// unchanged.
+func (m SchemaMap) RouteDDL(change *proto.Change) {
+	ddl := change.GetDdl()
+	if ddl == nil || len(m) == 0 {
+		return
+	}
+	schema := change.Schema
+	if schema == "" && change.SourceDialect == proto.SourceDialect_SOURCE_DIALECT_POSTGRESQL {
+		schema = "public"
+	}
+	if schema == "" {
+		schema = change.Database
+	}
+	ddl.Ddl = m.routeStatements(ddl.Ddl, schema)
+}
+
+// RouteDDL moves the tables a DDL change names into their replica schemas. Table and
+// column mappings aren't applied to DDL.
+func (r *Router) RouteDDL(change *proto.Change) {
+	r.schemas.RouteDDL(change)
+}
+
+type ddlTokenKind int
+
+const (
+	ddlSpace  ddlTokenKind = iota // whitespace and comments
+	ddlWord                       // keywords and unquoted identifiers
+	ddlQuoted                     // "quoted" or `quoted` identifiers
+	ddlString                     // 'string literals'
+	ddlPunct
+)
+
+type ddlToken struct {
+	kind ddlTokenKind
+	text string
+}
+
+// routeStatements rewrites the table names of the statements in ddl
+func (m SchemaMap) routeStatements(ddl, schema string) string {
+	tokens := tokenizeDDL(ddl)
+
+	// Statements are routed separately, each by the indices of its tokens that aren't space
+	var statement []int
+	for i, token := range tokens {
+		if token.kind == ddlSpace {
+			continue
+		}
+		if token.kind == ddlPunct && token.text == ";" {
+			m.routeStatement(tokens, statement, schema)
+			statement = statement[:0]
+			continue
+		}
+		statement = append(statement, i)
+	}
+	m.routeStatement(tokens, statement, schema)
+
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteString(token.text)
+	}
+	return b.String()
+}
+
+// routeStatement rewrites the table names of one statement, whose tokens are at the
+// indices in sig
+func (m SchemaMap) routeStatement(tokens []ddlToken, sig []int, schema string) {
+	if len(sig) == 0 {
+		return
+	}
+	at := func(k int) ddlToken {
+		if k < 0 || k >= len(sig) {
+			return ddlToken{}
+		}
+		return tokens[sig[k]]
+	}
+	keyword := func(k int) string {
+		if token := at(k); token.kind == ddlWord {
+			return strings.ToUpper(token.text)
+		}
+		return ""
+	}
+	isName := func(k int) bool { return at(k).kind == ddlWord || at(k).kind == ddlQuoted }
+	dot := func(k int) bool { return at(k).kind == ddlPunct && at(k).text == "." }
+
+	// Qualified names: schema.table
+	for k := range sig {
+		if isName(k) && dot(k+1) && isName(k+2) && !dot(k-1) {
+			if replica, ok := m[identifier(at(k))]; ok {
+				tokens[sig[k]].text = quoteLike(at(k), replica)
+			}
+		}
+	}
+
+	replica, ok := m[schema]
+	if !ok {
+		return
+	}
+	// Unqualified table names, found by the keywords before them
+	var names []int
+	first := keyword(0)
+	index := first == "DROP" && keyword(1) == "INDEX" ||
+		first == "CREATE" && (keyword(1) == "INDEX" || keyword(2) == "INDEX")
+	on := false
+	for k := range sig {
+		on = on || keyword(k) == "ON"
+	}
+	for k := range sig {
+		switch keyword(k) {
+		case "TABLE":
+			j := k + 1
+			for keyword(j) == "IF" || keyword(j) == "NOT" || keyword(j) == "EXISTS" || keyword(j) == "ONLY" {
+				j++
+			}
+			names = append(names, j)
+			// CREATE TABLE a LIKE b and CREATE TABLE a (LIKE b)
+			if keyword(j+1) == "LIKE" {
+				names = append(names, j+2)
+			} else if at(j+1).text == "(" && keyword(j+2) == "LIKE" {
+				names = append(names, j+3)
+			}
+			// DROP TABLE a, b and RENAME TABLE a TO b, c TO d name several tables
+			if first == "DROP" || first == "RENAME" {
+				for j++; at(j).text == "," || keyword(j) == "TO"; j += 2 {
+					names = append(names, j+1)
+				}
+			}
+		case "INDEX":
+			// PostgreSQL's DROP INDEX name; MySQL names the table with ON instead
+			if first == "DROP" && k == 1 && !on {
+				j := k + 1
+				for keyword(j) == "CONCURRENTLY" || keyword(j) == "IF" || keyword(j) == "EXISTS" {
+					j++
+				}
+				names = append(names, j)
+			}
+		case "REFERENCES":
+			names = append(names, k+1)
+		case "ON":
+			if index {
+				j := k + 1
+				if keyword(j) == "ONLY" {
+					j++
+				}
+				names = append(names, j)
+				index = false
+			}
+		case "TRUNCATE":
+			if k == 0 && keyword(1) != "TABLE" {
+				names = append(names, 1)
+			}
+		case "RENAME":
+			// ALTER TABLE a RENAME [TO|AS] b, but not RENAME COLUMN, INDEX or KEY, or
+			// PostgreSQL's RENAME column TO name
+			if first != "ALTER" || k == 0 {
+				continue
+			}
+			switch keyword(k + 1) {
+			case "TO", "AS":
+				names = append(names, k+2)
+			case "COLUMN", "INDEX", "KEY", "CONSTRAINT":
+			default:
+				if keyword(k+2) != "TO" {
+					names = append(names, k+1)
+				}
+			}
+		}
+	}
+	for _, k := range names {
+		if isName(k) && !dot(k+1) && !dot(k-1) {
+			tokens[sig[k]].text = quoteLike(at(k), replica) + "." + at(k).text
+		}
+	}
+}
+
+// identifier returns the name of an identifier token without its quotes
+func identifier(token ddlToken) string {
+	if token.kind != ddlQuoted || len(token.text) < 2 {
+		return token.text
+	}
+	q := token.text[:1]
+	return strings.ReplaceAll(token.text[1:len(token.text)-1], q+q, q)
+}
+
+// quoteLike returns name quoted the way token is
+func quoteLike(token ddlToken, name string) string {
+	if token.kind != ddlQuoted {
+		return name
+	}
+	q := token.text[:1]
+	return q + strings.ReplaceAll(name, q, q+q) + q
+}
+
+// tokenizeDDL splits a statement into tokens that join back into it
+func tokenizeDDL(s string) []ddlToken {
+	var tokens []ddlToken
+	for i := 0; i < len(s); {
+		start := i
+		kind := ddlPunct
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			kind = ddlSpace
+			for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r') {
+				i++
+			}
+		case strings.HasPrefix(s[i:], "--"):
+			kind = ddlSpace
+			if end := strings.IndexByte(s[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(s)
+			}
+		case strings.HasPrefix(s[i:], "/*"):
+			kind = ddlSpace
+			if end := strings.Index(s[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(s)
+			}
+		case c == '\'' || c == '"' || c == '`':
+			kind = ddlQuoted
+			if c == '\'' {
+				kind = ddlString
+			}
+			i = closingQuote(s, i)
+		case isWordByte(c):
+			kind = ddlWord
+			for i < len(s) && isWordByte(s[i]) {
+				i++
+			}
+		default:
+			i++
+		}
+		tokens = append(tokens, ddlToken{kind: kind, text: s[start:i]})
+	}
+	return tokens
+}
+
+// closingQuote returns the index after the quote closing the one at open. A doubled
+// quote is part of the quoted text.
+func closingQuote(s string, open int) int {
+	q := s[open]
+	for i := open + 1; i < len(s); i++ {
+		if s[i] != q {
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == q {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(s)
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '$' || c >= 0x80
+}
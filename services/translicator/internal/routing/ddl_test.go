@@ -0,0 +1,67 @@
+package routing
+
+import (
+	"testing"
+
+	"kasho/proto"
+)
+
+func TestRouteDDL(t *testing.T) {
+	m := SchemaMap{"app1": "tenant_a", "public": "main"}
+	mysql := proto.SourceDialect_SOURCE_DIALECT_MYSQL
+	postgres := proto.SourceDialect_SOURCE_DIALECT_POSTGRESQL
+
+	tests := []struct {
+		name    string
+		dialect proto.SourceDialect
+		schema  string
+		ddl     string
+		want    string
+	}{
+		{"qualified create", postgres, "", "CREATE TABLE app1.users (id int)", "CREATE TABLE tenant_a.users (id int)"},
+		{"quoted qualified name", postgres, "", `ALTER TABLE "app1"."users" ADD COLUMN age int`, `ALTER TABLE "tenant_a"."users" ADD COLUMN age int`},
+		{"unqualified postgres table is in public", postgres, "", "CREATE TABLE users (id int)", "CREATE TABLE main.users (id int)"},
+		{"unmapped qualified name", postgres, "", "CREATE TABLE audit.users (id int)", "CREATE TABLE audit.users (id int)"},
+		{"mysql database", mysql, "app1", "CREATE TABLE IF NOT EXISTS `users` (`id` int)", "CREATE TABLE IF NOT EXISTS `tenant_a`.`users` (`id` int)"},
+		{"unmapped mysql database", mysql, "app2", "CREATE TABLE users (id int)", "CREATE TABLE users (id int)"},
+		{"foreign key", mysql, "app1", "ALTER TABLE orders ADD CONSTRAINT fk FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE", "ALTER TABLE tenant_a.orders ADD CONSTRAINT fk FOREIGN KEY (user_id) REFERENCES tenant_a.users (id) ON DELETE CASCADE"},
+		{"drop several tables", mysql, "app1", "DROP TABLE IF EXISTS a, b", "DROP TABLE IF EXISTS tenant_a.a, tenant_a.b"},
+		{"rename tables", mysql, "app1", "RENAME TABLE a TO b, c TO d", "RENAME TABLE tenant_a.a TO tenant_a.b, tenant_a.c TO tenant_a.d"},
+		{"alter rename", mysql, "app1", "ALTER TABLE a RENAME TO b", "ALTER TABLE tenant_a.a RENAME TO tenant_a.b"},
+		{"rename column", mysql, "app1", "ALTER TABLE a RENAME COLUMN x TO y", "ALTER TABLE tenant_a.a RENAME COLUMN x TO y"},
+		{"create index", mysql, "app1", "CREATE UNIQUE INDEX idx ON users (email)", "CREATE UNIQUE INDEX idx ON tenant_a.users (email)"},
+		{"drop postgres index", postgres, "", "DROP INDEX IF EXISTS idx", "DROP INDEX IF EXISTS main.idx"},
+		{"drop mysql index", mysql, "app1", "DROP INDEX idx ON users", "DROP INDEX idx ON tenant_a.users"},
+		{"truncate", mysql, "app1", "TRUNCATE users", "TRUNCATE tenant_a.users"},
+		{"create like", mysql, "app1", "CREATE TABLE b LIKE a", "CREATE TABLE tenant_a.b LIKE tenant_a.a"},
+		{"literals and comments unchanged", postgres, "", "CREATE TABLE t (s text DEFAULT 'app1.users') -- app1.users", "CREATE TABLE main.t (s text DEFAULT 'app1.users') -- app1.users"},
+		{"several statements", postgres, "", "CREATE TABLE app1.a (id int); CREATE INDEX ON app1.a (id);", "CREATE TABLE tenant_a.a (id int); CREATE INDEX ON tenant_a.a (id);"},
+		{"other statements unchanged", mysql, "app1", "CREATE VIEW v AS SELECT * FROM users", "CREATE VIEW v AS SELECT * FROM users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change := &proto.Change{
+				SourceDialect: tt.dialect,
+				Schema:        tt.schema,
+				Data:          &proto.Change_Ddl{Ddl: &proto.DDLData{Ddl: tt.ddl}},
+			}
+			m.RouteDDL(change)
+			if got := change.GetDdl().Ddl; got != tt.want {
+				t.Errorf("RouteDDL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// DML changes and an empty map are left alone
+	dml := &proto.Change{Data: &proto.Change_Dml{Dml: &proto.DMLData{Table: "app1.users"}}}
+	m.RouteDDL(dml)
+	if dml.GetDml().Table != "app1.users" {
+		t.Errorf("RouteDDL() changed a DML change: %v", dml.GetDml().Table)
+	}
+	ddl := &proto.Change{Data: &proto.Change_Ddl{Ddl: &proto.DDLData{Ddl: "CREATE TABLE app1.users (id int)"}}}
+	SchemaMap{}.RouteDDL(ddl)
+	if got := ddl.GetDdl().Ddl; got != "CREATE TABLE app1.users (id int)" {
+		t.Errorf("RouteDDL() with an empty map = %q", got)
+	}
+}
@@ -0,0 +1,99 @@
+package routing
+
+import (
+	"fmt"
+	"strings"
+
+	"kasho/pkg/transform"
+	"kasho/proto"
+)
+
+// SchemaMap maps source schemas (PostgreSQL) or databases (MySQL) to replica schemas,
+// so several applications can share one pipeline without their tables colliding
+type SchemaMap map[string]string
+
+// ParseSchemaMap parses a comma-separated list of source:replica pairs,
+// e.g. "app1:app1_replica,app2:app2_replica"
+func ParseSchemaMap(spec string) (SchemaMap, error) {
+	m := SchemaMap{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		source, replica, ok := strings.Cut(entry, ":")
+		source, replica = strings.TrimSpace(source), strings.TrimSpace(replica)
+		if !ok || source == "" || replica == "" {
+			return nil, fmt.Errorf("invalid schema mapping %q: expected source:replica", entry)
+		}
+		if _, exists := m[source]; exists {
+			return nil, fmt.Errorf("duplicate schema mapping for %q", source)
+		}
+		m[source] = replica
+	}
+	return m, nil
+}
+
+// Route moves a DML change into its replica schema. The source schema is taken from the
+// table name when it is qualified (PostgreSQL), otherwise from the change's schema
+// (MySQL). Tables in unmapped schemas are left unchanged.
+func (m SchemaMap) Route(dml *proto.DMLData) {
+	if len(m) == 0 {
+		return
+	}
+	schema, table, qualified := strings.Cut(dml.Table, ".")
+	if !qualified {
+		schema, table = dml.Schema, dml.Table
+	}
+	if replica, ok := m[schema]; ok {
+		dml.Table = replica + "." + table
+	}
+}
+
+// Router decides which replica table and columns a change is written to
+type Router struct {
+	schemas SchemaMap
+	tables  map[string]string
+	columns map[string]map[string]string
+}
+
+// NewRouter creates a router from the schema map and the routing section of transforms.yml
+func NewRouter(schemas SchemaMap, config transform.RoutingConfig) *Router {
+	return &Router{schemas: schemas, tables: config.Tables, columns: config.Columns}
+}
+
+// Route renames the table and columns of a DML change to their replica names. A table
+// mapping takes precedence over the schema map. Changes must already be transformed,
+// since transforms match source names.
+func (r *Router) Route(dml *proto.DMLData) {
+	if dml == nil {
+		return
+	}
+	if columns, ok := r.columns[dml.Table]; ok {
+		dml.ColumnNames = renameColumns(dml.ColumnNames, columns)
+		dml.PrimaryKey = renameColumns(dml.PrimaryKey, columns)
+		if dml.OldKeys != nil {
+			dml.OldKeys.KeyNames = renameColumns(dml.OldKeys.KeyNames, columns)
+		}
+	}
+	if table, ok := r.tables[dml.Table]; ok {
+		dml.Table = table
+		return
+	}
+	r.schemas.Route(dml)
+}
+
+// renameColumns returns a copy of names with mapped columns renamed
+func renameColumns(names []string, columns map[string]string) []string {
+	if names == nil {
+		return nil
+	}
+	renamed := make([]string, len(names))
+	for i, name := range names {
+		if target, ok := columns[name]; ok {
+			name = target
+		}
+		renamed[i] = name
+	}
+	return renamed
+}
@@ -0,0 +1,106 @@
+package routing
+
+import (
+	"reflect"
+	"testing"
+
+	"kasho/pkg/transform"
+	"kasho/proto"
+)
+
+func TestParseSchemaMap(t *testing.T) {
+	got, err := ParseSchemaMap(" app1:app1_replica, app2 : shared ,")
+	if err != nil {
+		t.Fatalf("ParseSchemaMap() unexpected error: %v", err)
+	}
+	want := SchemaMap{"app1": "app1_replica", "app2": "shared"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSchemaMap() = %v, want %v", got, want)
+	}
+
+	for _, spec := range []string{"app1", "app1:", ":replica", "app1:a,app1:b"} {
+		if _, err := ParseSchemaMap(spec); err == nil {
+			t.Errorf("ParseSchemaMap(%q) expected error", spec)
+		}
+	}
+}
+
+func TestRoute(t *testing.T) {
+	m := SchemaMap{"app1": "tenant_a", "app2": "tenant_b"}
+
+	tests := []struct {
+		name   string
+		table  string
+		schema string
+		want   string
+	}{
+		{"qualified table", "app1.users", "app1", "tenant_a.users"},
+		{"bootstrap change without schema", "app2.orders", "", "tenant_b.orders"},
+		{"bare table with schema", "users", "app2", "tenant_b.users"},
+		{"unmapped schema", "public.users", "public", "public.users"},
+		{"bare table without schema", "users", "", "users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dml := &proto.DMLData{Table: tt.table, Schema: tt.schema}
+			m.Route(dml)
+			if dml.Table != tt.want {
+				t.Errorf("Route() table = %q, want %q", dml.Table, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouterRoute(t *testing.T) {
+	router := NewRouter(SchemaMap{"app1": "tenant_a"}, transform.RoutingConfig{
+		Tables: map[string]string{"app1.users": "warehouse.dim_users"},
+		Columns: map[string]map[string]string{
+			"app1.users":  {"id": "user_id", "name": "full_name"},
+			"app1.orders": {"total": "order_total"},
+		},
+	})
+
+	users := &proto.DMLData{
+		Table:       "app1.users",
+		Schema:      "app1",
+		Kind:        "update",
+		ColumnNames: []string{"id", "name", "email"},
+		PrimaryKey:  []string{"id"},
+		OldKeys:     &proto.OldKeys{KeyNames: []string{"id"}},
+	}
+	router.Route(users)
+	if users.Table != "warehouse.dim_users" {
+		t.Errorf("table = %q, want warehouse.dim_users (table mapping wins over schema map)", users.Table)
+	}
+	if want := []string{"user_id", "full_name", "email"}; !reflect.DeepEqual(users.ColumnNames, want) {
+		t.Errorf("columns = %v, want %v", users.ColumnNames, want)
+	}
+	if want := []string{"user_id"}; !reflect.DeepEqual(users.PrimaryKey, want) {
+		t.Errorf("primary key = %v, want %v", users.PrimaryKey, want)
+	}
+	if want := []string{"user_id"}; !reflect.DeepEqual(users.OldKeys.KeyNames, want) {
+		t.Errorf("old key names = %v, want %v", users.OldKeys.KeyNames, want)
+	}
+
+	orders := &proto.DMLData{Table: "app1.orders", Schema: "app1", ColumnNames: []string{"id", "total"}}
+	router.Route(orders)
+	if orders.Table != "tenant_a.orders" {
+		t.Errorf("table = %q, want tenant_a.orders", orders.Table)
+	}
+	if want := []string{"id", "order_total"}; !reflect.DeepEqual(orders.ColumnNames, want) {
+		t.Errorf("columns = %v, want %v", orders.ColumnNames, want)
+	}
+}
+
+func TestRouterRoute_DoesNotShareKeySlices(t *testing.T) {
+	router := NewRouter(nil, transform.RoutingConfig{
+		Columns: map[string]map[string]string{"users": {"id": "user_id"}},
+	})
+
+	primaryKey := []string{"id"}
+	router.Route(&proto.DMLData{Table: "users", PrimaryKey: primaryKey})
+	if primaryKey[0] != "id" {
+		t.Errorf("Route() modified the source change's primary key: %v", primaryKey)
+	}
+}
@@ -0,0 +1,144 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+	cloudkms "google.golang.org/api/cloudkms/v1"
+
+	"kasho/pkg/transform"
+)
+
+// requestTimeout bounds each call to a KMS service while the config is loaded
+const requestTimeout = 30 * time.Second
+
+// Register lets transforms configs reference secrets encrypted with AWS KMS or Google
+// Cloud KMS keys:
+//
+//	salt:
+//	  secretRef: awskms://alias/kasho?region=us-east-1
+//	  ciphertext: AQICAHh...
+//
+// The ciphertext is decrypted when the config is loaded. Credentials come from the usual
+// AWS sources or Google Application Default Credentials.
+func Register() {
+	transform.RegisterSecretResolver("awskms", decryptAWS)
+	transform.RegisterSecretResolver("gcpkms", decryptGCP)
+}
+
+// keyName returns the key a KMS reference names, e.g. alias/kasho for awskms://alias/kasho,
+// or arn:aws:kms:... for awskms:///arn:aws:kms:...
+func keyName(ref transform.SecretRef) string {
+	return strings.TrimPrefix(ref.URI.Host+ref.URI.Path, "/")
+}
+
+type awsDecryptRequest struct {
+	CiphertextBlob []byte
+	KeyId          string `json:",omitempty"`
+}
+
+type awsDecryptResponse struct {
+	Plaintext []byte
+	Type      string `json:"__type"`
+	Message   string `json:"message"`
+}
+
+// decryptAWS decrypts a secret with AWS KMS. The KMS API is called directly, signed with
+// the SDK's signer, rather than through the KMS client for this one call.
+func decryptAWS(ref transform.SecretRef) (string, error) {
+	if len(ref.Ciphertext) == 0 {
+		return "", fmt.Errorf("awskms references require a ciphertext")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	region := ref.URI.Query().Get("region")
+	if region == "" {
+		region = cfg.Region
+	}
+	if region == "" {
+		return "", fmt.Errorf("no AWS region: add ?region= to the reference or set AWS_REGION")
+	}
+	credentials, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+
+	body, err := json.Marshal(awsDecryptRequest{CiphertextBlob: ref.Ciphertext, KeyId: keyName(ref)})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://kms."+region+".amazonaws.com/", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService.Decrypt")
+	payloadHash := sha256.Sum256(body)
+	if err := v4.NewSigner().SignHTTP(ctx, credentials, req, hex.EncodeToString(payloadHash[:]), "kms", region, time.Now()); err != nil {
+		return "", fmt.Errorf("failed to sign KMS request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	var out awsDecryptResponse
+	if err := json.Unmarshal(data, &out); err != nil {
+		return "", fmt.Errorf("unexpected KMS response (%s)", resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("KMS decrypt failed (%s): %s %s", resp.Status, out.Type, out.Message)
+	}
+	return string(out.Plaintext), nil
+}
+
+// decryptGCP decrypts a secret with a Google Cloud KMS key, named like
+// gcpkms://projects/p/locations/global/keyRings/r/cryptoKeys/k
+func decryptGCP(ref transform.SecretRef) (string, error) {
+	if len(ref.Ciphertext) == 0 {
+		return "", fmt.Errorf("gcpkms references require a ciphertext")
+	}
+	name := keyName(ref)
+	if !strings.HasPrefix(name, "projects/") {
+		return "", fmt.Errorf("gcpkms reference must name a key as projects/.../cryptoKeys/...")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	service, err := cloudkms.NewService(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create Cloud KMS client: %w", err)
+	}
+	resp, err := service.Projects.Locations.KeyRings.CryptoKeys.Decrypt(name, &cloudkms.DecryptRequest{
+		Ciphertext: base64.StdEncoding.EncodeToString(ref.Ciphertext),
+	}).Context(ctx).Do()
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(resp.Plaintext)
+	if err != nil {
+		return "", fmt.Errorf("unexpected Cloud KMS response: %w", err)
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,119 @@
+package sequences
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SyncFunc brings the sequences of the given tables in line with their data; an
+// empty list means every table
+type SyncFunc func(ctx context.Context, tables []string) error
+
+// Syncer tracks which tables received inserts and syncs their sequences, so that
+// writes made directly on the replica don't collide with replicated keys
+type Syncer struct {
+	sync SyncFunc
+
+	mu      sync.Mutex
+	pending map[string]struct{}
+
+	// syncMu keeps periodic and on-demand syncs from running at the same time
+	syncMu sync.Mutex
+}
+
+// NewSyncer creates a syncer that uses fn to sync sequences
+func NewSyncer(fn SyncFunc) *Syncer {
+	return &Syncer{sync: fn, pending: make(map[string]struct{})}
+}
+
+// MarkInsert records that a table received an insert
+func (s *Syncer) MarkInsert(table string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[table] = struct{}{}
+}
+
+// takePending returns and clears the tables that received inserts
+func (s *Syncer) takePending() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tables := make([]string, 0, len(s.pending))
+	for table := range s.pending {
+		tables = append(tables, table)
+	}
+	clear(s.pending)
+	slices.Sort(tables)
+	return tables
+}
+
+// SyncPending syncs the sequences of tables that received inserts since the last sync.
+// Tables whose sync fails stay pending and are retried on the next call.
+func (s *Syncer) SyncPending(ctx context.Context) error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	tables := s.takePending()
+	if len(tables) == 0 {
+		return nil
+	}
+	if err := s.sync(ctx, tables); err != nil {
+		for _, table := range tables {
+			s.MarkInsert(table)
+		}
+		return err
+	}
+	return nil
+}
+
+// SyncAll syncs the sequences of every table, e.g. after a bootstrap
+func (s *Syncer) SyncAll(ctx context.Context) error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	pending := s.takePending()
+	if err := s.sync(ctx, nil); err != nil {
+		for _, table := range pending {
+			s.MarkInsert(table)
+		}
+		return err
+	}
+	return nil
+}
+
+// Run syncs pending tables every interval until ctx is cancelled
+func (s *Syncer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.SyncPending(ctx); err != nil {
+				log.Printf("Error during sequence sync: %v", err)
+			}
+		}
+	}
+}
+
+// Handler returns an HTTP handler that syncs every table's sequences on POST (SyncNow)
+func (s *Syncer) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := s.SyncAll(r.Context()); err != nil {
+			log.Printf("Error during on-demand sequence sync: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Synced all sequences on demand")
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
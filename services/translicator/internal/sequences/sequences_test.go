@@ -0,0 +1,115 @@
+package sequences
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// recorder records the table lists passed to each sync
+type recorder struct {
+	calls [][]string
+	err   error
+}
+
+func (r *recorder) sync(ctx context.Context, tables []string) error {
+	r.calls = append(r.calls, tables)
+	return r.err
+}
+
+func TestSyncPending(t *testing.T) {
+	rec := &recorder{}
+	s := NewSyncer(rec.sync)
+
+	// Nothing to do before any insert
+	if err := s.SyncPending(context.Background()); err != nil {
+		t.Fatalf("SyncPending() unexpected error: %v", err)
+	}
+	if len(rec.calls) != 0 {
+		t.Fatalf("SyncPending() synced %v without inserts", rec.calls)
+	}
+
+	s.MarkInsert("public.users")
+	s.MarkInsert("public.orders")
+	s.MarkInsert("public.users")
+	if err := s.SyncPending(context.Background()); err != nil {
+		t.Fatalf("SyncPending() unexpected error: %v", err)
+	}
+	if err := s.SyncPending(context.Background()); err != nil {
+		t.Fatalf("SyncPending() unexpected error: %v", err)
+	}
+
+	want := [][]string{{"public.orders", "public.users"}}
+	if !reflect.DeepEqual(rec.calls, want) {
+		t.Errorf("synced %v, want %v", rec.calls, want)
+	}
+}
+
+func TestSyncPending_RetriesFailedTables(t *testing.T) {
+	rec := &recorder{err: errors.New("connection lost")}
+	s := NewSyncer(rec.sync)
+
+	s.MarkInsert("public.users")
+	if err := s.SyncPending(context.Background()); err == nil {
+		t.Fatal("SyncPending() expected error")
+	}
+
+	rec.err = nil
+	if err := s.SyncPending(context.Background()); err != nil {
+		t.Fatalf("SyncPending() unexpected error: %v", err)
+	}
+
+	want := [][]string{{"public.users"}, {"public.users"}}
+	if !reflect.DeepEqual(rec.calls, want) {
+		t.Errorf("synced %v, want %v", rec.calls, want)
+	}
+}
+
+func TestSyncAll(t *testing.T) {
+	rec := &recorder{}
+	s := NewSyncer(rec.sync)
+
+	s.MarkInsert("public.users")
+	if err := s.SyncAll(context.Background()); err != nil {
+		t.Fatalf("SyncAll() unexpected error: %v", err)
+	}
+	// The full sync covered the pending table
+	if err := s.SyncPending(context.Background()); err != nil {
+		t.Fatalf("SyncPending() unexpected error: %v", err)
+	}
+
+	want := [][]string{nil}
+	if !reflect.DeepEqual(rec.calls, want) {
+		t.Errorf("synced %v, want %v", rec.calls, want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	rec := &recorder{}
+	handler := NewSyncer(rec.sync).Handler()
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/admin/sync-sequences", nil))
+	if resp.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want %d", resp.Code, http.StatusMethodNotAllowed)
+	}
+
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/admin/sync-sequences", nil))
+	if resp.Code != http.StatusNoContent {
+		t.Errorf("POST status = %d, want %d", resp.Code, http.StatusNoContent)
+	}
+	if len(rec.calls) != 1 || rec.calls[0] != nil {
+		t.Errorf("synced %v, want one full sync", rec.calls)
+	}
+
+	rec.err = errors.New("boom")
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/admin/sync-sequences", nil))
+	if resp.Code != http.StatusInternalServerError {
+		t.Errorf("failed POST status = %d, want %d", resp.Code, http.StatusInternalServerError)
+	}
+}
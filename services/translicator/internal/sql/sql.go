@@ -1,16 +1,27 @@
 package sql
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
 
 	"kasho/pkg/dialect"
 	"kasho/proto"
 )
 
+// ErrNoChanges is returned for an UPDATE that has no columns left to set, e.g. when
+// every changed column is an unchanged TOAST value or a generated column. MySQL columns
+// left out of a MINIMAL or NOBLOB row image are sent as unchanged TOAST values too.
+var ErrNoChanges = errors.New("update has no columns to set")
+
 // SQLGenerator generates SQL statements using a specific dialect
 type SQLGenerator struct {
-	dialect dialect.Dialect
+	dialect          dialect.Dialect
+	idempotent       bool
+	generatedColumns map[string][]string
 }
 
 // NewSQLGenerator creates a new SQL generator with the specified dialect
@@ -18,11 +29,135 @@ func NewSQLGenerator(d dialect.Dialect) *SQLGenerator {
 	return &SQLGenerator{dialect: d}
 }
 
+// SetIdempotent enables upsert generation for inserts so that replayed changes
+// overwrite existing rows instead of failing with key violations
+func (g *SQLGenerator) SetIdempotent(idempotent bool) {
+	g.idempotent = idempotent
+}
+
+// Idempotent reports whether inserts are generated as upserts
+func (g *SQLGenerator) Idempotent() bool {
+	return g.idempotent
+}
+
+// SetGeneratedColumns sets the replica's generated columns by table. The replica computes
+// their values itself and rejects explicit ones, so they are left out of INSERTs and UPDATEs.
+func (g *SQLGenerator) SetGeneratedColumns(columns map[string][]string) {
+	g.generatedColumns = columns
+}
+
+// isGenerated reports whether a column of a table is generated on the replica
+func (g *SQLGenerator) isGenerated(table, column string) bool {
+	return slices.Contains(g.generatedColumns[table], column)
+}
+
+// values renders column values into a statement, either as literals or as bind
+// parameters collected in args
+type values struct {
+	dialect dialect.Dialect
+	bind    bool
+	args    []any
+}
+
+// format returns the SQL for a value
+func (v *values) format(val *proto.ColumnValue) (string, error) {
+	if !v.bind {
+		return v.dialect.FormatValue(val)
+	}
+	expr, arg, err := v.dialect.BindValue(val, v.dialect.Placeholder(len(v.args)+1))
+	if err != nil {
+		return "", err
+	}
+	v.args = append(v.args, arg)
+	return expr, nil
+}
+
+// write writes the SQL for a value to buf. Literals are written by the dialect straight
+// into the buffer, so long values aren't copied into a string of their own first.
+func (v *values) write(buf *bytes.Buffer, val *proto.ColumnValue) error {
+	if !v.bind {
+		return dialect.WriteValue(v.dialect, buf, val)
+	}
+	expr, err := v.format(val)
+	if err != nil {
+		return err
+	}
+	buf.WriteString(expr)
+	return nil
+}
+
+// maxPooledBuffer is the largest statement buffer kept for reuse. Buffers grown by very
+// wide rows are left to the garbage collector, so memory use goes back down after them.
+const maxPooledBuffer = 1 << 20
+
+// bufferPool holds the buffers statements are built in
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// getBuffer returns an empty buffer with room for a statement of about size bytes
+func getBuffer(size int) *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Grow(size)
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// statementSize estimates the length of the statement of a change, so that its buffer
+// is allocated once even for values of several megabytes
+func statementSize(dml *proto.DMLData) int {
+	size := 64 + len(dml.Table)
+	for _, name := range dml.ColumnNames {
+		size += len(name) + 8
+	}
+	for _, val := range dml.ColumnValues {
+		size += valueSize(val)
+	}
+	if dml.OldKeys != nil {
+		for i, name := range dml.OldKeys.KeyNames {
+			size += len(name) + 8
+			if i < len(dml.OldKeys.KeyValues) {
+				size += valueSize(dml.OldKeys.KeyValues[i])
+			}
+		}
+	}
+	return size
+}
+
+// valueSize estimates the length of the literal of a value, counting the characters
+// dialects may escape so that the buffer isn't regrown
+func valueSize(val *proto.ColumnValue) int {
+	switch v := val.GetValue().(type) {
+	case *proto.ColumnValue_StringValue:
+		return textSize(v.StringValue) + 2
+	case *proto.ColumnValue_JsonValue:
+		return textSize(v.JsonValue) + 16
+	case *proto.ColumnValue_BytesValue:
+		return 2*len(v.BytesValue) + 16
+	case *proto.ColumnValue_GeometryValue:
+		return len(v.GeometryValue.GetWkt()) + 32
+	default:
+		return 32
+	}
+}
+
+// textSize is the length of text with every quote and backslash escaped
+func textSize(s string) int {
+	return len(s) + strings.Count(s, "'") + strings.Count(s, `\`)
+}
+
 // ToSQL converts a Change into a SQL statement
 func (g *SQLGenerator) ToSQL(change *proto.Change) (string, error) {
 	switch data := change.Data.(type) {
 	case *proto.Change_Dml:
-		return g.toDMLSQL(data.Dml)
+		return g.toDMLSQL(data.Dml, &values{dialect: g.dialect})
 	case *proto.Change_Ddl:
 		return data.Ddl.Ddl, nil
 	default:
@@ -30,41 +165,156 @@ func (g *SQLGenerator) ToSQL(change *proto.Change) (string, error) {
 	}
 }
 
+// ToPreparedSQL converts a DML change into a statement with bind parameters and the
+// arguments to execute it with. Changes of the same kind to the same table and columns
+// produce the same statement, so it can be prepared once and reused.
+func (g *SQLGenerator) ToPreparedSQL(dml *proto.DMLData) (string, []any, error) {
+	v := &values{dialect: g.dialect, bind: true}
+	query, err := g.toDMLSQL(dml, v)
+	if err != nil {
+		return "", nil, err
+	}
+	return query, v.args, nil
+}
+
+// ErrNotBulkLoadable is returned for an insert with a value that can only be written
+// through a SQL expression, e.g. a geometry, and so can't be bulk loaded
+var ErrNotBulkLoadable = errors.New("insert has values that can't be bulk loaded")
+
+// ToBulkRow returns the columns and values an insert writes, leaving out generated
+// columns, for loading it together with other inserts into the same table. With a
+// versioned dialect every change is written as an insert, so updates and deletes
+// that produce a single row version can be bulk loaded too.
+func (g *SQLGenerator) ToBulkRow(dml *proto.DMLData) ([]string, []any, error) {
+	var columns []string
+	var vals []*proto.ColumnValue
+	if versioned, ok := g.dialect.(dialect.Versioned); ok {
+		names, rows, err := g.versionedRows(dml, versioned)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(rows) != 1 {
+			return nil, nil, fmt.Errorf("%w: update changes the primary key", ErrNotBulkLoadable)
+		}
+		columns, vals = names, rows[0]
+	} else {
+		if dml.Kind != "insert" {
+			return nil, nil, fmt.Errorf("%w: %s is not an insert", ErrNotBulkLoadable, dml.Kind)
+		}
+		if len(dml.ColumnNames) != len(dml.ColumnValues) {
+			return nil, nil, fmt.Errorf("mismatched column names and values: %d names, %d values", len(dml.ColumnNames), len(dml.ColumnValues))
+		}
+		for i, val := range dml.ColumnValues {
+			if val.GetUnchangedToast() || g.isGenerated(dml.Table, dml.ColumnNames[i]) {
+				continue
+			}
+			columns = append(columns, dml.ColumnNames[i])
+			vals = append(vals, val)
+		}
+	}
+
+	placeholder := g.dialect.Placeholder(1)
+	row := make([]any, 0, len(vals))
+	for i, val := range vals {
+		expr, arg, err := g.dialect.BindValue(val, placeholder)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error formatting value for column %s: %w", columns[i], err)
+		}
+		if expr != placeholder {
+			return nil, nil, fmt.Errorf("%w: column %s", ErrNotBulkLoadable, columns[i])
+		}
+		row = append(row, arg)
+	}
+	return columns, row, nil
+}
+
 // toDMLSQL converts a DMLData into a SQL statement
-func (g *SQLGenerator) toDMLSQL(dml *proto.DMLData) (string, error) {
+func (g *SQLGenerator) toDMLSQL(dml *proto.DMLData, v *values) (string, error) {
+	if versioned, ok := g.dialect.(dialect.Versioned); ok {
+		return g.toVersionedSQL(dml, versioned, v)
+	}
+
 	switch dml.Kind {
 	case "insert":
-		return g.toInsertSQL(dml)
+		return g.toInsertSQL(dml, v)
 	case "update":
-		return g.toUpdateSQL(dml)
+		return g.toUpdateSQL(dml, v)
 	case "delete":
-		return g.toDeleteSQL(dml)
+		return g.toDeleteSQL(dml, v)
 	default:
 		return "", fmt.Errorf("unsupported DML kind: '%s' (length: %d)", dml.Kind, len(dml.Kind))
 	}
 }
 
 // toInsertSQL generates an INSERT SQL statement
-func (g *SQLGenerator) toInsertSQL(dml *proto.DMLData) (string, error) {
+func (g *SQLGenerator) toInsertSQL(dml *proto.DMLData, v *values) (string, error) {
 	if len(dml.ColumnNames) != len(dml.ColumnValues) {
 		return "", fmt.Errorf("mismatched column names and values: %d names, %d values", len(dml.ColumnNames), len(dml.ColumnValues))
 	}
 
-	columns := strings.Join(dml.ColumnNames, ", ")
-	values := make([]string, len(dml.ColumnValues))
-	for i, v := range dml.ColumnValues {
-		formatted, err := g.dialect.FormatValue(v)
-		if err != nil {
-			return "", fmt.Errorf("error formatting value for column %s: %w", dml.ColumnNames[i], err)
+	// Unchanged columns, left out of a MINIMAL row image, take the replica's default
+	names := make([]string, 0, len(dml.ColumnNames))
+	vals := make([]*proto.ColumnValue, 0, len(dml.ColumnValues))
+	for i, val := range dml.ColumnValues {
+		if val.GetUnchangedToast() || g.isGenerated(dml.Table, dml.ColumnNames[i]) {
+			continue
+		}
+		names = append(names, dml.ColumnNames[i])
+		vals = append(vals, val)
+	}
+
+	var clause string
+	if g.idempotent {
+		if merging, ok := g.dialect.(dialect.Merging); ok {
+			formattedValues := make([]string, len(vals))
+			for i, val := range vals {
+				formatted, err := v.format(val)
+				if err != nil {
+					return "", fmt.Errorf("error formatting value for column %s: %w", names[i], err)
+				}
+				formattedValues[i] = formatted
+			}
+			if stmt := merging.MergeStatement(dml.Table, names, formattedValues, dml.PrimaryKey, nonKeyColumns(names, dml.PrimaryKey)); stmt != "" {
+				return stmt + g.terminator(), nil
+			}
+			return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)%s", dml.Table, strings.Join(names, ", "), strings.Join(formattedValues, ", "), g.terminator()), nil
 		}
-		values[i] = formatted
+		clause = g.dialect.UpsertClause(dml.PrimaryKey, nonKeyColumns(names, dml.PrimaryKey))
 	}
 
-	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);", dml.Table, columns, strings.Join(values, ", ")), nil
+	buf := getBuffer(statementSize(dml) + len(clause))
+	defer putBuffer(buf)
+	fmt.Fprintf(buf, "INSERT INTO %s (%s) VALUES (", dml.Table, strings.Join(names, ", "))
+	for i, val := range vals {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		if err := v.write(buf, val); err != nil {
+			return "", fmt.Errorf("error formatting value for column %s: %w", names[i], err)
+		}
+	}
+	buf.WriteByte(')')
+	if clause != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(clause)
+	}
+	buf.WriteString(g.terminator())
+	return buf.String(), nil
+}
+
+// nonKeyColumns returns the columns that are not part of the primary key
+func nonKeyColumns(columns, primaryKey []string) []string {
+	result := make([]string, 0, len(columns))
+	for _, col := range columns {
+		if !slices.Contains(primaryKey, col) {
+			result = append(result, col)
+		}
+	}
+	return result
 }
 
 // toUpdateSQL generates an UPDATE SQL statement
-func (g *SQLGenerator) toUpdateSQL(dml *proto.DMLData) (string, error) {
+func (g *SQLGenerator) toUpdateSQL(dml *proto.DMLData, v *values) (string, error) {
 	if len(dml.ColumnNames) != len(dml.ColumnValues) {
 		return "", fmt.Errorf("mismatched column names and values: %d names, %d values", len(dml.ColumnNames), len(dml.ColumnValues))
 	}
@@ -73,36 +323,198 @@ func (g *SQLGenerator) toUpdateSQL(dml *proto.DMLData) (string, error) {
 		return "", fmt.Errorf("update requires old keys")
 	}
 
-	// Build SET clause
-	setClauses := make([]string, len(dml.ColumnNames))
+	buf := getBuffer(statementSize(dml))
+	defer putBuffer(buf)
+	fmt.Fprintf(buf, "UPDATE %s SET ", dml.Table)
+
+	// Build SET clause, leaving out unchanged TOAST values so the replica keeps its copy
+	// and generated columns, which the replica recomputes
+	set := 0
 	for i, col := range dml.ColumnNames {
-		formatted, err := g.dialect.FormatValue(dml.ColumnValues[i])
-		if err != nil {
+		if dml.ColumnValues[i].GetUnchangedToast() || g.isGenerated(dml.Table, col) {
+			continue
+		}
+		if set > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(col)
+		buf.WriteString(" = ")
+		if err := v.write(buf, dml.ColumnValues[i]); err != nil {
 			return "", fmt.Errorf("error formatting value for column %s: %w", col, err)
 		}
-		setClauses[i] = fmt.Sprintf("%s = %s", col, formatted)
+		set++
+	}
+	if set == 0 {
+		return "", ErrNoChanges
 	}
 
 	// Build WHERE clause
-	whereClauses := make([]string, len(dml.OldKeys.KeyNames))
+	buf.WriteString(" WHERE ")
+	if err := g.writeKeys(buf, dml.OldKeys, v); err != nil {
+		return "", err
+	}
+	buf.WriteString(g.terminator())
+	return buf.String(), nil
+}
+
+// writeKeys writes the conditions matching a row by its keys
+func (g *SQLGenerator) writeKeys(buf *bytes.Buffer, keys *proto.OldKeys, v *values) error {
+	for i, key := range keys.KeyNames {
+		if i > 0 {
+			buf.WriteString(" AND ")
+		}
+		buf.WriteString(key)
+		buf.WriteString(" = ")
+		if err := v.write(buf, keys.KeyValues[i]); err != nil {
+			return fmt.Errorf("error formatting value for key %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// toDeleteSQL generates a DELETE SQL statement
+func (g *SQLGenerator) toDeleteSQL(dml *proto.DMLData, v *values) (string, error) {
+	if dml.OldKeys == nil || len(dml.OldKeys.KeyNames) == 0 || len(dml.OldKeys.KeyValues) == 0 {
+		return "", fmt.Errorf("delete requires old keys")
+	}
+
+	buf := getBuffer(statementSize(dml))
+	defer putBuffer(buf)
+	fmt.Fprintf(buf, "DELETE FROM %s WHERE ", dml.Table)
+	if err := g.writeKeys(buf, dml.OldKeys, v); err != nil {
+		return "", err
+	}
+	buf.WriteString(g.terminator())
+	return buf.String(), nil
+}
+
+// ErrUnchangedToast is returned for an update with unchanged TOAST values written to a
+// versioned replica. The new row version must carry every column, but the change
+// doesn't include the unchanged values.
+var ErrUnchangedToast = errors.New("update has unchanged TOAST values that can't be written as a new row version")
+
+// versionedRows returns the columns and the row versions a change writes to a
+// versioned replica. Inserts and updates write the new row; deletes write a tombstone
+// with the old key. An update that changes the key also writes a tombstone for the old
+// key, with NULLs (the column defaults) for the other columns.
+func (g *SQLGenerator) versionedRows(dml *proto.DMLData, versioned dialect.Versioned) ([]string, [][]*proto.ColumnValue, error) {
+	versionColumn, deletedColumn := versioned.VersionColumns()
+	version := &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: int64(versioned.NextVersion())}}
+	live := &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: 0}}
+	deleted := &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: 1}}
+
+	switch dml.Kind {
+	case "insert", "update":
+		if len(dml.ColumnNames) != len(dml.ColumnValues) {
+			return nil, nil, fmt.Errorf("mismatched column names and values: %d names, %d values", len(dml.ColumnNames), len(dml.ColumnValues))
+		}
+		columns := make([]string, 0, len(dml.ColumnNames)+2)
+		row := make([]*proto.ColumnValue, 0, len(dml.ColumnValues)+2)
+		for i, col := range dml.ColumnNames {
+			if g.isGenerated(dml.Table, col) {
+				continue
+			}
+			if dml.ColumnValues[i].GetUnchangedToast() {
+				return nil, nil, fmt.Errorf("%w: column %s", ErrUnchangedToast, col)
+			}
+			columns = append(columns, col)
+			row = append(row, dml.ColumnValues[i])
+		}
+		columns = append(columns, versionColumn, deletedColumn)
+		row = append(row, version, live)
+
+		rows := [][]*proto.ColumnValue{row}
+		if dml.Kind == "update" {
+			if dml.OldKeys == nil || len(dml.OldKeys.KeyNames) == 0 || len(dml.OldKeys.KeyValues) == 0 {
+				return nil, nil, fmt.Errorf("update requires old keys")
+			}
+			tombstone, err := g.keyChangeTombstone(dml, columns, row)
+			if err != nil {
+				return nil, nil, err
+			}
+			if tombstone != nil {
+				tombstone[len(tombstone)-2], tombstone[len(tombstone)-1] = version, deleted
+				rows = [][]*proto.ColumnValue{tombstone, row}
+			}
+		}
+		return columns, rows, nil
+	case "delete":
+		if dml.OldKeys == nil || len(dml.OldKeys.KeyNames) == 0 || len(dml.OldKeys.KeyValues) == 0 {
+			return nil, nil, fmt.Errorf("delete requires old keys")
+		}
+		columns := append(slices.Clone(dml.OldKeys.KeyNames), versionColumn, deletedColumn)
+		row := append(slices.Clone(dml.OldKeys.KeyValues), version, deleted)
+		return columns, [][]*proto.ColumnValue{row}, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported DML kind: '%s' (length: %d)", dml.Kind, len(dml.Kind))
+	}
+}
+
+// keyChangeTombstone returns a tombstone row for an update's old key if the update
+// changes the key, or nil if it doesn't
+func (g *SQLGenerator) keyChangeTombstone(dml *proto.DMLData, columns []string, row []*proto.ColumnValue) ([]*proto.ColumnValue, error) {
+	tombstone := make([]*proto.ColumnValue, len(row))
+	changed := false
 	for i, key := range dml.OldKeys.KeyNames {
-		formatted, err := g.dialect.FormatValue(dml.OldKeys.KeyValues[i])
+		idx := slices.Index(columns, key)
+		if idx < 0 {
+			return nil, fmt.Errorf("update is missing key column %s", key)
+		}
+		tombstone[idx] = dml.OldKeys.KeyValues[i]
+
+		// Compare the values as the replica sees them
+		oldValue, err := g.dialect.FormatValue(dml.OldKeys.KeyValues[i])
 		if err != nil {
-			return "", fmt.Errorf("error formatting value for key %s: %w", key, err)
+			return nil, fmt.Errorf("error formatting value for key %s: %w", key, err)
 		}
-		whereClauses[i] = fmt.Sprintf("%s = %s", key, formatted)
+		newValue, err := g.dialect.FormatValue(row[idx])
+		if err != nil {
+			return nil, fmt.Errorf("error formatting value for column %s: %w", key, err)
+		}
+		if oldValue != newValue {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil, nil
+	}
+	return tombstone, nil
+}
+
+// toVersionedSQL writes a change to a versioned replica as an INSERT of its row versions
+func (g *SQLGenerator) toVersionedSQL(dml *proto.DMLData, versioned dialect.Versioned, v *values) (string, error) {
+	columns, rows, err := g.versionedRows(dml, versioned)
+	if err != nil {
+		return "", err
 	}
 
-	return fmt.Sprintf("UPDATE %s SET %s WHERE %s;",
-		dml.Table,
-		strings.Join(setClauses, ", "),
-		strings.Join(whereClauses, " AND ")), nil
+	buf := getBuffer(statementSize(dml) * len(rows))
+	defer putBuffer(buf)
+	fmt.Fprintf(buf, "INSERT INTO %s (%s) VALUES ", dml.Table, strings.Join(columns, ", "))
+	for r, row := range rows {
+		if r > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteByte('(')
+		for i, val := range row {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			if err := v.write(buf, val); err != nil {
+				return "", fmt.Errorf("error formatting value for column %s: %w", columns[i], err)
+			}
+		}
+		buf.WriteByte(')')
+	}
+	buf.WriteString(g.terminator())
+	return buf.String(), nil
 }
 
-// toDeleteSQL generates a DELETE SQL statement
-func (g *SQLGenerator) toDeleteSQL(dml *proto.DMLData) (string, error) {
+// ToRowExistsSQL generates a query that returns a row if the row identified by the
+// change's old keys exists on the replica
+func (g *SQLGenerator) ToRowExistsSQL(dml *proto.DMLData) (string, error) {
 	if dml.OldKeys == nil || len(dml.OldKeys.KeyNames) == 0 || len(dml.OldKeys.KeyValues) == 0 {
-		return "", fmt.Errorf("delete requires old keys")
+		return "", fmt.Errorf("row lookup requires old keys")
 	}
 
 	whereClauses := make([]string, len(dml.OldKeys.KeyNames))
@@ -114,9 +526,25 @@ func (g *SQLGenerator) toDeleteSQL(dml *proto.DMLData) (string, error) {
 		whereClauses[i] = fmt.Sprintf("%s = %s", key, formatted)
 	}
 
-	return fmt.Sprintf("DELETE FROM %s WHERE %s;",
+	// Oracle has no LIMIT clause
+	limit := "LIMIT 1"
+	if g.dialect.Name() == "oracle" {
+		limit = "FETCH FIRST 1 ROWS ONLY"
+	}
+	return fmt.Sprintf("SELECT 1 FROM %s WHERE %s %s%s",
 		dml.Table,
-		strings.Join(whereClauses, " AND ")), nil
+		strings.Join(whereClauses, " AND "),
+		limit,
+		g.terminator()), nil
+}
+
+// terminator returns the end of a generated statement. Oracle's driver rejects
+// statements ending in a semicolon.
+func (g *SQLGenerator) terminator() string {
+	if g.dialect.Name() == "oracle" {
+		return ""
+	}
+	return ";"
 }
 
 // ToSQL converts a Change into a SQL statement using PostgreSQL dialect (backwards compatible)
@@ -1,8 +1,13 @@
 package sql
 
 import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
 
+	"kasho/pkg/dialect"
 	"kasho/proto"
 )
 
@@ -212,7 +217,7 @@ func TestToSQL(t *testing.T) {
 					},
 				},
 			},
-			wantSQL: "INSERT INTO users (name, created_at) VALUES ('John Doe', '2024-03-20 15:04:05');",
+			wantSQL: "INSERT INTO users (name, created_at) VALUES ('John Doe', '2024-03-20 15:04:05+00');",
 			wantErr: false,
 		},
 		{
@@ -340,3 +345,699 @@ func TestToSQL_ComplexScenarios(t *testing.T) {
 		})
 	}
 }
+
+func TestToSQL_Idempotent(t *testing.T) {
+	insert := &proto.Change{
+		Data: &proto.Change_Dml{
+			Dml: &proto.DMLData{
+				Table:       "users",
+				ColumnNames: []string{"id", "name"},
+				ColumnValues: []*proto.ColumnValue{
+					{Value: &proto.ColumnValue_IntValue{IntValue: 1}},
+					{Value: &proto.ColumnValue_StringValue{StringValue: "John Doe"}},
+				},
+				Kind:       "insert",
+				PrimaryKey: []string{"id"},
+			},
+		},
+	}
+
+	withoutKey := &proto.Change{
+		Data: &proto.Change_Dml{
+			Dml: &proto.DMLData{
+				Table:       "users",
+				ColumnNames: []string{"id", "name"},
+				ColumnValues: []*proto.ColumnValue{
+					{Value: &proto.ColumnValue_IntValue{IntValue: 1}},
+					{Value: &proto.ColumnValue_StringValue{StringValue: "John Doe"}},
+				},
+				Kind: "insert",
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		dialect dialect.Dialect
+		change  *proto.Change
+		wantSQL string
+	}{
+		{
+			name:    "postgres upsert on primary key",
+			dialect: dialect.NewPostgreSQL(),
+			change:  insert,
+			wantSQL: "INSERT INTO users (id, name) VALUES (1, 'John Doe') ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name;",
+		},
+		{
+			name:    "postgres without primary key skips conflicts",
+			dialect: dialect.NewPostgreSQL(),
+			change:  withoutKey,
+			wantSQL: "INSERT INTO users (id, name) VALUES (1, 'John Doe') ON CONFLICT DO NOTHING;",
+		},
+		{
+			name:    "mysql upsert",
+			dialect: dialect.NewMySQL(),
+			change:  insert,
+			wantSQL: "INSERT INTO users (id, name) VALUES (1, 'John Doe') ON DUPLICATE KEY UPDATE name = VALUES(name);",
+		},
+		{
+			name:    "oracle merge",
+			dialect: dialect.NewOracle(),
+			change:  insert,
+			wantSQL: "MERGE INTO users dst USING (SELECT 1 AS id, 'John Doe' AS name FROM DUAL) src ON (dst.id = src.id) WHEN MATCHED THEN UPDATE SET dst.name = src.name WHEN NOT MATCHED THEN INSERT (id, name) VALUES (src.id, src.name)",
+		},
+		{
+			name:    "oracle without primary key inserts",
+			dialect: dialect.NewOracle(),
+			change:  withoutKey,
+			wantSQL: "INSERT INTO users (id, name) VALUES (1, 'John Doe')",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewSQLGenerator(tt.dialect)
+			g.SetIdempotent(true)
+			got, err := g.ToSQL(tt.change)
+			if err != nil {
+				t.Fatalf("ToSQL() unexpected error: %v", err)
+			}
+			if got != tt.wantSQL {
+				t.Errorf("ToSQL() = %v, want %v", got, tt.wantSQL)
+			}
+		})
+	}
+
+	// Updates and deletes are unaffected by idempotent mode
+	g := NewSQLGenerator(dialect.NewPostgreSQL())
+	g.SetIdempotent(true)
+	got, err := g.ToSQL(&proto.Change{
+		Data: &proto.Change_Dml{
+			Dml: &proto.DMLData{
+				Table: "users",
+				Kind:  "delete",
+				OldKeys: &proto.OldKeys{
+					KeyNames:  []string{"id"},
+					KeyValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_IntValue{IntValue: 1}}},
+				},
+			},
+		},
+	})
+	if err != nil || got != "DELETE FROM users WHERE id = 1;" {
+		t.Errorf("ToSQL() delete = %q, %v", got, err)
+	}
+}
+
+func TestToRowExistsSQL(t *testing.T) {
+	g := NewSQLGenerator(dialect.NewPostgreSQL())
+
+	got, err := g.ToRowExistsSQL(&proto.DMLData{
+		Table: "users",
+		Kind:  "update",
+		OldKeys: &proto.OldKeys{
+			KeyNames: []string{"org_id", "id"},
+			KeyValues: []*proto.ColumnValue{
+				{Value: &proto.ColumnValue_IntValue{IntValue: 7}},
+				{Value: &proto.ColumnValue_IntValue{IntValue: 1}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ToRowExistsSQL() unexpected error: %v", err)
+	}
+	want := "SELECT 1 FROM users WHERE org_id = 7 AND id = 1 LIMIT 1;"
+	if got != want {
+		t.Errorf("ToRowExistsSQL() = %v, want %v", got, want)
+	}
+
+	if _, err := g.ToRowExistsSQL(&proto.DMLData{Table: "users", Kind: "update"}); err == nil {
+		t.Error("ToRowExistsSQL() without old keys should return an error")
+	}
+
+	// Oracle has no LIMIT and rejects the trailing semicolon
+	got, err = NewSQLGenerator(dialect.NewOracle()).ToRowExistsSQL(&proto.DMLData{
+		Table: "users",
+		Kind:  "delete",
+		OldKeys: &proto.OldKeys{
+			KeyNames:  []string{"id"},
+			KeyValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_IntValue{IntValue: 1}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ToRowExistsSQL() unexpected error: %v", err)
+	}
+	want = "SELECT 1 FROM users WHERE id = 1 FETCH FIRST 1 ROWS ONLY"
+	if got != want {
+		t.Errorf("ToRowExistsSQL() = %v, want %v", got, want)
+	}
+}
+
+func TestToSQL_UnchangedToast(t *testing.T) {
+	unchanged := &proto.ColumnValue{Value: &proto.ColumnValue_UnchangedToast{UnchangedToast: true}}
+	oldKeys := &proto.OldKeys{
+		KeyNames:  []string{"id"},
+		KeyValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_IntValue{IntValue: 1}}},
+	}
+	g := NewSQLGenerator(dialect.NewPostgreSQL())
+
+	got, err := g.ToSQL(&proto.Change{
+		Data: &proto.Change_Dml{
+			Dml: &proto.DMLData{
+				Table:       "documents",
+				Kind:        "update",
+				ColumnNames: []string{"title", "body"},
+				ColumnValues: []*proto.ColumnValue{
+					{Value: &proto.ColumnValue_StringValue{StringValue: "New title"}},
+					unchanged,
+				},
+				OldKeys: oldKeys,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ToSQL() unexpected error: %v", err)
+	}
+	want := "UPDATE documents SET title = 'New title' WHERE id = 1;"
+	if got != want {
+		t.Errorf("ToSQL() = %v, want %v", got, want)
+	}
+
+	// An update that only touches unchanged TOAST columns has nothing to set
+	_, err = g.ToSQL(&proto.Change{
+		Data: &proto.Change_Dml{
+			Dml: &proto.DMLData{
+				Table:        "documents",
+				Kind:         "update",
+				ColumnNames:  []string{"body"},
+				ColumnValues: []*proto.ColumnValue{unchanged},
+				OldKeys:      oldKeys,
+			},
+		},
+	})
+	if !errors.Is(err, ErrNoChanges) {
+		t.Errorf("ToSQL() error = %v, want ErrNoChanges", err)
+	}
+
+	// An insert leaves out unchanged columns, e.g. defaults missing from a MySQL MINIMAL
+	// row image, so the replica fills in its own default
+	got, err = g.ToSQL(&proto.Change{
+		Data: &proto.Change_Dml{
+			Dml: &proto.DMLData{
+				Table:       "documents",
+				Kind:        "insert",
+				ColumnNames: []string{"id", "title", "created_at"},
+				ColumnValues: []*proto.ColumnValue{
+					{Value: &proto.ColumnValue_IntValue{IntValue: 2}},
+					{Value: &proto.ColumnValue_StringValue{StringValue: "Draft"}},
+					unchanged,
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ToSQL() unexpected error: %v", err)
+	}
+	want = "INSERT INTO documents (id, title) VALUES (2, 'Draft');"
+	if got != want {
+		t.Errorf("ToSQL() = %v, want %v", got, want)
+	}
+}
+
+func TestToSQL_GeneratedColumns(t *testing.T) {
+	g := NewSQLGenerator(dialect.NewPostgreSQL())
+	g.SetGeneratedColumns(map[string][]string{"public.orders": {"total"}})
+
+	columnValues := []*proto.ColumnValue{
+		{Value: &proto.ColumnValue_IntValue{IntValue: 1}},
+		{Value: &proto.ColumnValue_IntValue{IntValue: 3}},
+		{Value: &proto.ColumnValue_FloatValue{FloatValue: 29.97}},
+	}
+	oldKeys := &proto.OldKeys{
+		KeyNames:  []string{"id"},
+		KeyValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_IntValue{IntValue: 1}}},
+	}
+
+	tests := []struct {
+		name       string
+		idempotent bool
+		dml        *proto.DMLData
+		want       string
+	}{
+		{
+			name: "insert",
+			dml: &proto.DMLData{
+				Table:        "public.orders",
+				Kind:         "insert",
+				ColumnNames:  []string{"id", "quantity", "total"},
+				ColumnValues: columnValues,
+			},
+			want: "INSERT INTO public.orders (id, quantity) VALUES (1, 3);",
+		},
+		{
+			name:       "idempotent insert",
+			idempotent: true,
+			dml: &proto.DMLData{
+				Table:        "public.orders",
+				Kind:         "insert",
+				ColumnNames:  []string{"id", "quantity", "total"},
+				ColumnValues: columnValues,
+				PrimaryKey:   []string{"id"},
+			},
+			want: "INSERT INTO public.orders (id, quantity) VALUES (1, 3) ON CONFLICT (id) DO UPDATE SET quantity = EXCLUDED.quantity;",
+		},
+		{
+			name: "update",
+			dml: &proto.DMLData{
+				Table:        "public.orders",
+				Kind:         "update",
+				ColumnNames:  []string{"id", "quantity", "total"},
+				ColumnValues: columnValues,
+				OldKeys:      oldKeys,
+			},
+			want: "UPDATE public.orders SET id = 1, quantity = 3 WHERE id = 1;",
+		},
+		{
+			name: "other tables are untouched",
+			dml: &proto.DMLData{
+				Table:        "public.invoices",
+				Kind:         "insert",
+				ColumnNames:  []string{"id", "quantity", "total"},
+				ColumnValues: columnValues,
+			},
+			want: "INSERT INTO public.invoices (id, quantity, total) VALUES (1, 3, 29.970000);",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g.SetIdempotent(tt.idempotent)
+			got, err := g.ToSQL(&proto.Change{Data: &proto.Change_Dml{Dml: tt.dml}})
+			if err != nil {
+				t.Fatalf("ToSQL() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ToSQL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToPreparedSQL(t *testing.T) {
+	update := &proto.DMLData{
+		Table:       "users",
+		Kind:        "update",
+		ColumnNames: []string{"id", "name"},
+		ColumnValues: []*proto.ColumnValue{
+			{Value: &proto.ColumnValue_IntValue{IntValue: 1}},
+			{Value: &proto.ColumnValue_StringValue{StringValue: "it's"}},
+		},
+		OldKeys: &proto.OldKeys{
+			KeyNames:  []string{"id"},
+			KeyValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_IntValue{IntValue: 1}}},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		dialect   dialect.Dialect
+		dml       *proto.DMLData
+		wantQuery string
+		wantArgs  []any
+	}{
+		{
+			name:      "postgres update",
+			dialect:   dialect.NewPostgreSQL(),
+			dml:       update,
+			wantQuery: "UPDATE users SET id = $1, name = $2 WHERE id = $3;",
+			wantArgs:  []any{int64(1), "it's", int64(1)},
+		},
+		{
+			name:      "mysql update",
+			dialect:   dialect.NewMySQL(),
+			dml:       update,
+			wantQuery: "UPDATE users SET id = ?, name = ? WHERE id = ?;",
+			wantArgs:  []any{int64(1), "it's", int64(1)},
+		},
+		{
+			name:    "postgres insert with null",
+			dialect: dialect.NewPostgreSQL(),
+			dml: &proto.DMLData{
+				Table:        "users",
+				Kind:         "insert",
+				ColumnNames:  []string{"id", "name"},
+				ColumnValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_IntValue{IntValue: 2}}, nil},
+			},
+			wantQuery: "INSERT INTO users (id, name) VALUES ($1, $2);",
+			wantArgs:  []any{int64(2), nil},
+		},
+		{
+			name:      "postgres delete",
+			dialect:   dialect.NewPostgreSQL(),
+			dml:       &proto.DMLData{Table: "users", Kind: "delete", OldKeys: update.OldKeys},
+			wantQuery: "DELETE FROM users WHERE id = $1;",
+			wantArgs:  []any{int64(1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewSQLGenerator(tt.dialect)
+			query, args, err := g.ToPreparedSQL(tt.dml)
+			if err != nil {
+				t.Fatalf("ToPreparedSQL() unexpected error: %v", err)
+			}
+			if query != tt.wantQuery {
+				t.Errorf("ToPreparedSQL() query = %v, want %v", query, tt.wantQuery)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("ToPreparedSQL() args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+
+	// The literal statement is unchanged
+	g := NewSQLGenerator(dialect.NewPostgreSQL())
+	got, err := g.ToSQL(&proto.Change{Data: &proto.Change_Dml{Dml: update}})
+	if err != nil {
+		t.Fatalf("ToSQL() unexpected error: %v", err)
+	}
+	if want := "UPDATE users SET id = 1, name = 'it''s' WHERE id = 1;"; got != want {
+		t.Errorf("ToSQL() = %v, want %v", got, want)
+	}
+}
+
+func TestToBulkRow(t *testing.T) {
+	g := NewSQLGenerator(dialect.NewMySQL())
+	g.SetGeneratedColumns(map[string][]string{"users": {"full_name"}})
+
+	columns, row, err := g.ToBulkRow(&proto.DMLData{
+		Table:       "users",
+		Kind:        "insert",
+		ColumnNames: []string{"id", "full_name", "active"},
+		ColumnValues: []*proto.ColumnValue{
+			{Value: &proto.ColumnValue_IntValue{IntValue: 1}},
+			{Value: &proto.ColumnValue_StringValue{StringValue: "Jane Doe"}},
+			{Value: &proto.ColumnValue_BoolValue{BoolValue: true}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ToBulkRow() unexpected error: %v", err)
+	}
+	if want := []string{"id", "active"}; !reflect.DeepEqual(columns, want) {
+		t.Errorf("ToBulkRow() columns = %v, want %v", columns, want)
+	}
+	if want := []any{int64(1), true}; !reflect.DeepEqual(row, want) {
+		t.Errorf("ToBulkRow() row = %#v, want %#v", row, want)
+	}
+
+	// Values written through SQL expressions and non-inserts can't be bulk loaded
+	_, _, err = g.ToBulkRow(&proto.DMLData{
+		Table:        "users",
+		Kind:         "insert",
+		ColumnNames:  []string{"settings"},
+		ColumnValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_JsonValue{JsonValue: "{}"}}},
+	})
+	if !errors.Is(err, ErrNotBulkLoadable) {
+		t.Errorf("ToBulkRow() with JSON on MySQL error = %v, want ErrNotBulkLoadable", err)
+	}
+	if _, _, err := g.ToBulkRow(&proto.DMLData{Table: "users", Kind: "delete"}); !errors.Is(err, ErrNotBulkLoadable) {
+		t.Errorf("ToBulkRow() on delete error = %v, want ErrNotBulkLoadable", err)
+	}
+}
+
+// fixedVersionClickHouse is the ClickHouse dialect with a fixed row version
+type fixedVersionClickHouse struct {
+	*dialect.ClickHouse
+}
+
+func (fixedVersionClickHouse) NextVersion() uint64 { return 7 }
+
+func TestToSQL_Versioned(t *testing.T) {
+	g := NewSQLGenerator(fixedVersionClickHouse{dialect.NewClickHouse()})
+	g.SetGeneratedColumns(map[string][]string{"analytics.users": {"name_length"}})
+
+	id := func(v int64) *proto.ColumnValue {
+		return &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: v}}
+	}
+	name := &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: "O'Brien"}}
+	oldKeys := &proto.OldKeys{KeyNames: []string{"id"}, KeyValues: []*proto.ColumnValue{id(1)}}
+
+	tests := []struct {
+		name    string
+		dml     *proto.DMLData
+		want    string
+		wantErr error
+	}{
+		{
+			name: "insert",
+			dml: &proto.DMLData{
+				Table:        "analytics.users",
+				Kind:         "insert",
+				ColumnNames:  []string{"id", "name", "name_length"},
+				ColumnValues: []*proto.ColumnValue{id(1), name, id(7)},
+			},
+			want: `INSERT INTO analytics.users (id, name, _version, _is_deleted) VALUES (1, 'O\'Brien', 7, 0);`,
+		},
+		{
+			name: "update writes a new row version",
+			dml: &proto.DMLData{
+				Table:        "analytics.users",
+				Kind:         "update",
+				ColumnNames:  []string{"id", "name"},
+				ColumnValues: []*proto.ColumnValue{id(1), name},
+				OldKeys:      oldKeys,
+			},
+			want: `INSERT INTO analytics.users (id, name, _version, _is_deleted) VALUES (1, 'O\'Brien', 7, 0);`,
+		},
+		{
+			name: "update changing the key also deletes the old key",
+			dml: &proto.DMLData{
+				Table:        "analytics.users",
+				Kind:         "update",
+				ColumnNames:  []string{"id", "name"},
+				ColumnValues: []*proto.ColumnValue{id(2), name},
+				OldKeys:      oldKeys,
+			},
+			want: `INSERT INTO analytics.users (id, name, _version, _is_deleted) VALUES (1, NULL, 7, 1), (2, 'O\'Brien', 7, 0);`,
+		},
+		{
+			name: "delete writes a tombstone",
+			dml: &proto.DMLData{
+				Table:   "analytics.users",
+				Kind:    "delete",
+				OldKeys: oldKeys,
+			},
+			want: "INSERT INTO analytics.users (id, _version, _is_deleted) VALUES (1, 7, 1);",
+		},
+		{
+			name: "update with unchanged TOAST values",
+			dml: &proto.DMLData{
+				Table:        "analytics.users",
+				Kind:         "update",
+				ColumnNames:  []string{"id", "name"},
+				ColumnValues: []*proto.ColumnValue{id(1), {Value: &proto.ColumnValue_UnchangedToast{UnchangedToast: true}}},
+				OldKeys:      oldKeys,
+			},
+			wantErr: ErrUnchangedToast,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := g.ToSQL(&proto.Change{Data: &proto.Change_Dml{Dml: tt.dml}})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("ToSQL() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ToSQL() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ToSQL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A key change writes two rows, which can't be bulk loaded as one
+	_, _, err := g.ToBulkRow(tests[2].dml)
+	if !errors.Is(err, ErrNotBulkLoadable) {
+		t.Errorf("ToBulkRow() error = %v, want ErrNotBulkLoadable", err)
+	}
+	columns, row, err := g.ToBulkRow(tests[3].dml)
+	if err != nil {
+		t.Fatalf("ToBulkRow() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(columns, []string{"id", "_version", "_is_deleted"}) || !reflect.DeepEqual(row, []any{int64(1), int64(7), int64(1)}) {
+		t.Errorf("ToBulkRow() = %v, %v", columns, row)
+	}
+}
+
+// wideRow is a document row with a multi-megabyte text column and binary attachment
+func wideRow(kind string) *proto.DMLData {
+	dml := &proto.DMLData{
+		Table:       "public.documents",
+		Kind:        kind,
+		ColumnNames: []string{"id", "body", "attachment"},
+		ColumnValues: []*proto.ColumnValue{
+			{Value: &proto.ColumnValue_IntValue{IntValue: 1}},
+			{Value: &proto.ColumnValue_StringValue{StringValue: strings.Repeat(`it's C:\docs `, 1<<18)}},
+			{Value: &proto.ColumnValue_BytesValue{BytesValue: bytes.Repeat([]byte{0xca, 0xfe}, 1<<18)}},
+		},
+		PrimaryKey: []string{"id"},
+	}
+	if kind != "insert" {
+		dml.OldKeys = &proto.OldKeys{
+			KeyNames:  []string{"id"},
+			KeyValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_IntValue{IntValue: 1}}},
+		}
+	}
+	return dml
+}
+
+func TestToSQL_WideRow(t *testing.T) {
+	body := strings.Repeat(`it's C:\docs `, 1<<18)
+	attachment := strings.Repeat("cafe", 1<<18)
+
+	tests := []struct {
+		dialect dialect.Dialect
+		kind    string
+		want    string
+	}{
+		{
+			dialect: dialect.NewPostgreSQL(),
+			kind:    "insert",
+			want:    "INSERT INTO public.documents (id, body, attachment) VALUES (1, '" + strings.ReplaceAll(body, "'", "''") + "', decode('" + attachment + "', 'hex'));",
+		},
+		{
+			dialect: dialect.NewMySQL(),
+			kind:    "insert",
+			want:    "INSERT INTO public.documents (id, body, attachment) VALUES (1, '" + strings.ReplaceAll(strings.ReplaceAll(body, "'", "''"), `\`, `\\`) + "', X'" + attachment + "');",
+		},
+		{
+			dialect: dialect.NewPostgreSQL(),
+			kind:    "update",
+			want:    "UPDATE public.documents SET id = 1, body = '" + strings.ReplaceAll(body, "'", "''") + "', attachment = decode('" + attachment + "', 'hex') WHERE id = 1;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dialect.Name()+"/"+tt.kind, func(t *testing.T) {
+			g := NewSQLGenerator(tt.dialect)
+			// Repeated statements reuse pooled buffers, which must start out empty
+			for range 3 {
+				got, err := g.ToSQL(&proto.Change{Data: &proto.Change_Dml{Dml: wideRow(tt.kind)}})
+				if err != nil {
+					t.Fatalf("ToSQL() unexpected error: %v", err)
+				}
+				if got != tt.want {
+					t.Fatalf("ToSQL() returned %d bytes, want the %d byte statement", len(got), len(tt.want))
+				}
+			}
+		})
+	}
+}
+
+// benchmarkRow is an orders row with the column types typical of application tables
+func benchmarkRow(kind string) *proto.DMLData {
+	dml := &proto.DMLData{
+		Table:       "public.orders",
+		Kind:        kind,
+		ColumnNames: []string{"id", "customer_id", "status", "total", "paid", "notes", "metadata", "placed_at", "external_id", "attachment"},
+		ColumnValues: []*proto.ColumnValue{
+			{Value: &proto.ColumnValue_IntValue{IntValue: 123456}},
+			{Value: &proto.ColumnValue_IntValue{IntValue: 42}},
+			{Value: &proto.ColumnValue_StringValue{StringValue: "shipped"}},
+			{Value: &proto.ColumnValue_FloatValue{FloatValue: 199.95}},
+			{Value: &proto.ColumnValue_BoolValue{BoolValue: true}},
+			{Value: &proto.ColumnValue_StringValue{StringValue: "Leave at the customer's back door"}},
+			{Value: &proto.ColumnValue_JsonValue{JsonValue: `{"channel":"web","coupon":"SPRING"}`}},
+			{Value: &proto.ColumnValue_TimestampValue{TimestampValue: "2024-03-20T15:00:00Z"}},
+			{Value: &proto.ColumnValue_UuidValue{UuidValue: "5f0c2c7e-8d7b-4bb0-9a52-4f7d8f0a3e11"}},
+			{Value: &proto.ColumnValue_BytesValue{BytesValue: []byte("%PDF-1.7 invoice")}},
+		},
+		PrimaryKey: []string{"id"},
+	}
+	if kind != "insert" {
+		dml.OldKeys = &proto.OldKeys{
+			KeyNames:  []string{"id"},
+			KeyValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_IntValue{IntValue: 123456}}},
+		}
+	}
+	return dml
+}
+
+func BenchmarkToSQL(b *testing.B) {
+	for _, d := range []dialect.Dialect{dialect.NewPostgreSQL(), dialect.NewMySQL()} {
+		for _, kind := range []string{"insert", "update", "delete"} {
+			b.Run(d.Name()+"/"+kind, func(b *testing.B) {
+				g := NewSQLGenerator(d)
+				change := &proto.Change{Data: &proto.Change_Dml{Dml: benchmarkRow(kind)}}
+				b.ReportAllocs()
+				for b.Loop() {
+					if _, err := g.ToSQL(change); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkToSQL_Idempotent(b *testing.B) {
+	for _, d := range []dialect.Dialect{dialect.NewPostgreSQL(), dialect.NewMySQL()} {
+		b.Run(d.Name(), func(b *testing.B) {
+			g := NewSQLGenerator(d)
+			g.SetIdempotent(true)
+			change := &proto.Change{Data: &proto.Change_Dml{Dml: benchmarkRow("insert")}}
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := g.ToSQL(change); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkToPreparedSQL(b *testing.B) {
+	for _, kind := range []string{"insert", "update", "delete"} {
+		b.Run(kind, func(b *testing.B) {
+			g := NewSQLGenerator(dialect.NewPostgreSQL())
+			dml := benchmarkRow(kind)
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, _, err := g.ToPreparedSQL(dml); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkToBulkRow(b *testing.B) {
+	g := NewSQLGenerator(dialect.NewPostgreSQL())
+	dml := benchmarkRow("insert")
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, _, err := g.ToBulkRow(dml); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkToSQL_WideRow(b *testing.B) {
+	for _, d := range []dialect.Dialect{dialect.NewPostgreSQL(), dialect.NewMySQL()} {
+		b.Run(d.Name(), func(b *testing.B) {
+			g := NewSQLGenerator(d)
+			change := &proto.Change{Data: &proto.Change_Dml{Dml: wideRow("insert")}}
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := g.ToSQL(change); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	"kasho/pkg/types"
+	"kasho/proto"
+	"translicator/internal/chaos"
+	"translicator/internal/metrics"
+)
+
+const (
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 30 * time.Second
+
+	// streamIDHeader names a stream with an in-flight limit in the change stream's response
+	streamIDHeader = "kasho-stream-id"
+)
+
+// Handler processes a single change. Returning an error stops the current stream
+// without advancing the resume position, so the change is redelivered after reconnecting.
+type Handler func(ctx context.Context, change *proto.Change) error
+
+// Config controls reconnect and liveness behavior of a Consumer
+type Config struct {
+	// IdleTimeout reconnects when no change or heartbeat arrives within this duration; zero disables it
+	IdleTimeout time.Duration
+	// InitialBackoff and MaxBackoff bound the exponential backoff between reconnect attempts
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// IncludeTables, ExcludeTables and ExcludeKinds are sent with the stream request so
+	// the change stream only sends the changes this consumer needs
+	IncludeTables []string
+	ExcludeTables []string
+	ExcludeKinds  []string
+	// MaxInFlightBytes asks the change stream to send at most this many bytes ahead of
+	// the changes acknowledged as handled; zero leaves it to the server
+	MaxInFlightBytes uint64
+	// OnCaughtUp is called from the handler goroutine on the first heartbeat after changes were
+	// handled, i.e. once the consumer has caught up with a backlog such as a bootstrap
+	OnCaughtUp func(ctx context.Context)
+	// SpoolDir holds the changes of transactions streamed before they commit; empty uses
+	// the system's temporary directory
+	SpoolDir string
+}
+
+// Consumer reads the change stream and reconnects with backoff when it fails,
+// resuming from the position of the last handled change
+type Consumer struct {
+	client proto.ChangeStreamClient
+	config Config
+
+	position       string
+	sourcePosition string
+	caughtUpAt     atomic.Int64 // unix nanoseconds
+	pause          pauseState
+	spool          *spool
+	sleep          func(ctx context.Context, d time.Duration) error
+	now            func() time.Time
+}
+
+// NewConsumer creates a consumer for the given change stream client
+func NewConsumer(client proto.ChangeStreamClient, config Config) *Consumer {
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = defaultInitialBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultMaxBackoff
+	}
+	c := &Consumer{
+		client: client,
+		config: config,
+		spool:  newSpool(config.SpoolDir),
+		sleep:  sleepContext,
+		now:    time.Now,
+	}
+	c.caughtUpAt.Store(c.now().UnixNano())
+	return c
+}
+
+// Position returns the position of the last handled change
+func (c *Consumer) Position() string {
+	return c.position
+}
+
+// SourcePosition returns the source position reported by the most recent heartbeat
+func (c *Consumer) SourcePosition() string {
+	return c.sourcePosition
+}
+
+// Lag returns how long it has been since the consumer was last caught up with the
+// change stream. The change stream only sends heartbeats once it has nothing left to
+// send, so each heartbeat marks the consumer as caught up.
+func (c *Consumer) Lag() time.Duration {
+	return c.now().Sub(c.CaughtUpAt())
+}
+
+// CaughtUpAt returns when the consumer was last caught up with the change stream
+func (c *Consumer) CaughtUpAt() time.Time {
+	return time.Unix(0, c.caughtUpAt.Load())
+}
+
+// Run consumes the stream until ctx is cancelled. startPosition is consulted for the
+// position to request whenever no change has been handled yet.
+func (c *Consumer) Run(ctx context.Context, startPosition func() string, handle Handler) error {
+	defer c.spool.close()
+	attempt := 0
+	for {
+		if err := c.waitWhilePaused(ctx); err != nil {
+			return err
+		}
+		position := c.position
+		if position == "" {
+			position = startPosition()
+		}
+		log.Printf("Starting stream from position: %s", position)
+
+		received, err := c.consume(ctx, position, handle)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if c.Paused() {
+			continue
+		}
+		if received {
+			attempt = 0
+		}
+
+		delay := backoff(attempt, c.config.InitialBackoff, c.config.MaxBackoff)
+		attempt++
+		metrics.StreamReconnects.Add(1)
+		log.Printf("Stream interrupted: %v; reconnecting in %v (resume position: %q)", err, delay, c.position)
+		sleepCtx, release, ok := c.pause.interruptible(ctx)
+		if !ok {
+			continue
+		}
+		c.sleep(sleepCtx, delay) // cut short by Pause
+		release()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// consume runs a single stream until it fails. It reports whether any message was received.
+func (c *Consumer) consume(ctx context.Context, position string, handle Handler) (bool, error) {
+	streamCtx, release, ok := c.pause.interruptible(ctx)
+	if !ok {
+		return false, errPaused
+	}
+	defer release()
+
+	stream, err := c.client.Stream(streamCtx, &proto.StreamRequest{
+		LastPosition:  position,
+		IncludeTables: c.config.IncludeTables,
+		ExcludeTables: c.config.ExcludeTables,
+		ExcludeKinds:  c.config.ExcludeKinds,
+
+		MaxInFlightBytes: c.config.MaxInFlightBytes,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to start stream: %w", err)
+	}
+
+	var idle atomic.Bool
+	var idleTimer *time.Timer
+	if c.config.IdleTimeout > 0 {
+		idleTimer = time.AfterFunc(c.config.IdleTimeout, func() {
+			idle.Store(true)
+			release()
+		})
+		defer idleTimer.Stop()
+	}
+
+	received := false
+	handledSinceHeartbeat := false
+	var acks *acknowledger
+	for {
+		change, err := stream.Recv()
+		if err != nil {
+			if idle.Load() {
+				return received, fmt.Errorf("no changes or heartbeats received in %v", c.config.IdleTimeout)
+			}
+			return received, err
+		}
+		if !received {
+			// The header arrives with the first message
+			acks = c.startAcknowledger(streamCtx, stream)
+		}
+		received = true
+		if streamCtx.Err() != nil {
+			// Paused or idle; don't handle anything the stream had already buffered
+			return received, streamCtx.Err()
+		}
+		if idleTimer != nil {
+			idleTimer.Reset(c.config.IdleTimeout)
+		}
+		types.NormalizeChange(change)
+
+		if change.ChangeType == proto.ChangeType_CHANGE_TYPE_HEARTBEAT {
+			c.sourcePosition = change.Position
+			c.caughtUpAt.Store(c.now().UnixNano())
+			metrics.StreamHeartbeatsReceived.Add(1)
+			metrics.StreamSourcePosition.Set(change.Position)
+			if handledSinceHeartbeat && c.config.OnCaughtUp != nil {
+				c.config.OnCaughtUp(ctx)
+			}
+			handledSinceHeartbeat = false
+			continue
+		}
+
+		if chaos.DropChange() {
+			// Lost in transit; resuming from the last handled position must recover it
+			return received, fmt.Errorf("change at %s: %w", change.Position, chaos.ErrDropped)
+		}
+		metrics.StreamChangesReceived.Add(1)
+		if message := change.GetMessage(); message != nil {
+			metrics.StreamMessagesReceived.Add(1)
+			log.Printf("Message %q at %s: %q", message.Prefix, change.Position, message.Content)
+		}
+		if err := c.dispatch(ctx, change, handle); err != nil {
+			return received, fmt.Errorf("failed to handle change at %s: %w", change.Position, err)
+		}
+		c.position = change.Position
+		acks.acknowledge(change.Position)
+		handledSinceHeartbeat = true
+		metrics.StreamPosition.Set(change.Position)
+	}
+}
+
+// acknowledger tells the change stream which changes were handled, so a stream with an
+// in-flight limit sends more. It acknowledges in the background so handling doesn't wait
+// for it, and positions handled while an acknowledgement is sent are coalesced into one.
+type acknowledger struct {
+	client   proto.ChangeStreamClient
+	streamID string
+	pending  chan string
+}
+
+// startAcknowledger starts acknowledging changes for a stream until ctx ends. It returns
+// nil, which acknowledges nothing, if the stream has no in-flight limit.
+func (c *Consumer) startAcknowledger(ctx context.Context, stream proto.ChangeStream_StreamClient) *acknowledger {
+	header, err := stream.Header()
+	if err != nil {
+		return nil
+	}
+	ids := header.Get(streamIDHeader)
+	if len(ids) == 0 {
+		return nil
+	}
+	a := &acknowledger{client: c.client, streamID: ids[0], pending: make(chan string, 1)}
+	go a.run(ctx)
+	return a
+}
+
+// acknowledge queues a handled position, replacing one not sent yet
+func (a *acknowledger) acknowledge(position string) {
+	if a == nil {
+		return
+	}
+	select {
+	case <-a.pending:
+	default:
+	}
+	a.pending <- position
+}
+
+func (a *acknowledger) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case position := <-a.pending:
+			_, err := a.client.Acknowledge(ctx, &proto.AcknowledgeRequest{StreamId: a.streamID, Position: position})
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Failed to acknowledge change at %s: %v", position, err)
+			}
+		}
+	}
+}
+
+// dispatch hands a change to the handler. Changes of transactions streamed before they
+// commit are spooled instead, and handed over once their transaction commits.
+func (c *Consumer) dispatch(ctx context.Context, change *proto.Change, handle Handler) error {
+	if change.TransactionStatus == proto.TransactionStatus_TRANSACTION_STATUS_IN_PROGRESS {
+		return c.spool.add(change)
+	}
+	if change.ChangeType != proto.ChangeType_CHANGE_TYPE_TRANSACTION {
+		return handle(ctx, change)
+	}
+	if change.TransactionStatus == proto.TransactionStatus_TRANSACTION_STATUS_ABORTED {
+		c.spool.abort(change)
+		return nil
+	}
+	return c.spool.commit(ctx, change, handle)
+}
+
+// backoff returns the delay before the given reconnect attempt: exponential growth
+// capped at max, with jitter over the upper half of the interval
+func backoff(attempt int, initial, max time.Duration) time.Duration {
+	d := initial
+	for i := 0; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,319 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"kasho/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// fakeStream replays a fixed list of changes and then fails with err
+type fakeStream struct {
+	grpc.ClientStream
+	changes   []*proto.Change
+	err       error
+	header    metadata.MD
+	beforeErr func() // called once the changes have been received, before failing
+}
+
+func (s *fakeStream) Header() (metadata.MD, error) {
+	return s.header, nil
+}
+
+func (s *fakeStream) Recv() (*proto.Change, error) {
+	if len(s.changes) == 0 {
+		if s.beforeErr != nil {
+			s.beforeErr()
+		}
+		return nil, s.err
+	}
+	change := s.changes[0]
+	s.changes = s.changes[1:]
+	return change, nil
+}
+
+// fakeClient hands out one fakeStream per Stream call and records the requests
+type fakeClient struct {
+	proto.ChangeStreamClient
+	streams   []*fakeStream
+	positions []string
+	requests  []*proto.StreamRequest
+	cancel    context.CancelFunc
+
+	mu   sync.Mutex
+	acks []*proto.AcknowledgeRequest
+}
+
+func (c *fakeClient) Acknowledge(ctx context.Context, in *proto.AcknowledgeRequest, opts ...grpc.CallOption) (*proto.AcknowledgeResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.acks = append(c.acks, in)
+	return &proto.AcknowledgeResponse{}, nil
+}
+
+// lastAck returns the most recent acknowledgement, if any
+func (c *fakeClient) lastAck() *proto.AcknowledgeRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.acks) == 0 {
+		return nil
+	}
+	return c.acks[len(c.acks)-1]
+}
+
+func (c *fakeClient) Stream(ctx context.Context, in *proto.StreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[proto.Change], error) {
+	c.positions = append(c.positions, in.LastPosition)
+	c.requests = append(c.requests, in)
+	if len(c.streams) == 0 {
+		c.cancel()
+		return nil, ctx.Err()
+	}
+	stream := c.streams[0]
+	c.streams = c.streams[1:]
+	return stream, nil
+}
+
+func dml(position string) *proto.Change {
+	return &proto.Change{Position: position, Type: "dml", Data: &proto.Change_Dml{Dml: &proto.DMLData{Table: "users", Kind: "insert"}}}
+}
+
+func TestConsumerResumesFromLastHandledPosition(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &fakeClient{
+		cancel: cancel,
+		streams: []*fakeStream{
+			{changes: []*proto.Change{dml("0/100"), dml("0/200")}, err: io.EOF},
+			{changes: []*proto.Change{{Position: "0/900", Type: "heartbeat"}, dml("0/300")}, err: errors.New("connection reset")},
+		},
+	}
+
+	consumer := NewConsumer(client, Config{})
+	var sleeps []time.Duration
+	consumer.sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+
+	var handled []string
+	startCalls := 0
+	err := consumer.Run(ctx, func() string {
+		startCalls++
+		return "bootstrap"
+	}, func(ctx context.Context, change *proto.Change) error {
+		handled = append(handled, change.Position)
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() error = %v, want context.Canceled", err)
+	}
+
+	wantPositions := []string{"bootstrap", "0/200", "0/300"}
+	if len(client.positions) != len(wantPositions) {
+		t.Fatalf("requested positions = %v, want %v", client.positions, wantPositions)
+	}
+	for i, want := range wantPositions {
+		if client.positions[i] != want {
+			t.Errorf("requested position %d = %q, want %q", i, client.positions[i], want)
+		}
+	}
+	if startCalls != 1 {
+		t.Errorf("startPosition called %d times, want 1", startCalls)
+	}
+	if len(handled) != 3 {
+		t.Errorf("handled = %v, want 3 changes (heartbeats are not handled)", handled)
+	}
+	if consumer.SourcePosition() != "0/900" {
+		t.Errorf("SourcePosition() = %q, want 0/900", consumer.SourcePosition())
+	}
+	if len(sleeps) != 2 {
+		t.Errorf("slept %d times, want 2", len(sleeps))
+	}
+}
+
+func TestConsumerDoesNotAdvanceOnHandlerError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &fakeClient{
+		cancel: cancel,
+		streams: []*fakeStream{
+			{changes: []*proto.Change{dml("0/100"), dml("0/200")}, err: io.EOF},
+		},
+	}
+
+	consumer := NewConsumer(client, Config{})
+	consumer.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+
+	consumer.Run(ctx, func() string { return "" }, func(ctx context.Context, change *proto.Change) error {
+		if change.Position == "0/200" {
+			return errors.New("replica unavailable")
+		}
+		return nil
+	})
+
+	if consumer.Position() != "0/100" {
+		t.Errorf("Position() = %q, want 0/100", consumer.Position())
+	}
+	if got := client.positions[len(client.positions)-1]; got != "0/100" {
+		t.Errorf("resumed from %q, want 0/100", got)
+	}
+}
+
+func TestConsumerLag(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &fakeClient{
+		cancel: cancel,
+		streams: []*fakeStream{
+			{changes: []*proto.Change{dml("0/100"), {Position: "0/100", Type: "heartbeat"}, dml("0/200")}, err: io.EOF},
+		},
+	}
+
+	consumer := NewConsumer(client, Config{})
+	consumer.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	start := time.Unix(1000, 0)
+	now := start
+	consumer.now = func() time.Time { return now }
+	consumer.caughtUpAt.Store(start.UnixNano())
+
+	consumer.Run(ctx, func() string { return "" }, func(ctx context.Context, change *proto.Change) error {
+		now = now.Add(time.Minute)
+		return nil
+	})
+
+	// The heartbeat arrived after the first change (one minute in); the second change took another minute
+	if got := consumer.Lag(); got != time.Minute {
+		t.Errorf("Lag() = %v, want 1m0s", got)
+	}
+}
+
+func TestConsumerOnCaughtUp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	heartbeat := func(position string) *proto.Change {
+		return &proto.Change{Position: position, Type: "heartbeat"}
+	}
+	client := &fakeClient{
+		cancel: cancel,
+		streams: []*fakeStream{
+			// Heartbeats while waiting for the bootstrap don't count, nor do repeated idle heartbeats
+			{changes: []*proto.Change{heartbeat("0/0"), dml("0/100"), dml("0/200"), heartbeat("0/200"), heartbeat("0/200"), dml("0/300"), heartbeat("0/300")}, err: io.EOF},
+		},
+	}
+
+	var caughtUpAt []string
+	var consumer *Consumer
+	consumer = NewConsumer(client, Config{OnCaughtUp: func(ctx context.Context) {
+		caughtUpAt = append(caughtUpAt, consumer.Position())
+	}})
+	consumer.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+
+	consumer.Run(ctx, func() string { return "bootstrap" }, func(ctx context.Context, change *proto.Change) error {
+		return nil
+	})
+
+	if len(caughtUpAt) != 2 || caughtUpAt[0] != "0/200" || caughtUpAt[1] != "0/300" {
+		t.Errorf("OnCaughtUp called at %v, want [0/200 0/300]", caughtUpAt)
+	}
+}
+
+func TestConsumerSendsFilters(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &fakeClient{cancel: cancel}
+	consumer := NewConsumer(client, Config{
+		IncludeTables: []string{"public.*"},
+		ExcludeTables: []string{"public.audit_log"},
+		ExcludeKinds:  []string{"delete"},
+	})
+	consumer.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	consumer.Run(ctx, func() string { return "" }, func(ctx context.Context, change *proto.Change) error { return nil })
+
+	if len(client.requests) != 1 {
+		t.Fatalf("got %d stream requests, want 1", len(client.requests))
+	}
+	req := client.requests[0]
+	if !slices.Equal(req.IncludeTables, []string{"public.*"}) ||
+		!slices.Equal(req.ExcludeTables, []string{"public.audit_log"}) ||
+		!slices.Equal(req.ExcludeKinds, []string{"delete"}) {
+		t.Errorf("stream request filters = %v / %v / %v", req.IncludeTables, req.ExcludeTables, req.ExcludeKinds)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	initial := time.Second
+	max := 30 * time.Second
+
+	tests := []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{3, 8 * time.Second},
+		{10, max},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			d := backoff(tt.attempt, initial, max)
+			if d < tt.ceiling/2 || d > tt.ceiling {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", tt.attempt, d, tt.ceiling/2, tt.ceiling)
+			}
+		}
+	}
+}
+
+func TestConsumerAcknowledgesHandledChanges(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &fakeClient{cancel: cancel}
+	limited := &fakeStream{
+		changes: []*proto.Change{dml("0/100"), {Position: "0/900", Type: "heartbeat"}, dml("0/200")},
+		err:     io.EOF,
+		header:  metadata.Pairs(streamIDHeader, "stream-1"),
+		beforeErr: func() {
+			// Acknowledgements are sent in the background
+			deadline := time.Now().Add(time.Second)
+			for time.Now().Before(deadline) {
+				if ack := client.lastAck(); ack != nil && ack.Position == "0/200" {
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+		},
+	}
+	unlimited := &fakeStream{changes: []*proto.Change{dml("0/300")}, err: io.EOF}
+	client.streams = []*fakeStream{limited, unlimited}
+
+	consumer := NewConsumer(client, Config{MaxInFlightBytes: 4096})
+	consumer.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	consumer.Run(ctx, func() string { return "" }, func(ctx context.Context, change *proto.Change) error { return nil })
+
+	if got := client.requests[0].MaxInFlightBytes; got != 4096 {
+		t.Errorf("requested MaxInFlightBytes = %d, want 4096", got)
+	}
+	ack := client.lastAck()
+	if ack == nil || ack.StreamId != "stream-1" || ack.Position != "0/200" {
+		t.Fatalf("last acknowledgement = %v, want 0/200 on stream-1", ack)
+	}
+	for _, ack := range client.acks {
+		if ack.Position == "0/900" || ack.Position == "0/300" {
+			t.Errorf("acknowledged %s, want only changes of the limited stream", ack.Position)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+)
+
+// errPaused stops a stream that was started while the consumer is being paused
+var errPaused = errors.New("consumer paused")
+
+// pauseState lets an operator stop the consumer between changes and resume it later,
+// optionally from a different position
+type pauseState struct {
+	mu       sync.Mutex
+	paused   bool
+	resumed  chan struct{}      // closed by Resume
+	stopped  chan struct{}      // closed once a paused consumer has stopped its stream
+	position string             // position to resume from; empty keeps the current one
+	cancel   context.CancelFunc // interrupts the active stream or reconnect delay
+	cancelID uint64             // identifies cancel so a stale release doesn't clear a newer one
+}
+
+// interruptible derives a context that Pause cancels. It reports false if the consumer
+// is already paused. The returned release function must be called when done.
+func (p *pauseState) interruptible(ctx context.Context) (context.Context, func(), bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.paused {
+		return nil, nil, false
+	}
+	ictx, cancel := context.WithCancel(ctx)
+	p.cancelID++
+	id := p.cancelID
+	p.cancel = cancel
+	return ictx, func() {
+		p.mu.Lock()
+		if p.cancelID == id {
+			p.cancel = nil
+		}
+		p.mu.Unlock()
+		cancel()
+	}, true
+}
+
+// Pause stops consuming after the change being handled, if any, and returns once the
+// stream has stopped. Changes are not acknowledged while paused, so nothing is lost.
+func (c *Consumer) Pause(ctx context.Context) error {
+	stopped := c.pause.request()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RequestPause pauses the consumer like Pause but returns at once. A handler uses it to
+// pause before the change it is handling: when it then returns an error, the change isn't
+// acknowledged and is handled again once the consumer is resumed.
+func (c *Consumer) RequestPause() {
+	c.pause.request()
+}
+
+// request pauses the consumer and returns a channel closed once its stream has stopped
+func (p *pauseState) request() <-chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.paused {
+		p.paused = true
+		p.resumed = make(chan struct{})
+		p.stopped = make(chan struct{})
+		p.position = ""
+		if p.cancel != nil {
+			p.cancel()
+		}
+	}
+	return p.stopped
+}
+
+// Resume continues a paused consumer. A non-empty position restarts the stream from
+// there, e.g. "bootstrap" to replay the whole buffer into a rebuilt replica.
+func (c *Consumer) Resume(position string) {
+	p := &c.pause
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.paused {
+		return
+	}
+	p.paused = false
+	p.position = position
+	close(p.resumed)
+}
+
+// Paused reports whether the consumer has been paused
+func (c *Consumer) Paused() bool {
+	c.pause.mu.Lock()
+	defer c.pause.mu.Unlock()
+	return c.pause.paused
+}
+
+// waitWhilePaused blocks while the consumer is paused and applies the resume position
+func (c *Consumer) waitWhilePaused(ctx context.Context) error {
+	p := &c.pause
+	p.mu.Lock()
+	if !p.paused {
+		p.mu.Unlock()
+		return nil
+	}
+	select {
+	case <-p.stopped:
+	default:
+		close(p.stopped)
+		log.Printf("Consumer paused at position %q", c.position)
+	}
+	resumed := p.resumed
+	p.mu.Unlock()
+
+	select {
+	case <-resumed:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	p.mu.Lock()
+	if p.position != "" {
+		c.position = p.position
+		p.position = ""
+	}
+	p.mu.Unlock()
+	log.Printf("Consumer resumed at position %q", c.position)
+	return nil
+}
@@ -0,0 +1,132 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"kasho/proto"
+)
+
+func TestConsumerPauseAndResume(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &fakeClient{
+		cancel: cancel,
+		streams: []*fakeStream{
+			{changes: []*proto.Change{dml("0/100"), dml("0/200"), dml("0/300")}, err: io.EOF},
+			{changes: []*proto.Change{dml("0/900")}, err: io.EOF},
+		},
+	}
+
+	consumer := NewConsumer(client, Config{})
+	consumer.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+
+	paused := make(chan error, 1)
+	var handled []string
+	consumer.Run(ctx, func() string { return "0/0" }, func(ctx context.Context, change *proto.Change) error {
+		handled = append(handled, change.Position)
+		if change.Position == "0/100" {
+			// Pause from another goroutine, as the admin endpoint does, and resume
+			// from the start of the buffer once the consumer has stopped
+			go func() {
+				err := consumer.Pause(ctx)
+				consumer.Resume("bootstrap")
+				paused <- err
+			}()
+			for !consumer.Paused() {
+				time.Sleep(time.Millisecond)
+			}
+		}
+		return nil
+	})
+
+	if err := <-paused; err != nil {
+		t.Fatalf("Pause() unexpected error: %v", err)
+	}
+	if want := []string{"0/100", "0/900"}; !slices.Equal(handled, want) {
+		t.Errorf("handled = %v, want %v (nothing after the pause point)", handled, want)
+	}
+	if want := []string{"0/0", "bootstrap", "0/900"}; !slices.Equal(client.positions, want) {
+		t.Errorf("requested positions = %v, want %v", client.positions, want)
+	}
+}
+
+func TestConsumerPause_WhileWaitingToReconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &fakeClient{
+		cancel:  cancel,
+		streams: []*fakeStream{{err: io.EOF}},
+	}
+	consumer := NewConsumer(client, Config{})
+
+	sleeping := make(chan struct{})
+	consumer.sleep = func(ctx context.Context, d time.Duration) error {
+		close(sleeping)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		consumer.Run(ctx, func() string { return "0/0" }, func(ctx context.Context, change *proto.Change) error { return nil })
+		close(done)
+	}()
+
+	<-sleeping
+	pauseCtx, pauseCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer pauseCancel()
+	if err := consumer.Pause(pauseCtx); err != nil {
+		t.Fatalf("Pause() should interrupt the reconnect delay: %v", err)
+	}
+	consumer.Resume("")
+	<-done
+}
+
+func TestConsumerRequestPause_FromHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &fakeClient{
+		cancel: cancel,
+		streams: []*fakeStream{
+			{changes: []*proto.Change{dml("0/100"), dml("0/200"), dml("0/300")}, err: io.EOF},
+			{changes: []*proto.Change{dml("0/200"), dml("0/300")}, err: io.EOF},
+		},
+	}
+
+	consumer := NewConsumer(client, Config{})
+	consumer.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+
+	held := false
+	var handled []string
+	consumer.Run(ctx, func() string { return "0/0" }, func(ctx context.Context, change *proto.Change) error {
+		if change.Position == "0/200" && !held {
+			// Hold the change until it is approved
+			held = true
+			consumer.RequestPause()
+			go func() {
+				for !consumer.Paused() {
+					time.Sleep(time.Millisecond)
+				}
+				consumer.Resume("")
+			}()
+			return errors.New("held for approval")
+		}
+		handled = append(handled, change.Position)
+		return nil
+	})
+
+	if want := []string{"0/100", "0/200", "0/300"}; !slices.Equal(handled, want) {
+		t.Errorf("handled = %v, want %v (the held change handled again after resuming)", handled, want)
+	}
+	if want := []string{"0/0", "0/100", "0/300"}; !slices.Equal(client.positions, want) {
+		t.Errorf("requested positions = %v, want %v", client.positions, want)
+	}
+}
@@ -0,0 +1,149 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"kasho/proto"
+	"translicator/internal/metrics"
+
+	gproto "google.golang.org/protobuf/proto"
+)
+
+// spool holds the changes of large transactions that the source streams before they
+// commit, in a file per transaction, until the transaction ends. Committed changes are
+// then handed to the handler in order; aborted ones are deleted.
+type spool struct {
+	dir  string
+	txns map[string]*spooledTxn
+}
+
+type spooledTxn struct {
+	file    *os.File
+	writer  *bufio.Writer
+	aborted map[string]bool // subtransactions rolled back
+	// handed counts the changes an interrupted replay has handed to the handler already
+	handed int
+}
+
+func newSpool(dir string) *spool {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return &spool{dir: dir, txns: make(map[string]*spooledTxn)}
+}
+
+// add appends an in-progress change to its transaction's file
+func (s *spool) add(change *proto.Change) error {
+	txn, ok := s.txns[change.TransactionId]
+	if !ok {
+		file, err := os.CreateTemp(s.dir, "kasho-txn-*.spool")
+		if err != nil {
+			return fmt.Errorf("failed to create spool file: %w", err)
+		}
+		txn = &spooledTxn{file: file, writer: bufio.NewWriter(file), aborted: make(map[string]bool)}
+		s.txns[change.TransactionId] = txn
+		log.Printf("Spooling streamed transaction %s to %s", change.TransactionId, file.Name())
+	}
+
+	data, err := gproto.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("failed to encode change: %w", err)
+	}
+	if _, err := txn.writer.Write(binary.AppendUvarint(nil, uint64(len(data)))); err != nil {
+		return fmt.Errorf("failed to spool change: %w", err)
+	}
+	if _, err := txn.writer.Write(data); err != nil {
+		return fmt.Errorf("failed to spool change: %w", err)
+	}
+	metrics.StreamSpooledChanges.Add(1)
+	return nil
+}
+
+// abort discards a transaction's changes, or only those of a subtransaction
+func (s *spool) abort(change *proto.Change) {
+	txn, ok := s.txns[change.TransactionId]
+	if !ok {
+		return
+	}
+	if change.SubtransactionId != "" {
+		txn.aborted[change.SubtransactionId] = true
+		return
+	}
+	log.Printf("Streamed transaction %s aborted, discarding its changes", change.TransactionId)
+	s.remove(change.TransactionId)
+}
+
+// commit hands a transaction's changes to handle in order, marked as committed. If
+// handle fails, a later commit of the same transaction continues after the changes
+// that were handled.
+func (s *spool) commit(ctx context.Context, marker *proto.Change, handle Handler) error {
+	txn, ok := s.txns[marker.TransactionId]
+	if !ok {
+		return nil
+	}
+	if err := txn.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to spool change: %w", err)
+	}
+	if _, err := txn.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read spool file: %w", err)
+	}
+
+	reader := bufio.NewReader(txn.file)
+	handed := 0
+	for {
+		size, err := binary.ReadUvarint(reader)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read spool file: %w", err)
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return fmt.Errorf("failed to read spool file: %w", err)
+		}
+
+		change := &proto.Change{}
+		if err := gproto.Unmarshal(data, change); err != nil {
+			return fmt.Errorf("failed to decode spooled change: %w", err)
+		}
+		if txn.aborted[change.SubtransactionId] {
+			continue
+		}
+		if handed++; handed <= txn.handed {
+			continue
+		}
+
+		change.TransactionStatus = proto.TransactionStatus_TRANSACTION_STATUS_COMMITTED
+		change.CommitTimestamp = marker.CommitTimestamp
+		if err := handle(ctx, change); err != nil {
+			return err
+		}
+		txn.handed = handed
+	}
+
+	log.Printf("Streamed transaction %s committed, %d changes handled", marker.TransactionId, handed)
+	s.remove(marker.TransactionId)
+	return nil
+}
+
+func (s *spool) remove(id string) {
+	txn := s.txns[id]
+	txn.file.Close()
+	os.Remove(txn.file.Name())
+	delete(s.txns, id)
+}
+
+// close deletes the files of all transactions that haven't ended
+func (s *spool) close() {
+	for id := range s.txns {
+		s.remove(id)
+	}
+}
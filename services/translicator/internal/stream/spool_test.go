@@ -0,0 +1,113 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"kasho/proto"
+)
+
+func streamed(position, xid, subxid string) *proto.Change {
+	change := dml(position)
+	change.TransactionId = xid
+	change.SubtransactionId = subxid
+	change.TransactionStatus = proto.TransactionStatus_TRANSACTION_STATUS_IN_PROGRESS
+	return change
+}
+
+func transactionEnd(position, xid, subxid string, status proto.TransactionStatus) *proto.Change {
+	return &proto.Change{
+		Position:          position,
+		Type:              "transaction",
+		TransactionId:     xid,
+		SubtransactionId:  subxid,
+		TransactionStatus: status,
+		CommitTimestamp:   "2024-03-20T15:00:00Z",
+	}
+}
+
+func TestConsumerSpoolsStreamedTransactions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir := t.TempDir()
+	client := &fakeClient{
+		cancel: cancel,
+		streams: []*fakeStream{{
+			changes: []*proto.Change{
+				streamed("0/100", "900", ""),
+				streamed("0/110", "900", "901"),
+				streamed("0/120", "902", ""),
+				dml("0/130"),
+				transactionEnd("0/140", "900", "901", proto.TransactionStatus_TRANSACTION_STATUS_ABORTED),
+				streamed("0/150", "900", ""),
+				transactionEnd("0/160", "902", "", proto.TransactionStatus_TRANSACTION_STATUS_ABORTED),
+				transactionEnd("0/170", "900", "", proto.TransactionStatus_TRANSACTION_STATUS_COMMITTED),
+			},
+			err: io.EOF,
+		}},
+	}
+
+	consumer := NewConsumer(client, Config{SpoolDir: dir})
+	consumer.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+
+	var handled []string
+	consumer.Run(ctx, func() string { return "" }, func(ctx context.Context, change *proto.Change) error {
+		if change.TransactionId != "" && (change.TransactionStatus != proto.TransactionStatus_TRANSACTION_STATUS_COMMITTED || change.CommitTimestamp == "") {
+			t.Errorf("change at %s handed over as %v, want committed with a commit time", change.Position, change.TransactionStatus)
+		}
+		handled = append(handled, change.Position)
+		return nil
+	})
+
+	// Transaction 902 and subtransaction 901 aborted; 900 is handled when it commits
+	want := []string{"0/130", "0/100", "0/150"}
+	if !slices.Equal(handled, want) {
+		t.Errorf("handled = %v, want %v", handled, want)
+	}
+	if consumer.Position() != "0/170" {
+		t.Errorf("Position() = %q, want 0/170", consumer.Position())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("spool directory holds %d files after the transactions ended, want none", len(entries))
+	}
+}
+
+func TestSpoolCommitResumesAfterHandlerError(t *testing.T) {
+	s := newSpool(t.TempDir())
+	defer s.close()
+	for _, position := range []string{"0/100", "0/110", "0/120"} {
+		if err := s.add(streamed(position, "900", "")); err != nil {
+			t.Fatalf("add() error = %v", err)
+		}
+	}
+
+	var handled []string
+	fail := true
+	handle := func(ctx context.Context, change *proto.Change) error {
+		if change.Position == "0/110" && fail {
+			fail = false
+			return errors.New("replica unavailable")
+		}
+		handled = append(handled, change.Position)
+		return nil
+	}
+
+	commit := transactionEnd("0/130", "900", "", proto.TransactionStatus_TRANSACTION_STATUS_COMMITTED)
+	if err := s.commit(context.Background(), commit, handle); err == nil {
+		t.Fatal("commit() expected the handler's error")
+	}
+	if err := s.commit(context.Background(), commit, handle); err != nil {
+		t.Fatalf("commit() error = %v", err)
+	}
+
+	want := []string{"0/100", "0/110", "0/120"}
+	if !slices.Equal(handled, want) {
+		t.Errorf("handled = %v, want %v", handled, want)
+	}
+}
@@ -0,0 +1,124 @@
+package validate
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"kasho/proto"
+)
+
+// RuleMaxValueSize is violated by values larger than the maximum value size
+const RuleMaxValueSize = "max_value_size"
+
+// SizePolicy decides what happens to a change with a value larger than the maximum
+// value size
+type SizePolicy string
+
+const (
+	// RejectOversized skips the change and records it in the dead-letter file
+	RejectOversized SizePolicy = "reject"
+	// TruncateOversized cuts text and binary values down to the maximum size and applies
+	// the change. Keys and values that would be invalid when cut, such as JSON, are
+	// rejected instead.
+	TruncateOversized SizePolicy = "truncate"
+)
+
+// ParseSizePolicy validates a value size policy name; empty means reject
+func ParseSizePolicy(name string) (SizePolicy, error) {
+	switch policy := SizePolicy(name); policy {
+	case "":
+		return RejectOversized, nil
+	case RejectOversized, TruncateOversized:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown value size policy %q (expected reject or truncate)", name)
+	}
+}
+
+// SizeLimit bounds the size of the values of a change before it is transformed and
+// written as SQL, where each copy of a value of several megabytes adds to memory use
+type SizeLimit struct {
+	// MaxBytes is the largest value size in bytes; 0 means unlimited
+	MaxBytes int
+	Policy   SizePolicy
+}
+
+// Enabled reports whether values are limited
+func (l SizeLimit) Enabled() bool {
+	return l.MaxBytes > 0
+}
+
+// Check reports the values of a change larger than the limit. With the truncate policy,
+// text and binary column values are truncated in place and reported as fixed.
+func (l SizeLimit) Check(dml *proto.DMLData) []Violation {
+	if !l.Enabled() || dml == nil {
+		return nil
+	}
+
+	var violations []Violation
+	for i, value := range dml.ColumnValues {
+		size := valueSize(value)
+		if size <= l.MaxBytes {
+			continue
+		}
+		column := ""
+		if i < len(dml.ColumnNames) {
+			column = dml.ColumnNames[i]
+		}
+		detail := fmt.Sprintf("%d bytes exceeds %d", size, l.MaxBytes)
+		// Truncating a key would make the change target another row
+		if l.Policy == TruncateOversized && !slices.Contains(dml.PrimaryKey, column) {
+			if truncated, ok := truncateBytes(value, l.MaxBytes); ok {
+				dml.ColumnValues[i] = truncated
+				violations = append(violations, Violation{Table: dml.Table, Column: column, Rule: RuleMaxValueSize, Detail: detail + ", truncated", Fixed: true})
+				continue
+			}
+		}
+		violations = append(violations, Violation{Table: dml.Table, Column: column, Rule: RuleMaxValueSize, Detail: detail})
+	}
+
+	if dml.OldKeys != nil {
+		for i, value := range dml.OldKeys.KeyValues {
+			if size := valueSize(value); size > l.MaxBytes && i < len(dml.OldKeys.KeyNames) {
+				violations = append(violations, Violation{Table: dml.Table, Column: dml.OldKeys.KeyNames[i], Rule: RuleMaxValueSize, Detail: fmt.Sprintf("key of %d bytes exceeds %d", size, l.MaxBytes)})
+			}
+		}
+	}
+	return violations
+}
+
+// valueSize returns the size in bytes of the variable-length values
+func valueSize(value *proto.ColumnValue) int {
+	switch v := value.GetValue().(type) {
+	case *proto.ColumnValue_StringValue:
+		return len(v.StringValue)
+	case *proto.ColumnValue_BytesValue:
+		return len(v.BytesValue)
+	case *proto.ColumnValue_JsonValue:
+		return len(v.JsonValue)
+	case *proto.ColumnValue_GeometryValue:
+		return len(v.GeometryValue.GetWkt())
+	default:
+		return 0
+	}
+}
+
+// truncateBytes cuts a text value down to at most size bytes on a character boundary, or
+// a binary value to size bytes. The result is a copy, so the oversized value can be freed.
+func truncateBytes(value *proto.ColumnValue, size int) (*proto.ColumnValue, bool) {
+	switch v := value.Value.(type) {
+	case *proto.ColumnValue_StringValue:
+		cut := size
+		for cut > 0 && !utf8.RuneStart(v.StringValue[cut]) {
+			cut--
+		}
+		return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: strings.Clone(v.StringValue[:cut])}}, true
+	case *proto.ColumnValue_BytesValue:
+		return &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: bytes.Clone(v.BytesValue[:size])}}, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,90 @@
+package validate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"kasho/proto"
+)
+
+func TestParseSizePolicy(t *testing.T) {
+	for name, want := range map[string]SizePolicy{"": RejectOversized, "reject": RejectOversized, "truncate": TruncateOversized} {
+		if got, err := ParseSizePolicy(name); err != nil || got != want {
+			t.Errorf("ParseSizePolicy(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseSizePolicy("drop"); err == nil {
+		t.Error("ParseSizePolicy(drop) should fail")
+	}
+}
+
+func TestSizeLimitRejects(t *testing.T) {
+	limit := SizeLimit{MaxBytes: 8, Policy: RejectOversized}
+	dml := &proto.DMLData{
+		Table:       "public.documents",
+		Kind:        "insert",
+		ColumnNames: []string{"id", "title", "body", "metadata"},
+		ColumnValues: []*proto.ColumnValue{
+			{Value: &proto.ColumnValue_IntValue{IntValue: 1234567890}},
+			str("Short"),
+			str("A body longer than eight bytes"),
+			{Value: &proto.ColumnValue_JsonValue{JsonValue: `{"a": 1}`}},
+		},
+	}
+
+	got := limit.Check(dml)
+	if !reflect.DeepEqual(rules(got), []string{"body:max_value_size"}) || !got[0].Blocking() {
+		t.Fatalf("Check() = %v, want a blocking violation of body", got)
+	}
+	if dml.ColumnValues[2].GetStringValue() != "A body longer than eight bytes" {
+		t.Error("value was changed by the reject policy")
+	}
+
+	if got := (SizeLimit{}).Check(dml); got != nil {
+		t.Errorf("Check() without a limit = %v, want none", got)
+	}
+}
+
+func TestSizeLimitTruncates(t *testing.T) {
+	limit := SizeLimit{MaxBytes: 6, Policy: TruncateOversized}
+	dml := &proto.DMLData{
+		Table:       "public.documents",
+		Kind:        "update",
+		ColumnNames: []string{"code", "body", "attachment", "metadata"},
+		ColumnValues: []*proto.ColumnValue{
+			str("ABCDEFGH"),
+			str("Zo Zoë!"), // the ë at bytes 5-6 is cut whole
+			{Value: &proto.ColumnValue_BytesValue{BytesValue: []byte(strings.Repeat("x", 10))}},
+			{Value: &proto.ColumnValue_JsonValue{JsonValue: `{"key": "value"}`}},
+		},
+		PrimaryKey: []string{"code"},
+		OldKeys: &proto.OldKeys{
+			KeyNames:  []string{"code"},
+			KeyValues: []*proto.ColumnValue{str("ABCDEFGH")},
+		},
+	}
+
+	got := limit.Check(dml)
+	want := []string{"code:max_value_size", "body:max_value_size", "attachment:max_value_size", "metadata:max_value_size", "code:max_value_size"}
+	if !reflect.DeepEqual(rules(got), want) {
+		t.Fatalf("Check() = %v, want %v", got, want)
+	}
+
+	// Keys and JSON can't be cut; text and binary values are fixed
+	blocking := []bool{true, false, false, true, true}
+	for i, violation := range got {
+		if violation.Blocking() != blocking[i] {
+			t.Errorf("%v: Blocking() = %v, want %v", violation, violation.Blocking(), blocking[i])
+		}
+	}
+	if body := dml.ColumnValues[1].GetStringValue(); body != "Zo Zo" {
+		t.Errorf("body = %q, want %q", body, "Zo Zo")
+	}
+	if attachment := dml.ColumnValues[2].GetBytesValue(); string(attachment) != "xxxxxx" {
+		t.Errorf("attachment = %q, want %q", attachment, "xxxxxx")
+	}
+	if code := dml.ColumnValues[0].GetStringValue(); code != "ABCDEFGH" {
+		t.Errorf("key column was truncated to %q", code)
+	}
+}
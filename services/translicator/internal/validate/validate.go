@@ -0,0 +1,217 @@
+package validate
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+
+	"kasho/pkg/dialect"
+	"kasho/pkg/transform"
+	"kasho/proto"
+)
+
+// maxTrackedValues bounds the values remembered per unique column
+const maxTrackedValues = 100000
+
+// Rules a value can violate
+const (
+	RuleMaxLength     = "max_length"
+	RuleNotNull       = "not_null"
+	RuleAllowedValues = "allowed_values"
+	RuleUnique        = "unique"
+)
+
+// Violation is a transformed value the replica would reject, or a uniqueness hint
+type Violation struct {
+	Table  string
+	Column string
+	Rule   string
+	Detail string
+	// Fixed is set when the value was truncated to fit its column
+	Fixed bool
+}
+
+// Blocking reports whether the change can't be applied. Fixed values and uniqueness
+// hints are reported without blocking the change.
+func (v Violation) Blocking() bool {
+	return !v.Fixed && v.Rule != RuleUnique
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s.%s: %s: %s", v.Table, v.Column, v.Rule, v.Detail)
+}
+
+// Validator checks transformed changes against the replica's column definitions and
+// the validation section of transforms.yml
+type Validator struct {
+	config transform.ValidationConfig
+
+	mu      sync.RWMutex
+	columns map[string]map[string]dialect.ColumnConstraint
+
+	seenMu sync.Mutex
+	seen   map[string]map[string]string // table.column -> transformed value -> source value
+}
+
+// NewValidator creates a validator
+func NewValidator(config transform.ValidationConfig) *Validator {
+	return &Validator{config: config, seen: make(map[string]map[string]string)}
+}
+
+// Enabled reports whether any check is configured
+func (v *Validator) Enabled() bool {
+	return v.config.Enabled || len(v.config.AllowedValues) > 0 || len(v.config.Unique) > 0
+}
+
+// SetColumns replaces the replica column definitions used for length and NOT NULL checks
+func (v *Validator) SetColumns(columns map[string]map[string]dialect.ColumnConstraint) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.columns = columns
+}
+
+// Check validates the values of a transformed insert or update. source is the change
+// before transforms, with columns in the same order; it is used to tell apart values
+// that collide in a unique column. Values too long for their column are truncated in
+// place when truncation is enabled.
+func (v *Validator) Check(source, transformed *proto.DMLData) []Violation {
+	if transformed == nil || (transformed.Kind != "insert" && transformed.Kind != "update") {
+		return nil
+	}
+
+	v.mu.RLock()
+	columns := v.columns[transformed.Table]
+	v.mu.RUnlock()
+	allowed := v.config.AllowedValues[transformed.Table]
+
+	var violations []Violation
+	for i, column := range transformed.ColumnNames {
+		if i >= len(transformed.ColumnValues) {
+			break
+		}
+		value := transformed.ColumnValues[i]
+		if value.GetUnchangedToast() {
+			continue
+		}
+		violation := func(rule, detail string) {
+			violations = append(violations, Violation{Table: transformed.Table, Column: column, Rule: rule, Detail: detail})
+		}
+
+		if v.config.Enabled {
+			if constraint, ok := columns[column]; ok {
+				if value.GetValue() == nil && !constraint.Nullable {
+					violation(RuleNotNull, "NULL in a NOT NULL column")
+				}
+				if length := valueLength(value); constraint.MaxLength > 0 && length > constraint.MaxLength {
+					detail := fmt.Sprintf("length %d exceeds %d", length, constraint.MaxLength)
+					if v.config.Truncate {
+						transformed.ColumnValues[i] = truncate(value, constraint.MaxLength)
+						violations = append(violations, Violation{Table: transformed.Table, Column: column, Rule: RuleMaxLength, Detail: detail + ", truncated", Fixed: true})
+					} else {
+						violation(RuleMaxLength, detail)
+					}
+				}
+			}
+		}
+
+		if values, ok := allowed[column]; ok && value.GetValue() != nil {
+			text, _ := valueText(value)
+			if !slices.Contains(values, text) {
+				violation(RuleAllowedValues, fmt.Sprintf("%q is not one of %v", text, values))
+			}
+		}
+	}
+
+	violations = append(violations, v.checkUnique(source, transformed)...)
+	return violations
+}
+
+// checkUnique reports transformed values of unique columns that were already produced
+// from a different source value
+func (v *Validator) checkUnique(source, transformed *proto.DMLData) []Violation {
+	uniqueColumns := v.config.Unique[transformed.Table]
+	if len(uniqueColumns) == 0 || source == nil {
+		return nil
+	}
+
+	v.seenMu.Lock()
+	defer v.seenMu.Unlock()
+
+	var violations []Violation
+	for i, column := range transformed.ColumnNames {
+		if !slices.Contains(uniqueColumns, column) || i >= len(transformed.ColumnValues) || i >= len(source.ColumnValues) {
+			continue
+		}
+		value, ok := valueText(transformed.ColumnValues[i])
+		if !ok {
+			continue
+		}
+		sourceValue, _ := valueText(source.ColumnValues[i])
+
+		key := transformed.Table + "." + column
+		seen := v.seen[key]
+		if seen == nil {
+			seen = make(map[string]string)
+			v.seen[key] = seen
+		}
+		if previous, exists := seen[value]; exists {
+			if previous != sourceValue {
+				violations = append(violations, Violation{
+					Table:  transformed.Table,
+					Column: column,
+					Rule:   RuleUnique,
+					Detail: fmt.Sprintf("different source values transform to %q", value),
+				})
+			}
+		} else if len(seen) < maxTrackedValues {
+			seen[value] = sourceValue
+		}
+	}
+	return violations
+}
+
+// valueLength returns the length of character and binary values, in characters and bytes
+func valueLength(value *proto.ColumnValue) int {
+	switch v := value.Value.(type) {
+	case *proto.ColumnValue_StringValue:
+		return utf8.RuneCountInString(v.StringValue)
+	case *proto.ColumnValue_BytesValue:
+		return len(v.BytesValue)
+	default:
+		return 0
+	}
+}
+
+// truncate shortens a character or binary value to length
+func truncate(value *proto.ColumnValue, length int) *proto.ColumnValue {
+	switch v := value.Value.(type) {
+	case *proto.ColumnValue_StringValue:
+		return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: string([]rune(v.StringValue)[:length])}}
+	case *proto.ColumnValue_BytesValue:
+		return &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: v.BytesValue[:length]}}
+	default:
+		return value
+	}
+}
+
+// valueText returns scalar values as text, and false for NULL and other types
+func valueText(value *proto.ColumnValue) (string, bool) {
+	switch v := value.GetValue().(type) {
+	case *proto.ColumnValue_StringValue:
+		return v.StringValue, true
+	case *proto.ColumnValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10), true
+	case *proto.ColumnValue_FloatValue:
+		return strconv.FormatFloat(v.FloatValue, 'g', -1, 64), true
+	case *proto.ColumnValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue), true
+	case *proto.ColumnValue_UuidValue:
+		return v.UuidValue, true
+	case *proto.ColumnValue_TimestampValue:
+		return v.TimestampValue, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,126 @@
+package validate
+
+import (
+	"reflect"
+	"testing"
+
+	"kasho/pkg/dialect"
+	"kasho/pkg/transform"
+	"kasho/proto"
+)
+
+func str(s string) *proto.ColumnValue {
+	return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: s}}
+}
+
+func rules(violations []Violation) []string {
+	var result []string
+	for _, v := range violations {
+		result = append(result, v.Column+":"+v.Rule)
+	}
+	return result
+}
+
+func TestCheck(t *testing.T) {
+	v := NewValidator(transform.ValidationConfig{
+		Enabled:       true,
+		AllowedValues: map[string]map[string][]string{"public.users": {"status": {"active", "inactive"}}},
+	})
+	v.SetColumns(map[string]map[string]dialect.ColumnConstraint{
+		"public.users": {
+			"name":   {MaxLength: 5, Nullable: true},
+			"email":  {MaxLength: 50, Nullable: false},
+			"status": {Nullable: true},
+		},
+	})
+
+	dml := &proto.DMLData{
+		Table:        "public.users",
+		Kind:         "insert",
+		ColumnNames:  []string{"name", "email", "status"},
+		ColumnValues: []*proto.ColumnValue{str("Alexandra"), {}, str("banned")},
+	}
+	got := v.Check(nil, dml)
+	want := []string{"name:max_length", "email:not_null", "status:allowed_values"}
+	if !reflect.DeepEqual(rules(got), want) {
+		t.Fatalf("Check() = %v, want %v", got, want)
+	}
+	for _, violation := range got {
+		if !violation.Blocking() {
+			t.Errorf("%v should block the change", violation)
+		}
+	}
+	if dml.ColumnValues[0].GetStringValue() != "Alexandra" {
+		t.Error("value was changed without truncation enabled")
+	}
+
+	// Deletes carry old values only and aren't checked
+	dml.Kind = "delete"
+	if got := v.Check(nil, dml); len(got) != 0 {
+		t.Errorf("Check() of a delete = %v, want none", got)
+	}
+}
+
+func TestCheckTruncates(t *testing.T) {
+	v := NewValidator(transform.ValidationConfig{Enabled: true, Truncate: true})
+	v.SetColumns(map[string]map[string]dialect.ColumnConstraint{
+		"users": {"name": {MaxLength: 4, Nullable: true}, "avatar": {MaxLength: 2, Nullable: true}},
+	})
+
+	dml := &proto.DMLData{
+		Table:       "users",
+		Kind:        "update",
+		ColumnNames: []string{"name", "avatar"},
+		ColumnValues: []*proto.ColumnValue{
+			str("Zoë Smith"),
+			{Value: &proto.ColumnValue_BytesValue{BytesValue: []byte{1, 2, 3}}},
+		},
+	}
+	got := v.Check(nil, dml)
+	if len(got) != 2 || got[0].Blocking() || got[1].Blocking() {
+		t.Fatalf("Check() = %v, want two fixed violations", got)
+	}
+	if name := dml.ColumnValues[0].GetStringValue(); name != "Zoë " {
+		t.Errorf("name = %q, want %q", name, "Zoë ")
+	}
+	if avatar := dml.ColumnValues[1].GetBytesValue(); !reflect.DeepEqual(avatar, []byte{1, 2}) {
+		t.Errorf("avatar = %v, want [1 2]", avatar)
+	}
+}
+
+func TestCheckUnique(t *testing.T) {
+	v := NewValidator(transform.ValidationConfig{Unique: map[string][]string{"users": {"email"}}})
+	if !v.Enabled() {
+		t.Fatal("Enabled() = false with unique columns configured")
+	}
+
+	check := func(source, transformed string) []Violation {
+		return v.Check(
+			&proto.DMLData{Table: "users", ColumnNames: []string{"email"}, ColumnValues: []*proto.ColumnValue{str(source)}},
+			&proto.DMLData{Table: "users", Kind: "insert", ColumnNames: []string{"email"}, ColumnValues: []*proto.ColumnValue{str(transformed)}},
+		)
+	}
+
+	if got := check("a@example.com", "x@fake.test"); len(got) != 0 {
+		t.Errorf("first value: %v", got)
+	}
+	// The same source row replayed or updated isn't a collision
+	if got := check("a@example.com", "x@fake.test"); len(got) != 0 {
+		t.Errorf("same source value: %v", got)
+	}
+	got := check("b@example.com", "x@fake.test")
+	if !reflect.DeepEqual(rules(got), []string{"email:unique"}) {
+		t.Fatalf("collision = %v, want a unique violation", got)
+	}
+	if got[0].Blocking() {
+		t.Error("uniqueness hints should not block the change")
+	}
+}
+
+func TestCheckWithoutColumns(t *testing.T) {
+	v := NewValidator(transform.ValidationConfig{Enabled: true})
+	dml := &proto.DMLData{Table: "unknown", Kind: "insert", ColumnNames: []string{"a"}, ColumnValues: []*proto.ColumnValue{{}}}
+	if got := v.Check(nil, dml); len(got) != 0 {
+		t.Errorf("Check() for a table without column definitions = %v, want none", got)
+	}
+}
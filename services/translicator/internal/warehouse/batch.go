@@ -0,0 +1,332 @@
+package warehouse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	"kasho/proto"
+	"translicator/internal/objectstore"
+)
+
+// Operations of a staged row
+const (
+	OpUpsert = "upsert"
+	OpDelete = "delete"
+)
+
+// Row is the last change to one row of a table within a batch. Upserts hold the new
+// row, deletes only the key.
+type Row struct {
+	Op       string
+	Values   map[string]any
+	Position string
+}
+
+// TableBatch holds the rows of one table to load. Tables with a key are merged, with at
+// most one row per key; tables without one are append only.
+type TableBatch struct {
+	Table   string
+	Columns []Column
+	Key     []string
+	Rows    []Row
+
+	index map[string]int
+}
+
+// add records a row, replacing an earlier row with the same key
+func (b *TableBatch) add(row Row) {
+	if len(b.Key) == 0 {
+		if row.Op == OpUpsert {
+			b.Rows = append(b.Rows, row)
+		}
+		return
+	}
+	id := rowKey(b.Key, row.Values)
+	if i, ok := b.index[id]; ok {
+		b.Rows[i] = row
+		return
+	}
+	b.index[id] = len(b.Rows)
+	b.Rows = append(b.Rows, row)
+}
+
+// rowKey identifies a row by its key values
+func rowKey(key []string, values map[string]any) string {
+	parts := make([]any, len(key))
+	for i, name := range key {
+		parts[i] = values[name]
+	}
+	data, _ := json.Marshal(parts)
+	return string(data)
+}
+
+// Batcher buffers transformed changes into per-table micro-batches and loads them into
+// a warehouse, creating tables from DDL changes or, for tables the stream never saw
+// created, from the changes themselves. Each load records the position it ends at,
+// which is where replication resumes after a restart.
+type Batcher struct {
+	warehouse Warehouse
+	maxRows   int
+
+	mu           sync.Mutex
+	position     string
+	lastPosition string
+	batches      map[string]*TableBatch
+	order        []string
+	pending      int
+	// schemas caches the columns of tables known to exist
+	schemas map[string][]Column
+}
+
+// NewBatcher creates a batcher that loads at most maxRows changes per load, continuing
+// after the position of the last load
+func NewBatcher(ctx context.Context, warehouse Warehouse, maxRows int) (*Batcher, error) {
+	position, err := warehouse.Position(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read load position: %w", err)
+	}
+	return &Batcher{
+		warehouse:    warehouse,
+		maxRows:      maxRows,
+		position:     position,
+		lastPosition: position,
+		batches:      make(map[string]*TableBatch),
+		schemas:      make(map[string][]Column),
+	}, nil
+}
+
+// Position returns the position of the last load, or "" if nothing was loaded yet
+func (b *Batcher) Position() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.position
+}
+
+// Pending returns the number of buffered changes
+func (b *Batcher) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pending
+}
+
+// Add buffers a transformed change, loading the buffer first if it is full. DDL
+// changes load the buffer and then create tables or add columns; DDL a warehouse
+// doesn't replicate is skipped. Messages load the buffer, so everything before a
+// marker is in the warehouse. If a load fails the change isn't added, so it can be
+// redelivered.
+func (b *Batcher) Add(ctx context.Context, change *proto.Change) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if change.GetMessage() != nil {
+		previous := b.lastPosition
+		b.lastPosition = change.Position
+		if err := b.flush(ctx); err != nil {
+			b.lastPosition = previous
+			return err
+		}
+		return nil
+	}
+
+	if ddl := change.GetDdl(); ddl != nil {
+		// Rows before the DDL load against the table as it was
+		if err := b.flush(ctx); err != nil {
+			return err
+		}
+		if schemaChange, ok := ParseSchemaChange(ddl.Ddl); ok {
+			if err := b.applySchemaChange(ctx, schemaChange); err != nil {
+				return err
+			}
+		}
+		b.lastPosition = change.Position
+		return nil
+	}
+
+	dml := change.GetDml()
+	if dml != nil && b.pending >= b.maxRows {
+		if err := b.flush(ctx); err != nil {
+			return err
+		}
+	}
+	if dml != nil {
+		b.addDML(change.Position, dml)
+	}
+	b.lastPosition = change.Position
+	return nil
+}
+
+// Flush loads the buffered changes. If it fails the changes stay buffered.
+func (b *Batcher) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flush(ctx)
+}
+
+func (b *Batcher) flush(ctx context.Context) error {
+	if b.lastPosition == b.position {
+		return nil
+	}
+
+	batches := make([]*TableBatch, 0, len(b.order))
+	for _, table := range b.order {
+		batch := b.batches[table]
+		columns, err := b.ensureTable(ctx, batch)
+		if err != nil {
+			return err
+		}
+		// Only deletes from a table that was never created: there is nothing to delete
+		if columns == nil {
+			continue
+		}
+		batch.Columns = columns
+		batches = append(batches, batch)
+	}
+	if err := b.warehouse.Load(ctx, batches, b.lastPosition); err != nil {
+		return fmt.Errorf("failed to load %d changes: %w", b.pending, err)
+	}
+
+	b.position = b.lastPosition
+	b.batches, b.order, b.pending = make(map[string]*TableBatch), nil, 0
+	return nil
+}
+
+// addDML buffers a DML change as an upsert of the new row, or a delete of the old key.
+// An update that changes the key also deletes the old key.
+func (b *Batcher) addDML(position string, dml *proto.DMLData) {
+	batch, ok := b.batches[dml.Table]
+	if !ok {
+		key := dml.PrimaryKey
+		if len(key) == 0 && dml.OldKeys != nil {
+			key = dml.OldKeys.KeyNames
+		}
+		batch = &TableBatch{Table: dml.Table, Key: slices.Clone(key), index: make(map[string]int)}
+		b.batches[dml.Table] = batch
+		b.order = append(b.order, dml.Table)
+	}
+	b.pending++
+
+	var oldKey map[string]any
+	if keys := dml.OldKeys; keys != nil && len(keys.KeyNames) > 0 {
+		oldKey = make(map[string]any, len(keys.KeyNames))
+		for i, name := range keys.KeyNames {
+			if i < len(keys.KeyValues) {
+				oldKey[name] = objectstore.JSONValue(keys.KeyValues[i])
+			}
+		}
+	}
+	if dml.Kind == "delete" {
+		if oldKey != nil {
+			batch.add(Row{Op: OpDelete, Values: oldKey, Position: position})
+		}
+		return
+	}
+
+	values := make(map[string]any, len(dml.ColumnNames))
+	for i, name := range dml.ColumnNames {
+		if i >= len(dml.ColumnValues) || dml.ColumnValues[i].GetUnchangedToast() {
+			continue
+		}
+		values[name] = objectstore.JSONValue(dml.ColumnValues[i])
+		batch.addColumn(name, inferType(dml.ColumnValues[i]))
+	}
+	if oldKey != nil && len(batch.Key) > 0 && rowKey(batch.Key, oldKey) != rowKey(batch.Key, values) {
+		batch.add(Row{Op: OpDelete, Values: oldKey, Position: position})
+	}
+	batch.add(Row{Op: OpUpsert, Values: values, Position: position})
+}
+
+// addColumn records a column seen in the batch's changes, keeping the first type seen
+func (b *TableBatch) addColumn(name string, typ Type) {
+	for _, column := range b.Columns {
+		if column.Name == name {
+			return
+		}
+	}
+	b.Columns = append(b.Columns, Column{Name: name, Type: typ})
+}
+
+// ensureTable creates the batch's table if it doesn't exist and adds the columns it
+// lacks, returning the table's columns. It returns nil if the table doesn't exist and
+// the batch has no rows to create it from.
+func (b *Batcher) ensureTable(ctx context.Context, batch *TableBatch) ([]Column, error) {
+	columns, known := b.schemas[batch.Table]
+	if !known {
+		var exists bool
+		var err error
+		columns, exists, err = b.warehouse.Describe(ctx, batch.Table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe %s: %w", batch.Table, err)
+		}
+		if !exists {
+			if len(batch.Columns) == 0 {
+				return nil, nil
+			}
+			if err := b.warehouse.CreateTable(ctx, batch.Table, batch.Columns, batch.Key); err != nil {
+				return nil, fmt.Errorf("failed to create %s: %w", batch.Table, err)
+			}
+			columns = slices.Clone(batch.Columns)
+		}
+	}
+	for _, column := range batch.Columns {
+		if !hasColumn(columns, column.Name) {
+			if err := b.warehouse.AddColumn(ctx, batch.Table, column); err != nil {
+				return nil, fmt.Errorf("failed to add column %s to %s: %w", column.Name, batch.Table, err)
+			}
+			columns = append(columns, column)
+		}
+	}
+	b.schemas[batch.Table] = columns
+	return columns, nil
+}
+
+// applySchemaChange replicates a CREATE TABLE or ADD COLUMN. Both are no-ops when
+// replayed, so a schema change needs no position of its own.
+func (b *Batcher) applySchemaChange(ctx context.Context, change *SchemaChange) error {
+	switch change.Kind {
+	case CreateTable:
+		if err := b.warehouse.CreateTable(ctx, change.Table, change.Columns, change.PrimaryKey); err != nil {
+			return fmt.Errorf("failed to create %s: %w", change.Table, err)
+		}
+	case AddColumns:
+		for _, column := range change.Columns {
+			if err := b.warehouse.AddColumn(ctx, change.Table, column); err != nil {
+				return fmt.Errorf("failed to add column %s to %s: %w", column.Name, change.Table, err)
+			}
+		}
+	}
+	// Read the table again before its next load
+	delete(b.schemas, change.Table)
+	return nil
+}
+
+func hasColumn(columns []Column, name string) bool {
+	for _, column := range columns {
+		if column.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// inferType picks the column type for a value of a table the stream never saw created
+func inferType(v *proto.ColumnValue) Type {
+	switch v.GetValue().(type) {
+	case *proto.ColumnValue_IntValue:
+		return TypeInt
+	case *proto.ColumnValue_FloatValue:
+		return TypeFloat
+	case *proto.ColumnValue_BoolValue:
+		return TypeBool
+	case *proto.ColumnValue_TimestampValue:
+		return TypeTimestamp
+	case *proto.ColumnValue_BytesValue:
+		return TypeBytes
+	case *proto.ColumnValue_JsonValue:
+		return TypeJSON
+	default:
+		return TypeString
+	}
+}
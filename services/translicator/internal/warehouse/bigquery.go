@@ -0,0 +1,329 @@
+package warehouse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+)
+
+// bigQueryStagingExpiration drops staging tables left behind by failed loads
+const bigQueryStagingExpiration = 24 * time.Hour
+
+// bigQueryWarehouse loads batches into BigQuery with a load job into a staging table,
+// then merges that into each table in one multi-statement transaction. It uses
+// Application Default Credentials.
+type bigQueryWarehouse struct {
+	client  *bigquery.Client
+	dataset string
+}
+
+func openBigQuery(ctx context.Context, project, dataset string) (*bigQueryWarehouse, error) {
+	client, err := bigquery.NewClient(ctx, project)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create BigQuery client: %w", err)
+	}
+	b := &bigQueryWarehouse{client: client, dataset: dataset}
+
+	schema := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType, Required: true},
+		{Name: "position", Type: bigquery.StringFieldType},
+	}
+	if err := b.createTable(ctx, b.client.Dataset(dataset).Table(positionTable), &bigquery.TableMetadata{Schema: schema}); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to create %s: %w", positionTable, err)
+	}
+	return b, nil
+}
+
+// tableRef returns the handle of a possibly dataset-qualified table
+func (b *bigQueryWarehouse) tableRef(table string) *bigquery.Table {
+	dataset, name := splitTable(table, b.dataset)
+	return b.client.Dataset(dataset).Table(name)
+}
+
+// table quotes a possibly dataset-qualified table name for queries
+func (b *bigQueryWarehouse) table(table string) string {
+	dataset, name := splitTable(table, b.dataset)
+	return fmt.Sprintf("`%s.%s.%s`", b.client.Project(), dataset, name)
+}
+
+func (b *bigQueryWarehouse) Describe(ctx context.Context, table string) ([]Column, bool, error) {
+	meta, err := b.tableRef(table).Metadata(ctx)
+	if isNotFound(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	columns := make([]Column, len(meta.Schema))
+	for i, field := range meta.Schema {
+		columns[i] = Column{
+			Name:      field.Name,
+			Type:      bigQueryColumnType(field.Type),
+			Precision: int(field.Precision),
+			Scale:     int(field.Scale),
+			Native:    string(field.Type),
+		}
+	}
+	return columns, true, nil
+}
+
+func (b *bigQueryWarehouse) CreateTable(ctx context.Context, table string, columns []Column, key []string) error {
+	meta := &bigquery.TableMetadata{Schema: make(bigquery.Schema, len(columns))}
+	for i, column := range columns {
+		meta.Schema[i] = bigQueryField(column)
+	}
+	// BigQuery doesn't enforce primary keys, but uses them to optimize joins
+	if len(key) > 0 {
+		meta.TableConstraints = &bigquery.TableConstraints{PrimaryKey: &bigquery.PrimaryKey{Columns: key}}
+	}
+	return b.createTable(ctx, b.tableRef(table), meta)
+}
+
+// createTable creates a table unless it exists
+func (b *bigQueryWarehouse) createTable(ctx context.Context, ref *bigquery.Table, meta *bigquery.TableMetadata) error {
+	err := ref.Create(ctx, meta)
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusConflict {
+		return nil
+	}
+	return err
+}
+
+func (b *bigQueryWarehouse) AddColumn(ctx context.Context, table string, column Column) error {
+	ref := b.tableRef(table)
+	meta, err := ref.Metadata(ctx)
+	if err != nil {
+		return err
+	}
+	for _, field := range meta.Schema {
+		if field.Name == column.Name {
+			return nil
+		}
+	}
+	// The ETag makes the update fail rather than drop a column added concurrently
+	schema := append(meta.Schema, bigQueryField(column))
+	_, err = ref.Update(ctx, bigquery.TableMetadataToUpdate{Schema: schema}, meta.ETag)
+	return err
+}
+
+func (b *bigQueryWarehouse) Load(ctx context.Context, batches []*TableBatch, position string) error {
+	var data bytes.Buffer
+	enc := json.NewEncoder(&data)
+	for _, batch := range batches {
+		for _, row := range batch.Rows {
+			if err := enc.Encode(stagedRow{Table: batch.Table, Op: row.Op, Position: row.Position, Row: row.Values}); err != nil {
+				return err
+			}
+		}
+	}
+
+	staging := b.client.Dataset(b.dataset).Table(fmt.Sprintf("_kasho_stage_%d", time.Now().UnixNano()))
+	err := staging.Create(ctx, &bigquery.TableMetadata{
+		Schema: bigquery.Schema{
+			{Name: "table", Type: bigquery.StringFieldType},
+			{Name: "op", Type: bigquery.StringFieldType},
+			{Name: "position", Type: bigquery.StringFieldType},
+			{Name: "row", Type: bigquery.JSONFieldType},
+		},
+		ExpirationTime: time.Now().Add(bigQueryStagingExpiration),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create staging table: %w", err)
+	}
+	defer staging.Delete(context.WithoutCancel(ctx))
+
+	source := bigquery.NewReaderSource(&data)
+	source.SourceFormat = bigquery.JSON
+	if err := b.runJob(ctx, staging.LoaderFrom(source).Run); err != nil {
+		return fmt.Errorf("failed to load staging table: %w", err)
+	}
+
+	stagingName := fmt.Sprintf("`%s.%s.%s`", b.client.Project(), b.dataset, staging.TableID)
+	script := []string{"BEGIN TRANSACTION"}
+	for _, batch := range batches {
+		script = append(script, b.mergeStatement(batch, stagingName))
+	}
+	script = append(script,
+		fmt.Sprintf(`MERGE %s t USING (SELECT 1 AS id, @position AS position) s ON t.id = s.id
+		WHEN MATCHED THEN UPDATE SET position = s.position
+		WHEN NOT MATCHED THEN INSERT (id, position) VALUES (s.id, s.position)`, b.table(positionTable)),
+		"COMMIT TRANSACTION")
+
+	query := b.client.Query(strings.Join(script, ";\n") + ";")
+	query.Parameters = []bigquery.QueryParameter{{Name: "position", Value: position}}
+	if err := b.runJob(ctx, query.Run); err != nil {
+		return fmt.Errorf("failed to merge staged rows: %w", err)
+	}
+	return nil
+}
+
+// runJob starts a job and waits for it to finish
+func (b *bigQueryWarehouse) runJob(ctx context.Context, run func(context.Context) (*bigquery.Job, error)) error {
+	job, err := run(ctx)
+	if err != nil {
+		return err
+	}
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return err
+	}
+	return status.Err()
+}
+
+// mergeStatement merges a table's staged rows into it, or appends them for tables
+// without a key
+func (b *bigQueryWarehouse) mergeStatement(batch *TableBatch, staging string) string {
+	selects := make([]string, len(batch.Columns))
+	names := make([]string, len(batch.Columns))
+	for i, column := range batch.Columns {
+		names[i] = quoteBigQueryName(column.Name)
+		selects[i] = bigQueryValue(column) + " AS " + names[i]
+	}
+	source := fmt.Sprintf("SELECT %s, op AS _kasho_op FROM %s WHERE `table` = %s",
+		strings.Join(selects, ", "), staging, quoteString(batch.Table))
+
+	if len(batch.Key) == 0 {
+		return fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM (%s)",
+			b.table(batch.Table), strings.Join(names, ", "), strings.Join(names, ", "), source)
+	}
+
+	on := make([]string, len(batch.Key))
+	for i, key := range batch.Key {
+		on[i] = fmt.Sprintf("t.%s = s.%s", quoteBigQueryName(key), quoteBigQueryName(key))
+	}
+	var sets, values []string
+	for _, name := range names {
+		sets = append(sets, fmt.Sprintf("t.%s = s.%s", name, name))
+		values = append(values, "s."+name)
+	}
+	return fmt.Sprintf(`MERGE %s t USING (%s) s ON %s
+		WHEN MATCHED AND s._kasho_op = '%s' THEN DELETE
+		WHEN MATCHED THEN UPDATE SET %s
+		WHEN NOT MATCHED AND s._kasho_op != '%s' THEN INSERT (%s) VALUES (%s)`,
+		b.table(batch.Table), source, strings.Join(on, " AND "),
+		OpDelete, strings.Join(sets, ", "), OpDelete, strings.Join(names, ", "), strings.Join(values, ", "))
+}
+
+func (b *bigQueryWarehouse) Position(ctx context.Context) (string, error) {
+	it, err := b.client.Query(fmt.Sprintf("SELECT position FROM %s WHERE id = 1", b.table(positionTable))).Read(ctx)
+	if err != nil {
+		return "", err
+	}
+	var row struct{ Position bigquery.NullString }
+	if err := it.Next(&row); err != nil {
+		if errors.Is(err, iterator.Done) {
+			return "", nil
+		}
+		return "", err
+	}
+	return row.Position.StringVal, nil
+}
+
+func (b *bigQueryWarehouse) Close() error {
+	return b.client.Close()
+}
+
+// bigQueryField returns the BigQuery schema field of a column
+func bigQueryField(column Column) *bigquery.FieldSchema {
+	field := &bigquery.FieldSchema{Name: column.Name}
+	switch column.Type {
+	case TypeInt:
+		field.Type = bigquery.IntegerFieldType
+	case TypeFloat:
+		field.Type = bigquery.FloatFieldType
+	case TypeNumeric:
+		// NUMERIC holds 38 digits with a scale of up to 9; anything larger needs BIGNUMERIC
+		field.Type = bigquery.BigNumericFieldType
+		if column.Precision > 0 && column.Precision-column.Scale <= 29 && column.Scale <= 9 {
+			field.Type = bigquery.NumericFieldType
+		}
+	case TypeBool:
+		field.Type = bigquery.BooleanFieldType
+	case TypeDate:
+		field.Type = bigquery.DateFieldType
+	case TypeTimestamp:
+		field.Type = bigquery.TimestampFieldType
+	case TypeBytes:
+		field.Type = bigquery.BytesFieldType
+	case TypeJSON:
+		field.Type = bigquery.JSONFieldType
+	default:
+		field.Type = bigquery.StringFieldType
+	}
+	return field
+}
+
+// bigQueryColumnType maps a BigQuery field type back to a type
+func bigQueryColumnType(fieldType bigquery.FieldType) Type {
+	switch fieldType {
+	case bigquery.IntegerFieldType:
+		return TypeInt
+	case bigquery.FloatFieldType:
+		return TypeFloat
+	case bigquery.NumericFieldType, bigquery.BigNumericFieldType:
+		return TypeNumeric
+	case bigquery.BooleanFieldType:
+		return TypeBool
+	case bigquery.DateFieldType:
+		return TypeDate
+	case bigquery.TimestampFieldType, bigquery.DateTimeFieldType:
+		return TypeTimestamp
+	case bigquery.BytesFieldType:
+		return TypeBytes
+	case bigquery.JSONFieldType:
+		return TypeJSON
+	default:
+		return TypeString
+	}
+}
+
+// bigQueryValue converts a staged JSON value to a column's type. Binary values are
+// staged as base64.
+func bigQueryValue(column Column) string {
+	path := quoteString(`$."` + column.Name + `"`)
+	switch column.Type {
+	case TypeJSON:
+		return fmt.Sprintf("JSON_QUERY(row, %s)", path)
+	case TypeBytes:
+		return fmt.Sprintf("SAFE.FROM_BASE64(JSON_VALUE(row, %s))", path)
+	}
+	typ := bigQueryField(column).Type
+	if column.Native != "" {
+		typ = bigquery.FieldType(column.Native)
+	}
+	return fmt.Sprintf("SAFE_CAST(JSON_VALUE(row, %s) AS %s)", path, bigQuerySQLType(typ))
+}
+
+// bigQuerySQLType returns the SQL name of a field type
+func bigQuerySQLType(fieldType bigquery.FieldType) string {
+	switch fieldType {
+	case bigquery.IntegerFieldType:
+		return "INT64"
+	case bigquery.FloatFieldType:
+		return "FLOAT64"
+	case bigquery.BooleanFieldType:
+		return "BOOL"
+	default:
+		return string(fieldType)
+	}
+}
+
+// quoteBigQueryName quotes a column name with backticks
+func quoteBigQueryName(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "\\`") + "`"
+}
+
+func isNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
@@ -0,0 +1,231 @@
+package warehouse
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Schema change kinds a warehouse replicates
+const (
+	CreateTable = "create_table"
+	AddColumns  = "add_columns"
+)
+
+// SchemaChange is a CREATE TABLE or ALTER TABLE ... ADD COLUMN statement from the source
+type SchemaChange struct {
+	Kind       string
+	Table      string
+	Columns    []Column
+	PrimaryKey []string
+}
+
+var (
+	createTablePattern = regexp.MustCompile(`(?is)^\s*CREATE\s+(?:UNLOGGED\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?([^\s(]+)\s*\((.*)\)[^)]*$`)
+	alterTablePattern  = regexp.MustCompile(`(?is)^\s*ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?(\S+)\s+(ADD\s+.*)$`)
+	addColumnPattern   = regexp.MustCompile(`(?is)^ADD\s+(?:COLUMN\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(.*)$`)
+	typeParamsPattern  = regexp.MustCompile(`\(\s*(\d+)\s*(?:,\s*(\d+)\s*)?\)`)
+)
+
+// ParseSchemaChange parses the DDL statements a warehouse replicates: CREATE TABLE and
+// ALTER TABLE ... ADD COLUMN. It reports false for any other statement, or one it
+// can't parse.
+func ParseSchemaChange(ddl string) (*SchemaChange, bool) {
+	ddl = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(ddl), ";"))
+
+	if m := createTablePattern.FindStringSubmatch(ddl); m != nil {
+		change := &SchemaChange{Kind: CreateTable, Table: unquoteName(m[1])}
+		for _, def := range splitTopLevel(m[2]) {
+			words := strings.Fields(def)
+			if len(words) == 0 {
+				continue
+			}
+			upper := strings.ToUpper(def)
+			switch strings.ToUpper(words[0]) {
+			case "PRIMARY", "CONSTRAINT":
+				if i := strings.Index(upper, "PRIMARY KEY"); i >= 0 {
+					change.PrimaryKey = parseNameList(def[i+len("PRIMARY KEY"):])
+				}
+				continue
+			case "UNIQUE", "FOREIGN", "CHECK", "KEY", "INDEX", "EXCLUDE", "LIKE", "FULLTEXT", "SPATIAL":
+				continue
+			}
+			column, ok := parseColumn(def)
+			if !ok {
+				return nil, false
+			}
+			change.Columns = append(change.Columns, column)
+			if strings.Contains(upper, "PRIMARY KEY") {
+				change.PrimaryKey = append(change.PrimaryKey, column.Name)
+			}
+		}
+		if len(change.Columns) == 0 {
+			return nil, false
+		}
+		return change, true
+	}
+
+	if m := alterTablePattern.FindStringSubmatch(ddl); m != nil {
+		change := &SchemaChange{Kind: AddColumns, Table: unquoteName(m[1])}
+		for _, action := range splitTopLevel(m[2]) {
+			add := addColumnPattern.FindStringSubmatch(strings.TrimSpace(action))
+			if add == nil {
+				return nil, false
+			}
+			// ADD CONSTRAINT, ADD PRIMARY KEY and the like don't add columns
+			switch strings.ToUpper(strings.Fields(add[1] + " x")[0]) {
+			case "CONSTRAINT", "PRIMARY", "UNIQUE", "FOREIGN", "CHECK", "INDEX", "KEY":
+				return nil, false
+			}
+			column, ok := parseColumn(add[1])
+			if !ok {
+				return nil, false
+			}
+			change.Columns = append(change.Columns, column)
+		}
+		return change, true
+	}
+
+	return nil, false
+}
+
+// constraintKeywords start the column constraints that follow a column's type
+var constraintKeywords = []string{
+	" NOT NULL ", " NULL ", " DEFAULT ", " PRIMARY KEY ", " REFERENCES ", " UNIQUE ", " CHECK",
+	" GENERATED ", " COLLATE ", " CHARACTER SET ", " AUTO_INCREMENT ", " COMMENT ", " CONSTRAINT ",
+}
+
+// parseColumn parses a column definition such as "price numeric(10, 2) NOT NULL"
+func parseColumn(def string) (Column, bool) {
+	def = strings.TrimSpace(def)
+	name, rest, found := cutName(def)
+	if !found || strings.TrimSpace(rest) == "" {
+		return Column{}, false
+	}
+	rest = strings.TrimSpace(rest)
+
+	// The type ends where the column constraints begin. Offsets into padded are one
+	// past the matching offsets into rest.
+	padded := " " + strings.ToUpper(rest) + " "
+	typeEnd := len(padded) - 1
+	for _, keyword := range constraintKeywords {
+		if i := strings.Index(padded, keyword); i >= 0 && i < typeEnd {
+			typeEnd = i
+		}
+	}
+	if typeEnd == 0 {
+		return Column{}, false
+	}
+	typeText := strings.TrimSpace(rest[:typeEnd-1])
+
+	column := Column{Name: name, Type: mapType(typeText)}
+	if column.Type == TypeNumeric {
+		if m := typeParamsPattern.FindStringSubmatch(typeText); m != nil {
+			column.Precision, _ = strconv.Atoi(m[1])
+			column.Scale, _ = strconv.Atoi(m[2])
+		}
+	}
+	return column, true
+}
+
+// mapType maps a PostgreSQL or MySQL column type to a warehouse type
+func mapType(sqlType string) Type {
+	t := strings.ToLower(strings.TrimSpace(sqlType))
+	if strings.HasSuffix(t, "[]") {
+		return TypeJSON
+	}
+	base := strings.TrimSpace(strings.SplitN(t, "(", 2)[0])
+	switch base {
+	case "smallint", "integer", "int", "bigint", "int2", "int4", "int8", "serial", "bigserial", "smallserial", "tinyint", "mediumint", "year":
+		return TypeInt
+	case "real", "float", "float4", "float8", "double", "double precision":
+		return TypeFloat
+	case "numeric", "decimal", "money":
+		return TypeNumeric
+	case "boolean", "bool":
+		return TypeBool
+	case "date":
+		return TypeDate
+	case "timestamp", "timestamptz", "datetime", "timestamp with time zone", "timestamp without time zone":
+		return TypeTimestamp
+	case "bytea", "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary":
+		return TypeBytes
+	case "json", "jsonb":
+		return TypeJSON
+	}
+	if strings.HasPrefix(base, "timestamp") {
+		return TypeTimestamp
+	}
+	if strings.HasPrefix(base, "bigint") || strings.HasPrefix(base, "int") {
+		return TypeInt
+	}
+	return TypeString
+}
+
+// splitTopLevel splits on commas outside parentheses and quotes
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote rune
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		parts = append(parts, last)
+	}
+	return parts
+}
+
+// cutName splits a leading, possibly quoted, identifier from the rest of s
+func cutName(s string) (string, string, bool) {
+	if s == "" {
+		return "", "", false
+	}
+	if q := s[0]; q == '"' || q == '`' {
+		end := strings.IndexByte(s[1:], q)
+		if end < 0 {
+			return "", "", false
+		}
+		return s[1 : end+1], s[end+2:], true
+	}
+	name, rest, _ := strings.Cut(s, " ")
+	return name, rest, true
+}
+
+// unquoteName removes identifier quotes from each part of a possibly qualified name
+func unquoteName(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = strings.Trim(part, "\"`")
+	}
+	return strings.Join(parts, ".")
+}
+
+// parseNameList parses a parenthesized list of column names, e.g. "(id, tenant_id)"
+func parseNameList(s string) []string {
+	open, end := strings.IndexByte(s, '('), strings.IndexByte(s, ')')
+	if open < 0 || end < open {
+		return nil
+	}
+	var names []string
+	for _, name := range strings.Split(s[open+1:end], ",") {
+		if name = strings.Trim(strings.TrimSpace(name), "\"`"); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,70 @@
+package warehouse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSchemaChange(t *testing.T) {
+	tests := []struct {
+		name string
+		ddl  string
+		want *SchemaChange
+	}{
+		{
+			name: "create table",
+			ddl: `CREATE TABLE IF NOT EXISTS public."orders" (
+				id bigserial PRIMARY KEY,
+				total numeric(10, 2) NOT NULL DEFAULT 0,
+				placed_at timestamp with time zone,
+				tags text[],
+				CONSTRAINT orders_total_check CHECK (total >= 0)
+			);`,
+			want: &SchemaChange{
+				Kind:  CreateTable,
+				Table: "public.orders",
+				Columns: []Column{
+					{Name: "id", Type: TypeInt},
+					{Name: "total", Type: TypeNumeric, Precision: 10, Scale: 2},
+					{Name: "placed_at", Type: TypeTimestamp},
+					{Name: "tags", Type: TypeJSON},
+				},
+				PrimaryKey: []string{"id"},
+			},
+		},
+		{
+			name: "create table with table primary key",
+			ddl:  "CREATE TABLE `memberships` (`user_id` int NOT NULL, `team_id` int NOT NULL, PRIMARY KEY (`user_id`, `team_id`)) ENGINE=InnoDB",
+			want: &SchemaChange{
+				Kind:       CreateTable,
+				Table:      "memberships",
+				Columns:    []Column{{Name: "user_id", Type: TypeInt}, {Name: "team_id", Type: TypeInt}},
+				PrimaryKey: []string{"user_id", "team_id"},
+			},
+		},
+		{
+			name: "add columns",
+			ddl:  "ALTER TABLE users ADD COLUMN age int, ADD COLUMN avatar bytea",
+			want: &SchemaChange{
+				Kind:    AddColumns,
+				Table:   "users",
+				Columns: []Column{{Name: "age", Type: TypeInt}, {Name: "avatar", Type: TypeBytes}},
+			},
+		},
+		{name: "add constraint", ddl: "ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email)"},
+		{name: "drop column", ddl: "ALTER TABLE users DROP COLUMN age"},
+		{name: "create index", ddl: "CREATE INDEX users_email_idx ON users (email)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseSchemaChange(tt.ddl)
+			if ok != (tt.want != nil) {
+				t.Fatalf("ParseSchemaChange() ok = %v, want %v", ok, tt.want != nil)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSchemaChange() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kasho/proto"
+	"translicator/internal/objectstore"
+)
+
+const (
+	// SignatureHeader carries the hex HMAC-SHA256 of "<timestamp>.<body>", prefixed
+	// with "sha256="
+	SignatureHeader = "X-Kasho-Signature"
+	// TimestampHeader carries the Unix time the request was signed at, so receivers
+	// can reject replayed requests
+	TimestampHeader = "X-Kasho-Timestamp"
+	// DeliveryHeader identifies a batch; a retried batch has the same ID, so receivers
+	// can drop duplicates
+	DeliveryHeader = "X-Kasho-Delivery"
+
+	defaultMaxAttempts  = 5
+	defaultRetryBackoff = 500 * time.Millisecond
+	maxRetryBackoff     = 30 * time.Second
+	requestTimeout      = 30 * time.Second
+)
+
+// Batch is the JSON body of a request: the changes since the previous batch, and the
+// position they end at
+type Batch struct {
+	ID       string               `json:"id"`
+	Position string               `json:"position"`
+	Changes  []objectstore.Record `json:"changes"`
+}
+
+// StatusError is returned for a response outside the 2xx range
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return "unexpected status " + e.Status
+}
+
+// Transient reports whether the request may succeed if retried: server errors,
+// timeouts and rate limiting are, other client errors are not
+func (e *StatusError) Transient() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusTooManyRequests
+}
+
+// IsURL reports whether a replica URL names an HTTP endpoint instead of a database
+func IsURL(rawURL string) bool {
+	scheme, _, found := strings.Cut(rawURL, "://")
+	if !found {
+		return false
+	}
+	switch strings.ToLower(scheme) {
+	case "http", "https":
+		return true
+	default:
+		return false
+	}
+}
+
+// Sender buffers transformed changes and POSTs them in batches to a URL, signing each
+// request with a shared secret. The position of the last delivered batch is saved to
+// a state file, which is where replication resumes after a restart.
+type Sender struct {
+	url        string
+	secret     []byte
+	client     *http.Client
+	statePath  string
+	maxRecords int
+
+	maxAttempts  int
+	retryBackoff time.Duration
+	sleep        func(ctx context.Context, d time.Duration) error
+	now          func() time.Time
+
+	mu           sync.Mutex
+	position     string
+	records      []objectstore.Record
+	lastPosition string
+}
+
+// NewSender creates a sender that posts at most maxRecords changes per request. If
+// statePath is set, the sender continues after the position saved there.
+func NewSender(url, secret, statePath string, maxRecords int) (*Sender, error) {
+	s := &Sender{
+		url:          url,
+		secret:       []byte(secret),
+		client:       &http.Client{Timeout: requestTimeout},
+		statePath:    statePath,
+		maxRecords:   maxRecords,
+		maxAttempts:  defaultMaxAttempts,
+		retryBackoff: defaultRetryBackoff,
+		sleep:        sleepContext,
+		now:          time.Now,
+	}
+	if statePath == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook state: %w", err)
+	}
+	s.position = strings.TrimSpace(string(data))
+	s.lastPosition = s.position
+	return s, nil
+}
+
+// SetRetry sets how often a batch is attempted in total, and the delay before the
+// first retry, which doubles with each further retry
+func (s *Sender) SetRetry(maxAttempts int, backoff time.Duration) {
+	s.maxAttempts = max(maxAttempts, 1)
+	s.retryBackoff = backoff
+}
+
+// Position returns the position of the last delivered batch, or "" if nothing was
+// delivered yet
+func (s *Sender) Position() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.position
+}
+
+// Pending returns the number of buffered changes
+func (s *Sender) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.records)
+}
+
+// Add buffers a transformed change, sending the buffer first if it is full. If that
+// fails the change isn't added, so it can be redelivered. Only DML is sent; other
+// changes just advance the position, and messages send the buffer so everything
+// before a marker is delivered.
+func (s *Sender) Add(ctx context.Context, change *proto.Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if change.GetMessage() != nil {
+		previous := s.lastPosition
+		s.lastPosition = change.Position
+		if err := s.flush(ctx); err != nil {
+			s.lastPosition = previous
+			return err
+		}
+		return nil
+	}
+
+	if change.GetDml() != nil && len(s.records) >= s.maxRecords {
+		if err := s.flush(ctx); err != nil {
+			return err
+		}
+	}
+	if change.GetDml() != nil {
+		s.records = append(s.records, objectstore.NewRecord(change))
+	}
+	s.lastPosition = change.Position
+	return nil
+}
+
+// Flush sends the buffered changes. If it fails the changes stay buffered.
+func (s *Sender) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flush(ctx)
+}
+
+func (s *Sender) flush(ctx context.Context) error {
+	if s.lastPosition == s.position {
+		return nil
+	}
+
+	// Batches of only DDL or heartbeats aren't sent, just saved
+	if len(s.records) > 0 {
+		id := batchID(s.records)
+		body, err := json.Marshal(Batch{ID: id, Position: s.lastPosition, Changes: s.records})
+		if err != nil {
+			return fmt.Errorf("failed to encode batch: %w", err)
+		}
+		if err := s.send(ctx, id, body); err != nil {
+			return err
+		}
+	}
+
+	if err := s.saveState(s.lastPosition); err != nil {
+		return err
+	}
+	s.position, s.records = s.lastPosition, nil
+	return nil
+}
+
+// send posts a batch, retrying transient failures with backoff
+func (s *Sender) send(ctx context.Context, id string, body []byte) error {
+	backoff := s.retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := s.post(ctx, id, body)
+		if err == nil {
+			return nil
+		}
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && !statusErr.Transient() {
+			return fmt.Errorf("webhook rejected batch %s: %w", id, err)
+		}
+		if attempt >= s.maxAttempts || ctx.Err() != nil {
+			return fmt.Errorf("failed to deliver batch %s after %d attempts: %w", id, attempt, err)
+		}
+
+		log.Printf("Webhook delivery failed (attempt %d of %d), retrying in %v: %v", attempt, s.maxAttempts, backoff, err)
+		if err := s.sleep(ctx, backoff); err != nil {
+			return err
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
+
+// post sends one signed request
+func (s *Sender) post(ctx context.Context, id string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DeliveryHeader, id)
+	req.Header.Set(TimestampHeader, timestamp)
+	if len(s.secret) > 0 {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(s.secret, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return nil
+}
+
+// saveState atomically replaces the state file with a position
+func (s *Sender) saveState(position string) error {
+	if s.statePath == "" {
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.statePath), ".webhook-state-*")
+	if err != nil {
+		return fmt.Errorf("failed to save webhook state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(position + "\n"); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save webhook state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save webhook state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.statePath); err != nil {
+		return fmt.Errorf("failed to save webhook state: %w", err)
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>". Receivers verify a request
+// by computing it with the shared secret and comparing it to the signature header.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// batchID identifies a batch by the positions of its first and last change, which
+// stay the same when it is retried
+func batchID(records []objectstore.Record) string {
+	return records[0].Position + "-" + records[len(records)-1].Position
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
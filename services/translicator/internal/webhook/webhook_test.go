@@ -0,0 +1,156 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"kasho/proto"
+)
+
+func dmlChange(position string, id int64) *proto.Change {
+	return &proto.Change{
+		Position: position,
+		Data: &proto.Change_Dml{Dml: &proto.DMLData{
+			Table:        "public.users",
+			Kind:         "insert",
+			ColumnNames:  []string{"id"},
+			ColumnValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_IntValue{IntValue: id}}},
+		}},
+	}
+}
+
+func noSleep(context.Context, time.Duration) error { return nil }
+
+func TestSender_Flush(t *testing.T) {
+	ctx := context.Background()
+	var batches []Batch
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp := r.Header.Get(TimestampHeader)
+		if got, want := r.Header.Get(SignatureHeader), "sha256="+Sign([]byte("s3cret"), timestamp, body); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+		var batch Batch
+		if err := json.Unmarshal(body, &batch); err != nil {
+			t.Errorf("invalid batch: %v", err)
+		}
+		if r.Header.Get(DeliveryHeader) != batch.ID {
+			t.Errorf("delivery header = %q, want batch ID %q", r.Header.Get(DeliveryHeader), batch.ID)
+		}
+		batches = append(batches, batch)
+	}))
+	defer server.Close()
+
+	statePath := filepath.Join(t.TempDir(), "webhook.position")
+	s, err := NewSender(server.URL, "s3cret", statePath, 100)
+	if err != nil {
+		t.Fatalf("NewSender() unexpected error: %v", err)
+	}
+	changes := []*proto.Change{
+		dmlChange("0/1", 1),
+		dmlChange("0/2", 2),
+		{Position: "0/3", Data: &proto.Change_Ddl{Ddl: &proto.DDLData{Ddl: "ALTER TABLE users ADD COLUMN age int"}}},
+	}
+	for _, change := range changes {
+		if err := s.Add(ctx, change); err != nil {
+			t.Fatalf("Add() unexpected error: %v", err)
+		}
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush() unexpected error: %v", err)
+	}
+
+	if len(batches) != 1 {
+		t.Fatalf("received %d batches, want 1", len(batches))
+	}
+	if batch := batches[0]; batch.ID != "0/1-0/2" || batch.Position != "0/3" || len(batch.Changes) != 2 {
+		t.Errorf("batch = id %q position %q with %d changes, want 0/1-0/2, 0/3 and 2", batch.ID, batch.Position, len(batch.Changes))
+	}
+	if data, _ := os.ReadFile(statePath); strings.TrimSpace(string(data)) != "0/3" {
+		t.Errorf("state file = %q, want 0/3", data)
+	}
+
+	// A new sender continues after the saved position, with nothing to send
+	s, err = NewSender(server.URL, "s3cret", statePath, 100)
+	if err != nil {
+		t.Fatalf("NewSender() unexpected error: %v", err)
+	}
+	if s.Position() != "0/3" {
+		t.Errorf("Position() after restart = %q, want 0/3", s.Position())
+	}
+	if err := s.Flush(ctx); err != nil {
+		t.Fatalf("Flush() unexpected error: %v", err)
+	}
+	if len(batches) != 1 {
+		t.Errorf("empty flush sent a batch")
+	}
+}
+
+func TestSender_Retry(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		wantErr  bool
+		requests int
+	}{
+		{name: "transient failures", statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}, requests: 3},
+		{name: "rejected", statuses: []int{http.StatusBadRequest}, wantErr: true, requests: 1},
+		{name: "out of attempts", statuses: []int{500, 500, 500}, wantErr: true, requests: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statuses[min(requests, len(tt.statuses)-1)])
+				requests++
+			}))
+			defer server.Close()
+
+			s, err := NewSender(server.URL, "", "", 100)
+			if err != nil {
+				t.Fatalf("NewSender() unexpected error: %v", err)
+			}
+			s.SetRetry(3, time.Millisecond)
+			s.sleep = noSleep
+
+			if err := s.Add(ctx, dmlChange("0/1", 1)); err != nil {
+				t.Fatalf("Add() unexpected error: %v", err)
+			}
+			err = s.Flush(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Flush() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if requests != tt.requests {
+				t.Errorf("requests = %d, want %d", requests, tt.requests)
+			}
+			// Undelivered changes stay buffered
+			if wantPending := map[bool]int{true: 1, false: 0}[tt.wantErr]; s.Pending() != wantPending {
+				t.Errorf("Pending() = %d, want %d", s.Pending(), wantPending)
+			}
+		})
+	}
+}
+
+func TestIsURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://events.internal/kasho": true,
+		"HTTP://localhost:8080/hook":    true,
+		"s3://bucket/kasho":             false,
+		"postgresql://localhost/db":     false,
+	}
+	for url, want := range tests {
+		if got := IsURL(url); got != want {
+			t.Errorf("IsURL(%q) = %v, want %v", url, got, want)
+		}
+	}
+}
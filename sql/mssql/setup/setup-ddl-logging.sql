@@ -0,0 +1,68 @@
+-- Setup DDL logging for Kasho (SQL Server version)
+--
+-- WHY THIS IS NEEDED FOR SQL SERVER:
+-- Change data capture records only DML (INSERT, UPDATE, DELETE) on tracked tables.
+-- Schema changes are not written to the change tables, so, as with PostgreSQL, we
+-- capture DDL statements explicitly with a database-level DDL trigger.
+--
+-- This script creates:
+-- 1. kasho_ddl_log table - stores DDL statements, with CDC enabled on it so they
+--    arrive in commit order with the data changes
+-- 2. kasho_log_ddl trigger - logs every database-level DDL statement
+-- 3. Cleanup query - removes entries older than 7 days, to schedule as an Agent job
+--
+-- Run setup-replication.sql first so that CDC is enabled on the database.
+
+IF EXISTS (SELECT 1 FROM sys.triggers WHERE name = 'kasho_log_ddl' AND parent_class = 0)
+  DROP TRIGGER kasho_log_ddl ON DATABASE;
+GO
+
+-- Drop and recreate kasho_ddl_log table
+IF EXISTS (SELECT 1 FROM cdc.change_tables WHERE capture_instance = 'dbo_kasho_ddl_log')
+  EXEC sys.sp_cdc_disable_table
+    @source_schema = N'dbo',
+    @source_name = N'kasho_ddl_log',
+    @capture_instance = N'dbo_kasho_ddl_log';
+DROP TABLE IF EXISTS dbo.kasho_ddl_log;
+CREATE TABLE dbo.kasho_ddl_log (
+  id INT IDENTITY(1,1) PRIMARY KEY,
+  ts DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(),
+  username NVARCHAR(128),
+  [database] NVARCHAR(128),
+  ddl NVARCHAR(MAX) NOT NULL
+);
+GO
+
+EXEC sys.sp_cdc_enable_table
+  @source_schema = N'dbo',
+  @source_name = N'kasho_ddl_log',
+  @role_name = NULL,
+  @capture_instance = N'dbo_kasho_ddl_log',
+  @supports_net_changes = 0;
+GO
+
+-- Log the DDL command
+CREATE TRIGGER kasho_log_ddl
+ON DATABASE
+FOR DDL_DATABASE_LEVEL_EVENTS
+AS
+BEGIN
+  SET NOCOUNT ON;
+  DECLARE @event XML = EVENTDATA();
+
+  -- Skip CDC's own objects and changes to the log table itself
+  IF @event.value('(/EVENT_INSTANCE/SchemaName)[1]', 'NVARCHAR(128)') = 'cdc'
+    OR @event.value('(/EVENT_INSTANCE/ObjectName)[1]', 'NVARCHAR(128)') = 'kasho_ddl_log'
+    RETURN;
+
+  INSERT INTO dbo.kasho_ddl_log (username, [database], ddl)
+  VALUES (
+    @event.value('(/EVENT_INSTANCE/LoginName)[1]', 'NVARCHAR(128)'),
+    DB_NAME(),
+    @event.value('(/EVENT_INSTANCE/TSQLCommand/CommandText)[1]', 'NVARCHAR(MAX)')
+  );
+END;
+GO
+
+-- Delete entries older than 7 days; schedule this as a SQL Server Agent job step
+-- DELETE FROM dbo.kasho_ddl_log WHERE ts < DATEADD(day, -7, SYSUTCDATETIME());
@@ -0,0 +1,45 @@
+-- Setup SQL Server change data capture for Kasho
+-- This enables CDC on the database and on every user table
+--
+-- NOTE: CDC requires SQL Server Agent to be running; its capture job reads the
+-- transaction log into the cdc.<capture_instance>_CT change tables that the
+-- mssql-change-stream service polls. Change Tracking is not supported because it
+-- records only which rows changed, not their values.
+
+-- Enable CDC on the database (requires sysadmin)
+IF (SELECT is_cdc_enabled FROM sys.databases WHERE name = DB_NAME()) = 0
+  EXEC sys.sp_cdc_enable_db;
+GO
+
+-- Enable CDC on every user table that is not already tracked
+DECLARE @schema SYSNAME, @table SYSNAME;
+DECLARE tables_cursor CURSOR LOCAL FAST_FORWARD FOR
+  SELECT s.name, t.name
+  FROM sys.tables t
+  JOIN sys.schemas s ON s.schema_id = t.schema_id
+  WHERE t.is_ms_shipped = 0
+    AND t.is_tracked_by_cdc = 0
+    AND s.name <> 'cdc';
+
+OPEN tables_cursor;
+FETCH NEXT FROM tables_cursor INTO @schema, @table;
+WHILE @@FETCH_STATUS = 0
+BEGIN
+  EXEC sys.sp_cdc_enable_table
+    @source_schema = @schema,
+    @source_name = @table,
+    @role_name = NULL,
+    @supports_net_changes = 0;
+  FETCH NEXT FROM tables_cursor INTO @schema, @table;
+END;
+CLOSE tables_cursor;
+DEALLOCATE tables_cursor;
+GO
+
+-- CDC does not pick up columns added after a table was enabled. To capture them,
+-- create a second capture instance for the table; Kasho reads from the newest one:
+-- EXEC sys.sp_cdc_enable_table @source_schema = N'dbo', @source_name = N'users',
+--   @role_name = NULL, @capture_instance = N'dbo_users_v2';
+
+-- To view captured tables:
+-- EXEC sys.sp_cdc_help_change_data_capture;
@@ -0,0 +1,49 @@
+-- Setup Oracle LogMiner for Kasho
+-- This enables the redo logging LogMiner needs and creates the kasho user that the
+-- oracle-change-stream service connects as
+--
+-- NOTE: run as SYSDBA. Switching to ARCHIVELOG mode restarts the database; skip
+-- that step if ARCHIVE LOG LIST already shows "Archive Mode". XStream is not
+-- supported because it requires an Oracle GoldenGate license.
+
+-- Archive redo logs so that changes stay available while Kasho is stopped
+-- SHUTDOWN IMMEDIATE;
+-- STARTUP MOUNT;
+-- ALTER DATABASE ARCHIVELOG;
+-- ALTER DATABASE OPEN;
+
+-- Minimal supplemental logging lets LogMiner reconstruct DML at all
+ALTER DATABASE ADD SUPPLEMENTAL LOG DATA;
+
+-- Logging all columns puts a row's old values in every UPDATE and DELETE, which
+-- Kasho needs to produce complete rows. To limit redo volume, log only the
+-- captured tables instead, e.g.:
+--   ALTER TABLE app.users ADD SUPPLEMENTAL LOG DATA (ALL) COLUMNS;
+ALTER DATABASE ADD SUPPLEMENTAL LOG DATA (ALL) COLUMNS;
+
+-- Create the kasho user (on a CDB, create c##kasho in CDB$ROOT and connect to the PDB)
+CREATE USER kasho IDENTIFIED BY kasho_password;
+GRANT CREATE SESSION TO kasho;
+
+-- LogMiner
+GRANT LOGMINING TO kasho;
+GRANT EXECUTE ON DBMS_LOGMNR TO kasho;
+GRANT SELECT ANY TRANSACTION TO kasho;
+GRANT SELECT_CATALOG_ROLE TO kasho;
+GRANT EXECUTE_CATALOG_ROLE TO kasho;
+
+-- Positions, log files and mined changes
+GRANT SELECT ON V_$DATABASE TO kasho;
+GRANT SELECT ON V_$LOG TO kasho;
+GRANT SELECT ON V_$LOGFILE TO kasho;
+GRANT SELECT ON V_$ARCHIVED_LOG TO kasho;
+GRANT SELECT ON V_$TRANSACTION TO kasho;
+GRANT SELECT ON V_$LOGMNR_CONTENTS TO kasho;
+
+-- Table definitions and primary keys of the captured schemas; ALL_TAB_COLUMNS only
+-- lists tables the user can read, so grant SELECT on the captured tables instead
+-- to narrow this down
+GRANT SELECT ANY TABLE TO kasho;
+
+-- Verify the setup
+SELECT log_mode, supplemental_log_data_min, supplemental_log_data_all FROM v$database;
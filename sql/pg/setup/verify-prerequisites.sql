@@ -1,2 +0,0 @@
--- this will require a restart of the database before running the rest of the setup scripts
-ALTER SYSTEM SET wal_level = logical;
@@ -0,0 +1,93 @@
+//go:build integration
+
+package e2e
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestChaosIntegration runs the pipeline of TestPipelineIntegration once per fault the
+// chaos build tag injects, and checks that every replica still converges on the primary's
+// rows, none lost or applied twice. It needs the same variables, services built with
+// `-tags chaos` and E2E_CHAOS=1, as `task test:integration:chaos` sets up.
+func TestChaosIntegration(t *testing.T) {
+	if os.Getenv("E2E_CHAOS") == "" {
+		t.Skip("E2E_CHAOS not set; the services must be built with -tags chaos")
+	}
+
+	tests := []struct {
+		name            string
+		changeStreamEnv []string
+		translicatorEnv []string
+	}{
+		{name: "dropped stream changes", translicatorEnv: []string{"CHAOS_DROP_STREAM_PERCENT=5"}},
+		{name: "failed statements", translicatorEnv: []string{"CHAOS_FAIL_EXEC_EVERY=7"}},
+		{name: "killed Redis connections", changeStreamEnv: []string{"CHAOS_KILL_REDIS_EVERY=25"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			defer cancel()
+			p := startPipeline(t, ctx, tt.changeStreamEnv, tt.translicatorEnv)
+			p.waitForUsers(t, ctx, runWorkload(t, p.primary))
+		})
+	}
+}
+
+// runWorkload makes a few hundred row changes on the primary, alone and in transactions,
+// and returns the users the replicas should end up with
+func runWorkload(t *testing.T, primary *sql.DB) []user {
+	t.Helper()
+	const users = 200
+	mustExec(t, primary,
+		"CREATE TABLE e2e_users (id integer PRIMARY KEY, name varchar(100), email varchar(200), plan varchar(20))",
+	)
+
+	for id := 1; id <= users; id++ {
+		mustExec(t, primary, fmt.Sprintf("INSERT INTO e2e_users VALUES (%d, 'User %d', 'user%d@primary.test', NULL)", id, id, id))
+	}
+	for id := 3; id <= users; id += 3 {
+		mustExec(t, primary, fmt.Sprintf("UPDATE e2e_users SET name = 'Renamed %d' WHERE id = %d", id, id))
+	}
+
+	// Upgrade and remove users in transactions of ten, so they apply all or nothing
+	for first := 1; first <= users; first += 10 {
+		tx, err := primary.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for id := first; id < first+10; id++ {
+			statement := fmt.Sprintf("UPDATE e2e_users SET plan = 'pro' WHERE id = %d", id)
+			if id%5 == 0 {
+				statement = fmt.Sprintf("DELETE FROM e2e_users WHERE id = %d", id)
+			}
+			if _, err := tx.Exec(statement); err != nil {
+				tx.Rollback()
+				t.Fatalf("failed to run %q: %v", statement, err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var want []user
+	for id := 1; id <= users; id++ {
+		if id%5 == 0 {
+			continue
+		}
+		u := user{id: id, name: fmt.Sprintf("User %d", id), plan: sql.NullString{String: "pro", Valid: true}}
+		if id%3 == 0 {
+			u.name = fmt.Sprintf("Renamed %d", id)
+		}
+		u.email = strings.ToLower(strings.ReplaceAll(u.name, " ", "-")) + "@example.com"
+		want = append(want, u)
+	}
+	return want
+}
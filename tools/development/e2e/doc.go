@@ -0,0 +1,7 @@
+// Package e2e holds end-to-end tests of a pipeline: they run pg-change-stream and
+// translicator against real databases and Redis, change the primary and check the
+// transformed rows on the replicas. The tests are built with the integration tag and
+// skip unless the databases are given by E2E_ variables, see TestPipelineIntegration.
+// `task test:integration:pipeline` builds the services, starts the databases and Redis
+// in Docker containers and runs the tests against them; CI runs it with the Go tests.
+package e2e
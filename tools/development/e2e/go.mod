@@ -0,0 +1,27 @@
+module e2e
+
+go 1.24.3
+
+require (
+	github.com/go-sql-driver/mysql v1.9.3
+	github.com/lib/pq v1.10.9
+	github.com/redis/go-redis/v9 v9.8.0
+	google.golang.org/grpc v1.72.1
+	kasho/pkg/dialect v0.0.0
+	kasho/proto v0.0.0-00010101000000-000000000000
+)
+
+require (
+	filippo.io/edwards25519 v1.1.0 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	golang.org/x/net v0.40.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/text v0.26.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250218202821-56aae31c358a // indirect
+	google.golang.org/protobuf v1.36.6 // indirect
+)
+
+replace kasho/pkg/dialect => ../../../pkg/dialect
+
+replace kasho/proto => ../../../proto/kasho/proto
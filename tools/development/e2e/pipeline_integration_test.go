@@ -0,0 +1,315 @@
+//go:build integration
+
+package e2e
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"kasho/pkg/dialect"
+	"kasho/proto"
+)
+
+// user is a row of e2e_users as the replica should hold it
+type user struct {
+	id    int
+	name  string
+	email string
+	plan  sql.NullString
+}
+
+// TestPipelineIntegration runs pg-change-stream and one translicator per replica, such as
+// `task test:integration:pipeline` sets up, and checks that row changes and DDL on the
+// primary reach every replica, transformed by testdata/config/transforms.yml. It needs:
+//
+//   - E2E_PRIMARY_URL: a PostgreSQL primary with wal_level=logical, as a superuser
+//   - E2E_REPLICA_URLS: comma-separated PostgreSQL or MySQL replicas without an e2e_users table
+//   - E2E_KV_URL: a Redis used only by this test, as it is flushed first
+//   - E2E_BIN_DIR: the directory with the pg-change-stream and translicator binaries,
+//     if they aren't on the PATH
+func TestPipelineIntegration(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+	p := startPipeline(t, ctx, nil, nil)
+
+	mustExec(t, p.primary,
+		"CREATE TABLE e2e_users (id integer PRIMARY KEY, name varchar(100), email varchar(200))",
+		"INSERT INTO e2e_users VALUES (1, 'Ada Lovelace', 'ada@primary.test'), (2, 'Alan Turing', 'alan@primary.test')",
+		"UPDATE e2e_users SET name = 'Grace Hopper' WHERE id = 1",
+		"DELETE FROM e2e_users WHERE id = 2",
+		"ALTER TABLE e2e_users ADD COLUMN plan varchar(20)",
+		"INSERT INTO e2e_users VALUES (3, 'Edsger Dijkstra', 'edsger@primary.test', 'pro')",
+	)
+
+	p.waitForUsers(t, ctx, []user{
+		{id: 1, name: "Grace Hopper", email: "grace-hopper@example.com"},
+		{id: 3, name: "Edsger Dijkstra", email: "edsger-dijkstra@example.com", plan: sql.NullString{String: "pro", Valid: true}},
+	})
+}
+
+// pipeline is a running pg-change-stream with a translicator per replica
+type pipeline struct {
+	primary     *sql.DB
+	replicas    []*sql.DB
+	replicaURLs []string
+}
+
+// startPipeline resets the primary, replicas and Redis named by the E2E_ variables, skipping
+// the test if they aren't set, and runs the services until the test ends. changeStreamEnv
+// and translicatorEnv are added to the environments of the services.
+func startPipeline(t *testing.T, ctx context.Context, changeStreamEnv, translicatorEnv []string) *pipeline {
+	t.Helper()
+	primaryURL := os.Getenv("E2E_PRIMARY_URL")
+	replicaURLs := splitList(os.Getenv("E2E_REPLICA_URLS"))
+	kvURL := os.Getenv("E2E_KV_URL")
+	if primaryURL == "" || len(replicaURLs) == 0 || kvURL == "" {
+		t.Skip("E2E_PRIMARY_URL, E2E_REPLICA_URLS and E2E_KV_URL not set; run `task test:integration:pipeline` to start them in containers")
+	}
+
+	// Start from an empty buffer, a fresh slot and no test table anywhere
+	opts, err := redis.ParseURL(kvURL)
+	if err != nil {
+		t.Fatalf("invalid E2E_KV_URL: %v", err)
+	}
+	kv := redis.NewClient(opts)
+	defer kv.Close()
+	if err := kv.FlushDB(ctx).Err(); err != nil {
+		t.Fatalf("failed to flush Redis: %v", err)
+	}
+
+	p := &pipeline{primary: openDB(t, primaryURL), replicaURLs: replicaURLs}
+	mustExec(t, p.primary,
+		"SELECT pg_drop_replication_slot(slot_name) FROM pg_replication_slots WHERE slot_name = 'kasho_slot'",
+		"DROP TABLE IF EXISTS e2e_users",
+	)
+	for _, file := range []string{"setup-ddl-logging.sql", "setup-replication.sql"} {
+		script, err := os.ReadFile(filepath.Join("..", "..", "..", "sql", "pg", "setup", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		mustExec(t, p.primary, string(script))
+	}
+	for _, replicaURL := range replicaURLs {
+		replica := openDB(t, replicaURL)
+		mustExec(t, replica, "DROP TABLE IF EXISTS e2e_users")
+		p.replicas = append(p.replicas, replica)
+	}
+
+	// Bootstrap with nothing to copy, as bootstrap-kasho-pg.sh would on an empty primary
+	var startLSN string
+	if err := p.primary.QueryRowContext(ctx, "SELECT lsn FROM pg_create_logical_replication_slot('kasho_slot', 'pgoutput')").Scan(&startLSN); err != nil {
+		t.Fatalf("failed to create replication slot: %v", err)
+	}
+
+	addr := freeAddr(t)
+	startService(t, "pg-change-stream", "pg-change-stream", append([]string{
+		"PRIMARY_DATABASE_URL=" + primaryURL,
+		"KV_URL=" + kvURL,
+		"GRPC_HOST=127.0.0.1",
+		"GRPC_PORT=" + addr[strings.LastIndex(addr, ":")+1:],
+	}, changeStreamEnv...)...)
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	changeStream := proto.NewChangeStreamClient(conn)
+
+	waiting := poll(ctx, func() bool {
+		status, err := changeStream.GetStatus(ctx, &proto.GetStatusRequest{})
+		return err == nil && status.GetState() == "WAITING"
+	})
+	if !waiting {
+		t.Fatal("timed out waiting for pg-change-stream to wait for a bootstrap")
+	}
+	if _, err := changeStream.StartBootstrap(ctx, &proto.StartBootstrapRequest{StartPosition: startLSN}); err != nil {
+		t.Fatalf("StartBootstrap() error = %v", err)
+	}
+	if _, err := changeStream.CompleteBootstrap(ctx, &proto.CompleteBootstrapRequest{}); err != nil {
+		t.Fatalf("CompleteBootstrap() error = %v", err)
+	}
+
+	configDir, err := filepath.Abs(filepath.Join("testdata", "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, replicaURL := range replicaURLs {
+		startService(t, fmt.Sprintf("translicator-%d", i+1), "translicator", append([]string{
+			"REPLICA_DATABASE_URL=" + replicaURL,
+			"CHANGE_STREAM_SERVICE_ADDR=" + addr,
+			"CONFIG_DIR=" + configDir,
+		}, translicatorEnv...)...)
+	}
+	connected := poll(ctx, func() bool {
+		status, err := changeStream.GetStatus(ctx, &proto.GetStatusRequest{})
+		return err == nil && status.GetState() == "STREAMING" && int(status.GetConnectedClients()) >= len(replicaURLs)
+	})
+	if !connected {
+		t.Fatal("timed out waiting for the translicators to connect")
+	}
+	return p
+}
+
+// waitForUsers waits for every replica to hold exactly the users in want
+func (p *pipeline) waitForUsers(t *testing.T, ctx context.Context, want []user) {
+	t.Helper()
+	for i, replica := range p.replicas {
+		var got []user
+		var err error
+		caughtUp := poll(ctx, func() bool {
+			got, err = readUsers(ctx, replica)
+			return err == nil && equalUsers(got, want)
+		})
+		if !caughtUp {
+			t.Errorf("replica %s has %+v (error %v), want %+v", p.replicaURLs[i], got, err, want)
+		}
+	}
+}
+
+// openDB opens a database by URL with the driver of its dialect
+func openDB(t *testing.T, url string) *sql.DB {
+	t.Helper()
+	d, err := dialect.FromConnectionString(url)
+	if err != nil {
+		t.Fatalf("failed to determine dialect of %s: %v", url, err)
+	}
+	db, err := sql.Open(d.GetDriverName(), d.FormatDSN(url))
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", url, err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func mustExec(t *testing.T, db *sql.DB, statements ...string) {
+	t.Helper()
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("failed to run %q: %v", statement, err)
+		}
+	}
+}
+
+// freeAddr returns a local address nothing listens on
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	return lis.Addr().String()
+}
+
+// startService runs a service binary from E2E_BIN_DIR or the PATH until the test ends,
+// logging its output under name
+func startService(t *testing.T, name, binary string, env ...string) {
+	t.Helper()
+	path := binary
+	if dir := os.Getenv("E2E_BIN_DIR"); dir != "" {
+		path = filepath.Join(dir, binary)
+	}
+
+	cmd := exec.Command(path)
+	cmd.Env = append(os.Environ(), env...)
+	out := &logWriter{t: t, prefix: name}
+	cmd.Stdout, cmd.Stderr = out, out
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start %s: %v", name, err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Signal(syscall.SIGTERM)
+		done := make(chan struct{})
+		go func() {
+			cmd.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			cmd.Process.Kill()
+			<-done
+		}
+	})
+}
+
+// logWriter logs each line a service writes
+type logWriter struct {
+	t      *testing.T
+	prefix string
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		w.t.Logf("[%s] %s", w.prefix, line)
+	}
+	return len(p), nil
+}
+
+// poll calls done until it returns true, and returns false if ctx ends first
+func poll(ctx context.Context, done func() bool) bool {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for !done() {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
+
+func readUsers(ctx context.Context, db *sql.DB) ([]user, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, name, email, plan FROM e2e_users ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var users []user
+	for rows.Next() {
+		var u user
+		if err := rows.Scan(&u.id, &u.name, &u.email, &u.plan); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func equalUsers(a, b []user) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitList splits a comma-separated list, ignoring blank entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,27 @@
+module loadgen
+
+go 1.24.3
+
+require (
+	github.com/go-sql-driver/mysql v1.9.3
+	github.com/lib/pq v1.10.9
+	github.com/spf13/cobra v1.8.1
+	kasho/pkg/dialect v0.0.0
+)
+
+require (
+	filippo.io/edwards25519 v1.1.0 // indirect
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/net v0.40.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/text v0.26.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250218202821-56aae31c358a // indirect
+	google.golang.org/grpc v1.72.1 // indirect
+	google.golang.org/protobuf v1.36.6 // indirect
+	kasho/proto v0.0.0-00010101000000-000000000000 // indirect
+)
+
+replace kasho/pkg/dialect => ../../../pkg/dialect
+
+replace kasho/proto => ../../../proto/kasho/proto
@@ -0,0 +1,258 @@
+package loadgen
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"kasho/pkg/dialect"
+)
+
+// statuses are the values of the generated status column
+var statuses = []string{"pending", "paid", "shipped", "delivered", "cancelled"}
+
+// Config controls the load a Generator puts on the primary
+type Config struct {
+	// Tables picks the table of each statement
+	Tables Weights
+	// Mix picks the kind of each statement: insert, update or delete
+	Mix Weights
+	// Workers is the number of concurrent connections
+	Workers int
+	// Rate is the target number of statements per second across workers; 0 is unlimited
+	Rate float64
+	// Duration is how long to generate load; 0 runs until the context is canceled
+	Duration time.Duration
+	// TxnSize is the number of statements per transaction
+	TxnSize int
+	// PayloadSize is the length of the text payload of inserted rows
+	PayloadSize int
+	// Seed makes the generated statements reproducible; worker n uses Seed+n
+	Seed int64
+}
+
+// Generator runs a mix of inserts, updates and deletes against the generated tables
+type Generator struct {
+	db      *sql.DB
+	dialect dialect.Dialect
+	config  Config
+	tables  map[string]*table
+}
+
+// table is a generated table and the largest key inserted into it
+type table struct {
+	statements statements
+	maxID      atomic.Int64
+}
+
+// NewGenerator creates a generator for a primary using the given dialect
+func NewGenerator(db *sql.DB, d dialect.Dialect, config Config) (*Generator, error) {
+	for _, kind := range config.Mix.Names() {
+		switch kind {
+		case "insert", "update", "delete":
+		default:
+			return nil, fmt.Errorf("invalid statement kind %q: must be insert, update or delete", kind)
+		}
+	}
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.TxnSize <= 0 {
+		config.TxnSize = 1
+	}
+
+	g := &Generator{db: db, dialect: d, config: config, tables: make(map[string]*table)}
+	for _, name := range config.Tables.Names() {
+		g.tables[name] = &table{statements: newStatements(d, name)}
+	}
+	return g, nil
+}
+
+// Setup creates the generated tables if they don't exist, and continues their keys after
+// the rows of earlier runs
+func (g *Generator) Setup(ctx context.Context) error {
+	for _, name := range g.config.Tables.Names() {
+		if _, err := g.db.ExecContext(ctx, CreateTableSQL(g.dialect, name)); err != nil {
+			return fmt.Errorf("failed to create %s: %w", tableName(name), err)
+		}
+		var maxID int64
+		if err := g.db.QueryRowContext(ctx, g.tables[name].statements.maxID).Scan(&maxID); err != nil {
+			return fmt.Errorf("failed to read the largest key of %s: %w", tableName(name), err)
+		}
+		g.tables[name].maxID.Store(maxID)
+	}
+	return nil
+}
+
+// Drop drops the generated tables
+func (g *Generator) Drop(ctx context.Context) error {
+	for _, name := range g.config.Tables.Names() {
+		if _, err := g.db.ExecContext(ctx, DropTableSQL(g.dialect, name)); err != nil {
+			return fmt.Errorf("failed to drop %s: %w", tableName(name), err)
+		}
+	}
+	return nil
+}
+
+// Run generates load until the configured duration has passed or ctx is canceled, and
+// reports the results
+func (g *Generator) Run(ctx context.Context) (*Report, error) {
+	if g.config.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.config.Duration)
+		defer cancel()
+	}
+
+	report := &Report{
+		Dialect:     g.dialect.Name(),
+		Tables:      g.config.Tables.String(),
+		Mix:         g.config.Mix.String(),
+		Workers:     g.config.Workers,
+		Rate:        g.config.Rate,
+		TxnSize:     g.config.TxnSize,
+		PayloadSize: g.config.PayloadSize,
+		Seed:        g.config.Seed,
+		Started:     time.Now().UTC(),
+	}
+
+	stats := make([]*workerStats, g.config.Workers)
+	var wg sync.WaitGroup
+	for i := range stats {
+		stats[i] = newWorkerStats()
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			g.work(ctx, worker, stats[worker])
+		}(i)
+	}
+	wg.Wait()
+
+	report.Duration = time.Since(report.Started).Seconds()
+	report.merge(stats)
+	return report, nil
+}
+
+// work runs transactions until ctx is done. With a rate, each worker runs its share of
+// the transactions at a fixed interval, catching up if it falls behind.
+func (g *Generator) work(ctx context.Context, worker int, stats *workerStats) {
+	r := rand.New(rand.NewSource(g.config.Seed + int64(worker)))
+
+	var interval time.Duration
+	if g.config.Rate > 0 {
+		perWorker := g.config.Rate / float64(g.config.Workers) / float64(g.config.TxnSize)
+		interval = time.Duration(float64(time.Second) / perWorker)
+	}
+	next := time.Now()
+
+	for ctx.Err() == nil {
+		if interval > 0 {
+			if wait := time.Until(next); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(wait):
+				}
+			}
+			next = next.Add(interval)
+		}
+
+		start := time.Now()
+		if err := g.transaction(ctx, r, stats); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			stats.errors++
+			if stats.errors <= 10 {
+				slog.Warn("Transaction failed", "worker", worker, "error", err)
+			}
+			continue
+		}
+		stats.latencies = append(stats.latencies, time.Since(start))
+	}
+}
+
+// transaction runs one transaction of TxnSize statements, or a single statement outside
+// a transaction
+func (g *Generator) transaction(ctx context.Context, r *rand.Rand, stats *workerStats) error {
+	if g.config.TxnSize == 1 {
+		return g.statement(ctx, g.db, r, stats)
+	}
+
+	tx, err := g.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for range g.config.TxnSize {
+		if err := g.statement(ctx, tx, r, stats); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// execer runs statements on the database or in a transaction
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// statement runs one statement of the mix against a table picked by weight
+func (g *Generator) statement(ctx context.Context, db execer, r *rand.Rand, stats *workerStats) error {
+	name := g.config.Tables.Pick(r)
+	t := g.tables[name]
+	kind := g.config.Mix.Pick(r)
+
+	// Updates and deletes target a random key inserted so far; with no rows yet, insert
+	maxID := t.maxID.Load()
+	if maxID == 0 {
+		kind = "insert"
+	}
+
+	var result sql.Result
+	var err error
+	switch kind {
+	case "insert":
+		id := t.maxID.Add(1)
+		result, err = db.ExecContext(ctx, t.statements.insert,
+			id, r.Intn(10000)+1, statuses[r.Intn(len(statuses))], amount(r), payload(r, g.config.PayloadSize), time.Now().UTC())
+	case "update":
+		result, err = db.ExecContext(ctx, t.statements.update,
+			statuses[r.Intn(len(statuses))], amount(r), time.Now().UTC(), r.Int63n(maxID)+1)
+	case "delete":
+		result, err = db.ExecContext(ctx, t.statements.delete, r.Int63n(maxID)+1)
+	}
+	if err != nil {
+		return fmt.Errorf("%s on %s: %w", kind, tableName(name), err)
+	}
+
+	stats.statements[kind]++
+	stats.byTable[name]++
+	if kind != "insert" {
+		if n, err := result.RowsAffected(); err == nil && n == 0 {
+			stats.misses++
+		}
+	}
+	return nil
+}
+
+// amount is a random amount with two decimals
+func amount(r *rand.Rand) float64 {
+	return math.Round(r.Float64()*100000) / 100
+}
+
+const payloadLetters = "abcdefghijklmnopqrstuvwxyz "
+
+// payload is random text of the given length
+func payload(r *rand.Rand, size int) string {
+	b := make([]byte, size)
+	for i := range b {
+		b[i] = payloadLetters[r.Intn(len(payloadLetters))]
+	}
+	return string(b)
+}
@@ -0,0 +1,146 @@
+package loadgen
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"kasho/pkg/dialect"
+)
+
+// fakeDB records the statements it executes; every update and delete affects one row
+type fakeDB struct {
+	mu         sync.Mutex
+	statements []string
+	commits    int
+}
+
+func (f *fakeDB) open() *sql.DB { return sql.OpenDB(fakeConnector{f}) }
+
+type fakeConnector struct{ db *fakeDB }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{c.db}, nil }
+func (c fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return fakeTx{c.db}, nil }
+
+func (c fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.statements = append(c.db.statements, query)
+	return driver.RowsAffected(1), nil
+}
+
+func (c fakeConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &maxIDRows{}, nil
+}
+
+type fakeTx struct{ db *fakeDB }
+
+func (t fakeTx) Commit() error {
+	t.db.mu.Lock()
+	defer t.db.mu.Unlock()
+	t.db.commits++
+	return nil
+}
+
+func (t fakeTx) Rollback() error { return nil }
+
+// maxIDRows answers the largest key query of Setup with 0
+type maxIDRows struct{ done bool }
+
+func (r *maxIDRows) Columns() []string { return []string{"max"} }
+func (r *maxIDRows) Close() error      { return nil }
+
+func (r *maxIDRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(0)
+	return nil
+}
+
+func testGenerator(t *testing.T, db *fakeDB, config Config) *Generator {
+	t.Helper()
+	var err error
+	if config.Tables, err = ParseWeights("orders=3,customers=1"); err != nil {
+		t.Fatal(err)
+	}
+	if config.Mix, err = ParseWeights("insert=50,update=40,delete=10"); err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewGenerator(db.open(), dialect.NewPostgreSQL(), config)
+	if err != nil {
+		t.Fatalf("NewGenerator() error = %v", err)
+	}
+	if err := g.Setup(context.Background()); err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	return g
+}
+
+func TestGeneratorRun(t *testing.T) {
+	db := &fakeDB{}
+	g := testGenerator(t, db, Config{Workers: 2, Rate: 200, Duration: 500 * time.Millisecond, TxnSize: 2, PayloadSize: 10, Seed: 7})
+
+	report, err := g.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	var total int64
+	for _, n := range report.Statements {
+		total += n
+	}
+	// 200 statements per second for half a second, with some slack for slow machines
+	if total < 50 || total > 110 {
+		t.Errorf("ran %d statements, want about 100 at the target rate", total)
+	}
+	if report.Statements["insert"] == 0 || report.Statements["update"] == 0 {
+		t.Errorf("Statements = %v, want inserts and updates", report.Statements)
+	}
+	if report.StatementsByTable["orders"] <= report.StatementsByTable["customers"] {
+		t.Errorf("StatementsByTable = %v, want orders weighted over customers", report.StatementsByTable)
+	}
+	if db.commits == 0 {
+		t.Error("statements weren't run in transactions")
+	}
+	if report.Tables != "orders=3,customers=1" || report.Seed != 7 || report.TxnSize != 2 {
+		t.Errorf("report settings = %+v", report)
+	}
+
+	// The first statement against each table is an insert
+	for _, stmt := range db.statements {
+		if strings.HasPrefix(stmt, "CREATE") {
+			continue
+		}
+		if !strings.HasPrefix(stmt, "INSERT") {
+			t.Errorf("first statement = %s, want an insert", stmt)
+		}
+		break
+	}
+}
+
+func TestNewGeneratorInvalidMix(t *testing.T) {
+	mix, err := ParseWeights("insert=1,truncate=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables, err := ParseWeights("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewGenerator(nil, dialect.NewPostgreSQL(), Config{Tables: tables, Mix: mix}); err == nil {
+		t.Error("NewGenerator() with a truncate mix should fail")
+	}
+}
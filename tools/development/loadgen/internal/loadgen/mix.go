@@ -0,0 +1,78 @@
+package loadgen
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Weights picks names, such as tables or statement kinds, in proportion to their weight
+type Weights struct {
+	names      []string
+	cumulative []int
+}
+
+// ParseWeights parses a comma-separated list of name=weight pairs, e.g.
+// "insert=60,update=30,delete=10". A name without a weight has weight 1.
+func ParseWeights(spec string) (Weights, error) {
+	var w Weights
+	total := 0
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, weightStr, found := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		weight := 1
+		if found {
+			var err error
+			weight, err = strconv.Atoi(strings.TrimSpace(weightStr))
+			if err != nil || weight < 0 {
+				return Weights{}, fmt.Errorf("invalid weight for %s: %q", name, weightStr)
+			}
+		}
+		if name == "" {
+			return Weights{}, fmt.Errorf("missing name in %q", part)
+		}
+		if seen[name] {
+			return Weights{}, fmt.Errorf("%s is listed twice", name)
+		}
+		seen[name] = true
+		if weight == 0 {
+			continue
+		}
+		total += weight
+		w.names = append(w.names, name)
+		w.cumulative = append(w.cumulative, total)
+	}
+	if total == 0 {
+		return Weights{}, fmt.Errorf("no positive weights in %q", spec)
+	}
+	return w, nil
+}
+
+// Names returns the names with a positive weight, in the order they were listed
+func (w Weights) Names() []string {
+	return w.names
+}
+
+// Pick returns a name at random, in proportion to the weights
+func (w Weights) Pick(r *rand.Rand) string {
+	n := r.Intn(w.cumulative[len(w.cumulative)-1])
+	return w.names[sort.SearchInts(w.cumulative, n+1)]
+}
+
+// String formats the weights as they are parsed
+func (w Weights) String() string {
+	parts := make([]string, len(w.names))
+	previous := 0
+	for i, name := range w.names {
+		parts[i] = fmt.Sprintf("%s=%d", name, w.cumulative[i]-previous)
+		previous = w.cumulative[i]
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,68 @@
+package loadgen
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestParseWeights(t *testing.T) {
+	tests := []struct {
+		spec      string
+		wantNames []string
+		wantSpec  string
+		wantErr   bool
+	}{
+		{spec: "insert=60,update=30,delete=10", wantNames: []string{"insert", "update", "delete"}, wantSpec: "insert=60,update=30,delete=10"},
+		{spec: " orders = 2 , customers ", wantNames: []string{"orders", "customers"}, wantSpec: "orders=2,customers=1"},
+		{spec: "insert=1,delete=0", wantNames: []string{"insert"}, wantSpec: "insert=1"},
+		{spec: "", wantErr: true},
+		{spec: "insert=0", wantErr: true},
+		{spec: "insert=-1", wantErr: true},
+		{spec: "insert=x", wantErr: true},
+		{spec: "=5", wantErr: true},
+		{spec: "orders,orders", wantErr: true},
+	}
+	for _, tt := range tests {
+		w, err := ParseWeights(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseWeights(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if !reflect.DeepEqual(w.Names(), tt.wantNames) {
+			t.Errorf("ParseWeights(%q).Names() = %v, want %v", tt.spec, w.Names(), tt.wantNames)
+		}
+		if got := w.String(); got != tt.wantSpec {
+			t.Errorf("ParseWeights(%q).String() = %q, want %q", tt.spec, got, tt.wantSpec)
+		}
+	}
+}
+
+func TestWeightsPick(t *testing.T) {
+	w, err := ParseWeights("insert=70,update=20,delete=10")
+	if err != nil {
+		t.Fatalf("ParseWeights() error = %v", err)
+	}
+
+	counts := make(map[string]int)
+	r := rand.New(rand.NewSource(1))
+	for range 100000 {
+		counts[w.Pick(r)]++
+	}
+	for name, want := range map[string]int{"insert": 70000, "update": 20000, "delete": 10000} {
+		if got := counts[name]; got < want*95/100 || got > want*105/100 {
+			t.Errorf("picked %s %d times, want about %d", name, got, want)
+		}
+	}
+
+	// The same seed picks the same sequence
+	a, b := rand.New(rand.NewSource(42)), rand.New(rand.NewSource(42))
+	for range 100 {
+		if w.Pick(a) != w.Pick(b) {
+			t.Fatal("Pick() with the same seed picked differently")
+		}
+	}
+}
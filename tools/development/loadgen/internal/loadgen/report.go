@@ -0,0 +1,110 @@
+package loadgen
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"slices"
+	"time"
+)
+
+// Report summarizes a load generator run. It records the settings along with the results
+// so that a run can be repeated and compared.
+type Report struct {
+	Dialect     string    `json:"dialect"`
+	Tables      string    `json:"tables"`
+	Mix         string    `json:"mix"`
+	Workers     int       `json:"workers"`
+	Rate        float64   `json:"rate"`
+	TxnSize     int       `json:"txn_size"`
+	PayloadSize int       `json:"payload_size"`
+	Seed        int64     `json:"seed"`
+	Started     time.Time `json:"started"`
+	Duration    float64   `json:"duration_seconds"`
+
+	// Statements counts the statements executed by kind and by table
+	Statements        map[string]int64 `json:"statements"`
+	StatementsByTable map[string]int64 `json:"statements_by_table"`
+	// Misses counts updates and deletes of rows that were already deleted
+	Misses int64 `json:"misses"`
+	// Errors counts failed transactions
+	Errors int64 `json:"errors"`
+	// Throughput is the statements executed per second
+	Throughput float64 `json:"throughput"`
+	// Latency is the distribution of transaction latencies, in milliseconds
+	Latency Latency `json:"latency_ms"`
+}
+
+// Latency is a latency distribution in milliseconds
+type Latency struct {
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// Write writes the report as indented JSON
+func (r *Report) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// workerStats are the results of one worker, merged into the report when the run ends
+type workerStats struct {
+	latencies  []time.Duration
+	statements map[string]int64
+	byTable    map[string]int64
+	misses     int64
+	errors     int64
+}
+
+func newWorkerStats() *workerStats {
+	return &workerStats{statements: make(map[string]int64), byTable: make(map[string]int64)}
+}
+
+// merge adds the results of the workers to the report
+func (r *Report) merge(stats []*workerStats) {
+	r.Statements = make(map[string]int64)
+	r.StatementsByTable = make(map[string]int64)
+	var latencies []time.Duration
+	var total int64
+	for _, s := range stats {
+		for kind, n := range s.statements {
+			r.Statements[kind] += n
+			total += n
+		}
+		for table, n := range s.byTable {
+			r.StatementsByTable[table] += n
+		}
+		r.Misses += s.misses
+		r.Errors += s.errors
+		latencies = append(latencies, s.latencies...)
+	}
+	if r.Duration > 0 {
+		r.Throughput = float64(total) / r.Duration
+	}
+	r.Latency = latencyDistribution(latencies)
+}
+
+// latencyDistribution returns the percentiles of the latencies, using the nearest rank
+func latencyDistribution(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	slices.Sort(latencies)
+	percentile := func(p float64) float64 {
+		rank := int(math.Ceil(p*float64(len(latencies)))) - 1
+		return milliseconds(latencies[max(rank, 0)])
+	}
+	return Latency{
+		P50: percentile(0.50),
+		P95: percentile(0.95),
+		P99: percentile(0.99),
+		Max: milliseconds(latencies[len(latencies)-1]),
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
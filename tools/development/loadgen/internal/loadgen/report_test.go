@@ -0,0 +1,65 @@
+package loadgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestLatencyDistribution(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	got := latencyDistribution(latencies)
+	want := Latency{P50: 50, P95: 95, P99: 99, Max: 100}
+	if got != want {
+		t.Errorf("latencyDistribution() = %+v, want %+v", got, want)
+	}
+
+	if got := latencyDistribution(nil); got != (Latency{}) {
+		t.Errorf("latencyDistribution(nil) = %+v, want zero", got)
+	}
+}
+
+func TestReportMerge(t *testing.T) {
+	a, b := newWorkerStats(), newWorkerStats()
+	a.statements["insert"], a.byTable["orders"] = 30, 30
+	a.latencies = []time.Duration{time.Millisecond, 2 * time.Millisecond}
+	b.statements["insert"], b.statements["update"] = 10, 20
+	b.byTable["orders"], b.byTable["customers"] = 25, 5
+	b.misses, b.errors = 2, 1
+	b.latencies = []time.Duration{3 * time.Millisecond}
+
+	report := &Report{Duration: 2}
+	report.merge([]*workerStats{a, b})
+
+	if report.Statements["insert"] != 40 || report.Statements["update"] != 20 {
+		t.Errorf("Statements = %v, want 40 inserts and 20 updates", report.Statements)
+	}
+	if report.StatementsByTable["orders"] != 55 || report.StatementsByTable["customers"] != 5 {
+		t.Errorf("StatementsByTable = %v", report.StatementsByTable)
+	}
+	if report.Misses != 2 || report.Errors != 1 {
+		t.Errorf("Misses, Errors = %d, %d, want 2, 1", report.Misses, report.Errors)
+	}
+	if report.Throughput != 30 {
+		t.Errorf("Throughput = %v, want 30", report.Throughput)
+	}
+	if report.Latency.Max != 3 {
+		t.Errorf("Latency.Max = %v, want 3", report.Latency.Max)
+	}
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("report isn't valid JSON: %v", err)
+	}
+	if _, ok := decoded["latency_ms"]; !ok {
+		t.Errorf("report = %s, want latency_ms", buf.String())
+	}
+}
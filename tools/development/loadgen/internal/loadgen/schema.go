@@ -0,0 +1,63 @@
+package loadgen
+
+import (
+	"fmt"
+	"strings"
+
+	"kasho/pkg/dialect"
+)
+
+// TablePrefix prefixes the tables the load generator creates, so they can't be mistaken
+// for application tables
+const TablePrefix = "loadgen_"
+
+// tableName is the name of a generated table
+func tableName(name string) string {
+	return TablePrefix + name
+}
+
+// CreateTableSQL returns the DDL of a generated table. Every table has the same columns:
+// an integer key, a few typical scalar columns and a text payload.
+func CreateTableSQL(d dialect.Dialect, name string) string {
+	columns := []string{
+		fmt.Sprintf("%s BIGINT PRIMARY KEY", d.QuoteIdentifier("id")),
+		fmt.Sprintf("%s %s NOT NULL", d.QuoteIdentifier("account_id"), d.TypeInteger()),
+		fmt.Sprintf("%s VARCHAR(32) NOT NULL", d.QuoteIdentifier("status")),
+		fmt.Sprintf("%s %s NOT NULL", d.QuoteIdentifier("amount"), d.TypeDecimal(12, 2)),
+		fmt.Sprintf("%s %s", d.QuoteIdentifier("payload"), d.TypeText()),
+		fmt.Sprintf("%s %s NOT NULL", d.QuoteIdentifier("updated_at"), d.TypeTimestamp()),
+	}
+	ddl := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", d.QuoteIdentifier(tableName(name)), strings.Join(columns, ", "))
+	if d.Name() == "mysql" {
+		ddl += " ENGINE=InnoDB"
+	}
+	return ddl
+}
+
+// DropTableSQL returns the DDL that drops a generated table
+func DropTableSQL(d dialect.Dialect, name string) string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS %s", d.QuoteIdentifier(tableName(name)))
+}
+
+// statements are the DML statements of a generated table
+type statements struct {
+	insert string
+	update string
+	delete string
+	maxID  string
+}
+
+func newStatements(d dialect.Dialect, name string) statements {
+	table := d.QuoteIdentifier(tableName(name))
+	q := d.QuoteIdentifier
+	p := d.Placeholder
+	return statements{
+		insert: fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s, %s, %s) VALUES (%s, %s, %s, %s, %s, %s)",
+			table, q("id"), q("account_id"), q("status"), q("amount"), q("payload"), q("updated_at"),
+			p(1), p(2), p(3), p(4), p(5), p(6)),
+		update: fmt.Sprintf("UPDATE %s SET %s = %s, %s = %s, %s = %s WHERE %s = %s",
+			table, q("status"), p(1), q("amount"), p(2), q("updated_at"), p(3), q("id"), p(4)),
+		delete: fmt.Sprintf("DELETE FROM %s WHERE %s = %s", table, q("id"), p(1)),
+		maxID:  fmt.Sprintf("SELECT COALESCE(MAX(%s), 0) FROM %s", q("id"), table),
+	}
+}
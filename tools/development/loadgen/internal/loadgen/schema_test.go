@@ -0,0 +1,50 @@
+package loadgen
+
+import (
+	"testing"
+
+	"kasho/pkg/dialect"
+)
+
+func TestCreateTableSQL(t *testing.T) {
+	pg := CreateTableSQL(dialect.NewPostgreSQL(), "orders")
+	if want := `CREATE TABLE IF NOT EXISTS "loadgen_orders" (`; pg[:len(want)] != want {
+		t.Errorf("PostgreSQL DDL = %s", pg)
+	}
+
+	mysql := CreateTableSQL(dialect.NewMySQL(), "orders")
+	if want := "CREATE TABLE IF NOT EXISTS `loadgen_orders` ("; mysql[:len(want)] != want {
+		t.Errorf("MySQL DDL = %s", mysql)
+	}
+	if want := " ENGINE=InnoDB"; mysql[len(mysql)-len(want):] != want {
+		t.Errorf("MySQL DDL = %s, want InnoDB", mysql)
+	}
+}
+
+func TestStatements(t *testing.T) {
+	tests := []struct {
+		dialect    dialect.Dialect
+		wantUpdate string
+		wantDelete string
+	}{
+		{
+			dialect:    dialect.NewPostgreSQL(),
+			wantUpdate: `UPDATE "loadgen_orders" SET "status" = $1, "amount" = $2, "updated_at" = $3 WHERE "id" = $4`,
+			wantDelete: `DELETE FROM "loadgen_orders" WHERE "id" = $1`,
+		},
+		{
+			dialect:    dialect.NewMySQL(),
+			wantUpdate: "UPDATE `loadgen_orders` SET `status` = ?, `amount` = ?, `updated_at` = ? WHERE `id` = ?",
+			wantDelete: "DELETE FROM `loadgen_orders` WHERE `id` = ?",
+		},
+	}
+	for _, tt := range tests {
+		s := newStatements(tt.dialect, "orders")
+		if s.update != tt.wantUpdate {
+			t.Errorf("%s update = %s, want %s", tt.dialect.Name(), s.update, tt.wantUpdate)
+		}
+		if s.delete != tt.wantDelete {
+			t.Errorf("%s delete = %s, want %s", tt.dialect.Name(), s.delete, tt.wantDelete)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	"github.com/spf13/cobra"
+	"kasho/pkg/dialect"
+	"loadgen/internal/loadgen"
+)
+
+var (
+	primaryURL  string
+	tables      string
+	mix         string
+	workers     int
+	rate        float64
+	duration    time.Duration
+	txnSize     int
+	payloadSize int
+	seed        int64
+	drop        bool
+	reportFile  string
+)
+
+func main() {
+	rootCmd := &cobra.Command{
+		Use:   "loadgen",
+		Short: "Generate synthetic DML against a primary",
+		Long: `loadgen runs a configurable mix of inserts, updates and deletes against tables it
+creates on a primary database (named loadgen_<table>), so the whole pipeline can be
+measured under a known, reproducible load. The same seed and settings produce the same
+statements.
+
+When the run ends, a JSON report of the settings, statement counts, throughput and
+transaction latency percentiles is written, for comparison between runs.`,
+		RunE: runLoadgen,
+	}
+
+	rootCmd.Flags().StringVarP(&primaryURL, "primary-url", "p", os.Getenv("PRIMARY_DATABASE_URL"), "Primary database connection URL (default $PRIMARY_DATABASE_URL)")
+	rootCmd.Flags().StringVarP(&tables, "tables", "t", "orders=6,customers=3,events=1", "Tables to write to, with relative weights")
+	rootCmd.Flags().StringVarP(&mix, "mix", "m", "insert=50,update=40,delete=10", "Statement kinds, with relative weights")
+	rootCmd.Flags().IntVarP(&workers, "workers", "w", 4, "Concurrent connections")
+	rootCmd.Flags().Float64VarP(&rate, "rate", "r", 100, "Target statements per second across workers (0 = unlimited)")
+	rootCmd.Flags().DurationVarP(&duration, "duration", "d", time.Minute, "How long to generate load (0 = until interrupted)")
+	rootCmd.Flags().IntVar(&txnSize, "txn-size", 1, "Statements per transaction")
+	rootCmd.Flags().IntVar(&payloadSize, "payload-size", 200, "Length of the text payload of inserted rows")
+	rootCmd.Flags().Int64Var(&seed, "seed", 1, "Random seed")
+	rootCmd.Flags().BoolVar(&drop, "drop", false, "Drop the generated tables when the run ends")
+	rootCmd.Flags().StringVarP(&reportFile, "report", "o", "-", "File to write the JSON report to (- for stdout)")
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runLoadgen(cmd *cobra.Command, args []string) error {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if primaryURL == "" {
+		return fmt.Errorf("--primary-url or PRIMARY_DATABASE_URL is required")
+	}
+	tableWeights, err := loadgen.ParseWeights(tables)
+	if err != nil {
+		return fmt.Errorf("invalid --tables: %w", err)
+	}
+	mixWeights, err := loadgen.ParseWeights(mix)
+	if err != nil {
+		return fmt.Errorf("invalid --mix: %w", err)
+	}
+
+	primaryDialect, err := dialect.FromConnectionString(primaryURL)
+	if err != nil {
+		return fmt.Errorf("failed to determine primary dialect: %w", err)
+	}
+	if name := primaryDialect.Name(); name != "postgresql" && name != "mysql" {
+		return fmt.Errorf("unsupported primary database: %s", name)
+	}
+
+	primary, err := sql.Open(primaryDialect.GetDriverName(), primaryDialect.FormatDSN(primaryURL))
+	if err != nil {
+		return fmt.Errorf("failed to open primary: %w", err)
+	}
+	defer primary.Close()
+	primary.SetMaxOpenConns(workers)
+	primary.SetMaxIdleConns(workers)
+
+	generator, err := loadgen.NewGenerator(primary, primaryDialect, loadgen.Config{
+		Tables:      tableWeights,
+		Mix:         mixWeights,
+		Workers:     workers,
+		Rate:        rate,
+		Duration:    duration,
+		TxnSize:     txnSize,
+		PayloadSize: payloadSize,
+		Seed:        seed,
+	})
+	if err != nil {
+		return err
+	}
+	if err := generator.Setup(ctx); err != nil {
+		return err
+	}
+
+	slog.Info("Generating load",
+		"dialect", primaryDialect.Name(),
+		"tables", tableWeights.String(),
+		"mix", mixWeights.String(),
+		"workers", workers,
+		"rate", rate,
+		"duration", duration,
+	)
+	report, err := generator.Run(ctx)
+	if err != nil {
+		return err
+	}
+	slog.Info("Load generation completed",
+		"throughput", fmt.Sprintf("%.1f/s", report.Throughput),
+		"p50_ms", report.Latency.P50,
+		"p99_ms", report.Latency.P99,
+		"errors", report.Errors,
+	)
+
+	if drop {
+		// The run's context may be canceled by now
+		if err := generator.Drop(context.Background()); err != nil {
+			return err
+		}
+	}
+
+	out := os.Stdout
+	if reportFile != "-" {
+		out, err = os.Create(reportFile)
+		if err != nil {
+			return fmt.Errorf("failed to create report: %w", err)
+		}
+		defer out.Close()
+	}
+	if err := report.Write(out); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	if report.Errors > 0 {
+		return fmt.Errorf("%d transactions failed", report.Errors)
+	}
+	return nil
+}
@@ -20,7 +20,21 @@ func EnvMap() map[string]interface{} {
 	return envMap
 }
 
-func processTemplate(templatePath string, envMap map[string]interface{}) error {
+// outputPathFor returns where a template is rendered: next to it without the extension, or
+// at the same path relative to its source directory under outDir
+func outputPathFor(dir, templatePath, ext, outDir string) (string, error) {
+	outputPath := strings.TrimSuffix(templatePath, ext)
+	if outDir == "" {
+		return outputPath, nil
+	}
+	rel, err := filepath.Rel(dir, outputPath)
+	if err != nil {
+		return "", fmt.Errorf("error resolving output path: %w", err)
+	}
+	return filepath.Join(outDir, rel), nil
+}
+
+func processTemplate(templatePath, outputPath string, envMap map[string]interface{}, diff bool) error {
 	tmpl, err := template.ParseFiles(templatePath)
 	if err != nil {
 		return fmt.Errorf("error parsing template: %w", err)
@@ -31,8 +45,14 @@ func processTemplate(templatePath string, envMap map[string]interface{}) error {
 		return fmt.Errorf("error executing template: %w", err)
 	}
 
-	// Write to output file (remove .template extension)
-	outputPath := templatePath[:len(templatePath)-9] // Remove .template
+	if diff {
+		return printDiff(outputPath, buf.Bytes())
+	}
+
+	// Write to output file, creating its directory under the output directory
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("error creating output directory: %w", err)
+	}
 	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
 		return fmt.Errorf("error writing output file: %w", err)
 	}
@@ -41,15 +61,104 @@ func processTemplate(templatePath string, envMap map[string]interface{}) error {
 	return nil
 }
 
+// printDiff prints how rendering would change an output file, without writing it
+func printDiff(outputPath string, rendered []byte) error {
+	current, err := os.ReadFile(outputPath)
+	if os.IsNotExist(err) {
+		fmt.Printf("Would create %s\n", outputPath)
+		for _, line := range splitLines(rendered) {
+			fmt.Printf("+%s\n", line)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading output file: %w", err)
+	}
+	if bytes.Equal(current, rendered) {
+		fmt.Printf("No changes to %s\n", outputPath)
+		return nil
+	}
+
+	fmt.Printf("--- %s\n+++ %s (rendered)\n", outputPath, outputPath)
+	for _, line := range diffLines(splitLines(current), splitLines(rendered)) {
+		fmt.Println(line)
+	}
+	return nil
+}
+
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// diffLines compares two files line by line through their longest common subsequence,
+// returning the removed lines prefixed with -, the added lines with +, and a @@ marker with
+// the current file's line number before each run of changes
+func diffLines(current, rendered []string) []string {
+	// lcs[i][j] is the length of the longest common subsequence of current[i:] and rendered[j:]
+	lcs := make([][]int, len(current)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(rendered)+1)
+	}
+	for i := len(current) - 1; i >= 0; i-- {
+		for j := len(rendered) - 1; j >= 0; j-- {
+			if current[i] == rendered[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []string
+	inChange := false
+	mark := func(i int) {
+		if !inChange {
+			out = append(out, fmt.Sprintf("@@ line %d", i+1))
+			inChange = true
+		}
+	}
+	i, j := 0, 0
+	for i < len(current) || j < len(rendered) {
+		switch {
+		case i < len(current) && j < len(rendered) && current[i] == rendered[j]:
+			inChange = false
+			i++
+			j++
+		case i < len(current) && (j == len(rendered) || lcs[i+1][j] >= lcs[i][j+1]):
+			mark(i)
+			out = append(out, "-"+current[i])
+			i++
+		default:
+			mark(i)
+			out = append(out, "+"+rendered[j])
+			j++
+		}
+	}
+	return out
+}
+
 func main() {
 	// Define command line flags
 	templateDirs := flag.String("dirs", "", "Comma-separated list of directories to process")
+	ext := flag.String("ext", ".template", "Extension of the template files, removed from the output file names")
+	outDir := flag.String("out", "", "Directory to write rendered files to, at their paths relative to their source directory (default: next to each template)")
+	diff := flag.Bool("diff", false, "Print how rendering would change the output files, without writing them")
 	flag.Parse()
 
 	if *templateDirs == "" {
 		fmt.Println("Error: --dirs flag is required")
 		os.Exit(1)
 	}
+	if strings.Trim(*ext, ".") == "" {
+		fmt.Println("Error: --ext must not be empty")
+		os.Exit(1)
+	}
+	if !strings.HasPrefix(*ext, ".") {
+		*ext = "." + *ext
+	}
 
 	// Get environment variables
 	envMap := EnvMap()
@@ -69,7 +178,7 @@ func main() {
 			if info.IsDir() {
 				return nil
 			}
-			if strings.HasSuffix(path, ".template") {
+			if strings.HasSuffix(path, *ext) {
 				templateFiles = append(templateFiles, path)
 			}
 			return nil
@@ -87,7 +196,11 @@ func main() {
 
 		// Process each template file
 		for _, file := range templateFiles {
-			if err := processTemplate(file, envMap); err != nil {
+			outputPath, err := outputPathFor(dir, file, *ext, *outDir)
+			if err == nil {
+				err = processTemplate(file, outputPath, envMap, *diff)
+			}
+			if err != nil {
 				fmt.Printf("Error processing %s: %v\n", file, err)
 			}
 		}
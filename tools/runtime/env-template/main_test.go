@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOutputPathFor(t *testing.T) {
+	tests := []struct {
+		name         string
+		dir          string
+		templatePath string
+		ext          string
+		outDir       string
+		want         string
+	}{
+		{"next to template", "config", "config/app.yml.template", ".template", "", "config/app.yml"},
+		{"custom extension", "config", "config/app.yml.tmpl", ".tmpl", "", "config/app.yml"},
+		{"output directory", "config", "config/app.yml.template", ".template", "/tmp/out", "/tmp/out/app.yml"},
+		{"nested under output directory", "config", "config/services/db.env.template", ".template", "out", "out/services/db.env"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := outputPathFor(tt.dir, tt.templatePath, tt.ext, tt.outDir)
+			if err != nil {
+				t.Fatalf("outputPathFor() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("outputPathFor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  []string
+		rendered []string
+		want     []string
+	}{
+		{"unchanged", []string{"a", "b"}, []string{"a", "b"}, nil},
+		{"changed line", []string{"a", "b", "c"}, []string{"a", "x", "c"}, []string{"@@ line 2", "-b", "+x"}},
+		{"added line", []string{"a"}, []string{"a", "b"}, []string{"@@ line 2", "+b"}},
+		{"removed line", []string{"a", "b"}, []string{"b"}, []string{"@@ line 1", "-a"}},
+		{"separate changes", []string{"a", "b", "c"}, []string{"x", "b", "y"}, []string{"@@ line 1", "-a", "+x", "@@ line 3", "-c", "+y"}},
+		{"new file", nil, []string{"a"}, []string{"@@ line 1", "+a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines(tt.current, tt.rendered); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessTemplate(t *testing.T) {
+	dir := t.TempDir()
+	templatePath := filepath.Join(dir, "app.env.tmpl")
+	if err := os.WriteFile(templatePath, []byte("HOST={{.HOST}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputPath := filepath.Join(dir, "out", "app.env")
+	env := map[string]interface{}{"HOST": "db"}
+
+	// With --diff nothing is written
+	if err := processTemplate(templatePath, outputPath, env, true); err != nil {
+		t.Fatalf("processTemplate() with diff unexpected error: %v", err)
+	}
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Errorf("processTemplate() with diff wrote %s", outputPath)
+	}
+
+	// Otherwise the output directory is created
+	if err := processTemplate(templatePath, outputPath, env, false); err != nil {
+		t.Fatalf("processTemplate() unexpected error: %v", err)
+	}
+	got, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	if string(got) != "HOST=db\n" {
+		t.Errorf("output = %q, want %q", got, "HOST=db\n")
+	}
+}
@@ -0,0 +1,10 @@
+module kasho-approvals
+
+go 1.24.3
+
+require github.com/spf13/cobra v1.8.1
+
+require (
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+)
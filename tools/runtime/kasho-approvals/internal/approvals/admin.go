@@ -0,0 +1,129 @@
+package approvals
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Item is a change the translicator holds for approval, as reported by /admin/approvals
+type Item struct {
+	ID            int64      `json:"id"`
+	Kind          string     `json:"kind"`
+	Position      string     `json:"position"`
+	TransactionID string     `json:"transaction_id,omitempty"`
+	Table         string     `json:"table,omitempty"`
+	Statement     string     `json:"statement,omitempty"`
+	Rows          int        `json:"rows,omitempty"`
+	Reason        string     `json:"reason"`
+	HeldAt        time.Time  `json:"held_at"`
+	Deadline      *time.Time `json:"deadline,omitempty"`
+	Decision      string     `json:"decision,omitempty"`
+	DecidedBy     string     `json:"decided_by,omitempty"`
+	DecidedAt     *time.Time `json:"decided_at,omitempty"`
+}
+
+// List is the translicator's /admin/approvals response
+type List struct {
+	Pending []Item `json:"pending"`
+	Decided []Item `json:"decided"`
+}
+
+// Admin talks to the translicator's admin server (ADMIN_ADDR)
+type Admin struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewAdmin creates an admin client for the admin server at baseURL, e.g. http://translicator:9091
+func NewAdmin(baseURL string) *Admin {
+	return &Admin{baseURL: strings.TrimSuffix(baseURL, "/"), client: http.DefaultClient}
+}
+
+// List returns the pending and recently decided items
+func (a *Admin) List(ctx context.Context) (List, error) {
+	body, err := a.do(ctx, http.MethodGet, "/admin/approvals")
+	if err != nil {
+		return List{}, err
+	}
+	var list List
+	if err := json.Unmarshal(body, &list); err != nil {
+		return List{}, fmt.Errorf("failed to decode approvals: %w", err)
+	}
+	return list, nil
+}
+
+// Decide approves or rejects a pending item and returns it
+func (a *Admin) Decide(ctx context.Context, id int64, approve bool) (Item, error) {
+	action := "reject"
+	if approve {
+		action = "approve"
+	}
+	body, err := a.do(ctx, http.MethodPost, fmt.Sprintf("/admin/approvals/%d/%s", id, action))
+	if err != nil {
+		return Item{}, err
+	}
+	var item Item
+	if err := json.Unmarshal(body, &item); err != nil {
+		return Item{}, fmt.Errorf("failed to decode approval: %w", err)
+	}
+	return item, nil
+}
+
+func (a *Admin) do(ctx context.Context, method, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// Write prints items as a table, one row each
+func Write(w io.Writer, items []Item) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tKIND\tPOSITION\tHELD\tDECISION\tREASON\tCHANGE")
+	for _, item := range items {
+		decision := item.Decision
+		switch {
+		case decision != "":
+			decision += " by " + item.DecidedBy
+		case item.Deadline != nil:
+			decision = "pending until " + item.Deadline.Format(time.RFC3339)
+		default:
+			decision = "pending"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", item.ID, item.Kind, item.Position,
+			item.HeldAt.Format(time.RFC3339), decision, item.Reason, describe(item))
+	}
+	return tw.Flush()
+}
+
+// describe summarizes what an item would change
+func describe(item Item) string {
+	if item.Kind == "delete" {
+		return fmt.Sprintf("%d+ deletes from %s in transaction %s", item.Rows, item.Table, item.TransactionID)
+	}
+	statement := strings.Join(strings.Fields(item.Statement), " ")
+	if len(statement) > 80 {
+		statement = statement[:77] + "..."
+	}
+	return statement
+}
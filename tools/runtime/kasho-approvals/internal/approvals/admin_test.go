@@ -0,0 +1,63 @@
+package approvals
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdmin(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/admin/approvals":
+			w.Write([]byte(`{"pending":[{"id":2,"kind":"delete","position":"0/200","transaction_id":"42","table":"public.orders","rows":10001,"reason":"more than 10000 deletes in one transaction","held_at":"2026-10-16T10:00:00Z"}],` +
+				`"decided":[{"id":1,"kind":"ddl","position":"0/100","statement":"DROP TABLE\n  users","reason":"APPROVAL_DDL is set","held_at":"2026-10-16T09:00:00Z","decision":"approved","decided_by":"admin"}]}`))
+		case "/admin/approvals/2/reject":
+			w.Write([]byte(`{"id":2,"kind":"delete","position":"0/200","decision":"rejected","decided_by":"admin"}`))
+		default:
+			http.Error(w, "no pending item with that ID", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	admin := NewAdmin(server.URL + "/")
+	list, err := admin.List(ctx)
+	if err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	if len(list.Pending) != 1 || list.Pending[0].Rows != 10001 || len(list.Decided) != 1 {
+		t.Fatalf("List() = %+v, want one pending and one decided item", list)
+	}
+
+	var out bytes.Buffer
+	if err := Write(&out, append(list.Pending, list.Decided...)); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	for _, want := range []string{"10001+ deletes from public.orders in transaction 42", "DROP TABLE users", "approved by admin", "pending"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Write() output is missing %q:\n%s", want, out.String())
+		}
+	}
+
+	item, err := admin.Decide(ctx, 2, false)
+	if err != nil {
+		t.Fatalf("Decide() unexpected error: %v", err)
+	}
+	if item.Decision != "rejected" {
+		t.Errorf("Decide() = %+v, want rejected", item)
+	}
+	if _, err := admin.Decide(ctx, 9, true); err == nil || !strings.Contains(err.Error(), "no pending item") {
+		t.Errorf("Decide() of an unknown item: error = %v, want the server's message", err)
+	}
+
+	want := []string{"GET /admin/approvals", "POST /admin/approvals/2/reject", "POST /admin/approvals/9/approve"}
+	if strings.Join(requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"kasho-approvals/internal/approvals"
+)
+
+var (
+	adminURL string
+	all      bool
+)
+
+func main() {
+	rootCmd := &cobra.Command{
+		Use:   "kasho-approvals",
+		Short: "Review changes the translicator holds for approval",
+		Long: `kasho-approvals lists the DDL statements and mass deletes a translicator holds for
+approval (APPROVAL_DDL, APPROVAL_DELETE_THRESHOLD or ddl rules with action pause), and
+approves or rejects them. The translicator resumes once the held change is decided.`,
+		SilenceUsage: true,
+	}
+	rootCmd.PersistentFlags().StringVarP(&adminURL, "admin-url", "a", "http://127.0.0.1:9091", "Translicator admin server URL")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "Show the changes waiting for a decision",
+		Args:  cobra.NoArgs,
+		RunE:  runList,
+	}
+	listCmd.Flags().BoolVar(&all, "all", false, "Show recently decided changes too")
+
+	approveCmd := &cobra.Command{
+		Use:   "approve ID",
+		Short: "Apply a held change and resume",
+		Args:  cobra.ExactArgs(1),
+		RunE:  func(cmd *cobra.Command, args []string) error { return runDecide(args[0], true) },
+	}
+
+	rejectCmd := &cobra.Command{
+		Use:   "reject ID",
+		Short: "Skip a held change and resume",
+		Args:  cobra.ExactArgs(1),
+		RunE:  func(cmd *cobra.Command, args []string) error { return runDecide(args[0], false) },
+	}
+
+	rootCmd.AddCommand(listCmd, approveCmd, rejectCmd)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	list, err := approvals.NewAdmin(adminURL).List(ctx)
+	if err != nil {
+		return err
+	}
+	items := list.Pending
+	if all {
+		items = append(items, list.Decided...)
+	}
+	if len(items) == 0 {
+		fmt.Println("No changes are held for approval")
+		return nil
+	}
+	return approvals.Write(os.Stdout, items)
+}
+
+func runDecide(arg string, approve bool) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid ID %q", arg)
+	}
+	item, err := approvals.NewAdmin(adminURL).Decide(ctx, id, approve)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s at %s %s\n", item.Kind, item.Position, item.Decision)
+	return nil
+}
@@ -0,0 +1,59 @@
+module kasho-cli
+
+go 1.24.3
+
+require (
+	github.com/alicebob/miniredis/v2 v2.39.0
+	github.com/fergusstrange/embedded-postgres v1.34.0
+	github.com/jackc/pgx/v5 v5.5.4
+	github.com/spf13/cobra v1.8.1
+	google.golang.org/grpc v1.72.1
+	google.golang.org/protobuf v1.36.6
+	kasho/pkg/dialect v0.0.0
+	kasho/pkg/kvbuffer v0.0.0
+	kasho/pkg/transform v0.0.0
+	kasho/pkg/types v0.0.0
+	kasho/pkg/version v0.0.0
+	kasho/proto v0.0.0-00010101000000-000000000000
+)
+
+require (
+	cel.dev/expr v0.20.0 // indirect
+	github.com/antlr4-go/antlr/v4 v4.13.0 // indirect
+	github.com/brianvoe/gofakeit/v7 v7.0.2 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/google/cel-go v0.23.2 // indirect
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/jackc/pgio v1.0.0 // indirect
+	github.com/jackc/pglogrepl v0.0.0-20250509230407-a9884f6bd75a // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a // indirect
+	github.com/lib/pq v1.12.3 // indirect
+	github.com/redis/go-redis/v9 v9.8.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+	github.com/stoewer/go-strcase v1.2.0 // indirect
+	github.com/tetratelabs/wazero v1.9.0 // indirect
+	github.com/xi2/xz v0.0.0-20171230120015-48954b6210f8 // indirect
+	github.com/yuin/gopher-lua v1.1.1 // indirect
+	golang.org/x/crypto v0.39.0 // indirect
+	golang.org/x/exp v0.0.0-20240823005443-9b4947da3948 // indirect
+	golang.org/x/net v0.40.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/text v0.26.0 // indirect
+	google.golang.org/genproto/googleapis/api v0.0.0-20250218202821-56aae31c358a // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250218202821-56aae31c358a // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace kasho/pkg/dialect => ../../../pkg/dialect
+
+replace kasho/pkg/kvbuffer => ../../../pkg/kvbuffer
+
+replace kasho/pkg/transform => ../../../pkg/transform
+
+replace kasho/pkg/types => ../../../pkg/types
+
+replace kasho/pkg/version => ../../../pkg/version
+
+replace kasho/proto => ../../../proto/kasho/proto
@@ -0,0 +1,131 @@
+package buffer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"kasho/pkg/kvbuffer"
+	"kasho/pkg/types"
+)
+
+// pageSize is the number of changes read from Redis at a time when exporting
+const pageSize = 1000
+
+// maxLineSize is the longest line of an export file accepted by Import
+const maxLineSize = 64 * 1024 * 1024
+
+// Reader reads changes from a KV buffer
+type Reader interface {
+	ChangesBetween(ctx context.Context, from, to string, offset, limit int64) ([]json.RawMessage, error)
+}
+
+// Writer adds changes to a KV buffer
+type Writer interface {
+	AddChange(ctx context.Context, change kvbuffer.Change) error
+}
+
+// rawChange is a validated change that is stored exactly as it was read, so an export
+// imports back unchanged
+type rawChange struct {
+	change types.Change
+	data   json.RawMessage
+}
+
+func (c rawChange) Type() string                 { return c.change.Type() }
+func (c rawChange) GetPosition() string          { return c.change.GetPosition() }
+func (c rawChange) MarshalJSON() ([]byte, error) { return c.data, nil }
+
+// Validate checks that data is a change the translicator can apply: JSON of a known change
+// type with a position, and for row and DDL changes, the fields they are applied from
+func Validate(data []byte) (types.Change, error) {
+	var change types.Change
+	if err := json.Unmarshal(data, &change); err != nil {
+		return types.Change{}, fmt.Errorf("invalid change: %w", err)
+	}
+	if change.Position == "" {
+		return types.Change{}, fmt.Errorf("invalid change: no position")
+	}
+
+	switch d := change.Data.(type) {
+	case *types.DMLData:
+		if d.Table == "" {
+			return change, fmt.Errorf("invalid change at %s: no table", change.Position)
+		}
+		if d.Kind != "insert" && d.Kind != "update" && d.Kind != "delete" {
+			return change, fmt.Errorf("invalid change at %s: unknown kind %q", change.Position, d.Kind)
+		}
+		if len(d.ColumnNames) != len(d.ColumnValues) {
+			return change, fmt.Errorf("invalid change at %s: %d column names for %d values", change.Position, len(d.ColumnNames), len(d.ColumnValues))
+		}
+		if d.OldKeys != nil && len(d.OldKeys.KeyNames) != len(d.OldKeys.KeyValues) {
+			return change, fmt.Errorf("invalid change at %s: %d old key names for %d values", change.Position, len(d.OldKeys.KeyNames), len(d.OldKeys.KeyValues))
+		}
+	case *types.DDLData:
+		if strings.TrimSpace(d.DDL) == "" {
+			return change, fmt.Errorf("invalid change at %s: no DDL", change.Position)
+		}
+	}
+	return change, nil
+}
+
+// Export writes the changes from one position to another, both included, as one line of
+// JSON each, and returns the number written. An empty from or to leaves the range open at
+// that end. Changes are written as stored, without validation, so an export can be taken of
+// a buffer with broken changes.
+func Export(ctx context.Context, r Reader, w io.Writer, from, to string) (int, error) {
+	bw := bufio.NewWriter(w)
+	written := 0
+	for {
+		changes, err := r.ChangesBetween(ctx, from, to, int64(written), pageSize)
+		if err != nil {
+			return written, err
+		}
+		for _, change := range changes {
+			if _, err := bw.Write(append(change, '\n')); err != nil {
+				return written, fmt.Errorf("failed to write change: %w", err)
+			}
+			written++
+		}
+		if len(changes) < pageSize {
+			break
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return written, fmt.Errorf("failed to write changes: %w", err)
+	}
+	return written, nil
+}
+
+// Import reads changes written by Export and adds them to the buffer, and returns the number
+// read. Every line is validated before any change is added, so a broken file adds nothing.
+// Changes already in the buffer are skipped by the buffer as duplicates.
+func Import(ctx context.Context, w Writer, r io.Reader) (int, error) {
+	var changes []rawChange
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		data := scanner.Bytes()
+		if strings.TrimSpace(string(data)) == "" {
+			continue
+		}
+		change, err := Validate(data)
+		if err != nil {
+			return 0, fmt.Errorf("line %d: %w", line, err)
+		}
+		changes = append(changes, rawChange{change: change, data: append(json.RawMessage{}, data...)})
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read changes: %w", err)
+	}
+
+	for i, change := range changes {
+		if err := w.AddChange(ctx, change); err != nil {
+			return i, fmt.Errorf("failed to add change at %s: %w", change.GetPosition(), err)
+		}
+	}
+	return len(changes), nil
+}
@@ -0,0 +1,130 @@
+package buffer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"kasho/pkg/kvbuffer"
+)
+
+const (
+	insertChange = `{"type":"dml","position":"0/1","data":{"table":"users","columnnames":["id"],"columnvalues":[1],"kind":"insert"}}`
+	ddlChange    = `{"type":"ddl","position":"0/2","data":{"id":1,"time":"2025-01-01T00:00:00Z","username":"kasho","database":"app","ddl":"CREATE TABLE users (id int)"}}`
+)
+
+// fakeBuffer holds changes in memory, returning them a page at a time
+type fakeBuffer struct {
+	changes []json.RawMessage
+	added   []string
+	err     error
+}
+
+func (f *fakeBuffer) ChangesBetween(ctx context.Context, from, to string, offset, limit int64) ([]json.RawMessage, error) {
+	end := min(offset+limit, int64(len(f.changes)))
+	if offset >= end {
+		return nil, nil
+	}
+	return f.changes[offset:end], nil
+}
+
+func (f *fakeBuffer) AddChange(ctx context.Context, change kvbuffer.Change) error {
+	if f.err != nil {
+		return f.err
+	}
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	f.added = append(f.added, string(data))
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"insert", insertChange, ""},
+		{"ddl", ddlChange, ""},
+		{"transaction", `{"type":"transaction","position":"0/3","data":{},"transactionstatus":"committed"}`, ""},
+		{"not JSON", `{"type":`, "invalid change"},
+		{"unknown type", `{"type":"row","position":"0/1","data":{}}`, "unknown change type"},
+		{"no position", `{"type":"ddl","data":{"ddl":"DROP TABLE users"}}`, "no position"},
+		{"no table", `{"type":"dml","position":"0/1","data":{"kind":"insert"}}`, "no table"},
+		{"unknown kind", `{"type":"dml","position":"0/1","data":{"table":"users","kind":"upsert"}}`, `unknown kind "upsert"`},
+		{"missing values", `{"type":"dml","position":"0/1","data":{"table":"users","columnnames":["id","name"],"columnvalues":[1],"kind":"insert"}}`, "2 column names for 1 values"},
+		{"empty DDL", `{"type":"ddl","position":"0/1","data":{"ddl":" "}}`, "no DDL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Validate([]byte(tt.data))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	// More changes than fit in one page, plus one that isn't valid
+	buffer := &fakeBuffer{}
+	for range pageSize {
+		buffer.changes = append(buffer.changes, json.RawMessage(insertChange))
+	}
+	buffer.changes = append(buffer.changes, json.RawMessage(`not a change`))
+
+	var out bytes.Buffer
+	n, err := Export(context.Background(), buffer, &out, "", "")
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if n != pageSize+1 {
+		t.Errorf("Export() = %d, want %d", n, pageSize+1)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != pageSize+1 || lines[0] != insertChange || lines[pageSize] != "not a change" {
+		t.Errorf("Export() wrote %d lines, first %q, last %q", len(lines), lines[0], lines[len(lines)-1])
+	}
+}
+
+func TestImport(t *testing.T) {
+	// Changes are added exactly as they were exported
+	buffer := &fakeBuffer{}
+	n, err := Import(context.Background(), buffer, strings.NewReader(insertChange+"\n\n"+ddlChange+"\n"))
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Import() = %d, want 2", n)
+	}
+	if len(buffer.added) != 2 || buffer.added[0] != insertChange || buffer.added[1] != ddlChange {
+		t.Errorf("Import() added %q", buffer.added)
+	}
+
+	// A broken line adds nothing
+	buffer = &fakeBuffer{}
+	_, err = Import(context.Background(), buffer, strings.NewReader(insertChange+"\n{\"type\":\"dml\"}\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Import() error = %v, want an error for line 2", err)
+	}
+	if len(buffer.added) != 0 {
+		t.Errorf("Import() added %d changes from a broken file", len(buffer.added))
+	}
+
+	// A failed write returns the number of changes added before it
+	buffer = &fakeBuffer{err: errors.New("connection reset")}
+	if n, err := Import(context.Background(), buffer, strings.NewReader(insertChange+"\n")); err == nil || n != 0 {
+		t.Errorf("Import() = %d, %v, want 0 and an error", n, err)
+	}
+}
@@ -0,0 +1,47 @@
+package buffer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"kasho/pkg/kvbuffer"
+)
+
+// statsJSON is the JSON form of kvbuffer.Stats
+type statsJSON struct {
+	Changes       int64  `json:"changes"`
+	MemoryBytes   int64  `json:"memory_bytes"`
+	FirstPosition string `json:"first_position"`
+	LastPosition  string `json:"last_position"`
+	TTLSeconds    int64  `json:"ttl_seconds"`
+}
+
+// PrintStats writes the buffer's stats as JSON, or as aligned text
+func PrintStats(w io.Writer, stats kvbuffer.Stats, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(statsJSON{
+			Changes:       stats.Changes,
+			MemoryBytes:   stats.MemoryBytes,
+			FirstPosition: stats.FirstPosition,
+			LastPosition:  stats.LastPosition,
+			TTLSeconds:    int64(stats.TTL.Seconds()),
+		})
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Changes:\t%d\n", stats.Changes)
+	fmt.Fprintf(tw, "Memory:\t%d bytes\n", stats.MemoryBytes)
+	fmt.Fprintf(tw, "First position:\t%s\n", stats.FirstPosition)
+	fmt.Fprintf(tw, "Last position:\t%s\n", stats.LastPosition)
+	switch {
+	case stats.TTL > 0:
+		fmt.Fprintf(tw, "Expires in:\t%s\n", stats.TTL)
+	case stats.Changes > 0:
+		fmt.Fprintf(tw, "Expires in:\tnever\n")
+	}
+	return tw.Flush()
+}
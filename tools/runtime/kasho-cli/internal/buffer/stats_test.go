@@ -0,0 +1,43 @@
+package buffer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"kasho/pkg/kvbuffer"
+)
+
+func TestPrintStats(t *testing.T) {
+	stats := kvbuffer.Stats{
+		Changes:       42,
+		MemoryBytes:   8192,
+		FirstPosition: "0/BOOTSTRAP0000000000000001",
+		LastPosition:  "0/1A2B3C",
+		TTL:           90 * time.Minute,
+	}
+
+	var text bytes.Buffer
+	if err := PrintStats(&text, stats, false); err != nil {
+		t.Fatalf("PrintStats() error = %v", err)
+	}
+	for _, want := range []string{"Changes:         42", "Memory:          8192 bytes", "Last position:   0/1A2B3C", "Expires in:      1h30m0s"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("PrintStats() text missing %q:\n%s", want, text.String())
+		}
+	}
+
+	var out bytes.Buffer
+	if err := PrintStats(&out, stats, true); err != nil {
+		t.Fatalf("PrintStats() error = %v", err)
+	}
+	var decoded statsJSON
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("PrintStats() JSON invalid: %v", err)
+	}
+	if decoded.Changes != 42 || decoded.FirstPosition != stats.FirstPosition || decoded.TTLSeconds != 5400 {
+		t.Errorf("PrintStats() JSON = %+v", decoded)
+	}
+}
@@ -0,0 +1,236 @@
+package dev
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"kasho-cli/internal/tools"
+)
+
+const (
+	// readyTimeout is how long a service may take to start listening
+	readyTimeout = time.Minute
+	// stopTimeout is how long a service may take to shut down before it is killed
+	stopTimeout = 10 * time.Second
+)
+
+// Config is what a local pipeline runs against
+type Config struct {
+	PrimaryURL string // PostgreSQL primary the change stream reads from
+	ReplicaURL string // PostgreSQL replica the translicator writes to
+	KVURL      string // Redis the change stream buffers changes in
+	ConfigDir  string // directory with the translicator's transforms.yml
+	Port       int    // port the change stream listens on
+
+	// SetupPrimary sets the primary up for logical replication before the change stream
+	// starts, as kasho setup-primary does
+	SetupPrimary bool
+}
+
+// Service is a pipeline service run as a child process
+type Service struct {
+	Name   string
+	Binary string
+	Args   []string
+	Env    []string // set on top of kasho's own environment
+	Listen string   // address the service accepts connections on once it is ready, if any
+	Once   bool     // runs to completion before the next service starts
+}
+
+// Services returns the services of a PostgreSQL pipeline, in the order they start
+func Services(cfg Config) []Service {
+	addr := fmt.Sprintf("127.0.0.1:%d", cfg.Port)
+	var services []Service
+	if cfg.SetupPrimary {
+		services = append(services, Service{
+			Name:   "setup-primary",
+			Binary: "kasho-setup-primary",
+			Args:   []string{"--primary-url", cfg.PrimaryURL},
+			Once:   true,
+		})
+	}
+	return append(services, []Service{
+		{
+			Name:   "pg-change-stream",
+			Binary: "pg-change-stream",
+			Env: []string{
+				"PRIMARY_DATABASE_URL=" + cfg.PrimaryURL,
+				"KV_URL=" + cfg.KVURL,
+				"GRPC_HOST=127.0.0.1",
+				fmt.Sprintf("GRPC_PORT=%d", cfg.Port),
+			},
+			Listen: addr,
+		},
+		{
+			Name:   "translicator",
+			Binary: "translicator",
+			Env: []string{
+				"REPLICA_DATABASE_URL=" + cfg.ReplicaURL,
+				"CHANGE_STREAM_SERVICE_ADDR=" + addr,
+				"CONFIG_DIR=" + cfg.ConfigDir,
+			},
+		},
+	}...)
+}
+
+// process is a started service
+type process struct {
+	name string
+	cmd  *exec.Cmd
+	done chan struct{} // closed once the service has exited
+	err  error         // why the service exited, set before done is closed
+}
+
+// Up starts the services in order, waiting for each that listens to accept connections,
+// or that runs once to finish, before starting the next, and writes their output to w
+// with each line prefixed by the service's name. It runs until ctx is done or a service
+// that keeps running exits, then stops the others.
+func Up(ctx context.Context, services []Service, w io.Writer) error {
+	var mu sync.Mutex
+	exited := make(chan *process, len(services))
+	var running []*process
+	defer func() { stop(running) }()
+
+	for _, service := range services {
+		path, err := tools.Find(service.Binary)
+		if err != nil {
+			return err
+		}
+
+		cmd := exec.Command(path, service.Args...)
+		cmd.Env = append(os.Environ(), service.Env...)
+		out := &prefixWriter{mu: &mu, w: w, prefix: fmt.Sprintf("[%s] ", service.Name)}
+		cmd.Stdout, cmd.Stderr = out, out
+		if err := cmd.Start(); err != nil {
+			return fmt.Errorf("failed to start %s: %w", service.Name, err)
+		}
+
+		p := &process{name: service.Name, cmd: cmd, done: make(chan struct{})}
+		running = append(running, p)
+		go func() {
+			p.err = cmd.Wait()
+			out.flush()
+			close(p.done)
+			if !service.Once {
+				exited <- p
+			}
+		}()
+
+		if service.Once {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-p.done:
+				if p.err != nil {
+					return exitError(p)
+				}
+			}
+		}
+		if service.Listen != "" {
+			if err := waitForListen(ctx, p, service.Listen); err != nil {
+				return err
+			}
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case p := <-exited:
+		if ctx.Err() != nil {
+			return nil
+		}
+		return exitError(p)
+	}
+}
+
+// waitForListen waits until a service accepts connections on addr
+func waitForListen(ctx context.Context, p *process, addr string) error {
+	deadline := time.After(readyTimeout)
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+			conn.Close()
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-p.done:
+			return exitError(p)
+		case <-deadline:
+			return fmt.Errorf("%s is not listening on %s after %s", p.name, addr, readyTimeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// exitError describes why a service exited
+func exitError(p *process) error {
+	if p.err != nil {
+		return fmt.Errorf("%s exited: %w", p.name, p.err)
+	}
+	return fmt.Errorf("%s exited", p.name)
+}
+
+// stop asks the services to shut down, newest first, and kills those that don't in time
+func stop(running []*process) {
+	for i := len(running) - 1; i >= 0; i-- {
+		p := running[i]
+		select {
+		case <-p.done:
+			continue
+		default:
+		}
+		p.cmd.Process.Signal(syscall.SIGTERM)
+		select {
+		case <-p.done:
+		case <-time.After(stopTimeout):
+			p.cmd.Process.Kill()
+			<-p.done
+		}
+	}
+}
+
+// prefixWriter writes whole lines to w, each prefixed. Writers for several services share
+// a mutex so their lines don't interleave.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		fmt.Fprintf(p.w, "%s%s\n", p.prefix, p.buf[:i])
+		p.buf = p.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// flush writes a last line that didn't end with a newline
+func (p *prefixWriter) flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.buf) > 0 {
+		fmt.Fprintf(p.w, "%s%s\n", p.prefix, p.buf)
+		p.buf = nil
+	}
+}
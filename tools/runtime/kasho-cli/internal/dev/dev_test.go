@@ -0,0 +1,166 @@
+package dev
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// installScript puts a shell script on the PATH as a service's binary
+func installScript(t *testing.T, dir, name, script string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// syncBuffer is a bytes.Buffer that is safe to read while services write to it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestServices(t *testing.T) {
+	services := Services(Config{
+		PrimaryURL: "postgresql://primary",
+		ReplicaURL: "postgresql://replica",
+		KVURL:      "redis://localhost:6379",
+		ConfigDir:  "config",
+		Port:       50052,
+	})
+	if len(services) != 2 || services[0].Name != "pg-change-stream" || services[1].Name != "translicator" {
+		t.Fatalf("Services() = %+v", services)
+	}
+	if services[0].Listen != "127.0.0.1:50052" {
+		t.Errorf("change stream listens on %q", services[0].Listen)
+	}
+	env := strings.Join(services[1].Env, " ")
+	for _, want := range []string{"REPLICA_DATABASE_URL=postgresql://replica", "CHANGE_STREAM_SERVICE_ADDR=127.0.0.1:50052", "CONFIG_DIR=config"} {
+		if !strings.Contains(env, want) {
+			t.Errorf("translicator env %q missing %q", env, want)
+		}
+	}
+}
+
+func TestServices_SetupPrimary(t *testing.T) {
+	services := Services(Config{PrimaryURL: "postgresql://primary", Port: 50051, SetupPrimary: true})
+	if len(services) != 3 || services[0].Binary != "kasho-setup-primary" || !services[0].Once {
+		t.Fatalf("Services() = %+v, want kasho-setup-primary run once first", services)
+	}
+	if got := strings.Join(services[0].Args, " "); got != "--primary-url postgresql://primary" {
+		t.Errorf("setup-primary args = %q", got)
+	}
+}
+
+func TestUp(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	installScript(t, dir, "dev-server", "echo \"serving $SERVER_NAME\"\nexec sleep 30\n")
+	installScript(t, dir, "dev-client", "printf 'connecting\\nfailed'\nexit 3\n")
+	services := []Service{
+		{Name: "server", Binary: "dev-server", Env: []string{"SERVER_NAME=test"}, Listen: listener.Addr().String()},
+		{Name: "client", Binary: "dev-client"},
+	}
+
+	// The client exits, which stops the server
+	var out syncBuffer
+	start := time.Now()
+	err = Up(context.Background(), services, &out)
+	if err == nil || err.Error() != "client exited: exit status 3" {
+		t.Errorf("Up() error = %v, want the client's exit", err)
+	}
+	if elapsed := time.Since(start); elapsed > stopTimeout {
+		t.Errorf("Up() took %s to stop the server", elapsed)
+	}
+	for _, want := range []string{"[server] serving test\n", "[client] connecting\n", "[client] failed\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Up() output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	// A service that isn't installed fails before anything starts
+	err = Up(context.Background(), []Service{{Name: "missing", Binary: "dev-missing"}}, &out)
+	if err == nil || !strings.Contains(err.Error(), "dev-missing") {
+		t.Errorf("Up() error = %v, want dev-missing not installed", err)
+	}
+}
+
+func TestUp_Once(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	marker := filepath.Join(dir, "setup-done")
+	installScript(t, dir, "dev-setup", "echo \"setting up $1\"\ntouch "+marker+"\n")
+	installScript(t, dir, "dev-failing-setup", "exit 2\n")
+	installScript(t, dir, "dev-client", "test -f "+marker+" || exit 4\nexit 3\n")
+
+	// A service that runs once finishes before the next starts, and its exit doesn't stop Up
+	var out syncBuffer
+	services := []Service{
+		{Name: "setup", Binary: "dev-setup", Args: []string{"primary"}, Once: true},
+		{Name: "client", Binary: "dev-client"},
+	}
+	err := Up(context.Background(), services, &out)
+	if err == nil || err.Error() != "client exited: exit status 3" {
+		t.Errorf("Up() error = %v, want the client's exit after the setup finished", err)
+	}
+	if !strings.Contains(out.String(), "[setup] setting up primary\n") {
+		t.Errorf("Up() output missing the setup's:\n%s", out.String())
+	}
+
+	// One that fails stops Up before the next starts
+	os.Remove(marker)
+	services[0].Binary = "dev-failing-setup"
+	err = Up(context.Background(), services, &out)
+	if err == nil || err.Error() != "setup exited: exit status 2" {
+		t.Errorf("Up() error = %v, want the setup's exit", err)
+	}
+}
+
+func TestUp_Cancel(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	installScript(t, dir, "dev-server", "exec sleep 30\n")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := Up(ctx, []Service{{Name: "server", Binary: "dev-server"}}, &syncBuffer{}); err != nil {
+		t.Errorf("Up() error = %v, want nil once cancelled", err)
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := &prefixWriter{mu: &sync.Mutex{}, w: &out, prefix: "[svc] "}
+	fmt.Fprint(w, "one\ntw")
+	fmt.Fprint(w, "o\nthree")
+	w.flush()
+	if got, want := out.String(), "[svc] one\n[svc] two\n[svc] three\n"; got != want {
+		t.Errorf("prefixWriter wrote %q, want %q", got, want)
+	}
+}
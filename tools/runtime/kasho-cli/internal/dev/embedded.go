@@ -0,0 +1,143 @@
+package dev
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// embeddedUser is the user and password of the embedded PostgreSQL
+	embeddedUser = "kasho"
+	// primaryDatabase and replicaDatabase are the databases the embedded PostgreSQL serves
+	primaryDatabase = "primary_db"
+	replicaDatabase = "replica_db"
+	// postgresStartTimeout allows for PostgreSQL to be downloaded on the first run
+	postgresStartTimeout = 2 * time.Minute
+)
+
+// Embedded is the Redis and PostgreSQL started in-process for the URLs a Config leaves
+// empty, so a pipeline can run without any databases installed
+type Embedded struct {
+	redis    *miniredis.Miniredis
+	postgres *embeddedpostgres.EmbeddedPostgres
+	dir      string // the embedded PostgreSQL's binaries and data
+}
+
+// StartEmbedded starts miniredis if cfg has no KV URL, and an embedded PostgreSQL serving
+// both the primary and replica databases if either URL is missing, and points cfg at them.
+// PostgreSQL keeps its data in a temporary directory, so each run starts empty, and cfg is
+// set to set up the embedded primary for logical replication. What was started is written
+// to w.
+func StartEmbedded(cfg *Config, w io.Writer) (*Embedded, error) {
+	e := &Embedded{}
+	if cfg.KVURL == "" {
+		redis, err := miniredis.Run()
+		if err != nil {
+			return nil, fmt.Errorf("failed to start embedded Redis: %w", err)
+		}
+		e.redis = redis
+		cfg.KVURL = "redis://" + redis.Addr()
+		fmt.Fprintf(w, "Started embedded Redis at %s\n", cfg.KVURL)
+	}
+	if cfg.PrimaryURL == "" || cfg.ReplicaURL == "" {
+		if err := e.startPostgres(cfg, w); err != nil {
+			e.Stop()
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// startPostgres starts PostgreSQL with logical replication enabled and fills in the
+// missing database URLs
+func (e *Embedded) startPostgres(cfg *Config, w io.Writer) error {
+	dir, err := os.MkdirTemp("", "kasho-dev-")
+	if err != nil {
+		return err
+	}
+	e.dir = dir
+	port, err := freePort()
+	if err != nil {
+		return err
+	}
+
+	log := &prefixWriter{mu: &sync.Mutex{}, w: w, prefix: "[postgres] "}
+	defer log.flush()
+	postgres := embeddedpostgres.NewDatabase(embeddedpostgres.DefaultConfig().
+		Version(embeddedpostgres.V17).
+		Port(port).
+		Username(embeddedUser).
+		Password(embeddedUser).
+		Database(primaryDatabase).
+		RuntimePath(filepath.Join(dir, "runtime")).
+		DataPath(filepath.Join(dir, "data")).
+		StartParameters(map[string]string{"wal_level": "logical"}).
+		StartTimeout(postgresStartTimeout).
+		Logger(log))
+	if err := postgres.Start(); err != nil {
+		return fmt.Errorf("failed to start embedded PostgreSQL: %w", err)
+	}
+	e.postgres = postgres
+
+	url := func(database string) string {
+		return fmt.Sprintf("postgresql://%s:%s@127.0.0.1:%d/%s?sslmode=disable", embeddedUser, embeddedUser, port, database)
+	}
+	if cfg.ReplicaURL == "" {
+		if err := createDatabase(url(primaryDatabase), replicaDatabase); err != nil {
+			return fmt.Errorf("failed to create %s: %w", replicaDatabase, err)
+		}
+		cfg.ReplicaURL = url(replicaDatabase)
+	}
+	if cfg.PrimaryURL == "" {
+		cfg.PrimaryURL = url(primaryDatabase)
+		cfg.SetupPrimary = true
+	}
+	fmt.Fprintf(w, "Started embedded PostgreSQL at %s\n", url(""))
+	return nil
+}
+
+// Stop stops the embedded databases and deletes their data
+func (e *Embedded) Stop() {
+	if e.postgres != nil {
+		e.postgres.Stop()
+	}
+	if e.redis != nil {
+		e.redis.Close()
+	}
+	if e.dir != "" {
+		os.RemoveAll(e.dir)
+	}
+}
+
+// createDatabase creates a database on the server of url
+func createDatabase(url, database string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), readyTimeout)
+	defer cancel()
+	conn, err := pgx.Connect(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	_, err = conn.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{database}.Sanitize())
+	return err
+}
+
+// freePort returns a local port nothing is listening on
+func freePort() (uint32, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return uint32(listener.Addr().(*net.TCPAddr).Port), nil
+}
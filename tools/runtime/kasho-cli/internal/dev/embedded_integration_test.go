@@ -0,0 +1,45 @@
+//go:build integration
+
+package dev
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TestStartEmbedded_Postgres downloads PostgreSQL on its first run. PostgreSQL refuses to
+// run as root.
+func TestStartEmbedded_Postgres(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("PostgreSQL doesn't run as root")
+	}
+	cfg := Config{KVURL: "redis://localhost:6379"}
+	embedded, err := StartEmbedded(&cfg, io.Discard)
+	if err != nil {
+		t.Fatalf("StartEmbedded() unexpected error: %v", err)
+	}
+	defer embedded.Stop()
+
+	if !cfg.SetupPrimary {
+		t.Error("StartEmbedded() didn't ask for the embedded primary to be set up")
+	}
+	ctx := context.Background()
+	for name, url := range map[string]string{"primary": cfg.PrimaryURL, "replica": cfg.ReplicaURL} {
+		conn, err := pgx.Connect(ctx, url)
+		if err != nil {
+			t.Fatalf("connecting to the %s: %v", name, err)
+		}
+		var walLevel string
+		if err := conn.QueryRow(ctx, "SHOW wal_level").Scan(&walLevel); err != nil {
+			t.Fatalf("SHOW wal_level on the %s: %v", name, err)
+		}
+		if walLevel != "logical" {
+			t.Errorf("%s wal_level = %q, want logical", name, walLevel)
+		}
+		conn.Close(ctx)
+	}
+}
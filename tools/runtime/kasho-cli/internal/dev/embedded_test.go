@@ -0,0 +1,48 @@
+package dev
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"kasho/pkg/kvbuffer"
+	"kasho/pkg/types"
+)
+
+func TestStartEmbedded_Redis(t *testing.T) {
+	cfg := Config{PrimaryURL: "postgresql://primary", ReplicaURL: "postgresql://replica"}
+	embedded, err := StartEmbedded(&cfg, io.Discard)
+	if err != nil {
+		t.Fatalf("StartEmbedded() unexpected error: %v", err)
+	}
+	defer embedded.Stop()
+
+	if !strings.HasPrefix(cfg.KVURL, "redis://127.0.0.1:") {
+		t.Fatalf("KVURL = %q, want the embedded Redis", cfg.KVURL)
+	}
+	if embedded.postgres != nil || cfg.SetupPrimary || cfg.PrimaryURL != "postgresql://primary" {
+		t.Errorf("StartEmbedded() started PostgreSQL for given URLs: %+v", cfg)
+	}
+
+	// The change stream's buffer works against it
+	buffer, err := kvbuffer.NewKVBuffer(cfg.KVURL)
+	if err != nil {
+		t.Fatalf("NewKVBuffer() unexpected error: %v", err)
+	}
+	defer buffer.Close()
+	ctx := context.Background()
+	for _, position := range []string{"0/100", "0/200"} {
+		change := types.Change{Position: position, Data: types.DDLData{DDL: "CREATE TABLE t (id int)"}}
+		if err := buffer.AddChange(ctx, change); err != nil {
+			t.Fatalf("AddChange() unexpected error: %v", err)
+		}
+	}
+	changes, err := buffer.GetChangesAfter(ctx, "0/100")
+	if err != nil {
+		t.Fatalf("GetChangesAfter() unexpected error: %v", err)
+	}
+	if len(changes) != 1 || !strings.Contains(string(changes[0]), "0/200") {
+		t.Errorf("GetChangesAfter() = %s, want the change at 0/200", changes)
+	}
+}
@@ -0,0 +1,161 @@
+package stream
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"kasho/pkg/dialect"
+	"kasho/proto"
+)
+
+// FormatJSON renders a change as one line of JSON, with the field names of the proto
+func FormatJSON(change *proto.Change) (string, error) {
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(change)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal change at %s: %w", change.GetPosition(), err)
+	}
+	// protojson varies its spacing, so compact it to one line
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// FormatSQL renders a change as the SQL statement the translicator would apply, in dialect
+// d, after a comment with its position. Updates and deletes match the row by its old keys,
+// or else by its primary key or all of its columns. Changes without a statement, such as
+// logical decoding messages and transaction ends, are rendered as a comment only.
+func FormatSQL(d dialect.Dialect, change *proto.Change) (string, error) {
+	var buf strings.Builder
+	buf.WriteString("-- " + change.GetPosition())
+	if tx := change.GetTransactionId(); tx != "" {
+		buf.WriteString(" tx " + tx)
+	}
+	if ts := change.GetCommitTimestamp(); ts != "" {
+		buf.WriteString(" at " + ts)
+	}
+	buf.WriteByte('\n')
+
+	switch data := change.Data.(type) {
+	case *proto.Change_Dml:
+		stmt, err := dmlSQL(d, data.Dml)
+		if err != nil {
+			return "", fmt.Errorf("change at %s: %w", change.GetPosition(), err)
+		}
+		buf.WriteString(stmt)
+	case *proto.Change_Ddl:
+		buf.WriteString(strings.TrimSuffix(strings.TrimSpace(data.Ddl.GetDdl()), ";") + ";")
+	case *proto.Change_Message:
+		fmt.Fprintf(&buf, "-- message %s: %q", data.Message.GetPrefix(), data.Message.GetContent())
+	default:
+		if status := change.GetTransactionStatus(); status != proto.TransactionStatus_TRANSACTION_STATUS_UNSPECIFIED {
+			fmt.Fprintf(&buf, "-- transaction %s", strings.ToLower(strings.TrimPrefix(status.String(), "TRANSACTION_STATUS_")))
+		} else {
+			fmt.Fprintf(&buf, "-- %s", change.GetType())
+		}
+	}
+	return buf.String(), nil
+}
+
+// dmlSQL renders an insert, update or delete
+func dmlSQL(d dialect.Dialect, dml *proto.DMLData) (string, error) {
+	if len(dml.ColumnNames) != len(dml.ColumnValues) {
+		return "", fmt.Errorf("mismatched column names and values: %d names, %d values", len(dml.ColumnNames), len(dml.ColumnValues))
+	}
+
+	// Unchanged TOAST values and columns left out of a MINIMAL row image have no value
+	var names, values []string
+	for i, val := range dml.ColumnValues {
+		if val.GetUnchangedToast() {
+			continue
+		}
+		formatted, err := d.FormatValue(val)
+		if err != nil {
+			return "", fmt.Errorf("error formatting value for column %s: %w", dml.ColumnNames[i], err)
+		}
+		names = append(names, dml.ColumnNames[i])
+		values = append(values, formatted)
+	}
+
+	switch dml.Kind {
+	case "insert":
+		return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);", dml.Table, strings.Join(names, ", "), strings.Join(values, ", ")), nil
+	case "update":
+		if len(names) == 0 {
+			return fmt.Sprintf("-- update of %s with no changed columns", dml.Table), nil
+		}
+		set := make([]string, len(names))
+		for i := range names {
+			set[i] = names[i] + " = " + values[i]
+		}
+		where, err := whereSQL(d, dml)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("UPDATE %s SET %s WHERE %s;", dml.Table, strings.Join(set, ", "), where), nil
+	case "delete":
+		where, err := whereSQL(d, dml)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("DELETE FROM %s WHERE %s;", dml.Table, where), nil
+	default:
+		return "", fmt.Errorf("unsupported DML kind: %q", dml.Kind)
+	}
+}
+
+// whereSQL renders the conditions matching the row a change updates or deletes
+func whereSQL(d dialect.Dialect, dml *proto.DMLData) (string, error) {
+	names, values := dml.GetOldKeys().GetKeyNames(), dml.GetOldKeys().GetKeyValues()
+	if len(names) == 0 {
+		names, values = dml.ColumnNames, dml.ColumnValues
+		if len(dml.PrimaryKey) > 0 {
+			names, values = nil, nil
+			for i, name := range dml.ColumnNames {
+				if slices.Contains(dml.PrimaryKey, name) {
+					names = append(names, name)
+					values = append(values, dml.ColumnValues[i])
+				}
+			}
+		}
+	}
+	if len(names) == 0 || len(names) != len(values) {
+		return "", fmt.Errorf("no keys to match the %s row by", dml.Kind)
+	}
+
+	conditions := make([]string, 0, len(names))
+	for i, name := range names {
+		if values[i].GetUnchangedToast() {
+			continue
+		}
+		if values[i].GetValue() == nil {
+			conditions = append(conditions, name+" IS NULL")
+			continue
+		}
+		formatted, err := d.FormatValue(values[i])
+		if err != nil {
+			return "", fmt.Errorf("error formatting key %s: %w", name, err)
+		}
+		conditions = append(conditions, name+" = "+formatted)
+	}
+	return strings.Join(conditions, " AND "), nil
+}
+
+// DialectOf returns the dialect a change was captured in, for rendering it as SQL
+func DialectOf(change *proto.Change) (dialect.Dialect, error) {
+	switch change.GetSourceDialect() {
+	case proto.SourceDialect_SOURCE_DIALECT_MYSQL:
+		return dialect.NewMySQL(), nil
+	case proto.SourceDialect_SOURCE_DIALECT_ORACLE:
+		return dialect.NewOracle(), nil
+	case proto.SourceDialect_SOURCE_DIALECT_POSTGRESQL, proto.SourceDialect_SOURCE_DIALECT_UNSPECIFIED:
+		return dialect.NewPostgreSQL(), nil
+	default:
+		return nil, fmt.Errorf("no SQL dialect for changes from %s; pass --dialect", change.GetSourceDialect())
+	}
+}
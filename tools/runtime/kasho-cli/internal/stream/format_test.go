@@ -0,0 +1,138 @@
+package stream
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"kasho/pkg/dialect"
+	"kasho/proto"
+)
+
+func str(s string) *proto.ColumnValue {
+	return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: s}}
+}
+
+func num(i int64) *proto.ColumnValue {
+	return &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: i}}
+}
+
+func dmlChange(dml *proto.DMLData) *proto.Change {
+	return &proto.Change{Position: "0/16B3748", Type: "dml", Data: &proto.Change_Dml{Dml: dml}}
+}
+
+func TestFormatSQL(t *testing.T) {
+	d := dialect.NewPostgreSQL()
+	unchanged := &proto.ColumnValue{Value: &proto.ColumnValue_UnchangedToast{UnchangedToast: true}}
+
+	tests := []struct {
+		name   string
+		change *proto.Change
+		want   string
+	}{
+		{
+			name: "insert",
+			change: dmlChange(&proto.DMLData{Table: "public.users", Kind: "insert",
+				ColumnNames: []string{"id", "name", "email"}, ColumnValues: []*proto.ColumnValue{num(1), str("O'Brien"), {}}}),
+			want: "INSERT INTO public.users (id, name, email) VALUES (1, 'O''Brien', NULL);",
+		},
+		{
+			name: "update by old keys, leaving out unchanged TOAST values",
+			change: dmlChange(&proto.DMLData{Table: "public.users", Kind: "update",
+				ColumnNames: []string{"id", "name", "bio"}, ColumnValues: []*proto.ColumnValue{num(1), str("Ann"), unchanged},
+				OldKeys: &proto.OldKeys{KeyNames: []string{"id"}, KeyValues: []*proto.ColumnValue{num(1)}}}),
+			want: "UPDATE public.users SET id = 1, name = 'Ann' WHERE id = 1;",
+		},
+		{
+			name: "delete by primary key",
+			change: dmlChange(&proto.DMLData{Table: "orders", Kind: "delete", PrimaryKey: []string{"id"},
+				ColumnNames: []string{"id", "total"}, ColumnValues: []*proto.ColumnValue{num(7), num(10)}}),
+			want: "DELETE FROM orders WHERE id = 7;",
+		},
+		{
+			name: "delete by all columns",
+			change: dmlChange(&proto.DMLData{Table: "tags", Kind: "delete",
+				ColumnNames: []string{"name", "note"}, ColumnValues: []*proto.ColumnValue{str("a"), {}}}),
+			want: "DELETE FROM tags WHERE name = 'a' AND note IS NULL;",
+		},
+		{
+			name:   "ddl",
+			change: &proto.Change{Position: "0/16B3748", Type: "ddl", Data: &proto.Change_Ddl{Ddl: &proto.DDLData{Ddl: "CREATE TABLE t (id int)"}}},
+			want:   "CREATE TABLE t (id int);",
+		},
+		{
+			name: "transaction end",
+			change: &proto.Change{Position: "0/16B3748", ChangeType: proto.ChangeType_CHANGE_TYPE_TRANSACTION,
+				TransactionStatus: proto.TransactionStatus_TRANSACTION_STATUS_ABORTED},
+			want: "-- transaction aborted",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FormatSQL(d, tt.change)
+			if err != nil {
+				t.Fatalf("FormatSQL() error = %v", err)
+			}
+			want := "-- 0/16B3748\n" + tt.want
+			if got != want {
+				t.Errorf("FormatSQL() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestFormatSQL_Header(t *testing.T) {
+	change := dmlChange(&proto.DMLData{Table: "t", Kind: "insert", ColumnNames: []string{"id"}, ColumnValues: []*proto.ColumnValue{num(1)}})
+	change.TransactionId = "742"
+	change.CommitTimestamp = "2025-01-02T03:04:05Z"
+
+	got, err := FormatSQL(dialect.NewPostgreSQL(), change)
+	if err != nil {
+		t.Fatalf("FormatSQL() error = %v", err)
+	}
+	if want := "-- 0/16B3748 tx 742 at 2025-01-02T03:04:05Z\n"; !strings.HasPrefix(got, want) {
+		t.Errorf("FormatSQL() = %q, want prefix %q", got, want)
+	}
+}
+
+func TestFormatSQL_UpdateWithoutKeys(t *testing.T) {
+	change := dmlChange(&proto.DMLData{Table: "t", Kind: "update", ColumnNames: []string{"a"},
+		ColumnValues: []*proto.ColumnValue{{Value: &proto.ColumnValue_UnchangedToast{UnchangedToast: true}}}})
+	if _, err := FormatSQL(dialect.NewPostgreSQL(), change); err != nil {
+		t.Fatalf("FormatSQL() error = %v", err)
+	}
+
+	change.GetDml().Kind = "truncate"
+	if _, err := FormatSQL(dialect.NewPostgreSQL(), change); err == nil {
+		t.Error("FormatSQL() should fail for an unknown DML kind")
+	}
+}
+
+func TestFormatJSON(t *testing.T) {
+	change := dmlChange(&proto.DMLData{Table: "users", Kind: "insert", ColumnNames: []string{"id"}, ColumnValues: []*proto.ColumnValue{num(1)}})
+
+	got, err := FormatJSON(change)
+	if err != nil {
+		t.Fatalf("FormatJSON() error = %v", err)
+	}
+	if strings.Contains(got, "\n") {
+		t.Errorf("FormatJSON() spans several lines: %s", got)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal([]byte(got), &decoded); err != nil {
+		t.Fatalf("FormatJSON() invalid JSON: %v", err)
+	}
+	if decoded["position"] != "0/16B3748" || decoded["dml"].(map[string]any)["table"] != "users" {
+		t.Errorf("FormatJSON() = %s", got)
+	}
+}
+
+func TestDialectOf(t *testing.T) {
+	if d, err := DialectOf(&proto.Change{SourceDialect: proto.SourceDialect_SOURCE_DIALECT_MYSQL}); err != nil || d.Name() != "mysql" {
+		t.Errorf("DialectOf(mysql) = %v, %v", d, err)
+	}
+	if _, err := DialectOf(&proto.Change{SourceDialect: proto.SourceDialect_SOURCE_DIALECT_MONGODB}); err == nil {
+		t.Error("DialectOf(mongodb) should fail")
+	}
+}
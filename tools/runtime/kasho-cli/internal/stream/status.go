@@ -0,0 +1,86 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"kasho/proto"
+)
+
+// Status is the status of a change stream service
+type Status struct {
+	Addr               string `json:"addr"`
+	State              string `json:"state"`
+	StartPosition      string `json:"start_position"`
+	CurrentPosition    string `json:"current_position"`
+	AccumulatedChanges int64  `json:"accumulated_changes"`
+	ConnectedClients   int32  `json:"connected_clients"`
+	UptimeSeconds      int64  `json:"uptime_seconds"`
+	DuplicateChanges   int64  `json:"duplicate_changes"`
+	UnpublishedTables  int64  `json:"unpublished_tables"`
+}
+
+// Dial connects to a change stream service
+func Dial(addr string) (*grpc.ClientConn, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	return conn, nil
+}
+
+// withToken adds the API token to the outgoing requests, if there is one
+func withToken(ctx context.Context, token string) context.Context {
+	if token == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+// GetStatus asks a change stream service for its status, with the API token if the
+// service requires one
+func GetStatus(ctx context.Context, client proto.ChangeStreamClient, addr, token string) (Status, error) {
+	resp, err := client.GetStatus(withToken(ctx, token), &proto.GetStatusRequest{})
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to get status from %s: %w", addr, err)
+	}
+	return Status{
+		Addr:               addr,
+		State:              resp.GetState(),
+		StartPosition:      resp.GetStartPosition(),
+		CurrentPosition:    resp.GetCurrentPosition(),
+		AccumulatedChanges: resp.GetAccumulatedChanges(),
+		ConnectedClients:   resp.GetConnectedClients(),
+		UptimeSeconds:      resp.GetUptimeSeconds(),
+		DuplicateChanges:   resp.GetDuplicateChanges(),
+		UnpublishedTables:  resp.GetUnpublishedTables(),
+	}, nil
+}
+
+// Print writes a status as JSON, or as aligned text
+func Print(w io.Writer, status Status, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(status)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Address:\t%s\n", status.Addr)
+	fmt.Fprintf(tw, "State:\t%s\n", status.State)
+	fmt.Fprintf(tw, "Start position:\t%s\n", status.StartPosition)
+	fmt.Fprintf(tw, "Current position:\t%s\n", status.CurrentPosition)
+	fmt.Fprintf(tw, "Accumulated changes:\t%d\n", status.AccumulatedChanges)
+	fmt.Fprintf(tw, "Connected clients:\t%d\n", status.ConnectedClients)
+	fmt.Fprintf(tw, "Uptime:\t%s\n", time.Duration(status.UptimeSeconds)*time.Second)
+	fmt.Fprintf(tw, "Duplicate changes:\t%d\n", status.DuplicateChanges)
+	fmt.Fprintf(tw, "Unpublished tables:\t%d\n", status.UnpublishedTables)
+	return tw.Flush()
+}
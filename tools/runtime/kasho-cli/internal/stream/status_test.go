@@ -0,0 +1,87 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"kasho/proto"
+)
+
+// fakeClient answers GetStatus and records the authorization it was called with
+type fakeClient struct {
+	proto.ChangeStreamClient
+	resp          *proto.StatusResponse
+	err           error
+	authorization []string
+}
+
+func (f *fakeClient) GetStatus(ctx context.Context, in *proto.GetStatusRequest, opts ...grpc.CallOption) (*proto.StatusResponse, error) {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	f.authorization = md.Get("authorization")
+	return f.resp, f.err
+}
+
+func TestGetStatus(t *testing.T) {
+	client := &fakeClient{resp: &proto.StatusResponse{
+		State:              "STREAMING",
+		CurrentPosition:    "0/16B3748",
+		AccumulatedChanges: 12,
+		ConnectedClients:   2,
+		UptimeSeconds:      3725,
+	}}
+
+	status, err := GetStatus(context.Background(), client, "pg-change-stream:50051", "secret")
+	if err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+	if status.Addr != "pg-change-stream:50051" || status.State != "STREAMING" || status.ConnectedClients != 2 {
+		t.Errorf("GetStatus() = %+v", status)
+	}
+	if len(client.authorization) != 1 || client.authorization[0] != "Bearer secret" {
+		t.Errorf("authorization = %q, want the bearer token", client.authorization)
+	}
+
+	if _, err := GetStatus(context.Background(), client, "pg-change-stream:50051", ""); err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+	if len(client.authorization) != 0 {
+		t.Errorf("authorization = %q, want none without a token", client.authorization)
+	}
+
+	client.err = errors.New("unavailable")
+	if _, err := GetStatus(context.Background(), client, "pg-change-stream:50051", ""); err == nil {
+		t.Error("GetStatus() should fail when the service does")
+	}
+}
+
+func TestPrint(t *testing.T) {
+	status := Status{Addr: "pg-change-stream:50051", State: "STREAMING", UptimeSeconds: 3725}
+
+	var text bytes.Buffer
+	if err := Print(&text, status, false); err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+	for _, want := range []string{"State:                STREAMING\n", "Uptime:               1h2m5s\n"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("Print() text missing %q:\n%s", want, text.String())
+		}
+	}
+
+	var out bytes.Buffer
+	if err := Print(&out, status, true); err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+	var decoded Status
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("Print() JSON invalid: %v", err)
+	}
+	if decoded != status {
+		t.Errorf("Print() JSON = %+v, want %+v", decoded, status)
+	}
+}
@@ -0,0 +1,51 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"kasho/proto"
+)
+
+// streamIDHeader names a stream with an in-flight limit in the change stream's response
+const streamIDHeader = "kasho-stream-id"
+
+// Tail streams changes from a change stream service to handle, leaving out heartbeats,
+// until ctx ends, the service closes the stream, or handle returns an error. Handled
+// changes are acknowledged if the stream has an in-flight limit.
+func Tail(ctx context.Context, client proto.ChangeStreamClient, req *proto.StreamRequest, token string, handle func(*proto.Change) error) error {
+	stream, err := client.Stream(withToken(ctx, token), req)
+	if err != nil {
+		return fmt.Errorf("failed to open stream: %w", err)
+	}
+	var streamID string
+	for received := false; ; received = true {
+		change, err := stream.Recv()
+		if errors.Is(err, io.EOF) || ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("stream failed: %w", err)
+		}
+		if !received {
+			// The header arrives with the first message
+			if header, err := stream.Header(); err == nil && len(header.Get(streamIDHeader)) > 0 {
+				streamID = header.Get(streamIDHeader)[0]
+			}
+		}
+		if change.GetChangeType() == proto.ChangeType_CHANGE_TYPE_HEARTBEAT || change.GetType() == "heartbeat" {
+			continue
+		}
+		if err := handle(change); err != nil {
+			return err
+		}
+		if streamID != "" {
+			ack := &proto.AcknowledgeRequest{StreamId: streamID, Position: change.GetPosition()}
+			if _, err := client.Acknowledge(withToken(ctx, token), ack); err != nil && ctx.Err() == nil {
+				return fmt.Errorf("failed to acknowledge change at %s: %w", change.GetPosition(), err)
+			}
+		}
+	}
+}
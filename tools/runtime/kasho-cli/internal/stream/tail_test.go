@@ -0,0 +1,111 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"kasho/proto"
+)
+
+// fakeStream returns its changes, then err
+type fakeStream struct {
+	grpc.ServerStreamingClient[proto.Change]
+	changes []*proto.Change
+	err     error
+	header  metadata.MD
+}
+
+func (f *fakeStream) Header() (metadata.MD, error) {
+	return f.header, nil
+}
+
+func (f *fakeStream) Recv() (*proto.Change, error) {
+	if len(f.changes) == 0 {
+		return nil, f.err
+	}
+	change := f.changes[0]
+	f.changes = f.changes[1:]
+	return change, nil
+}
+
+type fakeStreamClient struct {
+	proto.ChangeStreamClient
+	stream *fakeStream
+	req    *proto.StreamRequest
+	acks   []string
+}
+
+func (f *fakeStreamClient) Acknowledge(ctx context.Context, in *proto.AcknowledgeRequest, opts ...grpc.CallOption) (*proto.AcknowledgeResponse, error) {
+	f.acks = append(f.acks, in.StreamId+" "+in.Position)
+	return &proto.AcknowledgeResponse{}, nil
+}
+
+func (f *fakeStreamClient) Stream(ctx context.Context, in *proto.StreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[proto.Change], error) {
+	f.req = in
+	return f.stream, nil
+}
+
+func TestTail(t *testing.T) {
+	client := &fakeStreamClient{stream: &fakeStream{
+		changes: []*proto.Change{
+			{Position: "0/1", Type: "dml"},
+			{Position: "0/2", Type: "heartbeat", ChangeType: proto.ChangeType_CHANGE_TYPE_HEARTBEAT},
+			{Position: "0/3", Type: "ddl"},
+		},
+		err: io.EOF,
+	}}
+	req := &proto.StreamRequest{LastPosition: "bootstrap", IncludeTables: []string{"users"}}
+
+	var positions []string
+	err := Tail(context.Background(), client, req, "", func(change *proto.Change) error {
+		positions = append(positions, change.GetPosition())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Tail() error = %v", err)
+	}
+	if client.req != req {
+		t.Error("Tail() did not send the stream request")
+	}
+	if len(positions) != 2 || positions[0] != "0/1" || positions[1] != "0/3" {
+		t.Errorf("Tail() handled %q, want the changes without the heartbeat", positions)
+	}
+	if len(client.acks) != 0 {
+		t.Errorf("Tail() acknowledged %q on a stream without an in-flight limit", client.acks)
+	}
+}
+
+func TestTail_Acknowledges(t *testing.T) {
+	client := &fakeStreamClient{stream: &fakeStream{
+		changes: []*proto.Change{
+			{Position: "0/1", Type: "dml"},
+			{Position: "0/2", Type: "heartbeat", ChangeType: proto.ChangeType_CHANGE_TYPE_HEARTBEAT},
+			{Position: "0/3", Type: "ddl"},
+		},
+		err:    io.EOF,
+		header: metadata.Pairs(streamIDHeader, "stream-1"),
+	}}
+	if err := Tail(context.Background(), client, &proto.StreamRequest{}, "", func(*proto.Change) error { return nil }); err != nil {
+		t.Fatalf("Tail() error = %v", err)
+	}
+	if len(client.acks) != 2 || client.acks[0] != "stream-1 0/1" || client.acks[1] != "stream-1 0/3" {
+		t.Errorf("Tail() acknowledged %q, want each handled change", client.acks)
+	}
+}
+
+func TestTail_Errors(t *testing.T) {
+	client := &fakeStreamClient{stream: &fakeStream{err: errors.New("unavailable")}}
+	if err := Tail(context.Background(), client, &proto.StreamRequest{}, "", func(*proto.Change) error { return nil }); err == nil {
+		t.Error("Tail() should fail when the stream does")
+	}
+
+	stop := errors.New("stop")
+	client = &fakeStreamClient{stream: &fakeStream{changes: []*proto.Change{{Position: "0/1"}}, err: io.EOF}}
+	if err := Tail(context.Background(), client, &proto.StreamRequest{}, "", func(*proto.Change) error { return stop }); !errors.Is(err, stop) {
+		t.Errorf("Tail() error = %v, want the handler's error", err)
+	}
+}
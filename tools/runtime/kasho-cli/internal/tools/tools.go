@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+)
+
+// Tool is a Kasho tool run as a kasho subcommand
+type Tool struct {
+	Path   []string // Subcommand path below kasho, e.g. bootstrap pg
+	Binary string   // Name of the tool's executable
+	Short  string
+}
+
+// All lists the tools kasho wraps
+var All = []Tool{
+	{Path: []string{"bootstrap", "pg"}, Binary: "pg-bootstrap-sync", Short: "Bootstrap PostgreSQL replica databases from pg_dump files"},
+	{Path: []string{"bootstrap", "mysql"}, Binary: "mysql-bootstrap-sync", Short: "Bootstrap MySQL replica databases from mysqldump files"},
+	{Path: []string{"setup-primary"}, Binary: "kasho-setup-primary", Short: "Prepare a primary database for kasho and report whether it is ready"},
+	{Path: []string{"verify"}, Binary: "kasho-verify", Short: "Verify that a replica matches its primary"},
+	{Path: []string{"rebuild-replica"}, Binary: "kasho-rebuild-replica", Short: "Rebuild a replica from the change buffer"},
+	{Path: []string{"position"}, Binary: "kasho-position", Short: "Export and import a pipeline's saved positions"},
+	{Path: []string{"approvals"}, Binary: "kasho-approvals", Short: "Review changes the translicator holds for approval"},
+	{Path: []string{"transforms"}, Binary: "kasho-transforms", Short: "Check and preview a transforms config"},
+	{Path: []string{"env-template"}, Binary: "env-template", Short: "Render template files from environment variables"},
+}
+
+// Find returns the path of a tool's executable: next to the kasho executable, as installed
+// in the Kasho image, or else on the PATH
+func Find(binary string) (string, error) {
+	if self, err := os.Executable(); err == nil {
+		path := filepath.Join(filepath.Dir(self), binary)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return path, nil
+		}
+	}
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return "", fmt.Errorf("%s is not installed next to kasho or on the PATH", binary)
+	}
+	return path, nil
+}
+
+// Run runs a tool with args, connected to the terminal, and returns its exit code.
+// Interrupt and terminate signals are passed on to the tool so it can shut down cleanly.
+func Run(binary string, args []string) (int, error) {
+	path, err := Find(binary)
+	if err != nil {
+		return 0, err
+	}
+
+	cmd := exec.Command(path, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start %s: %w", binary, err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				cmd.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// A tool killed by a signal has no exit code
+		if code := exitErr.ExitCode(); code >= 0 {
+			return code, nil
+		}
+		return 1, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to run %s: %w", binary, err)
+	}
+	return 0, nil
+}
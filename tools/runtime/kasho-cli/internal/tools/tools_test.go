@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// writeTool writes a shell script tool that exits with the code in its first argument
+func writeTool(t *testing.T, dir, name string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\nexit \"$1\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	writeTool(t, dir, "kasho-fake-tool")
+	t.Setenv("PATH", dir)
+
+	path, err := Find("kasho-fake-tool")
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if path != filepath.Join(dir, "kasho-fake-tool") {
+		t.Errorf("Find() = %s, want the tool on the PATH", path)
+	}
+
+	if _, err := Find("kasho-missing-tool"); err == nil {
+		t.Error("Find() should fail for a tool that isn't installed")
+	}
+}
+
+func TestRun_ExitCode(t *testing.T) {
+	dir := t.TempDir()
+	writeTool(t, dir, "kasho-fake-tool")
+	t.Setenv("PATH", dir)
+
+	for _, want := range []int{0, 3} {
+		code, err := Run("kasho-fake-tool", []string{strconv.Itoa(want)})
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if code != want {
+			t.Errorf("Run() exit code = %d, want %d", code, want)
+		}
+	}
+}
+
+func TestAll_UniquePaths(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, tool := range All {
+		key := filepath.Join(tool.Path...)
+		if seen[key] {
+			t.Errorf("two tools at kasho %s", key)
+		}
+		seen[key] = true
+	}
+}
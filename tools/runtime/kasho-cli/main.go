@@ -0,0 +1,508 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"kasho-cli/internal/buffer"
+	"kasho-cli/internal/dev"
+	"kasho-cli/internal/stream"
+	"kasho-cli/internal/tools"
+	"kasho/pkg/dialect"
+	"kasho/pkg/kvbuffer"
+	"kasho/pkg/transform"
+	"kasho/pkg/version"
+	"kasho/proto"
+)
+
+var (
+	addr          string
+	token         string
+	timeout       time.Duration
+	asJSON        bool
+	from          string
+	tables        []string
+	excludeTables []string
+	excludeKinds  []string
+	format        string
+	dialectName   string
+	transforms    string
+	limit         int
+	kvURL         string
+	to            string
+	file          string
+	yes           bool
+	devConfig     dev.Config
+)
+
+// groups describes the commands that only group tools, like bootstrap
+var groups = map[string]string{
+	"bootstrap": "Bootstrap replica databases from dumps",
+}
+
+func main() {
+	rootCmd := &cobra.Command{
+		Use:   "kasho",
+		Short: "Run the Kasho tools and check a pipeline's services",
+		Long: `kasho runs the Kasho tools as subcommands - kasho bootstrap pg runs pg-bootstrap-sync,
+kasho verify runs kasho-verify, and so on - and checks the services of a pipeline.
+
+The tools are looked up next to the kasho executable, as installed in the Kasho image, and
+then on the PATH. Their arguments are passed through unchanged, so kasho bootstrap pg
+--help shows the flags of pg-bootstrap-sync.`,
+		Version:       fmt.Sprintf("%s (commit %s, built %s)", version.Version, version.GitCommit, version.BuildDate),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	for _, tool := range tools.All {
+		parent := rootCmd
+		for _, name := range tool.Path[:len(tool.Path)-1] {
+			parent = groupCommand(parent, name)
+		}
+		parent.AddCommand(toolCommand(tool))
+	}
+
+	streamCmd := &cobra.Command{
+		Use:   "stream",
+		Short: "Check and tail change stream services",
+	}
+	streamCmd.PersistentFlags().StringVarP(&addr, "addr", "a", os.Getenv("CHANGE_STREAM_SERVICE_ADDR"), "Change stream service address, e.g. pg-change-stream:50051 (default: $CHANGE_STREAM_SERVICE_ADDR)")
+	streamCmd.PersistentFlags().StringVar(&token, "token", "", "API token, if the service requires one (default: $CHANGE_STREAM_TOKEN)")
+
+	statusCmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the state and position of a change stream service",
+		Args:  cobra.NoArgs,
+		RunE:  runStreamStatus,
+	}
+	statusCmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "How long to wait for the service")
+	statusCmd.Flags().BoolVar(&asJSON, "json", false, "Print the status as JSON")
+
+	tailCmd := &cobra.Command{
+		Use:   "tail",
+		Short: "Print the changes of a change stream service as they arrive",
+		Long: `Print the changes of a change stream service as they arrive, as one line of JSON each
+or as the SQL statements the translicator would apply. With --from, the changes buffered
+after that position are printed first; --from bootstrap prints the whole buffer. With
+--transforms, changes are printed as the translicator would transform them.`,
+		Args: cobra.NoArgs,
+		RunE: runStreamTail,
+	}
+	tailCmd.Flags().StringVar(&from, "from", "", "Print the buffered changes after this position first (default: only new changes)")
+	tailCmd.Flags().StringSliceVarP(&tables, "table", "t", nil, "Only print changes to these tables, e.g. users or public.order_*")
+	tailCmd.Flags().StringSliceVar(&excludeTables, "exclude-table", nil, "Never print changes to these tables")
+	tailCmd.Flags().StringSliceVar(&excludeKinds, "exclude-kind", nil, "Change kinds to skip: insert, update, delete or ddl")
+	tailCmd.Flags().StringVarP(&format, "format", "o", "json", "Output format: json or sql")
+	tailCmd.Flags().StringVar(&dialectName, "dialect", "", "SQL dialect for --format sql (default: the source's dialect)")
+	tailCmd.Flags().StringVar(&transforms, "transforms", "", "Transforms config to apply before printing, e.g. transforms.yml")
+	tailCmd.Flags().IntVarP(&limit, "limit", "n", 0, "Stop after this many changes (0 = no limit)")
+
+	streamCmd.AddCommand(statusCmd, tailCmd)
+
+	bufferCmd := &cobra.Command{
+		Use:   "buffer",
+		Short: "Inspect and repair the KV buffer of changes",
+		Long: `Inspect and repair the Redis buffer that change stream services keep their changes in.
+Positions are given as the change stream reports them, e.g. 0/1A2B3C, and --from and --to
+both include the changes at their position.`,
+	}
+	bufferCmd.PersistentFlags().StringVarP(&kvURL, "kv-url", "k", os.Getenv("KV_URL"), "Redis connection URL (default: $KV_URL)")
+
+	bufferStatsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show the number, size and position range of the buffered changes",
+		Args:  cobra.NoArgs,
+		RunE:  runBufferStats,
+	}
+	bufferStatsCmd.Flags().BoolVar(&asJSON, "json", false, "Print the stats as JSON")
+
+	inspectCmd := &cobra.Command{
+		Use:   "inspect <position>",
+		Short: "Print and validate the buffered change at a position",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runBufferInspect,
+	}
+
+	deleteRangeCmd := &cobra.Command{
+		Use:   "delete-range",
+		Short: "Delete the buffered changes between two positions",
+		Long: `Delete the buffered changes between two positions, both included. Without --yes, only
+the number of changes that would be deleted is printed. Clients that already received the
+changes are not affected.`,
+		Args: cobra.NoArgs,
+		RunE: runBufferDeleteRange,
+	}
+	deleteRangeCmd.Flags().StringVar(&from, "from", "", "First position to delete (default: the oldest change)")
+	deleteRangeCmd.Flags().StringVar(&to, "to", "", "Last position to delete (default: the newest change)")
+	deleteRangeCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Delete the changes instead of only counting them")
+
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write the buffered changes to a file, one line of JSON each",
+		Args:  cobra.NoArgs,
+		RunE:  runBufferExport,
+	}
+	exportCmd.Flags().StringVar(&from, "from", "", "First position to export (default: the oldest change)")
+	exportCmd.Flags().StringVar(&to, "to", "", "Last position to export (default: the newest change)")
+	exportCmd.Flags().StringVarP(&file, "file", "f", "-", "File to write, or - for stdout")
+
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Validate and add the changes of an exported file to the buffer",
+		Long: `Validate and add the changes of a file written by kasho buffer export to the buffer. Every
+change is validated before any is added, and changes already in the buffer are skipped.
+Added changes are published to the connected change stream clients.`,
+		Args: cobra.NoArgs,
+		RunE: runBufferImport,
+	}
+	importCmd.Flags().StringVarP(&file, "file", "f", "-", "File to read, or - for stdin")
+
+	bufferCmd.AddCommand(bufferStatsCmd, inspectCmd, deleteRangeCmd, exportCmd, importCmd)
+
+	devCmd := &cobra.Command{
+		Use:   "dev",
+		Short: "Run a pipeline locally for development",
+	}
+
+	devUpCmd := &cobra.Command{
+		Use:   "up",
+		Short: "Run pg-change-stream and translicator against local databases",
+		Long: `Run pg-change-stream and translicator as child processes against a PostgreSQL primary
+and replica and Redis, without containers. The translicator starts once the change stream is
+listening, and their output is printed with each line prefixed by the service. Both stop on
+Ctrl-C, or when either exits.
+
+Redis and PostgreSQL run inside kasho unless --kv-url, --primary-url or --replica-url are
+given: Redis is miniredis, and one embedded PostgreSQL serves both primary_db and
+replica_db. The embedded PostgreSQL is downloaded on the first run, keeps its data in a
+temporary directory, so each run starts empty, and its primary is set up for logical
+replication with kasho-setup-primary. A primary given by URL must already be set up, e.g.
+with kasho setup-primary.
+
+The services are looked up next to the kasho executable, then on the PATH.`,
+		Args: cobra.NoArgs,
+		RunE: runDevUp,
+	}
+	devUpCmd.Flags().StringVar(&devConfig.PrimaryURL, "primary-url", os.Getenv("PRIMARY_DATABASE_URL"), "Primary database URL; an embedded PostgreSQL if empty (default: $PRIMARY_DATABASE_URL)")
+	devUpCmd.Flags().StringVar(&devConfig.ReplicaURL, "replica-url", os.Getenv("REPLICA_DATABASE_URL"), "Replica database URL; an embedded PostgreSQL if empty (default: $REPLICA_DATABASE_URL)")
+	devUpCmd.Flags().StringVarP(&devConfig.KVURL, "kv-url", "k", os.Getenv("KV_URL"), "Redis connection URL; an embedded Redis if empty (default: $KV_URL)")
+	devUpCmd.Flags().StringVar(&devConfig.ConfigDir, "config-dir", "config", "Directory with the translicator's transforms.yml")
+	devUpCmd.Flags().IntVar(&devConfig.Port, "port", 50051, "Port the change stream listens on")
+
+	devCmd.AddCommand(devUpCmd)
+	rootCmd.AddCommand(streamCmd, bufferCmd, devCmd)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// groupCommand returns the group command name below parent, adding it the first time
+func groupCommand(parent *cobra.Command, name string) *cobra.Command {
+	for _, cmd := range parent.Commands() {
+		if cmd.Name() == name {
+			return cmd
+		}
+	}
+	cmd := &cobra.Command{
+		Use:   name,
+		Short: groups[name],
+	}
+	parent.AddCommand(cmd)
+	return cmd
+}
+
+// toolCommand runs a tool with the command's arguments and exits with the tool's exit code
+func toolCommand(tool tools.Tool) *cobra.Command {
+	return &cobra.Command{
+		Use:   tool.Path[len(tool.Path)-1],
+		Short: tool.Short,
+		Long: fmt.Sprintf("%s.\n\nRuns %s with the given arguments; kasho %s --help shows its flags.",
+			tool.Short, tool.Binary, strings.Join(tool.Path, " ")),
+		DisableFlagParsing: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			code, err := tools.Run(tool.Binary, args)
+			if err != nil {
+				return err
+			}
+			if code != 0 {
+				os.Exit(code)
+			}
+			return nil
+		},
+	}
+}
+
+func runStreamStatus(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	conn, err := dialStream()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	status, err := stream.GetStatus(ctx, proto.NewChangeStreamClient(conn), addr, token)
+	if err != nil {
+		return err
+	}
+	return stream.Print(os.Stdout, status, asJSON)
+}
+
+func runStreamTail(cmd *cobra.Command, args []string) error {
+	if format != "json" && format != "sql" {
+		return fmt.Errorf("unknown format %q: use json or sql", format)
+	}
+	var d dialect.Dialect
+	if dialectName != "" {
+		var err error
+		if d, err = dialect.FromName(dialectName); err != nil {
+			return err
+		}
+	}
+	var config *transform.Config
+	if transforms != "" {
+		var err error
+		if config, err = transform.LoadConfig(transforms); err != nil {
+			return fmt.Errorf("failed to load transforms: %w", err)
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	conn, err := dialStream()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	req := &proto.StreamRequest{
+		LastPosition:  from,
+		IncludeTables: tables,
+		ExcludeTables: excludeTables,
+		ExcludeKinds:  excludeKinds,
+	}
+	printed := 0
+	return stream.Tail(ctx, proto.NewChangeStreamClient(conn), req, token, func(change *proto.Change) error {
+		if config != nil {
+			transformed, err := transform.TransformChange(config, change)
+			if err != nil {
+				return fmt.Errorf("failed to transform change at %s: %w", change.GetPosition(), err)
+			}
+			change = transformed
+		}
+
+		var out string
+		var err error
+		if format == "sql" {
+			changeDialect := d
+			if changeDialect == nil {
+				if changeDialect, err = stream.DialectOf(change); err != nil {
+					return err
+				}
+			}
+			out, err = stream.FormatSQL(changeDialect, change)
+		} else {
+			out, err = stream.FormatJSON(change)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println(out)
+
+		printed++
+		if limit > 0 && printed >= limit {
+			cancel()
+		}
+		return nil
+	})
+}
+
+func runBufferStats(cmd *cobra.Command, args []string) error {
+	kv, err := openBuffer()
+	if err != nil {
+		return err
+	}
+	defer kv.Close()
+
+	stats, err := kv.Stats(cmd.Context())
+	if err != nil {
+		return err
+	}
+	return buffer.PrintStats(os.Stdout, stats, asJSON)
+}
+
+func runBufferInspect(cmd *cobra.Command, args []string) error {
+	kv, err := openBuffer()
+	if err != nil {
+		return err
+	}
+	defer kv.Close()
+
+	position := args[0]
+	changes, err := kv.ChangesBetween(cmd.Context(), position, position, 0, 0)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		return fmt.Errorf("no change at position %s", position)
+	}
+
+	invalid := 0
+	for _, change := range changes {
+		var out bytes.Buffer
+		if err := json.Indent(&out, change, "", "  "); err != nil {
+			out.Reset()
+			out.Write(change)
+		}
+		fmt.Println(out.String())
+		if _, err := buffer.Validate(change); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid: %v\n", err)
+			invalid++
+		}
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d changes at %s are invalid", invalid, len(changes), position)
+	}
+	return nil
+}
+
+func runBufferDeleteRange(cmd *cobra.Command, args []string) error {
+	if from == "" && to == "" {
+		return fmt.Errorf("--from or --to is required")
+	}
+	kv, err := openBuffer()
+	if err != nil {
+		return err
+	}
+	defer kv.Close()
+
+	if !yes {
+		count, err := kv.CountBetween(cmd.Context(), from, to)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d changes would be deleted; run again with --yes to delete them\n", count)
+		return nil
+	}
+	removed, err := kv.DeleteBetween(cmd.Context(), from, to)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %d changes\n", removed)
+	return nil
+}
+
+func runBufferExport(cmd *cobra.Command, args []string) error {
+	kv, err := openBuffer()
+	if err != nil {
+		return err
+	}
+	defer kv.Close()
+
+	out := os.Stdout
+	if file != "-" {
+		if out, err = os.Create(file); err != nil {
+			return fmt.Errorf("failed to create export file: %w", err)
+		}
+		defer out.Close()
+	}
+
+	n, err := buffer.Export(cmd.Context(), kv, out, from, to)
+	if err != nil {
+		return err
+	}
+	if file != "-" {
+		if err := out.Close(); err != nil {
+			return fmt.Errorf("failed to write export file: %w", err)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d changes\n", n)
+	return nil
+}
+
+func runBufferImport(cmd *cobra.Command, args []string) error {
+	kv, err := openBuffer()
+	if err != nil {
+		return err
+	}
+	defer kv.Close()
+
+	in := os.Stdin
+	if file != "-" {
+		if in, err = os.Open(file); err != nil {
+			return fmt.Errorf("failed to open import file: %w", err)
+		}
+		defer in.Close()
+	}
+
+	n, err := buffer.Import(cmd.Context(), kv, in)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Imported %d changes, %d of them already in the buffer\n", n, kv.Duplicates())
+	return nil
+}
+
+func runDevUp(cmd *cobra.Command, args []string) error {
+	configDir, err := filepath.Abs(devConfig.ConfigDir)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(configDir, "transforms.yml")); err != nil {
+		return fmt.Errorf("no transforms.yml in %s: %w", configDir, err)
+	}
+	devConfig.ConfigDir = configDir
+
+	embedded, err := dev.StartEmbedded(&devConfig, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer embedded.Stop()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	return dev.Up(ctx, dev.Services(devConfig), os.Stdout)
+}
+
+// openBuffer connects to the KV buffer given by --kv-url
+func openBuffer() (*kvbuffer.KVBuffer, error) {
+	if kvURL == "" {
+		return nil, fmt.Errorf("--kv-url or KV_URL is required")
+	}
+	return kvbuffer.NewKVBuffer(kvURL)
+}
+
+// dialStream connects to the change stream service given by --addr, and takes the API token
+// from the environment if --token isn't set
+func dialStream() (*grpc.ClientConn, error) {
+	if addr == "" {
+		return nil, fmt.Errorf("--addr or CHANGE_STREAM_SERVICE_ADDR is required")
+	}
+	if token == "" {
+		token = os.Getenv("CHANGE_STREAM_TOKEN")
+	}
+	return stream.Dial(addr)
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,18 @@
+module kasho-position
+
+go 1.24.3
+
+require (
+	github.com/redis/go-redis/v9 v9.8.0
+	github.com/spf13/cobra v1.8.1
+	kasho/pkg/version v0.0.0
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+)
+
+replace kasho/pkg/version => ../../../pkg/version
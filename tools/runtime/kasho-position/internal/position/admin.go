@@ -0,0 +1,85 @@
+package position
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Status is the part of the translicator's /admin/status response holding its position
+type Status struct {
+	Position string `json:"position"`
+	Paused   bool   `json:"paused"`
+}
+
+// Admin controls a running translicator
+type Admin interface {
+	Pause(ctx context.Context) error
+	Resume(ctx context.Context, position string) error
+	Status(ctx context.Context) (Status, error)
+}
+
+// HTTPAdmin talks to the translicator's admin server (ADMIN_ADDR)
+type HTTPAdmin struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHTTPAdmin creates an admin client for the admin server at baseURL, e.g. http://translicator:9091
+func NewHTTPAdmin(baseURL string) *HTTPAdmin {
+	return &HTTPAdmin{baseURL: strings.TrimSuffix(baseURL, "/"), client: http.DefaultClient}
+}
+
+// Pause stops the translicator from applying changes and waits until it has stopped
+func (a *HTTPAdmin) Pause(ctx context.Context) error {
+	_, err := a.do(ctx, http.MethodPost, "/admin/pause")
+	return err
+}
+
+// Resume restarts a paused translicator; a non-empty position replaces its stored one
+func (a *HTTPAdmin) Resume(ctx context.Context, position string) error {
+	path := "/admin/resume"
+	if position != "" {
+		path += "?position=" + url.QueryEscape(position)
+	}
+	_, err := a.do(ctx, http.MethodPost, path)
+	return err
+}
+
+// Status returns the translicator's replication status
+func (a *HTTPAdmin) Status(ctx context.Context) (Status, error) {
+	body, err := a.do(ctx, http.MethodGet, "/admin/status")
+	if err != nil {
+		return Status{}, err
+	}
+	var status Status
+	if err := json.Unmarshal(body, &status); err != nil {
+		return Status{}, fmt.Errorf("failed to decode status: %w", err)
+	}
+	return status, nil
+}
+
+func (a *HTTPAdmin) do(ctx context.Context, method, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
@@ -0,0 +1,94 @@
+package position
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// documentVersion is the format version written by Export; Read rejects other versions
+const documentVersion = 1
+
+// ErrInvalidSignature is returned for a document that wasn't signed with the given key or
+// was changed after signing
+var ErrInvalidSignature = errors.New("invalid document signature")
+
+// Document is a snapshot of the positions a pipeline has saved, for restoring it into
+// another KV store or after a disaster
+type Document struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	// State holds the change stream services' state and resume keys from the KV store
+	State map[string]string `json:"state"`
+	// TranslicatorPosition is the last position the translicator applied; empty if it
+	// wasn't exported
+	TranslicatorPosition string `json:"translicator_position,omitempty"`
+	// Signature is the hex HMAC-SHA256 of the document without the signature
+	Signature string `json:"signature,omitempty"`
+}
+
+// Sign sets the document's signature
+func (d *Document) Sign(key []byte) error {
+	signature, err := d.sign(key)
+	if err != nil {
+		return err
+	}
+	d.Signature = signature
+	return nil
+}
+
+// Verify checks the document's signature
+func (d *Document) Verify(key []byte) error {
+	want, err := d.sign(key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(d.Signature), []byte(want)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (d *Document) sign(key []byte) (string, error) {
+	unsigned := *d
+	unsigned.Signature = ""
+	payload, err := json.Marshal(unsigned)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode document: %w", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Write writes the document as indented JSON
+func (d *Document) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode document: %w", err)
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write document: %w", err)
+	}
+	return nil
+}
+
+// Read reads a document and verifies its signature
+func Read(r io.Reader, key []byte) (*Document, error) {
+	var doc Document
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+	if doc.Version != documentVersion {
+		return nil, fmt.Errorf("unsupported document version %d, expected %d", doc.Version, documentVersion)
+	}
+	if err := doc.Verify(key); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
@@ -0,0 +1,97 @@
+package position
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Export reads the state and resume keys of the change stream services and, with an
+// admin, the position the translicator has applied up to
+func Export(ctx context.Context, store Store, admin Admin, now time.Time) (*Document, error) {
+	state, err := readState(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{Version: documentVersion, ExportedAt: now.UTC(), State: state}
+
+	if admin != nil {
+		status, err := admin.Status(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read translicator status: %w", err)
+		}
+		doc.TranslicatorPosition = status.Position
+	}
+	return doc, nil
+}
+
+// Import writes the document's keys into the store and, with an admin, moves the
+// translicator to the document's position. A store that already holds state is only
+// overwritten when overwrite is set, so a live pipeline isn't reset by mistake.
+func Import(ctx context.Context, doc *Document, store Store, admin Admin, overwrite bool) error {
+	existing, err := readState(ctx, store)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 && !overwrite {
+		keys := make([]string, 0, len(existing))
+		for key := range existing {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		return fmt.Errorf("KV store already holds state (%s); use --overwrite to replace it", strings.Join(keys, ", "))
+	}
+
+	keys := make([]string, 0, len(doc.State))
+	for key := range doc.State {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		if !isStateKey(key) {
+			return fmt.Errorf("document holds %s, which is not a state key", key)
+		}
+	}
+	for _, key := range keys {
+		if err := store.Set(ctx, key, doc.State[key]); err != nil {
+			return err
+		}
+		slog.Info("Imported key", "key", key)
+	}
+
+	if admin == nil || doc.TranslicatorPosition == "" {
+		return nil
+	}
+	slog.Info("Pausing translicator")
+	if err := admin.Pause(ctx); err != nil {
+		return fmt.Errorf("failed to pause translicator: %w", err)
+	}
+	slog.Info("Resuming translicator", "position", doc.TranslicatorPosition)
+	if err := admin.Resume(ctx, doc.TranslicatorPosition); err != nil {
+		return fmt.Errorf("failed to resume translicator (left paused): %w", err)
+	}
+	return nil
+}
+
+// readState returns the state and resume keys in the store and their values
+func readState(ctx context.Context, store Store) (map[string]string, error) {
+	keys, err := store.Keys(ctx, stateKeyPattern)
+	if err != nil {
+		return nil, err
+	}
+	state := make(map[string]string)
+	for _, key := range keys {
+		if !isStateKey(key) {
+			continue
+		}
+		value, err := store.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		state[key] = value
+	}
+	return state, nil
+}
@@ -0,0 +1,143 @@
+package position
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memStore is a Store in memory
+type memStore map[string]string
+
+func (s memStore) Keys(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+	for key := range s {
+		if ok, _ := path.Match(pattern, key); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (s memStore) Get(ctx context.Context, key string) (string, error) {
+	return s[key], nil
+}
+
+func (s memStore) Set(ctx context.Context, key, value string) error {
+	s[key] = value
+	return nil
+}
+
+// fakeAdmin records admin calls
+type fakeAdmin struct {
+	position string
+	calls    []string
+}
+
+func (a *fakeAdmin) Pause(ctx context.Context) error {
+	a.calls = append(a.calls, "pause")
+	return nil
+}
+
+func (a *fakeAdmin) Resume(ctx context.Context, position string) error {
+	a.calls = append(a.calls, "resume "+position)
+	return nil
+}
+
+func (a *fakeAdmin) Status(ctx context.Context) (Status, error) {
+	return Status{Position: a.position}, nil
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	key := []byte("secret")
+	source := memStore{
+		"kasho:change-stream:state":        `{"current":2,"start_lsn":"0/1A2B3C"}`,
+		"kasho:mongo-change-stream:resume": "mongo:0000000000000042:8264...",
+		"kasho:changes":                    "buffer",
+	}
+
+	doc, err := Export(ctx, source, &fakeAdmin{position: "0/1A2B40"}, time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Export() unexpected error: %v", err)
+	}
+	wantState := map[string]string{
+		"kasho:change-stream:state":        `{"current":2,"start_lsn":"0/1A2B3C"}`,
+		"kasho:mongo-change-stream:resume": "mongo:0000000000000042:8264...",
+	}
+	if !reflect.DeepEqual(doc.State, wantState) {
+		t.Errorf("Export() state = %v, want %v", doc.State, wantState)
+	}
+	if doc.TranslicatorPosition != "0/1A2B40" {
+		t.Errorf("Export() translicator position = %q, want %q", doc.TranslicatorPosition, "0/1A2B40")
+	}
+
+	if err := doc.Sign(key); err != nil {
+		t.Fatalf("Sign() unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	read, err := Read(bytes.NewReader(buf.Bytes()), key)
+	if err != nil {
+		t.Fatalf("Read() unexpected error: %v", err)
+	}
+
+	target := memStore{}
+	admin := &fakeAdmin{}
+	if err := Import(ctx, read, target, admin, false); err != nil {
+		t.Fatalf("Import() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(map[string]string(target), wantState) {
+		t.Errorf("Import() store = %v, want %v", target, wantState)
+	}
+	if want := []string{"pause", "resume 0/1A2B40"}; !reflect.DeepEqual(admin.calls, want) {
+		t.Errorf("Import() admin calls = %v, want %v", admin.calls, want)
+	}
+
+	// A store with state is only replaced when asked to
+	if err := Import(ctx, read, target, nil, false); err == nil || !strings.Contains(err.Error(), "--overwrite") {
+		t.Errorf("Import() into a store with state = %v, want an error", err)
+	}
+	if err := Import(ctx, read, target, nil, true); err != nil {
+		t.Errorf("Import() with overwrite unexpected error: %v", err)
+	}
+}
+
+func TestRead_Signature(t *testing.T) {
+	doc := &Document{Version: documentVersion, State: map[string]string{"kasho:change-stream:state": `{"current":2}`}}
+	if err := doc.Sign([]byte("secret")); err != nil {
+		t.Fatalf("Sign() unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+
+	if _, err := Read(bytes.NewReader(buf.Bytes()), []byte("other")); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Read() with the wrong key = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	tampered := strings.Replace(buf.String(), `\"current\":2`, `\"current\":0`, 1)
+	if _, err := Read(strings.NewReader(tampered), []byte("secret")); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Read() of a changed document = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	unsupported := strings.Replace(buf.String(), `"version": 1`, `"version": 2`, 1)
+	if _, err := Read(strings.NewReader(unsupported), []byte("secret")); err == nil {
+		t.Error("Read() of an unsupported version should return an error")
+	}
+}
+
+func TestImport_RejectsOtherKeys(t *testing.T) {
+	doc := &Document{Version: documentVersion, State: map[string]string{"kasho:changes": "buffer"}}
+	if err := Import(context.Background(), doc, memStore{}, nil, false); err == nil {
+		t.Error("Import() of a key that isn't state should return an error")
+	}
+}
@@ -0,0 +1,84 @@
+package position
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store is the KV store the change stream services keep their state in
+type Store interface {
+	// Keys returns the keys matching a glob pattern
+	Keys(ctx context.Context, pattern string) ([]string, error)
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string) error
+}
+
+// stateKeyPattern matches the keys of every Kasho service; isStateKey picks the saved
+// positions out of them, leaving out the change buffer
+const stateKeyPattern = "kasho:*"
+
+// isStateKey reports whether a key holds a service's state or resume position, e.g.
+// kasho:change-stream:state or kasho:mongo-change-stream:resume
+func isStateKey(key string) bool {
+	return strings.HasSuffix(key, ":state") || strings.HasSuffix(key, ":resume")
+}
+
+// RedisStore is a Store on Redis
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore connects to the Redis at kvURL, e.g. redis://127.0.0.1:6379
+func NewRedisStore(ctx context.Context, kvURL string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(kvURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse KV URL: %w", err)
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to KV: %w", err)
+	}
+	return &RedisStore{client: client}, nil
+}
+
+// Keys scans for the keys matching a pattern
+func (s *RedisStore) Keys(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+	iter := s.client.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Get returns the value of a key, or "" if it doesn't exist
+func (s *RedisStore) Get(ctx context.Context, key string) (string, error) {
+	val, err := s.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get key %s: %w", key, err)
+	}
+	return val, nil
+}
+
+// Set stores a value without expiry, as the services do
+func (s *RedisStore) Set(ctx context.Context, key, value string) error {
+	if err := s.client.Set(ctx, key, value, 0).Err(); err != nil {
+		return fmt.Errorf("failed to set key %s: %w", key, err)
+	}
+	return nil
+}
+
+// Close closes the connection
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,113 @@
+package rebuild
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const defaultPollInterval = 2 * time.Second
+
+// CountFunc returns the row count of every table in a database
+type CountFunc func(ctx context.Context) (map[string]int64, error)
+
+// Config wires a rebuild to the translicator and the databases
+type Config struct {
+	// Admin controls the translicator applying changes to the replica
+	Admin Admin
+	// Reset empties the replica while the translicator is paused
+	Reset func(ctx context.Context) error
+	// PrimaryCounts and ReplicaCounts are compared once the replica has caught up;
+	// verification is skipped when PrimaryCounts is nil
+	PrimaryCounts CountFunc
+	ReplicaCounts CountFunc
+	// PollInterval is how often the translicator's status is checked while catching up
+	PollInterval time.Duration
+}
+
+// Rebuilder rebuilds a replica by replaying the buffered bootstrap into an emptied database
+type Rebuilder struct {
+	config Config
+	now    func() time.Time
+}
+
+// NewRebuilder creates a rebuilder
+func NewRebuilder(config Config) *Rebuilder {
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultPollInterval
+	}
+	return &Rebuilder{config: config, now: time.Now}
+}
+
+// Rebuild pauses the translicator, resets the replica, resumes from the start of the
+// buffer and waits until the replica has caught up. If the reset fails the translicator
+// is left paused so it doesn't apply changes onto a half-dropped replica.
+// It returns the tables whose row counts differ from the primary, if verification is
+// configured.
+func (r *Rebuilder) Rebuild(ctx context.Context) ([]CountMismatch, error) {
+	slog.Info("Pausing translicator")
+	if err := r.config.Admin.Pause(ctx); err != nil {
+		return nil, fmt.Errorf("failed to pause translicator: %w", err)
+	}
+	status, err := r.config.Admin.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read translicator status: %w", err)
+	}
+	slog.Info("Translicator paused", "position", status.Position)
+
+	slog.Info("Resetting replica")
+	if err := r.config.Reset(ctx); err != nil {
+		return nil, fmt.Errorf("failed to reset replica (translicator left paused): %w", err)
+	}
+
+	resumedAt := r.now()
+	slog.Info("Resuming translicator from bootstrap")
+	if err := r.config.Admin.Resume(ctx, "bootstrap"); err != nil {
+		return nil, fmt.Errorf("failed to resume translicator: %w", err)
+	}
+
+	if err := r.waitForCatchUp(ctx, resumedAt); err != nil {
+		return nil, err
+	}
+
+	if r.config.PrimaryCounts == nil {
+		return nil, nil
+	}
+	return r.verifyCounts(ctx)
+}
+
+// waitForCatchUp polls the translicator until it reports being caught up after since
+func (r *Rebuilder) waitForCatchUp(ctx context.Context, since time.Time) error {
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		status, err := r.config.Admin.Status(ctx)
+		if err != nil {
+			slog.Warn("Failed to read translicator status", "error", err)
+		} else if !status.Paused && status.CaughtUpAt.After(since) {
+			slog.Info("Replica caught up", "position", status.Position)
+			return nil
+		} else {
+			slog.Debug("Waiting for replica to catch up", "position", status.Position)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for replica to catch up: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Rebuilder) verifyCounts(ctx context.Context) ([]CountMismatch, error) {
+	primary, err := r.config.PrimaryCounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count primary rows: %w", err)
+	}
+	replica, err := r.config.ReplicaCounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count replica rows: %w", err)
+	}
+	return compareCounts(primary, replica), nil
+}
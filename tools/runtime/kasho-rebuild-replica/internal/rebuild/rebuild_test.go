@@ -0,0 +1,171 @@
+package rebuild
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeAdmin records admin calls and reports caught up once resumed
+type fakeAdmin struct {
+	calls      []string
+	resumedAt  time.Time
+	statusPoll int
+}
+
+func (a *fakeAdmin) Pause(ctx context.Context) error {
+	a.calls = append(a.calls, "pause")
+	return nil
+}
+
+func (a *fakeAdmin) Resume(ctx context.Context, position string) error {
+	a.calls = append(a.calls, "resume "+position)
+	a.resumedAt = time.Now()
+	return nil
+}
+
+func (a *fakeAdmin) Status(ctx context.Context) (Status, error) {
+	a.statusPoll++
+	if a.resumedAt.IsZero() {
+		return Status{Position: "0/100", Paused: len(a.calls) > 0}, nil
+	}
+	// Catch up on the second poll after resuming
+	if a.statusPoll < 3 {
+		return Status{Position: "0/50"}, nil
+	}
+	return Status{Position: "0/100", CaughtUpAt: a.resumedAt.Add(time.Millisecond)}, nil
+}
+
+func counts(c map[string]int64) CountFunc {
+	return func(ctx context.Context) (map[string]int64, error) { return c, nil }
+}
+
+func TestRebuild(t *testing.T) {
+	admin := &fakeAdmin{}
+	var resetCalls []string
+	r := NewRebuilder(Config{
+		Admin: admin,
+		Reset: func(ctx context.Context) error {
+			resetCalls = append(resetCalls, admin.calls...)
+			return nil
+		},
+		PrimaryCounts: counts(map[string]int64{"public.users": 3, "public.orders": 5}),
+		ReplicaCounts: counts(map[string]int64{"public.users": 3, "public.orders": 4}),
+		PollInterval:  time.Millisecond,
+	})
+
+	mismatches, err := r.Rebuild(context.Background())
+	if err != nil {
+		t.Fatalf("Rebuild() unexpected error: %v", err)
+	}
+
+	if want := []string{"pause", "resume bootstrap"}; !reflect.DeepEqual(admin.calls, want) {
+		t.Errorf("admin calls = %v, want %v", admin.calls, want)
+	}
+	// The replica must only be reset while the translicator is paused
+	if want := []string{"pause"}; !reflect.DeepEqual(resetCalls, want) {
+		t.Errorf("admin calls before reset = %v, want %v", resetCalls, want)
+	}
+	if admin.statusPoll != 3 {
+		t.Errorf("status polled %d times, want 3", admin.statusPoll)
+	}
+	want := []CountMismatch{{Table: "public.orders", PrimaryRows: 5, ReplicaRows: 4}}
+	if !reflect.DeepEqual(mismatches, want) {
+		t.Errorf("mismatches = %+v, want %+v", mismatches, want)
+	}
+}
+
+func TestRebuild_ResetFailureLeavesPaused(t *testing.T) {
+	admin := &fakeAdmin{}
+	r := NewRebuilder(Config{
+		Admin: admin,
+		Reset: func(ctx context.Context) error { return errors.New("permission denied") },
+	})
+
+	if _, err := r.Rebuild(context.Background()); err == nil {
+		t.Fatal("Rebuild() expected error")
+	}
+	if want := []string{"pause"}; !reflect.DeepEqual(admin.calls, want) {
+		t.Errorf("admin calls = %v, want %v", admin.calls, want)
+	}
+}
+
+func TestRebuild_TimesOutWaitingForCatchUp(t *testing.T) {
+	admin := &stuckAdmin{}
+	r := NewRebuilder(Config{
+		Admin:        admin,
+		Reset:        func(ctx context.Context) error { return nil },
+		PollInterval: time.Millisecond,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := r.Rebuild(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Rebuild() error = %v, want deadline exceeded", err)
+	}
+}
+
+// stuckAdmin never reports catching up
+type stuckAdmin struct{}
+
+func (stuckAdmin) Pause(ctx context.Context) error                   { return nil }
+func (stuckAdmin) Resume(ctx context.Context, position string) error { return nil }
+func (stuckAdmin) Status(ctx context.Context) (Status, error)        { return Status{}, nil }
+
+func TestCompareCounts(t *testing.T) {
+	primary := map[string]int64{"users": 3, "orders": 5, "items": 0}
+	replica := map[string]int64{"users": 3, "orders": 4, "audit": 1}
+
+	got := compareCounts(primary, replica)
+	want := []CountMismatch{
+		{Table: "audit", PrimaryRows: -1, ReplicaRows: 1},
+		{Table: "items", PrimaryRows: 0, ReplicaRows: -1},
+		{Table: "orders", PrimaryRows: 5, ReplicaRows: 4},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("compareCounts() = %+v, want %+v", got, want)
+	}
+}
+
+func TestHTTPAdmin(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		switch r.URL.Path {
+		case "/admin/status":
+			w.Write([]byte(`{"position":"0/100","caught_up_at":"2025-01-02T03:04:05Z","paused":true,"tables":{}}`))
+		case "/admin/resume":
+			http.Error(w, "not paused", http.StatusConflict)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	admin := NewHTTPAdmin(server.URL + "/")
+	ctx := context.Background()
+
+	if err := admin.Pause(ctx); err != nil {
+		t.Fatalf("Pause() unexpected error: %v", err)
+	}
+	status, err := admin.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() unexpected error: %v", err)
+	}
+	want := Status{Position: "0/100", CaughtUpAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), Paused: true}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("Status() = %+v, want %+v", status, want)
+	}
+	if err := admin.Resume(ctx, "bootstrap"); err == nil {
+		t.Error("Resume() expected error for a failed request")
+	}
+
+	wantRequests := []string{"POST /admin/pause", "GET /admin/status", "POST /admin/resume?position=bootstrap"}
+	if !reflect.DeepEqual(requests, wantRequests) {
+		t.Errorf("requests = %v, want %v", requests, wantRequests)
+	}
+}
@@ -0,0 +1,209 @@
+package rebuild
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"kasho/pkg/dialect"
+)
+
+// ddlLogTable holds captured DDL on the primary and is never replicated
+const ddlLogTable = "kasho_ddl_log"
+
+const postgresSchemasQuery = `SELECT nspname FROM pg_namespace
+	WHERE nspname NOT IN ('pg_catalog', 'information_schema', 'pg_toast')
+	AND nspname NOT LIKE 'pg_temp_%'
+	AND nspname NOT LIKE 'pg_toast_temp_%'
+	ORDER BY nspname`
+
+const postgresTablesQuery = `SELECT table_schema || '.' || table_name
+	FROM information_schema.tables
+	WHERE table_schema NOT IN ('pg_catalog', 'information_schema', 'pg_toast')
+	AND table_type = 'BASE TABLE'
+	ORDER BY 1`
+
+const mysqlObjectsQuery = `SELECT table_name, table_type
+	FROM information_schema.tables
+	WHERE table_schema = DATABASE()
+	ORDER BY table_name`
+
+const mysqlTablesQuery = `SELECT table_name
+	FROM information_schema.tables
+	WHERE table_schema = DATABASE()
+	AND table_type = 'BASE TABLE'
+	ORDER BY table_name`
+
+// ResetReplica drops everything the translicator created on the replica so the bootstrap
+// can be replayed into an empty database. PostgreSQL user schemas are dropped with CASCADE
+// and an empty public schema is recreated; on MySQL every table and view in the connected
+// database is dropped.
+func ResetReplica(ctx context.Context, db *sql.DB, d dialect.Dialect) error {
+	if d.Name() == "mysql" {
+		return resetMySQL(ctx, db, d)
+	}
+	return resetPostgres(ctx, db, d)
+}
+
+func resetPostgres(ctx context.Context, db *sql.DB, d dialect.Dialect) error {
+	schemas, err := queryStrings(ctx, db, postgresSchemasQuery)
+	if err != nil {
+		return fmt.Errorf("failed to list schemas: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, schema := range schemas {
+		slog.Debug("Dropping schema", "schema", schema)
+		if _, err := tx.ExecContext(ctx, "DROP SCHEMA "+d.QuoteIdentifier(schema)+" CASCADE"); err != nil {
+			return fmt.Errorf("failed to drop schema %s: %w", schema, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "CREATE SCHEMA public"); err != nil {
+		return fmt.Errorf("failed to recreate public schema: %w", err)
+	}
+	return tx.Commit()
+}
+
+func resetMySQL(ctx context.Context, db *sql.DB, d dialect.Dialect) error {
+	// FOREIGN_KEY_CHECKS is per session, so every statement must use the same connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	rows, err := conn.QueryContext(ctx, mysqlObjectsQuery)
+	if err != nil {
+		return fmt.Errorf("failed to list tables: %w", err)
+	}
+	var tables, views []string
+	for rows.Next() {
+		var name, tableType string
+		if err := rows.Scan(&name, &tableType); err != nil {
+			rows.Close()
+			return err
+		}
+		if tableType == "VIEW" {
+			views = append(views, name)
+		} else {
+			tables = append(tables, name)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if _, err := conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+		return err
+	}
+	for _, view := range views {
+		slog.Debug("Dropping view", "view", view)
+		if _, err := conn.ExecContext(ctx, "DROP VIEW IF EXISTS "+d.QuoteIdentifier(view)); err != nil {
+			return fmt.Errorf("failed to drop view %s: %w", view, err)
+		}
+	}
+	for _, table := range tables {
+		slog.Debug("Dropping table", "table", table)
+		if _, err := conn.ExecContext(ctx, "DROP TABLE IF EXISTS "+d.QuoteIdentifier(table)); err != nil {
+			return fmt.Errorf("failed to drop table %s: %w", table, err)
+		}
+	}
+	_, err = conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 1")
+	return err
+}
+
+// CountRows returns the row count of the given tables, or of every user table when
+// tables is empty. Tables use the replicated naming: schema.table on PostgreSQL and
+// the bare table name on MySQL.
+func CountRows(ctx context.Context, db *sql.DB, d dialect.Dialect, tables []string) (map[string]int64, error) {
+	if len(tables) == 0 {
+		query := postgresTablesQuery
+		if d.Name() == "mysql" {
+			query = mysqlTablesQuery
+		}
+		all, err := queryStrings(ctx, db, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tables: %w", err)
+		}
+		for _, table := range all {
+			if !strings.HasSuffix(table, ddlLogTable) {
+				tables = append(tables, table)
+			}
+		}
+	}
+
+	counts := make(map[string]int64, len(tables))
+	for _, table := range tables {
+		var count int64
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+quoteTable(d, table)).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to count %s: %w", table, err)
+		}
+		counts[table] = count
+	}
+	return counts, nil
+}
+
+// CountMismatch is a table whose row count differs between primary and replica.
+// A count of -1 means the table is missing on that side.
+type CountMismatch struct {
+	Table       string
+	PrimaryRows int64
+	ReplicaRows int64
+}
+
+// compareCounts returns the tables whose counts differ, ordered by table name
+func compareCounts(primary, replica map[string]int64) []CountMismatch {
+	var mismatches []CountMismatch
+	for table, primaryRows := range primary {
+		replicaRows, ok := replica[table]
+		if !ok {
+			replicaRows = -1
+		}
+		if primaryRows != replicaRows {
+			mismatches = append(mismatches, CountMismatch{Table: table, PrimaryRows: primaryRows, ReplicaRows: replicaRows})
+		}
+	}
+	for table, replicaRows := range replica {
+		if _, ok := primary[table]; !ok {
+			mismatches = append(mismatches, CountMismatch{Table: table, PrimaryRows: -1, ReplicaRows: replicaRows})
+		}
+	}
+	slices.SortFunc(mismatches, func(a, b CountMismatch) int { return strings.Compare(a.Table, b.Table) })
+	return mismatches
+}
+
+// quoteTable quotes each part of a possibly schema-qualified table name
+func quoteTable(d dialect.Dialect, table string) string {
+	parts := strings.Split(table, ".")
+	for i, part := range parts {
+		parts[i] = d.QuoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func queryStrings(ctx context.Context, db *sql.DB, query string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	"github.com/spf13/cobra"
+	"kasho-rebuild-replica/internal/rebuild"
+	"kasho/pkg/dialect"
+	"kasho/pkg/version"
+)
+
+var (
+	adminURL   string
+	replicaURL string
+	primaryURL string
+	tables     []string
+	timeout    time.Duration
+	yes        bool
+	verbose    bool
+)
+
+func main() {
+	rootCmd := &cobra.Command{
+		Use:   "kasho-rebuild-replica",
+		Short: "Rebuild a replica from the change buffer",
+		Long: `kasho-rebuild-replica rebuilds a replica without re-running the bootstrap. It pauses the
+translicator through its admin server, drops every table on the replica, resumes the
+translicator from the start of the change buffer so the bootstrap and all later changes
+are replayed, waits until it has caught up and then compares row counts with the primary.
+
+The buffered bootstrap must still be complete. To rebuild from a new snapshot instead,
+run the bootstrap script first and then this command.`,
+		RunE: runRebuild,
+	}
+
+	rootCmd.Flags().StringVarP(&adminURL, "admin-url", "a", "", "Translicator admin server URL, e.g. http://translicator:8081 (required)")
+	rootCmd.Flags().StringVarP(&replicaURL, "replica-url", "r", "", "Replica database connection URL (required)")
+	rootCmd.Flags().StringVarP(&primaryURL, "primary-url", "p", "", "Primary database connection URL; enables row count verification")
+	rootCmd.Flags().StringSliceVarP(&tables, "tables", "t", nil, "Tables to verify (default: all tables)")
+	rootCmd.Flags().DurationVar(&timeout, "timeout", time.Hour, "Maximum time to wait for the replica to catch up")
+	rootCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Don't ask for confirmation before dropping the replica's tables")
+	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
+
+	rootCmd.MarkFlagRequired("admin-url")
+	rootCmd.MarkFlagRequired("replica-url")
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runRebuild(cmd *cobra.Command, args []string) error {
+	logLevel := slog.LevelInfo
+	if verbose {
+		logLevel = slog.LevelDebug
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})))
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	slog.Info("Starting kasho-rebuild-replica",
+		"version", version.Version,
+		"commit", version.GitCommit,
+		"built", version.BuildDate,
+	)
+
+	replicaDialect, err := dialect.FromConnectionString(replicaURL)
+	if err != nil {
+		return fmt.Errorf("failed to determine replica dialect: %w", err)
+	}
+	replica, err := sql.Open(replicaDialect.GetDriverName(), replicaDialect.FormatDSN(replicaURL))
+	if err != nil {
+		return fmt.Errorf("failed to open replica: %w", err)
+	}
+	defer replica.Close()
+	if err := replica.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to connect to replica: %w", err)
+	}
+
+	config := rebuild.Config{
+		Admin: rebuild.NewHTTPAdmin(adminURL),
+		Reset: func(ctx context.Context) error {
+			return rebuild.ResetReplica(ctx, replica, replicaDialect)
+		},
+	}
+
+	if primaryURL != "" {
+		primaryDialect, err := dialect.FromConnectionString(primaryURL)
+		if err != nil {
+			return fmt.Errorf("failed to determine primary dialect: %w", err)
+		}
+		if primaryDialect.Name() != replicaDialect.Name() {
+			return fmt.Errorf("primary (%s) and replica (%s) must use the same database type", primaryDialect.Name(), replicaDialect.Name())
+		}
+		primary, err := sql.Open(primaryDialect.GetDriverName(), primaryDialect.FormatDSN(primaryURL))
+		if err != nil {
+			return fmt.Errorf("failed to open primary: %w", err)
+		}
+		defer primary.Close()
+
+		config.PrimaryCounts = func(ctx context.Context) (map[string]int64, error) {
+			return rebuild.CountRows(ctx, primary, primaryDialect, tables)
+		}
+		config.ReplicaCounts = func(ctx context.Context) (map[string]int64, error) {
+			return rebuild.CountRows(ctx, replica, replicaDialect, tables)
+		}
+	}
+
+	if !yes && !confirm(fmt.Sprintf("This drops every table on the %s replica. Continue?", replicaDialect.Name())) {
+		return fmt.Errorf("aborted")
+	}
+
+	ctx, cancelTimeout := context.WithTimeout(ctx, timeout)
+	defer cancelTimeout()
+
+	start := time.Now()
+	mismatches, err := rebuild.NewRebuilder(config).Rebuild(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, mismatch := range mismatches {
+		slog.Error("Row counts differ",
+			"table", mismatch.Table,
+			"primary_rows", mismatch.PrimaryRows,
+			"replica_rows", mismatch.ReplicaRows,
+		)
+	}
+	slog.Info("Rebuild completed", "duration", time.Since(start), "verified", primaryURL != "")
+
+	if len(mismatches) > 0 {
+		return fmt.Errorf("row counts differ in %d tables; run kasho-verify for details", len(mismatches))
+	}
+	return nil
+}
+
+// confirm asks a yes/no question on stdin
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,81 @@
+package setup
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// MySQLState is what a MySQL primary reports about its binary log settings. MySQL writes
+// DDL statements to the binary log, so unlike PostgreSQL it needs no DDL log objects.
+type MySQLState struct {
+	// Flavor is mysql, mariadb or aurora
+	Flavor         string
+	LogBin         bool
+	BinlogFormat   string
+	BinlogRowImage string
+	ServerID       int64
+	GTIDMode       string
+	// BinlogRetentionHours is Aurora's binlog retention period, empty when unset
+	BinlogRetentionHours string
+}
+
+// ReadMySQL reads the binary log settings of a MySQL, MariaDB or Aurora MySQL primary
+func ReadMySQL(ctx context.Context, db *sql.DB) (MySQLState, error) {
+	s := MySQLState{Flavor: "mysql"}
+	var version, auroraVersion string
+	if err := db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version); err != nil {
+		return s, fmt.Errorf("failed to read version: %w", err)
+	}
+	// @@aurora_version is an unknown variable elsewhere
+	switch {
+	case strings.Contains(strings.ToLower(version), "mariadb"):
+		s.Flavor = "mariadb"
+	case db.QueryRowContext(ctx, "SELECT @@aurora_version").Scan(&auroraVersion) == nil:
+		s.Flavor = "aurora"
+	}
+
+	// MariaDB always writes GTIDs and has no gtid_mode
+	gtidMode := "@@gtid_mode"
+	if s.Flavor == "mariadb" {
+		gtidMode = "'ON'"
+	}
+	err := db.QueryRowContext(ctx, "SELECT @@log_bin, @@binlog_format, @@binlog_row_image, @@server_id, "+gtidMode).
+		Scan(&s.LogBin, &s.BinlogFormat, &s.BinlogRowImage, &s.ServerID, &s.GTIDMode)
+	if err != nil {
+		return s, fmt.Errorf("failed to read binary log settings: %w", err)
+	}
+
+	if s.Flavor == "aurora" {
+		var hours sql.NullString
+		err := db.QueryRowContext(ctx, "SELECT value FROM mysql.rds_configuration WHERE name = 'binlog retention hours'").Scan(&hours)
+		if err != nil && err != sql.ErrNoRows {
+			return s, fmt.Errorf("failed to read binlog retention: %w", err)
+		}
+		s.BinlogRetentionHours = hours.String
+	}
+	return s, nil
+}
+
+// Checks returns the readiness report of the primary. These settings are only read at
+// startup, so fixing them takes a change to my.cnf, or Aurora's DB cluster parameter
+// group, and a restart.
+func (s MySQLState) Checks() Report {
+	config := "my.cnf"
+	if s.Flavor == "aurora" {
+		config = "the DB cluster parameter group"
+	}
+	report := Report{
+		check("log_bin", s.LogBin, Fail, "binary logging is off; set log_bin in "+config),
+		check("binlog_format", strings.EqualFold(s.BinlogFormat, "ROW"), Fail, fmt.Sprintf("is %s; must be ROW", s.BinlogFormat)),
+		check("binlog_row_image", strings.EqualFold(s.BinlogRowImage, "FULL"), Warn, fmt.Sprintf("is %s; FULL is recommended, otherwise left-out columns are fetched from the primary", s.BinlogRowImage)),
+		check("server_id", s.ServerID > 0, Fail, "is 0; set a unique server-id in "+config),
+		check("gtid_mode", strings.EqualFold(s.GTIDMode, "ON"), Warn, fmt.Sprintf("is %s; ON is recommended so changes carry transaction IDs", s.GTIDMode)),
+	}
+	if s.Flavor == "aurora" {
+		report = append(report, check("binlog retention", s.BinlogRetentionHours != "", Warn,
+			"isn't set, so binlogs can be purged before they are read; CALL mysql.rds_set_configuration('binlog retention hours', 24)"))
+	}
+	return append(report, Check{Name: "ddl capture", Status: OK, Detail: "DDL is read from the binary log"})
+}
@@ -0,0 +1,254 @@
+package setup
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+const (
+	// DDLLogTable is the table DDL statements are logged to. pg-change-stream reads inserts
+	// into public.kasho_ddl_log as DDL changes.
+	DDLLogTable = "kasho_ddl_log"
+	// Publication is the publication pg-change-stream subscribes to
+	Publication = "kasho_pub"
+
+	captureTrigger = "kasho_capture_ddl"
+	logTrigger     = "kasho_log_ddl"
+)
+
+// dropEventTriggers removes the event triggers first, so the setup's own DDL isn't logged
+var dropEventTriggers = []string{
+	`DROP EVENT TRIGGER IF EXISTS kasho_capture_ddl`,
+	`DROP EVENT TRIGGER IF EXISTS kasho_log_ddl`,
+}
+
+// ddlLogObjects are the statements creating the DDL log and its functions. They are safe
+// to run again: the log keeps its entries and the functions are replaced.
+var ddlLogObjects = []string{
+	`CREATE TABLE IF NOT EXISTS public.kasho_ddl_log (
+		id SERIAL PRIMARY KEY,
+		lsn pg_lsn NOT NULL,
+		ts TIMESTAMPTZ NOT NULL DEFAULT now(),
+		username TEXT,
+		database TEXT,
+		ddl TEXT NOT NULL
+	)`,
+
+	// Entries older than 7 days have been streamed long ago
+	`CREATE OR REPLACE FUNCTION public.kasho_cleanup_old_ddl_logs()
+	RETURNS void
+	LANGUAGE plpgsql
+	SET search_path = pg_catalog, pg_temp
+	AS $$
+	BEGIN
+		DELETE FROM public.kasho_ddl_log WHERE ts < now() - INTERVAL '7 days';
+	END;
+	$$`,
+
+	// Cleaning up on every 1000th entry keeps the log small without counting its rows
+	`CREATE OR REPLACE FUNCTION public.kasho_trigger_cleanup_ddl_logs()
+	RETURNS trigger
+	LANGUAGE plpgsql
+	SET search_path = pg_catalog, pg_temp
+	AS $$
+	BEGIN
+		IF NEW.id % 1000 = 0 THEN
+			PERFORM public.kasho_cleanup_old_ddl_logs();
+		END IF;
+		RETURN NEW;
+	END;
+	$$`,
+	`DROP TRIGGER IF EXISTS kasho_cleanup_ddl_logs_trigger ON public.kasho_ddl_log`,
+	`CREATE TRIGGER kasho_cleanup_ddl_logs_trigger
+		AFTER INSERT ON public.kasho_ddl_log
+		FOR EACH ROW
+		EXECUTE FUNCTION public.kasho_trigger_cleanup_ddl_logs()`,
+
+	// The statement is captured when the command starts, since current_query() is the
+	// client's statement there
+	`CREATE OR REPLACE FUNCTION public.kasho_capture_ddl_command()
+	RETURNS event_trigger
+	LANGUAGE plpgsql
+	SET search_path = pg_catalog, pg_temp
+	AS $$
+	BEGIN
+		PERFORM set_config('kasho.ddl_command', current_query(), true);
+	END;
+	$$`,
+
+	// The statement is logged once the command succeeded. A statement running several
+	// commands (CREATE TABLE with a SERIAL column, or several statements sent at once) is
+	// logged once per transaction, and commands without a captured statement are skipped
+	// rather than failing the user's DDL on the NOT NULL column.
+	`CREATE OR REPLACE FUNCTION public.kasho_log_ddl_command()
+	RETURNS event_trigger
+	LANGUAGE plpgsql
+	SET search_path = pg_catalog, pg_temp
+	AS $$
+	DECLARE
+		ddl_stmt TEXT;
+	BEGIN
+		ddl_stmt := current_setting('kasho.ddl_command', true);
+		IF ddl_stmt IS NULL OR ddl_stmt = '' OR ddl_stmt = current_setting('kasho.ddl_logged', true) THEN
+			RETURN;
+		END IF;
+
+		INSERT INTO public.kasho_ddl_log(lsn, ddl, username, database)
+		VALUES (pg_current_wal_lsn(), ddl_stmt, session_user, current_database());
+		PERFORM set_config('kasho.ddl_logged', ddl_stmt, true);
+	END;
+	$$`,
+}
+
+// createEventTriggers installs the event triggers last, once everything they call exists
+var createEventTriggers = []string{
+	`CREATE EVENT TRIGGER kasho_capture_ddl
+		ON ddl_command_start
+		EXECUTE FUNCTION public.kasho_capture_ddl_command()`,
+	`CREATE EVENT TRIGGER kasho_log_ddl
+		ON ddl_command_end
+		EXECUTE FUNCTION public.kasho_log_ddl_command()`,
+}
+
+// PostgresState is what a PostgreSQL primary reports about its replication setup
+type PostgresState struct {
+	VersionNum          int
+	Superuser           bool
+	WalLevel            string
+	MaxReplicationSlots int
+	MaxWalSenders       int
+	// PublicationExists and PublicationAllTables describe kasho_pub; PublishesDDLLog is
+	// whether it includes kasho_ddl_log
+	PublicationExists    bool
+	PublicationAllTables bool
+	PublishesDDLLog      bool
+	DDLLogExists         bool
+	// EventTriggers maps the kasho event triggers that exist to their evtenabled flag
+	EventTriggers map[string]string
+}
+
+// ReadPostgres reads the replication setup of a PostgreSQL primary
+func ReadPostgres(ctx context.Context, db *sql.DB) (PostgresState, error) {
+	var s PostgresState
+	err := db.QueryRowContext(ctx, `SELECT current_setting('server_version_num')::int,
+		(SELECT rolsuper FROM pg_roles WHERE rolname = current_user),
+		current_setting('wal_level'),
+		current_setting('max_replication_slots')::int,
+		current_setting('max_wal_senders')::int,
+		to_regclass('public.kasho_ddl_log') IS NOT NULL`).
+		Scan(&s.VersionNum, &s.Superuser, &s.WalLevel, &s.MaxReplicationSlots, &s.MaxWalSenders, &s.DDLLogExists)
+	if err != nil {
+		return s, fmt.Errorf("failed to read server settings: %w", err)
+	}
+
+	err = db.QueryRowContext(ctx, `SELECT puballtables,
+		EXISTS (SELECT 1 FROM pg_publication_tables WHERE pubname = $1 AND schemaname = 'public' AND tablename = $2)
+		FROM pg_publication WHERE pubname = $1`, Publication, DDLLogTable).
+		Scan(&s.PublicationAllTables, &s.PublishesDDLLog)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return s, fmt.Errorf("failed to read publication: %w", err)
+	default:
+		s.PublicationExists = true
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT evtname, evtenabled FROM pg_event_trigger WHERE evtname IN ($1, $2)`, captureTrigger, logTrigger)
+	if err != nil {
+		return s, fmt.Errorf("failed to read event triggers: %w", err)
+	}
+	defer rows.Close()
+	s.EventTriggers = map[string]string{}
+	for rows.Next() {
+		var name, enabled string
+		if err := rows.Scan(&name, &enabled); err != nil {
+			return s, fmt.Errorf("failed to read event triggers: %w", err)
+		}
+		s.EventTriggers[name] = enabled
+	}
+	return s, rows.Err()
+}
+
+// Checks returns the readiness report of the primary
+func (s PostgresState) Checks() Report {
+	report := Report{
+		check("version", s.VersionNum >= 110000, Fail, fmt.Sprintf("server_version_num is %d; PostgreSQL 11 or later is required", s.VersionNum)),
+		check("wal_level", s.WalLevel == "logical", Fail, fmt.Sprintf("is %s; must be logical, which takes a restart", s.WalLevel)),
+		check("max_replication_slots", s.MaxReplicationSlots > 0, Fail, fmt.Sprintf("is %d; kasho needs a slot", s.MaxReplicationSlots)),
+		check("max_wal_senders", s.MaxWalSenders > 0, Fail, fmt.Sprintf("is %d; kasho needs a WAL sender", s.MaxWalSenders)),
+	}
+
+	switch {
+	case !s.PublicationExists:
+		report = append(report, Check{Name: "publication", Status: Fail, Detail: Publication + " doesn't exist"})
+	case s.PublicationAllTables:
+		report = append(report, Check{Name: "publication", Status: OK, Detail: Publication + " publishes all tables"})
+	case !s.PublishesDDLLog:
+		report = append(report, Check{Name: "publication", Status: Fail, Detail: Publication + " doesn't publish " + DDLLogTable + ", so DDL isn't streamed"})
+	default:
+		report = append(report, Check{Name: "publication", Status: Warn, Detail: Publication + " publishes listed tables only; tables created later aren't replicated"})
+	}
+
+	report = append(report, check("ddl log", s.DDLLogExists, Fail, "public."+DDLLogTable+" doesn't exist"))
+	for _, name := range []string{captureTrigger, logTrigger} {
+		enabled, ok := s.EventTriggers[name]
+		switch {
+		case !ok:
+			report = append(report, Check{Name: "event trigger", Status: Fail, Detail: name + " doesn't exist"})
+		case enabled == "D":
+			report = append(report, Check{Name: "event trigger", Status: Fail, Detail: name + " is disabled"})
+		default:
+			report = append(report, Check{Name: "event trigger", Status: OK, Detail: name})
+		}
+	}
+	return report
+}
+
+// InstallPostgres installs the DDL log, its capture functions and event triggers, and the
+// publication, in one transaction. wal_level is set with ALTER SYSTEM if needed, which
+// takes effect after a restart.
+func InstallPostgres(ctx context.Context, db *sql.DB, state PostgresState) error {
+	if !state.Superuser {
+		return fmt.Errorf("event triggers can only be created by a superuser; connect as one")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := append(append([]string{}, dropEventTriggers...), ddlLogObjects...)
+	switch {
+	case !state.PublicationExists:
+		statements = append(statements, fmt.Sprintf("CREATE PUBLICATION %s FOR ALL TABLES", Publication))
+	case !state.PublicationAllTables && !state.PublishesDDLLog:
+		statements = append(statements, fmt.Sprintf("ALTER PUBLICATION %s ADD TABLE public.%s", Publication, DDLLogTable))
+	}
+	statements = append(statements, createEventTriggers...)
+
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to execute %q: %w", firstLine(statement), err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+
+	// ALTER SYSTEM can't run in a transaction
+	if state.WalLevel != "logical" {
+		if _, err := db.ExecContext(ctx, "ALTER SYSTEM SET wal_level = logical"); err != nil {
+			return fmt.Errorf("failed to set wal_level: %w", err)
+		}
+	}
+	return nil
+}
+
+// firstLine returns the first line of a statement for error messages
+func firstLine(statement string) string {
+	line, _, _ := strings.Cut(statement, "\n")
+	return line
+}
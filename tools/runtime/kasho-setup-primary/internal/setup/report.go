@@ -0,0 +1,57 @@
+package setup
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Status is the outcome of a readiness check
+type Status string
+
+const (
+	OK   Status = "ok"
+	Warn Status = "warn"
+	Fail Status = "fail"
+)
+
+// Check is one item of the readiness report
+type Check struct {
+	Name   string
+	Status Status
+	Detail string
+}
+
+// Report is the readiness report of a primary
+type Report []Check
+
+// Failed returns how many checks failed. Warnings don't stop replication, so they aren't
+// counted.
+func (r Report) Failed() int {
+	failed := 0
+	for _, check := range r {
+		if check.Status == Fail {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Write writes the report as a table
+func (r Report) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tCHECK\tDETAIL")
+	for _, check := range r {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", check.Status, check.Name, check.Detail)
+	}
+	return tw.Flush()
+}
+
+// check builds a check that passes when ok holds, and otherwise has the given status and
+// explains why
+func check(name string, ok bool, otherwise Status, detail string) Check {
+	if ok {
+		return Check{Name: name, Status: OK}
+	}
+	return Check{Name: name, Status: otherwise, Detail: detail}
+}
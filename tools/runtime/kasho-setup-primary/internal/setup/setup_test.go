@@ -0,0 +1,159 @@
+package setup
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// statuses maps each check's name and detail to its status
+func statuses(report Report) map[string]Status {
+	m := map[string]Status{}
+	for _, check := range report {
+		m[check.Name+": "+check.Detail] = check.Status
+	}
+	return m
+}
+
+func readyPostgres() PostgresState {
+	return PostgresState{
+		VersionNum:           160002,
+		Superuser:            true,
+		WalLevel:             "logical",
+		MaxReplicationSlots:  10,
+		MaxWalSenders:        10,
+		PublicationExists:    true,
+		PublicationAllTables: true,
+		PublishesDDLLog:      true,
+		DDLLogExists:         true,
+		EventTriggers:        map[string]string{captureTrigger: "O", logTrigger: "O"},
+	}
+}
+
+func TestPostgresChecks(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*PostgresState)
+		want   map[string]Status
+	}{
+		{
+			name:   "ready",
+			modify: func(s *PostgresState) {},
+		},
+		{
+			name:   "wal_level replica",
+			modify: func(s *PostgresState) { s.WalLevel = "replica" },
+			want:   map[string]Status{"wal_level: is replica; must be logical, which takes a restart": Fail},
+		},
+		{
+			name: "publication missing",
+			modify: func(s *PostgresState) {
+				s.PublicationExists, s.PublicationAllTables, s.PublishesDDLLog = false, false, false
+			},
+			want: map[string]Status{"publication: kasho_pub doesn't exist": Fail},
+		},
+		{
+			name:   "publication without ddl log",
+			modify: func(s *PostgresState) { s.PublicationAllTables, s.PublishesDDLLog = false, false },
+			want:   map[string]Status{"publication: kasho_pub doesn't publish kasho_ddl_log, so DDL isn't streamed": Fail},
+		},
+		{
+			name:   "publication of listed tables",
+			modify: func(s *PostgresState) { s.PublicationAllTables = false },
+			want:   map[string]Status{"publication: kasho_pub publishes listed tables only; tables created later aren't replicated": Warn},
+		},
+		{
+			name: "ddl capture missing",
+			modify: func(s *PostgresState) {
+				s.DDLLogExists = false
+				s.EventTriggers = map[string]string{logTrigger: "D"}
+			},
+			want: map[string]Status{
+				"ddl log: public.kasho_ddl_log doesn't exist":    Fail,
+				"event trigger: kasho_capture_ddl doesn't exist": Fail,
+				"event trigger: kasho_log_ddl is disabled":       Fail,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := readyPostgres()
+			tt.modify(&state)
+			got := statuses(state.Checks())
+			for key, status := range got {
+				want, ok := tt.want[key]
+				if !ok {
+					want = OK
+				}
+				if status != want {
+					t.Errorf("check %q = %s, want %s", key, status, want)
+				}
+			}
+			for key := range tt.want {
+				if _, ok := got[key]; !ok {
+					t.Errorf("missing check %q in %v", key, got)
+				}
+			}
+		})
+	}
+}
+
+func TestMySQLChecks(t *testing.T) {
+	state := MySQLState{LogBin: true, BinlogFormat: "ROW", BinlogRowImage: "MINIMAL", ServerID: 0, GTIDMode: "OFF"}
+	report := state.Checks()
+
+	got := statuses(report)
+	if got["binlog_row_image: is MINIMAL; FULL is recommended, otherwise left-out columns are fetched from the primary"] != Warn {
+		t.Errorf("binlog_row_image check = %v, want a warning", report)
+	}
+	if got["server_id: is 0; set a unique server-id in my.cnf"] != Fail {
+		t.Errorf("server_id check = %v, want a failure", report)
+	}
+	if got["gtid_mode: is OFF; ON is recommended so changes carry transaction IDs"] != Warn {
+		t.Errorf("gtid_mode check = %v, want a warning", report)
+	}
+	if report.Failed() != 1 {
+		t.Errorf("Failed() = %d, want 1; warnings don't count", report.Failed())
+	}
+}
+
+func TestMySQLChecksAurora(t *testing.T) {
+	state := MySQLState{Flavor: "aurora", LogBin: false, BinlogFormat: "ROW", BinlogRowImage: "FULL", ServerID: 1, GTIDMode: "ON"}
+
+	got := statuses(state.Checks())
+	if got["log_bin: binary logging is off; set log_bin in the DB cluster parameter group"] != Fail {
+		t.Errorf("log_bin check = %v, want a failure pointing at the parameter group", got)
+	}
+	if got["binlog retention: isn't set, so binlogs can be purged before they are read; CALL mysql.rds_set_configuration('binlog retention hours', 24)"] != Warn {
+		t.Errorf("binlog retention check = %v, want a warning", got)
+	}
+
+	state.BinlogRetentionHours = "24"
+	if got := statuses(state.Checks()); got["binlog retention: "] != OK {
+		t.Errorf("binlog retention check = %v, want OK once set", got)
+	}
+
+	// Only Aurora has a retention setting to check
+	state.Flavor = "mariadb"
+	for key := range statuses(state.Checks()) {
+		if strings.HasPrefix(key, "binlog retention") {
+			t.Errorf("%s checked on MariaDB", key)
+		}
+	}
+}
+
+func TestReportWrite(t *testing.T) {
+	report := Report{
+		{Name: "wal_level", Status: OK},
+		{Name: "publication", Status: Fail, Detail: "kasho_pub doesn't exist"},
+	}
+	var buf bytes.Buffer
+	if err := report.Write(&buf); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[2], "fail") || !strings.HasSuffix(lines[2], "kasho_pub doesn't exist") {
+		t.Errorf("Write() = %q, want a header and a line per check", buf.String())
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	"github.com/spf13/cobra"
+	"kasho-setup-primary/internal/setup"
+	"kasho/pkg/dialect"
+	"kasho/pkg/version"
+)
+
+var (
+	primaryURL string
+	checkOnly  bool
+)
+
+func main() {
+	rootCmd := &cobra.Command{
+		Use:   "kasho-setup-primary",
+		Short: "Prepare a primary database for kasho and report whether it is ready",
+		Long: `kasho-setup-primary prepares a primary database for replication and prints a readiness
+report.
+
+On PostgreSQL it installs the kasho_ddl_log table, the functions and event triggers that
+log DDL statements to it, and the kasho_pub publication. It can be run again: the DDL log
+keeps its entries and the functions and triggers are replaced. wal_level is set to
+logical if needed, which takes effect after a restart. Creating event triggers requires
+a superuser.
+
+On MySQL DDL statements are read from the binary log, so nothing is installed; the
+binary log settings are checked.
+
+The command fails if the primary isn't ready.`,
+		SilenceUsage: true,
+		RunE:         runSetup,
+	}
+
+	rootCmd.Flags().StringVarP(&primaryURL, "primary-url", "p", "", "Primary database connection URL (required)")
+	rootCmd.Flags().BoolVar(&checkOnly, "check", false, "Only print the readiness report; don't change the primary")
+
+	rootCmd.MarkFlagRequired("primary-url")
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runSetup(cmd *cobra.Command, args []string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	slog.Info("Starting kasho-setup-primary",
+		"version", version.Version,
+		"commit", version.GitCommit,
+		"built", version.BuildDate,
+	)
+
+	primaryDialect, err := dialect.FromConnectionString(primaryURL)
+	if err != nil {
+		return fmt.Errorf("failed to determine primary dialect: %w", err)
+	}
+	primary, err := sql.Open(primaryDialect.GetDriverName(), primaryDialect.FormatDSN(primaryURL))
+	if err != nil {
+		return fmt.Errorf("failed to open primary: %w", err)
+	}
+	defer primary.Close()
+	if err := primary.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to connect to primary: %w", err)
+	}
+
+	var report setup.Report
+	switch primaryDialect.Name() {
+	case "postgresql":
+		state, err := setup.ReadPostgres(ctx, primary)
+		if err != nil {
+			return err
+		}
+		if !checkOnly {
+			if err := setup.InstallPostgres(ctx, primary, state); err != nil {
+				return err
+			}
+			slog.Info("Installed DDL logging and publication", "table", setup.DDLLogTable, "publication", setup.Publication)
+			if state, err = setup.ReadPostgres(ctx, primary); err != nil {
+				return err
+			}
+		}
+		report = state.Checks()
+	case "mysql":
+		state, err := setup.ReadMySQL(ctx, primary)
+		if err != nil {
+			return err
+		}
+		report = state.Checks()
+	default:
+		return fmt.Errorf("%s primaries aren't supported", primaryDialect.Name())
+	}
+
+	if err := report.Write(os.Stdout); err != nil {
+		return err
+	}
+	if failed := report.Failed(); failed > 0 {
+		return fmt.Errorf("primary is not ready: %d checks failed", failed)
+	}
+	fmt.Println("Primary is ready for kasho")
+	return nil
+}
@@ -0,0 +1,217 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"kasho/pkg/transform"
+	"kasho/proto"
+)
+
+// Column is a column of a previewed row
+type Column struct {
+	Name      string
+	Transform string // empty if the column has none
+	Before    string
+	After     string
+}
+
+// Row is a previewed sample row; Number counts from 1
+type Row struct {
+	Number  int
+	Columns []Column
+}
+
+// ReadSample reads sample rows of a table from a CSV file with a header, or a JSON array
+// of objects. Values are converted to the data types of the table's columns in the schema,
+// if given; empty CSV values are NULL.
+func ReadSample(path string, columnTypes map[string]string) ([]*proto.DMLData, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sample: %w", err)
+	}
+
+	var rows []*proto.DMLData
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		rows, err = readJSON(data, columnTypes)
+	} else {
+		rows, err = readCSV(data, columnTypes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sample %s: %w", path, err)
+	}
+	return rows, nil
+}
+
+func readCSV(data []byte, columnTypes map[string]string) ([]*proto.DMLData, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("missing header")
+	}
+
+	header := records[0]
+	var rows []*proto.DMLData
+	for _, record := range records[1:] {
+		row := &proto.DMLData{ColumnNames: header}
+		for i, text := range record {
+			value := &proto.ColumnValue{}
+			if text != "" {
+				value, err = transform.ParseValue(columnTypes[header[i]], text)
+				if err != nil {
+					return nil, fmt.Errorf("row %d, column %s: %w", len(rows)+1, header[i], err)
+				}
+			}
+			row.ColumnValues = append(row.ColumnValues, value)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// readJSON reads an array of objects, keeping the order of their keys as the column order
+func readJSON(data []byte, columnTypes map[string]string) ([]*proto.DMLData, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, fmt.Errorf("expected an array of rows")
+	}
+
+	var rows []*proto.DMLData
+	for dec.More() {
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return nil, fmt.Errorf("row %d: expected an object", len(rows)+1)
+		}
+		row := &proto.DMLData{}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			column := tok.(string)
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return nil, err
+			}
+			value, err := jsonValue(raw, columnTypes[column])
+			if err != nil {
+				return nil, fmt.Errorf("row %d, column %s: %w", len(rows)+1, column, err)
+			}
+			row.ColumnNames = append(row.ColumnNames, column)
+			row.ColumnValues = append(row.ColumnValues, value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// jsonValue converts a JSON value to a column value. Strings and numbers are converted to
+// the column's data type if it's known; objects and arrays become JSON values.
+func jsonValue(raw json.RawMessage, dataType string) (*proto.ColumnValue, error) {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return &proto.ColumnValue{}, nil
+	case bool:
+		return &proto.ColumnValue{Value: &proto.ColumnValue_BoolValue{BoolValue: v}}, nil
+	case string:
+		return transform.ParseValue(dataType, v)
+	case json.Number:
+		if dataType != "" {
+			return transform.ParseValue(dataType, v.String())
+		}
+		if i, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			return &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: i}}, nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return &proto.ColumnValue{Value: &proto.ColumnValue_FloatValue{FloatValue: f}}, nil
+	default:
+		return &proto.ColumnValue{Value: &proto.ColumnValue_JsonValue{JsonValue: string(raw)}}, nil
+	}
+}
+
+// Preview transforms sample rows of a table the way the translicator would transform
+// them as inserts
+func Preview(config *transform.Config, table string, rows []*proto.DMLData) ([]Row, error) {
+	var result []Row
+	for i, row := range rows {
+		// NULLs are passed through untouched, so leave them out of the change
+		dml := &proto.DMLData{Table: table, Kind: "insert"}
+		for j, value := range row.ColumnValues {
+			if value.GetValue() != nil {
+				dml.ColumnNames = append(dml.ColumnNames, row.ColumnNames[j])
+				dml.ColumnValues = append(dml.ColumnValues, value)
+			}
+		}
+		transformed, err := transform.TransformChange(config, &proto.Change{Type: "dml", Data: &proto.Change_Dml{Dml: dml}})
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+		after := make(map[string]*proto.ColumnValue)
+		for j, name := range transformed.GetDml().ColumnNames {
+			after[name] = transformed.GetDml().ColumnValues[j]
+		}
+
+		previewed := Row{Number: i + 1}
+		for j, name := range row.ColumnNames {
+			column := Column{
+				Name:   name,
+				Before: transform.FormatValue(row.ColumnValues[j]),
+				After:  transform.FormatValue(after[name]),
+			}
+			if ct, ok := config.ColumnTransform(table, name); ok {
+				column.Transform = string(ct.Type)
+			}
+			previewed.Columns = append(previewed.Columns, column)
+		}
+		result = append(result, previewed)
+	}
+	return result, nil
+}
+
+// Write prints previewed rows as a table of before and after values. Only columns with a
+// transform are printed, unless all is set.
+func Write(w io.Writer, rows []Row, all bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROW\tCOLUMN\tTRANSFORM\tBEFORE\tAFTER")
+	for _, row := range rows {
+		for _, column := range row.Columns {
+			if column.Transform == "" && !all {
+				continue
+			}
+			transformName := column.Transform
+			if transformName == "" {
+				transformName = "-"
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", row.Number, column.Name, transformName, printable(column.Before), printable(column.After))
+		}
+	}
+	return tw.Flush()
+}
+
+// printable keeps a value on one line of the table
+func printable(value string) string {
+	return strings.NewReplacer("\n", `\n`, "\t", `\t`).Replace(value)
+}
@@ -0,0 +1,106 @@
+package preview
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"kasho/pkg/transform"
+	"kasho/proto"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadSampleCSV(t *testing.T) {
+	path := writeFile(t, "users.csv", "id,email,nickname\n1,jane@example.com,\n")
+	rows, err := ReadSample(path, map[string]string{"id": "bigint", "email": "text"})
+	if err != nil {
+		t.Fatalf("ReadSample() unexpected error: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("ReadSample() = %d rows, want 1", len(rows))
+	}
+	values := rows[0].ColumnValues
+	if values[0].GetIntValue() != 1 {
+		t.Errorf("id = %v, want the integer 1", values[0])
+	}
+	if values[1].GetStringValue() != "jane@example.com" {
+		t.Errorf("email = %v, want jane@example.com", values[1])
+	}
+	if values[2].GetValue() != nil {
+		t.Errorf("nickname = %v, want NULL", values[2])
+	}
+}
+
+func TestReadSampleJSONKeepsColumnOrder(t *testing.T) {
+	path := writeFile(t, "users.json", `[{"zip": "10001", "id": 7, "score": 1.5, "tags": ["a"], "deleted": null}]`)
+	rows, err := ReadSample(path, nil)
+	if err != nil {
+		t.Fatalf("ReadSample() unexpected error: %v", err)
+	}
+	if got := strings.Join(rows[0].ColumnNames, ","); got != "zip,id,score,tags,deleted" {
+		t.Errorf("columns = %s, want the order of the sample", got)
+	}
+	values := rows[0].ColumnValues
+	if values[1].GetIntValue() != 7 || values[2].GetFloatValue() != 1.5 || values[3].GetJsonValue() != `["a"]` || values[4].GetValue() != nil {
+		t.Errorf("values = %v", values)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	config := &transform.Config{Tables: map[string]transform.TableConfig{
+		"users": {"email": {Type: transform.FakeEmail}},
+	}}
+	rows := []*proto.DMLData{{
+		ColumnNames: []string{"id", "email", "note"},
+		ColumnValues: []*proto.ColumnValue{
+			{Value: &proto.ColumnValue_IntValue{IntValue: 1}},
+			{Value: &proto.ColumnValue_StringValue{StringValue: "jane@example.com"}},
+			{},
+		},
+	}}
+
+	previewed, err := Preview(config, "users", rows)
+	if err != nil {
+		t.Fatalf("Preview() unexpected error: %v", err)
+	}
+	email := previewed[0].Columns[1]
+	if email.Transform != "FakeEmail" || email.Before != "jane@example.com" || email.After == email.Before {
+		t.Errorf("email = %+v, want it transformed by FakeEmail", email)
+	}
+	if note := previewed[0].Columns[2]; note.Before != "NULL" || note.After != "NULL" {
+		t.Errorf("note = %+v, want NULL before and after", note)
+	}
+
+	var out strings.Builder
+	if err := Write(&out, previewed, false); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(strings.Join(strings.Fields(lines[1]), " "), "1 email FakeEmail jane@example.com") {
+		t.Errorf("Write() =\n%s\nwant a header and the email column", out.String())
+	}
+}
+
+func TestLoadSchemaCSV(t *testing.T) {
+	path := writeFile(t, "schema.csv", "table_schema,table_name,column_name,data_type\npublic,users,id,bigint\npublic,users,email,text\n")
+	schema, err := LoadSchema(path)
+	if err != nil {
+		t.Fatalf("LoadSchema() unexpected error: %v", err)
+	}
+	if schema["public.users"]["id"] != "bigint" || schema["public.users"]["email"] != "text" {
+		t.Errorf("LoadSchema() = %v", schema)
+	}
+
+	if _, err := LoadSchema(writeFile(t, "schema.csv", "table,column\nusers,id\n")); err == nil {
+		t.Error("LoadSchema() should reject a header without data_type")
+	}
+}
@@ -0,0 +1,73 @@
+package preview
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kasho/pkg/transform"
+)
+
+// LoadSchema reads a schema dump: a JSON object mapping tables to their columns' data
+// types, or a CSV file with a header and table_name, column_name and data_type columns,
+// e.g. exported from information_schema.columns. With a table_schema column too, tables
+// are named schema.table.
+func LoadSchema(path string) (transform.Schema, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open schema: %w", err)
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var schema transform.Schema
+		if err := json.NewDecoder(f).Decode(&schema); err != nil {
+			return nil, fmt.Errorf("failed to parse schema %s: %w", path, err)
+		}
+		return schema, nil
+	}
+	schema, err := readSchemaCSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema %s: %w", path, err)
+	}
+	return schema, nil
+}
+
+func readSchemaCSV(r io.Reader) (transform.Schema, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("missing header")
+	}
+
+	index := make(map[string]int)
+	for i, name := range records[0] {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	tableIndex, ok := index["table_name"]
+	columnIndex, ok2 := index["column_name"]
+	typeIndex, ok3 := index["data_type"]
+	if !ok || !ok2 || !ok3 {
+		return nil, fmt.Errorf("header must name the table_name, column_name and data_type columns")
+	}
+	schemaIndex, qualified := index["table_schema"]
+
+	schema := make(transform.Schema)
+	for _, record := range records[1:] {
+		table := record[tableIndex]
+		if qualified {
+			table = record[schemaIndex] + "." + table
+		}
+		if schema[table] == nil {
+			schema[table] = make(map[string]string)
+		}
+		schema[table][record[columnIndex]] = record[typeIndex]
+	}
+	return schema, nil
+}
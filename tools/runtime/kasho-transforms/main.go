@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"kasho-transforms/internal/preview"
+	"kasho/pkg/transform"
+)
+
+var (
+	configFile string
+	schemaFile string
+	table      string
+	sampleFile string
+	allColumns bool
+)
+
+func main() {
+	rootCmd := &cobra.Command{
+		Use:   "kasho-transforms",
+		Short: "Check and preview a transforms config",
+		Long: `kasho-transforms checks a transforms.yml before it's deployed: lint reports every
+problem the translicator would reject it for or run into while transforming, and preview
+shows how sample rows of a table look before and after the transforms, for review.`,
+		SilenceUsage: true,
+	}
+	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "transforms.yml", "Path to transforms.yml")
+	rootCmd.PersistentFlags().StringVarP(&schemaFile, "schema", "s", "", "Schema dump of the source database: JSON, or CSV of information_schema.columns")
+
+	lintCmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Validate a transforms config",
+		Long: `Validate a transforms config: unknown transform types, invalid settings, regular
+expressions, templates and expressions, and, with --schema, tables and columns that don't
+exist and transforms that can't handle their column's type.`,
+		Args: cobra.NoArgs,
+		RunE: runLint,
+	}
+
+	previewCmd := &cobra.Command{
+		Use:   "preview",
+		Short: "Show sample rows of a table before and after the transforms",
+		Long: `Show sample rows of a table before and after the transforms. The sample is a CSV
+file with a header row or a JSON array of objects; with --schema, values are read as
+their columns' types. Only columns with a transform are shown, unless --all is set.`,
+		Args: cobra.NoArgs,
+		RunE: runPreview,
+	}
+	previewCmd.Flags().StringVarP(&table, "table", "t", "", "Table the sample rows belong to, as named under tables (required)")
+	previewCmd.Flags().StringVarP(&sampleFile, "sample", "f", "", "CSV or JSON file of sample rows (required)")
+	previewCmd.Flags().BoolVarP(&allColumns, "all", "a", false, "Show columns without a transform too")
+	previewCmd.MarkFlagRequired("table")
+	previewCmd.MarkFlagRequired("sample")
+
+	rootCmd.AddCommand(lintCmd, previewCmd)
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func loadSchema() (transform.Schema, error) {
+	if schemaFile == "" {
+		return nil, nil
+	}
+	return preview.LoadSchema(schemaFile)
+}
+
+func runLint(cmd *cobra.Command, args []string) error {
+	schema, err := loadSchema()
+	if err != nil {
+		return err
+	}
+	findings, err := transform.LintFile(configFile, schema)
+	if err != nil {
+		return err
+	}
+
+	for _, finding := range findings {
+		fmt.Fprintln(cmd.OutOrStdout(), finding)
+	}
+	if len(findings) > 0 {
+		return fmt.Errorf("%s has %d problems", configFile, len(findings))
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", configFile)
+	return nil
+}
+
+func runPreview(cmd *cobra.Command, args []string) error {
+	config, err := transform.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load transforms config: %w", err)
+	}
+	schema, err := loadSchema()
+	if err != nil {
+		return err
+	}
+
+	rows, err := preview.ReadSample(sampleFile, schema[table])
+	if err != nil {
+		return err
+	}
+	previewed, err := preview.Preview(config, table, rows)
+	if err != nil {
+		return err
+	}
+	return preview.Write(cmd.OutOrStdout(), previewed, allColumns)
+}
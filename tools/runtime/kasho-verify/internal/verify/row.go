@@ -0,0 +1,148 @@
+package verify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"kasho/proto"
+)
+
+// row is a single table row read in primary key order
+type row struct {
+	columns []string
+	values  []*proto.ColumnValue
+	key     []*proto.ColumnValue
+}
+
+// toColumnValue converts a value scanned by database/sql into a ColumnValue.
+// Drivers return some numeric columns as text (MySQL always does over the text protocol),
+// so the database type name is used to recover ints and floats.
+func toColumnValue(raw any, typeName string) *proto.ColumnValue {
+	switch v := raw.(type) {
+	case nil:
+		return nil
+	case int64:
+		return &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: v}}
+	case float64:
+		return &proto.ColumnValue{Value: &proto.ColumnValue_FloatValue{FloatValue: v}}
+	case bool:
+		return &proto.ColumnValue{Value: &proto.ColumnValue_BoolValue{BoolValue: v}}
+	case time.Time:
+		return &proto.ColumnValue{Value: &proto.ColumnValue_TimestampValue{TimestampValue: v.UTC().Format(time.RFC3339Nano)}}
+	case []byte:
+		if isBinaryType(typeName) {
+			return &proto.ColumnValue{Value: &proto.ColumnValue_BytesValue{BytesValue: v}}
+		}
+		return textColumnValue(string(v), typeName)
+	case string:
+		return textColumnValue(v, typeName)
+	default:
+		return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: fmt.Sprint(v)}}
+	}
+}
+
+// isBinaryType reports whether a database type name is a binary type
+func isBinaryType(typeName string) bool {
+	switch strings.ToUpper(typeName) {
+	case "BYTEA", "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BINARY", "VARBINARY":
+		return true
+	}
+	return false
+}
+
+func textColumnValue(s string, typeName string) *proto.ColumnValue {
+	switch strings.TrimPrefix(strings.ToUpper(typeName), "UNSIGNED ") {
+	case "INT", "INT2", "INT4", "INT8", "INTEGER", "TINYINT", "SMALLINT", "MEDIUMINT", "BIGINT":
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: i}}
+		}
+	case "FLOAT", "FLOAT4", "FLOAT8", "DOUBLE", "REAL":
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return &proto.ColumnValue{Value: &proto.ColumnValue_FloatValue{FloatValue: f}}
+		}
+	}
+	return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: s}}
+}
+
+// formatValue renders a ColumnValue as text for hashing and reporting.
+// Values are compared as text so an int written by the translicator matches
+// the same number read back from a text column.
+func formatValue(v *proto.ColumnValue) (string, bool) {
+	if v == nil || v.Value == nil {
+		return "", false
+	}
+	switch val := v.Value.(type) {
+	case *proto.ColumnValue_StringValue:
+		return val.StringValue, true
+	case *proto.ColumnValue_IntValue:
+		return strconv.FormatInt(val.IntValue, 10), true
+	case *proto.ColumnValue_FloatValue:
+		return strconv.FormatFloat(val.FloatValue, 'g', -1, 64), true
+	case *proto.ColumnValue_BoolValue:
+		return strconv.FormatBool(val.BoolValue), true
+	case *proto.ColumnValue_TimestampValue:
+		return val.TimestampValue, true
+	case *proto.ColumnValue_BytesValue:
+		return `\x` + hex.EncodeToString(val.BytesValue), true
+	case *proto.ColumnValue_JsonValue:
+		return val.JsonValue, true
+	case *proto.ColumnValue_UuidValue:
+		return val.UuidValue, true
+	case *proto.ColumnValue_GeometryValue:
+		return val.GeometryValue.GetWkt(), true
+	default:
+		return fmt.Sprint(v.Value), true
+	}
+}
+
+// formatKey renders a primary key for reports, e.g. "42" or "7,2024-01-01"
+func formatKey(key []*proto.ColumnValue) string {
+	parts := make([]string, len(key))
+	for i, v := range key {
+		if s, ok := formatValue(v); ok {
+			parts[i] = s
+		} else {
+			parts[i] = "NULL"
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// hashRows returns a hex SHA-256 over the rows in order. Columns are hashed by
+// name in sorted order so column position differences between primary and
+// replica don't matter; excluded columns are left out entirely.
+func hashRows(rows []row, excluded map[string]bool) string {
+	h := sha256.New()
+	for _, r := range rows {
+		order := make([]int, len(r.columns))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(a, b int) bool { return r.columns[order[a]] < r.columns[order[b]] })
+
+		for _, i := range order {
+			if excluded[r.columns[i]] {
+				continue
+			}
+			writeField(h, r.columns[i])
+			if s, ok := formatValue(r.values[i]); ok {
+				writeField(h, s)
+			} else {
+				h.Write([]byte("N;"))
+			}
+		}
+		h.Write([]byte("\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeField writes a length-prefixed field so adjacent values can't run together
+func writeField(h hash.Hash, s string) {
+	fmt.Fprintf(h, "%d:%s;", len(s), s)
+}
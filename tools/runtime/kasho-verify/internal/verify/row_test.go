@@ -0,0 +1,110 @@
+package verify
+
+import (
+	"testing"
+	"time"
+
+	"kasho/proto"
+)
+
+func TestToColumnValue(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 600000000, time.FixedZone("EST", -5*3600))
+
+	tests := []struct {
+		name     string
+		raw      any
+		typeName string
+		want     string
+		wantNull bool
+	}{
+		{name: "nil", raw: nil, wantNull: true},
+		{name: "int64", raw: int64(42), want: "42"},
+		{name: "float64", raw: 1.5, want: "1.5"},
+		{name: "bool", raw: true, want: "true"},
+		{name: "time is normalized to UTC", raw: ts, want: "2024-01-02T08:04:05.6Z"},
+		{name: "text", raw: []byte("hello"), typeName: "TEXT", want: "hello"},
+		{name: "mysql int as text", raw: []byte("7"), typeName: "INT", want: "7"},
+		{name: "unsigned bigint", raw: []byte("8"), typeName: "UNSIGNED BIGINT", want: "8"},
+		{name: "numeric stays text", raw: []byte("12.50"), typeName: "NUMERIC", want: "12.50"},
+		{name: "bytea", raw: []byte{0x00, 0xff}, typeName: "BYTEA", want: `\x00ff`},
+		{name: "mysql blob", raw: []byte{0x10}, typeName: "BLOB", want: `\x10`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := formatValue(toColumnValue(tt.raw, tt.typeName))
+			if tt.wantNull {
+				if ok {
+					t.Errorf("expected NULL, got %q", got)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("toColumnValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, ok := toColumnValue([]byte("7"), "INT").Value.(*proto.ColumnValue_IntValue); !ok {
+		t.Error("INT columns scanned as text should become int values")
+	}
+}
+
+func stringValue(s string) *proto.ColumnValue {
+	return &proto.ColumnValue{Value: &proto.ColumnValue_StringValue{StringValue: s}}
+}
+
+func intValue(i int64) *proto.ColumnValue {
+	return &proto.ColumnValue{Value: &proto.ColumnValue_IntValue{IntValue: i}}
+}
+
+func TestHashRows(t *testing.T) {
+	base := []row{{
+		columns: []string{"id", "name", "token"},
+		values:  []*proto.ColumnValue{intValue(1), stringValue("Jane"), stringValue("abc")},
+	}}
+	reordered := []row{{
+		columns: []string{"token", "id", "name"},
+		values:  []*proto.ColumnValue{stringValue("abc"), intValue(1), stringValue("Jane")},
+	}}
+	changed := []row{{
+		columns: []string{"id", "name", "token"},
+		values:  []*proto.ColumnValue{intValue(1), stringValue("Janet"), stringValue("abc")},
+	}}
+	otherToken := []row{{
+		columns: []string{"id", "name", "token"},
+		values:  []*proto.ColumnValue{intValue(1), stringValue("Jane"), stringValue("xyz")},
+	}}
+	nullName := []row{{
+		columns: []string{"id", "name", "token"},
+		values:  []*proto.ColumnValue{intValue(1), nil, stringValue("abc")},
+	}}
+	emptyName := []row{{
+		columns: []string{"id", "name", "token"},
+		values:  []*proto.ColumnValue{intValue(1), stringValue(""), stringValue("abc")},
+	}}
+
+	if hashRows(base, nil) != hashRows(reordered, nil) {
+		t.Error("column order should not affect the hash")
+	}
+	if hashRows(base, nil) == hashRows(changed, nil) {
+		t.Error("different values should produce different hashes")
+	}
+	if hashRows(nullName, nil) == hashRows(emptyName, nil) {
+		t.Error("NULL and empty string should produce different hashes")
+	}
+	excluded := map[string]bool{"token": true}
+	if hashRows(base, excluded) != hashRows(otherToken, excluded) {
+		t.Error("excluded columns should not affect the hash")
+	}
+	if hashRows(nil, nil) == hashRows(base, nil) {
+		t.Error("missing rows should change the hash")
+	}
+}
+
+func TestFormatKey(t *testing.T) {
+	key := []*proto.ColumnValue{intValue(7), stringValue("a"), nil}
+	if got := formatKey(key); got != "7,a,NULL" {
+		t.Errorf("formatKey() = %q, want %q", got, "7,a,NULL")
+	}
+}
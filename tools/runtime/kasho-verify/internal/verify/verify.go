@@ -0,0 +1,391 @@
+package verify
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"kasho/pkg/dialect"
+	"kasho/pkg/transform"
+	"kasho/proto"
+)
+
+const defaultChunkSize = 1000
+
+// ddlLogTable holds captured DDL on the primary and is never replicated
+const ddlLogTable = "kasho_ddl_log"
+
+const postgresPrimaryKeysQuery = `SELECT kcu.table_schema || '.' || kcu.table_name, kcu.column_name
+	FROM information_schema.table_constraints tc
+	JOIN information_schema.key_column_usage kcu
+		ON kcu.constraint_schema = tc.constraint_schema
+		AND kcu.constraint_name = tc.constraint_name
+		AND kcu.table_name = tc.table_name
+	WHERE tc.constraint_type = 'PRIMARY KEY'
+	AND tc.table_schema NOT IN ('pg_catalog', 'information_schema', 'pg_toast')
+	ORDER BY 1, kcu.ordinal_position`
+
+const mysqlPrimaryKeysQuery = `SELECT kcu.table_name, kcu.column_name
+	FROM information_schema.table_constraints tc
+	JOIN information_schema.key_column_usage kcu
+		ON kcu.constraint_schema = tc.constraint_schema
+		AND kcu.constraint_name = tc.constraint_name
+		AND kcu.table_name = tc.table_name
+	WHERE tc.constraint_type = 'PRIMARY KEY'
+	AND tc.table_schema = DATABASE()
+	ORDER BY kcu.table_name, kcu.ordinal_position`
+
+// Config controls which tables are compared and how
+type Config struct {
+	// Tables limits verification to these tables; empty means every table with a primary key
+	Tables []string
+	// ChunkSize is the number of rows, in primary key order, hashed together
+	ChunkSize int
+	// Transforms are re-applied to primary rows before hashing so they match the replica
+	Transforms *transform.Config
+}
+
+// Chunk describes a primary key range whose contents differ
+type Chunk struct {
+	After       string // last key of the previous chunk (exclusive), empty for the first chunk
+	Through     string // last key of this chunk (inclusive), empty for the final chunk
+	PrimaryRows int
+	ReplicaRows int
+}
+
+// TableResult is the outcome of verifying a single table
+type TableResult struct {
+	Table           string
+	PrimaryRows     int64
+	ReplicaRows     int64
+	Chunks          int
+	Mismatches      []Chunk
+	ExcludedColumns []string // transformed columns whose output can't be reproduced
+	Skipped         string   // reason the table wasn't compared
+}
+
+// OK reports whether the table was compared and matched
+func (r TableResult) OK() bool {
+	return r.Skipped == "" && r.PrimaryRows == r.ReplicaRows && len(r.Mismatches) == 0
+}
+
+// Verifier compares tables between a primary and its replica
+type Verifier struct {
+	primary *sql.DB
+	replica *sql.DB
+	dialect dialect.Dialect
+	config  Config
+}
+
+// NewVerifier creates a verifier; both databases must use the given dialect
+func NewVerifier(primary, replica *sql.DB, d dialect.Dialect, config Config) *Verifier {
+	if config.ChunkSize <= 0 {
+		config.ChunkSize = defaultChunkSize
+	}
+	return &Verifier{
+		primary: primary,
+		replica: replica,
+		dialect: d,
+		config:  config,
+	}
+}
+
+// Verify compares every configured table and returns one result per table
+func (v *Verifier) Verify(ctx context.Context) ([]TableResult, error) {
+	primaryKeys, err := v.primaryKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read primary keys: %w", err)
+	}
+
+	tables := v.config.Tables
+	if len(tables) == 0 {
+		for table := range primaryKeys {
+			tables = append(tables, table)
+		}
+		sort.Strings(tables)
+	}
+
+	var results []TableResult
+	for _, table := range tables {
+		keys, ok := primaryKeys[table]
+		if !ok {
+			results = append(results, TableResult{Table: table, Skipped: "table not found or has no primary key"})
+			continue
+		}
+
+		slog.Debug("Verifying table", "table", table, "primary_key", keys)
+		result, err := v.verifyTable(ctx, table, keys)
+		if err != nil {
+			return results, fmt.Errorf("failed to verify %s: %w", table, err)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// primaryKeys returns the primary key columns of every user table on the primary, in key order
+func (v *Verifier) primaryKeys(ctx context.Context) (map[string][]string, error) {
+	query := postgresPrimaryKeysQuery
+	if v.dialect.Name() == "mysql" {
+		query = mysqlPrimaryKeysQuery
+	}
+
+	rows, err := v.primary.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make(map[string][]string)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, err
+		}
+		if unqualified(table) == ddlLogTable {
+			continue
+		}
+		keys[table] = append(keys[table], column)
+	}
+	return keys, rows.Err()
+}
+
+func (v *Verifier) verifyTable(ctx context.Context, table string, keys []string) (TableResult, error) {
+	result := TableResult{Table: table}
+
+	for _, key := range keys {
+		if _, ok := v.columnTransform(table, key); ok {
+			result.Skipped = fmt.Sprintf("primary key column %s is transformed", key)
+			return result, nil
+		}
+	}
+
+	// Transforms can match columns by wildcard, so the excluded columns are found once
+	// the table's columns are known
+	var excluded map[string]bool
+
+	// Chunk boundaries come from the primary; the replica is read over the same key range
+	var after []*proto.ColumnValue
+	for {
+		primaryRows, err := v.readRows(ctx, v.primary, table, keys, after, nil, v.config.ChunkSize)
+		if err != nil {
+			return result, fmt.Errorf("failed to read primary: %w", err)
+		}
+
+		var through []*proto.ColumnValue
+		if len(primaryRows) == v.config.ChunkSize {
+			through = primaryRows[len(primaryRows)-1].key
+		}
+
+		replicaRows, err := v.readRows(ctx, v.replica, table, keys, after, through, 0)
+		if err != nil {
+			return result, fmt.Errorf("failed to read replica: %w", err)
+		}
+		if excluded == nil && len(primaryRows) > 0 {
+			excluded = make(map[string]bool)
+			for _, column := range primaryRows[0].columns {
+				if ct, ok := v.columnTransform(table, column); ok && !ct.Deterministic() {
+					excluded[column] = true
+					result.ExcludedColumns = append(result.ExcludedColumns, column)
+				}
+			}
+			sort.Strings(result.ExcludedColumns)
+		}
+
+		for i := range primaryRows {
+			primaryRows[i], err = v.transformRow(table, primaryRows[i])
+			if err != nil {
+				return result, err
+			}
+		}
+
+		result.Chunks++
+		result.PrimaryRows += int64(len(primaryRows))
+		result.ReplicaRows += int64(len(replicaRows))
+		if hashRows(primaryRows, excluded) != hashRows(replicaRows, excluded) {
+			chunk := Chunk{PrimaryRows: len(primaryRows), ReplicaRows: len(replicaRows)}
+			if after != nil {
+				chunk.After = formatKey(after)
+			}
+			if through != nil {
+				chunk.Through = formatKey(through)
+			}
+			result.Mismatches = append(result.Mismatches, chunk)
+		}
+
+		if through == nil {
+			return result, nil
+		}
+		after = through
+	}
+}
+
+// readRows reads rows with a primary key greater than after and, if through is set,
+// no greater than through. A limit of zero reads the whole range.
+func (v *Verifier) readRows(ctx context.Context, db *sql.DB, table string, keys []string, after, through []*proto.ColumnValue, limit int) ([]row, error) {
+	query, err := v.chunkQuery(table, keys, after, through, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	keyIndexes := make([]int, len(keys))
+	for i, key := range keys {
+		keyIndexes[i] = indexOf(columns, key)
+		if keyIndexes[i] < 0 {
+			return nil, fmt.Errorf("primary key column %s not found", key)
+		}
+	}
+
+	var result []row
+	for rows.Next() {
+		raw := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range raw {
+			dest[i] = &raw[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		r := row{columns: columns, values: make([]*proto.ColumnValue, len(columns))}
+		for i := range raw {
+			r.values[i] = toColumnValue(raw[i], columnTypes[i].DatabaseTypeName())
+		}
+		for _, i := range keyIndexes {
+			r.key = append(r.key, r.values[i])
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// chunkQuery builds the SELECT for one key range, comparing row values so composite keys work
+func (v *Verifier) chunkQuery(table string, keys []string, after, through []*proto.ColumnValue, limit int) (string, error) {
+	quotedKeys := make([]string, len(keys))
+	for i, key := range keys {
+		quotedKeys[i] = v.dialect.QuoteIdentifier(key)
+	}
+	keyTuple := "(" + strings.Join(quotedKeys, ", ") + ")"
+
+	var conditions []string
+	if after != nil {
+		values, err := v.valueTuple(after)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, keyTuple+" > "+values)
+	}
+	if through != nil {
+		values, err := v.valueTuple(through)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, keyTuple+" <= "+values)
+	}
+
+	query := "SELECT * FROM " + v.quoteTable(table)
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY " + strings.Join(quotedKeys, ", ")
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	return query, nil
+}
+
+func (v *Verifier) valueTuple(values []*proto.ColumnValue) (string, error) {
+	formatted := make([]string, len(values))
+	for i, value := range values {
+		s, err := v.dialect.FormatValue(value)
+		if err != nil {
+			return "", err
+		}
+		formatted[i] = s
+	}
+	return "(" + strings.Join(formatted, ", ") + ")", nil
+}
+
+// quoteTable quotes each part of a possibly schema-qualified table name
+func (v *Verifier) quoteTable(table string) string {
+	parts := strings.Split(table, ".")
+	for i, part := range parts {
+		parts[i] = v.dialect.QuoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// columnTransform returns the configured transform of a column
+func (v *Verifier) columnTransform(table, column string) (transform.ColumnTransform, bool) {
+	if v.config.Transforms == nil {
+		return transform.ColumnTransform{}, false
+	}
+	return v.config.Transforms.ColumnTransform(table, column)
+}
+
+// transformRow applies the configured transforms to a primary row, the same way
+// the translicator does before writing it to the replica
+func (v *Verifier) transformRow(table string, r row) (row, error) {
+	if v.config.Transforms == nil {
+		return r, nil
+	}
+	if !v.config.Transforms.TransformsTable(table) {
+		return r, nil
+	}
+
+	// NULLs are passed through untouched, so leave them out of the change
+	dml := &proto.DMLData{Table: table, Kind: "insert"}
+	for i, column := range r.columns {
+		if r.values[i] != nil {
+			dml.ColumnNames = append(dml.ColumnNames, column)
+			dml.ColumnValues = append(dml.ColumnValues, r.values[i])
+		}
+	}
+
+	transformed, err := transform.TransformChange(v.config.Transforms, &proto.Change{
+		Type: "dml",
+		Data: &proto.Change_Dml{Dml: dml},
+	})
+	if err != nil {
+		return r, fmt.Errorf("failed to transform row %s: %w", formatKey(r.key), err)
+	}
+
+	result := row{columns: r.columns, values: make([]*proto.ColumnValue, len(r.values)), key: r.key}
+	copy(result.values, r.values)
+	transformedDML := transformed.GetDml()
+	for i, column := range transformedDML.ColumnNames {
+		result.values[indexOf(r.columns, column)] = transformedDML.ColumnValues[i]
+	}
+	return result, nil
+}
+
+func indexOf(columns []string, name string) int {
+	for i, column := range columns {
+		if column == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func unqualified(table string) string {
+	return table[strings.LastIndex(table, ".")+1:]
+}
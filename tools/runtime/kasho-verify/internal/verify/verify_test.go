@@ -0,0 +1,254 @@
+package verify
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"testing"
+
+	"kasho/pkg/dialect"
+	"kasho/pkg/transform"
+	"kasho/proto"
+)
+
+// fakeResult is the canned answer to one query
+type fakeResult struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+// fakeDB answers queries from a fixed map and fails on anything unexpected
+type fakeDB map[string]fakeResult
+
+func (f fakeDB) open() *sql.DB { return sql.OpenDB(fakeConnector{f}) }
+
+type fakeConnector struct{ db fakeDB }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{c.db}, nil }
+func (c fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ db fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, fmt.Errorf("not supported") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return nil, fmt.Errorf("not supported") }
+
+func (c fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	result, ok := c.db[query]
+	if !ok {
+		return nil, fmt.Errorf("unexpected query: %s", query)
+	}
+	return &fakeRows{result: result}, nil
+}
+
+type fakeRows struct {
+	result fakeResult
+	next   int
+}
+
+func (r *fakeRows) Columns() []string { return r.result.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.rows[r.next])
+	r.next++
+	return nil
+}
+
+const (
+	firstChunk  = `SELECT * FROM "public"."users" ORDER BY "id" LIMIT 2`
+	firstRange  = `SELECT * FROM "public"."users" WHERE ("id") <= (2) ORDER BY "id"`
+	secondChunk = `SELECT * FROM "public"."users" WHERE ("id") > (2) ORDER BY "id" LIMIT 2`
+	secondRange = `SELECT * FROM "public"."users" WHERE ("id") > (2) ORDER BY "id"`
+	wholeTable  = `SELECT * FROM "public"."users" ORDER BY "id"`
+)
+
+var userColumns = []string{"id", "email"}
+
+func primaryKeysResult() fakeResult {
+	return fakeResult{
+		columns: []string{"table", "column"},
+		rows: [][]driver.Value{
+			{"public.kasho_ddl_log", "id"},
+			{"public.users", "id"},
+		},
+	}
+}
+
+func users(rows ...[]driver.Value) fakeResult {
+	return fakeResult{columns: userColumns, rows: rows}
+}
+
+func TestVerify(t *testing.T) {
+	masked := &transform.Config{Tables: map[string]transform.TableConfig{
+		"public.users": {
+			"email": {Type: transform.Regex, Config: map[string]any{"pattern": `^[^@]+`, "replacement": "user"}},
+		},
+	}}
+
+	primary := fakeDB{
+		postgresPrimaryKeysQuery: primaryKeysResult(),
+		firstChunk:               users([]driver.Value{int64(1), "jane@example.com"}, []driver.Value{int64(2), "john@example.com"}),
+		secondChunk:              users([]driver.Value{int64(3), nil}),
+	}
+
+	tests := []struct {
+		name            string
+		transforms      *transform.Config
+		replica         fakeDB
+		wantMismatches  []Chunk
+		wantReplicaRows int64
+	}{
+		{
+			name:       "transformed replica matches",
+			transforms: masked,
+			replica: fakeDB{
+				firstRange:  users([]driver.Value{int64(1), "user@example.com"}, []driver.Value{int64(2), "user@example.com"}),
+				secondRange: users([]driver.Value{int64(3), nil}),
+			},
+			wantReplicaRows: 3,
+		},
+		{
+			name:       "untransformed values diverge",
+			transforms: masked,
+			replica: fakeDB{
+				firstRange:  users([]driver.Value{int64(1), "jane@example.com"}, []driver.Value{int64(2), "user@example.com"}),
+				secondRange: users([]driver.Value{int64(3), nil}),
+			},
+			wantMismatches:  []Chunk{{Through: "2", PrimaryRows: 2, ReplicaRows: 2}},
+			wantReplicaRows: 3,
+		},
+		{
+			name: "extra replica row past the last primary key",
+			replica: fakeDB{
+				firstRange:  users([]driver.Value{int64(1), "jane@example.com"}, []driver.Value{int64(2), "john@example.com"}),
+				secondRange: users([]driver.Value{int64(3), nil}, []driver.Value{int64(4), "extra@example.com"}),
+			},
+			wantMismatches:  []Chunk{{After: "2", PrimaryRows: 1, ReplicaRows: 2}},
+			wantReplicaRows: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVerifier(primary.open(), tt.replica.open(), dialect.NewPostgreSQL(), Config{ChunkSize: 2, Transforms: tt.transforms})
+			results, err := v.Verify(context.Background())
+			if err != nil {
+				t.Fatalf("Verify() unexpected error: %v", err)
+			}
+			if len(results) != 1 || results[0].Table != "public.users" {
+				t.Fatalf("Verify() results = %+v, want only public.users", results)
+			}
+
+			result := results[0]
+			if result.PrimaryRows != 3 || result.ReplicaRows != tt.wantReplicaRows {
+				t.Errorf("rows = %d/%d, want 3/%d", result.PrimaryRows, result.ReplicaRows, tt.wantReplicaRows)
+			}
+			if result.Chunks != 2 {
+				t.Errorf("chunks = %d, want 2", result.Chunks)
+			}
+			if fmt.Sprint(result.Mismatches) != fmt.Sprint(tt.wantMismatches) {
+				t.Errorf("mismatches = %+v, want %+v", result.Mismatches, tt.wantMismatches)
+			}
+			if result.OK() != (len(tt.wantMismatches) == 0) {
+				t.Errorf("OK() = %v", result.OK())
+			}
+		})
+	}
+}
+
+func TestVerify_SkipsTables(t *testing.T) {
+	transforms := &transform.Config{Tables: map[string]transform.TableConfig{
+		"public.users": {"id": {Type: transform.FakeYear}},
+	}}
+	primary := fakeDB{postgresPrimaryKeysQuery: primaryKeysResult()}
+
+	v := NewVerifier(primary.open(), fakeDB{}.open(), dialect.NewPostgreSQL(), Config{
+		Tables:     []string{"public.users", "public.events"},
+		Transforms: transforms,
+	})
+	results, err := v.Verify(context.Background())
+	if err != nil {
+		t.Fatalf("Verify() unexpected error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Verify() returned %d results, want 2", len(results))
+	}
+	if results[0].Skipped != "primary key column id is transformed" {
+		t.Errorf("users skipped = %q", results[0].Skipped)
+	}
+	if results[1].Skipped != "table not found or has no primary key" {
+		t.Errorf("events skipped = %q", results[1].Skipped)
+	}
+	if results[0].OK() || results[1].OK() {
+		t.Error("skipped tables should not be reported as OK")
+	}
+}
+
+func TestVerify_ExcludesNonDeterministicColumns(t *testing.T) {
+	transforms := &transform.Config{Tables: map[string]transform.TableConfig{
+		"public.users": {"email": {Type: transform.PasswordBcrypt, Config: map[string]any{"cleartext": "secret"}}},
+	}}
+	primary := fakeDB{
+		postgresPrimaryKeysQuery: primaryKeysResult(),
+		firstChunk:               users([]driver.Value{int64(1), "jane@example.com"}),
+	}
+	replica := fakeDB{
+		wholeTable: users([]driver.Value{int64(1), "$2a$10$whatever"}),
+	}
+
+	v := NewVerifier(primary.open(), replica.open(), dialect.NewPostgreSQL(), Config{ChunkSize: 2, Transforms: transforms})
+	results, err := v.Verify(context.Background())
+	if err != nil {
+		t.Fatalf("Verify() unexpected error: %v", err)
+	}
+	if !results[0].OK() {
+		t.Errorf("bcrypt columns should be excluded, got %+v", results[0])
+	}
+	if fmt.Sprint(results[0].ExcludedColumns) != "[email]" {
+		t.Errorf("ExcludedColumns = %v, want [email]", results[0].ExcludedColumns)
+	}
+}
+
+func TestChunkQuery(t *testing.T) {
+	key := []string{"tenant_id", "id"}
+	after := []*proto.ColumnValue{intValue(1), intValue(10)}
+	through := []*proto.ColumnValue{intValue(2), stringValue("o'k")}
+
+	tests := []struct {
+		name    string
+		dialect dialect.Dialect
+		table   string
+		want    string
+	}{
+		{
+			name:    "postgresql",
+			dialect: dialect.NewPostgreSQL(),
+			table:   "public.orders",
+			want:    `SELECT * FROM "public"."orders" WHERE ("tenant_id", "id") > (1, 10) AND ("tenant_id", "id") <= (2, 'o''k') ORDER BY "tenant_id", "id" LIMIT 500`,
+		},
+		{
+			name:    "mysql",
+			dialect: dialect.NewMySQL(),
+			table:   "orders",
+			want:    "SELECT * FROM `orders` WHERE (`tenant_id`, `id`) > (1, 10) AND (`tenant_id`, `id`) <= (2, 'o''k') ORDER BY `tenant_id`, `id` LIMIT 500",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVerifier(nil, nil, tt.dialect, Config{})
+			got, err := v.chunkQuery(tt.table, key, after, through, 500)
+			if err != nil {
+				t.Fatalf("chunkQuery() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("chunkQuery() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	"github.com/spf13/cobra"
+	"kasho-verify/internal/verify"
+	"kasho/pkg/dialect"
+	"kasho/pkg/transform"
+	"kasho/pkg/version"
+)
+
+var (
+	primaryURL string
+	replicaURL string
+	configFile string
+	tables     []string
+	chunkSize  int
+	fixCounter bool
+	verbose    bool
+)
+
+func main() {
+	rootCmd := &cobra.Command{
+		Use:   "kasho-verify",
+		Short: "Verify that a replica matches its primary",
+		Long: `kasho-verify compares row counts and chunked checksums of every table between a
+primary database and its Kasho replica. Rows are hashed in primary key order; when a
+transforms config is given, the transforms are re-applied to primary rows before hashing
+so transformed replicas can be verified too.
+
+On MySQL replicas, AUTO_INCREMENT counters are checked as well: a counter at or below
+the largest value in its column, e.g. after a bootstrap, makes the next insert on the
+replica fail with a duplicate key. --fix-auto-increment moves such counters past it.`,
+		RunE: runVerify,
+	}
+
+	rootCmd.Flags().StringVarP(&primaryURL, "primary-url", "p", "", "Primary database connection URL (required)")
+	rootCmd.Flags().StringVarP(&replicaURL, "replica-url", "r", "", "Replica database connection URL (required)")
+	rootCmd.Flags().StringVarP(&configFile, "config", "c", "", "Path to transforms.yml used by the translicator")
+	rootCmd.Flags().StringSliceVarP(&tables, "tables", "t", nil, "Tables to verify (default: all tables with a primary key)")
+	rootCmd.Flags().IntVarP(&chunkSize, "chunk-size", "s", 1000, "Rows per checksum chunk")
+	rootCmd.Flags().BoolVar(&fixCounter, "fix-auto-increment", false, "Move drifted AUTO_INCREMENT counters on a MySQL replica past their column's largest value")
+	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
+
+	rootCmd.MarkFlagRequired("primary-url")
+	rootCmd.MarkFlagRequired("replica-url")
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	logLevel := slog.LevelInfo
+	if verbose {
+		logLevel = slog.LevelDebug
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})))
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	slog.Info("Starting kasho-verify",
+		"version", version.Version,
+		"commit", version.GitCommit,
+		"built", version.BuildDate,
+		"chunk_size", chunkSize,
+	)
+
+	primaryDialect, err := dialect.FromConnectionString(primaryURL)
+	if err != nil {
+		return fmt.Errorf("failed to determine primary dialect: %w", err)
+	}
+	replicaDialect, err := dialect.FromConnectionString(replicaURL)
+	if err != nil {
+		return fmt.Errorf("failed to determine replica dialect: %w", err)
+	}
+	if primaryDialect.Name() != replicaDialect.Name() {
+		return fmt.Errorf("primary (%s) and replica (%s) must use the same database type", primaryDialect.Name(), replicaDialect.Name())
+	}
+
+	var transforms *transform.Config
+	if configFile != "" {
+		transforms, err = transform.LoadConfig(configFile)
+		if err != nil {
+			return fmt.Errorf("failed to load transforms config: %w", err)
+		}
+	}
+
+	primary, err := sql.Open(primaryDialect.GetDriverName(), primaryDialect.FormatDSN(primaryURL))
+	if err != nil {
+		return fmt.Errorf("failed to open primary: %w", err)
+	}
+	defer primary.Close()
+
+	replica, err := sql.Open(replicaDialect.GetDriverName(), replicaDialect.FormatDSN(replicaURL))
+	if err != nil {
+		return fmt.Errorf("failed to open replica: %w", err)
+	}
+	defer replica.Close()
+
+	verifier := verify.NewVerifier(primary, replica, primaryDialect, verify.Config{
+		Tables:     tables,
+		ChunkSize:  chunkSize,
+		Transforms: transforms,
+	})
+
+	start := time.Now()
+	results, err := verifier.Verify(ctx)
+	if err != nil {
+		return err
+	}
+
+	diverged := 0
+	for _, result := range results {
+		switch {
+		case result.Skipped != "":
+			slog.Warn("Skipped table", "table", result.Table, "reason", result.Skipped)
+			continue
+		case result.OK():
+			slog.Info("Table matches",
+				"table", result.Table,
+				"rows", result.PrimaryRows,
+				"chunks", result.Chunks,
+			)
+		default:
+			diverged++
+			slog.Error("Table diverges",
+				"table", result.Table,
+				"primary_rows", result.PrimaryRows,
+				"replica_rows", result.ReplicaRows,
+				"mismatched_chunks", len(result.Mismatches),
+			)
+			for _, chunk := range result.Mismatches {
+				slog.Error("Mismatched chunk",
+					"table", result.Table,
+					"after_key", chunk.After,
+					"through_key", chunk.Through,
+					"primary_rows", chunk.PrimaryRows,
+					"replica_rows", chunk.ReplicaRows,
+				)
+			}
+		}
+		if len(result.ExcludedColumns) > 0 {
+			slog.Info("Excluded non-deterministic transformed columns",
+				"table", result.Table,
+				"columns", strings.Join(result.ExcludedColumns, ","),
+			)
+		}
+	}
+
+	drifted := 0
+	if mysqlDialect, ok := replicaDialect.(*dialect.MySQL); ok {
+		drifted, err = checkAutoIncrement(ctx, replica, mysqlDialect)
+		if err != nil {
+			return err
+		}
+	}
+
+	slog.Info("Verification completed", "tables", len(results), "diverged", diverged, "drifted_counters", drifted, "duration", time.Since(start))
+
+	if diverged > 0 {
+		return fmt.Errorf("replica diverges from primary in %d tables", diverged)
+	}
+	if drifted > 0 {
+		return fmt.Errorf("%d AUTO_INCREMENT counters on the replica are behind their column's values", drifted)
+	}
+	return nil
+}
+
+// checkAutoIncrement reports AUTO_INCREMENT counters of the replica that would hand out
+// values already in use, fixing them with --fix-auto-increment. It returns how many are
+// left drifted.
+func checkAutoIncrement(ctx context.Context, replica *sql.DB, d *dialect.MySQL) (int, error) {
+	counters, err := d.AutoIncrementCounters(ctx, replica, tables)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read AUTO_INCREMENT counters: %w", err)
+	}
+
+	drifted := 0
+	for _, counter := range counters {
+		table := counter.Schema + "." + counter.Table
+		switch {
+		case counter.PerGroup:
+			slog.Debug("Skipped per-group AUTO_INCREMENT", "table", table, "column", counter.Column)
+			continue
+		case counter.Exhausted():
+			slog.Error("AUTO_INCREMENT exhausted", "table", table, "column", counter.Column, "max", counter.Max)
+			drifted++
+			continue
+		case !counter.Drifted():
+			slog.Debug("AUTO_INCREMENT ok", "table", table, "column", counter.Column, "next", counter.Next, "max", counter.Max)
+			continue
+		}
+
+		if !fixCounter {
+			slog.Error("AUTO_INCREMENT drifted", "table", table, "column", counter.Column, "next", counter.Next, "max", counter.Max)
+			drifted++
+			continue
+		}
+		if err := d.FixAutoIncrement(ctx, replica, counter); err != nil {
+			slog.Error("Failed to fix AUTO_INCREMENT", "table", table, "error", err)
+			drifted++
+			continue
+		}
+		slog.Info("Fixed AUTO_INCREMENT", "table", table, "column", counter.Column, "was", counter.Next, "now", counter.Max+1)
+	}
+	return drifted, nil
+}
@@ -8,6 +8,9 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"kasho/pkg/types"
+	"mysql-bootstrap-sync/internal/progress"
 )
 
 func TestNewBootstrapper_DryRun(t *testing.T) {
@@ -205,6 +208,30 @@ func TestBootstrapper_Bootstrap_FileNotFound(t *testing.T) {
 	if err == nil {
 		t.Error("expected error for non-existent file")
 	}
+	if status := b.Progress().Status(); status.Phase != progress.PhaseFailed || status.Error == "" {
+		t.Errorf("expected failed phase with an error, got %s %q", status.Phase, status.Error)
+	}
+}
+
+func TestBootstrapper_Verify_DryRun(t *testing.T) {
+	b, err := NewBootstrapper(Config{DumpFile: "dump.sql", DryRun: true})
+	if err != nil {
+		t.Fatalf("NewBootstrapper() failed: %v", err)
+	}
+	defer b.Close()
+
+	if err := b.Verify(context.Background()); err == nil {
+		t.Error("expected an error verifying a dry run, which stores nothing")
+	}
+}
+
+func TestChangeTable(t *testing.T) {
+	if got := changeTable(&types.Change{Data: &types.DMLData{Table: "users"}}); got != "users" {
+		t.Errorf("changeTable() of a row change = %q, want users", got)
+	}
+	if got := changeTable(&types.Change{Data: &types.DDLData{DDL: "CREATE TABLE users (id int)"}}); got != "" {
+		t.Errorf("changeTable() of DDL = %q, want empty", got)
+	}
 }
 
 func TestBootstrapper_Bootstrap_ContextCancellation(t *testing.T) {
@@ -248,6 +275,117 @@ func TestBootstrapper_Bootstrap_ContextCancellation(t *testing.T) {
 	_ = b.Bootstrap(ctx)
 }
 
+func TestBootstrapper_Bootstrap_CSVDir(t *testing.T) {
+	// Flat file exports are read from a directory instead of a dump
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"products.csv": "id,name,price\n1,Widget,9.99\n2,\"Gadget, large\",19.99\n",
+		"orders.tsv":   "id\tproduct_id\tnote\n1\t1\t\\N\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	config := Config{
+		CSVDir: tmpDir,
+		DryRun: true,
+	}
+
+	b, err := NewBootstrapper(config)
+	if err != nil {
+		t.Fatalf("NewBootstrapper() failed: %v", err)
+	}
+	defer b.Close()
+
+	err = b.Bootstrap(context.Background())
+	if err != nil {
+		t.Fatalf("Bootstrap() failed: %v", err)
+	}
+
+	stats := b.GetStatistics()
+	if stats.StatementsRead != 2 {
+		t.Errorf("expected 2 statements read, got %d", stats.StatementsRead)
+	}
+	if stats.ChangesGenerated != 3 {
+		t.Errorf("expected 3 changes generated, got %d", stats.ChangesGenerated)
+	}
+
+	status := b.Progress().Status()
+	if status.Phase != progress.PhaseDone {
+		t.Errorf("expected phase done, got %s", status.Phase)
+	}
+	if status.TotalBytes == 0 || status.BytesRead != status.TotalBytes {
+		t.Errorf("expected all %d bytes read, got %d", status.TotalBytes, status.BytesRead)
+	}
+	if rows := status.Tables["products"].RowsRead; rows != 2 {
+		t.Errorf("expected 2 rows read for products, got %d", rows)
+	}
+}
+
+func TestBootstrapper_Bootstrap_SchemaOnlyThenDataOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	dumpFile := filepath.Join(tmpDir, "test.sql")
+
+	dumpContent := `CREATE TABLE ` + "`products`" + ` (
+  ` + "`id`" + ` int NOT NULL,
+  ` + "`name`" + ` text,
+  PRIMARY KEY (` + "`id`" + `)
+) ENGINE=InnoDB;
+
+INSERT INTO ` + "`products`" + ` VALUES (1,'Widget'),(2,'Gadget');
+`
+
+	err := os.WriteFile(dumpFile, []byte(dumpContent), 0644)
+	if err != nil {
+		t.Fatalf("failed to write temp dump file: %v", err)
+	}
+
+	run := func(config Config) Statistics {
+		t.Helper()
+		config.DumpFile = dumpFile
+		config.DryRun = true
+		b, err := NewBootstrapper(config)
+		if err != nil {
+			t.Fatalf("NewBootstrapper() failed: %v", err)
+		}
+		defer b.Close()
+		if err := b.Bootstrap(context.Background()); err != nil {
+			t.Fatalf("Bootstrap() failed: %v", err)
+		}
+		return b.GetStatistics()
+	}
+
+	schema := run(Config{SchemaOnly: true})
+	if schema.ChangesGenerated != 1 || schema.DDLCount != 1 || schema.DMLCount != 0 {
+		t.Errorf("schema-only: expected 1 DDL change, got %d changes (%d DDL, %d DML)",
+			schema.ChangesGenerated, schema.DDLCount, schema.DMLCount)
+	}
+
+	// The data continues after the schema's positions, so it's applied after the DDL
+	data := run(Config{DataOnly: true, ResumeFromPos: schema.LastPosition})
+	if data.ChangesGenerated != 2 || data.DDLCount != 0 || data.DMLCount != 1 {
+		t.Errorf("data-only: expected 2 row changes, got %d changes (%d DDL, %d DML)",
+			data.ChangesGenerated, data.DDLCount, data.DMLCount)
+	}
+	if data.LastPosition != "0/BOOTSTRAP0000000000000003" {
+		t.Errorf("data-only: expected last position 0/BOOTSTRAP0000000000000003, got %s", data.LastPosition)
+	}
+}
+
+func TestNewBootstrapper_InvalidResumePosition(t *testing.T) {
+	config := Config{
+		DumpFile:      "nonexistent.sql",
+		ResumeFromPos: "mysql-bin.000003:154",
+		DryRun:        true,
+	}
+
+	if _, err := NewBootstrapper(config); err == nil {
+		t.Error("expected error for a resume position that isn't a bootstrap position")
+	}
+}
+
 func TestStatistics_Fields(t *testing.T) {
 	stats := Statistics{
 		StartTime:         time.Now(),
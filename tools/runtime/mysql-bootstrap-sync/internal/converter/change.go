@@ -70,6 +70,9 @@ func (c *ChangeConverter) convertDDLStatement(stmt parser.DDLStatement) (*types.
 	change := &types.Change{
 		Position: pos,
 		Data:     ddlData,
+		Dialect:  "mysql",
+		Database: stmt.Database,
+		Schema:   stmt.Database,
 	}
 
 	return change, nil
@@ -109,11 +112,15 @@ func (c *ChangeConverter) convertDMLStatement(stmt parser.DMLStatement) ([]*type
 			ColumnValues: columnValues,
 			Kind:         "insert", // All bootstrap data is inserts
 			OldKeys:      nil,      // No old keys for inserts
+			Schema:       stmt.Database,
 		}
 
 		change := &types.Change{
 			Position: pos,
 			Data:     dmlData,
+			Dialect:  "mysql",
+			Database: stmt.Database,
+			Schema:   stmt.Database,
 		}
 
 		changes = append(changes, change)
@@ -190,3 +197,12 @@ func (c *ChangeConverter) GetNextPosition() string {
 func (c *ChangeConverter) GetCurrentSequence() int64 {
 	return c.positionGenerator.GetSequence()
 }
+
+// ResumeAfter continues generating positions after a bootstrap position of an earlier run
+func (c *ChangeConverter) ResumeAfter(pos string) error {
+	seq, err := ParseBootstrapPosition(pos)
+	if err != nil {
+		return err
+	}
+	return c.positionGenerator.SetSequence(seq)
+}
@@ -3,6 +3,7 @@ package converter
 import (
 	"testing"
 
+	"kasho/pkg/types"
 	"mysql-bootstrap-sync/internal/parser"
 )
 
@@ -72,6 +73,42 @@ func TestChangeConverter_ConvertDDLStatement(t *testing.T) {
 	}
 }
 
+func TestChangeConverter_RecordsDatabase(t *testing.T) {
+	conv := NewChangeConverter()
+
+	// A dump of several databases, as mysqldump --databases writes it
+	statements := []parser.Statement{
+		parser.DDLStatement{SQL: "CREATE TABLE users (id INT PRIMARY KEY)", Table: "users", Database: "app1"},
+		parser.DMLStatement{Table: "users", Database: "app1", ColumnNames: []string{"id"}, ColumnValues: [][]string{{"1"}}},
+		parser.DDLStatement{SQL: "CREATE TABLE users (id INT PRIMARY KEY)", Table: "users", Database: "app2"},
+		parser.DMLStatement{Table: "users", Database: "app2", ColumnNames: []string{"id"}, ColumnValues: [][]string{{"1"}}},
+	}
+
+	changes, err := conv.ConvertStatements(statements)
+	if err != nil {
+		t.Fatalf("ConvertStatements() error = %v", err)
+	}
+	want := []string{"app1", "app1", "app2", "app2"}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d changes, got %d", len(want), len(changes))
+	}
+	for i, change := range changes {
+		if change.Database != want[i] || change.Schema != want[i] {
+			t.Errorf("change %d: database = %q, schema = %q, want %q", i, change.Database, change.Schema, want[i])
+		}
+		switch data := change.Data.(type) {
+		case *types.DMLData:
+			if data.Schema != want[i] {
+				t.Errorf("change %d: DML schema = %q, want %q", i, data.Schema, want[i])
+			}
+		case *types.DDLData:
+			if data.Database != want[i] {
+				t.Errorf("change %d: DDL database = %q, want %q", i, data.Database, want[i])
+			}
+		}
+	}
+}
+
 func TestChangeConverter_ConvertValueTypes(t *testing.T) {
 	conv := NewChangeConverter()
 
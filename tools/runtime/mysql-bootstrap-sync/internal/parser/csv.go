@@ -0,0 +1,283 @@
+package parser
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"mysql-bootstrap-sync/internal/progress"
+)
+
+// CSVParser implements the Parser interface for a directory of per-table CSV or TSV files,
+// for databases that can only export flat files. Each file is named after its table, such
+// as users.csv, and starts with a header row of column names. The tables' DDL comes from a
+// dump made with --no-data.
+//
+// CSV files are read as RFC 4180 CSV. TSV files are read as tab-separated text with
+// backslash escapes and \N for NULL, as written by COPY ... TO and SELECT ... INTO OUTFILE.
+type CSVParser struct {
+	SchemaFile      string // Dump without data, with the tables' DDL (empty = no DDL)
+	NullString      string // CSV field value read as NULL (empty fields are always NULL)
+	MaxRowsPerTable int    // Limit rows per table for testing (0 = no limit)
+	MaxLineSize     int    // Longest schema or TSV line accepted, in bytes (0 = DefaultMaxLineSize)
+
+	Progress *progress.Tracker // Records the bytes and rows read (optional)
+}
+
+// NewCSVParser creates a parser for the CSV and TSV files of a directory
+func NewCSVParser(schemaFile string) *CSVParser {
+	return &CSVParser{
+		SchemaFile: schemaFile,
+	}
+}
+
+// Parse parses the schema file and then the CSV and TSV files of a directory, in name order
+func (p *CSVParser) Parse(dir string) (*ParseResult, error) {
+	result := &ParseResult{
+		Statements: make([]Statement, 0),
+		Metadata: ParseMetadata{
+			SourceFile:  dir,
+			TablesFound: make([]string, 0),
+		},
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV directory %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if _, _, ok := csvTable(entry.Name()); ok && !entry.IsDir() {
+			if info, err := entry.Info(); err == nil {
+				p.Progress.AddTotalBytes(info.Size())
+			}
+		}
+	}
+
+	if p.SchemaFile != "" {
+		if err := p.parseSchema(result); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		table, delimiter, ok := csvTable(entry.Name())
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(table), "kasho_") {
+			log.Printf("Skipping CSV data for Kasho internal table: %s", table)
+			continue
+		}
+
+		stmt, err := p.parseTableFile(filepath.Join(dir, entry.Name()), table, delimiter)
+		if err != nil {
+			return nil, err
+		}
+		if !contains(result.Metadata.TablesFound, table) {
+			result.Metadata.TablesFound = append(result.Metadata.TablesFound, table)
+		}
+		if len(stmt.ColumnValues) == 0 {
+			continue
+		}
+		result.Statements = append(result.Statements, stmt)
+		result.Metadata.DMLCount++
+	}
+
+	result.Metadata.ParsedAt = time.Now()
+	result.Metadata.StatementCount = len(result.Statements)
+	return result, nil
+}
+
+// parseSchema adds the DDL statements of the schema file to the result
+func (p *CSVParser) parseSchema(result *ParseResult) error {
+	dumpParser := NewDumpParser()
+	dumpParser.MaxLineSize = p.MaxLineSize
+	dumpParser.Progress = p.Progress
+	schema, err := dumpParser.Parse(p.SchemaFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse schema file: %w", err)
+	}
+
+	// Table data in the schema file would be loaded again from the CSV files
+	if schema.Metadata.DMLCount > 0 {
+		return fmt.Errorf("schema file %s contains table data; dump it with --no-data", p.SchemaFile)
+	}
+
+	result.Statements = append(result.Statements, schema.Statements...)
+	result.Metadata.DDLCount += schema.Metadata.DDLCount
+	result.Metadata.TablesFound = append(result.Metadata.TablesFound, schema.Metadata.TablesFound...)
+	return nil
+}
+
+// csvTable returns the table name and field delimiter of a data file, or false for files
+// that aren't CSV or TSV
+func csvTable(name string) (string, rune, bool) {
+	ext := filepath.Ext(name)
+	table := strings.TrimSuffix(name, ext)
+	if table == "" {
+		return "", 0, false
+	}
+	switch strings.ToLower(ext) {
+	case ".csv":
+		return table, ',', true
+	case ".tsv":
+		return table, '\t', true
+	default:
+		return "", 0, false
+	}
+}
+
+// parseTableFile reads the rows of one table's file
+func (p *CSVParser) parseTableFile(path, table string, delimiter rune) (DMLStatement, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return DMLStatement{}, fmt.Errorf("failed to open CSV file %s: %w", path, err)
+	}
+	defer file.Close()
+	p.Progress.SetTable(table)
+
+	var rows rowReader
+	if delimiter == '\t' {
+		rows = &tsvReader{lines: newLineReader(p.Progress.Reader(file), p.MaxLineSize)}
+	} else {
+		reader := csv.NewReader(p.Progress.Reader(file))
+		reader.Comma = delimiter
+		rows = &csvReader{reader: reader, null: p.NullString}
+	}
+
+	columns, err := rows.Read()
+	if err == io.EOF {
+		return DMLStatement{}, fmt.Errorf("CSV file %s has no header row", path)
+	}
+	if err != nil {
+		return DMLStatement{}, fmt.Errorf("failed to read header of %s: %w", path, err)
+	}
+	for i, column := range columns {
+		columns[i] = strings.TrimSpace(column)
+		if columns[i] == "" {
+			return DMLStatement{}, fmt.Errorf("CSV file %s has an empty column name in its header", path)
+		}
+	}
+
+	stmt := DMLStatement{
+		Table:        table,
+		ColumnNames:  columns,
+		ColumnValues: make([][]string, 0),
+	}
+	for p.MaxRowsPerTable <= 0 || len(stmt.ColumnValues) < p.MaxRowsPerTable {
+		values, err := rows.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return DMLStatement{}, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if len(values) != len(columns) {
+			return DMLStatement{}, fmt.Errorf("failed to read %s: row %d has %d fields, header has %d",
+				path, len(stmt.ColumnValues)+1, len(values), len(columns))
+		}
+		stmt.ColumnValues = append(stmt.ColumnValues, values)
+	}
+	p.Progress.AddRows(table, len(stmt.ColumnValues))
+	return stmt, nil
+}
+
+// rowReader reads the rows of a data file, with NULL values as empty strings
+type rowReader interface {
+	Read() ([]string, error)
+}
+
+// csvReader reads RFC 4180 CSV rows
+type csvReader struct {
+	reader *csv.Reader
+	null   string
+}
+
+func (r *csvReader) Read() ([]string, error) {
+	values, err := r.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	if r.null != "" {
+		for i, value := range values {
+			if value == r.null {
+				values[i] = ""
+			}
+		}
+	}
+	return values, nil
+}
+
+// tsvReader reads tab-separated rows with backslash escapes
+type tsvReader struct {
+	lines *lineReader
+}
+
+func (r *tsvReader) Read() ([]string, error) {
+	if !r.lines.Scan() {
+		if err := r.lines.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	values := strings.Split(r.lines.Text(), "\t")
+	for i, value := range values {
+		values[i] = unescapeTSVValue(value)
+	}
+	return values, nil
+}
+
+// unescapeTSVValue unescapes a tab-separated text value in one pass. \N is NULL, which
+// becomes an empty string, and unknown escapes keep the escaped character.
+func unescapeTSVValue(value string) string {
+	if value == `\N` {
+		return ""
+	}
+	i := strings.IndexByte(value, '\\')
+	if i < 0 {
+		return value
+	}
+
+	var b strings.Builder
+	b.Grow(len(value))
+	for ; i >= 0; i = strings.IndexByte(value, '\\') {
+		b.WriteString(value[:i])
+		if i+1 == len(value) {
+			// A trailing backslash is kept as is
+			b.WriteByte('\\')
+			value = ""
+			break
+		}
+		switch c := value[i+1]; c {
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case '0':
+			b.WriteByte(0)
+		case 'Z':
+			b.WriteByte(0x1a)
+		default:
+			b.WriteByte(c)
+		}
+		value = value[i+2:]
+	}
+	b.WriteString(value)
+	return b.String()
+}
@@ -0,0 +1,197 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates a directory with the given files
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestCSVParser_Parse(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"users.csv": "id,name,bio\n" +
+			"1,John Doe,\"Likes \"\"quotes\"\", commas\"\n" +
+			"2,Jane Smith,\"Two\nlines\"\n" +
+			"3,No Bio,\n",
+		"posts.tsv": "id\ttitle\tbody\n" +
+			"1\tFirst Post\tTab\\there\n" +
+			"2\tSecond Post\t\\N\n",
+		"kasho_ddl.csv": "id,ddl\n1,CREATE TABLE t ()\n",
+		"README.txt":    "not a table",
+	})
+
+	result, err := NewCSVParser("").Parse(dir)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	if result.Metadata.DMLCount != 2 || result.Metadata.StatementCount != 2 {
+		t.Fatalf("Expected 2 DML statements, got %d of %d", result.Metadata.DMLCount, result.Metadata.StatementCount)
+	}
+	if result.Metadata.SourceFile != dir {
+		t.Errorf("Expected source %s, got %s", dir, result.Metadata.SourceFile)
+	}
+
+	// Files are read in name order
+	posts := result.Statements[0].(DMLStatement)
+	if posts.Table != "posts" {
+		t.Errorf("Expected table posts, got %s", posts.Table)
+	}
+	wantPosts := [][]string{
+		{"1", "First Post", "Tab\there"},
+		{"2", "Second Post", ""},
+	}
+	if !reflect.DeepEqual(posts.ColumnValues, wantPosts) {
+		t.Errorf("Expected posts %q, got %q", wantPosts, posts.ColumnValues)
+	}
+
+	users := result.Statements[1].(DMLStatement)
+	if !reflect.DeepEqual(users.ColumnNames, []string{"id", "name", "bio"}) {
+		t.Errorf("Unexpected columns %v", users.ColumnNames)
+	}
+	wantUsers := [][]string{
+		{"1", "John Doe", `Likes "quotes", commas`},
+		{"2", "Jane Smith", "Two\nlines"},
+		{"3", "No Bio", ""},
+	}
+	if !reflect.DeepEqual(users.ColumnValues, wantUsers) {
+		t.Errorf("Expected users %q, got %q", wantUsers, users.ColumnValues)
+	}
+
+	if !reflect.DeepEqual(result.Metadata.TablesFound, []string{"posts", "users"}) {
+		t.Errorf("Unexpected tables found %v", result.Metadata.TablesFound)
+	}
+}
+
+func TestCSVParser_Schema(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"schema.sql": "CREATE TABLE `users` (\n  `id` int NOT NULL,\n  `name` text,\n  PRIMARY KEY (`id`)\n);\n",
+		"data.csv":   "id,name\n1,John\n",
+	})
+
+	p := NewCSVParser(filepath.Join(dir, "schema.sql"))
+	result, err := p.Parse(dir)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	// DDL comes first, so tables exist before their rows
+	if result.Metadata.DDLCount != 1 || result.Metadata.DMLCount != 1 {
+		t.Fatalf("Expected 1 DDL and 1 DML statement, got %d and %d", result.Metadata.DDLCount, result.Metadata.DMLCount)
+	}
+	if result.Statements[0].GetType() != "DDL" || result.Statements[1].GetType() != "DML" {
+		t.Errorf("Expected DDL before DML, got %s then %s", result.Statements[0].GetType(), result.Statements[1].GetType())
+	}
+}
+
+func TestCSVParser_SchemaWithData(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"schema.sql": "INSERT INTO `users` (`id`, `name`) VALUES (1,'John');\n",
+	})
+
+	_, err := NewCSVParser(filepath.Join(dir, "schema.sql")).Parse(dir)
+	if err == nil || !strings.Contains(err.Error(), "contains table data") {
+		t.Errorf("Expected an error for a schema file with data, got %v", err)
+	}
+}
+
+func TestCSVParser_NullString(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"users.csv": "id,name\n1,NULL\n2,\"NULL-ish\"\n",
+	})
+
+	p := NewCSVParser("")
+	p.NullString = "NULL"
+	result, err := p.Parse(dir)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	want := [][]string{{"1", ""}, {"2", "NULL-ish"}}
+	if got := result.Statements[0].(DMLStatement).ColumnValues; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestCSVParser_MaxRowsPerTable(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"users.csv": "id\n1\n2\n3\n",
+		"posts.tsv": "id\n1\n2\n3\n",
+	})
+
+	p := NewCSVParser("")
+	p.MaxRowsPerTable = 2
+	result, err := p.Parse(dir)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	for _, stmt := range result.Statements {
+		dml := stmt.(DMLStatement)
+		if len(dml.ColumnValues) != 2 {
+			t.Errorf("Expected 2 rows for %s, got %d", dml.Table, len(dml.ColumnValues))
+		}
+	}
+}
+
+func TestCSVParser_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{"empty file", map[string]string{"users.csv": ""}, "no header row"},
+		{"empty column name", map[string]string{"users.csv": "id,,name\n"}, "empty column name"},
+		{"csv field count", map[string]string{"users.csv": "id,name\n1\n"}, "wrong number of fields"},
+		{"tsv field count", map[string]string{"users.tsv": "id\tname\n1\tJohn\textra\n"}, "row 1 has 3 fields, header has 2"},
+		{"unterminated quote", map[string]string{"users.csv": "id,name\n1,\"John\n"}, "extraneous or missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCSVParser("").Parse(writeFiles(t, tt.files))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if _, err := NewCSVParser("").Parse(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
+
+func TestUnescapeTSVValue(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"plain", "plain"},
+		{`\N`, ""},
+		{`\\N`, `\N`},
+		{`a\tb\nc\rd`, "a\tb\nc\rd"},
+		{`C:\\path\\`, `C:\path\`},
+		{`\0\Z\b\f\v`, "\x00\x1a\b\f\v"},
+		{`\,`, ","},
+		{`trailing\`, `trailing\`},
+	}
+
+	for _, tt := range tests {
+		if got := unescapeTSVValue(tt.input); got != tt.want {
+			t.Errorf("unescapeTSVValue(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"log"
@@ -9,12 +8,17 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"mysql-bootstrap-sync/internal/progress"
 )
 
 // DumpParser implements the Parser interface for mysqldump files
 type DumpParser struct {
 	// Configuration options
 	MaxRowsPerTable int // Limit rows per table for testing (0 = no limit)
+	MaxLineSize     int // Longest line accepted, in bytes (0 = DefaultMaxLineSize)
+
+	Progress *progress.Tracker // Records the bytes and rows read (optional)
 }
 
 // NewDumpParser creates a new dump parser
@@ -32,7 +36,11 @@ func (p *DumpParser) Parse(filename string) (*ParseResult, error) {
 	}
 	defer file.Close()
 
-	result, err := p.ParseStream(file)
+	if info, err := file.Stat(); err == nil {
+		p.Progress.AddTotalBytes(info.Size())
+	}
+
+	result, err := p.ParseStream(p.Progress.Reader(file))
 	if err != nil {
 		return nil, err
 	}
@@ -48,11 +56,8 @@ func (p *DumpParser) ParseStream(reader interface{}) (*ParseResult, error) {
 		return nil, fmt.Errorf("reader must implement io.Reader")
 	}
 
-	scanner := bufio.NewScanner(r)
-	// Increase buffer size for large INSERT statements (extended inserts can be very long)
-	const maxScanTokenSize = 64 * 1024 * 1024 // 64MB
-	buf := make([]byte, maxScanTokenSize)
-	scanner.Buffer(buf, maxScanTokenSize)
+	// Extended INSERTs and COPY rows with large values can be megabytes long on one line
+	scanner := newLineReader(r, p.MaxLineSize)
 
 	result := &ParseResult{
 		Statements: make([]Statement, 0),
@@ -65,6 +70,7 @@ func (p *DumpParser) ParseStream(reader interface{}) (*ParseResult, error) {
 	var currentStatement strings.Builder
 	tableRowCounts := make(map[string]int)
 	delimiter := ";" // Current statement delimiter (mysqldump uses DELIMITER to change this)
+	database := ""   // Database selected by the last USE statement (mysqldump --databases)
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -85,13 +91,17 @@ func (p *DumpParser) ParseStream(reader interface{}) (*ParseResult, error) {
 			continue
 		}
 
+		if strings.HasPrefix(upperLine, "USE ") {
+			database = useDatabase(trimmedLine, delimiter)
+			continue
+		}
+
 		// Skip SET statements and session control
 		if strings.HasPrefix(upperLine, "SET ") ||
 			strings.HasPrefix(upperLine, "LOCK TABLES") ||
 			strings.HasPrefix(upperLine, "UNLOCK TABLES") ||
 			strings.HasPrefix(upperLine, "START TRANSACTION") ||
-			strings.HasPrefix(upperLine, "COMMIT") ||
-			strings.HasPrefix(upperLine, "USE ") {
+			strings.HasPrefix(upperLine, "COMMIT") {
 			continue
 		}
 
@@ -112,7 +122,7 @@ func (p *DumpParser) ParseStream(reader interface{}) (*ParseResult, error) {
 			}
 
 			// Parse the complete statement
-			if err := p.parseStatement(sql, result, tableRowCounts); err != nil {
+			if err := p.parseStatement(sql, database, result, tableRowCounts); err != nil {
 				log.Printf("Warning: failed to parse statement: %v", err)
 				// Continue parsing - don't fail on individual statement errors
 			}
@@ -127,13 +137,19 @@ func (p *DumpParser) ParseStream(reader interface{}) (*ParseResult, error) {
 	return result, nil
 }
 
-// parseStatement parses a single SQL statement and adds it to the result
-func (p *DumpParser) parseStatement(sql string, result *ParseResult, tableRowCounts map[string]int) error {
+// useDatabase returns the database a USE statement selects
+func useDatabase(line, delimiter string) string {
+	name := strings.TrimSpace(strings.TrimSuffix(line[len("USE "):], delimiter))
+	return strings.Trim(name, "`")
+}
+
+// parseStatement parses a single SQL statement run in database and adds it to the result
+func (p *DumpParser) parseStatement(sql, database string, result *ParseResult, tableRowCounts map[string]int) error {
 	upperSQL := strings.ToUpper(sql)
 
 	// Handle INSERT statements (DML)
 	if strings.HasPrefix(upperSQL, "INSERT ") {
-		return p.parseInsertStatement(sql, result, tableRowCounts)
+		return p.parseInsertStatement(sql, database, result, tableRowCounts)
 	}
 
 	// Handle DDL statements
@@ -141,7 +157,7 @@ func (p *DumpParser) parseStatement(sql string, result *ParseResult, tableRowCou
 		strings.HasPrefix(upperSQL, "ALTER ") ||
 		strings.HasPrefix(upperSQL, "DROP ") ||
 		strings.HasPrefix(upperSQL, "TRUNCATE ") {
-		return p.parseDDLStatement(sql, result)
+		return p.parseDDLStatement(sql, database, result)
 	}
 
 	// Skip other statement types (REPLACE, UPDATE, DELETE not expected in mysqldump)
@@ -149,7 +165,7 @@ func (p *DumpParser) parseStatement(sql string, result *ParseResult, tableRowCou
 }
 
 // parseInsertStatement parses an INSERT statement from mysqldump
-func (p *DumpParser) parseInsertStatement(sql string, result *ParseResult, tableRowCounts map[string]int) error {
+func (p *DumpParser) parseInsertStatement(sql, database string, result *ParseResult, tableRowCounts map[string]int) error {
 	// Extract table name: INSERT INTO `table` or INSERT INTO table
 	tableMatch := regexp.MustCompile(`(?i)INSERT\s+INTO\s+` + "`?" + `([\w.]+)` + "`?" + `\s*`).FindStringSubmatch(sql)
 	if len(tableMatch) < 2 {
@@ -188,11 +204,15 @@ func (p *DumpParser) parseInsertStatement(sql string, result *ParseResult, table
 	valueSets := p.parseValueSets(valuesSection)
 
 	// Apply max rows per table limit
+	countKey := tableName
+	if database != "" {
+		countKey = database + "." + tableName
+	}
 	var rows [][]string
 	for _, valueSet := range valueSets {
 		if p.MaxRowsPerTable > 0 {
-			tableRowCounts[tableName]++
-			if tableRowCounts[tableName] > p.MaxRowsPerTable {
+			tableRowCounts[countKey]++
+			if tableRowCounts[countKey] > p.MaxRowsPerTable {
 				continue
 			}
 		}
@@ -211,11 +231,13 @@ func (p *DumpParser) parseInsertStatement(sql string, result *ParseResult, table
 	// Create DML statement
 	stmt := DMLStatement{
 		Table:        tableName,
+		Database:     database,
 		ColumnNames:  columnNames,
 		ColumnValues: rows,
 	}
 	result.Statements = append(result.Statements, stmt)
 	result.Metadata.DMLCount++
+	p.Progress.AddRows(tableName, len(rows))
 
 	return nil
 }
@@ -322,7 +344,7 @@ func (p *DumpParser) cleanMySQLValue(value string) string {
 }
 
 // parseDDLStatement parses a DDL statement
-func (p *DumpParser) parseDDLStatement(sql string, result *ParseResult) error {
+func (p *DumpParser) parseDDLStatement(sql, database string, result *ParseResult) error {
 	// Extract table name from DDL
 	tableName := p.extractTableName(sql)
 
@@ -335,7 +357,7 @@ func (p *DumpParser) parseDDLStatement(sql string, result *ParseResult) error {
 	stmt := DDLStatement{
 		SQL:      sql,
 		Table:    tableName,
-		Database: "unknown",
+		Database: database,
 		Time:     time.Now(),
 	}
 	result.Statements = append(result.Statements, stmt)
@@ -1,6 +1,9 @@
 package parser
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -137,6 +140,39 @@ func TestDumpParser_ParseDDLStatement(t *testing.T) {
 	}
 }
 
+func TestDumpParser_UseDatabase(t *testing.T) {
+	parser := NewDumpParser()
+
+	// mysqldump --databases selects each database before its tables
+	sql := `
+USE ` + "`app1`" + `;
+CREATE TABLE users (id int);
+INSERT INTO users (id) VALUES (1);
+USE app2;
+CREATE TABLE users (id int);
+INSERT INTO users (id) VALUES (2);
+`
+
+	result, err := parser.ParseStream(strings.NewReader(sql))
+	if err != nil {
+		t.Fatalf("ParseStream() error = %v", err)
+	}
+
+	var got []string
+	for _, stmt := range result.Statements {
+		switch s := stmt.(type) {
+		case DDLStatement:
+			got = append(got, "DDL "+s.Table+" in "+s.Database)
+		case DMLStatement:
+			got = append(got, "DML "+s.Table+" in "+s.Database)
+		}
+	}
+	want := []string{"DDL users in app1", "DML users in app1", "DDL users in app2", "DML users in app2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+}
+
 func TestDumpParser_SkipsKashoTables(t *testing.T) {
 	parser := NewDumpParser()
 
@@ -337,6 +373,48 @@ func TestDumpParser_VeryLongStrings(t *testing.T) {
 	}
 }
 
+func TestDumpParser_MultiMegabyteInsert(t *testing.T) {
+	parser := NewDumpParser()
+
+	// An extended INSERT of 1000 rows with 8KB values on a single 8MB line
+	value := strings.Repeat("y", 8*1024)
+	var sql strings.Builder
+	sql.WriteString("INSERT INTO `documents` VALUES ")
+	for i := range 1000 {
+		if i > 0 {
+			sql.WriteString(",")
+		}
+		fmt.Fprintf(&sql, "(%d,'%s')", i+1, value)
+	}
+	sql.WriteString(";\n")
+
+	result, err := parser.ParseStream(strings.NewReader(sql.String()))
+	if err != nil {
+		t.Fatalf("ParseStream() error = %v", err)
+	}
+	if len(result.Statements) != 1 {
+		t.Fatalf("expected 1 statement, got %d", len(result.Statements))
+	}
+	dml := result.Statements[0].(DMLStatement)
+	if len(dml.ColumnValues) != 1000 || dml.ColumnValues[999][1] != value {
+		t.Errorf("expected 1000 rows of %d byte values, got %d rows", len(value), len(dml.ColumnValues))
+	}
+}
+
+func TestDumpParser_LineTooLong(t *testing.T) {
+	parser := NewDumpParser()
+	parser.MaxLineSize = 1024
+
+	sql := "CREATE TABLE `data` (`id` int);\nINSERT INTO `data` VALUES (1,'" + strings.Repeat("x", 2048) + "');\n"
+	_, err := parser.ParseStream(strings.NewReader(sql))
+	if !errors.Is(err, ErrLineTooLong) {
+		t.Fatalf("ParseStream() error = %v, want ErrLineTooLong", err)
+	}
+	if !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("error %q doesn't name the line", err)
+	}
+}
+
 func TestDumpParser_EscapeSequences(t *testing.T) {
 	parser := NewDumpParser()
 
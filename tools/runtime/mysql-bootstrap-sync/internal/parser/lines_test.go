@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func readLines(t *testing.T, l *lineReader) []string {
+	t.Helper()
+	var lines []string
+	for l.Scan() {
+		lines = append(lines, l.Text())
+	}
+	return lines
+}
+
+func TestLineReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"empty", "", nil},
+		{"trailing newline", "a\nb\n", []string{"a", "b"}},
+		{"no trailing newline", "a\nb", []string{"a", "b"}},
+		{"crlf", "a\r\nb\r\n", []string{"a", "b"}},
+		{"empty lines", "\n\na\n\n", []string{"", "", "a", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLineReader(strings.NewReader(tt.input), 0)
+			if got := readLines(t, l); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lines = %q, want %q", got, tt.want)
+			}
+			if err := l.Err(); err != nil {
+				t.Errorf("Err() = %v", err)
+			}
+		})
+	}
+}
+
+func TestLineReader_MultiMegabyteLine(t *testing.T) {
+	// Longer than bufio.Scanner's 64KB default and the reader's own buffer
+	long := strings.Repeat("0123456789", 512*1024)
+	input := "first\n" + long + "\nlast"
+
+	// Read a few bytes at a time, as from a slow pipe
+	l := newLineReader(iotest.HalfReader(strings.NewReader(input)), len(long))
+	got := readLines(t, l)
+	if err := l.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	if len(got) != 3 || got[0] != "first" || got[1] != long || got[2] != "last" {
+		t.Errorf("read %d lines, want first, the %d byte line and last", len(got), len(long))
+	}
+}
+
+func TestLineReader_LineTooLong(t *testing.T) {
+	for _, input := range []string{
+		"short\n" + strings.Repeat("x", 100) + "\nnever read\n",
+		"short\n" + strings.Repeat("x", 11),
+		"short\n" + strings.Repeat("x", 11) + "\r\n",
+	} {
+		l := newLineReader(strings.NewReader(input), 10)
+		got := readLines(t, l)
+		if !reflect.DeepEqual(got, []string{"short"}) {
+			t.Errorf("lines = %q, want only the short line", got)
+		}
+		if err := l.Err(); !errors.Is(err, ErrLineTooLong) || !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("Err() = %v, want ErrLineTooLong at line 2", err)
+		}
+	}
+
+	// A line ending doesn't count towards the limit
+	l := newLineReader(strings.NewReader(strings.Repeat("x", 10)+"\r\n"), 10)
+	if got := readLines(t, l); len(got) != 1 || l.Err() != nil {
+		t.Errorf("lines = %q, Err() = %v, want the 10 byte line", got, l.Err())
+	}
+}
+
+func TestLineReader_ReadError(t *testing.T) {
+	failure := errors.New("disk failure")
+	l := newLineReader(io.MultiReader(strings.NewReader("a\n"), iotest.ErrReader(failure)), 0)
+	if got := readLines(t, l); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("lines = %q, want [a]", got)
+	}
+	if err := l.Err(); !errors.Is(err, failure) {
+		t.Errorf("Err() = %v, want %v", err, failure)
+	}
+}
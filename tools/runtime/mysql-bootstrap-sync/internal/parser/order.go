@@ -0,0 +1,156 @@
+package parser
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	// ddlTablePattern matches the table a CREATE TABLE or ALTER TABLE statement is about
+	ddlTablePattern = regexp.MustCompile(`(?is)^\s*(?:CREATE\s+(?:(?:GLOBAL|LOCAL)\s+)?(?:UNLOGGED\s+|TEMPORARY\s+|TEMP\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?|ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?)([^\s(]+)`)
+	// referencesPattern matches the tables a foreign key references
+	referencesPattern = regexp.MustCompile(`(?i)\bREFERENCES\s+([^\s(),;]+)`)
+)
+
+// OrderStatements orders statements so a replica with foreign keys can apply them: all DDL
+// first, in dump order, then the rows of each table after the rows of the tables it
+// references. Foreign keys are read from the CREATE TABLE and ALTER TABLE ... ADD CONSTRAINT
+// statements. Tables whose foreign keys form a cycle can't all be loaded after the tables
+// they reference; they are loaded in dump order and returned so the cycle can be reported.
+func OrderStatements(statements []Statement) ([]Statement, []string) {
+	ordered := make([]Statement, 0, len(statements))
+	var ddl []string
+	tables := newTableSet()
+	rows := make(map[string][]Statement)
+
+	for _, stmt := range statements {
+		switch s := stmt.(type) {
+		case DMLStatement:
+			key := tables.add(s.Table)
+			rows[key] = append(rows[key], s)
+		case DDLStatement:
+			ddl = append(ddl, s.SQL)
+			ordered = append(ordered, stmt)
+		default:
+			ordered = append(ordered, stmt)
+		}
+	}
+
+	// Each table with rows depends on the tables with rows it references
+	dependsOn := make(map[string][]string)
+	for _, sql := range ddl {
+		match := ddlTablePattern.FindStringSubmatch(sql)
+		if match == nil {
+			continue
+		}
+		child, ok := tables.lookup(match[1])
+		if !ok {
+			continue
+		}
+		for _, ref := range referencesPattern.FindAllStringSubmatch(sql, -1) {
+			parent, ok := tables.lookup(ref[1])
+			// A table referencing itself can only be ordered within its own rows
+			if ok && parent != child && !slices.Contains(dependsOn[child], parent) {
+				dependsOn[child] = append(dependsOn[child], parent)
+			}
+		}
+	}
+	for _, deps := range dependsOn {
+		slices.SortFunc(deps, func(a, b string) int { return tables.position[a] - tables.position[b] })
+	}
+
+	// Tarjan's algorithm groups the tables that reference each other in a cycle, and
+	// completes each group after the groups it references, so groups are loaded in order
+	var cyclic []string
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var visit func(key string)
+	visit = func(key string) {
+		index[key] = len(index)
+		low[key] = index[key]
+		stack = append(stack, key)
+		onStack[key] = true
+
+		for _, dep := range dependsOn[key] {
+			if _, visited := index[dep]; !visited {
+				visit(dep)
+				low[key] = min(low[key], low[dep])
+			} else if onStack[dep] {
+				low[key] = min(low[key], index[dep])
+			}
+		}
+		if low[key] != index[key] {
+			return
+		}
+
+		i := slices.Index(stack, key)
+		group := slices.Clone(stack[i:])
+		stack = stack[:i]
+		slices.SortFunc(group, func(a, b string) int { return tables.position[a] - tables.position[b] })
+		for _, member := range group {
+			onStack[member] = false
+			if len(group) > 1 {
+				cyclic = append(cyclic, tables.names[member])
+			}
+			ordered = append(ordered, rows[member]...)
+		}
+	}
+	for _, key := range tables.keys {
+		if _, visited := index[key]; !visited {
+			visit(key)
+		}
+	}
+
+	return ordered, cyclic
+}
+
+// tableSet holds the tables with rows in dump order, keyed by their normalized names
+type tableSet struct {
+	keys     []string
+	names    map[string]string   // key -> table name as in the dump
+	position map[string]int      // key -> index in keys
+	short    map[string][]string // unqualified name -> keys
+}
+
+func newTableSet() *tableSet {
+	return &tableSet{
+		names:    make(map[string]string),
+		position: make(map[string]int),
+		short:    make(map[string][]string),
+	}
+}
+
+// add records a table and returns its key
+func (t *tableSet) add(name string) string {
+	key := normalizeTableName(name)
+	if _, ok := t.names[key]; !ok {
+		t.position[key] = len(t.keys)
+		t.keys = append(t.keys, key)
+		t.names[key] = name
+		unqualified := key[strings.LastIndex(key, ".")+1:]
+		t.short[unqualified] = append(t.short[unqualified], key)
+	}
+	return key
+}
+
+// lookup returns the key of a table named in DDL, matching an unqualified name when the
+// DDL and the rows qualify the table differently and the name is unambiguous
+func (t *tableSet) lookup(name string) (string, bool) {
+	key := normalizeTableName(name)
+	if _, ok := t.names[key]; ok {
+		return key, true
+	}
+	if keys := t.short[key[strings.LastIndex(key, ".")+1:]]; len(keys) == 1 {
+		return keys[0], true
+	}
+	return "", false
+}
+
+// normalizeTableName removes identifier quotes and case from a table name
+func normalizeTableName(name string) string {
+	name = strings.NewReplacer(`"`, "", "`", "").Replace(name)
+	return strings.ToLower(name)
+}
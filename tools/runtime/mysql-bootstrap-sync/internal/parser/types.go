@@ -11,7 +11,7 @@ type Statement interface {
 type DDLStatement struct {
 	SQL      string
 	Table    string
-	Database string
+	Database string // Database the statement ran in, empty if the dump doesn't say
 	Time     time.Time
 }
 
@@ -22,6 +22,7 @@ func (d DDLStatement) GetType() string {
 // DMLStatement represents a DML statement (INSERT in mysqldump)
 type DMLStatement struct {
 	Table        string
+	Database     string // Database the table is in, empty if the dump doesn't say
 	ColumnNames  []string
 	ColumnValues [][]string // Multiple rows from extended inserts
 }
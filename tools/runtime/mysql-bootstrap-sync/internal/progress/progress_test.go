@@ -0,0 +1,132 @@
+package progress
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestTracker returns a tracker with a clock advanced by the returned function
+func newTestTracker() (*Tracker, func(time.Duration)) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	t := NewTracker("dump.sql")
+	t.startedAt, t.phaseStartedAt = now, now
+	t.now = func() time.Time { return now }
+	return t, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestTracker_Parsing(t *testing.T) {
+	tracker, advance := newTestTracker()
+	tracker.AddTotalBytes(1000)
+	tracker.SetPhase(PhaseParsing)
+
+	if _, err := io.Copy(io.Discard, tracker.Reader(strings.NewReader(strings.Repeat("x", 250)))); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	tracker.SetTable("public.users")
+	tracker.AddRows("public.users", 10)
+	advance(5 * time.Second)
+
+	status := tracker.Status()
+	if status.Phase != PhaseParsing || status.BytesRead != 250 || status.TotalBytes != 1000 {
+		t.Errorf("unexpected status %+v", status)
+	}
+	if status.Rate != 50 || status.RateUnit != "bytes/sec" {
+		t.Errorf("expected 50 bytes/sec, got %v %s", status.Rate, status.RateUnit)
+	}
+	if status.ETASeconds != 15 {
+		t.Errorf("expected ETA of 15s, got %v", status.ETASeconds)
+	}
+	if status.CurrentTable != "public.users" || status.Tables["public.users"].RowsRead != 10 {
+		t.Errorf("unexpected table progress %s %+v", status.CurrentTable, status.Tables)
+	}
+}
+
+func TestTracker_Storing(t *testing.T) {
+	tracker, advance := newTestTracker()
+	tracker.AddRows("users", 3)
+	advance(time.Minute)
+	tracker.SetChangesTotal(4)
+	tracker.SetPhase(PhaseStoring)
+
+	tracker.Stored("")
+	tracker.Stored("users")
+	advance(2 * time.Second)
+
+	status := tracker.Status()
+	if status.ChangesStored != 2 || status.ChangesTotal != 4 {
+		t.Errorf("expected 2 of 4 changes stored, got %d of %d", status.ChangesStored, status.ChangesTotal)
+	}
+	// The rate covers the storing phase only
+	if status.Rate != 1 || status.RateUnit != "changes/sec" || status.ETASeconds != 2 {
+		t.Errorf("expected 1 change/sec and 2s left, got %v %s and %vs", status.Rate, status.RateUnit, status.ETASeconds)
+	}
+	if got := status.Tables["users"]; got != (TableProgress{RowsRead: 3, RowsStored: 1}) {
+		t.Errorf("unexpected table progress %+v", got)
+	}
+	if status.ElapsedSeconds != 62 {
+		t.Errorf("expected 62s elapsed, got %v", status.ElapsedSeconds)
+	}
+
+	tracker.SetPhase(PhaseDone)
+	status = tracker.Status()
+	if status.Rate != 0 || status.ETASeconds != 0 || status.CurrentTable != "" {
+		t.Errorf("expected no rate, ETA or table when done, got %+v", status)
+	}
+}
+
+func TestTracker_Fail(t *testing.T) {
+	tracker, _ := newTestTracker()
+	tracker.Fail(errors.New("boom"))
+
+	status := tracker.Status()
+	if status.Phase != PhaseFailed || status.Error != "boom" {
+		t.Errorf("expected failed phase with error, got %s %q", status.Phase, status.Error)
+	}
+}
+
+func TestTracker_Nil(t *testing.T) {
+	var tracker *Tracker
+	tracker.SetPhase(PhaseParsing)
+	tracker.AddTotalBytes(1)
+	tracker.SetTable("users")
+	tracker.AddRows("users", 1)
+	tracker.SetChangesTotal(1)
+	tracker.Stored("users")
+	tracker.Fail(errors.New("boom"))
+
+	r := strings.NewReader("data")
+	if tracker.Reader(r) != io.Reader(r) {
+		t.Error("expected a nil tracker to return the reader unchanged")
+	}
+}
+
+func TestTracker_Handler(t *testing.T) {
+	tracker, _ := newTestTracker()
+	tracker.AddRows("users", 2)
+
+	rec := httptest.NewRecorder()
+	tracker.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected JSON content type, got %s", ct)
+	}
+	var status Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if status.Source != "dump.sql" || status.Tables["users"].RowsRead != 2 {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	rec = httptest.NewRecorder()
+	tracker.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/other", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for another path, got %d", rec.Code)
+	}
+}
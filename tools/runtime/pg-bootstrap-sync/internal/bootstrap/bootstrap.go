@@ -10,8 +10,12 @@ import (
 	"kasho/pkg/types"
 	"pg-bootstrap-sync/internal/converter"
 	"pg-bootstrap-sync/internal/parser"
+	"pg-bootstrap-sync/internal/progress"
 )
 
+// storeAttempts is how many times a batch is written before the bootstrap fails
+const storeAttempts = 3
+
 // Bootstrapper orchestrates the bootstrap process
 type Bootstrapper struct {
 	parser    parser.Parser
@@ -19,19 +23,34 @@ type Bootstrapper struct {
 	kvBuffer  *kvbuffer.KVBuffer
 	config    Config
 	stats     Statistics
+	progress  *progress.Tracker
 }
 
 // Config contains configuration for the bootstrap process
 type Config struct {
 	DumpFile         string
+	CSVDir           string // Directory of per-table CSV or TSV files, read instead of DumpFile
+	SchemaFile       string // Schema-only dump with the DDL for the tables in CSVDir
+	CSVNull          string // CSV field value read as NULL
 	KVBufferURL      string
 	BatchSize        int
 	MaxRowsPerTable  int
-	ProgressInterval int // Log progress every N changes
-	ResumeFromLSN    string
+	MaxLineSize      int    // Longest dump line accepted, in bytes (0 = parser default)
+	ProgressInterval int    // Log progress every N changes
+	ResumeFromLSN    string // Continue LSNs after this bootstrap LSN of an earlier run
+	SchemaOnly       bool   // Store only the DDL changes
+	DataOnly         bool   // Store only the row changes
 	DryRun           bool
 }
 
+// source returns the dump file or CSV directory to read
+func (c Config) source() string {
+	if c.CSVDir != "" {
+		return c.CSVDir
+	}
+	return c.DumpFile
+}
+
 // Statistics tracks bootstrap progress
 type Statistics struct {
 	StartTime         time.Time
@@ -49,14 +68,34 @@ type Statistics struct {
 
 // NewBootstrapper creates a new bootstrapper instance
 func NewBootstrapper(config Config) (*Bootstrapper, error) {
+	tracker := progress.NewTracker(config.source())
+
 	// Create parser
-	dumpParser := parser.NewDumpParser()
-	if config.MaxRowsPerTable > 0 {
-		dumpParser.MaxRowsPerTable = config.MaxRowsPerTable
+	var p parser.Parser
+	if config.CSVDir != "" {
+		csvParser := parser.NewCSVParser(config.SchemaFile)
+		csvParser.NullString = config.CSVNull
+		csvParser.MaxRowsPerTable = config.MaxRowsPerTable
+		csvParser.MaxLineSize = config.MaxLineSize
+		csvParser.Progress = tracker
+		p = csvParser
+	} else {
+		dumpParser := parser.NewDumpParser()
+		if config.MaxRowsPerTable > 0 {
+			dumpParser.MaxRowsPerTable = config.MaxRowsPerTable
+		}
+		dumpParser.MaxLineSize = config.MaxLineSize
+		dumpParser.Progress = tracker
+		p = dumpParser
 	}
 
 	// Create converter
 	conv := converter.NewChangeConverter()
+	if config.ResumeFromLSN != "" {
+		if err := conv.ResumeAfter(config.ResumeFromLSN); err != nil {
+			return nil, fmt.Errorf("invalid resume LSN: %w", err)
+		}
+	}
 
 	// Create KV buffer connection
 	var kvBuffer *kvbuffer.KVBuffer
@@ -69,27 +108,45 @@ func NewBootstrapper(config Config) (*Bootstrapper, error) {
 	}
 
 	return &Bootstrapper{
-		parser:    dumpParser,
+		parser:    p,
 		converter: conv,
 		kvBuffer:  kvBuffer,
 		config:    config,
 		stats:     Statistics{},
+		progress:  tracker,
 	}, nil
 }
 
 // Bootstrap executes the full bootstrap process
-func (b *Bootstrapper) Bootstrap(ctx context.Context) error {
+func (b *Bootstrapper) Bootstrap(ctx context.Context) (err error) {
+	defer func() {
+		if err != nil {
+			b.progress.Fail(err)
+		}
+	}()
+
 	b.stats.StartTime = time.Now()
+	source := b.config.source()
 	slog.Info("Starting bootstrap process",
-		"dump_file", b.config.DumpFile)
+		"source", source)
 
-	// Parse the dump file
-	slog.Info("Parsing dump file")
-	parseResult, err := b.parser.Parse(b.config.DumpFile)
+	// Parse the dump file or CSV directory
+	slog.Info("Parsing source", "source", source)
+	b.progress.SetPhase(progress.PhaseParsing)
+	parseResult, err := b.parser.Parse(source)
 	if err != nil {
-		return fmt.Errorf("failed to parse dump file: %w", err)
+		return fmt.Errorf("failed to parse %s: %w", source, err)
 	}
 
+	// Apply the DDL first and load each table after the tables it references
+	ordered, cyclic := parser.OrderStatements(parseResult.Statements)
+	parseResult.Statements = ordered
+	if len(cyclic) > 0 {
+		slog.Warn("Foreign keys reference each other in a cycle, loading these tables in dump order",
+			"tables", cyclic)
+	}
+	b.filterStatements(parseResult)
+
 	b.stats.StatementsRead = len(parseResult.Statements)
 	slog.Info("Parsed statements successfully",
 		"total_statements", parseResult.Metadata.StatementCount,
@@ -99,6 +156,7 @@ func (b *Bootstrapper) Bootstrap(ctx context.Context) error {
 
 	// Convert statements to changes
 	slog.Info("Converting statements to changes")
+	b.progress.SetPhase(progress.PhaseConverting)
 	changes, err := b.converter.ConvertStatements(parseResult.Statements)
 	if err != nil {
 		return fmt.Errorf("failed to convert statements: %w", err)
@@ -110,6 +168,8 @@ func (b *Bootstrapper) Bootstrap(ctx context.Context) error {
 
 	// Store changes in KV buffer
 	if !b.config.DryRun {
+		b.progress.SetChangesTotal(len(changes))
+		b.progress.SetPhase(progress.PhaseStoring)
 		slog.Info("Storing changes in KV buffer",
 			"batch_size", b.config.BatchSize)
 		err = b.storeChanges(ctx, changes)
@@ -130,11 +190,41 @@ func (b *Bootstrapper) Bootstrap(ctx context.Context) error {
 	b.stats.DDLCount = parseResult.Metadata.DDLCount
 	b.stats.DMLCount = parseResult.Metadata.DMLCount
 
+	b.progress.SetPhase(progress.PhaseDone)
 	b.logFinalStatistics(ctx)
 	return nil
 }
 
-// storeChanges stores changes in the KV buffer with progress tracking
+// filterStatements keeps only the DDL statements for a schema-only bootstrap, or only the
+// DML statements for a data-only one
+func (b *Bootstrapper) filterStatements(result *parser.ParseResult) {
+	if !b.config.SchemaOnly && !b.config.DataOnly {
+		return
+	}
+
+	kept := make([]parser.Statement, 0, len(result.Statements))
+	for _, stmt := range result.Statements {
+		if _, isDDL := stmt.(parser.DDLStatement); isDDL == b.config.SchemaOnly {
+			kept = append(kept, stmt)
+		}
+	}
+	slog.Info("Skipping statements",
+		"schema_only", b.config.SchemaOnly,
+		"data_only", b.config.DataOnly,
+		"skipped", len(result.Statements)-len(kept))
+
+	result.Statements = kept
+	result.Metadata.StatementCount = len(kept)
+	if b.config.SchemaOnly {
+		result.Metadata.DMLCount = 0
+	} else {
+		result.Metadata.DDLCount = 0
+	}
+}
+
+// storeChanges stores changes in the KV buffer in batches of one table's rows, each written
+// with a manifest of its positions and checksum. A batch Redis doesn't acknowledge in full
+// is written again, and the bootstrap fails if it still isn't, so no rows are dropped.
 func (b *Bootstrapper) storeChanges(ctx context.Context, changes []*types.Change) error {
 	batchSize := b.config.BatchSize
 	if batchSize <= 0 {
@@ -147,7 +237,7 @@ func (b *Bootstrapper) storeChanges(ctx context.Context, changes []*types.Change
 	}
 
 	stored := 0
-	for i, change := range changes {
+	for start := 0; start < len(changes); {
 		// Check for context cancellation
 		select {
 		case <-ctx.Done():
@@ -155,41 +245,105 @@ func (b *Bootstrapper) storeChanges(ctx context.Context, changes []*types.Change
 		default:
 		}
 
-		// Store the change directly (no conversion needed)
-		err := b.kvBuffer.AddChange(ctx, change)
-		if err != nil {
-			b.stats.ErrorsEncountered++
-			slog.Error("Failed to store change",
-				"change_index", i+1,
-				"position", change.Position,
-				"error", err)
-			continue
+		table := changeTable(changes[start])
+		end := start + 1
+		for end < len(changes) && end-start < batchSize && changeTable(changes[end]) == table {
+			end++
+		}
+		batch := make([]kvbuffer.Change, 0, end-start)
+		for _, change := range changes[start:end] {
+			batch = append(batch, change)
 		}
 
-		stored++
-		b.stats.ChangesStored = stored
+		if err := b.storeBatch(ctx, table, batch); err != nil {
+			b.stats.ErrorsEncountered++
+			return err
+		}
 
+		for range batch {
+			b.progress.Stored(table)
+		}
 		// Log progress
-		if (i+1)%progressInterval == 0 {
+		if (stored+len(batch))/progressInterval > stored/progressInterval {
 			elapsed := time.Since(b.stats.StartTime)
-			rate := float64(stored) / elapsed.Seconds()
+			rate := float64(stored+len(batch)) / elapsed.Seconds()
 			slog.Info("Storage progress",
-				"stored", stored,
+				"stored", stored+len(batch),
 				"total", len(changes),
 				"rate", fmt.Sprintf("%.1f changes/sec", rate),
-				"percentage", fmt.Sprintf("%.1f%%", float64(stored)/float64(len(changes))*100))
+				"percentage", fmt.Sprintf("%.1f%%", float64(stored+len(batch))/float64(len(changes))*100))
 		}
+		stored += len(batch)
+		b.stats.ChangesStored = stored
+		start = end
 
 		// Optional: Add small delay between batches to avoid overwhelming Redis
-		if (i+1)%batchSize == 0 {
-			time.Sleep(10 * time.Millisecond)
-		}
+		time.Sleep(10 * time.Millisecond)
 	}
 
 	slog.Info("Storage completed",
 		"stored", stored,
-		"total", len(changes),
-		"success_rate", fmt.Sprintf("%.1f%%", float64(stored)/float64(len(changes))*100))
+		"total", len(changes))
+	return nil
+}
+
+// storeBatch writes a batch, retrying it when the write fails or isn't fully acknowledged.
+// Retrying is safe: the changes stored by an earlier attempt are skipped as duplicates.
+func (b *Bootstrapper) storeBatch(ctx context.Context, table string, batch []kvbuffer.Change) error {
+	var err error
+	for attempt := 1; attempt <= storeAttempts; attempt++ {
+		if _, err = b.kvBuffer.AddBatch(ctx, table, batch); err == nil {
+			return nil
+		}
+		slog.Warn("Failed to store batch",
+			"table", table,
+			"first_position", batch[0].GetPosition(),
+			"changes", len(batch),
+			"attempt", attempt,
+			"error", err)
+		if attempt < storeAttempts {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+	}
+	return fmt.Errorf("failed to store batch of %d changes at %s after %d attempts: %w",
+		len(batch), batch[0].GetPosition(), storeAttempts, err)
+}
+
+// changeTable returns the table of a row change, or "" for DDL
+func changeTable(change *types.Change) string {
+	if dml, ok := change.Data.(*types.DMLData); ok {
+		return dml.Table
+	}
+	return ""
+}
+
+// Verify re-reads the batches stored in the KV buffer and checks them against their
+// manifests, failing if any batch is incomplete or its checksum differs
+func (b *Bootstrapper) Verify(ctx context.Context) error {
+	if b.kvBuffer == nil {
+		return fmt.Errorf("nothing to verify in a dry run")
+	}
+
+	slog.Info("Verifying stored batches")
+	result, err := b.kvBuffer.VerifyBatches(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to verify stored batches: %w", err)
+	}
+	for _, failure := range result.Failed {
+		slog.Error("Stored batch does not match its manifest",
+			"table", failure.Manifest.Table,
+			"first_position", failure.Manifest.FirstPosition,
+			"last_position", failure.Manifest.LastPosition,
+			"reason", failure.Reason)
+	}
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("%d of %d stored batches failed verification", len(result.Failed), len(result.Failed)+result.Verified)
+	}
+	slog.Info("Verified stored batches", "batches", result.Verified)
 	return nil
 }
 
@@ -227,6 +381,11 @@ func (b *Bootstrapper) logFinalStatistics(ctx context.Context) {
 	}
 }
 
+// Progress returns the tracker reporting the progress of the run
+func (b *Bootstrapper) Progress() *progress.Tracker {
+	return b.progress
+}
+
 // GetStatistics returns the current bootstrap statistics
 func (b *Bootstrapper) GetStatistics() Statistics {
 	return b.stats
@@ -70,6 +70,7 @@ func (c *ChangeConverter) convertDDLStatement(stmt parser.DDLStatement) (*types.
 	change := &types.Change{
 		Position: lsn,
 		Data:     ddlData,
+		Dialect:  "postgresql",
 	}
 
 	return change, nil
@@ -114,6 +115,7 @@ func (c *ChangeConverter) convertDMLStatement(stmt parser.DMLStatement) ([]*type
 		change := &types.Change{
 			Position: lsn,
 			Data:     dmlData,
+			Dialect:  "postgresql",
 		}
 
 		changes = append(changes, change)
@@ -190,3 +192,12 @@ func (c *ChangeConverter) GetNextLSN() string {
 func (c *ChangeConverter) GetCurrentSequence() int64 {
 	return c.lsnGenerator.GetSequence()
 }
+
+// ResumeAfter continues generating LSNs after a bootstrap LSN of an earlier run
+func (c *ChangeConverter) ResumeAfter(lsn string) error {
+	seq, err := ParseBootstrapLSN(lsn)
+	if err != nil {
+		return err
+	}
+	return c.lsnGenerator.SetSequence(seq)
+}
@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"log"
@@ -9,12 +8,17 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"pg-bootstrap-sync/internal/progress"
 )
 
 // DumpParser implements the Parser interface for pg_dump files
 type DumpParser struct {
 	// Configuration options
 	MaxRowsPerTable int // Limit rows per table for testing (0 = no limit)
+	MaxLineSize     int // Longest line accepted, in bytes (0 = DefaultMaxLineSize)
+
+	Progress *progress.Tracker // Records the bytes and rows read (optional)
 }
 
 // NewDumpParser creates a new dump parser
@@ -32,7 +36,11 @@ func (p *DumpParser) Parse(filename string) (*ParseResult, error) {
 	}
 	defer file.Close()
 
-	result, err := p.ParseStream(file)
+	if info, err := file.Stat(); err == nil {
+		p.Progress.AddTotalBytes(info.Size())
+	}
+
+	result, err := p.ParseStream(p.Progress.Reader(file))
 	if err != nil {
 		return nil, err
 	}
@@ -48,7 +56,8 @@ func (p *DumpParser) ParseStream(reader interface{}) (*ParseResult, error) {
 		return nil, fmt.Errorf("reader must implement io.Reader")
 	}
 
-	scanner := bufio.NewScanner(r)
+	// Extended INSERTs and COPY rows with large values can be megabytes long on one line
+	scanner := newLineReader(r, p.MaxLineSize)
 
 	result := &ParseResult{
 		Statements: make([]Statement, 0),
@@ -101,6 +110,7 @@ func (p *DumpParser) ParseStream(reader interface{}) (*ParseResult, error) {
 				copyTable = copyInfo.table
 				copyColumns = copyInfo.columns
 				copyRows = make([][]string, 0)
+				p.Progress.SetTable(copyTable)
 
 				// Add table to found tables if not already present
 				if !contains(result.Metadata.TablesFound, copyTable) {
@@ -126,6 +136,7 @@ func (p *DumpParser) ParseStream(reader interface{}) (*ParseResult, error) {
 				}
 				result.Statements = append(result.Statements, stmt)
 				result.Metadata.DMLCount++
+				p.Progress.AddRows(copyTable, len(copyRows))
 			}
 			inCopyData = false
 			copyTable = ""
@@ -308,6 +319,7 @@ func (p *DumpParser) parseWithSQLParser(sql string, result *ParseResult) error {
 					}
 					result.Statements = append(result.Statements, dmlStmt)
 					result.Metadata.DMLCount++
+					p.Progress.AddRows(stmt.TableName, 1)
 
 					// Add table to found tables if not already present
 					if !contains(result.Metadata.TablesFound, stmt.TableName) {
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"errors"
 	"strings"
 	"testing"
 )
@@ -79,6 +80,46 @@ COPY posts (id, title) FROM stdin;
 	}
 }
 
+func TestDumpParser_MultiMegabyteCopyRow(t *testing.T) {
+	parser := NewDumpParser()
+
+	// A COPY row with a 5MB text value, far beyond bufio.Scanner's 64KB default
+	value := strings.Repeat("0123456789", 512*1024)
+	dumpData := "COPY public.documents (id, body) FROM stdin;\n" +
+		"1\t" + value + "\n" +
+		"2\tshort\n" +
+		"\\.\n"
+
+	result, err := parser.ParseStream(strings.NewReader(dumpData))
+	if err != nil {
+		t.Fatalf("ParseStream failed: %v", err)
+	}
+	if len(result.Statements) != 1 {
+		t.Fatalf("Expected 1 statement, got %d", len(result.Statements))
+	}
+	dml, ok := result.Statements[0].(DMLStatement)
+	if !ok || len(dml.ColumnValues) != 2 {
+		t.Fatalf("Expected a DML statement with 2 rows, got %#v", result.Statements[0])
+	}
+	if dml.ColumnValues[0][1] != value {
+		t.Errorf("Expected the %d byte value, got %d bytes", len(value), len(dml.ColumnValues[0][1]))
+	}
+}
+
+func TestDumpParser_LineTooLong(t *testing.T) {
+	parser := NewDumpParser()
+	parser.MaxLineSize = 1024
+
+	dumpData := "COPY public.documents (id, body) FROM stdin;\n1\t" + strings.Repeat("x", 2048) + "\n\\.\n"
+	_, err := parser.ParseStream(strings.NewReader(dumpData))
+	if !errors.Is(err, ErrLineTooLong) {
+		t.Fatalf("ParseStream() error = %v, want ErrLineTooLong", err)
+	}
+	if !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("error %q doesn't name the line", err)
+	}
+}
+
 func TestDumpParser_ParseCopyStatement(t *testing.T) {
 	parser := NewDumpParser()
 
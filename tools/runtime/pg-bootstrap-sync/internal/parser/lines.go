@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultMaxLineSize is the longest dump line accepted when MaxLineSize isn't set
+const DefaultMaxLineSize = 64 * 1024 * 1024 // 64MB
+
+// ErrLineTooLong is returned for a dump line longer than the maximum line size
+var ErrLineTooLong = errors.New("line exceeds the maximum line size")
+
+// lineReader reads a dump line by line like bufio.Scanner, but without a fixed token
+// limit: its buffer grows with the longest line read, up to a maximum
+type lineReader struct {
+	r       *bufio.Reader
+	maxSize int
+	line    []byte
+	number  int
+	err     error
+}
+
+func newLineReader(r io.Reader, maxSize int) *lineReader {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxLineSize
+	}
+	return &lineReader{r: bufio.NewReaderSize(r, 64*1024), maxSize: maxSize}
+}
+
+// Scan reads the next line, without its line ending. It returns false at the end of the
+// input or on an error, which Err returns.
+func (l *lineReader) Scan() bool {
+	if l.err != nil {
+		return false
+	}
+	l.line = l.line[:0]
+	for {
+		chunk, err := l.r.ReadSlice('\n')
+		// Stop reading a line once it can't fit, allowing for its line ending
+		if len(l.line)+len(chunk) > l.maxSize+2 {
+			return l.tooLong()
+		}
+		l.line = append(l.line, chunk...)
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			l.err = err
+			return false
+		}
+		// The last line may have no line ending
+		if err != nil && len(l.line) == 0 {
+			return false
+		}
+
+		l.line = bytes.TrimSuffix(l.line, []byte("\n"))
+		l.line = bytes.TrimSuffix(l.line, []byte("\r"))
+		if len(l.line) > l.maxSize {
+			return l.tooLong()
+		}
+		l.number++
+		return true
+	}
+}
+
+// tooLong stops reading at the line being read
+func (l *lineReader) tooLong() bool {
+	l.err = fmt.Errorf("%w of %d bytes at line %d", ErrLineTooLong, l.maxSize, l.number+1)
+	return false
+}
+
+// Text returns the line read by the last call to Scan
+func (l *lineReader) Text() string {
+	return string(l.line)
+}
+
+// Err returns the error that stopped Scan, or nil at the end of the input
+func (l *lineReader) Err() error {
+	return l.err
+}
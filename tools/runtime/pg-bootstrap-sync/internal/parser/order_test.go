@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// orderOf lists DDL for the DDL statements and the tables of the DML statements
+func orderOf(statements []Statement) []string {
+	var order []string
+	for _, stmt := range statements {
+		switch s := stmt.(type) {
+		case DDLStatement:
+			order = append(order, "DDL")
+		case DMLStatement:
+			order = append(order, s.Table)
+		}
+	}
+	return order
+}
+
+func rowsFor(table string) DMLStatement {
+	return DMLStatement{Table: table, ColumnNames: []string{"id"}, ColumnValues: [][]string{{"1"}}}
+}
+
+func TestOrderStatements(t *testing.T) {
+	tests := []struct {
+		name       string
+		statements []Statement
+		want       []string
+		wantCyclic []string
+	}{
+		{
+			name: "DDL before data",
+			statements: []Statement{
+				DDLStatement{SQL: "CREATE TABLE public.users (id integer)"},
+				rowsFor("public.users"),
+				DDLStatement{SQL: "CREATE INDEX idx ON public.users (id)"},
+			},
+			want: []string{"DDL", "DDL", "public.users"},
+		},
+		{
+			name: "foreign key from ALTER TABLE",
+			statements: []Statement{
+				rowsFor("public.orders"),
+				rowsFor("public.users"),
+				DDLStatement{SQL: "ALTER TABLE ONLY public.orders\n    ADD CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id);"},
+			},
+			want: []string{"DDL", "public.users", "public.orders"},
+		},
+		{
+			name: "foreign keys in CREATE TABLE",
+			statements: []Statement{
+				DDLStatement{SQL: "CREATE TABLE `order_items` (\n  `order_id` int,\n  `product_id` int,\n" +
+					"  CONSTRAINT `fk_order` FOREIGN KEY (`order_id`) REFERENCES `orders` (`id`),\n" +
+					"  CONSTRAINT `fk_product` FOREIGN KEY (`product_id`) REFERENCES `products` (`id`)\n)"},
+				DDLStatement{SQL: "CREATE TABLE IF NOT EXISTS orders (id int, user_id int REFERENCES users)"},
+				rowsFor("order_items"),
+				rowsFor("orders"),
+				rowsFor("products"),
+				rowsFor("users"),
+			},
+			want: []string{"DDL", "DDL", "users", "orders", "products", "order_items"},
+		},
+		{
+			name: "unrelated tables keep dump order",
+			statements: []Statement{
+				rowsFor("b"),
+				rowsFor("a"),
+				rowsFor("b"),
+				rowsFor("c"),
+			},
+			want: []string{"b", "b", "a", "c"},
+		},
+		{
+			name: "self reference",
+			statements: []Statement{
+				DDLStatement{SQL: "ALTER TABLE ONLY public.employees ADD CONSTRAINT fk FOREIGN KEY (manager_id) REFERENCES public.employees(id);"},
+				rowsFor("public.employees"),
+			},
+			want: []string{"DDL", "public.employees"},
+		},
+		{
+			name: "quoted and unqualified names",
+			statements: []Statement{
+				rowsFor(`public."Orders"`),
+				rowsFor("public.users"),
+				DDLStatement{SQL: `ALTER TABLE "public"."Orders" ADD CONSTRAINT fk FOREIGN KEY (user_id) REFERENCES users (id);`},
+			},
+			want: []string{"DDL", "public.users", `public."Orders"`},
+		},
+		{
+			name: "cycle",
+			statements: []Statement{
+				DDLStatement{SQL: "ALTER TABLE c ADD CONSTRAINT fk FOREIGN KEY (a_id) REFERENCES a(id);"},
+				DDLStatement{SQL: "ALTER TABLE a ADD CONSTRAINT fk FOREIGN KEY (b_id) REFERENCES b(id);"},
+				DDLStatement{SQL: "ALTER TABLE b ADD CONSTRAINT fk FOREIGN KEY (a_id) REFERENCES a(id);"},
+				rowsFor("c"),
+				rowsFor("b"),
+				rowsFor("a"),
+			},
+			want:       []string{"DDL", "DDL", "DDL", "b", "a", "c"},
+			wantCyclic: []string{"b", "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered, cyclic := OrderStatements(tt.statements)
+			got := orderOf(ordered)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("OrderStatements() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(cyclic, tt.wantCyclic) {
+				t.Errorf("OrderStatements() cyclic = %q, want %q", cyclic, tt.wantCyclic)
+			}
+		})
+	}
+}
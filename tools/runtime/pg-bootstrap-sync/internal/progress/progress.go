@@ -0,0 +1,238 @@
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Phase is the step a bootstrap run is at
+type Phase string
+
+const (
+	PhaseStarting   Phase = "starting"
+	PhaseParsing    Phase = "parsing"
+	PhaseConverting Phase = "converting"
+	PhaseStoring    Phase = "storing"
+	PhaseDone       Phase = "done"
+	PhaseFailed     Phase = "failed"
+)
+
+// TableProgress counts the rows of a table read from the dump and stored in the KV buffer
+type TableProgress struct {
+	RowsRead   int `json:"rows_read"`
+	RowsStored int `json:"rows_stored"`
+}
+
+// Status is a snapshot of the progress of a bootstrap run
+type Status struct {
+	Phase          Phase                    `json:"phase"`
+	Source         string                   `json:"source"`
+	StartedAt      time.Time                `json:"started_at"`
+	ElapsedSeconds float64                  `json:"elapsed_seconds"`
+	BytesRead      int64                    `json:"bytes_read"`
+	TotalBytes     int64                    `json:"total_bytes"`
+	ChangesTotal   int                      `json:"changes_total"`
+	ChangesStored  int                      `json:"changes_stored"`
+	CurrentTable   string                   `json:"current_table,omitempty"`
+	Rate           float64                  `json:"rate"`                  // bytes/sec while parsing, changes/sec while storing
+	RateUnit       string                   `json:"rate_unit,omitempty"`   // "bytes/sec" or "changes/sec"
+	ETASeconds     float64                  `json:"eta_seconds,omitempty"` // time left in the current phase, when known
+	Error          string                   `json:"error,omitempty"`
+	Tables         map[string]TableProgress `json:"tables"`
+}
+
+// Tracker records the progress of a bootstrap run for the status endpoint. It is safe for
+// concurrent use, and a nil Tracker records nothing.
+type Tracker struct {
+	mu             sync.Mutex
+	source         string
+	phase          Phase
+	startedAt      time.Time
+	phaseStartedAt time.Time
+	totalBytes     int64
+	bytesRead      atomic.Int64 // counted without the lock, as the dump is read
+	changesTotal   int
+	changesStored  int
+	table          string
+	tables         map[string]*TableProgress
+	err            string
+	now            func() time.Time
+}
+
+// NewTracker creates a tracker for a bootstrap run reading source
+func NewTracker(source string) *Tracker {
+	now := time.Now()
+	return &Tracker{
+		source:         source,
+		phase:          PhaseStarting,
+		startedAt:      now,
+		phaseStartedAt: now,
+		tables:         make(map[string]*TableProgress),
+		now:            time.Now,
+	}
+}
+
+// SetPhase moves the run to the next phase
+func (t *Tracker) SetPhase(phase Phase) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.phase = phase
+	t.phaseStartedAt = t.now()
+	if phase == PhaseDone {
+		t.table = ""
+	}
+}
+
+// Fail marks the run as failed
+func (t *Tracker) Fail(err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.phase = PhaseFailed
+	t.err = err.Error()
+}
+
+// AddTotalBytes adds the size of a file to be read
+func (t *Tracker) AddTotalBytes(n int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.totalBytes += n
+}
+
+// Reader counts the bytes read from r
+func (t *Tracker) Reader(r io.Reader) io.Reader {
+	if t == nil {
+		return r
+	}
+	return &countingReader{r: r, n: &t.bytesRead}
+}
+
+// SetTable records the table being read
+func (t *Tracker) SetTable(table string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.table = table
+}
+
+// AddRows counts rows read for a table
+func (t *Tracker) AddRows(table string, n int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.table = table
+	t.tableProgress(table).RowsRead += n
+}
+
+// SetChangesTotal records the number of changes to store
+func (t *Tracker) SetChangesTotal(n int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.changesTotal = n
+}
+
+// Stored counts a change stored in the KV buffer; table is empty for DDL
+func (t *Tracker) Stored(table string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.changesStored++
+	if table != "" {
+		t.table = table
+		t.tableProgress(table).RowsStored++
+	}
+}
+
+func (t *Tracker) tableProgress(table string) *TableProgress {
+	p, ok := t.tables[table]
+	if !ok {
+		p = &TableProgress{}
+		t.tables[table] = p
+	}
+	return p
+}
+
+// Status returns the current progress, with the rate and ETA of the current phase
+func (t *Tracker) Status() Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	status := Status{
+		Phase:          t.phase,
+		Source:         t.source,
+		StartedAt:      t.startedAt,
+		ElapsedSeconds: now.Sub(t.startedAt).Seconds(),
+		BytesRead:      t.bytesRead.Load(),
+		TotalBytes:     t.totalBytes,
+		ChangesTotal:   t.changesTotal,
+		ChangesStored:  t.changesStored,
+		CurrentTable:   t.table,
+		Error:          t.err,
+		Tables:         make(map[string]TableProgress, len(t.tables)),
+	}
+	for table, p := range t.tables {
+		status.Tables[table] = *p
+	}
+
+	// Rates cover the current phase, as parsing and storing run at unrelated speeds
+	var done, total float64
+	switch t.phase {
+	case PhaseParsing:
+		done, total, status.RateUnit = float64(status.BytesRead), float64(t.totalBytes), "bytes/sec"
+	case PhaseStoring:
+		done, total, status.RateUnit = float64(t.changesStored), float64(t.changesTotal), "changes/sec"
+	default:
+		return status
+	}
+	if elapsed := now.Sub(t.phaseStartedAt).Seconds(); elapsed > 0 {
+		status.Rate = done / elapsed
+	}
+	if status.Rate > 0 && total > done {
+		status.ETASeconds = (total - done) / status.Rate
+	}
+	return status
+}
+
+// Handler serves the status as JSON at /status
+func (t *Tracker) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t.Status())
+	})
+	return mux
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -11,14 +12,24 @@ import (
 	"github.com/spf13/cobra"
 	"kasho/pkg/version"
 	"pg-bootstrap-sync/internal/bootstrap"
+	"pg-bootstrap-sync/internal/parser"
 )
 
 var (
 	dumpFile         string
+	csvDir           string
+	schemaFile       string
+	csvNull          string
 	kvURL            string
 	batchSize        int
 	maxRowsPerTable  int
+	maxLineSize      int
+	schemaOnly       bool
+	dataOnly         bool
+	resumeFrom       string
+	statusAddr       string
 	progressInterval int
+	verify           bool
 	dryRun           bool
 	verbose          bool
 )
@@ -34,19 +45,38 @@ replica databases with historical data before starting real-time WAL replication
 	}
 
 	// Define command-line flags
-	rootCmd.Flags().StringVarP(&dumpFile, "dump-file", "d", "", "Path to pg_dump file (required)")
+	rootCmd.Flags().StringVarP(&dumpFile, "dump-file", "d", "", "Path to pg_dump file (required unless --csv-dir is set)")
+	rootCmd.Flags().StringVar(&csvDir, "csv-dir", "", "Directory of per-table CSV or TSV files to read instead of a dump")
+	rootCmd.Flags().StringVar(&schemaFile, "schema-file", "", "Schema-only pg_dump file with the DDL for --csv-dir (required with --csv-dir)")
+	rootCmd.Flags().StringVar(&csvNull, "csv-null", "", "CSV field value read as NULL, besides empty fields")
 	rootCmd.Flags().StringVarP(&kvURL, "kv-url", "k", "", "Redis connection URL (required)")
 	rootCmd.Flags().IntVarP(&batchSize, "batch-size", "b", 1000, "Processing batch size")
 	rootCmd.Flags().IntVarP(&maxRowsPerTable, "max-rows-per-table", "m", 0, "Maximum rows per table (0 = no limit)")
+	rootCmd.Flags().IntVar(&maxLineSize, "max-line-size", parser.DefaultMaxLineSize, "Longest dump line accepted, in bytes")
 	rootCmd.Flags().IntVarP(&progressInterval, "progress-interval", "p", 1000, "Log progress every N changes")
+	rootCmd.Flags().BoolVar(&schemaOnly, "schema-only", false, "Store only the DDL changes, to create the replica's tables before its data")
+	rootCmd.Flags().BoolVar(&dataOnly, "data-only", false, "Store only the row changes, skipping the DDL")
+	rootCmd.Flags().StringVar(&resumeFrom, "resume-from", "", "Continue positions after this one, such as the last_position of a --schema-only run")
+	rootCmd.Flags().StringVar(&statusAddr, "status-addr", "", "Serve progress as JSON at /status on this address, e.g. :8090")
+	rootCmd.Flags().BoolVar(&verify, "verify", false, "Re-read the stored changes and check them against their batch manifests; without --dump-file or --csv-dir, only verify")
 	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Parse and convert but don't store in KV buffer")
 	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose logging")
 
-	// Mark required flags
-	rootCmd.MarkFlagRequired("dump-file")
-
-	// Only require kv-url if not doing a dry run
+	// Read either a dump or a directory of CSV files, unless only verifying, and only
+	// require kv-url if not doing a dry run
 	rootCmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if dumpFile != "" && csvDir != "" || dumpFile == "" && csvDir == "" && !verify {
+			return fmt.Errorf("exactly one of --dump-file or --csv-dir is required")
+		}
+		if verify && dryRun {
+			return fmt.Errorf("--verify needs stored changes and can't be used with --dry-run")
+		}
+		if csvDir != "" && schemaFile == "" {
+			return fmt.Errorf("--schema-file is required with --csv-dir")
+		}
+		if schemaOnly && dataOnly {
+			return fmt.Errorf("--schema-only and --data-only can't be used together")
+		}
 		if !dryRun && kvURL == "" {
 			return fmt.Errorf("--kv-url is required unless --dry-run is specified")
 		}
@@ -87,15 +117,30 @@ func runBootstrap(cmd *cobra.Command, args []string) error {
 		"commit", version.GitCommit,
 		"built", version.BuildDate,
 		"dump_file", dumpFile,
+		"csv_dir", csvDir,
+		"schema_file", schemaFile,
 		"batch_size", batchSize,
 		"max_rows_per_table", maxRowsPerTable,
+		"max_line_size", maxLineSize,
 		"progress_interval", progressInterval,
+		"schema_only", schemaOnly,
+		"data_only", dataOnly,
+		"resume_from", resumeFrom,
+		"status_addr", statusAddr,
+		"verify", verify,
 		"dry_run", dryRun,
 		"verbose", verbose,
 	)
 
-	// Validate dump file exists
-	if _, err := os.Stat(dumpFile); os.IsNotExist(err) {
+	// Validate dump file or CSV directory exists
+	if csvDir != "" {
+		for _, path := range []string{csvDir, schemaFile} {
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				slog.Error("CSV input does not exist", "path", path)
+				return fmt.Errorf("CSV input does not exist: %s", path)
+			}
+		}
+	} else if _, err := os.Stat(dumpFile); dumpFile != "" && os.IsNotExist(err) {
 		slog.Error("Dump file does not exist", "path", dumpFile)
 		return fmt.Errorf("dump file does not exist: %s", dumpFile)
 	}
@@ -103,10 +148,17 @@ func runBootstrap(cmd *cobra.Command, args []string) error {
 	// Create bootstrap configuration
 	config := bootstrap.Config{
 		DumpFile:         dumpFile,
+		CSVDir:           csvDir,
+		SchemaFile:       schemaFile,
+		CSVNull:          csvNull,
 		KVBufferURL:      kvURL,
 		BatchSize:        batchSize,
 		MaxRowsPerTable:  maxRowsPerTable,
+		MaxLineSize:      maxLineSize,
 		ProgressInterval: progressInterval,
+		ResumeFromLSN:    resumeFrom,
+		SchemaOnly:       schemaOnly,
+		DataOnly:         dataOnly,
 		DryRun:           dryRun,
 	}
 
@@ -117,6 +169,21 @@ func runBootstrap(cmd *cobra.Command, args []string) error {
 	}
 	defer bootstrapper.Close()
 
+	// Without a source, only check the batches stored by an earlier run
+	if dumpFile == "" && csvDir == "" {
+		return bootstrapper.Verify(ctx)
+	}
+
+	// Serve progress for dashboards if an address is configured
+	if statusAddr != "" {
+		go func() {
+			slog.Info("Serving bootstrap status", "addr", statusAddr)
+			if err := http.ListenAndServe(statusAddr, bootstrapper.Progress().Handler()); err != nil {
+				slog.Error("Status server stopped", "error", err)
+			}
+		}()
+	}
+
 	// Handle graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -154,5 +221,9 @@ func runBootstrap(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("bootstrap completed with %d errors", stats.ErrorsEncountered)
 	}
 
+	if verify {
+		return bootstrapper.Verify(ctx)
+	}
+
 	return nil
 }